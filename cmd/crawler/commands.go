@@ -0,0 +1,781 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"web-crawler/internal/api"
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/checkpoint"
+	"web-crawler/internal/config"
+	"web-crawler/internal/crawler"
+	"web-crawler/internal/debug"
+	"web-crawler/internal/extract"
+	"web-crawler/internal/jobs"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/search"
+	"web-crawler/internal/storage"
+	"web-crawler/pkg/utils"
+)
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	apiAddr := fs.String("api", "", "Control API of a running crawl (default: api.addr when the API is enabled)")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string, counts stored pages when set")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	addr := *apiAddr
+	if addr == "" && cfg.API.Enabled {
+		addr = cfg.API.Addr
+	}
+	if addr != "" {
+		var stats map[string]interface{}
+		if err := apiRequest(http.MethodGet, addr, "/stats", nil, &stats); err == nil {
+			return printJSON(stats)
+		}
+		logger.Warn("No crawl answering on %s, showing offline stats", addr)
+	}
+
+	return printJSON(offlineStats(cfg, *mongoURI))
+}
+
+// offlineStats collects what can be read without a running crawl: the last
+// checkpoint, the dead-letter store, saved content, and stored pages
+func offlineStats(cfg *config.Config, mongoURI string) map[string]interface{} {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stats := make(map[string]interface{})
+
+	if cp, err := checkpoint.Load(cfg.Checkpoint.Path); err == nil {
+		stats["checkpoint"] = map[string]interface{}{
+			"path":      cfg.Checkpoint.Path,
+			"createdAt": cp.CreatedAt,
+			"queued":    len(cp.Queue),
+			"visited":   len(cp.Visited),
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to read checkpoint: %v", err)
+	}
+
+	if cfg.Queue.DeadLetter.Backend == "file" {
+		if store, err := queue.NewDeadLetterStore(cfg.Queue.DeadLetter); err == nil {
+			if n, err := store.Len(ctx); err == nil {
+				stats["deadLetters"] = n
+			}
+			store.Close()
+		}
+	}
+
+	saver := utils.NewContentSaver(cfg.ContentSaver.OutputDir, cfg.ContentSaver.Enabled, cfg.ContentSaver.MaxFileSize)
+	saver.SetFormat(cfg.ContentSaver.Format)
+	if content, err := saver.GetStats(); err == nil {
+		stats["content"] = content
+	}
+
+	if mongoURI != "" {
+		archiver, err := storage.NewMongoArchiver(mongoURI, cfg.Storage.MongoDB)
+		if err != nil {
+			logger.Warn("Failed to connect to MongoDB: %v", err)
+			return stats
+		}
+		defer archiver.Close(ctx)
+		if n, err := archiver.Count(ctx); err == nil {
+			stats["storedPages"] = n
+		}
+	}
+	return stats
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string (required)")
+	out := fs.String("out", "-", "Output file, - for stdout")
+	format := fs.String("format", "", "jsonl or csv, from the -out extension if empty")
+	fields := fs.String("fields", "", fmt.Sprintf("Comma-separated fields, all for jsonl and %s for csv if empty",
+		strings.Join(storage.DefaultCSVFields, ",")))
+	since := fs.String("since", "", "Only pages crawled at or after this time (RFC 3339 or YYYY-MM-DD)")
+	until := fs.String("until", "", "Only pages crawled before this time (RFC 3339 or YYYY-MM-DD)")
+	var domains stringList
+	fs.Var(&domains, "domain", "Only pages of this host and its subdomains, can be repeated")
+	fs.Parse(args)
+
+	query := storage.PageQuery{Domains: domains, Fields: splitFields(*fields)}
+	return exportPages(*configPath, *mongoURI, *out, *format, *since, *until, query)
+}
+
+func runChanges(args []string) error {
+	fs := flag.NewFlagSet("changes", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string (required)")
+	out := fs.String("out", "-", "Output file, - for stdout")
+	format := fs.String("format", "", "jsonl or csv, from the -out extension if empty")
+	fields := fs.String("fields", strings.Join(storage.ChangeFields, ","), "Comma-separated fields")
+	since := fs.String("since", "", "Only changes at or after this time (RFC 3339 or YYYY-MM-DD)")
+	until := fs.String("until", "", "Only changes before this time (RFC 3339 or YYYY-MM-DD)")
+	var domains stringList
+	fs.Var(&domains, "domain", "Only pages of this host and its subdomains, can be repeated")
+	fs.Parse(args)
+
+	query := storage.PageQuery{Domains: domains, Fields: splitFields(*fields), Changed: true}
+	return exportPages(*configPath, *mongoURI, *out, *format, *since, *until, query)
+}
+
+// splitFields parses a comma-separated field list
+func splitFields(list string) []string {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// exportPages writes the stored pages matching query, between since and
+// until, to out
+func exportPages(configPath, mongoURI, out, format, since, until string, query storage.PageQuery) error {
+	if mongoURI == "" {
+		return fmt.Errorf("-mongo is required")
+	}
+	if format == "" {
+		format = "jsonl"
+		if strings.HasSuffix(strings.ToLower(out), ".csv") {
+			format = "csv"
+		}
+	}
+	var err error
+	if query.Since, err = parseTime(since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if query.Until, err = parseTime(until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	exporter, err := storage.NewExporter(w, format, query.Fields)
+	if err != nil {
+		return err
+	}
+	// CSV reads its default fields only
+	query.Fields = exporter.Fields()
+
+	archiver, err := storage.NewMongoArchiver(mongoURI, cfg.Storage.MongoDB)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	defer archiver.Close(ctx)
+
+	if err := archiver.Each(ctx, query, exporter.Write); err != nil {
+		return err
+	}
+	if err := exporter.Close(); err != nil {
+		return err
+	}
+
+	if out != "-" {
+		logger.Success("Exported %d pages to %s", exporter.Count(), out)
+	}
+	return nil
+}
+
+// parseTime parses an RFC 3339 time or a date, which means midnight UTC. An
+// empty string is the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	dryRun := fs.Bool("dry-run", false, "Only report what would be deleted")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	retention := cfg.ContentSaver.Retention
+	if retention.MaxTotalSize == 0 && retention.MaxFilesPerDomain == 0 && retention.MaxAge == 0 {
+		return fmt.Errorf("content_saver.retention sets no limit")
+	}
+
+	saver := utils.NewContentSaver(cfg.ContentSaver.OutputDir, true, cfg.ContentSaver.MaxFileSize)
+	report, err := saver.Sweep(crawler.RetentionPolicy(retention), time.Now(), *dryRun)
+	if err != nil {
+		return err
+	}
+	return printJSON(report)
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	indexPath := fs.String("index", "", "Index directory, storage.search.path if empty")
+	limit := fs.Int("limit", 10, "Maximum number of results")
+	asJSON := fs.Bool("json", false, "Print results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawler search [flags] <query>")
+		fmt.Fprintln(fs.Output(), "Every term must match; restrict a term to a field with title:, url: or text:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fs.Usage()
+		return fmt.Errorf("a query is required")
+	}
+	path := *indexPath
+	if path == "" {
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		path = cfg.Storage.Search.Path
+	}
+
+	index, err := search.Load(path)
+	if err != nil {
+		return err
+	}
+	results, total := index.Search(query, *limit)
+	if *asJSON {
+		return printJSON(map[string]interface{}{"total": total, "results": results})
+	}
+
+	for i, r := range results {
+		fmt.Printf("%d. %s\n   %s (score %.2f)\n", i+1, r.Title, r.URL, r.Score)
+		if r.Snippet != "" {
+			fmt.Printf("   %s\n", r.Snippet)
+		}
+	}
+	fmt.Printf("%d of %d matches in %d pages\n", len(results), total, index.Len())
+	return nil
+}
+
+func runRequeue(args []string) error {
+	fs := flag.NewFlagSet("requeue", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	apiAddr := fs.String("api", "", "Control API of a running crawl; without it dead letters are added to the checkpoint")
+	fs.Parse(args)
+	urls := fs.Args()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if *apiAddr != "" {
+		var result map[string]int
+		if err := apiRequest(http.MethodPost, *apiAddr, "/dead-letters/requeue", map[string][]string{"urls": urls}, &result); err != nil {
+			return err
+		}
+		logger.Success("Requeued %d dead letters", result["requeued"])
+		return nil
+	}
+
+	// Without a running crawl the dead letters go into the checkpoint, so
+	// the next resume picks them up
+	if cfg.Queue.DeadLetter.Backend != "file" {
+		return fmt.Errorf("%s dead letters can only be requeued through -api", cfg.Queue.DeadLetter.Backend)
+	}
+	store, err := queue.NewDeadLetterStore(cfg.Queue.DeadLetter)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	cp, err := checkpoint.Load(cfg.Checkpoint.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	q := queue.NewURLQueue()
+	var visited []string
+	if cp != nil {
+		cp.Restore(ctx, q, nil)
+		visited = cp.Visited
+	}
+	n, err := queue.Requeue(ctx, store, q, urls...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := checkpoint.Capture(ctx, q, nil)
+	if err != nil {
+		return err
+	}
+	updated.Visited = visited
+	if err := updated.Save(cfg.Checkpoint.Path); err != nil {
+		return err
+	}
+	logger.Success("Added %d dead letters to %s", n, cfg.Checkpoint.Path)
+	return nil
+}
+
+func runHost(args []string) error {
+	fs := flag.NewFlagSet("host", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	apiAddr := fs.String("api", "", "Control API of the running crawl (default: api.addr)")
+	job := fs.String("job", "", "Crawl job of a crawler serve process")
+	pauseFor := fs.Duration("for", 0, "Resume the host on its own after this long (pause only)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawler host pause|resume <host> [flags]")
+		fmt.Fprintln(fs.Output(), "       crawler host list [flags]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	addr := *apiAddr
+	if addr == "" {
+		addr = cfg.API.Addr
+	}
+	prefix := ""
+	if *job != "" {
+		prefix = "/jobs/" + url.PathEscape(*job)
+	}
+
+	if action == "list" {
+		var result map[string]interface{}
+		if err := apiRequest(http.MethodGet, addr, prefix+"/hosts/paused", nil, &result); err != nil {
+			return err
+		}
+		return printJSON(result)
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("exactly one host is required")
+	}
+	host := fs.Arg(0)
+	path := prefix + "/hosts/" + url.PathEscape(host) + "/"
+	switch action {
+	case "pause":
+		var body interface{}
+		if *pauseFor > 0 {
+			body = map[string]string{"duration": pauseFor.String()}
+		}
+		var paused queue.PausedHost
+		if err := apiRequest(http.MethodPost, addr, path+"pause", body, &paused); err != nil {
+			return err
+		}
+		logger.Success("Paused %s, %d URLs held back", paused.Host, paused.Parked)
+	case "resume":
+		var result struct {
+			Requeued int `json:"requeued"`
+		}
+		if err := apiRequest(http.MethodPost, addr, path+"resume", nil, &result); err != nil {
+			return err
+		}
+		logger.Success("Resumed %s, requeued %d URLs", host, result.Requeued)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
+
+func runValidateConfig(args []string) error {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	fs.Parse(args)
+
+	path := *configPath
+	if fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	// Selectors and XPath expressions are only checked when compiled
+	if _, err := extract.NewRules(cfg.Extraction); err != nil {
+		return fmt.Errorf("%s: invalid extraction rule: %w", path, err)
+	}
+	for domain, p := range cfg.Domains {
+		if _, err := extract.NewRules(config.ExtractionConfig{Rules: p.Extraction}); err != nil {
+			return fmt.Errorf("%s: invalid extraction rule of domain %s: %w", path, domain, err)
+		}
+	}
+	if _, err := extract.NewJSONRules(cfg.JSON); err != nil {
+		return fmt.Errorf("%s: invalid json rule: %w", path, err)
+	}
+	for _, l := range cfg.HTTP.Login {
+		if l.Form == "" {
+			continue
+		}
+		if _, err := extract.CompileSelector(l.Form); err != nil {
+			return fmt.Errorf("%s: invalid login form of %s: %w", path, l.Domain, err)
+		}
+	}
+	for _, job := range cfg.Jobs {
+		if job.Schedule == "" {
+			continue
+		}
+		if _, err := jobs.ParseSchedule(job.Schedule); err != nil {
+			return fmt.Errorf("%s: job %s: %w", path, job.ID, err)
+		}
+	}
+	logger.Success("%s is valid", path)
+	return nil
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	addr := fs.String("addr", "", "Control API address (default: api.addr)")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string, pages are stored when set")
+	debugEndpoints := fs.Bool("debug", false, "Serve pprof and expvar at debug.addr")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *addr == "" {
+		*addr = cfg.API.Addr
+	}
+	if *debugEndpoints || cfg.Debug.Enabled {
+		// One server for the process, the jobs don't start their own
+		debugServer := debug.NewServer(cfg.Debug)
+		if err := debugServer.Start(); err != nil {
+			return err
+		}
+		defer debugServer.Shutdown(context.Background())
+	}
+
+	ctx, stop := checkpoint.NotifyContext(context.Background())
+	defer stop()
+
+	var mongo *storage.MongoArchiver
+	if cfg.JobHistory.Backend == "mongodb" {
+		if *mongoURI == "" {
+			return fmt.Errorf("mongodb job history requires -mongo")
+		}
+		if mongo, err = storage.NewMongoArchiver(*mongoURI, cfg.Storage.MongoDB); err != nil {
+			return err
+		}
+		defer mongo.Close(context.Background())
+	}
+	history, err := jobs.NewHistory(ctx, cfg.JobHistory, mongo)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	manager := jobs.NewManager(cfg, *mongoURI, history)
+	if err := manager.Load(cfg.Jobs); err != nil {
+		return err
+	}
+	go manager.Run(ctx)
+
+	server := api.NewServer(*addr, nil)
+	server.EnableJobs(manager)
+	server.Start()
+	<-ctx.Done()
+
+	logger.Warn("Stopping %d jobs", len(manager.List()))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Checkpoint.DrainTimeout+30*time.Second)
+	defer cancel()
+	err = manager.Shutdown(shutdownCtx)
+	if serr := server.Shutdown(shutdownCtx); serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+func runCompare(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	metric := fs.String("metric", "pages", fmt.Sprintf("Metric to overlay, one of %v", benchmark.CompareMetrics()))
+	out := fs.String("out", "benchmarks/compare.png", "Output PNG file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawler compare [flags] <metrics.json> <metrics.json> [...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("need at least two metrics.json files")
+	}
+
+	var runs []benchmark.Run
+	for _, path := range fs.Args() {
+		run, err := benchmark.LoadRun(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+	if err := benchmark.Compare(runs, *metric, *out); err != nil {
+		return err
+	}
+	logger.Success("Compared %d runs in %s", len(runs), *out)
+	return nil
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	out := fs.String("out", "benchmarks/report.html", "Output HTML file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawler report [flags] <metrics.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("need one metrics.json file")
+	}
+	run, err := benchmark.LoadRun(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := benchmark.WriteReport(run, *out); err != nil {
+		return err
+	}
+	logger.Success("Wrote the report of %s to %s", fs.Arg(0), *out)
+	return nil
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string, for the mongodb runs backend")
+	out := fs.String("out", "benchmarks/compare", "Directory for the graphs of compare")
+	threshold := fs.Float64("threshold", 0.05, "Relative change that counts as a regression in compare")
+	fail := fs.Bool("fail", false, "Exit with an error if compare finds a regression")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crawler bench list [flags]")
+		fmt.Fprintln(fs.Output(), "       crawler bench show [flags] <run>")
+		fmt.Fprintln(fs.Output(), "       crawler bench compare [flags] <base run> <run>")
+		fmt.Fprintln(fs.Output(), "Runs are given by ID, or a unique prefix of it, or as a path to a metrics.json file.")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("an action is required")
+	}
+	action := args[0]
+	fs.Parse(args[1:])
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	store, closeStore, err := openRunStore(cfg, *mongoURI)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	switch action {
+	case "list":
+		runs, err := store.List(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-*s  %-19s  %8s  %8s  %9s  %8s\n", benchmark.ShortIDLength, "RUN", "CREATED", "SECONDS", "PAGES", "PAGES/SEC", "P95 MS")
+		for _, run := range runs {
+			t := run.Totals
+			fmt.Printf("%-*s  %-19s  %8.0f  %8d  %9.1f  %8.0f\n", benchmark.ShortIDLength, benchmark.ShortID(run.ID),
+				run.Created.Local().Format("2006-01-02 15:04:05"), t.Seconds, t.Pages, t.PagesPerSec, t.LatencyP95)
+		}
+		return nil
+	case "show":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("exactly one run is required")
+		}
+		run, err := store.Load(ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Run %s, created %s\n\n", run.ID, run.Created.Local().Format(time.RFC1123))
+		if err := printJSON(run.Totals); err != nil {
+			return err
+		}
+		fmt.Printf("\n%s", run.Config)
+		return nil
+	case "compare":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("a base run and a run to compare with it are required")
+		}
+		var runs []benchmark.Run
+		for _, arg := range fs.Args() {
+			run, err := loadBenchRun(ctx, store, arg)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+		}
+		return benchCompare(runs[0], runs[1], *out, *threshold, *fail)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// openRunStore opens the benchmark run store of the config
+func openRunStore(cfg *config.Config, mongoURI string) (benchmark.RunStore, func(), error) {
+	runs := cfg.Benchmark.Runs
+	switch runs.Backend {
+	case "dir":
+		return benchmark.NewDirRunStore(runs.Dir), func() {}, nil
+	case "mongodb":
+		if mongoURI == "" {
+			return nil, nil, fmt.Errorf("-mongo is required for the mongodb runs backend")
+		}
+		archiver, err := storage.NewMongoArchiver(mongoURI, cfg.Storage.MongoDB)
+		if err != nil {
+			return nil, nil, err
+		}
+		return archiver.BenchmarkRuns(runs.Collection), func() { archiver.Close(context.Background()) }, nil
+	default:
+		return nil, nil, fmt.Errorf("benchmark runs are not kept, set benchmark.runs.backend")
+	}
+}
+
+// loadBenchRun loads a stored run by ID, or the metrics.json file at arg
+func loadBenchRun(ctx context.Context, store benchmark.RunStore, arg string) (benchmark.Run, error) {
+	if info, err := os.Stat(arg); err == nil && !info.IsDir() {
+		return benchmark.LoadRun(arg)
+	}
+	stored, err := store.Load(ctx, arg)
+	if err != nil {
+		return benchmark.Run{}, err
+	}
+	run := stored.Run
+	run.Name = benchmark.ShortID(stored.ID)
+	return run, nil
+}
+
+// benchCompare prints how run changed from base and overlays their metrics
+// in one graph per metric
+func benchCompare(base, run benchmark.Run, out string, threshold float64, fail bool) error {
+	if base.Name == run.Name {
+		base.Name, run.Name = "base "+base.Name, "new "+run.Name
+	}
+	for _, metric := range benchmark.CompareMetrics() {
+		path := filepath.Join(out, metric+".png")
+		if err := benchmark.Compare([]benchmark.Run{base, run}, metric, path); err != nil {
+			logger.Warn("No %s graph: %v", metric, err)
+		}
+	}
+
+	fmt.Printf("%s compared with %s (regression threshold %.0f%%)\n\n", run.Name, base.Name, threshold*100)
+	fmt.Printf("%-16s %12s %12s %9s\n", "METRIC", "BASE", "NEW", "CHANGE")
+	regressions := 0
+	for _, c := range benchmark.CompareRuns(base, run, threshold) {
+		change := "-"
+		if c.Base != 0 {
+			change = fmt.Sprintf("%+.1f%%", c.Delta*100)
+		}
+		mark := ""
+		if c.Regressed {
+			mark = "  " + logger.Red + "REGRESSION" + logger.Reset
+			regressions++
+		}
+		fmt.Printf("%-16s %12.2f %12.2f %9s%s\n", c.Metric, c.Base, c.Head, change, mark)
+	}
+	fmt.Printf("\nGraphs written to %s\n", out)
+
+	if regressions > 0 && fail {
+		return fmt.Errorf("%d metrics regressed", regressions)
+	}
+	return nil
+}
+
+// apiRequest calls the control API of a running crawl and decodes the JSON answer into out
+func apiRequest(method, addr, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, "http://"+addr+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach control api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("control api returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode api response: %w", err)
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
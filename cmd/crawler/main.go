@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"web-crawler/internal/checkpoint"
+	"web-crawler/internal/config"
+	"web-crawler/internal/crawler"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/seeds"
+)
+
+// command is a crawler subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"crawl", "Run a crawl from seed URLs", runCrawl},
+	{"resume", "Continue a crawl from a checkpoint", runResume},
+	{"serve", "Run named crawl jobs managed through the control API", runServe},
+	{"stats", "Print queue and storage statistics", runStats},
+	{"export", "Dump stored pages as JSON lines", runExport},
+	{"changes", "List pages whose content changed between two dates", runChanges},
+	{"search", "Query the full-text index of stored pages", runSearch},
+	{"prune", "Apply the retention policy to saved content", runPrune},
+	{"requeue", "Move dead letters back into the queue", runRequeue},
+	{"host", "Pause, resume or list paused hosts of a running crawl", runHost},
+	{"validate-config", "Check a configuration file", runValidateConfig},
+	{"compare", "Overlay the benchmark metrics of several runs", runCompare},
+	{"report", "Write the interactive HTML report of a run's benchmark metrics", runReport},
+	{"bench", "List, show and compare saved benchmark runs", runBench},
+}
+
+func main() {
+	args := os.Args[1:]
+
+	// Plain flags without a subcommand run a crawl, as before subcommands existed
+	name := "crawl"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(args)
+			if err != nil {
+				logger.Error("%s: %v", name, err)
+			}
+			logger.Close()
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// usage prints the list of subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: crawler <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'crawler <command> -h' for the flags of a command.")
+}
+
+// stringList is a flag that can be given several times
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// loadConfig reads the config file, or returns the defaults when path is
+// empty, and sets up logging from it
+func loadConfig(path string) (*config.Config, error) {
+	cfg := config.DefaultConfig()
+	if path != "" {
+		var err error
+		if cfg, err = config.LoadConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := configureLogging(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configureLogging sets up the logger from the config. While the dashboard
+// owns the terminal, logs go to a file instead of stdout.
+func configureLogging(cfg *config.Config) error {
+	file := cfg.Logging.File
+	if file == "" && cfg.Dashboard.Enabled {
+		file = cfg.Dashboard.LogFile
+	}
+	if file != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+
+	err := logger.Configure(logger.Options{
+		Format:     cfg.Logging.Format,
+		Level:      cfg.Logging.Level,
+		File:       file,
+		MaxSize:    cfg.Logging.MaxSize,
+		MaxBackups: cfg.Logging.MaxBackups,
+		Modules:    cfg.Logging.Modules,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up logging: %w", err)
+	}
+	return nil
+}
+
+// enableDashboard turns on the dashboard requested by a flag
+func enableDashboard(cfg *config.Config) error {
+	if cfg.Dashboard.Enabled {
+		return nil
+	}
+	cfg.Dashboard.Enabled = true
+	return configureLogging(cfg)
+}
+
+func runCrawl(args []string) error {
+	fs := flag.NewFlagSet("crawl", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	var seedURLs stringList
+	fs.Var(&seedURLs, "seed", "Seed URL, can be repeated")
+	seedFile := fs.String("seeds", "", "File with one seed per line, or - for stdin")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string, pages are stored when set")
+	resume := fs.Bool("resume", false, "Replay the persistent queue log before crawling")
+	resumeFrom := checkpoint.ResumeFrom(fs)
+	dash := fs.Bool("dashboard", false, "Show the live terminal dashboard")
+	debugEndpoints := fs.Bool("debug", false, "Serve pprof and expvar at debug.addr")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *dash {
+		if err := enableDashboard(cfg); err != nil {
+			return err
+		}
+	}
+	if *debugEndpoints {
+		cfg.Debug.Enabled = true
+	}
+
+	opts := crawler.Options{MongoURI: *mongoURI, Resume: *resume, ConfigPath: *configPath}
+	if opts.Checkpoint, err = resumeFrom(); err != nil {
+		return err
+	}
+
+	list, err := collectSeeds(cfg, seedURLs, *seedFile)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 && opts.Checkpoint == nil && !opts.Resume {
+		return fmt.Errorf("no seed URLs given, use -seed, -seeds or crawler.seeds in the config")
+	}
+
+	return crawl(cfg, opts, list)
+}
+
+func runResume(args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	configPath := fs.String("config", "configs/default.yaml", "Path to the configuration file")
+	from := fs.String("from", "", "Checkpoint file (default: checkpoint.path from the config)")
+	mongoURI := fs.String("mongo", "", "MongoDB connection string, pages are stored when set")
+	dash := fs.Bool("dashboard", false, "Show the live terminal dashboard")
+	debugEndpoints := fs.Bool("debug", false, "Serve pprof and expvar at debug.addr")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *dash {
+		if err := enableDashboard(cfg); err != nil {
+			return err
+		}
+	}
+	if *debugEndpoints {
+		cfg.Debug.Enabled = true
+	}
+
+	path := *from
+	if path == "" {
+		path = cfg.Checkpoint.Path
+	}
+	cp, err := checkpoint.Load(path)
+	if err != nil {
+		return err
+	}
+
+	return crawl(cfg, crawler.Options{MongoURI: *mongoURI, Checkpoint: cp, ConfigPath: *configPath}, nil)
+}
+
+// collectSeeds gathers seeds from the flags, the seed file, and the config
+func collectSeeds(cfg *config.Config, urls []string, seedFile string) ([]seeds.Seed, error) {
+	var list []seeds.Seed
+	for _, u := range append(cfg.Crawler.Seeds, urls...) {
+		if err := seeds.Validate(u); err != nil {
+			return nil, err
+		}
+		list = append(list, seeds.Seed{URL: u, Priority: queue.PriorityHigh})
+	}
+
+	if seedFile == "" {
+		seedFile = cfg.Crawler.SeedFile
+	}
+	if seedFile != "" {
+		result, err := seeds.Load(seedFile)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, result.Seeds...)
+	}
+	return list, nil
+}
+
+// crawl runs a crawl until it finishes or SIGINT/SIGTERM is received
+func crawl(cfg *config.Config, opts crawler.Options, list []seeds.Seed) error {
+	c, err := crawler.New(cfg, opts)
+	if err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		logger.Info("Queued %d seed URLs", c.AddSeedList(list))
+	}
+
+	ctx, stop := checkpoint.NotifyContext(context.Background())
+	defer stop()
+
+	if err := c.Run(ctx); err != nil {
+		return err
+	}
+	fmt.Print(c.Summary().Render())
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := map[string]time.Time{
+		"":                          {},
+		"2024-03-01":                time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-01T10:30:00Z":      time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01T10:30:00+02:00": time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := parseTime(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseTime("yesterday"); err == nil {
+		t.Error("parseTime(yesterday) succeeded")
+	}
+}
+
+func TestCollectSeeds(t *testing.T) {
+	seedFile := filepath.Join(t.TempDir(), "seeds.txt")
+	if err := os.WriteFile(seedFile, []byte("https://c.com/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Crawler.Seeds = []string{"https://a.com/"}
+
+	list, err := collectSeeds(cfg, []string{"https://b.com/"}, seedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, seed := range list {
+		urls = append(urls, seed.URL)
+	}
+	if got := strings.Join(urls, " "); got != "https://a.com/ https://b.com/ https://c.com/" {
+		t.Fatalf("collectSeeds() = %s", got)
+	}
+
+	if _, err := collectSeeds(cfg, []string{"not a url"}, ""); err == nil {
+		t.Fatal("collectSeeds() accepted an invalid URL")
+	}
+}
+
+func TestAPIRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]string{"method": r.Method, "host": body["host"], "type": r.Header.Get("Content-Type")})
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	var out map[string]string
+	if err := apiRequest("POST", addr, "/hosts/pause", map[string]string{"host": "a.com"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["method"] != "POST" || out["host"] != "a.com" || out["type"] != "application/json" {
+		t.Fatalf("apiRequest() sent %v", out)
+	}
+
+	err := apiRequest("GET", addr, "/missing", nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("apiRequest() = %v, want the API error", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]bool{
+		"crawler:\n  workers: 4\n":  true,
+		"crawler:\n  workers: -1\n": false,
+		"jobs:\n  - id: news\n    seeds: [\"https://news.com/\"]\n    schedule: \"61 * * * *\"\n": false,
+	}
+	i := 0
+	for yaml, valid := range tests {
+		i++
+		path := filepath.Join(dir, string(rune('a'+i))+".yaml")
+		if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := runValidateConfig([]string{path}); (err == nil) != valid {
+			t.Errorf("validate-config of %q = %v, want valid %v", yaml, err, valid)
+		}
+	}
+}
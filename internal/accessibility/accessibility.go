@@ -0,0 +1,205 @@
+// Package accessibility counts basic accessibility problems on pages:
+// images without alt text, unlabeled form fields and skipped heading levels
+package accessibility
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/storage"
+
+	"golang.org/x/net/html"
+)
+
+var log = logger.For("accessibility")
+
+// Analyze counts the accessibility problems of HTML content
+func Analyze(content string) *storage.Accessibility {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return &storage.Accessibility{}
+	}
+
+	// Labels can come before or after their field, so collect them first
+	labelled := make(map[string]bool)
+	walk(doc, func(n *html.Node, inLabel bool) {
+		if n.Data == "label" {
+			if id := attr(n, "for"); id != "" {
+				labelled[id] = true
+			}
+		}
+	})
+
+	a := &storage.Accessibility{}
+	lastLevel := 0
+	walk(doc, func(n *html.Node, inLabel bool) {
+		switch n.Data {
+		case "img":
+			if hidden(n) {
+				return
+			}
+			a.Images++
+			if _, ok := attrOK(n, "alt"); !ok {
+				a.ImagesNoAlt++
+			}
+		case "input", "select", "textarea":
+			if !isField(n) || hidden(n) {
+				return
+			}
+			a.FormFields++
+			if !inLabel && !labelled[attr(n, "id")] && !named(n) {
+				a.UnlabeledFields++
+			}
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			level := int(n.Data[1] - '0')
+			a.Headings++
+			if lastLevel > 0 && level > lastLevel+1 {
+				a.SkippedHeadings++
+			}
+			lastLevel = level
+		}
+	})
+	return a
+}
+
+// walk calls f on every element in document order, telling whether it is
+// inside a label
+func walk(n *html.Node, f func(n *html.Node, inLabel bool)) {
+	var visit func(n *html.Node, inLabel bool)
+	visit = func(n *html.Node, inLabel bool) {
+		if n.Type == html.ElementNode {
+			f(n, inLabel)
+			inLabel = inLabel || n.Data == "label"
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c, inLabel)
+		}
+	}
+	visit(n, false)
+}
+
+// isField reports whether an input takes a value from the user. Buttons are
+// named by their value and hidden inputs are never shown.
+func isField(n *html.Node) bool {
+	if n.Data != "input" {
+		return true
+	}
+	switch strings.ToLower(attr(n, "type")) {
+	case "hidden", "submit", "reset", "button", "image":
+		return false
+	}
+	return true
+}
+
+// hidden reports whether an element is hidden from assistive technology
+func hidden(n *html.Node) bool {
+	if strings.EqualFold(attr(n, "aria-hidden"), "true") {
+		return true
+	}
+	switch strings.ToLower(attr(n, "role")) {
+	case "presentation", "none":
+		return true
+	}
+	return false
+}
+
+// named reports whether an element has an accessible name from ARIA or its title
+func named(n *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(attr(n, key)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// attr returns the value of an attribute, empty if absent
+func attr(n *html.Node, key string) string {
+	v, _ := attrOK(n, key)
+	return v
+}
+
+// attrOK returns the value of an attribute and whether it is present
+func attrOK(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// pageCounts is the accessibility of a page with problems
+type pageCounts struct {
+	url    string
+	counts storage.Accessibility
+}
+
+// Collector aggregates the accessibility of crawled pages by host
+type Collector struct {
+	cfg config.AccessibilityConfig
+
+	mu    sync.Mutex
+	hosts map[string]*Host
+	pages []pageCounts // Pages with problems, up to max_pages
+
+	// Counters
+	dropped int64 // Pages with problems not listed because max_pages was reached
+}
+
+// New creates a collector. It returns nil if accessibility signals are disabled.
+func New(cfg config.AccessibilityConfig) *Collector {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Collector{cfg: cfg, hosts: make(map[string]*Host)}
+}
+
+// Observe adds the accessibility of a page to the report
+func (c *Collector) Observe(rawURL string, a *storage.Accessibility) {
+	if c == nil || a == nil {
+		return
+	}
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.hosts[host]
+	if !ok {
+		h = &Host{Host: host}
+		c.hosts[host] = h
+	}
+	h.add(a)
+	if a.Issues() == 0 {
+		return
+	}
+	if c.cfg.MaxPages > 0 && len(c.pages) >= c.cfg.MaxPages {
+		atomic.AddInt64(&c.dropped, 1)
+		return
+	}
+	c.pages = append(c.pages, pageCounts{rawURL, *a})
+}
+
+// GetStats returns the pages analyzed and the problems found
+func (c *Collector) GetStats() map[string]int64 {
+	c.mu.Lock()
+	var total Host
+	for _, h := range c.hosts {
+		total.merge(h)
+	}
+	c.mu.Unlock()
+	return map[string]int64{
+		"pages":           int64(total.Pages),
+		"pagesWithIssues": int64(total.PagesWithIssues),
+		"issues":          int64(total.Counts.Issues()),
+		"dropped":         atomic.LoadInt64(&c.dropped),
+	}
+}
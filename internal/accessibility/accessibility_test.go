@@ -0,0 +1,113 @@
+package accessibility
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/storage"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    storage.Accessibility
+	}{
+		{
+			name:    "images",
+			content: `<img src="a.png" alt="A cat"><img src="spacer.gif" alt=""><img src="b.png"><img src="c.png" aria-hidden="true"><img src="d.png" role="presentation">`,
+			want:    storage.Accessibility{Images: 3, ImagesNoAlt: 1},
+		},
+		{
+			name: "form fields",
+			content: `<form>
+				<label for="name">Name</label><input id="name">
+				<label>Email <input type="email"></label>
+				<input type="search" aria-label="Search">
+				<input type="text" title="Phone">
+				<input type="text" id="city">
+				<select><option>1</option></select>
+				<textarea id="notes"></textarea><label for="notes">Notes</label>
+				<input type="hidden" name="token"><input type="submit" value="Send"><input type="checkbox" aria-hidden="true">
+			</form>`,
+			want: storage.Accessibility{FormFields: 7, UnlabeledFields: 2},
+		},
+		{
+			name:    "headings",
+			content: `<h1>Title</h1><h2>Section</h2><h4>Skipped</h4><h2>Back up</h2><h3>Fine</h3><h6>Skipped</h6>`,
+			want:    storage.Accessibility{Headings: 6, SkippedHeadings: 2},
+		},
+		{
+			name:    "heading start",
+			content: `<h3>Starts low</h3><h4>Fine</h4>`,
+			want:    storage.Accessibility{Headings: 2},
+		},
+	}
+	for _, tt := range tests {
+		if got := Analyze(tt.content); *got != tt.want {
+			t.Errorf("%s: Analyze() = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestCollectorReport(t *testing.T) {
+	if New(config.AccessibilityConfig{}) != nil {
+		t.Fatal("New() returned a collector while disabled")
+	}
+	var disabled *Collector
+	disabled.Observe("https://example.com/", &storage.Accessibility{})
+	if disabled.Report() != nil || disabled.Export() != nil {
+		t.Fatal("nil collector reported pages")
+	}
+
+	dir := filepath.Join(t.TempDir(), "a11y")
+	c := New(config.AccessibilityConfig{Enabled: true, OutputDir: dir, Formats: []string{"json", "csv"}, MaxPages: 2})
+	c.Observe("https://example.com/", &storage.Accessibility{Images: 2, ImagesNoAlt: 1})
+	c.Observe("https://example.com/clean", &storage.Accessibility{Images: 1, Headings: 2})
+	c.Observe("https://example.com/form", &storage.Accessibility{FormFields: 3, UnlabeledFields: 2, Headings: 3, SkippedHeadings: 1})
+	c.Observe("https://other.com/", &storage.Accessibility{Images: 1, ImagesNoAlt: 1})
+
+	report := c.Report()
+	total := Host{Pages: 4, PagesWithIssues: 3, Counts: storage.Accessibility{Images: 4, ImagesNoAlt: 2, FormFields: 3, UnlabeledFields: 2, Headings: 5, SkippedHeadings: 1}}
+	if report.Total != total {
+		t.Errorf("total = %+v, want %+v", report.Total, total)
+	}
+	if len(report.Hosts) != 2 || report.Hosts[0].Host != "example.com" || report.Hosts[0].PagesWithIssues != 2 {
+		t.Errorf("hosts = %+v", report.Hosts)
+	}
+	// The third page with problems is past max_pages
+	if len(report.Pages) != 2 || report.Pages[0].URL != "https://example.com/form" || report.Pages[0].Issues != 3 {
+		t.Errorf("pages = %+v", report.Pages)
+	}
+	if stats := c.GetStats(); stats["pages"] != 4 || stats["issues"] != 5 || stats["dropped"] != 1 {
+		t.Errorf("stats = %v", stats)
+	}
+
+	if err := c.Export(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "accessibility.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported Report
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	if exported.Total != total {
+		t.Errorf("exported total = %+v", exported.Total)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "accessibility-pages.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "url,issues,images,images_no_alt,form_fields,unlabeled_fields,headings,skipped_headings\n" +
+		"https://example.com/form,3,0,0,3,2,3,1\n" +
+		"https://example.com/,1,2,1,0,0,0,0\n"
+	if string(data) != want {
+		t.Errorf("CSV = %q, want %q", data, want)
+	}
+}
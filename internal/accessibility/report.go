@@ -0,0 +1,157 @@
+package accessibility
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"web-crawler/internal/storage"
+)
+
+// Host is the accessibility of the pages of a host, summed
+type Host struct {
+	Host            string                `json:"host"`
+	Pages           int                   `json:"pages"`
+	PagesWithIssues int                   `json:"pages_with_issues"`
+	Counts          storage.Accessibility `json:"counts"`
+}
+
+// add counts a page
+func (h *Host) add(a *storage.Accessibility) {
+	h.Pages++
+	if a.Issues() > 0 {
+		h.PagesWithIssues++
+	}
+	sum(&h.Counts, a)
+}
+
+// merge adds the pages of another host
+func (h *Host) merge(o *Host) {
+	h.Pages += o.Pages
+	h.PagesWithIssues += o.PagesWithIssues
+	sum(&h.Counts, &o.Counts)
+}
+
+// sum adds the counts of a to dst
+func sum(dst, a *storage.Accessibility) {
+	dst.Images += a.Images
+	dst.ImagesNoAlt += a.ImagesNoAlt
+	dst.FormFields += a.FormFields
+	dst.UnlabeledFields += a.UnlabeledFields
+	dst.Headings += a.Headings
+	dst.SkippedHeadings += a.SkippedHeadings
+}
+
+// Page is the accessibility of a page with problems
+type Page struct {
+	URL    string                `json:"url"`
+	Issues int                   `json:"issues"`
+	Counts storage.Accessibility `json:"counts"`
+}
+
+// Report is the accessibility of all pages
+type Report struct {
+	Total Host   `json:"total"`
+	Hosts []Host `json:"hosts"` // Sorted by host
+	Pages []Page `json:"pages"` // Pages with problems, most first
+}
+
+// Report sums the pages by host and lists the pages with problems
+func (c *Collector) Report() *Report {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	report := &Report{Hosts: make([]Host, 0, len(c.hosts)), Pages: make([]Page, 0, len(c.pages))}
+	for _, h := range c.hosts {
+		report.Hosts = append(report.Hosts, *h)
+		report.Total.merge(h)
+	}
+	for _, p := range c.pages {
+		report.Pages = append(report.Pages, Page{URL: p.url, Issues: p.counts.Issues(), Counts: p.counts})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(report.Hosts, func(i, j int) bool { return report.Hosts[i].Host < report.Hosts[j].Host })
+	sort.SliceStable(report.Pages, func(i, j int) bool {
+		if report.Pages[i].Issues != report.Pages[j].Issues {
+			return report.Pages[i].Issues > report.Pages[j].Issues
+		}
+		return report.Pages[i].URL < report.Pages[j].URL
+	})
+	return report
+}
+
+// Export writes the report in the configured formats to the output
+// directory, as accessibility.json and accessibility-pages.csv
+func (c *Collector) Export() error {
+	if c == nil {
+		return nil
+	}
+	dir := c.cfg.OutputDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create accessibility report directory: %w", err)
+	}
+	report := c.Report()
+	for _, format := range c.cfg.Formats {
+		var err error
+		switch format {
+		case "json":
+			err = writeFile(filepath.Join(dir, "accessibility.json"), func(w *bufio.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			})
+		case "csv":
+			err = writeFile(filepath.Join(dir, "accessibility-pages.csv"), func(w *bufio.Writer) error { return writeCSV(w, report) })
+		}
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Exported accessibility of %d pages, %d with problems, to %s", report.Total.Pages, report.Total.PagesWithIssues, dir)
+	return nil
+}
+
+// writeFile creates path and fills it through a buffered writer
+func writeFile(path string, write func(*bufio.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeCSV writes one row per page with problems
+func writeCSV(w *bufio.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "issues", "images", "images_no_alt", "form_fields", "unlabeled_fields", "headings", "skipped_headings"})
+	for _, p := range report.Pages {
+		cw.Write([]string{
+			p.URL,
+			strconv.Itoa(p.Issues),
+			strconv.Itoa(p.Counts.Images),
+			strconv.Itoa(p.Counts.ImagesNoAlt),
+			strconv.Itoa(p.Counts.FormFields),
+			strconv.Itoa(p.Counts.UnlabeledFields),
+			strconv.Itoa(p.Counts.Headings),
+			strconv.Itoa(p.Counts.SkippedHeadings),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
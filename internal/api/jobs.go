@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Errors returned by a JobManager
+var (
+	ErrJobExists   = errors.New("job already exists")
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// JobRun is one run of a job as recorded in its history
+type JobRun struct {
+	Job          string    `json:"job" bson:"job"`
+	Trigger      string    `json:"trigger" bson:"trigger"` // api, config or schedule
+	State        string    `json:"state" bson:"state"`     // finished, failed or skipped
+	Started      time.Time `json:"started" bson:"started"`
+	Finished     time.Time `json:"finished" bson:"finished"`
+	PagesCrawled int64     `json:"pages_crawled" bson:"pages_crawled"`
+	PagesStored  int64     `json:"pages_stored" bson:"pages_stored"`
+	Errors       int64     `json:"errors" bson:"errors"`
+	Error        string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// JobManager runs named crawl jobs side by side in one process
+type JobManager interface {
+	// Create builds and starts a job. It returns ErrJobExists if a job with
+	// the same ID is still running.
+	Create(job config.JobConfig) error
+	// Job returns the crawl of a job
+	Job(id string) (Controller, bool)
+	// Status returns the state and statistics of a job
+	Status(id string) (map[string]interface{}, bool)
+	// List returns the status of every job
+	List() []map[string]interface{}
+	// Remove stops a job if it is running and forgets it
+	Remove(ctx context.Context, id string) error
+	// History returns the last limit runs of a job, newest first
+	History(ctx context.Context, id string, limit int) ([]JobRun, error)
+}
+
+// EnableJobs serves the jobs of m under /jobs. Every job has the control
+// endpoints of a crawl under /jobs/{id}, e.g. /jobs/{id}/stats.
+func (s *Server) EnableJobs(m JobManager) {
+	s.jobs = m
+	s.mux.HandleFunc("GET /jobs", s.handleListJobs)
+	s.mux.HandleFunc("POST /jobs", s.handleCreateJob)
+	s.mux.HandleFunc("GET /jobs/{id}", s.handleJobStatus)
+	s.mux.HandleFunc("DELETE /jobs/{id}", s.handleRemoveJob)
+	s.mux.HandleFunc("GET /jobs/{id}/history", s.handleJobHistory)
+	s.handleRoutes("/jobs/{id}")
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := s.jobs.List()
+	if jobs == nil {
+		jobs = []map[string]interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(jobs), "jobs": jobs})
+}
+
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	var job config.JobConfig
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	err := s.jobs.Create(job)
+	var verr *config.ValidationError
+	switch {
+	case errors.Is(err, ErrJobExists):
+		writeError(w, http.StatusConflict, "%v", err)
+		return
+	case errors.As(err, &verr):
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	log.WithJob(job.ID).Info("API: job started with %d seeds", len(job.Seeds))
+	status, _ := s.jobs.Status(job.ID)
+	writeJSON(w, http.StatusCreated, status)
+}
+
+func (s *Server) handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	status, ok := s.jobs.Status(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %q not found", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleRemoveJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.jobs.Remove(r.Context(), id); err != nil {
+		if errors.Is(err, ErrJobNotFound) {
+			writeError(w, http.StatusNotFound, "job %q not found", id)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	log.WithJob(id).Warn("API: job removed")
+	writeJSON(w, http.StatusOK, map[string]string{"removed": id})
+}
+
+func (s *Server) handleJobHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit %q", v)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := s.jobs.History(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if runs == nil {
+		runs = []JobRun{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(runs), "runs": runs})
+}
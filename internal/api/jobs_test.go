@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+// fakeJobs is a JobManager holding jobs in a map
+type fakeJobs struct {
+	jobs    map[string]*fakeCrawl
+	limit   int
+	failing bool
+}
+
+func newFakeJobs() *fakeJobs {
+	return &fakeJobs{jobs: make(map[string]*fakeCrawl)}
+}
+
+func (f *fakeJobs) Create(job config.JobConfig) error {
+	if f.failing {
+		return errors.New("storage unavailable")
+	}
+	if job.ID == "" {
+		return &config.ValidationError{Errors: []config.FieldError{{Path: "job.id", Message: "must not be empty"}}}
+	}
+	if _, ok := f.jobs[job.ID]; ok {
+		return ErrJobExists
+	}
+	f.jobs[job.ID] = newFakeCrawl()
+	return nil
+}
+func (f *fakeJobs) Job(id string) (Controller, bool) {
+	crawl, ok := f.jobs[id]
+	return crawl, ok
+}
+func (f *fakeJobs) Status(id string) (map[string]interface{}, bool) {
+	if _, ok := f.jobs[id]; !ok {
+		return nil, false
+	}
+	return map[string]interface{}{"id": id, "state": "running"}, true
+}
+func (f *fakeJobs) List() []map[string]interface{} {
+	var list []map[string]interface{}
+	for id := range f.jobs {
+		status, _ := f.Status(id)
+		list = append(list, status)
+	}
+	return list
+}
+func (f *fakeJobs) Remove(ctx context.Context, id string) error {
+	if _, ok := f.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	delete(f.jobs, id)
+	return nil
+}
+func (f *fakeJobs) History(ctx context.Context, id string, limit int) ([]JobRun, error) {
+	f.limit = limit
+	if _, ok := f.jobs[id]; !ok {
+		return nil, nil
+	}
+	return []JobRun{{Job: id, Trigger: "api", State: "finished", PagesCrawled: 3}}, nil
+}
+
+func newJobsServer() (*Server, *fakeJobs) {
+	s := NewServer("", newFakeCrawl())
+	jobs := newFakeJobs()
+	s.EnableJobs(jobs)
+	return s, jobs
+}
+
+func TestCreateJob(t *testing.T) {
+	s, jobs := newJobsServer()
+
+	body := `{"id": "news", "seeds": ["https://news.com/"]}`
+	if code, resp := call(t, s, "POST", "/jobs", body); code != http.StatusCreated || resp["id"] != "news" {
+		t.Fatalf("POST /jobs = %d %v", code, resp)
+	}
+	tests := []struct {
+		body string
+		code int
+	}{
+		{body, http.StatusConflict},
+		{`{"seeds": ["https://news.com/"]}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, resp := call(t, s, "POST", "/jobs", tt.body); code != tt.code || resp["error"] == nil {
+			t.Errorf("POST /jobs %s = %d %v, want %d", tt.body, code, resp, tt.code)
+		}
+	}
+
+	jobs.failing = true
+	if code, _ := call(t, s, "POST", "/jobs", `{"id": "other"}`); code != http.StatusInternalServerError {
+		t.Errorf("POST /jobs with a failing manager = %d, want 500", code)
+	}
+}
+
+func TestListAndRemoveJobs(t *testing.T) {
+	s, jobs := newJobsServer()
+
+	if _, resp := call(t, s, "GET", "/jobs", ""); resp["count"] != 0.0 || resp["jobs"] == nil {
+		t.Fatalf("GET /jobs without jobs = %v", resp)
+	}
+	jobs.Create(config.JobConfig{ID: "news"})
+	if _, resp := call(t, s, "GET", "/jobs", ""); resp["count"] != 1.0 {
+		t.Fatalf("GET /jobs = %v", resp)
+	}
+	if code, resp := call(t, s, "GET", "/jobs/news", ""); code != http.StatusOK || resp["state"] != "running" {
+		t.Fatalf("GET /jobs/news = %d %v", code, resp)
+	}
+
+	if code, resp := call(t, s, "DELETE", "/jobs/news", ""); code != http.StatusOK || resp["removed"] != "news" {
+		t.Fatalf("DELETE /jobs/news = %d %v", code, resp)
+	}
+	for _, method := range []string{"GET", "DELETE"} {
+		if code, _ := call(t, s, method, "/jobs/news", ""); code != http.StatusNotFound {
+			t.Errorf("%s /jobs/news of a removed job = %d, want 404", method, code)
+		}
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	s, jobs := newJobsServer()
+	jobs.Create(config.JobConfig{ID: "news"})
+
+	code, resp := call(t, s, "GET", "/jobs/news/history?limit=5", "")
+	runs, _ := resp["runs"].([]interface{})
+	if code != http.StatusOK || len(runs) != 1 || jobs.limit != 5 {
+		t.Fatalf("GET /jobs/news/history = %d %v, limit %d", code, resp, jobs.limit)
+	}
+	if run := runs[0].(map[string]interface{}); run["state"] != "finished" || run["pages_crawled"] != 3.0 {
+		t.Errorf("run = %v", run)
+	}
+
+	if call(t, s, "GET", "/jobs/news/history", ""); jobs.limit != 20 {
+		t.Errorf("default limit = %d, want 20", jobs.limit)
+	}
+	// Jobs without runs have an empty history rather than null
+	if _, resp := call(t, s, "GET", "/jobs/other/history", ""); resp["count"] != 0.0 || resp["runs"] == nil {
+		t.Errorf("history of an unknown job = %v", resp)
+	}
+	for _, limit := range []string{"0", "-1", "ten"} {
+		if code, _ := call(t, s, "GET", "/jobs/news/history?limit="+limit, ""); code != http.StatusBadRequest {
+			t.Errorf("limit %s = %d, want 400", limit, code)
+		}
+	}
+}
+
+func TestJobControlRoutes(t *testing.T) {
+	s, jobs := newJobsServer()
+	jobs.Create(config.JobConfig{ID: "news"})
+
+	if code, _ := call(t, s, "POST", "/jobs/news/pause", ""); code != http.StatusOK || !jobs.jobs["news"].paused {
+		t.Fatalf("POST /jobs/news/pause = %d, paused %v", code, jobs.jobs["news"].paused)
+	}
+	if s.ctrl.(*fakeCrawl).paused {
+		t.Fatal("pausing a job paused the main crawl")
+	}
+	if code, resp := call(t, s, "POST", "/jobs/news/seeds", `{"urls": ["https://news.com/a"]}`); code != http.StatusAccepted || len(jobs.jobs["news"].seeds) != 1 {
+		t.Fatalf("POST /jobs/news/seeds = %d %v", code, resp)
+	}
+	if code, _ := call(t, s, "GET", "/jobs/missing/stats", ""); code != http.StatusNotFound {
+		t.Errorf("GET /jobs/missing/stats = %d, want 404", code)
+	}
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/subdomain"
+)
+
+// log is the logger of the api package
+var log = logger.For("api")
+
+// Controller is implemented by the crawler to expose runtime control
+type Controller interface {
+	// AddSeeds queues new seed URLs and returns how many were accepted
+	AddSeeds(urls []string) (int, error)
+	Pause()
+	Resume()
+	Paused() bool
+	// PauseHost holds back the URLs of host, for d or until ResumeHost if d is 0
+	PauseHost(host string, d time.Duration) queue.PausedHost
+	// ResumeHost queues the held back URLs of host again. It returns false if
+	// the host wasn't paused.
+	ResumeHost(host string) (int, bool)
+	// PausedHosts lists the paused hosts
+	PausedHosts() []queue.PausedHost
+	SetRateLimit(d time.Duration)
+	RateLimit() time.Duration
+	// Stats returns queue statistics and crawl progress
+	Stats() map[string]interface{}
+	// Shutdown stops the crawl gracefully
+	Shutdown(ctx context.Context) error
+	// DeadLetters lists URLs that failed after all retries
+	DeadLetters(ctx context.Context) ([]queue.DeadLetter, error)
+	// RequeueDeadLetters queues the given dead letters again, or all of them if urls is empty
+	RequeueDeadLetters(ctx context.Context, urls []string) (int, error)
+	// Subdomains lists the hosts links pointed to by registrable domain, nil
+	// when subdomain tracking is disabled
+	Subdomains() []subdomain.Domain
+	// QueueSnapshot describes the queued URLs with up to top per priority
+	// band and the top busiest hosts, false if the queue can't be inspected
+	QueueSnapshot(top int) (queue.Snapshot, bool)
+	// QueueItems calls fn with every queued URL until fn returns false. It
+	// returns false if the queue can't be inspected.
+	QueueItems(fn func(queue.URLItem) bool) bool
+}
+
+// Server is the HTTP control API for a running crawl, or for the crawl jobs
+// of a JobManager
+type Server struct {
+	ctrl   Controller
+	jobs   JobManager
+	server *http.Server
+	mux    *http.ServeMux
+}
+
+// routes are the control endpoints of a crawl, served at the root for the
+// main crawl and under /jobs/{id} for every job
+var routes = []struct {
+	method, path string
+	handle       func(s *Server, w http.ResponseWriter, r *http.Request)
+}{
+	{"POST", "/seeds", (*Server).handleAddSeeds},
+	{"POST", "/pause", (*Server).handlePause},
+	{"POST", "/resume", (*Server).handleResume},
+	{"GET", "/hosts/paused", (*Server).handlePausedHosts},
+	{"POST", "/hosts/{host}/pause", (*Server).handlePauseHost},
+	{"POST", "/hosts/{host}/resume", (*Server).handleResumeHost},
+	{"GET", "/rate-limit", (*Server).handleGetRateLimit},
+	{"PUT", "/rate-limit", (*Server).handleSetRateLimit},
+	{"GET", "/stats", (*Server).handleStats},
+	{"POST", "/shutdown", (*Server).handleShutdown},
+	{"GET", "/dead-letters", (*Server).handleDeadLetters},
+	{"POST", "/dead-letters/requeue", (*Server).handleRequeueDeadLetters},
+	{"GET", "/subdomains", (*Server).handleSubdomains},
+	{"GET", "/queue", (*Server).handleQueue},
+	{"GET", "/queue/items", (*Server).handleQueueItems},
+}
+
+// NewServer creates a control API server listening on addr. ctrl may be nil
+// when the server only manages jobs.
+func NewServer(addr string, ctrl Controller) *Server {
+	s := &Server{
+		ctrl: ctrl,
+		mux:  http.NewServeMux(),
+	}
+
+	if ctrl != nil {
+		s.handleRoutes("")
+	}
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// handleRoutes registers the control endpoints under prefix
+func (s *Server) handleRoutes(prefix string) {
+	for _, route := range routes {
+		handle := route.handle
+		s.mux.HandleFunc(route.method+" "+prefix+route.path, func(w http.ResponseWriter, r *http.Request) {
+			handle(s, w, r)
+		})
+	}
+}
+
+// controller returns the crawl a request is for, the job named in its path
+// or the main crawl, and a logger tagged with the job
+func (s *Server) controller(w http.ResponseWriter, r *http.Request) (Controller, *logger.Logger, bool) {
+	id := r.PathValue("id")
+	if id == "" {
+		return s.ctrl, log, true
+	}
+	ctrl, ok := s.jobs.Job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %q not found", id)
+		return nil, nil, false
+	}
+	return ctrl, log.WithJob(id), true
+}
+
+// Handle registers an additional handler on the API mux
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start serves the API in the background
+func (s *Server) Start() {
+	log.Info("Control API listening on %s", s.server.Addr)
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Control API stopped: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops the API server
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down api server: %w", err)
+	}
+	return nil
+}
+
+// seedsRequest is the body of POST /seeds
+type seedsRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// pauseHostRequest is the optional body of POST /hosts/{host}/pause
+type pauseHostRequest struct {
+	Duration string `json:"duration"`
+}
+
+// rateLimitBody is the body of GET/PUT /rate-limit
+type rateLimitBody struct {
+	RateLimit string `json:"rate_limit"`
+}
+
+func (s *Server) handleAddSeeds(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	var req seedsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+	if len(req.URLs) == 0 {
+		writeError(w, http.StatusBadRequest, "no urls given")
+		return
+	}
+
+	added, err := ctrl.AddSeeds(req.URLs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	l.Info("API: added %d seed URLs", added)
+	writeJSON(w, http.StatusAccepted, map[string]int{"added": added})
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	ctrl.Pause()
+	l.Warn("API: crawl paused")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	ctrl.Resume()
+	l.Info("API: crawl resumed")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func (s *Server) handlePausedHosts(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	hosts := ctrl.PausedHosts()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(hosts), "hosts": hosts})
+}
+
+func (s *Server) handlePauseHost(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	// An empty body pauses the host until it is resumed
+	var req pauseHostRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+			return
+		}
+	}
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration %q", req.Duration)
+			return
+		}
+	}
+
+	paused := ctrl.PauseHost(r.PathValue("host"), d)
+	if d > 0 {
+		l.Warn("API: host %s paused for %s", paused.Host, d)
+	} else {
+		l.Warn("API: host %s paused", paused.Host)
+	}
+	writeJSON(w, http.StatusOK, paused)
+}
+
+func (s *Server) handleResumeHost(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	host := r.PathValue("host")
+	requeued, ok := ctrl.ResumeHost(host)
+	if !ok {
+		writeError(w, http.StatusNotFound, "host %q is not paused", host)
+		return
+	}
+	l.Info("API: host %s resumed, requeued %d URLs", host, requeued)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"host": host, "requeued": requeued})
+}
+
+func (s *Server) handleGetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, rateLimitBody{RateLimit: ctrl.RateLimit().String()})
+}
+
+func (s *Server) handleSetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	var body rateLimitBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return
+	}
+
+	d, err := time.ParseDuration(body.RateLimit)
+	if err != nil || d < 0 {
+		writeError(w, http.StatusBadRequest, "invalid rate_limit %q", body.RateLimit)
+		return
+	}
+
+	ctrl.SetRateLimit(d)
+	l.Info("API: rate limit set to %s", d)
+	writeJSON(w, http.StatusOK, rateLimitBody{RateLimit: d.String()})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	stats := ctrl.Stats()
+	stats["paused"] = ctrl.Paused()
+	stats["rate_limit"] = ctrl.RateLimit().String()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "shutting down"})
+
+	// Shut down after the response has been written
+	go func() {
+		l.Warn("API: graceful shutdown requested")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := ctrl.Shutdown(ctx); err != nil {
+			l.Error("Shutdown failed: %v", err)
+		}
+	}()
+}
+
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	entries, err := ctrl.DeadLetters(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	if entries == nil {
+		entries = []queue.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(entries), "dead_letters": entries})
+}
+
+func (s *Server) handleRequeueDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctrl, l, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	// An empty body requeues everything
+	var req seedsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+			return
+		}
+	}
+
+	requeued, err := ctrl.RequeueDeadLetters(r.Context(), req.URLs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	l.Info("API: requeued %d dead letters", requeued)
+	writeJSON(w, http.StatusOK, map[string]int{"requeued": requeued})
+}
+
+// handleSubdomains lists the discovered hosts, of one registrable domain if
+// the domain query parameter is set
+func (s *Server) handleSubdomains(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	domains := ctrl.Subdomains()
+	if domains == nil {
+		writeError(w, http.StatusNotFound, "subdomain tracking is disabled")
+		return
+	}
+	if want := strings.ToLower(r.URL.Query().Get("domain")); want != "" {
+		filtered := []subdomain.Domain{}
+		for _, d := range domains {
+			if d.Domain == want {
+				filtered = append(filtered, d)
+			}
+		}
+		domains = filtered
+	}
+	hosts := 0
+	for _, d := range domains {
+		hosts += len(d.Subdomains)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"domains": domains, "hosts": hosts})
+}
+
+// defaultQueueTop is how many URLs per priority band and hosts GET /queue
+// lists unless the top query parameter says otherwise
+const defaultQueueTop = 10
+
+// handleQueue describes the queued URLs: the next ones to pop by priority
+// band, the busiest hosts and the age of the oldest URL
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	top := defaultQueueTop
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid top %q", v)
+			return
+		}
+		top = n
+	}
+	snapshot, ok := ctrl.QueueSnapshot(top)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "the queue backend can't be inspected")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleQueueItems streams every queued URL as a line of JSON, in pop order
+// as far as the queue has one
+func (s *Server) handleQueueItems(w http.ResponseWriter, r *http.Request) {
+	ctrl, _, ok := s.controller(w, r)
+	if !ok {
+		return
+	}
+	// The status is only known once the first item comes or the walk ends
+	started := false
+	start := func() {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+	enc := json.NewEncoder(w)
+	inspected := ctrl.QueueItems(func(item queue.URLItem) bool {
+		if !started {
+			start()
+		}
+		// Stop once the client is gone
+		return enc.Encode(item) == nil && r.Context().Err() == nil
+	})
+	switch {
+	case !inspected:
+		writeError(w, http.StatusNotImplemented, "the queue backend can't be inspected")
+	case !started:
+		start()
+	}
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/queue"
+	"web-crawler/internal/subdomain"
+)
+
+// fakeCrawl is a Controller that records what the API asked of it
+type fakeCrawl struct {
+	mu        sync.Mutex
+	seeds     []string
+	paused    bool
+	rateLimit time.Duration
+	hosts     map[string]time.Duration
+	requeued  []string
+	shutdown  chan struct{}
+	domains   []subdomain.Domain
+	queue     *queue.PriorityQueue // Nil for a queue that can't be inspected
+}
+
+func newFakeCrawl() *fakeCrawl {
+	return &fakeCrawl{hosts: make(map[string]time.Duration), shutdown: make(chan struct{})}
+}
+
+func (f *fakeCrawl) AddSeeds(urls []string) (int, error) {
+	for _, u := range urls {
+		if !strings.HasPrefix(u, "http") {
+			return 0, errors.New("invalid seed URL " + u)
+		}
+	}
+	f.seeds = append(f.seeds, urls...)
+	return len(urls), nil
+}
+func (f *fakeCrawl) Pause()       { f.paused = true }
+func (f *fakeCrawl) Resume()      { f.paused = false }
+func (f *fakeCrawl) Paused() bool { return f.paused }
+func (f *fakeCrawl) PauseHost(host string, d time.Duration) queue.PausedHost {
+	f.hosts[host] = d
+	return queue.PausedHost{Host: host}
+}
+func (f *fakeCrawl) ResumeHost(host string) (int, bool) {
+	if _, ok := f.hosts[host]; !ok {
+		return 0, false
+	}
+	delete(f.hosts, host)
+	return 2, true
+}
+func (f *fakeCrawl) PausedHosts() []queue.PausedHost {
+	var hosts []queue.PausedHost
+	for host := range f.hosts {
+		hosts = append(hosts, queue.PausedHost{Host: host})
+	}
+	return hosts
+}
+func (f *fakeCrawl) SetRateLimit(d time.Duration) { f.rateLimit = d }
+func (f *fakeCrawl) RateLimit() time.Duration     { return f.rateLimit }
+func (f *fakeCrawl) Stats() map[string]interface{} {
+	return map[string]interface{}{"pagesCrawled": 5}
+}
+func (f *fakeCrawl) Shutdown(ctx context.Context) error {
+	close(f.shutdown)
+	return nil
+}
+func (f *fakeCrawl) DeadLetters(ctx context.Context) ([]queue.DeadLetter, error) {
+	return nil, nil
+}
+func (f *fakeCrawl) RequeueDeadLetters(ctx context.Context, urls []string) (int, error) {
+	f.requeued = urls
+	return len(urls), nil
+}
+func (f *fakeCrawl) Subdomains() []subdomain.Domain { return f.domains }
+func (f *fakeCrawl) QueueSnapshot(top int) (queue.Snapshot, bool) {
+	if f.queue == nil {
+		return queue.Snapshot{}, false
+	}
+	return f.queue.Snapshot(top), true
+}
+func (f *fakeCrawl) QueueItems(fn func(queue.URLItem) bool) bool {
+	if f.queue == nil {
+		return false
+	}
+	f.queue.Walk(fn)
+	return true
+}
+
+// call sends a request to the API and decodes the JSON response
+func call(t *testing.T, s *Server, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body == "" {
+		req.ContentLength = 0
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: invalid JSON response %q", method, path, rec.Body.String())
+	}
+	return rec.Code, resp
+}
+
+func TestAddSeeds(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	code, resp := call(t, s, "POST", "/seeds", `{"urls": ["https://a.com/", "https://b.com/"]}`)
+	if code != http.StatusAccepted || resp["added"] != 2.0 || len(crawl.seeds) != 2 {
+		t.Fatalf("POST /seeds = %d %v", code, resp)
+	}
+	for _, body := range []string{`{"urls": []}`, `not json`, `{"urls": ["ftp"]}`} {
+		if code, resp := call(t, s, "POST", "/seeds", body); code != http.StatusBadRequest || resp["error"] == nil {
+			t.Errorf("POST /seeds %s = %d %v, want 400", body, code, resp)
+		}
+	}
+}
+
+func TestPauseResumeAndStats(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	if code, resp := call(t, s, "POST", "/pause", ""); code != http.StatusOK || resp["paused"] != true || !crawl.paused {
+		t.Fatalf("POST /pause = %d %v", code, resp)
+	}
+	if _, resp := call(t, s, "GET", "/stats", ""); resp["paused"] != true || resp["pagesCrawled"] != 5.0 || resp["rate_limit"] != "0s" {
+		t.Fatalf("GET /stats = %v", resp)
+	}
+	if code, _ := call(t, s, "POST", "/resume", ""); code != http.StatusOK || crawl.paused {
+		t.Fatal("POST /resume didn't resume")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	if code, resp := call(t, s, "PUT", "/rate-limit", `{"rate_limit": "250ms"}`); code != http.StatusOK || resp["rate_limit"] != "250ms" {
+		t.Fatalf("PUT /rate-limit = %d %v", code, resp)
+	}
+	if crawl.rateLimit != 250*time.Millisecond {
+		t.Fatalf("rate limit = %s", crawl.rateLimit)
+	}
+	if _, resp := call(t, s, "GET", "/rate-limit", ""); resp["rate_limit"] != "250ms" {
+		t.Fatalf("GET /rate-limit = %v", resp)
+	}
+	for _, body := range []string{`{"rate_limit": "-1s"}`, `{"rate_limit": "fast"}`} {
+		if code, _ := call(t, s, "PUT", "/rate-limit", body); code != http.StatusBadRequest {
+			t.Errorf("PUT /rate-limit %s = %d, want 400", body, code)
+		}
+	}
+}
+
+func TestPauseHost(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	if code, resp := call(t, s, "POST", "/hosts/a.com/pause", `{"duration": "10m"}`); code != http.StatusOK || resp["host"] != "a.com" {
+		t.Fatalf("POST /hosts/a.com/pause = %d %v", code, resp)
+	}
+	if crawl.hosts["a.com"] != 10*time.Minute {
+		t.Fatalf("host paused for %s", crawl.hosts["a.com"])
+	}
+	if code, _ := call(t, s, "POST", "/hosts/b.com/pause", ""); code != http.StatusOK || crawl.hosts["b.com"] != 0 {
+		t.Fatal("pause without a body isn't open-ended")
+	}
+	if code, _ := call(t, s, "POST", "/hosts/c.com/pause", `{"duration": "0s"}`); code != http.StatusBadRequest {
+		t.Fatalf("zero duration = %d, want 400", code)
+	}
+	if _, resp := call(t, s, "GET", "/hosts/paused", ""); resp["count"] != 2.0 {
+		t.Fatalf("GET /hosts/paused = %v", resp)
+	}
+
+	if code, resp := call(t, s, "POST", "/hosts/a.com/resume", ""); code != http.StatusOK || resp["requeued"] != 2.0 {
+		t.Fatalf("POST /hosts/a.com/resume = %d %v", code, resp)
+	}
+	if code, _ := call(t, s, "POST", "/hosts/a.com/resume", ""); code != http.StatusNotFound {
+		t.Fatalf("resuming a host that isn't paused = %d, want 404", code)
+	}
+}
+
+func TestDeadLettersAndShutdown(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	if _, resp := call(t, s, "GET", "/dead-letters", ""); resp["count"] != 0.0 || resp["dead_letters"] == nil {
+		t.Fatalf("GET /dead-letters = %v, want an empty list", resp)
+	}
+	if _, resp := call(t, s, "POST", "/dead-letters/requeue", `{"urls": ["https://a.com/"]}`); resp["requeued"] != 1.0 {
+		t.Fatalf("POST /dead-letters/requeue = %v", resp)
+	}
+
+	if code, _ := call(t, s, "POST", "/shutdown", ""); code != http.StatusAccepted {
+		t.Fatalf("POST /shutdown = %d", code)
+	}
+	select {
+	case <-crawl.shutdown:
+	case <-time.After(time.Second):
+		t.Fatal("crawl wasn't shut down")
+	}
+}
+
+func TestSubdomains(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	if code, _ := call(t, s, "GET", "/subdomains", ""); code != http.StatusNotFound {
+		t.Fatalf("GET /subdomains without tracking = %d, want 404", code)
+	}
+
+	crawl.domains = []subdomain.Domain{
+		{Domain: "a.com", Subdomains: []subdomain.Subdomain{{Host: "a.com"}, {Host: "dev.a.com"}}},
+		{Domain: "b.co.uk", Subdomains: []subdomain.Subdomain{{Host: "www.b.co.uk"}}},
+	}
+	tests := []struct {
+		path    string
+		domains int
+		hosts   float64
+	}{
+		{"/subdomains", 2, 3},
+		{"/subdomains?domain=A.com", 1, 2},
+		{"/subdomains?domain=c.com", 0, 0},
+	}
+	for _, tt := range tests {
+		code, resp := call(t, s, "GET", tt.path, "")
+		domains, _ := resp["domains"].([]interface{})
+		if code != http.StatusOK || len(domains) != tt.domains || resp["hosts"] != tt.hosts {
+			t.Errorf("GET %s = %d %v", tt.path, code, resp)
+		}
+	}
+}
+
+func TestQueueInspection(t *testing.T) {
+	crawl := newFakeCrawl()
+	s := NewServer("", crawl)
+
+	for _, path := range []string{"/queue", "/queue/items"} {
+		if code, resp := call(t, s, "GET", path, ""); code != http.StatusNotImplemented || resp["error"] == nil {
+			t.Errorf("GET %s without an inspectable queue = %d %v", path, code, resp)
+		}
+	}
+
+	crawl.queue = queue.NewURLQueue()
+	defer crawl.queue.Close()
+	crawl.queue.PushWithPriority("https://a.com/1", queue.PriorityNormal, "a.com", 1)
+	crawl.queue.PushWithPriority("https://a.com/2", queue.PriorityNormal, "a.com", 1)
+	crawl.queue.PushWithPriority("https://b.com/", queue.PriorityHigh, "b.com", 0)
+
+	code, resp := call(t, s, "GET", "/queue?top=1", "")
+	top, _ := resp["top"].(map[string]interface{})
+	if code != http.StatusOK || resp["size"] != 3.0 || resp["host_total"] != 2.0 || len(top["normal"].([]interface{})) != 1 {
+		t.Fatalf("GET /queue = %d %v", code, resp)
+	}
+	if code, _ := call(t, s, "GET", "/queue?top=many", ""); code != http.StatusBadRequest {
+		t.Errorf("GET /queue with an invalid top = %d", code)
+	}
+
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/queue/items", nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Code != http.StatusOK || len(lines) != 3 || !strings.Contains(lines[0], "https://b.com/") {
+		t.Fatalf("GET /queue/items = %d %q", rec.Code, rec.Body.String())
+	}
+}
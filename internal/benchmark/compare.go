@@ -0,0 +1,79 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// comparable is a series that Compare can overlay
+type comparable struct {
+	title    string
+	yLabel   string
+	perFetch bool // Undefined in intervals without fetches
+	value    func(m RunMetric) float64
+}
+
+var comparables = map[string]comparable{
+	"pages":       {"Pages Crawled", "Pages Crawled", false, func(m RunMetric) float64 { return float64(m.Pages) }},
+	"queued":      {"Queue Size", "Queued URLs", false, func(m RunMetric) float64 { return float64(m.Queued) }},
+	"workers":     {"Workers", "Running Workers", false, func(m RunMetric) float64 { return float64(m.Workers) }},
+	"bytes":       {"Bytes Downloaded", "Downloaded (MB)", false, func(m RunMetric) float64 { return float64(m.Bytes) / (1024 * 1024) }},
+	"latency_p50": {"Fetch Latency p50", "Latency (ms)", true, func(m RunMetric) float64 { return m.LatencyP50 }},
+	"latency_p95": {"Fetch Latency p95", "Latency (ms)", true, func(m RunMetric) float64 { return m.LatencyP95 }},
+	"latency_p99": {"Fetch Latency p99", "Latency (ms)", true, func(m RunMetric) float64 { return m.LatencyP99 }},
+	"heap":        {"Heap Allocated", "Heap (MB)", false, func(m RunMetric) float64 { return float64(m.HeapAlloc) / (1024 * 1024) }},
+	"goroutines":  {"Goroutines", "Goroutines", false, func(m RunMetric) float64 { return float64(m.Goroutines) }},
+	"errors": {"Errors", "Errors", false, func(m RunMetric) float64 {
+		var total int64
+		for _, n := range m.Errors {
+			total += n
+		}
+		return float64(total)
+	}},
+}
+
+// CompareMetrics lists the metric names Compare accepts
+func CompareMetrics() []string {
+	names := make([]string, 0, len(comparables))
+	for name := range comparables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compare overlays one metric of several runs on a single plot, by time
+// since each run started, and saves it to outputPath
+func Compare(runs []Run, metric, outputPath string) error {
+	c, ok := comparables[metric]
+	if !ok {
+		return fmt.Errorf("unknown metric %q, expected one of %v", metric, CompareMetrics())
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no runs to compare")
+	}
+
+	var lines []series
+	for _, run := range runs {
+		var pts plotter.XYs
+		for _, m := range run.Metrics {
+			if c.perFetch && m.Fetches == 0 {
+				continue
+			}
+			pts = append(pts, plotter.XY{X: m.Elapsed, Y: c.value(m)})
+		}
+		if len(pts) == 0 {
+			return fmt.Errorf("run %s has no %s samples", run.Name, metric)
+		}
+		lines = append(lines, series{run.Name, pts})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return saveLineGraph(c.title+" by Run", c.yLabel, outputPath, lines)
+}
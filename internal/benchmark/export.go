@@ -0,0 +1,245 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Run is the exported metrics of one crawl
+type Run struct {
+	Name      string        `json:"name,omitempty"`
+	Start     time.Time     `json:"start"`
+	Metrics   []RunMetric   `json:"metrics"`
+	Histogram []BucketCount `json:"latency_histogram"`
+	Hosts     []HostFetches `json:"hosts,omitempty"` // Most fetches first
+}
+
+// RunMetric is a Metric with the elapsed time and JSON names
+type RunMetric struct {
+	Timestamp   time.Time        `json:"timestamp"`
+	Elapsed     float64          `json:"elapsed_seconds"`
+	Pages       int              `json:"pages"`
+	Queued      int              `json:"queued"`
+	Workers     int              `json:"workers"`
+	Bytes       int64            `json:"bytes"`
+	Fetches     int              `json:"fetches"`
+	LatencyP50  float64          `json:"latency_p50_ms"`
+	LatencyP95  float64          `json:"latency_p95_ms"`
+	LatencyP99  float64          `json:"latency_p99_ms"`
+	HeapAlloc   uint64           `json:"heap_alloc_bytes"`
+	HeapInuse   uint64           `json:"heap_inuse_bytes"`
+	HeapObjects uint64           `json:"heap_objects"`
+	Sys         uint64           `json:"sys_bytes"`
+	NumGC       uint32           `json:"num_gc"`
+	GCPause     float64          `json:"gc_pause_ms"` // Since the previous data point
+	GCPauseMax  float64          `json:"gc_pause_max_ms"`
+	Goroutines  int              `json:"goroutines"`
+	Errors      map[string]int64 `json:"errors,omitempty"`
+	QueueDepths map[string]int64 `json:"queue_depths,omitempty"`
+}
+
+// BucketCount is one latency histogram bucket. LE is the upper bound,
+// empty for the overflow bucket.
+type BucketCount struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// HostFetches is what was downloaded from one host over a run
+type HostFetches struct {
+	Host       string  `json:"host"`
+	Fetches    int64   `json:"fetches"`
+	Bytes      int64   `json:"bytes"`
+	AvgLatency float64 `json:"avg_latency_ms"`
+}
+
+// Run returns the metrics recorded so far in export form
+func (r *Recorder) Run() Run {
+	metrics := r.GetMetrics()
+	run := Run{Start: r.start, Metrics: make([]RunMetric, len(metrics))}
+	for i, m := range metrics {
+		run.Metrics[i] = RunMetric{
+			Timestamp:   m.Timestamp,
+			Elapsed:     m.Timestamp.Sub(r.start).Seconds(),
+			Pages:       m.PagesCount,
+			Queued:      m.QueuedCount,
+			Workers:     m.Workers,
+			Bytes:       m.Bytes,
+			Fetches:     m.Fetches,
+			LatencyP50:  millis(m.LatencyP50),
+			LatencyP95:  millis(m.LatencyP95),
+			LatencyP99:  millis(m.LatencyP99),
+			HeapAlloc:   m.Memory.HeapAlloc,
+			HeapInuse:   m.Memory.HeapInuse,
+			HeapObjects: m.Memory.HeapObjects,
+			Sys:         m.Memory.Sys,
+			NumGC:       m.Memory.NumGC,
+			GCPause:     millis(m.Memory.GCPause),
+			GCPauseMax:  millis(m.Memory.GCPauseMax),
+			Goroutines:  m.Memory.Goroutines,
+			Errors:      m.Errors,
+			QueueDepths: m.QueueDepths,
+		}
+	}
+	for i, n := range r.Histogram() {
+		bucket := BucketCount{Count: n}
+		if i < len(LatencyBuckets) {
+			bucket.LE = LatencyBuckets[i].String()
+		}
+		run.Histogram = append(run.Histogram, bucket)
+	}
+	run.Hosts = r.hostFetches()
+	return run
+}
+
+// hostFetches returns the fetch totals of every host, most fetches first
+func (r *Recorder) hostFetches() []HostFetches {
+	r.mu.RLock()
+	hosts := make([]HostFetches, 0, len(r.hosts))
+	for host, h := range r.hosts {
+		hosts = append(hosts, HostFetches{
+			Host:       host,
+			Fetches:    h.fetches,
+			Bytes:      h.bytes,
+			AvgLatency: millis(h.latency / time.Duration(h.fetches)),
+		})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Fetches != hosts[j].Fetches {
+			return hosts[i].Fetches > hosts[j].Fetches
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// ExportJSON writes the recorded metrics and latency histogram to path
+func (r *Recorder) ExportJSON(path string) error {
+	data, err := json.MarshalIndent(r.Run(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// ExportCSV writes one row per recorded metric to path. Error classes and
+// queue priorities get one column each.
+func (r *Recorder) ExportCSV(path string) error {
+	run := r.Run()
+
+	errorClasses := make(map[string]bool)
+	priorities := make(map[string]bool)
+	for _, m := range run.Metrics {
+		for class := range m.Errors {
+			errorClasses[class] = true
+		}
+		for priority := range m.QueueDepths {
+			priorities[priority] = true
+		}
+	}
+	classes := sortedKeys(errorClasses)
+	var depths []string
+	for _, priority := range []string{"high", "normal", "low"} {
+		if priorities[priority] {
+			depths = append(depths, priority)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	header := []string{"timestamp", "elapsed_seconds", "pages", "queued", "workers", "bytes", "fetches",
+		"latency_p50_ms", "latency_p95_ms", "latency_p99_ms", "heap_alloc_bytes", "heap_inuse_bytes", "heap_objects",
+		"sys_bytes", "num_gc", "gc_pause_ms", "gc_pause_max_ms", "goroutines"}
+	for _, class := range classes {
+		header = append(header, "errors_"+class)
+	}
+	for _, priority := range depths {
+		header = append(header, "queue_"+priority)
+	}
+	w.Write(header)
+
+	for _, m := range run.Metrics {
+		row := []string{
+			m.Timestamp.Format(time.RFC3339Nano),
+			formatFloat(m.Elapsed),
+			strconv.Itoa(m.Pages),
+			strconv.Itoa(m.Queued),
+			strconv.Itoa(m.Workers),
+			strconv.FormatInt(m.Bytes, 10),
+			strconv.Itoa(m.Fetches),
+			formatFloat(m.LatencyP50),
+			formatFloat(m.LatencyP95),
+			formatFloat(m.LatencyP99),
+			strconv.FormatUint(m.HeapAlloc, 10),
+			strconv.FormatUint(m.HeapInuse, 10),
+			strconv.FormatUint(m.HeapObjects, 10),
+			strconv.FormatUint(m.Sys, 10),
+			strconv.FormatUint(uint64(m.NumGC), 10),
+			formatFloat(m.GCPause),
+			formatFloat(m.GCPauseMax),
+			strconv.Itoa(m.Goroutines),
+		}
+		for _, class := range classes {
+			row = append(row, strconv.FormatInt(m.Errors[class], 10))
+		}
+		for _, priority := range depths {
+			row = append(row, strconv.FormatInt(m.QueueDepths[priority], 10))
+		}
+		w.Write(row)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return file.Close()
+}
+
+// LoadRun reads metrics written by ExportJSON. The run is named after the
+// file's directory, e.g. benchmarks/run-1/metrics.json becomes run-1.
+func LoadRun(path string) (Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, fmt.Errorf("failed to parse metrics %s: %w", path, err)
+	}
+	if run.Name == "" {
+		run.Name = filepath.Base(filepath.Dir(path))
+	}
+	sort.Slice(run.Metrics, func(i, j int) bool { return run.Metrics[i].Elapsed < run.Metrics[j].Elapsed })
+	return run, nil
+}
+
+// millis converts d to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,154 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := map[int]time.Duration{
+		0:   time.Millisecond,
+		50:  5 * time.Millisecond,
+		95:  10 * time.Millisecond,
+		99:  10 * time.Millisecond,
+		100: 10 * time.Millisecond,
+	}
+	for p, want := range tests {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("percentile(%d) = %s, want %s", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of no latencies = %s", got)
+	}
+}
+
+// testRecorder returns a recorder with two data points
+func testRecorder() *Recorder {
+	r := New()
+	r.ObserveFetch("a.com", 5*time.Millisecond, 100)
+	r.ObserveFetch("b.com", 40*time.Millisecond, 200)
+	r.ObserveFetch("a.com", time.Minute, 0) // Overflow bucket
+	r.ObserveError("timeout")
+	r.Record(3, 10, 4, map[string]int64{"high": 2, "low": 8})
+	r.ObserveError("http_5xx")
+	r.Record(5, 6, 4, map[string]int64{"normal": 6})
+	return r
+}
+
+func TestRun(t *testing.T) {
+	run := testRecorder().Run()
+	if len(run.Metrics) != 2 {
+		t.Fatalf("run has %d metrics, want 2", len(run.Metrics))
+	}
+	m := run.Metrics[0]
+	if m.Pages != 3 || m.Fetches != 3 || m.Bytes != 300 || m.LatencyP50 != 40 || m.Errors["timeout"] != 1 {
+		t.Errorf("first metric = %+v", m)
+	}
+	if m := run.Metrics[1]; m.Fetches != 0 || m.Errors["http_5xx"] != 1 || m.Errors["timeout"] != 1 {
+		t.Errorf("second metric = %+v", m)
+	}
+	if m.HeapAlloc == 0 || m.Sys < m.HeapInuse || m.Goroutines == 0 {
+		t.Errorf("first metric memory = %+v", m)
+	}
+
+	if len(run.Histogram) != len(LatencyBuckets)+1 {
+		t.Fatalf("histogram has %d buckets", len(run.Histogram))
+	}
+	if b := run.Histogram[0]; b.LE != "10ms" || b.Count != 1 {
+		t.Errorf("first bucket = %+v", b)
+	}
+	if b := run.Histogram[2]; b.LE != "50ms" || b.Count != 1 {
+		t.Errorf("50ms bucket = %+v", b)
+	}
+	if b := run.Histogram[len(LatencyBuckets)]; b.LE != "" || b.Count != 1 {
+		t.Errorf("overflow bucket = %+v", b)
+	}
+}
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run-1", "metrics.json")
+	if err := testRecorder().ExportJSON(path); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := LoadRun(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Name != "run-1" || len(run.Metrics) != 2 || run.Metrics[1].Pages != 5 {
+		t.Fatalf("LoadRun() = %+v", run)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRun(path); err == nil {
+		t.Fatal("LoadRun() accepted invalid JSON")
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.csv")
+	if err := testRecorder().ExportCSV(path); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := strings.Join(rows[0], ",")
+	want := "timestamp,elapsed_seconds,pages,queued,workers,bytes,fetches,latency_p50_ms,latency_p95_ms,latency_p99_ms," +
+		"heap_alloc_bytes,heap_inuse_bytes,heap_objects,sys_bytes,num_gc,gc_pause_ms,gc_pause_max_ms,goroutines," +
+		"errors_http_5xx,errors_timeout,queue_high,queue_normal,queue_low"
+	if header != want {
+		t.Fatalf("header = %s\nwant %s", header, want)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	// Columns missing from a data point are zero
+	if got := strings.Join(rows[2][18:], ","); got != "1,1,0,6,0" {
+		t.Fatalf("second row errors and queues = %s", got)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	run := testRecorder().Run()
+	if len(run.Hosts) != 2 || run.Hosts[0] != (HostFetches{"a.com", 2, 100, 30002.5}) {
+		t.Fatalf("hosts = %+v", run.Hosts)
+	}
+
+	path := filepath.Join(t.TempDir(), "out", "report.html")
+	if err := WriteReport(run, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	// The run is embedded as data for the script, not as escaped text
+	for _, part := range []string{`const run = {"start":`, `"host":"a.com"`, `"latency_histogram":`} {
+		if !strings.Contains(page, part) {
+			t.Errorf("report lacks %s", part)
+		}
+	}
+	if strings.Contains(page, "<script src") || strings.Contains(page, "<link") {
+		t.Error("report loads external resources")
+	}
+}
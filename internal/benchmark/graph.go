@@ -5,6 +5,8 @@
 	"image/color"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
@@ -34,6 +36,49 @@ func (r *Recorder) GenerateGraphs(outputDir string) error {
 		return fmt.Errorf("failed to generate ratio graph: %w", err)
 	}
 
+	// Generate latency percentiles vs time graph
+	if err := r.generateLatencyGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate latency graph: %w", err)
+	}
+
+	// Generate latency histogram of the whole run
+	if err := r.generateHistogramGraph(outputDir); err != nil {
+		return fmt.Errorf("failed to generate latency histogram: %w", err)
+	}
+
+	// Generate downloaded bytes vs time graph
+	if err := r.generateBytesGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate bytes graph: %w", err)
+	}
+
+	// Generate errors by class vs time graph
+	if err := r.generateErrorsGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate errors graph: %w", err)
+	}
+
+	// Generate worker count vs time graph
+	if err := r.generateWorkersGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate workers graph: %w", err)
+	}
+
+	// Generate queue depth by priority vs time graph
+	if err := r.generateQueueDepthGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate queue depth graph: %w", err)
+	}
+
+	// Generate heap and GC graphs
+	if err := r.generateMemoryGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate memory graph: %w", err)
+	}
+	if err := r.generateGCGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate GC graph: %w", err)
+	}
+
+	// Generate goroutine count vs time graph
+	if err := r.generateGoroutinesGraph(outputDir, metrics); err != nil {
+		return fmt.Errorf("failed to generate goroutines graph: %w", err)
+	}
+
 	return nil
 }
 
@@ -104,3 +149,223 @@ func (r *Recorder) generateRatioGraph(outputDir string, metrics []Metric) error
 
 	return nil
 }
+
+// palette colors the lines of multi-series graphs
+var palette = []color.RGBA{
+	{R: 0, G: 0, B: 255, A: 255},
+	{R: 255, G: 0, B: 0, A: 255},
+	{R: 0, G: 160, B: 0, A: 255},
+	{R: 200, G: 120, B: 0, A: 255},
+	{R: 140, G: 0, B: 180, A: 255},
+	{R: 0, G: 150, B: 150, A: 255},
+	{R: 100, G: 100, B: 100, A: 255},
+}
+
+// series is one named line of a graph
+type series struct {
+	name string
+	pts  plotter.XYs
+}
+
+// saveLineGraph plots one line per series and writes the graph to filename
+func saveLineGraph(title, yLabel, filename string, lines []series) error {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "Time (seconds)"
+	p.Y.Label.Text = yLabel
+	p.Legend.Top = true
+
+	for i, s := range lines {
+		line, err := plotter.NewLine(s.pts)
+		if err != nil {
+			return err
+		}
+		line.Color = palette[i%len(palette)]
+		p.Add(line)
+		p.Legend.Add(s.name, line)
+	}
+
+	if err := p.Save(8*vg.Inch, 6*vg.Inch, filename); err != nil {
+		return fmt.Errorf("failed to save %s: %w", filepath.Base(filename), err)
+	}
+	return nil
+}
+
+func (r *Recorder) generateLatencyGraph(outputDir string, metrics []Metric) error {
+	var p50, p95, p99 plotter.XYs
+	for _, m := range metrics {
+		// Intervals without fetches have no latency to show
+		if m.Fetches == 0 {
+			continue
+		}
+		x := m.Timestamp.Sub(r.start).Seconds()
+		p50 = append(p50, plotter.XY{X: x, Y: float64(m.LatencyP50) / float64(time.Millisecond)})
+		p95 = append(p95, plotter.XY{X: x, Y: float64(m.LatencyP95) / float64(time.Millisecond)})
+		p99 = append(p99, plotter.XY{X: x, Y: float64(m.LatencyP99) / float64(time.Millisecond)})
+	}
+	if len(p50) == 0 {
+		return nil
+	}
+
+	return saveLineGraph("Fetch Latency vs Time", "Latency (ms)", filepath.Join(outputDir, "latency_vs_time.png"),
+		[]series{{"p50", p50}, {"p95", p95}, {"p99", p99}})
+}
+
+func (r *Recorder) generateHistogramGraph(outputDir string) error {
+	counts := r.Histogram()
+	values := make(plotter.Values, len(counts))
+	labels := make([]string, len(counts))
+	total := 0.0
+	for i, n := range counts {
+		values[i] = float64(n)
+		total += float64(n)
+		if i < len(LatencyBuckets) {
+			labels[i] = "≤" + LatencyBuckets[i].String()
+		} else {
+			labels[i] = ">" + LatencyBuckets[len(LatencyBuckets)-1].String()
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	p := plot.New()
+	p.Title.Text = "Fetch Latency Distribution"
+	p.Y.Label.Text = "Fetches"
+
+	bars, err := plotter.NewBarChart(values, vg.Points(30))
+	if err != nil {
+		return err
+	}
+	bars.Color = palette[0]
+	p.Add(bars)
+	p.NominalX(labels...)
+
+	filename := filepath.Join(outputDir, "latency_histogram.png")
+	if err := p.Save(8*vg.Inch, 6*vg.Inch, filename); err != nil {
+		return fmt.Errorf("failed to save latency histogram: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) generateBytesGraph(outputDir string, metrics []Metric) error {
+	total := make(plotter.XYs, len(metrics))
+	for i, m := range metrics {
+		total[i].X = m.Timestamp.Sub(r.start).Seconds()
+		total[i].Y = float64(m.Bytes) / (1024 * 1024)
+	}
+
+	return saveLineGraph("Bytes Downloaded vs Time", "Downloaded (MB)", filepath.Join(outputDir, "bytes_vs_time.png"),
+		[]series{{"MB", total}})
+}
+
+func (r *Recorder) generateWorkersGraph(outputDir string, metrics []Metric) error {
+	workers := make(plotter.XYs, len(metrics))
+	for i, m := range metrics {
+		workers[i].X = m.Timestamp.Sub(r.start).Seconds()
+		workers[i].Y = float64(m.Workers)
+	}
+
+	return saveLineGraph("Workers vs Time", "Running Workers", filepath.Join(outputDir, "workers_vs_time.png"),
+		[]series{{"Workers", workers}})
+}
+
+func (r *Recorder) generateErrorsGraph(outputDir string, metrics []Metric) error {
+	classes := make(map[string]bool)
+	for _, m := range metrics {
+		for class := range m.Errors {
+			classes[class] = true
+		}
+	}
+	if len(classes) == 0 {
+		return nil
+	}
+
+	var lines []series
+	for _, class := range sortedKeys(classes) {
+		pts := make(plotter.XYs, len(metrics))
+		for i, m := range metrics {
+			pts[i].X = m.Timestamp.Sub(r.start).Seconds()
+			pts[i].Y = float64(m.Errors[class])
+		}
+		lines = append(lines, series{class, pts})
+	}
+
+	return saveLineGraph("Errors by Class vs Time", "Errors", filepath.Join(outputDir, "errors_vs_time.png"), lines)
+}
+
+func (r *Recorder) generateQueueDepthGraph(outputDir string, metrics []Metric) error {
+	priorities := make(map[string]bool)
+	for _, m := range metrics {
+		for priority := range m.QueueDepths {
+			priorities[priority] = true
+		}
+	}
+	if len(priorities) == 0 {
+		return nil
+	}
+
+	var lines []series
+	for _, priority := range []string{"high", "normal", "low"} {
+		if !priorities[priority] {
+			continue
+		}
+		pts := make(plotter.XYs, len(metrics))
+		for i, m := range metrics {
+			pts[i].X = m.Timestamp.Sub(r.start).Seconds()
+			pts[i].Y = float64(m.QueueDepths[priority])
+		}
+		lines = append(lines, series{priority, pts})
+	}
+
+	return saveLineGraph("Queue Depth by Priority vs Time", "Queued URLs", filepath.Join(outputDir, "queue_depth_vs_time.png"), lines)
+}
+
+func (r *Recorder) generateMemoryGraph(outputDir string, metrics []Metric) error {
+	alloc := make(plotter.XYs, len(metrics))
+	inuse := make(plotter.XYs, len(metrics))
+	sys := make(plotter.XYs, len(metrics))
+	for i, m := range metrics {
+		x := m.Timestamp.Sub(r.start).Seconds()
+		alloc[i] = plotter.XY{X: x, Y: float64(m.Memory.HeapAlloc) / (1024 * 1024)}
+		inuse[i] = plotter.XY{X: x, Y: float64(m.Memory.HeapInuse) / (1024 * 1024)}
+		sys[i] = plotter.XY{X: x, Y: float64(m.Memory.Sys) / (1024 * 1024)}
+	}
+
+	return saveLineGraph("Memory vs Time", "Memory (MB)", filepath.Join(outputDir, "memory_vs_time.png"),
+		[]series{{"Heap allocated", alloc}, {"Heap in use", inuse}, {"From the OS", sys}})
+}
+
+func (r *Recorder) generateGCGraph(outputDir string, metrics []Metric) error {
+	pause := make(plotter.XYs, len(metrics))
+	pauseMax := make(plotter.XYs, len(metrics))
+	for i, m := range metrics {
+		x := m.Timestamp.Sub(r.start).Seconds()
+		pause[i] = plotter.XY{X: x, Y: float64(m.Memory.GCPause) / float64(time.Millisecond)}
+		pauseMax[i] = plotter.XY{X: x, Y: float64(m.Memory.GCPauseMax) / float64(time.Millisecond)}
+	}
+
+	return saveLineGraph("GC Pauses vs Time", "Pause per Interval (ms)", filepath.Join(outputDir, "gc_vs_time.png"),
+		[]series{{"Total", pause}, {"Longest", pauseMax}})
+}
+
+func (r *Recorder) generateGoroutinesGraph(outputDir string, metrics []Metric) error {
+	goroutines := make(plotter.XYs, len(metrics))
+	for i, m := range metrics {
+		goroutines[i].X = m.Timestamp.Sub(r.start).Seconds()
+		goroutines[i].Y = float64(m.Memory.Goroutines)
+	}
+
+	return saveLineGraph("Goroutines vs Time", "Goroutines", filepath.Join(outputDir, "goroutines_vs_time.png"),
+		[]series{{"Goroutines", goroutines}})
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,52 @@
+package benchmark
+
+import (
+	"runtime"
+	"time"
+)
+
+// MemoryStats is the memory and GC state of the Go runtime at one data point
+type MemoryStats struct {
+	HeapAlloc   uint64        // Bytes of allocated heap objects, live or not yet collected
+	HeapInuse   uint64        // Bytes in in-use heap spans
+	HeapObjects uint64        // Allocated heap objects
+	Sys         uint64        // Bytes obtained from the OS
+	NumGC       uint32        // GC cycles so far
+	GCPause     time.Duration // Stop-the-world pauses since the previous data point
+	GCPauseMax  time.Duration // Longest pause since the previous data point
+	Goroutines  int
+}
+
+// gcState is what the previous data point saw of the GC, to report pauses
+// per interval
+type gcState struct {
+	numGC      uint32
+	pauseTotal uint64
+}
+
+// readMemory reads the runtime memory state. It stops the world briefly,
+// so it's read once per data point.
+func readMemory(prev *gcState) MemoryStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	mem := MemoryStats{
+		HeapAlloc:   ms.HeapAlloc,
+		HeapInuse:   ms.HeapInuse,
+		HeapObjects: ms.HeapObjects,
+		Sys:         ms.Sys,
+		NumGC:       ms.NumGC,
+		GCPause:     time.Duration(ms.PauseTotalNs - prev.pauseTotal),
+		Goroutines:  runtime.NumGoroutine(),
+	}
+	// PauseNs holds the most recent pauses, the last one at (NumGC+255)%256
+	cycles := min(ms.NumGC-prev.numGC, uint32(len(ms.PauseNs)))
+	for i := uint32(0); i < cycles; i++ {
+		pause := time.Duration(ms.PauseNs[(ms.NumGC-i+uint32(len(ms.PauseNs))-1)%uint32(len(ms.PauseNs))])
+		mem.GCPauseMax = max(mem.GCPauseMax, pause)
+	}
+
+	prev.numGC = ms.NumGC
+	prev.pauseTotal = ms.PauseTotalNs
+	return mem
+}
@@ -0,0 +1,26 @@
+package benchmark
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestReadMemoryGCPauses(t *testing.T) {
+	var gc gcState
+	readMemory(&gc)
+
+	runtime.GC()
+	runtime.GC()
+	mem := readMemory(&gc)
+	if mem.NumGC < 2 || gc.numGC != mem.NumGC {
+		t.Fatalf("NumGC = %d, state %d", mem.NumGC, gc.numGC)
+	}
+	if mem.GCPause <= 0 || mem.GCPauseMax <= 0 || mem.GCPauseMax > mem.GCPause {
+		t.Errorf("pause = %s, max %s", mem.GCPause, mem.GCPauseMax)
+	}
+
+	// Pauses are counted once
+	if mem := readMemory(&gc); mem.NumGC == gc.numGC && (mem.GCPause != 0 || mem.GCPauseMax != 0) {
+		t.Errorf("pause without a GC = %s, max %s", mem.GCPause, mem.GCPauseMax)
+	}
+}
@@ -0,0 +1,92 @@
+package benchmark
+
+// RunTotals condenses a run into the figures regressions are judged by
+type RunTotals struct {
+	Seconds     float64 `json:"seconds"`
+	Pages       int     `json:"pages"`
+	PagesPerSec float64 `json:"pages_per_sec"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	Fetches     int     `json:"fetches"`
+	Errors      int64   `json:"errors"`
+	ErrorRate   float64 `json:"error_rate"`     // Errors per fetch
+	LatencyP50  float64 `json:"latency_p50_ms"` // Averaged over the intervals weighted by their fetches
+	LatencyP95  float64 `json:"latency_p95_ms"`
+	LatencyP99  float64 `json:"latency_p99_ms"`
+}
+
+// Totals returns the figures of the whole run
+func (run Run) Totals() RunTotals {
+	var t RunTotals
+	if len(run.Metrics) == 0 {
+		return t
+	}
+	last := run.Metrics[len(run.Metrics)-1]
+	t.Seconds = last.Elapsed
+	t.Pages = last.Pages
+	for _, n := range last.Errors {
+		t.Errors += n
+	}
+	for _, m := range run.Metrics {
+		t.Fetches += m.Fetches
+		t.LatencyP50 += m.LatencyP50 * float64(m.Fetches)
+		t.LatencyP95 += m.LatencyP95 * float64(m.Fetches)
+		t.LatencyP99 += m.LatencyP99 * float64(m.Fetches)
+	}
+	if t.Fetches > 0 {
+		t.LatencyP50 /= float64(t.Fetches)
+		t.LatencyP95 /= float64(t.Fetches)
+		t.LatencyP99 /= float64(t.Fetches)
+		t.ErrorRate = float64(t.Errors) / float64(t.Fetches)
+	}
+	if t.Seconds > 0 {
+		t.PagesPerSec = float64(t.Pages) / t.Seconds
+		t.BytesPerSec = float64(last.Bytes) / t.Seconds
+	}
+	return t
+}
+
+// Change is the difference in one figure between a base and a head run
+type Change struct {
+	Metric    string
+	Base      float64
+	Head      float64
+	Delta     float64 // Relative change from base to head, 0 if base is 0
+	Regressed bool    // Worse by more than the threshold
+}
+
+// judged are the figures CompareRuns looks at and whether more is better
+var judged = []struct {
+	name   string
+	higher bool
+	value  func(t RunTotals) float64
+}{
+	{"pages_per_sec", true, func(t RunTotals) float64 { return t.PagesPerSec }},
+	{"bytes_per_sec", true, func(t RunTotals) float64 { return t.BytesPerSec }},
+	{"latency_p50_ms", false, func(t RunTotals) float64 { return t.LatencyP50 }},
+	{"latency_p95_ms", false, func(t RunTotals) float64 { return t.LatencyP95 }},
+	{"latency_p99_ms", false, func(t RunTotals) float64 { return t.LatencyP99 }},
+	{"error_rate", false, func(t RunTotals) float64 { return t.ErrorRate }},
+}
+
+// CompareRuns compares the totals of head against base. A figure regressed
+// if it got worse by more than threshold, e.g. 0.1 for 10%. Any rise from 0
+// of a figure where less is better, like the error rate, is a regression.
+func CompareRuns(base, head Run, threshold float64) []Change {
+	bt, ht := base.Totals(), head.Totals()
+	changes := make([]Change, 0, len(judged))
+	for _, j := range judged {
+		c := Change{Metric: j.name, Base: j.value(bt), Head: j.value(ht)}
+		worse := c.Head - c.Base
+		if j.higher {
+			worse = -worse
+		}
+		if c.Base != 0 {
+			c.Delta = (c.Head - c.Base) / c.Base
+			c.Regressed = worse/c.Base > threshold
+		} else {
+			c.Regressed = worse > 0
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
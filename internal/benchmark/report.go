@@ -0,0 +1,46 @@
+package benchmark
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// reportHosts is how many of the busiest hosts the report charts
+const reportHosts = 20
+
+//go:embed report.html
+var reportHTML string
+
+// reportPage is the HTML report, the run is embedded as JSON and drawn by
+// the script in the page
+var reportPage = template.Must(template.New("report").Parse(reportHTML))
+
+// WriteReport writes run as a self-contained HTML page with interactive
+// charts to path. The page loads nothing from elsewhere, so it can be opened
+// from disk or mailed around.
+func WriteReport(run Run, path string) error {
+	var buf bytes.Buffer
+	data := struct {
+		Run      Run
+		MaxHosts int
+	}{run, reportHosts}
+	if err := reportPage.Execute(&buf, data); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// GenerateReport writes report.html with the recorded metrics to outputDir
+func (r *Recorder) GenerateReport(outputDir string) error {
+	return WriteReport(r.Run(), filepath.Join(outputDir, "report.html"))
+}
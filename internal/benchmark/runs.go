@@ -0,0 +1,139 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ShortIDLength is how many characters of a run ID are shown, like a short
+// git commit hash
+const ShortIDLength = 8
+
+// StoredRun is a benchmark run kept to compare later runs against
+type StoredRun struct {
+	ID      string    `json:"id" bson:"_id"`
+	Created time.Time `json:"created" bson:"created"`
+	Config  string    `json:"config" bson:"config"` // YAML of the crawl's config, without credentials
+	Totals  RunTotals `json:"totals" bson:"totals"` // Of Run, kept for listings without the metrics
+	Run     Run       `json:"run" bson:"run"`
+}
+
+// NewRunID returns the ID of a run started at start with config: the hex
+// SHA-1 of both, so it's unique and looks like a git commit hash
+func NewRunID(start time.Time, config string) string {
+	sum := sha1.Sum([]byte(start.UTC().Format(time.RFC3339Nano) + "\n" + config))
+	return hex.EncodeToString(sum[:])
+}
+
+// ShortID returns the abbreviated run ID
+func ShortID(id string) string {
+	if len(id) > ShortIDLength {
+		return id[:ShortIDLength]
+	}
+	return id
+}
+
+// RunStore keeps benchmark runs
+type RunStore interface {
+	Save(ctx context.Context, run StoredRun) error
+	// Load returns the run whose ID starts with id, an error unless exactly
+	// one matches
+	Load(ctx context.Context, id string) (StoredRun, error)
+	// List returns every run without its metrics, oldest first
+	List(ctx context.Context) ([]StoredRun, error)
+}
+
+// CheckRunID checks that id can be a run ID or a prefix of one
+func CheckRunID(id string) error {
+	if id == "" {
+		return fmt.Errorf("empty run ID")
+	}
+	if _, err := hex.DecodeString(id + strings.Repeat("0", len(id)%2)); err != nil {
+		return fmt.Errorf("invalid run ID %q", id)
+	}
+	return nil
+}
+
+// DirRunStore keeps one <id>.json file per run in a directory
+type DirRunStore struct {
+	dir string
+}
+
+// NewDirRunStore returns a store of runs in dir, created on the first save
+func NewDirRunStore(dir string) *DirRunStore {
+	return &DirRunStore{dir: dir}
+}
+
+// Save writes run to <id>.json
+func (s *DirRunStore) Save(ctx context.Context, run StoredRun) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create runs directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, run.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// Load reads the run whose ID starts with id
+func (s *DirRunStore) Load(ctx context.Context, id string) (StoredRun, error) {
+	if err := CheckRunID(id); err != nil {
+		return StoredRun{}, err
+	}
+	matches, err := filepath.Glob(filepath.Join(s.dir, id+"*.json"))
+	if err != nil {
+		return StoredRun{}, fmt.Errorf("failed to list runs: %w", err)
+	}
+	switch len(matches) {
+	case 0:
+		return StoredRun{}, fmt.Errorf("no run %s in %s", id, s.dir)
+	case 1:
+		return readRun(matches[0])
+	default:
+		return StoredRun{}, fmt.Errorf("run ID %s is ambiguous, %d runs match", id, len(matches))
+	}
+}
+
+// List reads every run in the directory
+func (s *DirRunStore) List(ctx context.Context) ([]StoredRun, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	runs := make([]StoredRun, 0, len(paths))
+	for _, path := range paths {
+		run, err := readRun(path)
+		if err != nil {
+			return nil, err
+		}
+		run.Run.Metrics = nil
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Created.Before(runs[j].Created) })
+	return runs, nil
+}
+
+// readRun reads a run saved by DirRunStore
+func readRun(path string) (StoredRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return StoredRun{}, fmt.Errorf("failed to read run: %w", err)
+	}
+	var run StoredRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return StoredRun{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return run, nil
+}
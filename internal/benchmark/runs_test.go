@@ -0,0 +1,78 @@
+package benchmark
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirRunStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewDirRunStore(t.TempDir())
+	run := testRecorder().Run()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	a := StoredRun{ID: NewRunID(start, "a: 1\n"), Created: start, Config: "a: 1\n", Totals: run.Totals(), Run: run}
+	b := StoredRun{ID: NewRunID(start, "a: 2\n"), Created: start.Add(time.Hour), Config: "a: 2\n", Run: run}
+	if len(a.ID) != 40 || a.ID == b.ID || a.ID != NewRunID(start, "a: 1\n") {
+		t.Fatalf("run IDs %s and %s", a.ID, b.ID)
+	}
+	for _, r := range []StoredRun{b, a} {
+		if err := s.Save(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.Load(ctx, ShortID(a.ID))
+	if err != nil || got.ID != a.ID || got.Config != "a: 1\n" || len(got.Run.Metrics) != 2 || got.Totals.Pages != 5 {
+		t.Fatalf("Load(%s) = %+v, %v", ShortID(a.ID), got, err)
+	}
+	if _, err := s.Load(ctx, "ffffffff"); err == nil || !strings.Contains(err.Error(), "no run") {
+		t.Errorf("loading a missing run: %v", err)
+	}
+	if _, err := s.Load(ctx, "../x"); err == nil {
+		t.Error("loaded a run by path")
+	}
+
+	list, err := s.List(ctx)
+	if err != nil || len(list) != 2 || list[0].ID != a.ID || list[1].ID != b.ID || list[0].Run.Metrics != nil {
+		t.Fatalf("List() = %+v, %v", list, err)
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	run := func(pages int, p95 float64, errors int64) Run {
+		return Run{Metrics: []RunMetric{
+			{Elapsed: 5, Pages: pages / 2, Fetches: 10, LatencyP95: p95},
+			{Elapsed: 10, Pages: pages, Fetches: 10, LatencyP95: p95, Errors: map[string]int64{"timeout": errors}},
+		}}
+	}
+	base := run(100, 200, 0)
+	if totals := base.Totals(); totals.PagesPerSec != 10 || totals.Fetches != 20 || totals.LatencyP95 != 200 {
+		t.Fatalf("Totals() = %+v", totals)
+	}
+
+	tests := []struct {
+		name      string
+		head      Run
+		regressed []string
+	}{
+		{"same", run(100, 200, 0), nil},
+		{"within threshold", run(96, 209, 0), nil},
+		{"faster", run(150, 100, 0), nil},
+		{"slower", run(80, 250, 0), []string{"pages_per_sec", "latency_p95_ms"}},
+		{"new errors", run(100, 200, 1), []string{"error_rate"}},
+	}
+	for _, tt := range tests {
+		var regressed []string
+		for _, c := range CompareRuns(base, tt.head, 0.05) {
+			if c.Regressed {
+				regressed = append(regressed, c.Metric)
+			}
+		}
+		if strings.Join(regressed, ",") != strings.Join(tt.regressed, ",") {
+			t.Errorf("%s: regressed %v, want %v", tt.name, regressed, tt.regressed)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package benchmark
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -10,6 +11,30 @@ type Metric struct {
 	Timestamp   time.Time
 	PagesCount  int
 	QueuedCount int
+	Workers     int           // Running workers
+	Bytes       int64         // Body bytes downloaded so far
+	Fetches     int           // Fetches since the previous data point
+	LatencyP50  time.Duration // Fetch latency percentiles since the previous data point
+	LatencyP95  time.Duration
+	LatencyP99  time.Duration
+	Errors      map[string]int64 // Errors so far by class
+	QueueDepths map[string]int64 // Queued URLs by priority
+	Memory      MemoryStats
+}
+
+// LatencyBuckets are the upper bounds of the latency histogram buckets.
+// Slower fetches fall into a final overflow bucket.
+var LatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
 }
 
 // Recorder handles the collection and storage of benchmark metrics
@@ -17,26 +42,116 @@ type Recorder struct {
 	metrics []Metric
 	mu      sync.RWMutex
 	start   time.Time
+
+	latencies []time.Duration // Fetch latencies since the last data point
+	histogram []int64         // Fetch latencies of the whole run, per LatencyBuckets
+	fetches   int64
+	latency   time.Duration // Sum of all fetch latencies, for the average
+	bytes     int64
+	errors    map[string]int64
+	hosts     map[string]*hostTotals
+	gc        gcState
+}
+
+// hostTotals are the fetches of one host over the whole run
+type hostTotals struct {
+	fetches int64
+	bytes   int64
+	latency time.Duration
 }
 
 // New creates a new benchmark recorder
 func New() *Recorder {
-	return &Recorder{
-		metrics: make([]Metric, 0),
-		start:   time.Now(),
+	r := &Recorder{
+		metrics:   make([]Metric, 0),
+		start:     time.Now(),
+		histogram: make([]int64, len(LatencyBuckets)+1),
+		errors:    make(map[string]int64),
+		hosts:     make(map[string]*hostTotals),
+	}
+	// GC pauses are reported from here on
+	readMemory(&r.gc)
+	return r
+}
+
+// ObserveFetch records the latency and body size of one fetch from host
+func (r *Recorder) ObserveFetch(host string, latency time.Duration, bytes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+	r.histogram[sort.Search(len(LatencyBuckets), func(i int) bool { return latency <= LatencyBuckets[i] })]++
+	r.fetches++
+	r.latency += latency
+	r.bytes += int64(bytes)
+
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &hostTotals{}
+		r.hosts[host] = h
+	}
+	h.fetches++
+	h.bytes += int64(bytes)
+	h.latency += latency
+}
+
+// Totals returns the number of fetches of the whole run, their average
+// latency and the body bytes downloaded
+func (r *Recorder) Totals() (fetches int64, avgLatency time.Duration, bytes int64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.fetches > 0 {
+		avgLatency = r.latency / time.Duration(r.fetches)
 	}
+	return r.fetches, avgLatency, r.bytes
 }
 
-// Record adds a new metric point
-func (r *Recorder) Record(pagesCount, queuedCount int) {
+// ObserveError counts one error of the given class
+func (r *Recorder) ObserveError(class string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.errors[class]++
+}
+
+// Record adds a new metric point with the runtime memory state. depths
+// holds the queued URLs by priority and may be nil.
+func (r *Recorder) Record(pagesCount, queuedCount, workers int, depths map[string]int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	errors := make(map[string]int64, len(r.errors))
+	for class, n := range r.errors {
+		errors[class] = n
+	}
+
 	r.metrics = append(r.metrics, Metric{
 		Timestamp:   time.Now(),
 		PagesCount:  pagesCount,
 		QueuedCount: queuedCount,
+		Workers:     workers,
+		Bytes:       r.bytes,
+		Fetches:     len(r.latencies),
+		LatencyP50:  percentile(r.latencies, 50),
+		LatencyP95:  percentile(r.latencies, 95),
+		LatencyP99:  percentile(r.latencies, 99),
+		Errors:      errors,
+		QueueDepths: depths,
+		Memory:      readMemory(&r.gc),
 	})
+	r.latencies = r.latencies[:0]
+}
+
+// percentile returns the p-th percentile of sorted latencies, 0 if empty
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	// Nearest rank
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
 }
 
 // GetMetrics returns a copy of all recorded metrics
@@ -49,7 +164,21 @@ func (r *Recorder) GetMetrics() []Metric {
 	return metrics
 }
 
+// Histogram returns the fetch count per LatencyBuckets entry, plus the
+// overflow bucket at the end
+func (r *Recorder) Histogram() []int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]int64(nil), r.histogram...)
+}
+
 // ElapsedSeconds returns the number of seconds since the recorder started
 func (r *Recorder) ElapsedSeconds() float64 {
 	return time.Since(r.start).Seconds()
 }
+
+// Start returns when the recorder started
+func (r *Recorder) Start() time.Time {
+	return r.start
+}
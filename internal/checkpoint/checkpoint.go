@@ -0,0 +1,115 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"web-crawler/internal/dedup"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+)
+
+// log is the logger of the checkpoint package
+var log = logger.For("checkpoint")
+
+// version is bumped when the checkpoint format changes incompatibly
+const version = 1
+
+// Checkpoint is a snapshot of the crawl frontier taken at shutdown
+type Checkpoint struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Queue     []queue.URLItem  `json:"queue"`
+	Visited   []string         `json:"visited,omitempty"`
+	Stats     map[string]int64 `json:"stats,omitempty"`
+}
+
+// Capture drains the remaining queue and collects the visited set. The visited
+// set is left out when the dedup store can't enumerate its keys.
+func Capture(ctx context.Context, q queue.URLQueue, seen *dedup.URLFilter) (*Checkpoint, error) {
+	cp := &Checkpoint{
+		Version:   version,
+		CreatedAt: time.Now(),
+		Queue:     queue.Drain(q),
+	}
+
+	if seen != nil {
+		visited, err := seen.Visited(ctx)
+		switch {
+		case errors.Is(err, dedup.ErrNotListable):
+			log.Warn("Dedup store can't be listed, checkpoint won't include visited URLs")
+		case err != nil:
+			return nil, fmt.Errorf("failed to collect visited urls: %w", err)
+		default:
+			cp.Visited = visited
+		}
+	}
+	return cp, nil
+}
+
+// Save writes the checkpoint to a temp file and renames it into place, so an
+// interrupted write never leaves a truncated checkpoint behind
+func (c *Checkpoint) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+
+	if err := json.NewEncoder(file).Encode(c); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Load reads a checkpoint written by Save
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if cp.Version != version {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", cp.Version)
+	}
+	return &cp, nil
+}
+
+// Restore marks the visited URLs as seen and queues the saved frontier again.
+// Returns the number of queued items.
+func (c *Checkpoint) Restore(ctx context.Context, q queue.URLQueue, seen *dedup.URLFilter) int {
+	if seen != nil {
+		seen.MarkSeen(ctx, c.Visited...)
+	}
+	for _, item := range c.Queue {
+		queue.Repush(q, item)
+	}
+	log.Info("Restored %d queued and %d visited URLs from checkpoint taken at %s",
+		len(c.Queue), len(c.Visited), c.CreatedAt.Format(time.RFC3339))
+	return len(c.Queue)
+}
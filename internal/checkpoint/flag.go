@@ -0,0 +1,15 @@
+package checkpoint
+
+import "flag"
+
+// ResumeFrom adds the -resume-from flag to fs. The returned function loads
+// the checkpoint it names once fs is parsed, and returns nil if it wasn't set.
+func ResumeFrom(fs *flag.FlagSet) func() (*Checkpoint, error) {
+	path := fs.String("resume-from", "", "Restore the frontier from a checkpoint file")
+	return func() (*Checkpoint, error) {
+		if *path == "" {
+			return nil, nil
+		}
+		return Load(*path)
+	}
+}
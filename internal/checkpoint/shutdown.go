@@ -0,0 +1,113 @@
+package checkpoint
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"web-crawler/internal/dedup"
+	"web-crawler/internal/queue"
+)
+
+// NotifyContext returns a context that is canceled on SIGINT or SIGTERM
+func NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+// Shutdown describes the steps of a graceful stop: wait for in-flight
+// requests, flush pending stores, then snapshot the frontier
+type Shutdown struct {
+	InFlight     *sync.WaitGroup               // Tracks requests being processed
+	DrainTimeout time.Duration                 // How long to wait for in-flight requests
+	Flush        []func(context.Context) error // Flushes pending stores, run in order
+	Queue        queue.URLQueue
+	Seen         *dedup.URLFilter
+	Path         string        // Checkpoint file, no checkpoint is written if empty. An empty frontier removes it.
+	StepTimeout  time.Duration // Time each flush and the checkpoint get, DefaultStepTimeout if 0
+}
+
+// DefaultStepTimeout is how long a flush or the checkpoint may take on shutdown
+const DefaultStepTimeout = 30 * time.Second
+
+// Run performs the shutdown steps and returns the first flush or checkpoint
+// error. Each flush and the checkpoint get StepTimeout even if ctx was
+// cancelled by the signal that started the shutdown.
+func (s *Shutdown) Run(ctx context.Context) error {
+	if s.InFlight != nil {
+		log.Info("Waiting for in-flight requests to finish...")
+		if !waitTimeout(s.InFlight, s.DrainTimeout) {
+			log.Warn("In-flight requests still running after %s, continuing shutdown", s.DrainTimeout)
+		}
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	stepTimeout := s.StepTimeout
+	if stepTimeout <= 0 {
+		stepTimeout = DefaultStepTimeout
+	}
+
+	var firstErr error
+	for _, flush := range s.Flush {
+		stepCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+		err := flush(stepCtx)
+		cancel()
+		if err != nil {
+			log.Error("Failed to flush on shutdown: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if s.Path != "" && s.Queue != nil {
+		stepCtx, cancel := context.WithTimeout(ctx, stepTimeout)
+		cp, err := Capture(stepCtx, s.Queue, s.Seen)
+		cancel()
+		if err == nil && len(cp.Queue) == 0 {
+			// A finished crawl leaves nothing to resume
+			if rerr := os.Remove(s.Path); rerr == nil {
+				log.Info("Removed checkpoint %s of the completed crawl", s.Path)
+			}
+			return firstErr
+		}
+		if err == nil {
+			err = cp.Save(s.Path)
+		}
+		if err != nil {
+			log.Error("Failed to write checkpoint: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			log.Success("Checkpoint with %d queued URLs written to %s", len(cp.Queue), s.Path)
+		}
+	}
+
+	return firstErr
+}
+
+// waitTimeout waits for wg and reports whether it finished before the timeout (0 waits forever)
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if timeout <= 0 {
+		<-done
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package checkpoint
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/queue"
+)
+
+func TestShutdownRunsAfterCancel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	q := queue.NewURLQueue()
+
+	// A fetch cut short by the shutdown puts its URL back while the drain waits
+	var inFlight sync.WaitGroup
+	inFlight.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.PushWithPriority("https://example.com/a", queue.PriorityHigh, "example.com", 1)
+		inFlight.Done()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var flushErr error
+	s := &Shutdown{
+		InFlight:     &inFlight,
+		DrainTimeout: time.Second,
+		Flush: []func(context.Context) error{func(ctx context.Context) error {
+			flushErr = ctx.Err()
+			return nil
+		}},
+		Queue: q,
+		Path:  path,
+	}
+	if err := s.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if flushErr != nil {
+		t.Fatalf("flush ran with a cancelled context: %v", flushErr)
+	}
+
+	cp, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Queue) != 1 || cp.Queue[0].URL != "https://example.com/a" || cp.Queue[0].Depth != 1 {
+		t.Fatalf("checkpoint queue = %+v", cp.Queue)
+	}
+}
+
+func TestShutdownRemovesCheckpointOfFinishedCrawl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Shutdown{Queue: queue.NewURLQueue(), Path: path}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint of a finished crawl still exists: %v", err)
+	}
+}
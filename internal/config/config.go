@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,21 +11,75 @@
 
 // Config represents the main configuration structure
 type Config struct {
-	Crawler      CrawlerConfig      `yaml:"crawler"`
-	ContentSaver ContentSaverConfig `yaml:"content_saver"`
-	Storage      StorageConfig      `yaml:"storage"`
-	HTTP         HTTPConfig         `yaml:"http"`
-	Filters      FiltersConfig      `yaml:"filters"`
-	Benchmark    BenchmarkConfig    `yaml:"benchmark"`
+	Crawler      CrawlerConfig            `yaml:"crawler"`
+	Queue        QueueConfig              `yaml:"queue"`
+	ContentSaver ContentSaverConfig       `yaml:"content_saver"`
+	Storage      StorageConfig            `yaml:"storage"`
+	HTTP         HTTPConfig               `yaml:"http"`
+	Filters      FiltersConfig            `yaml:"filters"`
+	Robots       RobotsConfig             `yaml:"robots"`
+	Dedup        DedupConfig              `yaml:"dedup"`
+	API          APIConfig                `yaml:"api"`
+	Recrawl      RecrawlConfig            `yaml:"recrawl"`
+	Checkpoint   CheckpointConfig         `yaml:"checkpoint"`
+	Reload       ReloadConfig             `yaml:"reload"`
+	Logging      LoggingConfig            `yaml:"logging"`
+	Dashboard    DashboardConfig          `yaml:"dashboard"`
+	Telemetry    TelemetryConfig          `yaml:"telemetry"`
+	Debug        DebugConfig              `yaml:"debug"`
+	Benchmark    BenchmarkConfig          `yaml:"benchmark"`
+	Graph        GraphConfig              `yaml:"graph"`
+	Subdomains   SubdomainsConfig         `yaml:"subdomains"`
+	LinkCheck    LinkCheckConfig          `yaml:"link_check"`
+	SEO          SEOConfig                `yaml:"seo"`
+	A11y         AccessibilityConfig      `yaml:"accessibility"`
+	Focus        FocusConfig              `yaml:"focus"`
+	Extraction   ExtractionConfig         `yaml:"extraction"`
+	Documents    DocumentsConfig          `yaml:"documents"`
+	JSON         JSONConfig               `yaml:"json"`
+	GraphQL      GraphQLConfig            `yaml:"graphql"`
+	Domains      map[string]DomainProfile `yaml:"domains"` // Per-domain overrides, keyed by domain
+	Jobs         []JobConfig              `yaml:"jobs"`    // Started by the serve command
+	JobHistory   JobHistoryConfig         `yaml:"job_history"`
 }
 
 // CrawlerConfig holds crawler-specific settings
 type CrawlerConfig struct {
-	Workers   int           `yaml:"workers"`
-	RateLimit time.Duration `yaml:"rate_limit"`
-	Timeout   time.Duration `yaml:"timeout"`
-	MaxDepth  int           `yaml:"max_depth"`
-	MaxPages  int           `yaml:"max_pages"`
+	Workers    int                  `yaml:"workers"`
+	Autoscale  AutoscaleConfig      `yaml:"autoscale"`
+	Parse      ParseConfig          `yaml:"parse"`
+	RateLimit  time.Duration        `yaml:"rate_limit"`
+	Timeout    time.Duration        `yaml:"timeout"`
+	MaxDepth   int                  `yaml:"max_depth"`
+	MaxPages   int                  `yaml:"max_pages"`
+	Frontier   string               `yaml:"frontier"`    // Pop order: priority, breadth_first, or best_first
+	Score      string               `yaml:"score"`       // Expression scoring queued URLs for best_first, empty for the linking page's relevance
+	HostLimits map[string]HostLimit `yaml:"host_limits"` // Keyed by host, "*.domain", or "*"
+	Seeds      []string             `yaml:"seeds"`
+	SeedFile   string               `yaml:"seed_file"` // One "url [priority] [depth]" per line, "-" for stdin
+}
+
+// AutoscaleConfig holds settings for growing and shrinking the worker pool
+type AutoscaleConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	MinWorkers     int           `yaml:"min_workers"`
+	MaxWorkers     int           `yaml:"max_workers"`
+	Interval       time.Duration `yaml:"interval"`         // How often the pool is resized
+	TargetLatency  time.Duration `yaml:"target_latency"`   // Shrink when the average fetch latency is above this
+	QueuePerWorker int           `yaml:"queue_per_worker"` // Grow while more URLs than this are queued per worker
+}
+
+// ParseConfig holds settings for the parse workers, which parse fetched pages
+// so the fetch workers can move on to the next URL
+type ParseConfig struct {
+	Workers int `yaml:"workers"` // 0 parses pages in the fetch workers
+	Queue   int `yaml:"queue"`   // Fetched pages waiting for a parse worker, fetch workers wait while it's full
+}
+
+// HostLimit caps how much of a single host is crawled, 0 means no limit
+type HostLimit struct {
+	MaxPages int `yaml:"max_pages"`
+	MaxDepth int `yaml:"max_depth"`
 }
 
 // GetRateLimit returns the rate limit as a time.Duration
@@ -32,17 +87,217 @@ func (c *CrawlerConfig) GetRateLimit() time.Duration {
 	return c.RateLimit
 }
 
+// QueueConfig holds URL queue settings
+type QueueConfig struct {
+	Backend      string           `yaml:"backend"` // memory or redis
+	Persistent   bool             `yaml:"persistent"`
+	Path         string           `yaml:"path"`
+	SyncInterval time.Duration    `yaml:"sync_interval"`
+	CompactAfter int              `yaml:"compact_after"` // Acks before the queue log is rewritten
+	HostAware    bool             `yaml:"host_aware"`
+	HostDelay    time.Duration    `yaml:"host_delay"`
+	Capacity     int              `yaml:"capacity"`     // URLs held in memory before the overflow policy applies
+	Aging        time.Duration    `yaml:"aging"`        // A queued URL gains one priority level per interval it waits, 0 = never
+	InlinkBoost  int              `yaml:"inlink_boost"` // Priority levels a queued URL gains per further link found to it
+	InstanceID   int              `yaml:"instance_id"`
+	Instances    int              `yaml:"instances"`
+	Redis        RedisConfig      `yaml:"redis"`
+	DeadLetter   DeadLetterConfig `yaml:"dead_letter"`
+	Overflow     OverflowConfig   `yaml:"overflow"` // What the memory and persistent queues do when full
+}
+
+// OverflowConfig holds what happens to URLs pushed while the in-memory queue is full
+type OverflowConfig struct {
+	Policy  string        `yaml:"policy"`  // drop, block, or spill
+	Timeout time.Duration `yaml:"timeout"` // How long block waits for room before dropping
+	Path    string        `yaml:"path"`    // JSON-lines file spill keeps overflowing URLs in
+}
+
+// DeadLetterConfig holds settings for the store of permanently failed URLs
+type DeadLetterConfig struct {
+	Backend    string `yaml:"backend"`    // memory, file, or mongodb
+	Path       string `yaml:"path"`       // JSON-lines file for the file backend
+	Collection string `yaml:"collection"` // Collection for the mongodb backend
+}
+
+// CheckpointConfig holds graceful shutdown and checkpoint settings
+type CheckpointConfig struct {
+	Enabled      bool          `yaml:"enabled"` // Write a checkpoint on SIGINT/SIGTERM
+	Path         string        `yaml:"path"`
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // Max wait for in-flight requests
+}
+
+// ReloadConfig holds settings for applying config file changes to a running crawl
+type ReloadConfig struct {
+	Enabled  bool          `yaml:"enabled"`  // Watch the config file for changes
+	Interval time.Duration `yaml:"interval"` // How often the file is checked
+}
+
+// DashboardConfig holds settings for the live terminal dashboard
+type DashboardConfig struct {
+	Enabled bool          `yaml:"enabled"`  // Show the dashboard instead of log lines
+	Refresh time.Duration `yaml:"refresh"`  // Redraw interval
+	LogFile string        `yaml:"log_file"` // Where logs go while it runs, if logging.file is empty
+}
+
+// TelemetryConfig holds settings for exporting pipeline spans over OTLP/HTTP
+type TelemetryConfig struct {
+	Enabled       bool              `yaml:"enabled"`
+	Endpoint      string            `yaml:"endpoint"`       // OTLP/HTTP traces URL, e.g. Jaeger or a collector
+	ServiceName   string            `yaml:"service_name"`   // service.name of the exported spans
+	SampleRate    float64           `yaml:"sample_rate"`    // Fraction of pages traced, 0-1
+	BatchSize     int               `yaml:"batch_size"`     // Spans per export request
+	FlushInterval time.Duration     `yaml:"flush_interval"` // Max wait before a partial batch is sent
+	Headers       map[string]string `yaml:"headers"`        // Extra request headers, e.g. for auth
+}
+
+// DebugConfig holds settings for the pprof and expvar endpoints
+type DebugConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Addr          string `yaml:"addr"`           // Listen address, keep it private since it exposes the process internals
+	BlockRate     int    `yaml:"block_rate"`     // runtime.SetBlockProfileRate, 0 leaves the block profile empty
+	MutexFraction int    `yaml:"mutex_fraction"` // runtime.SetMutexProfileFraction, 0 leaves the mutex profile empty
+}
+
+// LoggingConfig holds log format, level, and output settings
+type LoggingConfig struct {
+	Format     string            `yaml:"format"`      // console or json
+	Level      string            `yaml:"level"`       // debug, info, warn, or error
+	File       string            `yaml:"file"`        // Log file, stdout if empty
+	MaxSize    int64             `yaml:"max_size"`    // Bytes before the file is rotated, 0 = never
+	MaxBackups int               `yaml:"max_backups"` // Rotated files to keep
+	Modules    map[string]string `yaml:"modules"`     // Per-module levels, e.g. fetcher: debug
+	Trace      TraceConfig       `yaml:"trace"`
+}
+
+// TraceConfig holds settings for the per-URL lifecycle trace
+type TraceConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Path    string   `yaml:"path"`  // JSON-lines file, appended to
+	Match   []string `yaml:"match"` // Regexes, only matching URLs are traced if set
+}
+
 // ContentSaverConfig holds content saving settings
 type ContentSaverConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	OutputDir   string `yaml:"output_dir"`
-	MaxFileSize int64  `yaml:"max_file_size"`
-	SaveMeta    bool   `yaml:"save_metadata"`
+	Enabled     bool         `yaml:"enabled"`
+	OutputDir   string       `yaml:"output_dir"`
+	MaxFileSize int64        `yaml:"max_file_size"`
+	SaveMeta    bool         `yaml:"save_metadata"`
+	Assets      AssetsConfig `yaml:"assets"`
+
+	// Format of saved pages: html (with a metadata comment), raw, json
+	// (metadata fields and content), mhtml (single file with stylesheets,
+	// scripts and images) or warc (WARC 1.1 records)
+	Format          string `yaml:"format"`
+	MaxResourceSize int64  `yaml:"max_resource_size"` // Largest resource embedded in MHTML files or mirrored
+
+	// Archive writes pages into compressed archives instead of separate
+	// files: "" (files), zip or tar.gz, one per run or per domain
+	Archive    string `yaml:"archive"`
+	ArchivePer string `yaml:"archive_per"`
+
+	Retention RetentionConfig `yaml:"retention"`
+	Mirror    MirrorConfig    `yaml:"mirror"`
+}
+
+// MirrorConfig holds settings for an offline copy of the crawled sites, with
+// links rewritten to relative paths and the resources pages need downloaded
+type MirrorConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Dir          string `yaml:"dir"`           // Relative to the content output directory
+	MaxResources int    `yaml:"max_resources"` // Resources downloaded per page, stylesheet references included
+}
+
+// RetentionConfig holds the limits a background janitor enforces on saved
+// pages, deleting the oldest first. Zero limits are off.
+type RetentionConfig struct {
+	Enabled           bool          `yaml:"enabled"`
+	MaxTotalSize      int64         `yaml:"max_total_size"`       // Bytes of all saved pages
+	MaxFilesPerDomain int           `yaml:"max_files_per_domain"` // Saved pages per domain
+	MaxAge            time.Duration `yaml:"max_age"`              // Age of a saved page
+	Interval          time.Duration `yaml:"interval"`             // Time between sweeps
+	DryRun            bool          `yaml:"dry_run"`              // Only log what would be deleted
+}
+
+// AssetsConfig holds settings for downloading non-HTML assets referenced by pages
+type AssetsConfig struct {
+	Enabled  bool             `yaml:"enabled"`
+	Dir      string           `yaml:"dir"`       // Relative to the content output directory
+	MaxSizes map[string]int64 `yaml:"max_sizes"` // Media type or wildcard (image/*) -> max bytes, other types are skipped
 }
 
 // StorageConfig holds storage-related settings
 type StorageConfig struct {
+	// Optional page fields to persist: content (raw HTML), text (cleaned
+	// plain text), article (main text, title, author and date without
+	// boilerplate), links and headers. URL, title, status and other metadata
+	// are always stored.
+	Fields  []string      `yaml:"fields"`
 	MongoDB MongoDBConfig `yaml:"mongodb"`
+	Search  SearchConfig  `yaml:"search"`
+	Publish PublishConfig `yaml:"publish"`
+	Object  ObjectConfig  `yaml:"object"`
+	Raw     RawConfig     `yaml:"raw"`
+}
+
+// RawConfig holds settings for archiving the exact bytes of every response,
+// before content decoding, with its request
+type RawConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Backend string `yaml:"backend"` // file or gridfs
+	Dir     string `yaml:"dir"`     // Directory of the file backend
+	Bucket  string `yaml:"bucket"`  // GridFS bucket of the gridfs backend
+}
+
+// ObjectConfig holds settings for archiving pages to S3 or GCS. GCS is used
+// through its S3-compatible XML API with HMAC keys.
+type ObjectConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Provider  string `yaml:"provider"`   // s3 or gcs, selects the default endpoint and region
+	Endpoint  string `yaml:"endpoint"`   // API URL, e.g. for MinIO; derived from provider and region if empty
+	Region    string `yaml:"region"`     // Signing region, us-east-1 for s3 and auto for gcs if empty
+	Bucket    string `yaml:"bucket"`     // Must exist
+	Prefix    string `yaml:"prefix"`     // Prepended to every key
+	PathStyle bool   `yaml:"path_style"` // Bucket in the path instead of the host name
+
+	// Credentials, read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN if empty
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+
+	Raw                bool          `yaml:"raw"`                 // Also store the raw response body next to the page
+	MultipartThreshold int64         `yaml:"multipart_threshold"` // Objects from this size on are uploaded in parts
+	PartSize           int64         `yaml:"part_size"`           // At least 5 MiB
+	Timeout            time.Duration `yaml:"timeout"`             // Per request
+	MaxRetries         int           `yaml:"max_retries"`         // Retries of failed requests, 5xx and 429 answers
+}
+
+// PublishConfig holds settings for streaming stored pages to Kafka or NATS
+type PublishConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Backend string   `yaml:"backend"` // kafka or nats
+	Brokers []string `yaml:"brokers"` // Kafka bootstrap brokers, host:port
+	URL     string   `yaml:"url"`     // NATS server, nats://[user:pass@]host:port
+	Topic   string   `yaml:"topic"`   // Kafka topic, or NATS subject prefix followed by the host
+	Format  string   `yaml:"format"`  // Message encoding: json or avro
+
+	// Pages are published in the background, a batch when it holds BatchSize
+	// pages or FlushInterval after its first page
+	BatchSize       int           `yaml:"batch_size"`
+	FlushInterval   time.Duration `yaml:"flush_interval"`
+	BufferSize      int           `yaml:"buffer_size"`       // Pages waiting to be published before Store blocks
+	MaxMessageBytes int           `yaml:"max_message_bytes"` // Largest Kafka record batch, the broker's message.max.bytes
+	Timeout         time.Duration `yaml:"timeout"`
+	MaxRetries      int           `yaml:"max_retries"`   // Retries of undelivered pages, with doubling backoff
+	RetryBackoff    time.Duration `yaml:"retry_backoff"` // Wait before the first retry
+	FailedPath      string        `yaml:"failed_path"`   // JSON-lines file for pages that could not be delivered, dropped if empty
+}
+
+// SearchConfig holds settings for the local full-text index of stored pages
+type SearchConfig struct {
+	Enabled bool   `yaml:"enabled"` // Index title, text and URL of every stored page
+	Path    string `yaml:"path"`    // Index directory, queried with the search command
 }
 
 // MongoDBConfig holds MongoDB-specific settings
@@ -53,22 +308,232 @@ type MongoDBConfig struct {
 	MaxPoolSize uint64        `yaml:"max_pool_size"`
 	MinPoolSize uint64        `yaml:"min_pool_size"`
 	MaxIdleTime time.Duration `yaml:"max_idle_time"`
+
+	// Pages are upserted in the background with BulkWrite. A batch is written
+	// when it holds BatchSize pages or FlushInterval after its first page;
+	// BatchSize 1 writes every page synchronously.
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	BufferSize    int           `yaml:"buffer_size"` // Pages waiting for a write before Store blocks
+
+	Compression string `yaml:"compression"` // Page content encoding: none, gzip or zstd
+
+	ChangeHistory int `yaml:"change_history"` // Content changes kept per page, 0 keeps none
 }
 
 // HTTPConfig holds HTTP client settings
 type HTTPConfig struct {
-	UserAgent      string        `yaml:"user_agent"`
-	FollowRedirect bool          `yaml:"follow_redirects"`
-	MaxRedirects   int           `yaml:"max_redirects"`
-	Timeout        time.Duration `yaml:"timeout"`
+	UserAgent           string          `yaml:"user_agent"`
+	FollowRedirect      bool            `yaml:"follow_redirects"`
+	MaxRedirects        int             `yaml:"max_redirects"`
+	CrossDomainRedirect bool            `yaml:"cross_domain_redirects"` // Follow redirects to another registrable domain
+	HTTPSDowngrade      bool            `yaml:"https_downgrades"`       // Follow redirects from HTTPS to HTTP
+	Timeout             time.Duration   `yaml:"timeout"`
+	ConditionalRequests bool            `yaml:"conditional_requests"`
+	Proxy               string          `yaml:"proxy"`              // http://, https://, or socks5:// URL
+	ProxyFile           string          `yaml:"proxy_file"`         // One proxy per line
+	ProxyRotation       string          `yaml:"proxy_rotation"`     // round_robin, sticky, or failure_aware
+	ProxyMaxFailures    int             `yaml:"proxy_max_failures"` // Consecutive failures before cooldown
+	ProxyCooldown       time.Duration   `yaml:"proxy_cooldown"`
+	AllowedContentTypes []string        `yaml:"allowed_content_types"` // Media types to download, e.g. text/html or text/*
+	MaxBodySize         int64           `yaml:"max_body_size"`         // Bytes, 0 = unlimited
+	SniffContent        bool            `yaml:"sniff_content"`         // Parse mislabeled bodies by the type detected from their content
+	Render              RenderConfig    `yaml:"render"`
+	Cache               CacheConfig     `yaml:"cache"`
+	Retry               RetryConfig     `yaml:"retry"`
+	DNS                 DNSConfig       `yaml:"dns"`
+	Transport           TransportConfig `yaml:"transport"`
+	TLS                 TLSConfig       `yaml:"tls"`
+	Login               []LoginConfig   `yaml:"login"` // Sites logged in to before crawling
+}
+
+// TLSConfig holds settings for recording and checking TLS certificates
+type TLSConfig struct {
+	Capture         bool          `yaml:"capture"`          // Record the connection and certificate of each host
+	Output          string        `yaml:"output"`           // JSON report written when the crawl ends, none if empty
+	ExpiryWarning   time.Duration `yaml:"expiry_warning"`   // Warn about certificates expiring within
+	InsecureDomains []string      `yaml:"insecure_domains"` // Accept invalid certificates of these domains and their subdomains
+}
+
+// LoginConfig logs in to a site before the crawl so that its authenticated
+// area can be crawled. The session cookies are only kept for the domain.
+type LoginConfig struct {
+	Domain string            `yaml:"domain"` // Host the session cookies are sent to, subdomains included
+	URL    string            `yaml:"url"`    // Page with the login form, or where the fields are posted without form
+	Form   string            `yaml:"form"`   // CSS selector of the form on the page at url
+	Fields map[string]string `yaml:"fields"` // Credentials and other fields, ${VAR} expanded from the environment
+	Script []string          `yaml:"script"` // Program logging in instead, e.g. with a headless browser, printing cookies
+	Check  string            `yaml:"check"`  // Text the response to the login must contain
+}
+
+// TransportConfig holds HTTP connection pool and protocol settings
+type TransportConfig struct {
+	HTTP2               bool          `yaml:"http2"`                   // Negotiate HTTP/2 with TLS servers that offer it
+	MaxIdleConns        int           `yaml:"max_idle_conns"`          // Idle connections kept across all hosts, 0 = unlimited
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"` // Idle connections kept per host
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`      // Connections per host, 0 = unlimited
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`       // How long an idle connection is kept, 0 = forever
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout"`   // 0 = no limit
+	KeepAlive           time.Duration `yaml:"keep_alive"`              // TCP keep-alive probe interval, negative = off
+	DisableKeepAlives   bool          `yaml:"disable_keep_alives"`     // Open a new connection for every request
+}
+
+// DNSConfig holds settings of the in-process DNS cache and its upstream resolvers
+type DNSConfig struct {
+	Cache       bool          `yaml:"cache"`        // Cache resolved addresses for their TTL
+	Servers     []string      `yaml:"servers"`      // host[:port], tcp://host[:port] or https:// DoH URLs, empty = system resolver
+	MinTTL      time.Duration `yaml:"min_ttl"`      // Floor of record TTLs
+	MaxTTL      time.Duration `yaml:"max_ttl"`      // Ceiling of record TTLs, 0 = none
+	DefaultTTL  time.Duration `yaml:"default_ttl"`  // TTL of system resolver answers, which don't carry one
+	NegativeTTL time.Duration `yaml:"negative_ttl"` // How long failed lookups are cached
+	Timeout     time.Duration `yaml:"timeout"`      // Per query to an upstream server
+}
+
+// RetryConfig holds retry settings for transient fetch failures
+type RetryConfig struct {
+	MaxAttempts   int           `yaml:"max_attempts"` // Including the first request
+	BaseDelay     time.Duration `yaml:"base_delay"`   // Doubled on every retry
+	MaxDelay      time.Duration `yaml:"max_delay"`
+	Jitter        float64       `yaml:"jitter"`      // Fraction of the delay randomized, 0-1
+	HostBudget    int           `yaml:"host_budget"` // Retries allowed per host per window, 0 = unlimited
+	BudgetWindow  time.Duration `yaml:"budget_window"`
+	RetryStatuses []int         `yaml:"retry_statuses"` // HTTP statuses treated as transient
+}
+
+// CacheConfig holds on-disk HTTP response cache settings
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Dir     string        `yaml:"dir"`
+	TTL     time.Duration `yaml:"ttl"` // 0 = entries never expire
+}
+
+// RenderConfig holds headless browser rendering settings for JavaScript-heavy sites
+type RenderConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Render pages of every domain
+	Domains       []string      `yaml:"domains"`        // Render only these domains and their subdomains
+	Exclude       []string      `yaml:"exclude"`        // Never render these domains and their subdomains
+	BrowserPath   string        `yaml:"browser_path"`   // Chrome/Chromium binary, searched in PATH if empty
+	MaxConcurrent int           `yaml:"max_concurrent"` // Browser processes running at once
+	Timeout       time.Duration `yaml:"timeout"`
+	Budget        time.Duration `yaml:"budget"`     // Virtual time scripts get to run before the DOM is dumped
+	NoSandbox     bool          `yaml:"no_sandbox"` // Needed when running as root in containers
 }
 
 // FiltersConfig holds URL filtering settings
 type FiltersConfig struct {
-	AllowedDomains     []string `yaml:"allowed_domains"`
-	ExcludedPaths      []string `yaml:"excluded_paths"`
-	AllowedSchemes     []string `yaml:"allowed_schemes"`
-	ExcludedExtensions []string `yaml:"excluded_extensions"`
+	AllowedDomains     []string         `yaml:"allowed_domains"` // Hosts or "*.domain", the seed hosts if empty
+	Scope              string           `yaml:"scope"`           // host, or domain to widen them to their registrable domains
+	ExcludedPaths      []string         `yaml:"excluded_paths"`
+	AllowedSchemes     []string         `yaml:"allowed_schemes"`
+	ExcludedExtensions []string         `yaml:"excluded_extensions"`
+	RateLimits         RateLimitsConfig `yaml:"rate_limits"`
+	SkipLinkRels       []string         `yaml:"skip_link_rels"`   // e.g. nofollow, ugc, sponsored
+	IncludePatterns    []string         `yaml:"include_patterns"` // Regexes, URL must match one if set
+	ExcludePatterns    []string         `yaml:"exclude_patterns"` // Regexes, URL must match none
+	Languages          []string         `yaml:"languages"`        // ISO 639-1 codes, pages in other languages are dropped if set
+	Traps              TrapConfig       `yaml:"traps"`
+	Lists              DomainListConfig `yaml:"lists"`
+}
+
+// DomainListConfig holds block and allow lists of domains, loaded from
+// files or http(s) URLs. An entry covers the domain and its subdomains.
+type DomainListConfig struct {
+	Block   []string      `yaml:"block"`   // Lists of domains never crawled, e.g. ad and tracker lists
+	Allow   []string      `yaml:"allow"`   // Lists of domains crawled besides allowed_domains or the seed hosts
+	Refresh time.Duration `yaml:"refresh"` // How often the lists are loaded again, 0 = only at startup
+	Timeout time.Duration `yaml:"timeout"` // For downloading a list
+}
+
+// TrapConfig holds crawler trap detection thresholds, 0 disables a check
+type TrapConfig struct {
+	MaxURLLength      int `yaml:"max_url_length"`
+	MaxQueryParams    int `yaml:"max_query_params"`
+	MaxSegmentRepeats int `yaml:"max_segment_repeats"` // Times one path segment may repeat
+	CalendarYearRange int `yaml:"calendar_year_range"` // Years into the past a date in the URL may be
+	MaxPageNumber     int `yaml:"max_page_number"`
+	MaxQueryVariants  int `yaml:"max_query_variants"` // Distinct query strings per path
+}
+
+// RateLimitsConfig holds per-domain rate limiting settings
+type RateLimitsConfig struct {
+	Default  RateLimitRule            `yaml:"default"`
+	Domains  map[string]RateLimitRule `yaml:"domains"`
+	Adaptive AdaptiveRateConfig       `yaml:"adaptive"`
+	Breaker  CircuitBreakerConfig     `yaml:"circuit_breaker"`
+}
+
+// CircuitBreakerConfig holds settings for cutting off failing hosts. An open
+// circuit holds back the URLs of its host for the cooldown, then lets one
+// probe request through: success closes it, failure opens it again.
+type CircuitBreakerConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	ConsecutiveFailures int           `yaml:"consecutive_failures"` // Failures in a row that open the circuit, 0 = never
+	ErrorRate           float64       `yaml:"error_rate"`           // Share of failures in the window that opens the circuit, 0 = never
+	Window              int           `yaml:"window"`               // Latest requests the error rate is taken over
+	Cooldown            time.Duration `yaml:"cooldown"`             // How long an open circuit holds back its host
+}
+
+// AdaptiveRateConfig holds settings for adjusting rates from server responses
+type AdaptiveRateConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	MinRate        float64       `yaml:"min_rate"`        // Floor in requests per second
+	MaxRate        float64       `yaml:"max_rate"`        // Ceiling for hosts without a configured rate
+	FastResponse   time.Duration `yaml:"fast_response"`   // Responses faster than this speed the host up
+	DecreaseFactor float64       `yaml:"decrease_factor"` // Multiplier applied on 429/503
+	IncreaseFactor float64       `yaml:"increase_factor"` // Multiplier applied on fast responses
+}
+
+// RateLimitRule is a token bucket rate for one domain
+type RateLimitRule struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"` // 0 = unlimited
+	Burst             int     `yaml:"burst"`
+}
+
+// RobotsConfig holds robots.txt settings
+type RobotsConfig struct {
+	Ignore     bool          `yaml:"ignore"`
+	IgnoreMeta bool          `yaml:"ignore_meta"` // Ignore robots meta tags and X-Robots-Tag
+	CacheTTL   time.Duration `yaml:"cache_ttl"`
+	MaxSize    int64         `yaml:"max_size"`
+}
+
+// DedupConfig holds URL deduplication settings
+type DedupConfig struct {
+	Backend           string      `yaml:"backend"` // memory, bloom, or redis
+	ExpectedItems     uint64      `yaml:"expected_items"`
+	FalsePositiveRate float64     `yaml:"false_positive_rate"`
+	Redis             RedisConfig `yaml:"redis"`
+	ContentEnabled    bool        `yaml:"content_enabled"`
+	MaxDistance       int         `yaml:"max_distance"`
+}
+
+// RedisConfig holds Redis connection settings
+type RedisConfig struct {
+	Addr      string        `yaml:"addr"`
+	Password  string        `yaml:"password"`
+	DB        int           `yaml:"db"`
+	PoolSize  int           `yaml:"pool_size"`
+	Timeout   time.Duration `yaml:"timeout"`
+	KeyPrefix string        `yaml:"key_prefix"`
+}
+
+// APIConfig holds control API settings
+type APIConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Addr     string `yaml:"addr"`
+	UI       bool   `yaml:"ui"`        // Serve the web dashboard at /
+	GRPCAddr string `yaml:"grpc_addr"` // Serve the gRPC API of api/proto/crawler.proto, empty disables it
+}
+
+// RecrawlConfig holds periodic refresh settings
+type RecrawlConfig struct {
+	Enabled       bool                     `yaml:"enabled"`
+	Interval      time.Duration            `yaml:"interval"`
+	Domains       map[string]time.Duration `yaml:"domains"` // Per-domain interval overrides
+	Adaptive      bool                     `yaml:"adaptive"`
+	MinInterval   time.Duration            `yaml:"min_interval"`
+	MaxInterval   time.Duration            `yaml:"max_interval"`
+	CheckInterval time.Duration            `yaml:"check_interval"`
 }
 
 // BenchmarkConfig holds benchmark settings
@@ -76,40 +541,304 @@ type BenchmarkConfig struct {
 	Enabled   bool          `yaml:"enabled"`
 	Interval  time.Duration `yaml:"interval"`
 	OutputDir string        `yaml:"output_dir"`
+	Formats   []string      `yaml:"formats"` // png, html
+	Summary   bool          `yaml:"summary"` // Write the crawl summary next to the graphs
+	Runs      RunsConfig    `yaml:"runs"`    // Where runs are kept for bench compare
+}
+
+// RunsConfig holds settings for keeping benchmark runs to compare them later
+type RunsConfig struct {
+	Backend    string `yaml:"backend"`    // none, dir, or mongodb
+	Dir        string `yaml:"dir"`        // One <id>.json per run for the dir backend
+	Collection string `yaml:"collection"` // Collection for the mongodb backend
+}
+
+// GraphConfig holds settings for the link graph recorded during a crawl
+type GraphConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	OutputDir  string   `yaml:"output_dir"`
+	Formats    []string `yaml:"formats"`   // csv, graphml, dot
+	MaxNodes   int      `yaml:"max_nodes"` // Links to further URLs are dropped
+	Damping    float64  `yaml:"damping"`
+	Iterations int      `yaml:"iterations"` // PageRank iterations at most
+
+	// Reprioritize the queue by PageRank every prioritize_interval
+	Prioritize         bool          `yaml:"prioritize"`
+	PrioritizeInterval time.Duration `yaml:"prioritize_interval"`
+}
+
+// SubdomainsConfig holds settings for recording the hosts links point to
+type SubdomainsConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Output   string `yaml:"output"`    // JSON report written when the crawl ends, none if empty
+	MaxHosts int    `yaml:"max_hosts"` // Further hosts are not recorded, 0 = no limit
+}
+
+// LinkCheckConfig holds settings for reporting broken links
+type LinkCheckConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	OutputDir     string   `yaml:"output_dir"`
+	Formats       []string `yaml:"formats"`        // json, html
+	CheckFiltered bool     `yaml:"check_filtered"` // Also check links the filters don't crawl, with HEAD requests
+	Workers       int      `yaml:"workers"`        // Checkers of filtered links
+	MaxReferrers  int      `yaml:"max_referrers"`  // Pages recorded per broken link
+}
+
+// SEOConfig holds settings for the SEO audit of crawled pages
+type SEOConfig struct {
+	Enabled              bool     `yaml:"enabled"`
+	OutputDir            string   `yaml:"output_dir"`
+	Formats              []string `yaml:"formats"`                // html, csv
+	MaxTitleLength       int      `yaml:"max_title_length"`       // Characters
+	MaxDescriptionLength int      `yaml:"max_description_length"` // Characters
+	MinWords             int      `yaml:"min_words"`              // Pages with fewer words are thin
+	MaxRedirects         int      `yaml:"max_redirects"`          // More hops to reach a page make a redirect chain
+	MaxPages             int      `yaml:"max_pages"`              // Further pages are not audited, 0 = no limit
+}
+
+// AccessibilityConfig holds settings for collecting accessibility signals
+type AccessibilityConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	OutputDir string   `yaml:"output_dir"`
+	Formats   []string `yaml:"formats"`   // json, csv
+	MaxPages  int      `yaml:"max_pages"` // Pages listed in the report at most, 0 = no limit
+}
+
+// FocusConfig holds settings for crawling by topic relevance
+type FocusConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Keywords  []string `yaml:"keywords"`  // Words or phrases, scored by the share found on a page
+	Topic     string   `yaml:"topic"`     // Description, scored by TF-IDF similarity
+	Threshold float64  `yaml:"threshold"` // Score from which a page is relevant and its links high priority
+	MinScore  float64  `yaml:"min_score"` // Links of pages scoring below are not followed
+}
+
+// DocumentsConfig holds settings for crawling PDF and Word documents as
+// pages whose content is their extracted text
+type DocumentsConfig struct {
+	Enabled  bool                `yaml:"enabled"`
+	Types    []string            `yaml:"types"`    // Media types to extract, each needing a built-in extractor or a command
+	Commands map[string][]string `yaml:"commands"` // Media type -> program reading a document on stdin and writing its text
+	Timeout  time.Duration       `yaml:"timeout"`  // Per document
+}
+
+// JSONConfig holds settings for crawling JSON API responses as pages, for
+// sites whose content is loaded by XHR
+type JSONConfig struct {
+	Enabled bool       `yaml:"enabled"`
+	Types   []string   `yaml:"types"` // Media types parsed as JSON, besides any +json type
+	Rules   []JSONRule `yaml:"rules"`
+}
+
+// JSONRule picks the title and the links to crawl of JSON responses with
+// JSONPath expressions
+type JSONRule struct {
+	URL   string   `yaml:"url"`   // Regular expression of the response URLs, all if empty
+	Links []string `yaml:"links"` // Expressions selecting URLs, relative ones resolved against the response
+	Title string   `yaml:"title"` // Expression selecting the title
 }
 
-// LoadConfig loads configuration from a YAML file
+// GraphQLConfig holds settings for detecting GraphQL endpoints on crawled
+// hosts and recording their schema
+type GraphQLConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Paths      []string      `yaml:"paths"`      // Probed once on every crawled host
+	Introspect bool          `yaml:"introspect"` // Run the introspection query on detected endpoints
+	Samples    int           `yaml:"samples"`    // Root query fields without required arguments to run, 0 for none
+	Timeout    time.Duration `yaml:"timeout"`    // Per request
+}
+
+// ExtractionConfig holds user-defined rules that extract fields from pages
+type ExtractionConfig struct {
+	Rules []ExtractionRule `yaml:"rules"`
+}
+
+// ExtractionRule extracts one field with a CSS selector or an XPath expression
+type ExtractionRule struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`      // Regular expression of the page URLs, all pages if empty
+	Selector string `yaml:"selector"` // CSS selector
+	XPath    string `yaml:"xpath"`    // XPath expression, instead of a selector
+	Attr     string `yaml:"attr"`     // Attribute to read instead of the text
+	All      bool   `yaml:"all"`      // Every match as a list instead of the first
+}
+
+// JobConfig describes a named crawl job run next to others in one process.
+// Unset fields take the value of the main configuration. It is also the
+// body of POST /jobs.
+type JobConfig struct {
+	ID              string   `yaml:"id" json:"id"` // Letters, digits, - and _
+	Seeds           []string `yaml:"seeds" json:"seeds"`
+	AllowedDomains  []string `yaml:"allowed_domains" json:"allowed_domains,omitempty"`
+	IncludePatterns []string `yaml:"include_patterns" json:"include_patterns,omitempty"`
+	ExcludePatterns []string `yaml:"exclude_patterns" json:"exclude_patterns,omitempty"`
+	Workers         int      `yaml:"workers" json:"workers,omitempty"`
+	MaxDepth        int      `yaml:"max_depth" json:"max_depth,omitempty"`
+	MaxPages        int      `yaml:"max_pages" json:"max_pages,omitempty"`
+	Collection      string   `yaml:"collection" json:"collection,omitempty"` // MongoDB collection, <collection>_<id> if empty
+	Schedule        string   `yaml:"schedule" json:"schedule,omitempty"`     // Cron expression, runs once right away if empty
+}
+
+// JobHistoryConfig holds where the runs of crawl jobs are recorded
+type JobHistoryConfig struct {
+	Backend    string `yaml:"backend"`    // file or mongodb
+	Path       string `yaml:"path"`       // JSON-lines file for the file backend
+	Collection string `yaml:"collection"` // Collection for the mongodb backend
+}
+
+// LoadConfig loads configuration from a YAML file on top of the defaults and
+// validates it, reporting invalid settings with their line in the file
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return &config, nil
+	// Start from the defaults so settings missing from the file keep sane values
+	config := DefaultConfig()
+	if len(root.Content) > 0 {
+		if err := root.Decode(config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			annotateLines(verr, &root)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config.ApplyDomains()
+
+	return config, nil
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
 		Crawler: CrawlerConfig{
-			Workers:   5,
-			RateLimit: 500 * time.Millisecond,
-			Timeout:   30 * time.Second,
-			MaxDepth:  10,
-			MaxPages:  1000,
+			Workers: 5,
+			Autoscale: AutoscaleConfig{
+				Enabled:        false,
+				MinWorkers:     2,
+				MaxWorkers:     100,
+				Interval:       5 * time.Second,
+				TargetLatency:  2 * time.Second,
+				QueuePerWorker: 10,
+			},
+			Parse: ParseConfig{
+				Workers: 4,
+				Queue:   100,
+			},
+			RateLimit:  500 * time.Millisecond,
+			Timeout:    30 * time.Second,
+			MaxDepth:   10,
+			MaxPages:   1000,
+			Frontier:   "priority",
+			HostLimits: map[string]HostLimit{},
+			Seeds:      []string{},
+		},
+		Queue: QueueConfig{
+			Backend:      "memory",
+			Persistent:   false,
+			Path:         "queue_data/frontier.log",
+			SyncInterval: 1 * time.Second,
+			CompactAfter: 10000,
+			HostAware:    false,
+			HostDelay:    1 * time.Second,
+			Capacity:     32000,
+			Aging:        10 * time.Minute,
+			InlinkBoost:  0,
+			InstanceID:   0,
+			Instances:    1,
+			Redis: RedisConfig{
+				Addr:      "localhost:6379",
+				PoolSize:  10,
+				Timeout:   5 * time.Second,
+				KeyPrefix: "webcrawler:",
+			},
+			DeadLetter: DeadLetterConfig{
+				Backend:    "memory",
+				Path:       "queue_data/dead_letters.jsonl",
+				Collection: "dead_letters",
+			},
+			Overflow: OverflowConfig{
+				Policy:  "drop",
+				Timeout: 5 * time.Second,
+				Path:    "queue_data/spill.jsonl",
+			},
+		},
+		Checkpoint: CheckpointConfig{
+			Enabled:      true,
+			Path:         "queue_data/checkpoint.json",
+			DrainTimeout: 30 * time.Second,
+		},
+		Reload: ReloadConfig{
+			Enabled:  true,
+			Interval: 2 * time.Second,
+		},
+		Telemetry: TelemetryConfig{
+			Enabled:       false,
+			Endpoint:      "http://localhost:4318/v1/traces",
+			ServiceName:   "web-crawler",
+			SampleRate:    0.01,
+			BatchSize:     512,
+			FlushInterval: 5 * time.Second,
+		},
+		Debug: DebugConfig{
+			Enabled: false,
+			Addr:    "127.0.0.1:6060",
+		},
+		Dashboard: DashboardConfig{
+			Refresh: 500 * time.Millisecond,
+			LogFile: "logs/crawler.log",
+		},
+		Logging: LoggingConfig{
+			Format:     "console",
+			Level:      "info",
+			MaxSize:    100 * 1024 * 1024, // 100MB
+			MaxBackups: 5,
+			Modules:    map[string]string{},
+			Trace: TraceConfig{
+				Enabled: false,
+				Path:    "logs/trace.jsonl",
+				Match:   []string{},
+			},
 		},
 		ContentSaver: ContentSaverConfig{
-			Enabled:     false,
-			OutputDir:   "crawled_content",
-			MaxFileSize: 5242880, // 5MB
-			SaveMeta:    true,
+			Enabled:         false,
+			OutputDir:       "crawled_content",
+			MaxFileSize:     5242880, // 5MB
+			SaveMeta:        true,
+			Format:          "html",
+			MaxResourceSize: 2 * 1024 * 1024, // 2MB
+			ArchivePer:      "run",
+			Retention: RetentionConfig{
+				Enabled:  false,
+				Interval: 10 * time.Minute,
+			},
+			Mirror: MirrorConfig{
+				Enabled:      false,
+				Dir:          "mirror",
+				MaxResources: 100,
+			},
+			Assets: AssetsConfig{
+				Enabled: false,
+				Dir:     "assets",
+				MaxSizes: map[string]int64{
+					"image/*":         5 * 1024 * 1024,  // 5MB
+					"application/pdf": 20 * 1024 * 1024, // 20MB
+				},
+			},
 		},
 		Storage: StorageConfig{
+			Fields: []string{"content", "links"},
 			MongoDB: MongoDBConfig{
 				Database:    "webcrawler",
 				Collection:  "webpages",
@@ -117,16 +846,116 @@ func DefaultConfig() *Config {
 				MaxPoolSize: 50,
 				MinPoolSize: 10,
 				MaxIdleTime: 5 * time.Minute,
+
+				BatchSize:     100,
+				FlushInterval: time.Second,
+				BufferSize:    1000,
+
+				Compression: "none",
+
+				ChangeHistory: 20,
+			},
+			Search: SearchConfig{
+				Enabled: false,
+				Path:    "search_index",
+			},
+			Publish: PublishConfig{
+				Enabled:         false,
+				Backend:         "kafka",
+				Brokers:         []string{"localhost:9092"},
+				URL:             "nats://localhost:4222",
+				Topic:           "pages",
+				Format:          "json",
+				BatchSize:       100,
+				FlushInterval:   time.Second,
+				BufferSize:      1000,
+				MaxMessageBytes: 1000000,
+				Timeout:         10 * time.Second,
+				MaxRetries:      3,
+				RetryBackoff:    500 * time.Millisecond,
+				FailedPath:      "queue_data/publish_failed.jsonl",
+			},
+			Object: ObjectConfig{
+				Enabled:            false,
+				Provider:           "s3",
+				Prefix:             "pages",
+				MultipartThreshold: 16 << 20,
+				PartSize:           8 << 20,
+				Timeout:            time.Minute,
+				MaxRetries:         3,
+			},
+			Raw: RawConfig{
+				Enabled: false,
+				Backend: "file",
+				Dir:     "raw_responses",
+				Bucket:  "raw_responses",
 			},
 		},
 		HTTP: HTTPConfig{
-			UserAgent:      "GoWebCrawler/1.0",
-			FollowRedirect: true,
-			MaxRedirects:   10,
-			Timeout:        30 * time.Second,
+			UserAgent:           "GoWebCrawler/1.0",
+			FollowRedirect:      true,
+			MaxRedirects:        10,
+			CrossDomainRedirect: true,
+			HTTPSDowngrade:      true,
+			Timeout:             30 * time.Second,
+			ConditionalRequests: true,
+			ProxyRotation:       "round_robin",
+			ProxyMaxFailures:    3,
+			ProxyCooldown:       1 * time.Minute,
+			AllowedContentTypes: []string{"text/html", "application/xhtml+xml"},
+			MaxBodySize:         10 * 1024 * 1024, // 10MB
+			SniffContent:        true,
+			Render: RenderConfig{
+				Enabled:       false,
+				Domains:       []string{},
+				Exclude:       []string{},
+				MaxConcurrent: 2,
+				Timeout:       30 * time.Second,
+				Budget:        5 * time.Second,
+			},
+			Cache: CacheConfig{
+				Enabled: false,
+				Dir:     "http_cache",
+				TTL:     24 * time.Hour,
+			},
+			Retry: RetryConfig{
+				MaxAttempts:   3,
+				BaseDelay:     500 * time.Millisecond,
+				MaxDelay:      30 * time.Second,
+				Jitter:        0.5,
+				HostBudget:    50,
+				BudgetWindow:  1 * time.Minute,
+				RetryStatuses: []int{408, 429, 500, 502, 503, 504},
+			},
+			DNS: DNSConfig{
+				Cache:       true,
+				Servers:     []string{},
+				MinTTL:      10 * time.Second,
+				MaxTTL:      time.Hour,
+				DefaultTTL:  5 * time.Minute,
+				NegativeTTL: 30 * time.Second,
+				Timeout:     5 * time.Second,
+			},
+			Transport: TransportConfig{
+				HTTP2:               true,
+				MaxIdleConns:        2000,
+				MaxIdleConnsPerHost: 200,
+				MaxConnsPerHost:     500,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+				KeepAlive:           30 * time.Second,
+			},
+			TLS: TLSConfig{
+				Capture:         false,
+				Output:          "tls.json",
+				ExpiryWarning:   30 * 24 * time.Hour,
+				InsecureDomains: []string{},
+			},
+			Login: []LoginConfig{},
 		},
 		Filters: FiltersConfig{
 			AllowedDomains: []string{},
+			Scope:          "host",
 			ExcludedPaths: []string{
 				"/wp-admin",
 				"/wp-login",
@@ -144,11 +973,160 @@ func DefaultConfig() *Config {
 				".doc", ".docx", ".xls", ".xlsx",
 				".ppt", ".pptx",
 			},
+			SkipLinkRels:    []string{},
+			IncludePatterns: []string{},
+			ExcludePatterns: []string{},
+			Traps: TrapConfig{
+				MaxURLLength:      2048,
+				MaxQueryParams:    10,
+				MaxSegmentRepeats: 3,
+				CalendarYearRange: 10,
+				MaxPageNumber:     1000,
+				MaxQueryVariants:  200,
+			},
+			Lists: DomainListConfig{
+				Refresh: 24 * time.Hour,
+				Timeout: time.Minute,
+			},
+			RateLimits: RateLimitsConfig{
+				Default: RateLimitRule{
+					RequestsPerSecond: 2,
+					Burst:             4,
+				},
+				Domains: map[string]RateLimitRule{},
+				Adaptive: AdaptiveRateConfig{
+					Enabled:        true,
+					MinRate:        0.1,
+					MaxRate:        10,
+					FastResponse:   500 * time.Millisecond,
+					DecreaseFactor: 0.5,
+					IncreaseFactor: 1.1,
+				},
+				Breaker: CircuitBreakerConfig{
+					Enabled:             true,
+					ConsecutiveFailures: 5,
+					ErrorRate:           0.5,
+					Window:              20,
+					Cooldown:            time.Minute,
+				},
+			},
+		},
+		Robots: RobotsConfig{
+			Ignore:     false,
+			IgnoreMeta: false,
+			CacheTTL:   24 * time.Hour,
+			MaxSize:    512 * 1024, // 500KB
+		},
+		Dedup: DedupConfig{
+			Backend:           "memory",
+			ExpectedItems:     1000000,
+			FalsePositiveRate: 0.001,
+			Redis: RedisConfig{
+				Addr:      "localhost:6379",
+				PoolSize:  10,
+				Timeout:   5 * time.Second,
+				KeyPrefix: "webcrawler:",
+			},
+			ContentEnabled: false,
+			MaxDistance:    3,
+		},
+		API: APIConfig{
+			Enabled: false,
+			Addr:    "127.0.0.1:8080",
+			UI:      true,
+		},
+		Recrawl: RecrawlConfig{
+			Enabled:       false,
+			Interval:      24 * time.Hour,
+			Domains:       map[string]time.Duration{},
+			Adaptive:      true,
+			MinInterval:   1 * time.Hour,
+			MaxInterval:   30 * 24 * time.Hour,
+			CheckInterval: 1 * time.Minute,
 		},
 		Benchmark: BenchmarkConfig{
 			Enabled:   true,
 			Interval:  1 * time.Second,
 			OutputDir: "benchmarks",
+			Formats:   []string{"png", "html"},
+			Summary:   true,
+			Runs: RunsConfig{
+				Backend:    "dir",
+				Dir:        "runs",
+				Collection: "benchmark_runs",
+			},
+		},
+		Graph: GraphConfig{
+			Enabled:    false,
+			OutputDir:  "graph",
+			Formats:    []string{"csv", "graphml", "dot"},
+			MaxNodes:   1000000,
+			Damping:    0.85,
+			Iterations: 50,
+
+			Prioritize:         false,
+			PrioritizeInterval: 30 * time.Second,
+		},
+		Subdomains: SubdomainsConfig{
+			Enabled:  false,
+			Output:   "subdomains.json",
+			MaxHosts: 100000,
+		},
+		LinkCheck: LinkCheckConfig{
+			Enabled:       false,
+			OutputDir:     "link_check",
+			Formats:       []string{"json", "html"},
+			CheckFiltered: true,
+			Workers:       4,
+			MaxReferrers:  10,
+		},
+		SEO: SEOConfig{
+			Enabled:              false,
+			OutputDir:            "seo",
+			Formats:              []string{"html", "csv"},
+			MaxTitleLength:       60,
+			MaxDescriptionLength: 160,
+			MinWords:             200,
+			MaxRedirects:         1,
+			MaxPages:             100000,
+		},
+		A11y: AccessibilityConfig{
+			Enabled:   false,
+			OutputDir: "accessibility",
+			Formats:   []string{"json", "csv"},
+			MaxPages:  100000,
+		},
+		Focus: FocusConfig{
+			Enabled:   false,
+			Threshold: 0.3,
+			MinScore:  0,
+		},
+		Documents: DocumentsConfig{
+			Enabled: false,
+			Types: []string{
+				"application/pdf",
+				"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			},
+			Commands: map[string][]string{},
+			Timeout:  30 * time.Second,
+		},
+		JSON: JSONConfig{
+			Enabled: false,
+			Types:   []string{"application/json", "text/json"},
+			Rules:   []JSONRule{},
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:    false,
+			Paths:      []string{"/graphql", "/api/graphql"},
+			Introspect: true,
+			Samples:    5,
+			Timeout:    10 * time.Second,
+		},
+		Domains: map[string]DomainProfile{},
+		JobHistory: JobHistoryConfig{
+			Backend:    "file",
+			Path:       "queue_data/job_history.jsonl",
+			Collection: "job_history",
 		},
 	}
 }
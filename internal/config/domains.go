@@ -0,0 +1,94 @@
+package config
+
+import (
+	"strings"
+)
+
+// DomainProfile overrides global settings for a domain and its subdomains.
+// Unset fields keep the global value.
+type DomainProfile struct {
+	Workers    int               `yaml:"workers"`    // URLs of the domain processed at once, 0 = no limit
+	RateLimit  *RateLimitRule    `yaml:"rate_limit"` // Replaces filters.rate_limits for the domain
+	MaxDepth   int               `yaml:"max_depth"`
+	MaxPages   int               `yaml:"max_pages"`  // Per host of the domain
+	Headers    map[string]string `yaml:"headers"`    // Sent with every request, over the default ones
+	Render     *bool             `yaml:"render"`     // Render with the headless browser, or never
+	Insecure   bool              `yaml:"insecure"`   // Accept invalid TLS certificates
+	Extraction []ExtractionRule  `yaml:"extraction"` // Replace extraction.rules on pages of the domain
+}
+
+// ApplyDomains merges the domain profiles into the per-domain rate limits,
+// host limits, render domains and insecure TLS domains. Profiles win over entries configured
+// there for the same domain. Applying them again changes nothing.
+func (c *Config) ApplyDomains() {
+	for domain, p := range c.Domains {
+		domain = strings.ToLower(domain)
+		if p.RateLimit != nil {
+			if c.Filters.RateLimits.Domains == nil {
+				c.Filters.RateLimits.Domains = make(map[string]RateLimitRule)
+			}
+			c.Filters.RateLimits.Domains[domain] = *p.RateLimit
+		}
+		if p.MaxDepth > 0 || p.MaxPages > 0 {
+			if c.Crawler.HostLimits == nil {
+				c.Crawler.HostLimits = make(map[string]HostLimit)
+			}
+			for _, pattern := range []string{domain, "*." + domain} {
+				limit := c.Crawler.HostLimits[pattern]
+				if p.MaxDepth > 0 {
+					limit.MaxDepth = p.MaxDepth
+				}
+				if p.MaxPages > 0 {
+					limit.MaxPages = p.MaxPages
+				}
+				c.Crawler.HostLimits[pattern] = limit
+			}
+		}
+		if p.Render != nil {
+			render := &c.HTTP.Render
+			if *p.Render {
+				render.Domains = appendMissing(render.Domains, domain)
+			} else {
+				render.Exclude = appendMissing(render.Exclude, domain)
+			}
+		}
+		if p.Insecure {
+			c.HTTP.TLS.InsecureDomains = appendMissing(c.HTTP.TLS.InsecureDomains, domain)
+		}
+	}
+}
+
+// Profile returns the profile of host and the domain it is keyed by: the
+// longest domain that is host or one of its parents
+func (c *Config) Profile(host string) (DomainProfile, string, bool) {
+	if len(c.Domains) == 0 {
+		return DomainProfile{}, "", false
+	}
+	host = strings.ToLower(host)
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	for domain := host; domain != ""; {
+		for key, p := range c.Domains {
+			if strings.EqualFold(key, domain) {
+				return p, key, true
+			}
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return DomainProfile{}, "", false
+}
+
+// appendMissing appends s to list unless it is already there
+func appendMissing(list []string, s string) []string {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyDomains(t *testing.T) {
+	on, off := true, false
+	cfg := DefaultConfig()
+	cfg.Crawler.HostLimits["*.example.com"] = HostLimit{MaxPages: 50, MaxDepth: 9}
+	cfg.Filters.RateLimits.Domains["example.com"] = RateLimitRule{RequestsPerSecond: 10, Burst: 10}
+	cfg.Domains = map[string]DomainProfile{
+		"Example.com":     {MaxDepth: 2, RateLimit: &RateLimitRule{RequestsPerSecond: 1, Burst: 1}, Render: &on},
+		"old.example.com": {Render: &off, Insecure: true},
+	}
+	cfg.ApplyDomains()
+	cfg.ApplyDomains()
+
+	wantLimits := map[string]HostLimit{
+		"example.com":   {MaxDepth: 2},
+		"*.example.com": {MaxPages: 50, MaxDepth: 2},
+	}
+	if !reflect.DeepEqual(cfg.Crawler.HostLimits, wantLimits) {
+		t.Errorf("host limits = %v, want %v", cfg.Crawler.HostLimits, wantLimits)
+	}
+	if rule := cfg.Filters.RateLimits.Domains["example.com"]; rule.RequestsPerSecond != 1 {
+		t.Errorf("rate limit = %+v, want the profile's", rule)
+	}
+	if !reflect.DeepEqual(cfg.HTTP.Render.Domains, []string{"example.com"}) {
+		t.Errorf("render domains = %v", cfg.HTTP.Render.Domains)
+	}
+	if !reflect.DeepEqual(cfg.HTTP.Render.Exclude, []string{"old.example.com"}) {
+		t.Errorf("render exclude = %v", cfg.HTTP.Render.Exclude)
+	}
+	if !reflect.DeepEqual(cfg.HTTP.TLS.InsecureDomains, []string{"old.example.com"}) {
+		t.Errorf("insecure TLS domains = %v", cfg.HTTP.TLS.InsecureDomains)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Domains = map[string]DomainProfile{
+		"example.com":      {Workers: 1},
+		"shop.example.com": {Workers: 2},
+	}
+	tests := map[string]string{
+		"example.com":           "example.com",
+		"www.example.com:8080":  "example.com",
+		"SHOP.example.com":      "shop.example.com",
+		"eu.shop.example.com":   "shop.example.com",
+		"notexample.com":        "",
+		"example.com.evil.test": "",
+	}
+	for host, want := range tests {
+		_, got, ok := cfg.Profile(host)
+		if got != want || ok != (want != "") {
+			t.Errorf("Profile(%q) = %q, %v, want %q", host, got, ok, want)
+		}
+	}
+}
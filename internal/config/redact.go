@@ -0,0 +1,70 @@
+package config
+
+import (
+	"net/url"
+)
+
+// redacted replaces credentials in config copies
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the config without credentials, to keep it
+// alongside results. Header and login field values are all replaced since
+// any of them may hold a secret.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Queue.Redis.Password = redactString(r.Queue.Redis.Password)
+	r.Dedup.Redis.Password = redactString(r.Dedup.Redis.Password)
+	r.Storage.Object.AccessKeyID = redactString(r.Storage.Object.AccessKeyID)
+	r.Storage.Object.SecretAccessKey = redactString(r.Storage.Object.SecretAccessKey)
+	r.Storage.Object.SessionToken = redactString(r.Storage.Object.SessionToken)
+	r.Storage.Publish.URL = redactURL(r.Storage.Publish.URL)
+	r.Telemetry.Headers = redactValues(r.Telemetry.Headers)
+
+	if c.HTTP.Login != nil {
+		r.HTTP.Login = make([]LoginConfig, len(c.HTTP.Login))
+		for i, login := range c.HTTP.Login {
+			login.Fields = redactValues(login.Fields)
+			r.HTTP.Login[i] = login
+		}
+	}
+	if c.Domains != nil {
+		r.Domains = make(map[string]DomainProfile, len(c.Domains))
+		for domain, profile := range c.Domains {
+			profile.Headers = redactValues(profile.Headers)
+			r.Domains[domain] = profile
+		}
+	}
+	return &r
+}
+
+// redactString hides s unless it is empty
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactValues returns a copy of m with every value hidden
+func redactValues(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k := range m {
+		out[k] = redacted
+	}
+	return out
+}
+
+// redactURL hides the password in rawURL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return rawURL
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
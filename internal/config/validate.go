@@ -0,0 +1,888 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldError describes one invalid setting
+type FieldError struct {
+	Path    string // YAML path, e.g. crawler.workers or filters.include_patterns[1]
+	Line    int    // Line in the config file, 0 when the value comes from the defaults
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationError lists every invalid setting found in a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("invalid configuration:")
+	for _, fe := range e.Errors {
+		sb.WriteString("\n  ")
+		sb.WriteString(fe.Error())
+	}
+	return sb.String()
+}
+
+// validator collects field errors
+type validator struct {
+	errs []FieldError
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) atLeast(path string, value, min int) {
+	if value < min {
+		v.addf(path, "must be at least %d, got %d", min, value)
+	}
+}
+
+func (v *validator) positiveDuration(path string, d time.Duration) {
+	if d <= 0 {
+		v.addf(path, "must be a positive duration, got %s", d)
+	}
+}
+
+func (v *validator) nonNegativeDuration(path string, d time.Duration) {
+	if d < 0 {
+		v.addf(path, "must not be negative, got %s", d)
+	}
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) notEmpty(path, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(path, "must not be empty")
+	}
+}
+
+// Validate checks the configuration for values that would fail or misbehave
+// at runtime and reports all problems at once as a *ValidationError
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.validateCrawler(v)
+	c.validateQueue(v)
+	c.validateHTTP(v)
+	c.validateFilters(v)
+
+	if c.Checkpoint.Enabled {
+		v.notEmpty("checkpoint.path", c.Checkpoint.Path)
+	}
+	v.nonNegativeDuration("checkpoint.drain_timeout", c.Checkpoint.DrainTimeout)
+	if c.Reload.Enabled {
+		v.positiveDuration("reload.interval", c.Reload.Interval)
+	}
+	if c.Telemetry.Enabled {
+		u, err := url.Parse(c.Telemetry.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("telemetry.endpoint", "%q is not an absolute http(s) URL", c.Telemetry.Endpoint)
+		}
+		if c.Telemetry.SampleRate < 0 || c.Telemetry.SampleRate > 1 {
+			v.addf("telemetry.sample_rate", "must be between 0 and 1, got %g", c.Telemetry.SampleRate)
+		}
+		v.atLeast("telemetry.batch_size", c.Telemetry.BatchSize, 1)
+		v.positiveDuration("telemetry.flush_interval", c.Telemetry.FlushInterval)
+	}
+	if c.Debug.Enabled {
+		v.notEmpty("debug.addr", c.Debug.Addr)
+		v.atLeast("debug.block_rate", c.Debug.BlockRate, 0)
+		v.atLeast("debug.mutex_fraction", c.Debug.MutexFraction, 0)
+	}
+	if c.Dashboard.Enabled {
+		v.positiveDuration("dashboard.refresh", c.Dashboard.Refresh)
+	}
+
+	levels := []string{"debug", "info", "warn", "error"}
+	v.oneOf("logging.format", c.Logging.Format, "console", "json")
+	v.oneOf("logging.level", c.Logging.Level, levels...)
+	for module, level := range c.Logging.Modules {
+		v.oneOf("logging.modules."+module, level, levels...)
+	}
+	if c.Logging.MaxSize < 0 {
+		v.addf("logging.max_size", "must not be negative")
+	}
+	v.atLeast("logging.max_backups", c.Logging.MaxBackups, 0)
+	if c.Logging.Trace.Enabled {
+		v.notEmpty("logging.trace.path", c.Logging.Trace.Path)
+	}
+	for i, pattern := range c.Logging.Trace.Match {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(fmt.Sprintf("logging.trace.match[%d]", i), "invalid regular expression: %v", err)
+		}
+	}
+
+	if c.ContentSaver.Enabled {
+		v.notEmpty("content_saver.output_dir", c.ContentSaver.OutputDir)
+		v.oneOf("content_saver.format", c.ContentSaver.Format, "html", "raw", "json", "mhtml", "warc")
+		if c.ContentSaver.Archive != "" {
+			v.oneOf("content_saver.archive", c.ContentSaver.Archive, "zip", "tar.gz")
+			v.oneOf("content_saver.archive_per", c.ContentSaver.ArchivePer, "run", "domain")
+		}
+		if (c.ContentSaver.Format == "mhtml" || c.ContentSaver.Mirror.Enabled) && c.ContentSaver.MaxResourceSize <= 0 {
+			v.addf("content_saver.max_resource_size", "must be a positive size in bytes")
+		}
+		if m := c.ContentSaver.Mirror; m.Enabled {
+			v.notEmpty("content_saver.mirror.dir", m.Dir)
+			v.atLeast("content_saver.mirror.max_resources", m.MaxResources, 1)
+		}
+	}
+	if c.ContentSaver.MaxFileSize < 0 {
+		v.addf("content_saver.max_file_size", "must not be negative")
+	}
+	if r := c.ContentSaver.Retention; r.Enabled {
+		v.positiveDuration("content_saver.retention.interval", r.Interval)
+		v.nonNegativeDuration("content_saver.retention.max_age", r.MaxAge)
+		if r.MaxTotalSize < 0 {
+			v.addf("content_saver.retention.max_total_size", "must not be negative")
+		}
+		v.atLeast("content_saver.retention.max_files_per_domain", r.MaxFilesPerDomain, 0)
+		if r.MaxTotalSize == 0 && r.MaxFilesPerDomain == 0 && r.MaxAge == 0 {
+			v.addf("content_saver.retention", "needs max_total_size, max_files_per_domain or max_age")
+		}
+	}
+	for mediaType, size := range c.ContentSaver.Assets.MaxSizes {
+		if size <= 0 {
+			v.addf("content_saver.assets.max_sizes."+mediaType, "must be a positive size in bytes")
+		}
+	}
+
+	for i, field := range c.Storage.Fields {
+		v.oneOf(fmt.Sprintf("storage.fields[%d]", i), field, "content", "text", "article", "links", "headers")
+	}
+
+	mongo := c.Storage.MongoDB
+	v.positiveDuration("storage.mongodb.timeout", mongo.Timeout)
+	if mongo.MinPoolSize > mongo.MaxPoolSize {
+		v.addf("storage.mongodb.min_pool_size", "must not exceed max_pool_size (%d)", mongo.MaxPoolSize)
+	}
+	v.atLeast("storage.mongodb.batch_size", mongo.BatchSize, 1)
+	if mongo.BatchSize > 1 {
+		v.positiveDuration("storage.mongodb.flush_interval", mongo.FlushInterval)
+		v.atLeast("storage.mongodb.buffer_size", mongo.BufferSize, mongo.BatchSize)
+	}
+	v.oneOf("storage.mongodb.compression", mongo.Compression, "none", "gzip", "zstd")
+	v.atLeast("storage.mongodb.change_history", mongo.ChangeHistory, 0)
+	if c.Storage.Search.Enabled {
+		v.notEmpty("storage.search.path", c.Storage.Search.Path)
+	}
+	if c.Storage.Publish.Enabled {
+		c.validatePublish(v)
+	}
+	if c.Storage.Object.Enabled {
+		c.validateObject(v)
+	}
+	if raw := c.Storage.Raw; raw.Enabled {
+		v.oneOf("storage.raw.backend", raw.Backend, "file", "gridfs")
+		switch raw.Backend {
+		case "file":
+			v.notEmpty("storage.raw.dir", raw.Dir)
+		case "gridfs":
+			v.notEmpty("storage.raw.bucket", raw.Bucket)
+		}
+	}
+
+	v.nonNegativeDuration("robots.cache_ttl", c.Robots.CacheTTL)
+	if c.Robots.MaxSize < 0 {
+		v.addf("robots.max_size", "must not be negative")
+	}
+
+	v.oneOf("dedup.backend", c.Dedup.Backend, "memory", "bloom", "redis")
+	if c.Dedup.Backend == "bloom" {
+		if c.Dedup.ExpectedItems == 0 {
+			v.addf("dedup.expected_items", "must be positive for the bloom backend")
+		}
+		if c.Dedup.FalsePositiveRate <= 0 || c.Dedup.FalsePositiveRate >= 1 {
+			v.addf("dedup.false_positive_rate", "must be between 0 and 1, got %g", c.Dedup.FalsePositiveRate)
+		}
+	}
+	if c.Dedup.Backend == "redis" {
+		v.notEmpty("dedup.redis.addr", c.Dedup.Redis.Addr)
+	}
+	v.atLeast("dedup.max_distance", c.Dedup.MaxDistance, 0)
+
+	if c.API.Enabled {
+		v.notEmpty("api.addr", c.API.Addr)
+	}
+
+	if c.Recrawl.Enabled {
+		v.positiveDuration("recrawl.interval", c.Recrawl.Interval)
+		v.positiveDuration("recrawl.check_interval", c.Recrawl.CheckInterval)
+		if c.Recrawl.Adaptive && c.Recrawl.MinInterval > c.Recrawl.MaxInterval {
+			v.addf("recrawl.min_interval", "must not exceed max_interval (%s)", c.Recrawl.MaxInterval)
+		}
+		for domain, interval := range c.Recrawl.Domains {
+			v.positiveDuration("recrawl.domains."+domain, interval)
+		}
+	}
+
+	if c.Benchmark.Enabled {
+		v.positiveDuration("benchmark.interval", c.Benchmark.Interval)
+		v.notEmpty("benchmark.output_dir", c.Benchmark.OutputDir)
+		for i, format := range c.Benchmark.Formats {
+			v.oneOf(fmt.Sprintf("benchmark.formats[%d]", i), format, "png", "html")
+		}
+		runs := c.Benchmark.Runs
+		v.oneOf("benchmark.runs.backend", runs.Backend, "none", "dir", "mongodb")
+		switch runs.Backend {
+		case "dir":
+			v.notEmpty("benchmark.runs.dir", runs.Dir)
+		case "mongodb":
+			v.notEmpty("benchmark.runs.collection", runs.Collection)
+		}
+	}
+
+	if c.Graph.Enabled {
+		v.notEmpty("graph.output_dir", c.Graph.OutputDir)
+		for i, format := range c.Graph.Formats {
+			v.oneOf(fmt.Sprintf("graph.formats[%d]", i), format, "csv", "graphml", "dot")
+		}
+		v.atLeast("graph.max_nodes", c.Graph.MaxNodes, 1)
+		if c.Graph.Damping <= 0 || c.Graph.Damping >= 1 {
+			v.addf("graph.damping", "must be between 0 and 1 exclusive, got %g", c.Graph.Damping)
+		}
+		v.atLeast("graph.iterations", c.Graph.Iterations, 1)
+		if c.Graph.Prioritize {
+			v.positiveDuration("graph.prioritize_interval", c.Graph.PrioritizeInterval)
+		}
+	} else if c.Graph.Prioritize {
+		v.addf("graph.prioritize", "requires graph.enabled")
+	}
+
+	if c.Subdomains.Enabled {
+		v.atLeast("subdomains.max_hosts", c.Subdomains.MaxHosts, 0)
+	}
+
+	if c.LinkCheck.Enabled {
+		v.notEmpty("link_check.output_dir", c.LinkCheck.OutputDir)
+		for i, format := range c.LinkCheck.Formats {
+			v.oneOf(fmt.Sprintf("link_check.formats[%d]", i), format, "json", "html")
+		}
+		if c.LinkCheck.CheckFiltered {
+			v.atLeast("link_check.workers", c.LinkCheck.Workers, 1)
+		}
+		v.atLeast("link_check.max_referrers", c.LinkCheck.MaxReferrers, 1)
+	}
+
+	if c.SEO.Enabled {
+		v.notEmpty("seo.output_dir", c.SEO.OutputDir)
+		for i, format := range c.SEO.Formats {
+			v.oneOf(fmt.Sprintf("seo.formats[%d]", i), format, "html", "csv")
+		}
+		v.atLeast("seo.max_title_length", c.SEO.MaxTitleLength, 1)
+		v.atLeast("seo.max_description_length", c.SEO.MaxDescriptionLength, 1)
+		v.atLeast("seo.min_words", c.SEO.MinWords, 0)
+		v.atLeast("seo.max_redirects", c.SEO.MaxRedirects, 0)
+		v.atLeast("seo.max_pages", c.SEO.MaxPages, 0)
+	}
+
+	if c.A11y.Enabled {
+		v.notEmpty("accessibility.output_dir", c.A11y.OutputDir)
+		for i, format := range c.A11y.Formats {
+			v.oneOf(fmt.Sprintf("accessibility.formats[%d]", i), format, "json", "csv")
+		}
+		v.atLeast("accessibility.max_pages", c.A11y.MaxPages, 0)
+	}
+
+	if c.Focus.Enabled {
+		if len(c.Focus.Keywords) == 0 && strings.TrimSpace(c.Focus.Topic) == "" {
+			v.addf("focus", "needs keywords or a topic")
+		}
+		if c.Focus.Threshold <= 0 || c.Focus.Threshold > 1 {
+			v.addf("focus.threshold", "must be above 0 and at most 1, got %g", c.Focus.Threshold)
+		}
+		if c.Focus.MinScore < 0 || c.Focus.MinScore > 1 {
+			v.addf("focus.min_score", "must be between 0 and 1, got %g", c.Focus.MinScore)
+		}
+	}
+	if d := c.Documents; d.Enabled {
+		if len(d.Types) == 0 {
+			v.addf("documents.types", "must list at least one media type")
+		}
+		v.positiveDuration("documents.timeout", d.Timeout)
+		for mediaType, args := range d.Commands {
+			if len(args) == 0 || args[0] == "" {
+				v.addf("documents.commands."+mediaType, "must name a program")
+			}
+		}
+	}
+	if j := c.JSON; j.Enabled {
+		if len(j.Types) == 0 {
+			v.addf("json.types", "must list at least one media type")
+		}
+		for i, rule := range j.Rules {
+			path := fmt.Sprintf("json.rules[%d]", i)
+			if len(rule.Links) == 0 && rule.Title == "" {
+				v.addf(path, "needs links or a title")
+			}
+			if _, err := regexp.Compile(rule.URL); err != nil {
+				v.addf(path+".url", "invalid regular expression: %v", err)
+			}
+		}
+	}
+	if g := c.GraphQL; g.Enabled {
+		if len(g.Paths) == 0 {
+			v.addf("graphql.paths", "must list at least one path")
+		}
+		for i, p := range g.Paths {
+			if !strings.HasPrefix(p, "/") {
+				v.addf(fmt.Sprintf("graphql.paths[%d]", i), "must start with /, got %q", p)
+			}
+		}
+		v.atLeast("graphql.samples", g.Samples, 0)
+		v.positiveDuration("graphql.timeout", g.Timeout)
+	}
+	c.validateExtraction(v)
+	c.validateDomains(v)
+
+	ids := make(map[string]bool, len(c.Jobs))
+	for i, job := range c.Jobs {
+		path := fmt.Sprintf("jobs[%d]", i)
+		validateJob(v, path, job)
+		if ids[job.ID] {
+			v.addf(path+".id", "duplicate job %q", job.ID)
+		}
+		ids[job.ID] = true
+	}
+	v.oneOf("job_history.backend", c.JobHistory.Backend, "file", "mongodb")
+	if c.JobHistory.Backend == "mongodb" {
+		v.notEmpty("job_history.collection", c.JobHistory.Collection)
+	} else {
+		v.notEmpty("job_history.path", c.JobHistory.Path)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	// Map iteration order is random, keep the report stable
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Path < v.errs[j].Path })
+	return &ValidationError{Errors: v.errs}
+}
+
+func (c *Config) validateCrawler(v *validator) {
+	cr := c.Crawler
+	v.atLeast("crawler.workers", cr.Workers, 1)
+	if cr.Autoscale.Enabled {
+		v.atLeast("crawler.autoscale.min_workers", cr.Autoscale.MinWorkers, 1)
+		v.atLeast("crawler.autoscale.max_workers", cr.Autoscale.MaxWorkers, cr.Autoscale.MinWorkers)
+		v.positiveDuration("crawler.autoscale.interval", cr.Autoscale.Interval)
+		v.positiveDuration("crawler.autoscale.target_latency", cr.Autoscale.TargetLatency)
+		v.atLeast("crawler.autoscale.queue_per_worker", cr.Autoscale.QueuePerWorker, 1)
+	}
+	v.atLeast("crawler.parse.workers", cr.Parse.Workers, 0)
+	v.atLeast("crawler.parse.queue", cr.Parse.Queue, 0)
+	v.nonNegativeDuration("crawler.rate_limit", cr.RateLimit)
+	v.nonNegativeDuration("crawler.timeout", cr.Timeout)
+	v.atLeast("crawler.max_depth", cr.MaxDepth, 0)
+	v.atLeast("crawler.max_pages", cr.MaxPages, 0)
+	v.oneOf("crawler.frontier", cr.Frontier, "priority", "breadth_first", "best_first")
+	if cr.Frontier != "priority" && (c.Queue.Backend == "redis" || c.Queue.HostAware) {
+		v.addf("crawler.frontier", "%s needs the memory queue without host_aware", cr.Frontier)
+	}
+	if strings.TrimSpace(cr.Score) != "" && cr.Frontier != "best_first" {
+		v.addf("crawler.score", "needs the best_first frontier")
+	}
+
+	for host, limit := range cr.HostLimits {
+		v.atLeast("crawler.host_limits."+host+".max_pages", limit.MaxPages, 0)
+		v.atLeast("crawler.host_limits."+host+".max_depth", limit.MaxDepth, 0)
+	}
+	for i, seed := range cr.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf(fmt.Sprintf("crawler.seeds[%d]", i), "%q is not an absolute http(s) URL", seed)
+		}
+	}
+}
+
+func (c *Config) validateQueue(v *validator) {
+	q := c.Queue
+	v.oneOf("queue.backend", q.Backend, "memory", "redis")
+	v.atLeast("queue.instances", q.Instances, 1)
+	if q.InstanceID < 0 || (q.Instances > 0 && q.InstanceID >= q.Instances) {
+		v.addf("queue.instance_id", "must be between 0 and instances-1 (%d), got %d", q.Instances-1, q.InstanceID)
+	}
+	v.nonNegativeDuration("queue.host_delay", q.HostDelay)
+
+	switch q.Backend {
+	case "redis":
+		v.notEmpty("queue.redis.addr", q.Redis.Addr)
+		if q.Persistent {
+			v.addf("queue.persistent", "conflicts with the redis backend, which is already durable")
+		}
+		if q.HostAware {
+			v.addf("queue.host_aware", "is not supported by the redis backend")
+		}
+		if q.Instances > 1 && c.Dedup.Backend != "redis" {
+			v.addf("dedup.backend", "must be redis when %d instances share a redis queue, got %q", q.Instances, c.Dedup.Backend)
+		}
+	default:
+		if q.Instances > 1 {
+			v.addf("queue.instances", "more than one instance requires the redis backend")
+		}
+		if q.Persistent {
+			v.notEmpty("queue.path", q.Path)
+			v.positiveDuration("queue.sync_interval", q.SyncInterval)
+			v.atLeast("queue.compact_after", q.CompactAfter, 0)
+			if q.HostAware {
+				v.addf("queue.host_aware", "conflicts with persistent, only one queue type can be used")
+			}
+			if q.Overflow.Policy == "spill" {
+				v.addf("queue.overflow.policy", "spill can't be used with persistent, whose log keeps every queued URL in memory")
+			}
+		}
+	}
+
+	v.atLeast("queue.capacity", q.Capacity, 1)
+	v.nonNegativeDuration("queue.aging", q.Aging)
+	v.atLeast("queue.inlink_boost", q.InlinkBoost, 0)
+	v.oneOf("queue.overflow.policy", q.Overflow.Policy, "drop", "block", "spill")
+	switch q.Overflow.Policy {
+	case "block":
+		v.positiveDuration("queue.overflow.timeout", q.Overflow.Timeout)
+	case "spill":
+		v.notEmpty("queue.overflow.path", q.Overflow.Path)
+	}
+
+	dl := q.DeadLetter
+	v.oneOf("queue.dead_letter.backend", dl.Backend, "memory", "file", "mongodb")
+	switch dl.Backend {
+	case "file":
+		v.notEmpty("queue.dead_letter.path", dl.Path)
+	case "mongodb":
+		v.notEmpty("queue.dead_letter.collection", dl.Collection)
+	}
+}
+
+func (c *Config) validateHTTP(v *validator) {
+	h := c.HTTP
+	v.notEmpty("http.user_agent", h.UserAgent)
+	v.positiveDuration("http.timeout", h.Timeout)
+	v.atLeast("http.max_redirects", h.MaxRedirects, 0)
+	if h.MaxBodySize < 0 {
+		v.addf("http.max_body_size", "must not be negative")
+	}
+	if len(h.AllowedContentTypes) == 0 {
+		v.addf("http.allowed_content_types", "must list at least one media type")
+	}
+
+	if h.Proxy != "" {
+		u, err := url.Parse(h.Proxy)
+		if err != nil || u.Host == "" {
+			v.addf("http.proxy", "%q is not a valid proxy URL", h.Proxy)
+		} else {
+			v.oneOf("http.proxy", u.Scheme, "http", "https", "socks5", "socks5h")
+		}
+	}
+	if h.Proxy != "" || h.ProxyFile != "" {
+		v.oneOf("http.proxy_rotation", h.ProxyRotation, "round_robin", "sticky", "failure_aware")
+		v.atLeast("http.proxy_max_failures", h.ProxyMaxFailures, 1)
+		v.nonNegativeDuration("http.proxy_cooldown", h.ProxyCooldown)
+	}
+
+	if h.Render.Enabled || len(h.Render.Domains) > 0 {
+		v.atLeast("http.render.max_concurrent", h.Render.MaxConcurrent, 1)
+		v.positiveDuration("http.render.timeout", h.Render.Timeout)
+		v.nonNegativeDuration("http.render.budget", h.Render.Budget)
+	}
+	if h.Cache.Enabled {
+		v.notEmpty("http.cache.dir", h.Cache.Dir)
+	}
+	v.nonNegativeDuration("http.cache.ttl", h.Cache.TTL)
+
+	r := h.Retry
+	v.atLeast("http.retry.max_attempts", r.MaxAttempts, 1)
+	v.nonNegativeDuration("http.retry.base_delay", r.BaseDelay)
+	if r.MaxDelay < r.BaseDelay {
+		v.addf("http.retry.max_delay", "must not be below base_delay (%s), got %s", r.BaseDelay, r.MaxDelay)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		v.addf("http.retry.jitter", "must be between 0 and 1, got %g", r.Jitter)
+	}
+	v.atLeast("http.retry.host_budget", r.HostBudget, 0)
+	if r.HostBudget > 0 {
+		v.positiveDuration("http.retry.budget_window", r.BudgetWindow)
+	}
+	for i, status := range r.RetryStatuses {
+		if status < 100 || status > 599 {
+			v.addf(fmt.Sprintf("http.retry.retry_statuses[%d]", i), "%d is not an HTTP status code", status)
+		}
+	}
+
+	d := h.DNS
+	for i, server := range d.Servers {
+		if scheme, _, ok := strings.Cut(server, "://"); ok {
+			v.oneOf(fmt.Sprintf("http.dns.servers[%d]", i), scheme, "udp", "tcp", "https")
+		} else if server == "" {
+			v.addf(fmt.Sprintf("http.dns.servers[%d]", i), "must not be empty")
+		}
+	}
+	v.nonNegativeDuration("http.dns.min_ttl", d.MinTTL)
+	if d.MaxTTL != 0 && d.MaxTTL < d.MinTTL {
+		v.addf("http.dns.max_ttl", "must not be below min_ttl (%s), got %s", d.MinTTL, d.MaxTTL)
+	}
+	v.nonNegativeDuration("http.dns.default_ttl", d.DefaultTTL)
+	v.nonNegativeDuration("http.dns.negative_ttl", d.NegativeTTL)
+	if d.Cache || len(d.Servers) > 0 {
+		v.positiveDuration("http.dns.timeout", d.Timeout)
+	}
+
+	t := h.Transport
+	v.atLeast("http.transport.max_idle_conns", t.MaxIdleConns, 0)
+	v.atLeast("http.transport.max_idle_conns_per_host", t.MaxIdleConnsPerHost, 0)
+	v.atLeast("http.transport.max_conns_per_host", t.MaxConnsPerHost, 0)
+	v.nonNegativeDuration("http.transport.idle_conn_timeout", t.IdleConnTimeout)
+	v.nonNegativeDuration("http.transport.tls_handshake_timeout", t.TLSHandshakeTimeout)
+
+	v.nonNegativeDuration("http.tls.expiry_warning", h.TLS.ExpiryWarning)
+	validateDomainPatterns(v, "http.tls.insecure_domains", h.TLS.InsecureDomains)
+
+	for i, l := range h.Login {
+		path := fmt.Sprintf("http.login[%d]", i)
+		v.notEmpty(path+".domain", l.Domain)
+		if len(l.Script) > 0 {
+			if l.Script[0] == "" {
+				v.addf(path+".script", "must name a program")
+			}
+			continue
+		}
+		if u, err := url.Parse(l.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			v.addf(path+".url", "must be an http or https URL, got %q", l.URL)
+		}
+		if len(l.Fields) == 0 {
+			v.addf(path+".fields", "needs the credentials to post, or set a script")
+		}
+	}
+}
+
+func (c *Config) validateFilters(v *validator) {
+	f := c.Filters
+	if len(f.AllowedSchemes) == 0 {
+		v.addf("filters.allowed_schemes", "must list at least one scheme, otherwise every URL is rejected")
+	}
+	for i, scheme := range f.AllowedSchemes {
+		if scheme != "http" && scheme != "https" {
+			v.addf(fmt.Sprintf("filters.allowed_schemes[%d]", i), "%q can't be fetched, only http and https are supported", scheme)
+		}
+	}
+
+	validateDomainPatterns(v, "filters.allowed_domains", f.AllowedDomains)
+	v.oneOf("filters.scope", f.Scope, "host", "domain")
+
+	include := make(map[string]bool, len(f.IncludePatterns))
+	for i, pattern := range f.IncludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(fmt.Sprintf("filters.include_patterns[%d]", i), "invalid regular expression: %v", err)
+		}
+		include[pattern] = true
+	}
+	for i, pattern := range f.ExcludePatterns {
+		path := fmt.Sprintf("filters.exclude_patterns[%d]", i)
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(path, "invalid regular expression: %v", err)
+		}
+		if include[pattern] {
+			v.addf(path, "%q is also an include pattern, no URL can match it", pattern)
+		}
+	}
+	for i, ext := range f.ExcludedExtensions {
+		if !strings.HasPrefix(ext, ".") {
+			v.addf(fmt.Sprintf("filters.excluded_extensions[%d]", i), "%q must start with a dot", ext)
+		}
+	}
+	for i, lang := range f.Languages {
+		if len(lang) < 2 || len(lang) > 3 || strings.ToLower(lang) != lang {
+			v.addf(fmt.Sprintf("filters.languages[%d]", i), "%q is not a lowercase ISO 639 language code", lang)
+		}
+	}
+
+	t := f.Traps
+	v.atLeast("filters.traps.max_url_length", t.MaxURLLength, 0)
+	v.atLeast("filters.traps.max_query_params", t.MaxQueryParams, 0)
+	v.atLeast("filters.traps.max_segment_repeats", t.MaxSegmentRepeats, 0)
+	v.atLeast("filters.traps.calendar_year_range", t.CalendarYearRange, 0)
+	v.atLeast("filters.traps.max_page_number", t.MaxPageNumber, 0)
+	v.atLeast("filters.traps.max_query_variants", t.MaxQueryVariants, 0)
+
+	for _, list := range []struct {
+		kind    string
+		sources []string
+	}{{"block", f.Lists.Block}, {"allow", f.Lists.Allow}} {
+		for i, source := range list.sources {
+			path := fmt.Sprintf("filters.lists.%s[%d]", list.kind, i)
+			v.notEmpty(path, source)
+			if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+				if u, err := url.Parse(source); err != nil || u.Host == "" {
+					v.addf(path, "invalid URL %q", source)
+				}
+			}
+		}
+	}
+	if len(f.Lists.Block) > 0 || len(f.Lists.Allow) > 0 {
+		v.nonNegativeDuration("filters.lists.refresh", f.Lists.Refresh)
+		v.positiveDuration("filters.lists.timeout", f.Lists.Timeout)
+	}
+
+	rl := f.RateLimits
+	validateRateRule(v, "filters.rate_limits.default", rl.Default)
+	for domain, rule := range rl.Domains {
+		validateRateRule(v, "filters.rate_limits.domains."+domain, rule)
+	}
+	if a := rl.Adaptive; a.Enabled {
+		if a.MinRate <= 0 {
+			v.addf("filters.rate_limits.adaptive.min_rate", "must be positive, got %g", a.MinRate)
+		}
+		if a.MaxRate < a.MinRate {
+			v.addf("filters.rate_limits.adaptive.max_rate", "must not be below min_rate (%g), got %g", a.MinRate, a.MaxRate)
+		}
+		if a.DecreaseFactor <= 0 || a.DecreaseFactor >= 1 {
+			v.addf("filters.rate_limits.adaptive.decrease_factor", "must be between 0 and 1, got %g", a.DecreaseFactor)
+		}
+		if a.IncreaseFactor <= 1 {
+			v.addf("filters.rate_limits.adaptive.increase_factor", "must be greater than 1, got %g", a.IncreaseFactor)
+		}
+	}
+	if b := rl.Breaker; b.Enabled {
+		v.atLeast("filters.rate_limits.circuit_breaker.consecutive_failures", b.ConsecutiveFailures, 0)
+		if b.ErrorRate < 0 || b.ErrorRate > 1 {
+			v.addf("filters.rate_limits.circuit_breaker.error_rate", "must be between 0 and 1, got %g", b.ErrorRate)
+		}
+		if b.ErrorRate > 0 {
+			v.atLeast("filters.rate_limits.circuit_breaker.window", b.Window, 1)
+		}
+		v.positiveDuration("filters.rate_limits.circuit_breaker.cooldown", b.Cooldown)
+	}
+}
+
+func (c *Config) validateExtraction(v *validator) {
+	validateExtractionRules(v, "extraction.rules", c.Extraction.Rules)
+}
+
+func validateExtractionRules(v *validator, prefix string, rules []ExtractionRule) {
+	for i, rule := range rules {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		v.notEmpty(path+".name", rule.Name)
+		if (rule.Selector == "") == (rule.XPath == "") {
+			v.addf(path, "needs either a selector or an xpath")
+		}
+		if rule.URL != "" {
+			if _, err := regexp.Compile(rule.URL); err != nil {
+				v.addf(path+".url", "invalid regular expression: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Config) validateDomains(v *validator) {
+	for domain, p := range c.Domains {
+		path := "domains." + domain
+		if domain == "" || strings.ContainsAny(domain, "/:*") {
+			v.addf(path, "%q is not a domain name", domain)
+		}
+		v.atLeast(path+".workers", p.Workers, 0)
+		v.atLeast(path+".max_depth", p.MaxDepth, 0)
+		v.atLeast(path+".max_pages", p.MaxPages, 0)
+		if p.RateLimit != nil {
+			validateRateRule(v, path+".rate_limit", *p.RateLimit)
+		}
+		for name := range p.Headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				v.addf(path+".headers", "%q is not a header name", name)
+			}
+		}
+		validateExtractionRules(v, path+".extraction", p.Extraction)
+	}
+}
+
+// jobIDPattern matches job IDs, which appear in URLs and file paths
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Validate checks a job, e.g. one created through the API
+func (j JobConfig) Validate() error {
+	v := &validator{}
+	validateJob(v, "job", j)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+func validateJob(v *validator, path string, job JobConfig) {
+	if !jobIDPattern.MatchString(job.ID) {
+		v.addf(path+".id", "%q must be 1 to 64 letters, digits, - or _", job.ID)
+	}
+	if len(job.Seeds) == 0 {
+		v.addf(path+".seeds", "must not be empty")
+	}
+	for i, seed := range job.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf(fmt.Sprintf("%s.seeds[%d]", path, i), "%q is not an absolute http(s) URL", seed)
+		}
+	}
+	validateDomainPatterns(v, path+".allowed_domains", job.AllowedDomains)
+	for i, pattern := range job.IncludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(fmt.Sprintf("%s.include_patterns[%d]", path, i), "invalid regular expression: %v", err)
+		}
+	}
+	for i, pattern := range job.ExcludePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(fmt.Sprintf("%s.exclude_patterns[%d]", path, i), "invalid regular expression: %v", err)
+		}
+	}
+	v.atLeast(path+".workers", job.Workers, 0)
+	v.atLeast(path+".max_depth", job.MaxDepth, 0)
+	v.atLeast(path+".max_pages", job.MaxPages, 0)
+}
+
+func (c *Config) validatePublish(v *validator) {
+	p := c.Storage.Publish
+	v.oneOf("storage.publish.backend", p.Backend, "kafka", "nats")
+	switch p.Backend {
+	case "kafka":
+		if len(p.Brokers) == 0 {
+			v.addf("storage.publish.brokers", "must list at least one broker")
+		}
+		for i, broker := range p.Brokers {
+			if _, _, err := net.SplitHostPort(broker); err != nil {
+				v.addf(fmt.Sprintf("storage.publish.brokers[%d]", i), "%q is not a host:port address", broker)
+			}
+		}
+	case "nats":
+		if u, err := url.Parse(p.URL); err != nil || u.Scheme != "nats" || u.Host == "" {
+			v.addf("storage.publish.url", "%q is not a nats:// URL", p.URL)
+		}
+	}
+	v.notEmpty("storage.publish.topic", p.Topic)
+	v.oneOf("storage.publish.format", p.Format, "json", "avro")
+	v.atLeast("storage.publish.batch_size", p.BatchSize, 1)
+	v.positiveDuration("storage.publish.flush_interval", p.FlushInterval)
+	v.atLeast("storage.publish.buffer_size", p.BufferSize, p.BatchSize)
+	v.atLeast("storage.publish.max_message_bytes", p.MaxMessageBytes, 1024)
+	v.positiveDuration("storage.publish.timeout", p.Timeout)
+	v.atLeast("storage.publish.max_retries", p.MaxRetries, 0)
+	v.nonNegativeDuration("storage.publish.retry_backoff", p.RetryBackoff)
+}
+
+func (c *Config) validateObject(v *validator) {
+	o := c.Storage.Object
+	v.oneOf("storage.object.provider", o.Provider, "s3", "gcs")
+	if o.Endpoint != "" {
+		if u, err := url.Parse(o.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addf("storage.object.endpoint", "%q is not an absolute http(s) URL", o.Endpoint)
+		}
+	}
+	v.notEmpty("storage.object.bucket", o.Bucket)
+	if o.PartSize < 5<<20 {
+		v.addf("storage.object.part_size", "must be at least 5 MiB (5242880), got %d", o.PartSize)
+	}
+	if o.MultipartThreshold < o.PartSize {
+		v.addf("storage.object.multipart_threshold", "must not be below part_size (%d)", o.PartSize)
+	}
+	v.positiveDuration("storage.object.timeout", o.Timeout)
+	v.atLeast("storage.object.max_retries", o.MaxRetries, 0)
+}
+
+// validateDomainPatterns checks hosts and "*.domain" wildcards
+func validateDomainPatterns(v *validator, path string, domains []string) {
+	for i, d := range domains {
+		if strings.Contains(strings.TrimPrefix(d, "*."), "*") || strings.ContainsAny(d, "/ ") || strings.Trim(d, "*.") == "" {
+			v.addf(fmt.Sprintf("%s[%d]", path, i), "%q is not a host or *.domain", d)
+		}
+	}
+}
+
+func validateRateRule(v *validator, path string, rule RateLimitRule) {
+	if rule.RequestsPerSecond < 0 {
+		v.addf(path+".requests_per_second", "must not be negative, got %g", rule.RequestsPerSecond)
+	}
+	if rule.RequestsPerSecond > 0 && rule.Burst < 1 {
+		v.addf(path+".burst", "must be at least 1 when requests_per_second is set, got %d", rule.Burst)
+	}
+}
+
+// annotateLines fills in the line of every field error that points at a value
+// present in the parsed YAML document
+func annotateLines(verr *ValidationError, root *yaml.Node) {
+	for i := range verr.Errors {
+		if node := findNode(root, verr.Errors[i].Path); node != nil {
+			verr.Errors[i].Line = node.Line
+		}
+	}
+	// Report in file order, settings left at their defaults last
+	sort.SliceStable(verr.Errors, func(i, j int) bool {
+		li, lj := verr.Errors[i].Line, verr.Errors[j].Line
+		return li != 0 && (lj == 0 || li < lj)
+	})
+}
+
+// findNode returns the node at a path like "a.b[2].c". Map keys may contain
+// dots themselves (host names), so keys are matched as whole prefixes. When a
+// path only partially exists the closest existing parent is returned.
+func findNode(n *yaml.Node, path string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return findNode(n.Content[0], path)
+	}
+	if path == "" {
+		return n
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if path == key.Value {
+				return value
+			}
+			if rest, ok := strings.CutPrefix(path, key.Value); ok && (rest[0] == '.' || rest[0] == '[') {
+				if found := findNode(value, strings.TrimPrefix(rest, ".")); found != nil {
+					return found
+				}
+				return value
+			}
+		}
+	case yaml.SequenceNode:
+		if !strings.HasPrefix(path, "[") {
+			return nil
+		}
+		end := strings.Index(path, "]")
+		if end < 0 {
+			return nil
+		}
+		idx, err := strconv.Atoi(path[1:end])
+		if err != nil || idx < 0 || idx >= len(n.Content) {
+			return nil
+		}
+		return findNode(n.Content[idx], strings.TrimPrefix(path[end+1:], "."))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestDefaultsValidate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Fatalf("DefaultConfig().Validate() = %v", err)
+	}
+	cfg, err := LoadConfig("../../configs/default.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("configs/default.yaml: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		path    string
+		message string
+	}{
+		{"no workers", func(c *Config) { c.Crawler.Workers = 0 }, "crawler.workers", "must be at least 1, got 0"},
+		{"relative seed", func(c *Config) { c.Crawler.Seeds = []string{"https://a.com/", "/about"} }, "crawler.seeds[1]", `"/about" is not an absolute http(s) URL`},
+		{"unknown frontier", func(c *Config) { c.Crawler.Frontier = "random" }, "crawler.frontier", `must be one of priority, breadth_first, best_first, got "random"`},
+		{"frontier on redis", func(c *Config) {
+			c.Crawler.Frontier = "best_first"
+			c.Queue.Backend = "redis"
+			c.Queue.Redis.Addr = "localhost:6379"
+		}, "crawler.frontier", "best_first needs the memory queue without host_aware"},
+		{"score without best first", func(c *Config) { c.Crawler.Score = "depth" }, "crawler.score", "needs the best_first frontier"},
+		{"autoscale bounds", func(c *Config) {
+			c.Crawler.Autoscale.Enabled = true
+			c.Crawler.Autoscale.MinWorkers = 8
+			c.Crawler.Autoscale.MaxWorkers = 4
+		}, "crawler.autoscale.max_workers", "must be at least 8, got 4"},
+		{"negative rate limit", func(c *Config) { c.Crawler.RateLimit = -time.Second }, "crawler.rate_limit", "must not be negative, got -1s"},
+
+		{"unknown queue backend", func(c *Config) { c.Queue.Backend = "disk" }, "queue.backend", `must be one of memory, redis, got "disk"`},
+		{"instances without redis", func(c *Config) { c.Queue.Instances = 2 }, "queue.instances", "more than one instance requires the redis backend"},
+		{"instance out of range", func(c *Config) { c.Queue.InstanceID = 1 }, "queue.instance_id", "must be between 0 and instances-1 (0), got 1"},
+		{"persistent redis", func(c *Config) {
+			c.Queue.Backend = "redis"
+			c.Queue.Redis.Addr = "localhost:6379"
+			c.Queue.Persistent = true
+		}, "queue.persistent", "conflicts with the redis backend, which is already durable"},
+		{"persistent host aware", func(c *Config) {
+			c.Queue.Persistent = true
+			c.Queue.HostAware = true
+		}, "queue.host_aware", "conflicts with persistent, only one queue type can be used"},
+		{"persistent spill", func(c *Config) {
+			c.Queue.Persistent = true
+			c.Queue.Overflow.Policy = "spill"
+		}, "queue.overflow.policy", "spill can't be used with persistent, whose log keeps every queued URL in memory"},
+		{"block without timeout", func(c *Config) {
+			c.Queue.Overflow.Policy = "block"
+			c.Queue.Overflow.Timeout = 0
+		}, "queue.overflow.timeout", "must be a positive duration, got 0s"},
+		{"spill without path", func(c *Config) {
+			c.Queue.Overflow.Policy = "spill"
+			c.Queue.Overflow.Path = " "
+		}, "queue.overflow.path", "must not be empty"},
+		{"dead letter file", func(c *Config) {
+			c.Queue.DeadLetter.Backend = "file"
+			c.Queue.DeadLetter.Path = ""
+		}, "queue.dead_letter.path", "must not be empty"},
+
+		{"no user agent", func(c *Config) { c.HTTP.UserAgent = "" }, "http.user_agent", "must not be empty"},
+		{"proxy scheme", func(c *Config) { c.HTTP.Proxy = "ftp://p:21" }, "http.proxy", `must be one of http, https, socks5, socks5h, got "ftp"`},
+		{"proxy rotation", func(c *Config) {
+			c.HTTP.Proxy = "http://p:3128"
+			c.HTTP.ProxyRotation = "random"
+		}, "http.proxy_rotation", `must be one of round_robin, sticky, failure_aware, got "random"`},
+		{"retry max delay", func(c *Config) { c.HTTP.Retry.MaxDelay = time.Millisecond }, "http.retry.max_delay", "must not be below base_delay (500ms), got 1ms"},
+		{"retry jitter", func(c *Config) { c.HTTP.Retry.Jitter = 1.5 }, "http.retry.jitter", "must be between 0 and 1, got 1.5"},
+		{"retry status", func(c *Config) { c.HTTP.Retry.RetryStatuses = []int{503, 42} }, "http.retry.retry_statuses[1]", "42 is not an HTTP status code"},
+		{"dns scheme", func(c *Config) { c.HTTP.DNS.Servers = []string{"tls://1.1.1.1"} }, "http.dns.servers[0]", `must be one of udp, tcp, https, got "tls"`},
+		{"insecure domain", func(c *Config) { c.HTTP.TLS.InsecureDomains = []string{"a.*.com"} }, "http.tls.insecure_domains[0]", `"a.*.com" is not a host or *.domain`},
+		{"login without fields", func(c *Config) {
+			c.HTTP.Login = []LoginConfig{{Domain: "a.com", URL: "https://a.com/login"}}
+		}, "http.login[0].fields", "needs the credentials to post, or set a script"},
+
+		{"no schemes", func(c *Config) { c.Filters.AllowedSchemes = nil }, "filters.allowed_schemes", "must list at least one scheme, otherwise every URL is rejected"},
+		{"ftp scheme", func(c *Config) { c.Filters.AllowedSchemes = []string{"https", "ftp"} }, "filters.allowed_schemes[1]", `"ftp" can't be fetched, only http and https are supported`},
+		{"bad include", func(c *Config) { c.Filters.IncludePatterns = []string{"("} }, "filters.include_patterns[0]", "invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		{"exclude equals include", func(c *Config) {
+			c.Filters.IncludePatterns = []string{"/blog/"}
+			c.Filters.ExcludePatterns = []string{"/tag/", "/blog/"}
+		}, "filters.exclude_patterns[1]", `"/blog/" is also an include pattern, no URL can match it`},
+		{"extension without dot", func(c *Config) { c.Filters.ExcludedExtensions = []string{"pdf"} }, "filters.excluded_extensions[0]", `"pdf" must start with a dot`},
+		{"language", func(c *Config) { c.Filters.Languages = []string{"EN"} }, "filters.languages[0]", `"EN" is not a lowercase ISO 639 language code`},
+		{"rate burst", func(c *Config) {
+			c.Filters.RateLimits.Domains = map[string]RateLimitRule{"a.com": {RequestsPerSecond: 2}}
+		}, "filters.rate_limits.domains.a.com.burst", "must be at least 1 when requests_per_second is set, got 0"},
+		{"breaker error rate", func(c *Config) {
+			c.Filters.RateLimits.Breaker.Enabled = true
+			c.Filters.RateLimits.Breaker.ErrorRate = 2
+		}, "filters.rate_limits.circuit_breaker.error_rate", "must be between 0 and 1, got 2"},
+
+		{"log level", func(c *Config) { c.Logging.Level = "verbose" }, "logging.level", `must be one of debug, info, warn, error, got "verbose"`},
+		{"module log level", func(c *Config) { c.Logging.Modules = map[string]string{"queue": "trace"} }, "logging.modules.queue", `must be one of debug, info, warn, error, got "trace"`},
+		{"mongo pool", func(c *Config) {
+			c.Storage.MongoDB.MinPoolSize = 50
+			c.Storage.MongoDB.MaxPoolSize = 10
+		}, "storage.mongodb.min_pool_size", "must not exceed max_pool_size (10)"},
+		{"mongo compression", func(c *Config) { c.Storage.MongoDB.Compression = "lz4" }, "storage.mongodb.compression", `must be one of none, gzip, zstd, got "lz4"`},
+		{"bloom rate", func(c *Config) {
+			c.Dedup.Backend = "bloom"
+			c.Dedup.ExpectedItems = 1000
+			c.Dedup.FalsePositiveRate = 1
+		}, "dedup.false_positive_rate", "must be between 0 and 1, got 1"},
+		{"prioritize without graph", func(c *Config) {
+			c.Graph.Enabled = false
+			c.Graph.Prioritize = true
+		}, "graph.prioritize", "requires graph.enabled"},
+		{"extraction rule", func(c *Config) {
+			c.Extraction.Rules = []ExtractionRule{{Name: "price"}}
+		}, "extraction.rules[0]", "needs either a selector or an xpath"},
+		{"domain name", func(c *Config) { c.Domains = map[string]DomainProfile{"https://a.com": {}} }, "domains.https://a.com", `"https://a.com" is not a domain name`},
+		{"job id", func(c *Config) {
+			c.Jobs = []JobConfig{{ID: "daily news", Seeds: []string{"https://a.com/"}}}
+		}, "jobs[0].id", `"daily news" must be 1 to 64 letters, digits, - or _`},
+		{"duplicate job", func(c *Config) {
+			c.Jobs = []JobConfig{{ID: "news", Seeds: []string{"https://a.com/"}}, {ID: "news", Seeds: []string{"https://b.com/"}}}
+		}, "jobs[1].id", `duplicate job "news"`},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		tt.change(cfg)
+		var verr *ValidationError
+		if err := cfg.Validate(); !errors.As(err, &verr) {
+			t.Errorf("%s: Validate() = %v, want a validation error", tt.name, err)
+			continue
+		}
+		if len(verr.Errors) != 1 || verr.Errors[0].Path != tt.path || verr.Errors[0].Message != tt.message {
+			t.Errorf("%s: errors = %+v, want %s: %s", tt.name, verr.Errors, tt.path, tt.message)
+		}
+	}
+}
+
+func TestValidateReportsAll(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Queue.Capacity = 0
+	cfg.Crawler.Workers = 0
+	cfg.HTTP.UserAgent = ""
+	err := cfg.Validate()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v", err)
+	}
+	var paths []string
+	for _, fe := range verr.Errors {
+		paths = append(paths, fe.Path)
+	}
+	if got := strings.Join(paths, " "); got != "crawler.workers http.user_agent queue.capacity" {
+		t.Fatalf("paths = %s, want every error sorted by path", got)
+	}
+	if want := "invalid configuration:\n  crawler.workers: must be at least 1, got 0\n"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestJobConfigValidate(t *testing.T) {
+	if err := (JobConfig{ID: "news", Seeds: []string{"https://a.com/"}}).Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	err := JobConfig{ID: "news", Seeds: []string{"a.com"}, Workers: -1}.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 2 || verr.Errors[0].Path != "job.seeds[0]" || verr.Errors[1].Path != "job.workers" {
+		t.Fatalf("Validate() = %v", err)
+	}
+}
+
+func TestAnnotateLines(t *testing.T) {
+	data := `crawler:
+  workers: 0
+filters:
+  rate_limits:
+    domains:
+      a.example.com:
+        requests_per_second: 2
+  include_patterns:
+    - "/blog/"
+    - "("
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.HTTP.UserAgent = ""
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &root); err != nil {
+		t.Fatal(err)
+	}
+	root.Decode(cfg)
+
+	var verr *ValidationError
+	if !errors.As(cfg.Validate(), &verr) {
+		t.Fatal("Validate() found nothing")
+	}
+	annotateLines(verr, &root)
+
+	// In file order, with the default user agent left for last. The missing
+	// burst points at the rule it belongs to.
+	want := []FieldError{
+		{Path: "crawler.workers", Line: 2},
+		{Path: "filters.rate_limits.domains.a.example.com.burst", Line: 7},
+		{Path: "filters.include_patterns[1]", Line: 10},
+		{Path: "http.user_agent"},
+	}
+	if len(verr.Errors) != len(want) {
+		t.Fatalf("errors = %+v", verr.Errors)
+	}
+	for i, w := range want {
+		if got := verr.Errors[i]; got.Path != w.Path || got.Line != w.Line {
+			t.Errorf("error %d = %s on line %d, want %s on line %d", i, got.Path, got.Line, w.Path, w.Line)
+		}
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "line 2: crawler.workers") {
+		t.Errorf("LoadConfig() = %v", err)
+	}
+}
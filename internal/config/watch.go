@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the config package
+var log = logger.For("config")
+
+// Change is one setting that differs between two configurations
+type Change struct {
+	Path string // YAML path, e.g. filters.allowed_domains
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff lists the settings that differ between old and updated, by YAML path
+func Diff(old, updated *Config) []Change {
+	var changes []Change
+	diffValues("", reflect.ValueOf(*old), reflect.ValueOf(*updated), &changes)
+	return changes
+}
+
+// diffValues walks structs field by field and compares everything else as a whole
+func diffValues(path string, a, b reflect.Value, changes *[]Change) {
+	if a.Kind() == reflect.Struct {
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(name, a.Field(i), b.Field(i), changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		*changes = append(*changes, Change{
+			Path: path,
+			Old:  formatValue(a),
+			New:  formatValue(b),
+		})
+	}
+}
+
+// formatValue prints a setting for the change log, with map keys sorted
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Map {
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, fmt.Sprintf("%v:%v", k.Interface(), v.MapIndex(k).Interface()))
+		}
+		sort.Strings(keys)
+		return "{" + strings.Join(keys, " ") + "}"
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// Watcher polls a config file and hands every valid change to a callback.
+// Files that fail to load or validate are rejected as a whole and the
+// previous configuration stays in effect.
+type Watcher struct {
+	path     string
+	interval time.Duration
+	current  *Config
+	onChange func(updated *Config, changes []Change) error
+
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher creates a watcher for path. current is the configuration in
+// effect; onChange is called with the new configuration and what changed,
+// and may return an error to reject it.
+func NewWatcher(path string, current *Config, interval time.Duration, onChange func(updated *Config, changes []Change) error) *Watcher {
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		current:  current,
+		onChange: onChange,
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	return w
+}
+
+// Run polls the file until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check reloads the file if it was modified since the last check
+func (w *Watcher) check() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+	// Remember the version even if it's invalid so the error is logged once
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	updated, err := LoadConfig(w.path)
+	if err != nil {
+		log.Error("Rejected config reload, keeping the current settings: %v", err)
+		return
+	}
+
+	changes := Diff(w.current, updated)
+	if len(changes) == 0 {
+		return
+	}
+	if err := w.onChange(updated, changes); err != nil {
+		log.Error("Rejected config reload, keeping the current settings: %v", err)
+		return
+	}
+	w.current = updated
+}
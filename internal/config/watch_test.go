@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := DefaultConfig()
+	updated := DefaultConfig()
+	updated.Crawler.Workers = old.Crawler.Workers + 1
+	updated.Filters.AllowedDomains = []string{"example.com"}
+
+	changes := Diff(old, updated)
+	if len(changes) != 2 {
+		t.Fatalf("Diff() = %v, want 2 changes", changes)
+	}
+	if changes[0].Path != "crawler.workers" || changes[1].Path != "filters.allowed_domains" {
+		t.Fatalf("Diff() paths = %q, %q", changes[0].Path, changes[1].Path)
+	}
+	if changes[1].New != "[example.com]" {
+		t.Fatalf("Diff() new value = %q", changes[1].New)
+	}
+}
+
+// writeConfig writes data to path with the given modification time, so
+// polling sees a change regardless of the file system's mtime resolution
+func writeConfig(t *testing.T, path, data string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeConfig(t, path, "crawler:\n  workers: 4\n", start)
+	current, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Change
+	calls := 0
+	w := NewWatcher(path, current, time.Second, func(updated *Config, changes []Change) error {
+		calls++
+		got = changes
+		return nil
+	})
+
+	// Unchanged file
+	w.check()
+	if calls != 0 {
+		t.Fatal("unchanged file was reloaded")
+	}
+
+	writeConfig(t, path, "crawler:\n  workers: 8\n", start.Add(time.Minute))
+	w.check()
+	if calls != 1 || len(got) != 1 || got[0].Path != "crawler.workers" || got[0].New != "8" {
+		t.Fatalf("reload reported %v after %d calls", got, calls)
+	}
+	if w.current.Crawler.Workers != 8 {
+		t.Fatalf("watcher kept workers = %d", w.current.Crawler.Workers)
+	}
+
+	// A new mtime without a setting change isn't handed on
+	writeConfig(t, path, "crawler:\n  workers: 8\n", start.Add(2*time.Minute))
+	w.check()
+	if calls != 1 {
+		t.Fatal("file without changes was handed on")
+	}
+}
+
+func TestWatcherRejectsInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	start := time.Now().Add(-time.Hour)
+	writeConfig(t, path, "crawler:\n  workers: 4\n", start)
+	current, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	reject := errors.New("rejected")
+	w := NewWatcher(path, current, time.Second, func(updated *Config, changes []Change) error {
+		calls++
+		return reject
+	})
+
+	writeConfig(t, path, "queue:\n  backend: disk\n", start.Add(time.Minute))
+	w.check()
+	if calls != 0 {
+		t.Fatal("invalid config was handed on")
+	}
+
+	// A change the callback rejects leaves the current config in place
+	writeConfig(t, path, "crawler:\n  workers: 6\n", start.Add(2*time.Minute))
+	w.check()
+	if calls != 1 || w.current != current {
+		t.Fatalf("rejected change replaced the config after %d calls", calls)
+	}
+}
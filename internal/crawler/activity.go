@@ -0,0 +1,120 @@
+package crawler
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"web-crawler/internal/webui"
+)
+
+// recentLimit is how many crawled pages and errors the web UI lists
+const recentLimit = 100
+
+// hostCounts is the per-host breakdown shown on the dashboards
+type hostCounts struct {
+	pages   int64
+	errors  int64
+	bytes   int64
+	latency time.Duration // Sum over pages, for the average
+}
+
+// activity keeps the recent pages, recent errors, and per-host counts of a crawl
+type activity struct {
+	mu     sync.Mutex
+	pages  []webui.Page    // Newest last
+	errors []webui.Failure // Newest last
+	hosts  map[string]*hostCounts
+
+	statuses map[int]int64 // Responses by status code
+}
+
+func newActivity() *activity {
+	return &activity{hosts: make(map[string]*hostCounts), statuses: make(map[int]int64)}
+}
+
+// fetched counts a response by its status code
+func (a *activity) fetched(status int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.statuses[status]++
+}
+
+// crawled records a fetched page
+func (a *activity) crawled(rawURL, host string, status int, latency time.Duration, bytes int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pages = appendRecent(a.pages, webui.Page{
+		URL:     rawURL,
+		Status:  status,
+		Latency: float64(latency) / float64(time.Millisecond),
+		Bytes:   bytes,
+		Time:    time.Now(),
+	})
+	h := a.host(host)
+	h.pages++
+	h.bytes += int64(bytes)
+	h.latency += latency
+}
+
+// failed records an error on rawURL
+func (a *activity) failed(rawURL, host, reason string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.errors = appendRecent(a.errors, webui.Failure{URL: rawURL, Error: reason, Time: time.Now()})
+	a.host(host).errors++
+}
+
+// host returns the counts of host, keyed like the host budget.
+// Caller must hold a.mu.
+func (a *activity) host(host string) *hostCounts {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(host, "[") {
+		host = h
+	}
+	host = strings.TrimPrefix(host, "www.")
+
+	h, ok := a.hosts[host]
+	if !ok {
+		h = &hostCounts{}
+		a.hosts[host] = h
+	}
+	return h
+}
+
+// appendRecent appends v and drops the oldest entries beyond recentLimit
+func appendRecent[T any](list []T, v T) []T {
+	list = append(list, v)
+	if len(list) > recentLimit {
+		list = append(list[:0], list[len(list)-recentLimit:]...)
+	}
+	return list
+}
+
+// newestFirst returns a reversed copy of list
+func newestFirst[T any](list []T) []T {
+	out := make([]T, len(list))
+	for i, v := range list {
+		out[len(list)-1-i] = v
+	}
+	return out
+}
+
+// RecentPages returns the last crawled pages, newest first
+func (c *Crawler) RecentPages() []webui.Page {
+	c.activity.mu.Lock()
+	defer c.activity.mu.Unlock()
+
+	return newestFirst(c.activity.pages)
+}
+
+// RecentErrors returns the last fetch and storage errors, newest first
+func (c *Crawler) RecentErrors() []webui.Failure {
+	c.activity.mu.Lock()
+	defer c.activity.mu.Unlock()
+
+	return newestFirst(c.activity.errors)
+}
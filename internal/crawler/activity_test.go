@@ -0,0 +1,43 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestActivityKeepsRecentEntries(t *testing.T) {
+	a := newActivity()
+	for i := 0; i < recentLimit+5; i++ {
+		a.crawled(fmt.Sprintf("https://a.com/%d", i), "a.com", 200, time.Millisecond, 10)
+	}
+	a.failed("https://a.com/x", "a.com", "timeout")
+
+	c := &Crawler{activity: a}
+	pages := c.RecentPages()
+	if len(pages) != recentLimit {
+		t.Fatalf("kept %d pages, want %d", len(pages), recentLimit)
+	}
+	if pages[0].URL != fmt.Sprintf("https://a.com/%d", recentLimit+4) || pages[recentLimit-1].URL != "https://a.com/5" {
+		t.Fatalf("pages run from %s to %s", pages[0].URL, pages[recentLimit-1].URL)
+	}
+	if errs := c.RecentErrors(); len(errs) != 1 || errs[0].Error != "timeout" {
+		t.Fatalf("RecentErrors() = %+v", errs)
+	}
+}
+
+func TestActivityGroupsHosts(t *testing.T) {
+	a := newActivity()
+	a.crawled("https://www.a.com/", "WWW.A.com", 200, 10*time.Millisecond, 100)
+	a.crawled("https://a.com:8443/", "a.com:8443", 200, 30*time.Millisecond, 50)
+	a.failed("https://a.com/x", "a.com", "404")
+	a.crawled("http://[::1]:8080/", "[::1]:8080", 200, time.Millisecond, 1)
+
+	h := a.hosts["a.com"]
+	if h == nil || h.pages != 2 || h.errors != 1 || h.bytes != 150 || h.latency != 40*time.Millisecond {
+		t.Fatalf("a.com counts = %+v", h)
+	}
+	if len(a.hosts) != 2 {
+		t.Fatalf("hosts = %v", a.hosts)
+	}
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// autoscaler resizes the worker pool from queue depth and fetch latency
+type autoscaler struct {
+	mu  sync.Mutex
+	cfg config.AutoscaleConfig
+
+	// Fetches since the last resize
+	latencySum   int64 // Nanoseconds
+	latencyCount int64
+
+	// Counters
+	scaleUps   int64
+	scaleDowns int64
+	lastAvg    int64 // Average latency seen at the last resize, nanoseconds
+}
+
+func newAutoscaler(cfg config.AutoscaleConfig) *autoscaler {
+	return &autoscaler{cfg: cfg}
+}
+
+// config returns the current settings
+func (a *autoscaler) config() config.AutoscaleConfig {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.cfg
+}
+
+// setConfig applies reloaded settings from the next resize on
+func (a *autoscaler) setConfig(cfg config.AutoscaleConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cfg = cfg
+}
+
+// interval returns the resize interval, which is only validated while enabled
+func (a *autoscaler) interval() time.Duration {
+	if d := a.config().Interval; d > 0 {
+		return d
+	}
+	return 5 * time.Second
+}
+
+// observe records the latency of one fetch
+func (a *autoscaler) observe(latency time.Duration) {
+	atomic.AddInt64(&a.latencySum, int64(latency))
+	atomic.AddInt64(&a.latencyCount, 1)
+}
+
+// clamp keeps n between the configured bounds
+func (a *autoscaler) clamp(n int) int {
+	cfg := a.config()
+	if !cfg.Enabled {
+		return n
+	}
+	return max(cfg.MinWorkers, min(n, cfg.MaxWorkers))
+}
+
+// target decides the pool size for the next interval. Latency above the
+// target means hosts are struggling, so the pool shrinks; a deep queue grows
+// it; more workers than queued URLs shrinks it. Steps are a quarter of the
+// pool so large pools converge quickly and small ones don't overshoot.
+func (a *autoscaler) target(cfg config.AutoscaleConfig, workers, queued int, avg time.Duration, fetches int64) int {
+	step := max(1, workers/4)
+	switch {
+	case fetches > 0 && avg > cfg.TargetLatency:
+		workers -= step
+	case queued > workers*cfg.QueuePerWorker:
+		workers += step
+	case queued < workers:
+		workers -= step
+	}
+	return max(cfg.MinWorkers, min(workers, cfg.MaxWorkers))
+}
+
+// sample returns the average latency of the fetches observed since the
+// last sample, and how many there were
+func (a *autoscaler) sample() (time.Duration, int64) {
+	sum := atomic.SwapInt64(&a.latencySum, 0)
+	fetches := atomic.SwapInt64(&a.latencyCount, 0)
+	if fetches == 0 {
+		return 0, 0
+	}
+	avg := time.Duration(sum / fetches)
+	atomic.StoreInt64(&a.lastAvg, int64(avg))
+	return avg, fetches
+}
+
+// run resizes the pool of c every interval until ctx is done
+func (a *autoscaler) run(ctx context.Context, c *Crawler) {
+	timer := time.NewTimer(a.interval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		cfg := a.config()
+		avg, fetches := a.sample()
+		if cfg.Enabled && !c.Paused() {
+			workers := c.Workers()
+			queued := c.queue.Size()
+			if n := a.target(cfg, workers, queued, avg, fetches); n != workers {
+				if n > workers {
+					atomic.AddInt64(&a.scaleUps, 1)
+				} else {
+					atomic.AddInt64(&a.scaleDowns, 1)
+				}
+				c.log.Info("Autoscaling workers %d -> %d (queued %d, avg fetch latency %s)", workers, n, queued, avg.Round(time.Millisecond))
+				c.SetWorkers(n)
+			}
+		}
+
+		// Pick up a reloaded interval
+		timer.Reset(a.interval())
+	}
+}
+
+// GetStats returns autoscaler statistics
+func (a *autoscaler) GetStats() map[string]int64 {
+	cfg := a.config()
+	enabled := int64(0)
+	if cfg.Enabled {
+		enabled = 1
+	}
+	return map[string]int64{
+		"enabled":      enabled,
+		"minWorkers":   int64(cfg.MinWorkers),
+		"maxWorkers":   int64(cfg.MaxWorkers),
+		"scaleUps":     atomic.LoadInt64(&a.scaleUps),
+		"scaleDowns":   atomic.LoadInt64(&a.scaleDowns),
+		"avgLatencyMs": time.Duration(atomic.LoadInt64(&a.lastAvg)).Milliseconds(),
+	}
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func autoscaleConfig() config.AutoscaleConfig {
+	return config.AutoscaleConfig{
+		Enabled:        true,
+		MinWorkers:     2,
+		MaxWorkers:     20,
+		Interval:       time.Second,
+		TargetLatency:  time.Second,
+		QueuePerWorker: 10,
+	}
+}
+
+func TestAutoscalerTarget(t *testing.T) {
+	a := newAutoscaler(autoscaleConfig())
+	tests := []struct {
+		name    string
+		workers int
+		queued  int
+		avg     time.Duration
+		fetches int64
+		want    int
+	}{
+		{"deep queue", 8, 81, 100 * time.Millisecond, 50, 10},
+		{"queue at the threshold", 8, 80, 100 * time.Millisecond, 50, 8},
+		{"slow fetches", 8, 500, 2 * time.Second, 50, 6},
+		{"slow average without fetches", 8, 500, 2 * time.Second, 0, 10},
+		{"fewer URLs than workers", 8, 3, 0, 0, 6},
+		{"small pool steps by one", 3, 100, 0, 0, 4},
+		{"capped at max", 19, 1000, 0, 0, 20},
+		{"held at max", 20, 1000, 0, 0, 20},
+		{"floored at min", 2, 0, 0, 0, 2},
+		{"slow at min", 3, 0, 5 * time.Second, 10, 2},
+		{"outside the bounds", 40, 100, 0, 0, 20},
+	}
+	for _, tt := range tests {
+		if got := a.target(a.config(), tt.workers, tt.queued, tt.avg, tt.fetches); got != tt.want {
+			t.Errorf("%s: target(%d workers, %d queued) = %d, want %d", tt.name, tt.workers, tt.queued, got, tt.want)
+		}
+	}
+}
+
+func TestAutoscalerConverges(t *testing.T) {
+	a := newAutoscaler(autoscaleConfig())
+
+	// Each interval moves the pool by at most a quarter, until it hits the max
+	workers, sizes := 4, []int{}
+	for i := 0; i < 8; i++ {
+		avg, fetches := a.sample()
+		workers = a.target(a.config(), workers, 10000, avg, fetches)
+		sizes = append(sizes, workers)
+	}
+	want := []int{5, 6, 7, 8, 10, 12, 15, 18}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Fatalf("pool sizes = %v, want %v", sizes, want)
+		}
+	}
+
+	// Slow fetches in one interval shrink the pool once, the next interval
+	// only sees its own fetches
+	a.observe(3 * time.Second)
+	a.observe(time.Second)
+	avg, fetches := a.sample()
+	if avg != 2*time.Second || fetches != 2 {
+		t.Fatalf("sample() = %v over %d fetches", avg, fetches)
+	}
+	workers = a.target(a.config(), workers, 10000, avg, fetches)
+	a.observe(200 * time.Millisecond)
+	avg, fetches = a.sample()
+	if next := a.target(a.config(), workers, 10000, avg, fetches); workers != 14 || next != 17 {
+		t.Fatalf("pool shrank to %d, then went to %d, want 14 then 17", workers, next)
+	}
+	if ms := a.GetStats()["avgLatencyMs"]; ms != 200 {
+		t.Errorf("avgLatencyMs = %d, want the latest interval's 200", ms)
+	}
+
+	// An interval without fetches keeps the last average in the stats
+	if avg, fetches := a.sample(); avg != 0 || fetches != 0 || a.GetStats()["avgLatencyMs"] != 200 {
+		t.Errorf("empty sample() = %v over %d fetches, stats %v", avg, fetches, a.GetStats())
+	}
+}
+
+func TestAutoscalerClamp(t *testing.T) {
+	a := newAutoscaler(autoscaleConfig())
+	for n, want := range map[int]int{1: 2, 8: 8, 50: 20} {
+		if got := a.clamp(n); got != want {
+			t.Errorf("clamp(%d) = %d, want %d", n, got, want)
+		}
+	}
+
+	// A reload turning autoscaling off keeps the configured workers
+	cfg := autoscaleConfig()
+	cfg.Enabled = false
+	cfg.Interval = 0
+	a.setConfig(cfg)
+	if got := a.clamp(50); got != 50 {
+		t.Errorf("clamp(50) while disabled = %d", got)
+	}
+	if a.interval() != 5*time.Second || a.GetStats()["enabled"] != 0 {
+		t.Errorf("interval() = %v, stats %v", a.interval(), a.GetStats())
+	}
+}
@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"web-crawler/internal/storage"
+)
+
+func TestDetectChange(t *testing.T) {
+	body := []byte("<html>v2</html>")
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		prev    *storage.PageState
+		changed bool
+		entry   *storage.ContentChange
+	}{
+		{"first crawl", nil, false, &storage.ContentChange{At: at, Hash: hash}},
+		{"no stored hash", &storage.PageState{ETag: `"x"`}, false, &storage.ContentChange{At: at, Hash: hash}},
+		{"unchanged", &storage.PageState{ContentHash: hash}, false, nil},
+		{"changed", &storage.PageState{ContentHash: "old"}, true, &storage.ContentChange{At: at, Hash: hash, Previous: "old"}},
+	}
+	for _, tt := range tests {
+		page := &storage.WebPage{CrawledAt: at}
+		if got := detectChange(page, body, tt.prev); got != tt.changed || page.Changed != tt.changed {
+			t.Errorf("%s: detectChange() = %v, Changed %v", tt.name, got, page.Changed)
+		}
+		if page.ContentHash != hash {
+			t.Errorf("%s: content hash %s", tt.name, page.ContentHash)
+		}
+		switch {
+		case tt.entry == nil && len(page.Changes) != 0:
+			t.Errorf("%s: history entries %+v", tt.name, page.Changes)
+		case tt.entry != nil && (len(page.Changes) != 1 || page.Changes[0] != *tt.entry):
+			t.Errorf("%s: history entries %+v, want %+v", tt.name, page.Changes, *tt.entry)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package crawler
+
+import (
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/ratelimit"
+)
+
+// holdBack parks item while the circuit of its host is open or a probe is
+// out. The host is paused for the rest of the cooldown, or until the probe
+// closes the circuit, in case nothing paused it yet.
+func (c *Crawler) holdBack(item queue.URLItem, host string) {
+	if c.hosts.Park(item) {
+		return
+	}
+	c.hosts.Pause(host, c.breaker.Remaining(host), c.circuitExpired)
+	if !c.hosts.Park(item) {
+		c.interrupted(item)
+	}
+}
+
+// interruptedProbe hands back an item whose request didn't go out. If it
+// was the probe of its host, the probe is released and the URLs parked
+// meanwhile are requeued, so one of them probes the host instead.
+func (c *Crawler) interruptedProbe(item queue.URLItem, host string, probe bool) {
+	c.interrupted(item)
+	if probe {
+		c.breaker.Release(host)
+		c.ResumeHost(host)
+	}
+}
+
+// recordOutcome feeds the outcome of a request to the circuit breaker and
+// pauses or resumes the host when its circuit opens or closes
+func (c *Crawler) recordOutcome(host string, failed bool) {
+	switch c.breaker.Record(host, failed) {
+	case ratelimit.CircuitOpen:
+		d := c.breaker.Remaining(host)
+		c.hosts.Pause(host, d, c.circuitExpired)
+		c.log.Warn("Circuit of %s opened, holding back its URLs for %s", host, d.Round(time.Second))
+	case ratelimit.CircuitClosed:
+		n, _ := c.ResumeHost(host)
+		c.log.Info("Circuit of %s closed, requeued %d URLs", host, n)
+	}
+}
+
+// circuitExpired requeues the URLs of a host whose cooldown is over. The
+// first of them to be fetched probes the host.
+func (c *Crawler) circuitExpired(host string, items []queue.URLItem) {
+	c.requeue(items)
+	c.log.Info("Cooldown of %s over, probing it with one of %d URLs", host, len(items))
+}
+
+// reloadBreaker applies new circuit breaker settings. Hosts held back by the
+// breaker are resumed when it is turned off.
+func (c *Crawler) reloadBreaker(cfg config.CircuitBreakerConfig) {
+	if !cfg.Enabled {
+		for _, circuit := range c.breaker.Circuits() {
+			c.ResumeHost(circuit.Host)
+		}
+	}
+	c.breaker.Reload(cfg)
+}
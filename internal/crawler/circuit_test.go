@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/ratelimit"
+)
+
+func TestCircuitHoldsBackHost(t *testing.T) {
+	c := &Crawler{
+		log:     log,
+		queue:   queue.NewURLQueue(),
+		hosts:   queue.NewHostPauses(),
+		breaker: ratelimit.NewBreaker(config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 2, Cooldown: 50 * time.Millisecond}),
+	}
+	item := queue.URLItem{URL: "https://a.com/1", Host: "a.com", Priority: queue.PriorityNormal}
+
+	c.recordOutcome("a.com", true)
+	c.recordOutcome("a.com", true)
+	if paused := c.PausedHosts(); len(paused) != 1 || paused[0].Host != "a.com" || paused[0].Until == nil {
+		t.Fatalf("open circuit didn't pause its host: %+v", paused)
+	}
+	if ok, _ := c.breaker.Allow("a.com"); ok {
+		t.Fatal("open circuit allowed a request")
+	}
+	c.holdBack(item, "a.com")
+	if c.hosts.Parked() != 1 || c.queue.Size() != 0 {
+		t.Fatalf("URL wasn't parked: %d parked, %d queued", c.hosts.Parked(), c.queue.Size())
+	}
+
+	// The cooldown ends by requeueing the URL for the probe
+	deadline := time.Now().Add(2 * time.Second)
+	for c.queue.Size() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("URL wasn't requeued after the cooldown")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, probe := c.breaker.Allow("a.com"); !probe {
+		t.Fatal("no probe after the cooldown")
+	}
+
+	// URLs popped while the probe is out wait for it
+	c.holdBack(queue.URLItem{URL: "https://a.com/2", Host: "a.com"}, "a.com")
+	if paused := c.PausedHosts(); len(paused) != 1 || paused[0].Until != nil || paused[0].Parked != 1 {
+		t.Fatalf("paused hosts during the probe = %+v", paused)
+	}
+	c.recordOutcome("a.com", false)
+	if len(c.PausedHosts()) != 0 || c.queue.Size() != 2 {
+		t.Fatalf("closing the circuit left %d paused hosts, %d queued", len(c.PausedHosts()), c.queue.Size())
+	}
+}
+
+func TestCircuitReleasesInterruptedProbe(t *testing.T) {
+	c := &Crawler{
+		log:     log,
+		queue:   queue.NewURLQueue(),
+		hosts:   queue.NewHostPauses(),
+		breaker: ratelimit.NewBreaker(config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, Cooldown: time.Millisecond}),
+	}
+	c.recordOutcome("a.com", true)
+	time.Sleep(5 * time.Millisecond)
+	if _, probe := c.breaker.Allow("a.com"); !probe {
+		t.Fatal("no probe after the cooldown")
+	}
+	c.holdBack(queue.URLItem{URL: "https://a.com/2", Host: "a.com"}, "a.com")
+
+	// The probe's worker stops before sending it
+	c.interruptedProbe(queue.URLItem{URL: "https://a.com/1", Host: "a.com"}, "a.com", true)
+	if c.hosts.Parked() != 0 || c.queue.Size() != 2 {
+		t.Fatalf("interrupted probe left %d parked, %d queued", c.hosts.Parked(), c.queue.Size())
+	}
+	if _, probe := c.breaker.Allow("a.com"); !probe {
+		t.Fatal("no new probe after the first was interrupted")
+	}
+}
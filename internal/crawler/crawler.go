@@ -0,0 +1,1115 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/accessibility"
+	"web-crawler/internal/api"
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/checkpoint"
+	"web-crawler/internal/config"
+	"web-crawler/internal/dashboard"
+	"web-crawler/internal/debug"
+	"web-crawler/internal/dedup"
+	"web-crawler/internal/document"
+	"web-crawler/internal/extract"
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/filter"
+	"web-crawler/internal/focus"
+	"web-crawler/internal/graph"
+	"web-crawler/internal/graphql"
+	"web-crawler/internal/grpcapi"
+	"web-crawler/internal/linkcheck"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/login"
+	"web-crawler/internal/publish"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/ratelimit"
+	"web-crawler/internal/robots"
+	"web-crawler/internal/scheduler"
+	"web-crawler/internal/scoring"
+	"web-crawler/internal/search"
+	"web-crawler/internal/seeds"
+	"web-crawler/internal/seo"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/subdomain"
+	"web-crawler/internal/telemetry"
+	"web-crawler/internal/trace"
+	"web-crawler/internal/webui"
+	"web-crawler/pkg/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// log is the logger of the crawler package
+var log = logger.For("crawler")
+
+// idleTimeout is how long the frontier must stay empty with no work in flight
+// before a crawl is considered finished
+const idleTimeout = 2 * time.Second
+
+// statsQueueTop is how many URLs per priority band and hosts the queue
+// snapshot in the stats lists
+const statsQueueTop = 5
+
+// Options holds per-run settings that don't belong in the config file
+type Options struct {
+	MongoURI   string                 // Store pages in MongoDB when set
+	Resume     bool                   // Replay the persistent queue log
+	Checkpoint *checkpoint.Checkpoint // Frontier to restore before crawling
+	ConfigPath string                 // Config file watched for hot reloads, if enabled
+	JobID      string                 // Crawl job, tags stats and log messages
+
+	Fetcher   Fetcher                    // Downloads pages instead of the HTTP fetcher, if set
+	Archivers []storage.Archiver         // Store pages in addition to the configured backends
+	Filters   []func(rawURL string) bool // Checks every URL must pass to be queued
+
+	Extractors map[string]document.Extractor // Document text extractors by media type, when documents are enabled
+}
+
+// Fetcher downloads pages in place of the built-in HTTP fetcher. Robots.txt
+// and assets are still fetched over HTTP.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*fetcher.Response, error)
+}
+
+// Crawler wires the fetcher, frontier, filters, and storage into a worker pool
+type Crawler struct {
+	cfg        *config.Config
+	configPath string
+	jobID      string
+	log        *logger.Logger
+
+	fetcher     *fetcher.Fetcher
+	custom      Fetcher // Replaces fetcher for pages, nil for the built-in one
+	queue       queue.URLQueue
+	filter      *filter.Filter
+	lists       *filter.DomainLists // Block and allow lists, nil without any
+	budget      *filter.Budget
+	seen        *dedup.URLFilter
+	content     *dedup.ContentHasher
+	robots      *robots.Checker
+	limiter     *ratelimit.AdaptiveLimiter
+	breaker     *ratelimit.Breaker
+	archiver    *storage.BroadcastArchiver
+	projection  storage.Projection
+	mongo       *storage.MongoArchiver
+	index       *search.Index // Full-text index, nil when disabled
+	publisher   *publish.Publisher
+	objects     *storage.ObjectArchiver  // S3/GCS archive, nil when disabled
+	raw         storage.RawStore         // Raw exchanges, nil when disabled
+	graph       *graph.Graph             // Link graph, nil when disabled
+	subdomains  *subdomain.Tracker       // Hosts of links, nil when disabled
+	linkcheck   *linkcheck.Checker       // Broken links, nil when disabled
+	seo         *seo.Auditor             // SEO audit, nil when disabled
+	a11y        *accessibility.Collector // Accessibility report, nil when disabled
+	prioritizer *prioritizer             // PageRank priorities, nil when disabled
+	focus       *focus.Scorer            // Topic relevance, nil when disabled
+	scorer      *scoring.Scorer          // URL scores, nil without a score expression
+	rules       *extract.Rules           // Extraction rules, nil without any
+	saver       *utils.ContentSaver
+	documents   *document.Extractors // Nil unless documents are crawled
+	json        *extract.JSONRules   // Nil unless JSON responses are crawled
+	graphql     *graphql.Prober      // Nil unless hosts are probed for GraphQL
+	session     *login.Session       // Cookies of the sites logged in to, nil without logins
+	domains     *domains             // Per-domain workers, headers and rules, nil without profiles
+	hooks       hooks                // Page pipeline, ending in the saver and the archiver
+	recrawler   *scheduler.Recrawler
+	recorder    *benchmark.Recorder
+	runs        benchmark.RunStore // Nil unless benchmark runs are kept
+	runID       string             // Of the saved benchmark run
+	deadLetters queue.DeadLetterStore
+	tracer      *trace.Tracer
+	telemetry   *telemetry.Tracer
+	apiServer   *api.Server
+	grpcServer  *grpcapi.Server
+	debugServer *debug.Server
+
+	rateLimit int64 // Delay between two requests of a worker, in nanoseconds
+	paused    int32
+	pauseMu   sync.Mutex
+	resumeCh  chan struct{}
+	hosts     *queue.HostPauses // Paused hosts and their parked URLs
+
+	autoscale   *autoscaler
+	parsers     *parsePool // Nil when the fetch workers parse pages
+	workersMu   sync.Mutex
+	workerStops []chan struct{} // One per running worker, closed to stop it
+	workerState []*workerState  // What each running worker is doing
+	workerWG    sync.WaitGroup
+	runCtx      context.Context
+
+	inFlight sync.WaitGroup
+	active   int64
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	stopped  chan struct{}
+
+	activity *activity // Recent pages and errors for the dashboards
+
+	// Counters
+	pagesCrawled   int64
+	pagesStored    int64
+	errors         int64
+	notModified    int64
+	robotsBlocked  int64
+	nearDupes      int64
+	assetsSaved    int64
+	mirrorPages    int64
+	mirrorFiles    int64
+	jsonPages      int64
+	linksQueued    int64
+	hookSkips      int64
+	contentChanges int64
+
+	retentionSweeps  int64
+	retentionDeleted int64
+	retentionFreed   int64
+}
+
+// New builds a crawler and all of its components from the configuration
+func New(cfg *config.Config, opts Options) (*Crawler, error) {
+	// Configs built in code don't go through LoadConfig
+	cfg.ApplyDomains()
+	profiles, err := newDomains(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile domain profiles: %w", err)
+	}
+	documents, err := document.New(cfg.Documents, opts.Extractors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document extractors: %w", err)
+	}
+	jsonRules, err := extract.NewJSONRules(cfg.JSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile json rules: %w", err)
+	}
+	httpCfg := cfg.HTTP
+	if len(httpCfg.AllowedContentTypes) > 0 {
+		allowed := append([]string(nil), httpCfg.AllowedContentTypes...)
+		if documents != nil {
+			allowed = append(allowed, documents.Types()...)
+		}
+		if jsonRules != nil {
+			allowed = append(allowed, jsonRules.Types()...)
+		}
+		httpCfg.AllowedContentTypes = allowed
+	}
+
+	f, err := fetcher.New(httpCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fetcher: %w", err)
+	}
+	if profiles != nil {
+		f.SetHeaders(profiles.headers)
+	}
+	session := login.New(cfg.HTTP.Login)
+	if session != nil {
+		f.SetCookieJar(session)
+	}
+
+	q, err := queue.NewFromConfig(cfg.Queue, opts.Resume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue: %w", err)
+	}
+	if err := setFrontier(q, cfg.Crawler.Frontier); err != nil {
+		q.Close()
+		return nil, err
+	}
+
+	urlFilter, err := filter.New(cfg.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create url filter: %w", err)
+	}
+	for _, fn := range opts.Filters {
+		urlFilter.AddFunc(fn)
+	}
+	if documents != nil {
+		urlFilter.KeepExtensions(documents.Extensions()...)
+	}
+	if jsonRules != nil {
+		urlFilter.KeepExtensions(".json")
+	}
+	lists := filter.NewDomainLists(cfg.Filters.Lists, f.Client(), cfg.HTTP.UserAgent)
+	urlFilter.SetLists(lists)
+
+	store, err := dedup.NewStore(cfg.Dedup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dedup store: %w", err)
+	}
+
+	c := &Crawler{
+		cfg:        cfg,
+		configPath: opts.ConfigPath,
+		jobID:      opts.JobID,
+		log:        log,
+		fetcher:    f,
+		custom:     opts.Fetcher,
+		queue:      q,
+		filter:     urlFilter,
+		lists:      lists,
+		budget:     filter.NewBudget(cfg.Crawler),
+		seen:       dedup.NewURLFilter(store),
+		robots:     robots.NewChecker(f.Client(), cfg.Robots, cfg.HTTP.UserAgent),
+		limiter:    ratelimit.NewAdaptiveLimiter(cfg.Filters.RateLimits),
+		breaker:    ratelimit.NewBreaker(cfg.Filters.RateLimits.Breaker),
+		documents:  documents,
+		json:       jsonRules,
+		session:    session,
+		domains:    profiles,
+		saver:      utils.NewContentSaver(cfg.ContentSaver.OutputDir, cfg.ContentSaver.Enabled, cfg.ContentSaver.MaxFileSize),
+		recorder:   benchmark.New(),
+		rateLimit:  int64(cfg.Crawler.RateLimit),
+		resumeCh:   make(chan struct{}),
+		hosts:      queue.NewHostPauses(),
+		stopped:    make(chan struct{}),
+		activity:   newActivity(),
+		autoscale:  newAutoscaler(cfg.Crawler.Autoscale),
+		parsers:    newParsePool(cfg.Crawler.Parse.Workers, cfg.Crawler.Parse.Queue),
+		projection: storage.NewProjection(cfg.Storage.Fields),
+		graph:      graph.New(cfg.Graph),
+		subdomains: subdomain.New(cfg.Subdomains),
+		seo:        seo.New(cfg.SEO),
+		a11y:       accessibility.New(cfg.A11y),
+		focus:      focus.New(cfg.Focus),
+	}
+	if opts.JobID != "" {
+		c.log = log.WithJob(opts.JobID)
+	}
+	c.prioritizer = newPrioritizer(c.graph, cfg.Graph, c.log)
+	if cfg.ContentSaver.Enabled {
+		if err := c.saver.SetFormat(cfg.ContentSaver.Format); err != nil {
+			return nil, err
+		}
+		if cfg.ContentSaver.Archive != "" {
+			if err := c.saver.SetArchive(cfg.ContentSaver.Archive, cfg.ContentSaver.ArchivePer, time.Now()); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.ContentSaver.Mirror.Enabled {
+			// Links to pages the crawl can reach stay inside the copy
+			c.saver.SetMirror(cfg.ContentSaver.Mirror.Dir, c.filter.InScope)
+		}
+		if cfg.ContentSaver.Format == utils.FormatWARC {
+			// WARC records keep the exchange as it went over the wire
+			c.fetcher.SetCaptureRaw(true)
+		}
+	}
+	if c.rules, err = extract.NewRules(cfg.Extraction); err != nil {
+		return nil, fmt.Errorf("failed to compile extraction rules: %w", err)
+	}
+	if c.scorer, err = scoring.New(cfg.Crawler.Score); err != nil {
+		return nil, err
+	}
+	c.graphql = graphql.New(cfg.GraphQL, f.Client(), cfg.HTTP.UserAgent, cfg.HTTP.MaxBodySize, c.limiter.Wait)
+	c.linkcheck = linkcheck.New(cfg.LinkCheck, f.Client(), cfg.HTTP.UserAgent, c.limiter.Wait)
+
+	if cfg.Dedup.ContentEnabled {
+		c.content = dedup.NewContentHasher(cfg.Dedup.MaxDistance)
+	}
+
+	archivers := append([]storage.Archiver(nil), opts.Archivers...)
+	if opts.MongoURI != "" {
+		c.mongo, err = storage.NewMongoArchiver(opts.MongoURI, cfg.Storage.MongoDB)
+		if err != nil {
+			return nil, err
+		}
+		archivers = append(archivers, c.mongo)
+	}
+	if c.index, err = search.New(cfg.Storage.Search); err != nil {
+		return nil, err
+	}
+	if c.index != nil {
+		archivers = append(archivers, c.index)
+	}
+	if c.objects, err = storage.NewObjectArchiver(cfg.Storage.Object); err != nil {
+		return nil, err
+	}
+	if c.objects != nil {
+		archivers = append(archivers, c.objects)
+	}
+	if c.publisher, err = publish.New(cfg.Storage.Publish); err != nil {
+		return nil, err
+	}
+	if c.publisher != nil {
+		archivers = append(archivers, c.publisher)
+	}
+	c.archiver = storage.NewBroadcastArchiver(storage.NewMultiArchiver(archivers...))
+	c.hooks.store = []PageHook{c.saveContent, c.archive}
+	if c.raw, err = storage.NewRawStore(cfg.Storage.Raw, c.mongo); err != nil {
+		return nil, err
+	}
+	if c.raw != nil {
+		c.fetcher.SetCaptureRaw(true)
+		c.hooks.store = []PageHook{c.saveContent, c.storeRaw, c.archive}
+	}
+	c.hooks.builtin = len(c.hooks.store)
+
+	if cfg.Queue.DeadLetter.Backend == "mongodb" {
+		if c.mongo == nil {
+			return nil, fmt.Errorf("mongodb dead letter backend requires a MongoDB connection")
+		}
+		c.deadLetters, err = c.mongo.DeadLetters(context.Background(), cfg.Queue.DeadLetter.Collection)
+	} else {
+		c.deadLetters, err = queue.NewDeadLetterStore(cfg.Queue.DeadLetter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead letter store: %w", err)
+	}
+	f.SetDeadLetters(c.deadLetters)
+
+	if cfg.Benchmark.Enabled {
+		switch cfg.Benchmark.Runs.Backend {
+		case "dir":
+			c.runs = benchmark.NewDirRunStore(cfg.Benchmark.Runs.Dir)
+		case "mongodb":
+			if c.mongo == nil {
+				return nil, fmt.Errorf("mongodb benchmark runs backend requires a MongoDB connection")
+			}
+			c.runs = c.mongo.BenchmarkRuns(cfg.Benchmark.Runs.Collection)
+		}
+	}
+
+	if c.tracer, err = trace.New(cfg.Logging.Trace); err != nil {
+		return nil, fmt.Errorf("failed to open trace log: %w", err)
+	}
+	if c.telemetry, err = telemetry.New(cfg.Telemetry); err != nil {
+		return nil, fmt.Errorf("failed to start telemetry: %w", err)
+	}
+
+	if cfg.Recrawl.Enabled {
+		c.recrawler = scheduler.NewRecrawler(cfg.Recrawl, q)
+	}
+
+	if opts.Checkpoint != nil {
+		// Hosts of the saved frontier stand in for the seed hosts of the original run
+		for _, item := range opts.Checkpoint.Queue {
+			urlFilter.AddSeedHost(item.Host)
+		}
+		opts.Checkpoint.Restore(context.Background(), q, c.seen)
+	}
+
+	if cfg.API.Enabled {
+		c.apiServer = api.NewServer(cfg.API.Addr, c)
+		if cfg.API.UI {
+			webui.Register(c.apiServer, c)
+		}
+		if cfg.API.GRPCAddr != "" {
+			c.grpcServer = grpcapi.NewServer(cfg.API.GRPCAddr, c, c.archiver)
+		}
+	}
+	if cfg.Debug.Enabled {
+		c.debugServer = debug.NewServer(cfg.Debug)
+		debug.Publish("crawler", func() interface{} { return c.Stats() })
+	}
+
+	return c, nil
+}
+
+// Run crawls until the frontier is exhausted, MaxPages is reached, ctx is
+// cancelled, or Shutdown is called. On the way out in-flight requests are
+// drained and, if enabled, a checkpoint of the remaining frontier is written.
+func (c *Crawler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	defer cancel()
+
+	if c.session != nil {
+		if err := c.session.Login(ctx, c.fetcher.Client(), c.cfg.HTTP.UserAgent); err != nil {
+			return err
+		}
+	}
+	// Links found before the lists are loaded would slip past them
+	if err := c.lists.Load(ctx); err != nil {
+		return err
+	}
+	go c.lists.Run(ctx)
+	if c.apiServer != nil {
+		c.apiServer.Start()
+	}
+	if c.grpcServer != nil {
+		if gerr := c.grpcServer.Start(); gerr != nil {
+			c.log.Error("gRPC API not started: %v", gerr)
+		}
+	}
+	if c.debugServer != nil {
+		if derr := c.debugServer.Start(); derr != nil {
+			c.log.Error("Debug endpoints not started: %v", derr)
+		}
+	}
+	if c.recrawler != nil {
+		go c.recrawler.Run(ctx)
+	}
+	if c.cfg.ContentSaver.Enabled && c.cfg.ContentSaver.Retention.Enabled {
+		go c.runRetention(ctx)
+	}
+	if c.cfg.Benchmark.Enabled {
+		go c.recordMetrics(ctx)
+	}
+	if c.prioritizer != nil {
+		go c.prioritizer.run(ctx, c.queue)
+	}
+	c.linkcheck.Start(ctx)
+	if c.configPath != "" && c.cfg.Reload.Enabled {
+		go config.NewWatcher(c.configPath, c.cfg, c.cfg.Reload.Interval, c.applyConfig).Run(ctx)
+	}
+
+	workers := c.autoscale.clamp(c.cfg.Crawler.Workers)
+	if workers <= 0 {
+		workers = 1
+	}
+	go c.autoscale.run(ctx, c)
+	c.parsers.start(func(job parseJob) {
+		c.parse(job)
+		job.span.End()
+		c.completed(job.item)
+	})
+	c.log.Info("Starting crawl with %d workers, %d URLs queued", workers, c.queue.Size())
+
+	c.workersMu.Lock()
+	c.runCtx = ctx
+	c.workersMu.Unlock()
+	c.SetWorkers(workers)
+
+	// Keep drawing while the crawl drains, the context may already be cancelled
+	stopDashboard := c.startDashboard(context.Background())
+
+	// Stop once the workers are done or the crawl is cancelled from outside
+	done := make(chan struct{})
+	go func() {
+		c.workerWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		<-done
+	}
+	c.parsers.close()
+
+	err := c.finish()
+	stopDashboard()
+	return err
+}
+
+// startDashboard draws the live dashboard until the returned function is called
+func (c *Crawler) startDashboard(ctx context.Context) (stop func()) {
+	if !c.cfg.Dashboard.Enabled {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dashboard.New(c, os.Stdout, c.cfg.Dashboard.Refresh).Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// finish drains outstanding work, writes the checkpoint, and closes components
+func (c *Crawler) finish() error {
+	// URLs of paused hosts go back to the frontier so the checkpoint keeps them
+	c.requeue(c.hosts.Drain())
+
+	shutdown := &checkpoint.Shutdown{
+		InFlight:     &c.inFlight,
+		DrainTimeout: c.cfg.Checkpoint.DrainTimeout,
+		Queue:        c.queue,
+		Seen:         c.seen,
+	}
+	if c.cfg.Checkpoint.Enabled {
+		// Set even for an empty frontier, fetches cut short by the drain
+		// requeue their URLs
+		shutdown.Path = c.cfg.Checkpoint.Path
+	}
+	if pq, ok := c.queue.(*queue.PersistentQueue); ok {
+		shutdown.Flush = append(shutdown.Flush, func(context.Context) error { return pq.Sync() })
+	}
+	if c.mongo != nil {
+		shutdown.Flush = append(shutdown.Flush, c.mongo.Flush)
+	}
+	if c.publisher != nil {
+		shutdown.Flush = append(shutdown.Flush, c.publisher.Flush)
+	}
+	err := shutdown.Run(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if c.cfg.Benchmark.Enabled {
+		for _, format := range c.cfg.Benchmark.Formats {
+			var gerr error
+			switch format {
+			case "png":
+				gerr = c.recorder.GenerateGraphs(c.cfg.Benchmark.OutputDir)
+			case "html":
+				gerr = c.recorder.GenerateReport(c.cfg.Benchmark.OutputDir)
+			}
+			if gerr != nil {
+				c.log.Warn("Failed to generate benchmark %s output: %v", format, gerr)
+			}
+		}
+		if eerr := c.recorder.ExportCSV(filepath.Join(c.cfg.Benchmark.OutputDir, "metrics.csv")); eerr != nil {
+			c.log.Warn("Failed to export benchmark metrics: %v", eerr)
+		}
+		if eerr := c.recorder.ExportJSON(filepath.Join(c.cfg.Benchmark.OutputDir, "metrics.json")); eerr != nil {
+			c.log.Warn("Failed to export benchmark metrics: %v", eerr)
+		}
+		if c.runs != nil {
+			if rerr := c.saveRun(ctx); rerr != nil {
+				c.log.Warn("Failed to save benchmark run: %v", rerr)
+			}
+		}
+		if c.cfg.Benchmark.Summary {
+			if serr := c.Summary().Save(filepath.Join(c.cfg.Benchmark.OutputDir, "summary.json")); serr != nil {
+				c.log.Warn("Failed to save crawl summary: %v", serr)
+			}
+		}
+	}
+
+	if gerr := c.graph.Export(); gerr != nil {
+		c.log.Warn("Failed to export link graph: %v", gerr)
+	}
+	if serr := c.subdomains.Export(); serr != nil {
+		c.log.Warn("Failed to export subdomains: %v", serr)
+	}
+	if terr := c.fetcher.TLS().Export(); terr != nil {
+		c.log.Warn("Failed to export TLS details: %v", terr)
+	}
+	c.linkcheck.Close()
+	if lerr := c.linkcheck.Export(); lerr != nil {
+		c.log.Warn("Failed to export broken links: %v", lerr)
+	}
+	if serr := c.seo.Export(); serr != nil {
+		c.log.Warn("Failed to export SEO audit: %v", serr)
+	}
+	if aerr := c.a11y.Export(); aerr != nil {
+		c.log.Warn("Failed to export accessibility report: %v", aerr)
+	}
+	if serr := c.saver.Close(); serr != nil {
+		c.log.Warn("Failed to finish saved content: %v", serr)
+	}
+
+	if c.apiServer != nil {
+		c.apiServer.Shutdown(ctx)
+	}
+	if c.grpcServer != nil {
+		c.grpcServer.Shutdown(ctx)
+	}
+	if c.debugServer != nil {
+		c.debugServer.Shutdown(ctx)
+	}
+	c.queue.Close()
+	c.seen.Close()
+	c.deadLetters.Close()
+	if terr := c.tracer.Close(); terr != nil {
+		c.log.Warn("Failed to close trace log: %v", terr)
+	}
+	if terr := c.telemetry.Close(ctx); terr != nil {
+		c.log.Warn("Failed to export remaining spans: %v", terr)
+	}
+	if cerr := c.archiver.Close(ctx); cerr != nil && err == nil {
+		err = cerr
+	}
+
+	if dropped := c.queue.GetStats()["dropped"]; dropped > 0 {
+		c.log.Warn("%d URLs were dropped because the queue was full, set queue.overflow.policy to block or spill to keep them", dropped)
+	}
+	c.log.Success("Crawl finished: %d pages crawled, %d stored, %d errors",
+		atomic.LoadInt64(&c.pagesCrawled), atomic.LoadInt64(&c.pagesStored), atomic.LoadInt64(&c.errors))
+	c.stopOnce.Do(func() { close(c.stopped) })
+	return err
+}
+
+// SetWorkers starts or stops workers until n are running. Stopped workers
+// finish the URL they are processing first.
+func (c *Crawler) SetWorkers(n int) {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+
+	if c.runCtx == nil || c.runCtx.Err() != nil {
+		return
+	}
+	for len(c.workerStops) < n {
+		stop := make(chan struct{})
+		state := newWorkerState(len(c.workerStops) + 1)
+		c.workerStops = append(c.workerStops, stop)
+		c.workerState = append(c.workerState, state)
+		c.workerWG.Add(1)
+		go func(ctx context.Context) {
+			defer c.workerWG.Done()
+			c.worker(ctx, stop, state)
+		}(c.runCtx)
+	}
+	for len(c.workerStops) > n && len(c.workerStops) > 1 {
+		last := len(c.workerStops) - 1
+		close(c.workerStops[last])
+		c.workerStops = c.workerStops[:last]
+		c.workerState = c.workerState[:last]
+	}
+}
+
+// Workers returns the number of running workers
+func (c *Crawler) Workers() int {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+
+	return len(c.workerStops)
+}
+
+// worker pops URLs until the crawl ends or stop is closed
+func (c *Crawler) worker(ctx context.Context, stop <-chan struct{}, state *workerState) {
+	ctx = withWorker(ctx, state)
+	var idleSince time.Time
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if c.Paused() {
+			state.set(workerPaused, "")
+			c.waitIfPaused(ctx)
+		}
+		if c.cfg.Crawler.MaxPages > 0 && atomic.LoadInt64(&c.pagesCrawled) >= int64(c.cfg.Crawler.MaxPages) {
+			c.log.Info("Reached max pages (%d)", c.cfg.Crawler.MaxPages)
+			c.cancel()
+			return
+		}
+
+		// Count as active before popping so others don't see an idle crawl in between
+		atomic.AddInt64(&c.active, 1)
+		item, ok := c.queue.Pop()
+		if !ok {
+			atomic.AddInt64(&c.active, -1)
+			state.set(workerIdle, "")
+			if c.idle() && c.recrawler == nil && c.apiServer == nil {
+				if idleSince.IsZero() {
+					idleSince = time.Now()
+				} else if time.Since(idleSince) > idleTimeout {
+					return
+				}
+			}
+			sleep(ctx, 50*time.Millisecond)
+			continue
+		}
+		idleSince = time.Time{}
+		if c.hosts.Park(item) {
+			atomic.AddInt64(&c.active, -1)
+			continue
+		}
+
+		state.set(workerFetching, item.URL)
+		c.inFlight.Add(1)
+		if !c.process(ctx, item) {
+			c.completed(item)
+		}
+		atomic.AddInt64(&state.processed, 1)
+		state.set(workerIdle, "")
+
+		sleep(ctx, time.Duration(atomic.LoadInt64(&c.rateLimit)))
+	}
+}
+
+// completed acknowledges an item once its page is stored or dropped, by the
+// fetch worker or a parse worker. Until then it counts as in flight.
+func (c *Crawler) completed(item queue.URLItem) {
+	queue.Ack(c.queue, item)
+	c.inFlight.Done()
+	atomic.AddInt64(&c.active, -1)
+}
+
+// idle reports whether the frontier is empty, no worker is processing a URL
+// and no URL is parked for a paused host. With a shared queue the frontier
+// of every instance must be empty and none of them processing a URL.
+func (c *Crawler) idle() bool {
+	return queue.Pending(c.queue) == 0 && atomic.LoadInt64(&c.active) == 0 && c.hosts.Parked() == 0
+}
+
+// waitIfPaused blocks while the crawl is paused
+func (c *Crawler) waitIfPaused(ctx context.Context) {
+	for atomic.LoadInt32(&c.paused) == 1 {
+		c.pauseMu.Lock()
+		ch := c.resumeCh
+		c.pauseMu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// recordMetrics samples progress for the benchmark graphs
+func (c *Crawler) recordMetrics(ctx context.Context) {
+	interval := c.cfg.Benchmark.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.recorder.Record(int(atomic.LoadInt64(&c.pagesCrawled)), c.queue.Size(), c.Workers(), queueDepths(c.queue.GetStats()))
+		}
+	}
+}
+
+// queueDepths picks the queued URLs per priority out of the queue statistics
+func queueDepths(stats map[string]int64) map[string]int64 {
+	depths := make(map[string]int64, 3)
+	for _, priority := range []string{"high", "normal", "low"} {
+		if n, ok := stats[priority+"Buffer"]; ok {
+			depths[priority] = n
+		}
+	}
+	return depths
+}
+
+// AddSeeds validates and queues seed URLs with high priority
+func (c *Crawler) AddSeeds(urls []string) (int, error) {
+	list := make([]seeds.Seed, 0, len(urls))
+	for _, u := range urls {
+		if err := seeds.Validate(u); err != nil {
+			return 0, err
+		}
+		list = append(list, seeds.Seed{URL: u, Priority: queue.PriorityHigh})
+	}
+	return c.AddSeedList(list), nil
+}
+
+// AddSeedList queues parsed seeds, restricting the crawl to their hosts when
+// no allowed domains are configured. Already seen seeds and seeds beyond
+// their host's budget are skipped, the rest count against it.
+func (c *Crawler) AddSeedList(list []seeds.Seed) int {
+	ctx := context.Background()
+	added := 0
+	for _, seed := range list {
+		u, err := url.Parse(seed.URL)
+		if err != nil {
+			continue
+		}
+		c.filter.AddSeedHost(u.Host)
+		if ok, reason := c.budget.Check(seed.URL, seed.Depth); !ok {
+			c.tracer.Skipped(seed.URL, "", reason)
+			continue
+		}
+		if !c.seen.IsNew(ctx, seed.URL) {
+			c.tracer.Skipped(seed.URL, "", skipSeen)
+			continue
+		}
+		if ok, reason := c.budget.Admit(seed.URL, seed.Depth); !ok {
+			c.tracer.Skipped(seed.URL, "", reason)
+			continue
+		}
+		c.queue.PushWithPriority(seed.URL, seed.Priority, u.Host, seed.Depth)
+		c.tracer.Queued(seed.URL, "", seed.Depth)
+		added++
+	}
+	return added
+}
+
+// Pause stops workers from taking new URLs
+func (c *Crawler) Pause() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+
+	if atomic.CompareAndSwapInt32(&c.paused, 0, 1) {
+		c.resumeCh = make(chan struct{})
+	}
+}
+
+// Resume lets paused workers continue
+func (c *Crawler) Resume() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+
+	if atomic.CompareAndSwapInt32(&c.paused, 1, 0) {
+		close(c.resumeCh)
+	}
+}
+
+// Paused reports whether the crawl is paused
+func (c *Crawler) Paused() bool {
+	return atomic.LoadInt32(&c.paused) == 1
+}
+
+// PauseHost holds back the URLs of host, for d or until ResumeHost if d is 0.
+// The rest of the crawl keeps going.
+func (c *Crawler) PauseHost(host string, d time.Duration) queue.PausedHost {
+	return c.hosts.Pause(host, d, func(host string, items []queue.URLItem) {
+		c.requeue(items)
+		c.log.Info("Pause of %s expired, requeued %d URLs", host, len(items))
+	})
+}
+
+// ResumeHost lifts the pause of host and queues its parked URLs again. It
+// returns false if the host wasn't paused.
+func (c *Crawler) ResumeHost(host string) (int, bool) {
+	items, ok := c.hosts.Resume(host)
+	c.requeue(items)
+	return len(items), ok
+}
+
+// setFrontier sets the pop order of q. Only the memory and persistent queues
+// can change it.
+func setFrontier(q queue.URLQueue, order string) error {
+	if order == "" || order == queue.OrderPriority {
+		return nil
+	}
+	oq, ok := q.(queue.Orderer)
+	if !ok {
+		return fmt.Errorf("the queue backend can't pop in %s order", order)
+	}
+	return oq.SetOrder(order)
+}
+
+// requeue pushes parked items back into the frontier
+func (c *Crawler) requeue(items []queue.URLItem) {
+	for _, item := range items {
+		queue.Ack(c.queue, item)
+		queue.Repush(c.queue, item)
+	}
+}
+
+// PausedHosts lists the paused hosts
+func (c *Crawler) PausedHosts() []queue.PausedHost {
+	return c.hosts.List()
+}
+
+// QueueSnapshot describes the queued URLs with up to top of them per
+// priority band and the top busiest hosts. It returns false if the queue
+// backend can't be inspected.
+func (c *Crawler) QueueSnapshot(top int) (queue.Snapshot, bool) {
+	if iq, ok := c.queue.(queue.Inspector); ok {
+		return iq.Snapshot(top), true
+	}
+	return queue.Snapshot{}, false
+}
+
+// QueueItems calls fn with every queued URL until fn returns false. It
+// returns false if the queue backend can't be inspected.
+func (c *Crawler) QueueItems(fn func(queue.URLItem) bool) bool {
+	iq, ok := c.queue.(queue.Inspector)
+	if ok {
+		iq.Walk(fn)
+	}
+	return ok
+}
+
+// SetRateLimit changes the delay between two requests of a worker
+func (c *Crawler) SetRateLimit(d time.Duration) {
+	atomic.StoreInt64(&c.rateLimit, int64(d))
+}
+
+// RateLimit returns the delay between two requests of a worker
+func (c *Crawler) RateLimit() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.rateLimit))
+}
+
+// hostDelays returns the current delay between requests of every rate limited
+// host, including any Retry-After pause
+func (c *Crawler) hostDelays() map[string]string {
+	delays := make(map[string]string)
+	for host, d := range c.limiter.Delays() {
+		if d > 0 {
+			delays[host] = d.Round(time.Millisecond).String()
+		}
+	}
+	return delays
+}
+
+// Stats returns crawl progress and the statistics of every component
+func (c *Crawler) Stats() map[string]interface{} {
+	queueStats := c.queue.GetStats()
+	stats := map[string]interface{}{
+		"pagesCrawled":   atomic.LoadInt64(&c.pagesCrawled),
+		"pagesStored":    atomic.LoadInt64(&c.pagesStored),
+		"errors":         atomic.LoadInt64(&c.errors),
+		"notModified":    atomic.LoadInt64(&c.notModified),
+		"robotsBlocked":  atomic.LoadInt64(&c.robotsBlocked),
+		"nearDuplicates": atomic.LoadInt64(&c.nearDupes),
+		"assetsSaved":    atomic.LoadInt64(&c.assetsSaved),
+		"mirroredPages":  atomic.LoadInt64(&c.mirrorPages),
+		"mirroredFiles":  atomic.LoadInt64(&c.mirrorFiles),
+		"jsonPages":      atomic.LoadInt64(&c.jsonPages),
+		"linksQueued":    atomic.LoadInt64(&c.linksQueued),
+		"linksDropped":   queueStats["dropped"], // Lost to a full queue
+		"hookSkipped":    atomic.LoadInt64(&c.hookSkips),
+		"contentChanges": atomic.LoadInt64(&c.contentChanges),
+		"pausedHosts":    c.hosts.GetStats(),
+		"workers":        c.Workers(),
+		"workerStats":    c.workerStats(), // Per worker activity, to find stuck workers
+		"autoscale":      c.autoscale.GetStats(),
+		"elapsedSeconds": c.recorder.ElapsedSeconds(),
+		"queue":          queueStats,
+		"filter":         c.filter.GetStats(),
+		"budget":         c.budget.GetStats(),
+		"dedup":          c.seen.GetStats(),
+		"robots":         c.robots.GetStats(),
+		"fetcher":        c.fetcher.GetStats(),
+		"hostDelays":     c.hostDelays(),
+		"circuitBreaker": c.breaker.GetStats(),
+		"openCircuits":   c.breaker.Circuits(),
+	}
+	if snapshot, ok := c.QueueSnapshot(statsQueueTop); ok {
+		stats["queueSnapshot"] = snapshot
+	}
+	if c.jobID != "" {
+		stats["job"] = c.jobID
+	}
+	if n, err := c.deadLetters.Len(context.Background()); err == nil {
+		stats["deadLetters"] = n
+	}
+	if c.content != nil {
+		stats["content"] = c.content.GetStats()
+	}
+	if c.recrawler != nil {
+		stats["recrawl"] = c.recrawler.GetStats()
+	}
+	if c.parsers != nil {
+		stats["parse"] = c.parsers.GetStats()
+	}
+	if c.telemetry != nil {
+		stats["telemetry"] = c.telemetry.GetStats()
+	}
+	if c.mongo != nil {
+		stats["mongo"] = c.mongo.GetStats()
+	}
+	if c.index != nil {
+		stats["search"] = c.index.GetStats()
+	}
+	if c.publisher != nil {
+		stats["publish"] = c.publisher.GetStats()
+	}
+	if c.objects != nil {
+		stats["objects"] = c.objects.GetStats()
+	}
+	if c.raw != nil {
+		stats["raw"] = c.raw.GetStats()
+	}
+	if c.cfg.ContentSaver.Retention.Enabled {
+		stats["retention"] = map[string]int64{
+			"sweeps":     atomic.LoadInt64(&c.retentionSweeps),
+			"deleted":    atomic.LoadInt64(&c.retentionDeleted),
+			"freedBytes": atomic.LoadInt64(&c.retentionFreed),
+		}
+	}
+	if c.subdomains != nil {
+		stats["subdomains"] = c.subdomains.GetStats()
+	}
+	if c.linkcheck != nil {
+		stats["linkCheck"] = c.linkcheck.GetStats()
+	}
+	if c.seo != nil {
+		stats["seo"] = c.seo.GetStats()
+	}
+	if c.a11y != nil {
+		stats["accessibility"] = c.a11y.GetStats()
+	}
+	if c.graph != nil {
+		stats["graph"] = c.graph.GetStats()
+	}
+	if c.prioritizer != nil {
+		stats["prioritize"] = c.prioritizer.GetStats()
+	}
+	if c.focus != nil {
+		stats["focus"] = c.focus.GetStats()
+	}
+	if c.scorer != nil {
+		stats["scoring"] = c.scorer.GetStats()
+	}
+	if c.documents != nil {
+		stats["documents"] = c.documents.GetStats()
+	}
+	if c.graphql != nil {
+		stats["graphql"] = c.graphql.GetStats()
+	}
+	if c.session != nil {
+		stats["login"] = c.session.GetStats()
+	}
+	return stats
+}
+
+// saveRun keeps the benchmark run with the config, without credentials, for
+// comparing later runs against it
+func (c *Crawler) saveRun(ctx context.Context) error {
+	data, err := yaml.Marshal(c.cfg.Redacted())
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	run := c.recorder.Run()
+	id := benchmark.NewRunID(run.Start, string(data))
+	run.Name = benchmark.ShortID(id)
+	stored := benchmark.StoredRun{ID: id, Created: time.Now(), Config: string(data), Totals: run.Totals(), Run: run}
+	if err := c.runs.Save(ctx, stored); err != nil {
+		return err
+	}
+	c.runID = id
+	c.log.Info("Saved benchmark run %s", run.Name)
+	return nil
+}
+
+// Shutdown stops the crawl and waits until it has drained and checkpointed
+func (c *Crawler) Shutdown(ctx context.Context) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	select {
+	case <-c.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DeadLetters lists URLs that failed after all retries
+func (c *Crawler) DeadLetters(ctx context.Context) ([]queue.DeadLetter, error) {
+	return c.deadLetters.List(ctx)
+}
+
+// Subdomains lists the hosts links pointed to by registrable domain, nil
+// when subdomain tracking is disabled
+func (c *Crawler) Subdomains() []subdomain.Domain {
+	return c.subdomains.Report()
+}
+
+// RequeueDeadLetters queues dead letters again, all of them if urls is empty
+func (c *Crawler) RequeueDeadLetters(ctx context.Context, urls []string) (int, error) {
+	return queue.Requeue(ctx, c.deadLetters, c.queue, urls...)
+}
+
+// Archiver returns the broadcasting archiver, e.g. to subscribe to stored pages
+func (c *Crawler) Archiver() *storage.BroadcastArchiver {
+	return c.archiver
+}
@@ -0,0 +1,100 @@
+package crawler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/dashboard"
+)
+
+// Worker states shown on the dashboard
+const (
+	workerIdle     = "idle"
+	workerFetching = "fetching"
+	workerPaused   = "paused"
+)
+
+// workerState tracks what one worker is doing and what it has done
+type workerState struct {
+	mu    sync.Mutex
+	id    int
+	state string
+	url   string
+	since time.Time
+	start time.Time
+
+	// Counters
+	processed int64 // URLs popped and processed
+	pages     int64 // Pages fetched
+	errors    int64
+}
+
+func newWorkerState(id int) *workerState {
+	now := time.Now()
+	return &workerState{id: id, state: workerIdle, since: now, start: now}
+}
+
+// set records a new state. The start time only moves when something changes,
+// so an idle worker polling the queue keeps its idle time.
+func (w *workerState) set(state, url string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state == state && w.url == url {
+		return
+	}
+	w.state, w.url, w.since = state, url, time.Now()
+}
+
+func (w *workerState) snapshot() dashboard.WorkerState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return dashboard.WorkerState{ID: w.id, State: w.state, URL: w.url, Since: w.since}
+}
+
+// Snapshot returns the crawl state for the live dashboard
+func (c *Crawler) Snapshot() dashboard.Snapshot {
+	snap := dashboard.Snapshot{
+		Elapsed:   time.Duration(c.recorder.ElapsedSeconds() * float64(time.Second)),
+		Pages:     atomic.LoadInt64(&c.pagesCrawled),
+		Stored:    atomic.LoadInt64(&c.pagesStored),
+		Errors:    atomic.LoadInt64(&c.errors),
+		Paused:    c.Paused(),
+		RateLimit: c.RateLimit(),
+		Queue:     c.queue.GetStats(),
+	}
+
+	usage := c.budget.Usage()
+	c.activity.mu.Lock()
+	for host, h := range c.activity.hosts {
+		progress := dashboard.HostProgress{Host: host, Crawled: h.pages, Errors: h.errors, Bytes: h.bytes}
+		if h.pages > 0 {
+			progress.Latency = h.latency / time.Duration(h.pages)
+		}
+		if u, ok := usage[host]; ok {
+			progress.Admitted, progress.MaxPages = u.Pages, u.MaxPages
+		}
+		snap.Hosts = append(snap.Hosts, progress)
+	}
+	for host, u := range usage {
+		if _, ok := c.activity.hosts[host]; !ok {
+			snap.Hosts = append(snap.Hosts, dashboard.HostProgress{Host: host, Admitted: u.Pages, MaxPages: u.MaxPages})
+		}
+	}
+	c.activity.mu.Unlock()
+
+	c.workersMu.Lock()
+	for _, w := range c.workerState {
+		snap.Workers = append(snap.Workers, w.snapshot())
+	}
+	c.workersMu.Unlock()
+	return snap
+}
+
+// Recorder returns the benchmark recorder sampling crawl progress
+func (c *Crawler) Recorder() *benchmark.Recorder {
+	return c.recorder
+}
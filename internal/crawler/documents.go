@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"web-crawler/internal/extract"
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/telemetry"
+	"web-crawler/pkg/utils"
+)
+
+// processDocument stores a PDF, Word or other document as a page whose
+// content is its text, and queues the links it contains
+func (c *Crawler) processDocument(ctx context.Context, span *telemetry.Span, item queue.URLItem, host string, resp *fetcher.Response, prev *storage.PageState) {
+	stage := span.Child("extract_document", telemetry.KindInternal)
+	doc, err := c.documents.Extract(ctx, resp.ContentType, resp.Body)
+	stage.SetError(err)
+	stage.End()
+	if err != nil {
+		c.countError(ctx)
+		c.recorder.ObserveError(errorDocument)
+		c.log.Warn("Failed to extract %s: %v", item.URL, err)
+		c.tracer.Failed(item.URL, err)
+		c.activity.failed(item.URL, host, err.Error())
+		span.SetError(err)
+		c.failed(ctx, item, err)
+		return
+	}
+	c.seen.MarkSeen(ctx, resp.URL, "")
+
+	page := &storage.WebPage{
+		URL:          resp.URL,
+		RequestedURL: resp.RequestedURL,
+		FinalURL:     resp.URL,
+		Title:        doc.Title,
+		Content:      doc.Text,
+		Links:        make([]string, 0, len(doc.Links)),
+		Outlinks:     make([]storage.Outlink, 0, len(doc.Links)),
+		CrawledAt:    time.Now(),
+		StatusCode:   resp.StatusCode,
+		ContentType:  resp.ContentType,
+		DeclaredType: resp.DeclaredType,
+		DetectedType: resp.DetectedType,
+		Language:     extract.Language(doc.Text, resp.Header.Get("Content-Language")),
+		ETag:         resp.ETag,
+		LastModified: resp.LastModified,
+	}
+	for _, hop := range resp.Redirects {
+		page.Redirects = append(page.Redirects, storage.RedirectHop{URL: hop.URL, StatusCode: hop.StatusCode})
+	}
+	if base, err := url.Parse(resp.URL); err == nil {
+		for _, link := range doc.Links {
+			if abs := utils.ToAbsoluteURL(base, link); abs != "" {
+				page.Links = append(page.Links, abs)
+				page.Outlinks = append(page.Outlinks, storage.Outlink{URL: abs, Section: utils.SectionBody})
+			}
+		}
+	}
+	if c.projection.Text {
+		page.Text = doc.Text
+	}
+	if !c.hookPassed(ctx, span, item, host, runPageHooks(ctx, c.hooks.parse, page)) {
+		return
+	}
+
+	queued := c.enqueueLinks(ctx, page.URL, page.Links, item.Depth+1, queue.PriorityNormal, 0)
+	if c.graph != nil {
+		c.graph.AddPage(page.URL, page.Links)
+	}
+	c.store(ctx, span, item, host, resp, page, prev, queued)
+}
@@ -0,0 +1,83 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/extract"
+)
+
+// domains holds the parts of the domain profiles applied while crawling.
+// Rate limits, host limits and rendering are merged into the global
+// settings by config.ApplyDomains.
+type domains struct {
+	cfg   *config.Config
+	slots map[string]chan struct{}  // Worker slots, by the domain of profiles with workers
+	rules map[string]*extract.Rules // Extraction rules, by the domain of profiles with rules
+}
+
+// newDomains compiles the extraction rules of the profiles. It returns nil
+// without any profiles.
+func newDomains(cfg *config.Config) (*domains, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, nil
+	}
+	d := &domains{
+		cfg:   cfg,
+		slots: make(map[string]chan struct{}),
+		rules: make(map[string]*extract.Rules),
+	}
+	for domain, p := range cfg.Domains {
+		if p.Workers > 0 {
+			d.slots[domain] = make(chan struct{}, p.Workers)
+		}
+		if len(p.Extraction) > 0 {
+			rules, err := extract.NewRules(config.ExtractionConfig{Rules: p.Extraction})
+			if err != nil {
+				return nil, fmt.Errorf("domain %s: %w", domain, err)
+			}
+			d.rules[domain] = rules
+		}
+	}
+	return d, nil
+}
+
+// acquire waits until fewer URLs of the domain of host than its profile
+// allows are processed. The returned function frees the slot.
+func (d *domains) acquire(ctx context.Context, host string) (func(), error) {
+	if d == nil {
+		return func() {}, nil
+	}
+	_, domain, ok := d.cfg.Profile(host)
+	slots := d.slots[domain]
+	if !ok || slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// headers returns the request headers of the profile of host
+func (d *domains) headers(host string) map[string]string {
+	p, _, _ := d.cfg.Profile(host)
+	return p.Headers
+}
+
+// rulesFor returns the extraction rules of the profile of host, global if
+// the profile has none
+func (d *domains) rulesFor(host string, global *extract.Rules) *extract.Rules {
+	if d == nil {
+		return global
+	}
+	if _, domain, ok := d.cfg.Profile(host); ok {
+		if rules, ok := d.rules[domain]; ok {
+			return rules
+		}
+	}
+	return global
+}
@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"web-crawler/internal/graphql"
+	"web-crawler/internal/storage"
+)
+
+// probeGraphQL looks for GraphQL endpoints the first time a host is crawled
+// and stores a record of each one found
+func (c *Crawler) probeGraphQL(ctx context.Context, u *url.URL) {
+	if c.graphql == nil {
+		return
+	}
+	for _, endpoint := range c.graphql.Candidates(u) {
+		if !c.robots.Allowed(ctx, endpoint) {
+			continue
+		}
+		found, err := c.graphql.Probe(ctx, endpoint)
+		if err != nil {
+			c.log.Debug("GraphQL probe of %s failed: %v", endpoint, err)
+			continue
+		}
+		if found != nil {
+			c.storeGraphQL(ctx, found)
+		}
+	}
+}
+
+// storeGraphQL stores an endpoint as a record of kind graphql through the
+// store hooks
+func (c *Crawler) storeGraphQL(ctx context.Context, e *graphql.Endpoint) {
+	record := &storage.WebPage{
+		URL:         e.URL,
+		Kind:        storage.KindGraphQL,
+		Title:       "GraphQL endpoint",
+		Content:     string(e.Body),
+		CrawledAt:   time.Now(),
+		StatusCode:  e.StatusCode,
+		ContentType: "application/json",
+		GraphQL: &storage.GraphQLEndpoint{
+			Introspection: e.Introspection,
+			QueryType:     e.QueryType,
+			MutationType:  e.MutationType,
+			Types:         e.Types,
+			Schema:        e.Schema,
+		},
+	}
+	for _, s := range e.Samples {
+		record.GraphQL.Samples = append(record.GraphQL.Samples, storage.GraphQLSample{Query: s.Query, Data: s.Data, Errors: s.Errors})
+	}
+	if err := runPageHooks(ctx, c.hooks.store, record); err != nil {
+		c.countError(ctx)
+		c.recorder.ObserveError(errorStorage)
+		c.log.Warn("Failed to store GraphQL endpoint %s: %v", e.URL, err)
+	}
+}
@@ -0,0 +1,163 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/pkg/utils"
+)
+
+// ErrSkip returned by a hook drops the page without counting an error
+var ErrSkip = errors.New("skipped by hook")
+
+// FetchHook sees every HTML response before it is parsed and may change it
+type FetchHook func(ctx context.Context, item queue.URLItem, resp *fetcher.Response) error
+
+// PageHook sees a page and may change it. Parse hooks run before the links
+// are queued, so they decide which links in page.Links are followed. Before
+// store hooks run once the page is complete. Store hooks persist the page.
+type PageHook func(ctx context.Context, page *storage.WebPage) error
+
+// ErrorHook is told about every URL that failed to fetch, parse or store
+type ErrorHook func(ctx context.Context, item queue.URLItem, err error)
+
+// hooks are the page pipeline. The content saver and the archiver are the
+// first store hooks.
+type hooks struct {
+	fetch       []FetchHook
+	parse       []PageHook
+	beforeStore []PageHook
+	store       []PageHook
+	errors      []ErrorHook
+	builtin     int // Built-in store hooks, at the start of store
+}
+
+// OnFetch registers a hook run on every HTML response before parsing.
+// Hooks must be registered before Run.
+func (c *Crawler) OnFetch(h FetchHook) {
+	c.hooks.fetch = append(c.hooks.fetch, h)
+}
+
+// OnParse registers a hook run on every parsed page before its links are queued
+func (c *Crawler) OnParse(h PageHook) {
+	c.hooks.parse = append(c.hooks.parse, h)
+}
+
+// BeforeStore registers a hook run on every page before it is stored
+func (c *Crawler) BeforeStore(h PageHook) {
+	c.hooks.beforeStore = append(c.hooks.beforeStore, h)
+}
+
+// OnStore registers a hook that stores pages, run after the built-in ones.
+// It sees the whole page, regardless of storage.fields.
+func (c *Crawler) OnStore(h PageHook) {
+	c.hooks.store = append(c.hooks.store, h)
+}
+
+// OnError registers a hook told about failed URLs
+func (c *Crawler) OnError(h ErrorHook) {
+	c.hooks.errors = append(c.hooks.errors, h)
+}
+
+// shareBodies reports whether page content can share the memory of the
+// response body instead of copying it. Registered hooks may change the body,
+// and a custom fetcher may reuse it, which would change the content too.
+func (c *Crawler) shareBodies() bool {
+	h := &c.hooks
+	return c.custom == nil && len(h.fetch) == 0 && len(h.parse) == 0 &&
+		len(h.beforeStore) == 0 && len(h.store) == h.builtin
+}
+
+// runFetchHooks passes a response through the fetch hooks, stopping at the first error
+func (c *Crawler) runFetchHooks(ctx context.Context, item queue.URLItem, resp *fetcher.Response) error {
+	for _, h := range c.hooks.fetch {
+		if err := h(ctx, item, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPageHooks passes a page through hooks, stopping at the first error
+func runPageHooks(ctx context.Context, hooks []PageHook, page *storage.WebPage) error {
+	for _, h := range hooks {
+		if err := h(ctx, page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failed reports an error to the error hooks
+func (c *Crawler) failed(ctx context.Context, item queue.URLItem, err error) {
+	for _, h := range c.hooks.errors {
+		h(ctx, item, err)
+	}
+}
+
+// archive is the store hook of the archiver. Fields left out by
+// storage.fields are dropped from a copy, so later hooks see the whole page.
+func (c *Crawler) archive(ctx context.Context, page *storage.WebPage) error {
+	stored := *page
+	c.projection.Apply(&stored)
+	return c.archiver.Store(ctx, &stored)
+}
+
+// storeRaw is the store hook of the raw store. It runs before the archiver
+// so the page links to its raw exchange; failures are logged but don't fail
+// the page.
+func (c *Crawler) storeRaw(ctx context.Context, page *storage.WebPage) error {
+	if page.Raw == nil {
+		return nil
+	}
+	ref, err := c.raw.Put(ctx, page, page.Raw)
+	if err != nil {
+		c.log.Warn("Failed to store raw response of %s: %v", page.URL, err)
+		return nil
+	}
+	page.RawRef = ref
+	return nil
+}
+
+// saveContent is the store hook of the content saver. Failures are logged
+// but don't fail the page.
+func (c *Crawler) saveContent(ctx context.Context, page *storage.WebPage) error {
+	if page.Kind != "" {
+		return nil // Only pages are saved as files
+	}
+	saved := &utils.SavedPage{
+		URL:         page.URL,
+		Title:       page.Title,
+		Content:     page.Content,
+		ContentType: page.ContentType,
+		StatusCode:  page.StatusCode,
+		CrawledAt:   page.CrawledAt,
+		Headers:     page.Headers,
+		Metadata:    page.Metadata,
+	}
+	if page.Raw != nil {
+		saved.RawRequest = page.Raw.Request
+		saved.RawResponse = append(append([]byte(nil), page.Raw.Response...), page.Raw.Body...)
+	}
+	if c.saver.Format() == utils.FormatMHTML {
+		if base, err := url.Parse(page.URL); err == nil {
+			saved.Resources = c.embeddedResources(ctx, base, page.Content)
+		}
+	}
+	if err := c.saver.SavePage(saved); err != nil {
+		c.log.Warn("Failed to save content of %s: %v", page.URL, err)
+	}
+	if c.saver.Mirroring() && fetcher.IsHTML(page.ContentType) {
+		c.mirror(ctx, page.URL, page.Content)
+	}
+	if c.cfg.ContentSaver.Assets.Enabled {
+		if base, err := url.Parse(page.URL); err == nil {
+			c.saveAssets(ctx, base, page.Content)
+		}
+	}
+	return nil
+}
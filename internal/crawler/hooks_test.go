@@ -0,0 +1,41 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+)
+
+func TestShareBodies(t *testing.T) {
+	store := func(context.Context, *storage.WebPage) error { return nil }
+	c := &Crawler{}
+	c.hooks.store = []PageHook{store, store}
+	c.hooks.builtin = len(c.hooks.store)
+	if !c.shareBodies() {
+		t.Fatal("bodies copied with only the built-in hooks")
+	}
+
+	c.OnStore(store)
+	if c.shareBodies() {
+		t.Fatal("bodies shared with a store hook registered")
+	}
+	c.hooks.store = c.hooks.store[:c.hooks.builtin]
+	c.OnFetch(func(context.Context, queue.URLItem, *fetcher.Response) error { return nil })
+	if c.shareBodies() {
+		t.Fatal("bodies shared with a fetch hook registered")
+	}
+	c.hooks.fetch = nil
+	c.custom = fetcherFunc(nil)
+	if c.shareBodies() {
+		t.Fatal("bodies of a custom fetcher shared")
+	}
+}
+
+type fetcherFunc func(ctx context.Context, rawURL string) (*fetcher.Response, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, rawURL string) (*fetcher.Response, error) {
+	return f(ctx, rawURL)
+}
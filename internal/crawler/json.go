@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/telemetry"
+	"web-crawler/pkg/utils"
+)
+
+// processJSON stores a JSON response as a page holding the parsed body, and
+// queues the URLs the JSON rules select in it
+func (c *Crawler) processJSON(ctx context.Context, span *telemetry.Span, item queue.URLItem, host string, resp *fetcher.Response, prev *storage.PageState) {
+	stage := span.Child("parse_json", telemetry.KindInternal)
+	var doc interface{}
+	err := json.Unmarshal(resp.Body, &doc)
+	if err != nil {
+		err = fmt.Errorf("failed to parse json: %w", err)
+	}
+	stage.SetError(err)
+	stage.End()
+	if err != nil {
+		c.countError(ctx)
+		c.recorder.ObserveError(errorJSON)
+		c.log.Warn("Failed to parse %s: %v", item.URL, err)
+		c.tracer.Failed(item.URL, err)
+		c.activity.failed(item.URL, host, err.Error())
+		span.SetError(err)
+		c.failed(ctx, item, err)
+		return
+	}
+	atomic.AddInt64(&c.jsonPages, 1)
+	c.seen.MarkSeen(ctx, resp.URL, "")
+
+	title, links := c.json.Apply(resp.URL, doc)
+	c.tracer.Parsed(item.URL, len(links))
+
+	content := string(resp.Body)
+	if c.shareBodies() {
+		content = utils.BytesString(resp.Body)
+	}
+	page := &storage.WebPage{
+		URL:          resp.URL,
+		RequestedURL: resp.RequestedURL,
+		FinalURL:     resp.URL,
+		Title:        title,
+		Content:      content,
+		JSON:         doc,
+		Links:        make([]string, 0, len(links)),
+		Outlinks:     make([]storage.Outlink, 0, len(links)),
+		CrawledAt:    time.Now(),
+		StatusCode:   resp.StatusCode,
+		ContentType:  resp.ContentType,
+		DeclaredType: resp.DeclaredType,
+		DetectedType: resp.DetectedType,
+		ETag:         resp.ETag,
+		LastModified: resp.LastModified,
+	}
+	for _, hop := range resp.Redirects {
+		page.Redirects = append(page.Redirects, storage.RedirectHop{URL: hop.URL, StatusCode: hop.StatusCode})
+	}
+	if base, err := url.Parse(resp.URL); err == nil {
+		for _, link := range links {
+			if abs := utils.ToAbsoluteURL(base, link); abs != "" {
+				page.Links = append(page.Links, abs)
+				page.Outlinks = append(page.Outlinks, storage.Outlink{URL: abs, Section: utils.SectionBody})
+			}
+		}
+	}
+	if !c.hookPassed(ctx, span, item, host, runPageHooks(ctx, c.hooks.parse, page)) {
+		return
+	}
+
+	queued := c.enqueueLinks(ctx, page.URL, page.Links, item.Depth+1, queue.PriorityNormal, 0)
+	if c.graph != nil {
+		c.graph.AddPage(page.URL, page.Links)
+	}
+	c.store(ctx, span, item, host, resp, page, prev, queued)
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/telemetry"
+)
+
+// parseJob is a fetched page waiting to be parsed
+type parseJob struct {
+	ctx        context.Context // Of the fetch worker, which the page counts for, but never cancelled
+	span       *telemetry.Span
+	item       queue.URLItem
+	u          *url.URL
+	resp       *fetcher.Response
+	prev       *storage.PageState
+	isDocument bool
+	isJSON     bool
+}
+
+// parsePool parses fetched pages apart from the fetch workers, so these
+// free the page's domain slot and fetch the next URL while it's parsed.
+// The fetch workers wait while the queue is full.
+type parsePool struct {
+	workers int
+	jobs    chan parseJob
+	wg      sync.WaitGroup
+	mu      sync.RWMutex // Held to send, so close can't race a send
+	closed  bool
+	busy    int64 // Jobs being parsed
+}
+
+// newParsePool creates a pool of workers parsing up to size queued pages,
+// nil without workers
+func newParsePool(workers, size int) *parsePool {
+	if workers <= 0 {
+		return nil
+	}
+	return &parsePool{workers: workers, jobs: make(chan parseJob, size)}
+}
+
+// start runs the workers, which call handle with every submitted job
+func (p *parsePool) start(handle func(parseJob)) {
+	if p == nil {
+		return
+	}
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				atomic.AddInt64(&p.busy, 1)
+				handle(job)
+				atomic.AddInt64(&p.busy, -1)
+			}
+		}()
+	}
+}
+
+// submit queues job for the workers. It returns false if there is no pool
+// or it's closed, and the page should be parsed by the caller.
+func (p *parsePool) submit(job parseJob) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	p.jobs <- job
+	return true
+}
+
+// close waits for the workers to parse the queued pages and stops them
+func (p *parsePool) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// GetStats returns the parse workers and the pages waiting for and in parsing
+func (p *parsePool) GetStats() map[string]int64 {
+	return map[string]int64{
+		"workers": int64(p.workers),
+		"queued":  int64(len(p.jobs)),
+		"parsing": atomic.LoadInt64(&p.busy),
+	}
+}
@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+)
+
+func TestParsePool(t *testing.T) {
+	if newParsePool(0, 10) != nil {
+		t.Fatal("pool without workers")
+	}
+	var none *parsePool
+	if none.submit(parseJob{}) {
+		t.Fatal("nil pool took a job")
+	}
+	none.close()
+
+	p := newParsePool(2, 1)
+	var mu sync.Mutex
+	var parsed []string
+	p.start(func(job parseJob) {
+		mu.Lock()
+		parsed = append(parsed, job.item.URL)
+		mu.Unlock()
+	})
+	for _, u := range []string{"https://a.com/1", "https://a.com/2", "https://a.com/3"} {
+		if !p.submit(parseJob{item: queue.URLItem{URL: u}}) {
+			t.Fatalf("submit(%s) refused", u)
+		}
+	}
+	p.close()
+	if len(parsed) != 3 {
+		t.Fatalf("parsed %v, want every queued page before close returns", parsed)
+	}
+	if p.submit(parseJob{}) {
+		t.Fatal("closed pool took a job")
+	}
+	p.close()
+
+	stats := p.GetStats()
+	if stats["workers"] != 2 || stats["queued"] != 0 || stats["parsing"] != 0 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+// ctxArchiver records the stored pages and whether their context was done
+type ctxArchiver struct {
+	mu        sync.Mutex
+	stored    []string
+	cancelled []string
+}
+
+func (a *ctxArchiver) Store(ctx context.Context, page *storage.WebPage) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stored = append(a.stored, page.URL)
+	if ctx.Err() != nil {
+		a.cancelled = append(a.cancelled, page.URL)
+	}
+	return nil
+}
+
+func (a *ctxArchiver) Close(ctx context.Context) error { return nil }
+
+func TestParseQueueStoredAfterCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/1">1</a> <a href="/2">2</a> <a href="/3">3</a> <a href="/4">4</a> <a href="/5">5</a>`)
+			return
+		}
+		fmt.Fprintf(w, "<title>%s</title>", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.API.Enabled = false
+	cfg.ContentSaver.Enabled = false
+	cfg.Benchmark.Enabled = false
+	cfg.Checkpoint.Enabled = false
+	cfg.Crawler.Workers = 2
+	cfg.Crawler.RateLimit = time.Millisecond
+	cfg.Crawler.Parse.Workers = 1
+	cfg.Crawler.Parse.Queue = 10
+	archiver := &ctxArchiver{}
+	c, err := New(cfg, Options{Archivers: []storage.Archiver{archiver}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddSeeds([]string{srv.URL + "/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parse worker holds the first linked page until the crawl is
+	// cancelled, so the others wait in the parse queue
+	blocked, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	c.OnParse(func(ctx context.Context, page *storage.WebPage) error {
+		if page.URL != srv.URL+"/" {
+			once.Do(func() {
+				close(blocked)
+				<-release
+			})
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	<-blocked
+	deadline := time.Now().Add(10 * time.Second)
+	for c.parsers.GetStats()["queued"] < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("parse queue = %v, want 4 pages", c.parsers.GetStats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(archiver.stored) != 6 || len(archiver.cancelled) != 0 {
+		t.Fatalf("stored %v, %v with a cancelled context", archiver.stored, archiver.cancelled)
+	}
+}
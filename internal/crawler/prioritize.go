@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/graph"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+)
+
+// prioritizer periodically ranks the link graph by PageRank and moves queued
+// URLs to the priority of their tier, so pages many others link to are
+// fetched first. Discovered links take the priority of their last ranking.
+type prioritizer struct {
+	graph    *graph.Graph
+	interval time.Duration
+	ranking  atomic.Pointer[graph.Ranking]
+	log      *logger.Logger
+
+	// Counters
+	moved int64
+}
+
+// newPrioritizer returns nil unless the graph is recorded and prioritize is on
+func newPrioritizer(g *graph.Graph, cfg config.GraphConfig, log *logger.Logger) *prioritizer {
+	if g == nil || !cfg.Prioritize {
+		return nil
+	}
+	return &prioritizer{graph: g, interval: cfg.PrioritizeInterval, log: log}
+}
+
+// priority returns the priority of a discovered link, fallback if it is unranked
+func (p *prioritizer) priority(u string, fallback int) int {
+	if p == nil {
+		return fallback
+	}
+	if priority, ok := p.ranking.Load().Priority(u); ok {
+		return priority
+	}
+	return fallback
+}
+
+// run reranks the queue every interval until ctx is cancelled
+func (p *prioritizer) run(ctx context.Context, q queue.URLQueue) {
+	rq, ok := q.(queue.Reprioritizer)
+	if !ok {
+		p.log.Warn("The queue backend can't reprioritize queued URLs, PageRank only applies to new links")
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.rerank(rq)
+		}
+	}
+}
+
+// rerank computes a new ranking and applies it to the queued URLs. Seeds keep
+// the priority they were given.
+func (p *prioritizer) rerank(rq queue.Reprioritizer) {
+	ranking := p.graph.Rank()
+	p.ranking.Store(ranking)
+	if rq == nil {
+		return
+	}
+
+	moved := rq.Reprioritize(func(item queue.URLItem) int {
+		if item.Depth == 0 {
+			return item.Priority
+		}
+		if priority, ok := ranking.Priority(item.URL); ok {
+			return priority
+		}
+		return item.Priority
+	})
+	atomic.AddInt64(&p.moved, int64(moved))
+	p.log.Debug("Reprioritized %d queued URLs by PageRank", moved)
+}
+
+// GetStats returns prioritizer statistics
+func (p *prioritizer) GetStats() map[string]int64 {
+	return map[string]int64{
+		"moved": atomic.LoadInt64(&p.moved),
+	}
+}
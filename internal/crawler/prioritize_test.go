@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/graph"
+	"web-crawler/internal/queue"
+)
+
+func TestPrioritizerRerank(t *testing.T) {
+	cfg := config.GraphConfig{Enabled: true, MaxNodes: 100, Damping: 0.85, Iterations: 50, Prioritize: true}
+	g := graph.New(cfg)
+	for i := 0; i < 9; i++ {
+		g.AddPage(fmt.Sprintf("https://a.com/%d", i), []string{"https://a.com/hub"})
+	}
+	p := newPrioritizer(g, cfg, log)
+
+	// Before the first ranking links keep their default priority
+	if got := p.priority("https://a.com/hub", queue.PriorityNormal); got != queue.PriorityNormal {
+		t.Fatalf("priority() before ranking = %d", got)
+	}
+
+	q := queue.NewHostAwareQueue(0, 0)
+	defer q.Close()
+	q.PushWithPriority("https://a.com/hub", queue.PriorityNormal, "a.com", 0) // Seed
+	q.PushWithPriority("https://a.com/8", queue.PriorityHigh, "a.com", 1)
+	p.rerank(q)
+
+	if got := p.priority("https://a.com/hub", queue.PriorityNormal); got != queue.PriorityHigh {
+		t.Fatalf("priority(hub) = %d", got)
+	}
+	if got := p.priority("https://other.com/", queue.PriorityLow); got != queue.PriorityLow {
+		t.Fatalf("priority() of an unranked link = %d", got)
+	}
+	if moved := p.GetStats()["moved"]; moved != 1 {
+		t.Fatalf("moved %d URLs, want 1", moved)
+	}
+	// The seed keeps its priority, the poorly ranked page drops to low
+	want := map[string]int{"https://a.com/hub": queue.PriorityNormal, "https://a.com/8": queue.PriorityLow}
+	for range want {
+		item, _ := q.Pop()
+		if item.Priority != want[item.URL] {
+			t.Fatalf("%s queued at priority %d, want %d", item.URL, item.Priority, want[item.URL])
+		}
+	}
+}
+
+func TestPrioritizerDisabled(t *testing.T) {
+	if p := newPrioritizer(nil, config.GraphConfig{Prioritize: true}, log); p != nil {
+		t.Fatal("prioritizer without a graph")
+	}
+	var p *prioritizer
+	if got := p.priority("https://a.com/", queue.PriorityLow); got != queue.PriorityLow {
+		t.Fatalf("nil prioritizer changed the priority to %d", got)
+	}
+}
@@ -0,0 +1,682 @@
+package crawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/accessibility"
+	"web-crawler/internal/extract"
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/filter"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/telemetry"
+	"web-crawler/pkg/utils"
+)
+
+// Reasons recorded in the trace log when a URL is dropped. Links rejected by
+// the URL filter or a host budget use the filter's reason instead.
+const (
+	skipSeen          = "seen"
+	skipRobots        = "robots"
+	skipContentType   = "content_type"
+	skipNotModified   = "not_modified"
+	skipHTTPError     = "http_error"
+	skipRedirect      = "redirect"
+	skipNotHTML       = "not_html"
+	skipNoIndex       = "noindex"
+	skipNearDuplicate = "near_duplicate"
+	skipLanguage      = "language"
+	skipHook          = "hook"
+)
+
+// Benchmark error classes of pages that failed after the fetch
+const (
+	errorStorage  = "storage"
+	errorHook     = "hook"
+	errorDocument = "document"
+	errorJSON     = "json"
+)
+
+// process fetches one URL, stores the page, and queues its links. It
+// returns true if the page went to the parse workers, which finish it.
+func (c *Crawler) process(ctx context.Context, item queue.URLItem) (handedOff bool) {
+	span := c.telemetry.StartPage(item.URL)
+	span.SetInt("crawler.depth", int64(item.Depth))
+	if w := workerFrom(ctx); w != nil {
+		span.SetInt("crawler.worker", int64(w.id))
+	}
+	defer func() {
+		if !handedOff {
+			span.End()
+		}
+	}()
+
+	u, err := url.Parse(item.URL)
+	if err != nil {
+		c.countError(ctx)
+		c.recorder.ObserveError(fetcher.ErrorOther)
+		c.activity.failed(item.URL, "", err.Error())
+		span.SetError(err)
+		c.failed(ctx, item, err)
+		return
+	}
+
+	stage := span.Child("robots", telemetry.KindInternal)
+	allowed := c.robots.Allowed(ctx, item.URL)
+	stage.SetBool("robots.allowed", allowed)
+	stage.End()
+	if !allowed && ctx.Err() != nil {
+		c.interrupted(item)
+		return
+	}
+	if !allowed {
+		atomic.AddInt64(&c.robotsBlocked, 1)
+		c.tracer.Skipped(item.URL, "", skipRobots)
+		span.SetString("crawler.skip_reason", skipRobots)
+		return
+	}
+	// Also clears a delay dropped from a refreshed robots.txt
+	c.limiter.SetCrawlDelay(u.Host, c.robots.CrawlDelay(ctx, item.URL))
+	// Hand the item back before it takes a circuit probe or a domain slot
+	if c.deferred(item, u.Host) {
+		span.SetBool("crawler.deferred", true)
+		return
+	}
+	allowed, probe := c.breaker.Allow(u.Host)
+	if !allowed {
+		c.holdBack(item, u.Host)
+		return
+	}
+	release, err := c.domains.acquire(ctx, u.Host)
+	if err != nil {
+		c.interruptedProbe(item, u.Host, probe)
+		return
+	}
+	defer release()
+	if _, ok := c.queue.(queue.Delayer); !ok {
+		stage = span.Child("rate_limit", telemetry.KindInternal)
+		err = c.limiter.Wait(ctx, u.Host)
+		stage.End()
+		if err != nil {
+			c.interruptedProbe(item, u.Host, probe)
+			return
+		}
+	}
+
+	stage = span.Child("fetch", telemetry.KindClient)
+	prev := c.pageState(ctx, item.URL)
+	resp, err := c.fetch(ctx, item.URL, c.validators(prev))
+	if resp != nil {
+		stage.SetInt("http.response.status_code", int64(resp.StatusCode))
+		stage.SetInt("http.response.body.size", int64(len(resp.Body)))
+		stage.SetBool("http.cached", resp.Cached)
+	}
+	if !errors.Is(err, fetcher.ErrContentType) && !errors.Is(err, fetcher.ErrRedirectBlocked) {
+		stage.SetError(err)
+	}
+	stage.End()
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			c.interruptedProbe(item, u.Host, probe)
+		case errors.Is(err, fetcher.ErrContentType):
+			c.recordOutcome(u.Host, false)
+			c.tracer.Skipped(item.URL, "", skipContentType)
+			span.SetString("crawler.skip_reason", skipContentType)
+		case errors.Is(err, fetcher.ErrRedirectBlocked):
+			c.recordOutcome(u.Host, false)
+			c.log.Debug("Not following %s: %v", item.URL, err)
+			c.tracer.Skipped(item.URL, "", skipRedirect)
+			span.SetString("crawler.skip_reason", skipRedirect)
+		default:
+			c.recordOutcome(u.Host, true)
+			span.SetError(err)
+			c.countError(ctx)
+			c.recorder.ObserveError(fetcher.ErrorClass(err))
+			c.log.Error("Failed to fetch %s: %v%s", item.URL, err, workerSuffix(ctx))
+			c.tracer.Failed(item.URL, err)
+			c.activity.failed(item.URL, u.Host, err.Error())
+			c.linkcheck.Result(item.URL, 0, err)
+			c.failed(ctx, item, err)
+		}
+		return
+	}
+	c.tracer.Fetched(item.URL, resp.StatusCode, resp.Latency, len(resp.Body))
+	c.activity.fetched(resp.StatusCode)
+	c.recordOutcome(u.Host, resp.StatusCode >= 500)
+	if !resp.Cached {
+		c.recorder.ObserveFetch(u.Host, resp.Latency, len(resp.Body))
+		c.autoscale.observe(resp.Latency)
+		c.limiter.Observe(u.Host, resp.StatusCode, resp.Latency, resp.Header)
+	}
+
+	if resp.NotModified {
+		atomic.AddInt64(&c.notModified, 1)
+		c.markUnchanged(ctx, item)
+		c.tracer.Skipped(item.URL, "", skipNotModified)
+		span.SetString("crawler.skip_reason", skipNotModified)
+		return
+	}
+	if resp.StatusCode >= 400 {
+		err := fmt.Errorf("HTTP %d", resp.StatusCode)
+		c.countError(ctx)
+		c.recorder.ObserveError(fetcher.StatusClass(resp.StatusCode))
+		c.tracer.Skipped(item.URL, "", skipHTTPError)
+		c.activity.failed(item.URL, u.Host, err.Error())
+		span.SetError(err)
+		c.linkcheck.Result(item.URL, resp.StatusCode, nil)
+		c.failed(ctx, item, err)
+		return
+	}
+	isDocument := c.documents != nil && c.documents.Supports(resp.ContentType)
+	isJSON := c.json != nil && c.json.Supports(resp.ContentType)
+	if !isDocument && !isJSON && !fetcher.IsHTML(resp.ContentType) {
+		c.tracer.Skipped(item.URL, "", skipNotHTML)
+		span.SetString("crawler.skip_reason", skipNotHTML)
+		return
+	}
+	if !c.hookPassed(ctx, span, item, u.Host, c.runFetchHooks(ctx, item, resp)) {
+		return
+	}
+
+	c.countPage(ctx)
+	c.activity.crawled(item.URL, u.Host, resp.StatusCode, resp.Latency, len(resp.Body))
+	c.probeGraphQL(ctx, u)
+
+	// A fetched page is parsed and stored even if the crawl is cancelled
+	// meanwhile, as shutdown waits for it
+	job := parseJob{ctx: context.WithoutCancel(ctx), span: span, item: item, u: u, resp: resp, prev: prev, isDocument: isDocument, isJSON: isJSON}
+	if c.parsers.submit(job) {
+		return true
+	}
+	c.parse(job)
+	return false
+}
+
+// parse extracts a fetched page, queues its links and stores it
+func (c *Crawler) parse(job parseJob) {
+	ctx, span, item, u, resp, prev := job.ctx, job.span, job.item, job.u, job.resp, job.prev
+	if job.isDocument {
+		c.processDocument(ctx, span, item, u.Host, resp, prev)
+		return
+	}
+	if job.isJSON {
+		c.processJSON(ctx, span, item, u.Host, resp, prev)
+		return
+	}
+
+	stage := span.Child("parse", telemetry.KindInternal)
+	decode := utils.DecodeHTML
+	if c.shareBodies() {
+		decode = utils.DecodeHTMLShared
+	}
+	content, charset := decode(resp.Body, resp.ContentType)
+	language := extract.Language(content, resp.Header.Get("Content-Language"))
+	stage.SetString("html.charset", charset)
+	stage.SetString("html.language", language)
+	base, err := url.Parse(resp.URL)
+	if err != nil {
+		base = u
+	}
+	parsed := utils.ParseHTML(content, base)
+	canonical := parsed.Canonical
+	c.seen.MarkSeen(ctx, resp.URL, canonical)
+
+	directives := c.robots.PageDirectives(resp.Header, content)
+	links := utils.FilterLinksByRel(parsed.Links, c.filter.SkipLinkRels())
+	c.tracer.Parsed(item.URL, len(links))
+	stage.SetInt("links.found", int64(len(links)))
+	stage.End()
+
+	if ok, _ := c.filter.CheckLanguage(language); !ok {
+		c.tracer.Skipped(item.URL, "", skipLanguage)
+		span.SetString("crawler.skip_reason", skipLanguage)
+		return
+	}
+
+	page := &storage.WebPage{
+		URL:          resp.URL,
+		RequestedURL: resp.RequestedURL,
+		FinalURL:     resp.URL,
+		CanonicalURL: canonical,
+		Title:        parsed.Title,
+		Content:      content,
+		Links:        make([]string, 0, len(links)),
+		Outlinks:     make([]storage.Outlink, 0, len(links)),
+		CrawledAt:    time.Now(),
+		StatusCode:   resp.StatusCode,
+		ContentType:  resp.ContentType,
+		DeclaredType: resp.DeclaredType,
+		DetectedType: resp.DetectedType,
+		Charset:      charset,
+		Language:     language,
+		ETag:         resp.ETag,
+		LastModified: resp.LastModified,
+	}
+	for _, hop := range resp.Redirects {
+		page.Redirects = append(page.Redirects, storage.RedirectHop{URL: hop.URL, StatusCode: hop.StatusCode})
+	}
+	for _, link := range links {
+		abs := utils.ToAbsoluteURL(base, link.Href)
+		if abs == "" {
+			continue
+		}
+		page.Links = append(page.Links, abs)
+		page.Outlinks = append(page.Outlinks, storage.Outlink{
+			URL:     abs,
+			Text:    link.Text,
+			Rel:     link.Rel,
+			Section: link.Section,
+			Element: link.Element,
+		})
+	}
+
+	text := ""
+	priority := queue.PriorityNormal
+	follow := !directives.NoFollow
+	if c.focus != nil {
+		text = utils.CleanText(content)
+		page.Relevance = c.focus.Score(page.Title, text)
+		priority = c.focus.LinkPriority(page.Relevance)
+		follow = follow && c.focus.Follow(page.Relevance)
+	}
+	if !c.hookPassed(ctx, span, item, u.Host, runPageHooks(ctx, c.hooks.parse, page)) {
+		return
+	}
+
+	queued := 0
+	if follow {
+		stage = span.Child("filter", telemetry.KindInternal)
+		queued = c.enqueueLinks(ctx, page.URL, page.Links, item.Depth+1, priority, page.Relevance)
+		stage.SetInt("links.queued", int64(queued))
+		stage.End()
+	}
+	if c.graph != nil {
+		var targets []string
+		if !directives.NoFollow {
+			targets = page.Links
+		}
+		c.graph.AddPage(page.URL, targets)
+	}
+
+	if directives.NoIndex {
+		c.tracer.Skipped(item.URL, "", skipNoIndex)
+		span.SetString("crawler.skip_reason", skipNoIndex)
+		return
+	}
+	if c.isNearDuplicate(content) {
+		c.tracer.Skipped(item.URL, "", skipNearDuplicate)
+		span.SetString("crawler.skip_reason", skipNearDuplicate)
+		return
+	}
+
+	page.Metadata = parsed.Metadata
+	if data := extract.StructuredData(content, base); !data.Empty() {
+		page.Structured = &storage.StructuredData{JSONLD: data.JSONLD, Microdata: data.Microdata, RDFa: data.RDFa}
+	}
+	page.Extracted = c.domains.rulesFor(u.Host, c.rules).Apply(resp.URL, content, base)
+	if c.seo != nil {
+		if text == "" {
+			text = utils.CleanText(content)
+		}
+		c.seo.Observe(page, content, text)
+	}
+	if c.a11y != nil {
+		page.A11y = accessibility.Analyze(content)
+		c.a11y.Observe(page.URL, page.A11y)
+	}
+	if c.projection.Text {
+		stage = span.Child("extract_text", telemetry.KindInternal)
+		if text == "" {
+			text = utils.CleanText(content)
+		}
+		page.Text = text
+		stage.SetInt("crawler.text_bytes", int64(len(page.Text)))
+		stage.End()
+	}
+	if c.projection.Article {
+		stage = span.Child("extract_article", telemetry.KindInternal)
+		if article := extract.Extract(content); article != nil {
+			page.Article = &storage.Article{
+				Title:     article.Title,
+				Author:    article.Author,
+				Published: article.Published,
+				Text:      article.Text,
+			}
+			stage.SetInt("crawler.text_bytes", int64(len(article.Text)))
+		}
+		stage.End()
+	}
+	c.store(ctx, span, item, u.Host, resp, page, prev, queued)
+}
+
+// store runs the store hooks on a parsed page, after recording its content
+// change and keeping its headers and raw exchange for the hooks
+func (c *Crawler) store(ctx context.Context, span *telemetry.Span, item queue.URLItem, host string, resp *fetcher.Response, page *storage.WebPage, prev *storage.PageState, queued int) {
+	if detectChange(page, resp.Body, prev) {
+		atomic.AddInt64(&c.contentChanges, 1)
+	}
+	// Headers are dropped before archiving unless storage.fields keeps them
+	page.Headers = resp.Header
+	if c.objects.Raw() {
+		page.Body = resp.Body
+	}
+	if resp.Raw != nil {
+		page.Raw = &storage.RawRecord{Request: resp.Raw.Request, Response: resp.Raw.Response, Body: resp.Raw.Body}
+	}
+	if !c.hookPassed(ctx, span, item, host, runPageHooks(ctx, c.hooks.beforeStore, page)) {
+		return
+	}
+
+	stage := span.Child("store", telemetry.KindInternal)
+	err := runPageHooks(ctx, c.hooks.store, page)
+	stage.SetError(err)
+	stage.End()
+	switch {
+	case errors.Is(err, ErrSkip):
+		atomic.AddInt64(&c.hookSkips, 1)
+		c.tracer.Skipped(item.URL, "", skipHook)
+	case err != nil:
+		c.countError(ctx)
+		c.recorder.ObserveError(errorStorage)
+		c.tracer.Failed(item.URL, err)
+		c.activity.failed(item.URL, host, err.Error())
+		span.SetError(err)
+		c.failed(ctx, item, err)
+	default:
+		atomic.AddInt64(&c.pagesStored, 1)
+		c.tracer.Stored(item.URL)
+	}
+	if c.recrawler != nil {
+		c.recrawler.Record(item.URL, item.Host, item.Depth, page.ContentHash, page.CrawledAt)
+	}
+
+	c.log.CrawlStatus(item.URL, queued, int(atomic.LoadInt64(&c.pagesCrawled)), c.queue.Size())
+}
+
+// deferred reserves the next rate limit slot of host for item when the queue
+// can hold items back, and hands the item back to wait there if the slot is
+// yet to come, so the worker moves on instead of sleeping. Items coming back
+// from the queue already hold their slot.
+func (c *Crawler) deferred(item queue.URLItem, host string) bool {
+	dq, ok := c.queue.(queue.Delayer)
+	if !ok || !item.NotBefore.IsZero() {
+		return false
+	}
+	delay := c.limiter.Reserve(host)
+	if delay <= 0 {
+		return false
+	}
+	return dq.Defer(item, time.Now().Add(delay))
+}
+
+// interrupted puts back an item whose fetch was cut short by the end of the
+// crawl, so the checkpoint or queue log still has it. Its URL is already
+// marked as seen and would otherwise never be crawled.
+func (c *Crawler) interrupted(item queue.URLItem) {
+	queue.Repush(c.queue, item)
+}
+
+// fetch downloads a page with the custom fetcher if one is set, or the
+// HTTP fetcher with retries
+func (c *Crawler) fetch(ctx context.Context, rawURL string, validators *fetcher.Validators) (*fetcher.Response, error) {
+	if c.custom == nil {
+		return c.fetcher.FetchWithRetry(ctx, rawURL, validators)
+	}
+	start := time.Now()
+	resp, err := c.custom.Fetch(ctx, rawURL)
+	if resp != nil {
+		if resp.RequestedURL == "" {
+			resp.RequestedURL = rawURL
+		}
+		if resp.URL == "" {
+			resp.URL = rawURL
+		}
+		if resp.Latency == 0 {
+			resp.Latency = time.Since(start)
+		}
+	}
+	return resp, err
+}
+
+// hookPassed reports whether a page goes on after a hook stage returned err.
+// ErrSkip drops the page quietly, other errors count as failures.
+func (c *Crawler) hookPassed(ctx context.Context, span *telemetry.Span, item queue.URLItem, host string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrSkip):
+		atomic.AddInt64(&c.hookSkips, 1)
+		c.tracer.Skipped(item.URL, "", skipHook)
+		span.SetString("crawler.skip_reason", skipHook)
+	default:
+		c.countError(ctx)
+		c.recorder.ObserveError(errorHook)
+		c.log.Error("Hook failed on %s: %v", item.URL, err)
+		c.tracer.Failed(item.URL, err)
+		c.activity.failed(item.URL, host, err.Error())
+		span.SetError(err)
+		c.failed(ctx, item, err)
+	}
+	return false
+}
+
+// enqueueLinks queues the links that pass the filters, dedup, robots.txt and
+// host budgets with the given priority, unless the link graph ranks them
+// higher, and the score of the page they were found on or the score
+// expression's
+func (c *Crawler) enqueueLinks(ctx context.Context, parent string, links []string, depth, priority int, score float64) int {
+	queued := 0
+	for _, abs := range links {
+		if c.subdomains != nil {
+			c.subdomains.Observe(abs, parent, c.filter.InScope(abs))
+		}
+		c.linkcheck.Link(parent, abs)
+		if ok, reason := c.filter.Check(abs); !ok {
+			if reason == filter.ReasonDomain || reason == filter.ReasonExtension {
+				c.linkcheck.Check(ctx, abs)
+			}
+			c.tracer.Skipped(abs, parent, reason)
+			continue
+		}
+		if c.seen.Seen(ctx, abs) {
+			c.boostQueued(abs)
+			c.tracer.Skipped(abs, parent, skipSeen)
+			continue
+		}
+		if !c.robots.Allowed(ctx, abs) {
+			atomic.AddInt64(&c.robotsBlocked, 1)
+			c.tracer.Skipped(abs, parent, skipRobots)
+			continue
+		}
+		if ok, reason := c.budget.Check(abs, depth); !ok {
+			c.tracer.Skipped(abs, parent, reason)
+			continue
+		}
+		if !c.seen.IsNew(ctx, abs) {
+			c.boostQueued(abs)
+			c.tracer.Skipped(abs, parent, skipSeen)
+			continue
+		}
+		// Another worker may have used up the host's pages since the check
+		if ok, reason := c.budget.Admit(abs, depth); !ok {
+			c.tracer.Skipped(abs, parent, reason)
+			continue
+		}
+
+		host, linkScore := "", score
+		if u, err := url.Parse(abs); err == nil {
+			host = u.Host
+			// The page is added to the link graph after its links are queued
+			linkScore = c.scorer.Score(u, depth, c.graph.InDegree(abs)+1, score)
+		}
+		// Workers are the queue's consumers, so they never wait for room
+		queue.PushNoWait(c.queue, queue.URLItem{
+			URL:      abs,
+			Priority: min(priority, c.prioritizer.priority(abs, priority)),
+			Host:     host,
+			Depth:    depth,
+			Score:    linkScore,
+		})
+		c.tracer.Queued(abs, parent, depth)
+		queued++
+	}
+	atomic.AddInt64(&c.linksQueued, int64(queued))
+	return queued
+}
+
+// boostQueued raises a queued URL by the inlink boost each time another page
+// links to it. URLs already fetched or queued elsewhere are left alone.
+func (c *Crawler) boostQueued(u string) {
+	if c.cfg.Queue.InlinkBoost <= 0 {
+		return
+	}
+	if uq, ok := c.queue.(queue.Updater); ok {
+		uq.Update(u, func(item queue.URLItem) int {
+			return item.Priority - c.cfg.Queue.InlinkBoost
+		})
+	}
+}
+
+// isNearDuplicate reports whether the page text is a near duplicate of a stored page
+func (c *Crawler) isNearDuplicate(content string) bool {
+	if c.content == nil {
+		return false
+	}
+	if _, dup := c.content.IsDuplicate(utils.ExtractText(content)); dup {
+		atomic.AddInt64(&c.nearDupes, 1)
+		return true
+	}
+	return false
+}
+
+// pageState returns what the previous crawl of a URL stored, if the archiver
+// keeps it
+func (c *Crawler) pageState(ctx context.Context, rawURL string) *storage.PageState {
+	if c.mongo == nil {
+		return nil
+	}
+	state, err := c.mongo.State(ctx, rawURL)
+	if err != nil {
+		c.log.Debug("Failed to load stored state of %s: %v", rawURL, err)
+		return nil
+	}
+	return state
+}
+
+// validators returns the cache validators from a previous crawl
+func (c *Crawler) validators(prev *storage.PageState) *fetcher.Validators {
+	if prev == nil || !c.cfg.HTTP.ConditionalRequests {
+		return nil
+	}
+	return &fetcher.Validators{ETag: prev.ETag, LastModified: prev.LastModified}
+}
+
+// detectChange sets the content hash of page and compares it with the
+// previous crawl. The first crawl and every change add a history entry; it
+// reports whether the content changed.
+func detectChange(page *storage.WebPage, body []byte, prev *storage.PageState) bool {
+	sum := sha256.Sum256(body)
+	page.ContentHash = hex.EncodeToString(sum[:])
+	switch {
+	case prev == nil || prev.ContentHash == "":
+		page.Changes = []storage.ContentChange{{At: page.CrawledAt, Hash: page.ContentHash}}
+	case prev.ContentHash != page.ContentHash:
+		page.Changed = true
+		page.Changes = []storage.ContentChange{{At: page.CrawledAt, Hash: page.ContentHash, Previous: prev.ContentHash}}
+	}
+	return page.Changed
+}
+
+// markUnchanged records a 304 answer without re-storing the page
+func (c *Crawler) markUnchanged(ctx context.Context, item queue.URLItem) {
+	now := time.Now()
+	if c.mongo != nil {
+		c.mongo.MarkUnchanged(ctx, item.URL, now)
+	}
+	if c.recrawler != nil {
+		if hash, ok := c.recrawler.Hash(item.URL); ok {
+			// Same hash as last time counts as unchanged
+			c.recrawler.Record(item.URL, item.Host, item.Depth, hash, now)
+		}
+	}
+}
+
+// maxEmbeddedResources caps the resources fetched for one MHTML file
+const maxEmbeddedResources = 50
+
+// embeddedResources downloads the stylesheets, scripts and images of a page
+// for an MHTML file. Resources that fail or are too large are left out.
+func (c *Crawler) embeddedResources(ctx context.Context, base *url.URL, content string) []utils.Resource {
+	maxSizes := map[string]int64{"*/*": c.cfg.ContentSaver.MaxResourceSize}
+	var resources []utils.Resource
+	for _, ref := range utils.ExtractEmbeddedResources(content, base) {
+		if len(resources) == maxEmbeddedResources {
+			break
+		}
+		resp, err := c.fetcher.FetchAsset(ctx, ref, maxSizes)
+		if err != nil || resp.StatusCode != 200 {
+			continue
+		}
+		resources = append(resources, utils.Resource{URL: ref, ContentType: resp.ContentType, Data: resp.Body})
+	}
+	return resources
+}
+
+// mirror writes a page to the offline copy and downloads the resources it
+// needs, following stylesheet references, up to the per-page limit
+func (c *Crawler) mirror(ctx context.Context, pageURL, content string) {
+	pending, err := c.saver.MirrorPage(pageURL, content)
+	if err != nil {
+		c.log.Warn("Failed to mirror %s: %v", pageURL, err)
+		return
+	}
+	atomic.AddInt64(&c.mirrorPages, 1)
+
+	maxSizes := map[string]int64{"*/*": c.cfg.ContentSaver.MaxResourceSize}
+	for fetched := 0; len(pending) > 0; fetched++ {
+		if fetched == c.cfg.ContentSaver.Mirror.MaxResources {
+			c.log.Debug("Left %d resources of %s out of the mirror", len(pending), pageURL)
+			return
+		}
+		ref := pending[0]
+		pending = pending[1:]
+		resp, err := c.fetcher.FetchAsset(ctx, ref, maxSizes)
+		if err != nil || resp.StatusCode != 200 {
+			continue
+		}
+		nested, err := c.saver.MirrorResource(ref, resp.ContentType, resp.Body)
+		if err != nil {
+			c.log.Warn("Failed to mirror %s: %v", ref, err)
+			continue
+		}
+		atomic.AddInt64(&c.mirrorFiles, 1)
+		pending = append(pending, nested...)
+	}
+}
+
+// saveAssets downloads the images and documents referenced by a page
+func (c *Crawler) saveAssets(ctx context.Context, base *url.URL, content string) {
+	assets := c.cfg.ContentSaver.Assets
+	for _, assetURL := range utils.ExtractAssetLinks(content, base) {
+		if !c.seen.IsNew(ctx, assetURL) {
+			continue
+		}
+		resp, err := c.fetcher.FetchAsset(ctx, assetURL, assets.MaxSizes)
+		if err != nil || resp.StatusCode != 200 {
+			continue
+		}
+		if _, err := c.saver.SaveAsset(assets.Dir, assetURL, base.String(), resp.ContentType, resp.Body, time.Now()); err != nil {
+			c.log.Warn("Failed to save asset %s: %v", assetURL, err)
+			continue
+		}
+		atomic.AddInt64(&c.assetsSaved, 1)
+	}
+}
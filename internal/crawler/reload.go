@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"strings"
+
+	"web-crawler/internal/config"
+)
+
+// applyConfig applies a reloaded configuration. Filters, rate limits, the
+// worker count, and autoscaling take effect right away; other changes are
+// logged as needing a restart. Nothing is applied if the new filters don't compile.
+func (c *Crawler) applyConfig(updated *config.Config, changes []config.Change) error {
+	changed := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		changed[change.Path] = true
+	}
+
+	if err := c.filter.Reload(updated.Filters); err != nil {
+		return err
+	}
+	c.limiter.Reload(updated.Filters.RateLimits)
+	c.reloadBreaker(updated.Filters.RateLimits.Breaker)
+
+	// Only touch settings that were edited, so values set through the API stick
+	if changed["crawler.rate_limit"] {
+		c.SetRateLimit(updated.Crawler.RateLimit)
+	}
+	c.autoscale.setConfig(updated.Crawler.Autoscale)
+	if changed["crawler.workers"] {
+		c.SetWorkers(c.autoscale.clamp(updated.Crawler.Workers))
+	}
+
+	for _, change := range changes {
+		if reloadable(change.Path) {
+			c.log.Info("Config reloaded: %s", change)
+		} else {
+			c.log.Warn("Config changed, restart to apply: %s", change)
+		}
+	}
+	return nil
+}
+
+// reloadable reports whether a setting is applied to a running crawl. The
+// domain lists are refreshed on their own schedule, but the set of lists is
+// fixed at startup.
+func reloadable(path string) bool {
+	if strings.HasPrefix(path, "filters.lists.") {
+		return false
+	}
+	return strings.HasPrefix(path, "filters.") || strings.HasPrefix(path, "crawler.autoscale.") ||
+		path == "crawler.workers" || path == "crawler.rate_limit"
+}
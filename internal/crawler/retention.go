@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/pkg/utils"
+)
+
+// RetentionPolicy converts the retention settings of the content saver
+func RetentionPolicy(cfg config.RetentionConfig) utils.RetentionPolicy {
+	return utils.RetentionPolicy{
+		MaxTotalSize:      cfg.MaxTotalSize,
+		MaxFilesPerDomain: cfg.MaxFilesPerDomain,
+		MaxAge:            cfg.MaxAge,
+	}
+}
+
+// runRetention sweeps the saved pages every retention interval
+func (c *Crawler) runRetention(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.ContentSaver.Retention.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.sweepContent(now)
+		}
+	}
+}
+
+// sweepContent applies the retention policy once. A dry run only logs what
+// would be deleted.
+func (c *Crawler) sweepContent(now time.Time) {
+	cfg := c.cfg.ContentSaver.Retention
+	report, err := c.saver.Sweep(RetentionPolicy(cfg), now, cfg.DryRun)
+	atomic.AddInt64(&c.retentionSweeps, 1)
+	if err != nil {
+		c.log.Warn("Content retention sweep failed: %v", err)
+	}
+	if report == nil || len(report.Deleted) == 0 {
+		return
+	}
+	if cfg.DryRun {
+		for _, d := range report.Deleted {
+			c.log.Debug("Retention would delete %s (%s, %d bytes)", d.Path, d.Reason, d.Size)
+		}
+		c.log.Info("Retention dry run: would delete %d of %d saved pages, freeing %d bytes",
+			len(report.Deleted), report.Scanned, report.FreedBytes)
+		return
+	}
+	atomic.AddInt64(&c.retentionDeleted, int64(len(report.Deleted)))
+	atomic.AddInt64(&c.retentionFreed, report.FreedBytes)
+	c.log.Info("Retention deleted %d of %d saved pages, freeing %d bytes", len(report.Deleted), report.Scanned, report.FreedBytes)
+}
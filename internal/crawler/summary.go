@@ -0,0 +1,161 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/logger"
+)
+
+// summaryDomains is how many domains the console summary lists
+const summaryDomains = 10
+
+// Summary is the report of a crawl, printed and saved when it ends
+type Summary struct {
+	RunID          string          `json:"runId,omitempty"` // Of the saved benchmark run
+	StartedAt      time.Time       `json:"startedAt"`
+	FinishedAt     time.Time       `json:"finishedAt"`
+	ElapsedSeconds float64         `json:"elapsedSeconds"`
+	Pages          int64           `json:"pages"`
+	Stored         int64           `json:"stored"`
+	Errors         int64           `json:"errors"`
+	Fetches        int64           `json:"fetches"`      // Responses downloaded, without cache hits
+	AvgLatencyMs   float64         `json:"avgLatencyMs"` // Over the fetches
+	Bytes          int64           `json:"bytes"`        // Body bytes downloaded
+	DuplicateURLs  int64           `json:"duplicateUrls"`
+	NearDuplicates int64           `json:"nearDuplicates"` // Pages skipped for their content
+	Statuses       map[int]int64   `json:"statuses"`       // Responses by status code
+	Domains        []DomainSummary `json:"domains"`        // Most crawled pages first
+}
+
+// DomainSummary is the share of one domain in a crawl
+type DomainSummary struct {
+	Domain       string  `json:"domain"`
+	Pages        int64   `json:"pages"`
+	Errors       int64   `json:"errors"`
+	Bytes        int64   `json:"bytes"`
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+// Summary returns the report of the crawl so far
+func (c *Crawler) Summary() Summary {
+	fetches, latency, bytes := c.recorder.Totals()
+	now := time.Now()
+	s := Summary{
+		RunID:          c.runID,
+		StartedAt:      c.recorder.Start(),
+		FinishedAt:     now,
+		ElapsedSeconds: now.Sub(c.recorder.Start()).Seconds(),
+		Pages:          atomic.LoadInt64(&c.pagesCrawled),
+		Stored:         atomic.LoadInt64(&c.pagesStored),
+		Errors:         atomic.LoadInt64(&c.errors),
+		Fetches:        fetches,
+		AvgLatencyMs:   float64(latency) / float64(time.Millisecond),
+		Bytes:          bytes,
+		DuplicateURLs:  c.seen.GetStats()["duplicates"],
+		NearDuplicates: atomic.LoadInt64(&c.nearDupes),
+	}
+
+	c.activity.mu.Lock()
+	s.Statuses = make(map[int]int64, len(c.activity.statuses))
+	for status, n := range c.activity.statuses {
+		s.Statuses[status] = n
+	}
+	s.Domains = make([]DomainSummary, 0, len(c.activity.hosts))
+	for host, h := range c.activity.hosts {
+		d := DomainSummary{Domain: host, Pages: h.pages, Errors: h.errors, Bytes: h.bytes}
+		if h.pages > 0 {
+			d.AvgLatencyMs = float64(h.latency) / float64(h.pages) / float64(time.Millisecond)
+		}
+		s.Domains = append(s.Domains, d)
+	}
+	c.activity.mu.Unlock()
+
+	sort.Slice(s.Domains, func(i, j int) bool {
+		if s.Domains[i].Pages != s.Domains[j].Pages {
+			return s.Domains[i].Pages > s.Domains[j].Pages
+		}
+		return s.Domains[i].Domain < s.Domains[j].Domain
+	})
+	return s
+}
+
+// Save writes the summary as JSON to path
+func (s Summary) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
+
+// Render formats the summary for the console
+func (s Summary) Render() string {
+	var sb strings.Builder
+
+	elapsed := time.Duration(s.ElapsedSeconds * float64(time.Second)).Truncate(time.Second)
+	rate := 0.0
+	if s.ElapsedSeconds > 0 {
+		rate = float64(s.Pages) / s.ElapsedSeconds
+	}
+	fmt.Fprintf(&sb, "\n%sCrawl summary%s\n", logger.Cyan, logger.Reset)
+	if s.RunID != "" {
+		fmt.Fprintf(&sb, "  run          %s\n", benchmark.ShortID(s.RunID))
+	}
+	fmt.Fprintf(&sb, "  elapsed      %s\n", elapsed)
+	fmt.Fprintf(&sb, "  pages        %d (%.1f/s), %d stored\n", s.Pages, rate, s.Stored)
+	fmt.Fprintf(&sb, "  errors       %s%d%s\n", logger.Red, s.Errors, logger.Reset)
+	fmt.Fprintf(&sb, "  downloaded   %s in %d fetches, %.0fms average latency\n", formatBytes(s.Bytes), s.Fetches, s.AvgLatencyMs)
+	fmt.Fprintf(&sb, "  duplicates   %d URLs, %d near duplicate pages\n", s.DuplicateURLs, s.NearDuplicates)
+
+	if len(s.Statuses) > 0 {
+		statuses := make([]int, 0, len(s.Statuses))
+		for status := range s.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		fmt.Fprintf(&sb, "\n%sStatus codes%s\n", logger.Cyan, logger.Reset)
+		for _, status := range statuses {
+			fmt.Fprintf(&sb, "  %d  %8d\n", status, s.Statuses[status])
+		}
+	}
+
+	if len(s.Domains) > 0 {
+		fmt.Fprintf(&sb, "\n%sDomains%s  %d crawled\n", logger.Cyan, logger.Reset, len(s.Domains))
+		for i, d := range s.Domains {
+			if i == summaryDomains {
+				fmt.Fprintf(&sb, "  ... %d more\n", len(s.Domains)-summaryDomains)
+				break
+			}
+			fmt.Fprintf(&sb, "  %-32s %7d pages %5d err %10s %6.0fms\n", d.Domain, d.Pages, d.Errors, formatBytes(d.Bytes), d.AvgLatencyMs)
+		}
+	}
+	return sb.String()
+}
+
+// formatBytes formats n with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
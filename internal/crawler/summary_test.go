@@ -0,0 +1,68 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/dedup"
+)
+
+func TestSummary(t *testing.T) {
+	a := newActivity()
+	a.fetched(200)
+	a.fetched(200)
+	a.fetched(404)
+	a.crawled("https://a.com/", "a.com", 200, 10*time.Millisecond, 1024)
+	a.crawled("https://a.com/x", "www.a.com", 200, 30*time.Millisecond, 1024)
+	a.crawled("https://b.com/", "b.com", 200, 20*time.Millisecond, 10)
+	a.failed("https://b.com/y", "b.com", "HTTP 404")
+
+	rec := benchmark.New()
+	rec.ObserveFetch("a.com", 10*time.Millisecond, 1024)
+	rec.ObserveFetch("a.com", 30*time.Millisecond, 1024)
+	seen := dedup.NewURLFilter(dedup.NewMemoryStore())
+	seen.IsNew(context.Background(), "https://a.com/")
+	seen.IsNew(context.Background(), "https://a.com/")
+	c := &Crawler{activity: a, recorder: rec, seen: seen, pagesCrawled: 3, errors: 1, nearDupes: 2}
+
+	s := c.Summary()
+	if s.Pages != 3 || s.Errors != 1 || s.Fetches != 2 || s.Bytes != 2048 || s.AvgLatencyMs != 20 || s.DuplicateURLs != 1 || s.NearDuplicates != 2 {
+		t.Fatalf("Summary() = %+v", s)
+	}
+	if s.Statuses[200] != 2 || s.Statuses[404] != 1 {
+		t.Fatalf("statuses = %v", s.Statuses)
+	}
+	want := []DomainSummary{
+		{Domain: "a.com", Pages: 2, Bytes: 2048, AvgLatencyMs: 20},
+		{Domain: "b.com", Pages: 1, Errors: 1, Bytes: 10, AvgLatencyMs: 20},
+	}
+	if len(s.Domains) != 2 || s.Domains[0] != want[0] || s.Domains[1] != want[1] {
+		t.Fatalf("domains = %+v", s.Domains)
+	}
+
+	out := s.Render()
+	for _, part := range []string{"3 (", "2.0 KiB in 2 fetches", "404         1", "a.com"} {
+		if !strings.Contains(out, part) {
+			t.Errorf("Render() lacks %q:\n%s", part, out)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "bench", "summary.json")
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Summary
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Statuses[404] != 1 || len(saved.Domains) != 2 {
+		t.Fatalf("saved %s: %v", data, err)
+	}
+}
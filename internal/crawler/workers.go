@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// workerKey is the context key of the state of the worker processing a URL
+type workerKey struct{}
+
+// withWorker returns ctx carrying the state of worker w
+func withWorker(ctx context.Context, w *workerState) context.Context {
+	return context.WithValue(ctx, workerKey{}, w)
+}
+
+// workerFrom returns the state of the worker processing a URL with ctx, nil
+// outside of workers
+func workerFrom(ctx context.Context) *workerState {
+	w, _ := ctx.Value(workerKey{}).(*workerState)
+	return w
+}
+
+// workerSuffix names the worker processing a URL with ctx in log messages
+func workerSuffix(ctx context.Context) string {
+	if w := workerFrom(ctx); w != nil {
+		return fmt.Sprintf(" (worker %d)", w.id)
+	}
+	return ""
+}
+
+// countError counts a failed URL for the crawl and the worker processing it
+func (c *Crawler) countError(ctx context.Context) {
+	atomic.AddInt64(&c.errors, 1)
+	if w := workerFrom(ctx); w != nil {
+		atomic.AddInt64(&w.errors, 1)
+	}
+}
+
+// countPage counts a fetched page for the crawl and the worker processing it
+func (c *Crawler) countPage(ctx context.Context) {
+	atomic.AddInt64(&c.pagesCrawled, 1)
+	if w := workerFrom(ctx); w != nil {
+		atomic.AddInt64(&w.pages, 1)
+	}
+}
+
+// workerStats is what one worker is doing and has done, in the stats
+type workerStats struct {
+	ID             int     `json:"id"`
+	State          string  `json:"state"`
+	URL            string  `json:"url,omitempty"`
+	StateSeconds   float64 `json:"stateSeconds"` // Time in the current state, e.g. on the current URL
+	Processed      int64   `json:"processed"`
+	Pages          int64   `json:"pages"`
+	Errors         int64   `json:"errors"`
+	PagesPerSecond float64 `json:"pagesPerSecond"`
+	ErrorRate      float64 `json:"errorRate"` // Errors per processed URL
+}
+
+// stats returns the activity of the worker as of now
+func (w *workerState) stats(now time.Time) workerStats {
+	w.mu.Lock()
+	s := workerStats{
+		ID:           w.id,
+		State:        w.state,
+		URL:          w.url,
+		StateSeconds: now.Sub(w.since).Seconds(),
+	}
+	start := w.start
+	w.mu.Unlock()
+
+	s.Processed = atomic.LoadInt64(&w.processed)
+	s.Pages = atomic.LoadInt64(&w.pages)
+	s.Errors = atomic.LoadInt64(&w.errors)
+	if elapsed := now.Sub(start).Seconds(); elapsed > 0 {
+		s.PagesPerSecond = float64(s.Pages) / elapsed
+	}
+	if s.Processed > 0 {
+		s.ErrorRate = float64(s.Errors) / float64(s.Processed)
+	}
+	return s
+}
+
+// workerStats returns the activity of every running worker
+func (c *Crawler) workerStats() []workerStats {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+
+	now := time.Now()
+	stats := make([]workerStats, 0, len(c.workerState))
+	for _, w := range c.workerState {
+		stats = append(stats, w.stats(now))
+	}
+	return stats
+}
@@ -0,0 +1,40 @@
+package crawler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorkerStats(t *testing.T) {
+	w := newWorkerState(3)
+	c := &Crawler{workerState: []*workerState{newWorkerState(1), w}}
+	ctx := withWorker(context.Background(), w)
+
+	c.countPage(ctx)
+	c.countPage(ctx)
+	c.countError(ctx)
+	c.countError(context.Background()) // Outside of workers
+	w.processed = 4
+	w.set(workerFetching, "https://a.com/slow")
+
+	if c.pagesCrawled != 2 || c.errors != 2 {
+		t.Fatalf("crawl counted %d pages and %d errors", c.pagesCrawled, c.errors)
+	}
+	stats := c.workerStats()
+	if len(stats) != 2 || stats[0].Pages != 0 || stats[0].Errors != 0 {
+		t.Fatalf("workerStats() = %+v", stats)
+	}
+	s := stats[1]
+	if s.ID != 3 || s.State != workerFetching || s.URL != "https://a.com/slow" || s.Pages != 2 || s.Errors != 1 || s.ErrorRate != 0.25 || s.PagesPerSecond <= 0 {
+		t.Fatalf("worker 3 stats = %+v", s)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if s := w.stats(time.Now()); s.StateSeconds < 0.01 {
+		t.Fatalf("worker on its URL for %.3fs", s.StateSeconds)
+	}
+	if got := workerSuffix(ctx); got != " (worker 3)" {
+		t.Fatalf("workerSuffix() = %q", got)
+	}
+}
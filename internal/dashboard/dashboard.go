@@ -0,0 +1,242 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"web-crawler/internal/logger"
+)
+
+// ANSI control sequences
+const (
+	clearScreen = "\033[H\033[2J"
+	hideCursor  = "\033[?25l"
+	showCursor  = "\033[?25h"
+	bold        = "\033[1m"
+)
+
+// Rows shown in the per-host and per-worker tables
+const (
+	maxHosts   = 10
+	maxWorkers = 20
+)
+
+// WorkerState is what one worker is doing
+type WorkerState struct {
+	ID    int
+	State string // idle, fetching, or paused
+	URL   string
+	Since time.Time
+}
+
+// HostProgress is the crawl progress of one host
+type HostProgress struct {
+	Host     string
+	Crawled  int64         // Pages fetched and parsed
+	Errors   int64         // Failed fetches and error statuses
+	Bytes    int64         // Body bytes of crawled pages
+	Latency  time.Duration // Average fetch latency
+	Admitted int           // URLs let through by the host budget
+	MaxPages int           // 0 = unlimited
+}
+
+// Snapshot is the crawl state shown on one refresh
+type Snapshot struct {
+	Elapsed   time.Duration
+	Pages     int64
+	Stored    int64
+	Errors    int64
+	Paused    bool
+	RateLimit time.Duration
+	Queue     map[string]int64 // Queue statistics, as returned by GetStats
+	Hosts     []HostProgress
+	Workers   []WorkerState
+}
+
+// Source provides snapshots of a running crawl
+type Source interface {
+	Snapshot() Snapshot
+}
+
+// Dashboard redraws a live view of the crawl in the terminal
+type Dashboard struct {
+	src      Source
+	out      io.Writer
+	interval time.Duration
+	width    int
+
+	prev   Snapshot
+	prevAt time.Time
+}
+
+// New creates a dashboard that refreshes every interval
+func New(src Source, out io.Writer, interval time.Duration) *Dashboard {
+	width := 100
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 40 {
+		width = cols
+	}
+	return &Dashboard{src: src, out: out, interval: interval, width: width}
+}
+
+// Run redraws the dashboard until ctx is done
+func (d *Dashboard) Run(ctx context.Context) {
+	fmt.Fprint(d.out, hideCursor)
+	defer fmt.Fprint(d.out, showCursor)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.draw()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw renders one frame
+func (d *Dashboard) draw() {
+	snap := d.src.Snapshot()
+	now := time.Now()
+
+	// Current rate from the change since the last frame, average over the whole crawl
+	var rate, errRate float64
+	if !d.prevAt.IsZero() {
+		if secs := now.Sub(d.prevAt).Seconds(); secs > 0 {
+			rate = float64(snap.Pages-d.prev.Pages) / secs
+			errRate = float64(snap.Errors-d.prev.Errors) / secs
+		}
+	}
+	d.prev, d.prevAt = snap, now
+
+	fmt.Fprint(d.out, clearScreen+Render(snap, rate, errRate, d.width))
+}
+
+// Render formats a snapshot. rate and errRate are the current pages and
+// errors per second.
+func Render(snap Snapshot, rate, errRate float64, width int) string {
+	var sb strings.Builder
+
+	status := logger.Green + "running" + logger.Reset
+	if snap.Paused {
+		status = logger.Yellow + "paused" + logger.Reset
+	}
+	fmt.Fprintf(&sb, "%sWeb Crawler%s  %s  elapsed %s  delay %s\n\n",
+		bold, logger.Reset, status, snap.Elapsed.Truncate(time.Second), snap.RateLimit)
+
+	// Throughput
+	avg := 0.0
+	if secs := snap.Elapsed.Seconds(); secs > 0 {
+		avg = float64(snap.Pages) / secs
+	}
+	errPct := 0.0
+	if total := snap.Pages + snap.Errors; total > 0 {
+		errPct = float64(snap.Errors) * 100 / float64(total)
+	}
+	fmt.Fprintf(&sb, "%sThroughput%s\n", bold, logger.Reset)
+	fmt.Fprintf(&sb, "  pages     %s%-10d%s %6.1f/s now  %6.1f/s avg   stored %d\n",
+		logger.Cyan, snap.Pages, logger.Reset, rate, avg, snap.Stored)
+	fmt.Fprintf(&sb, "  errors    %s%-10d%s %6.1f/s now  %5.1f%% of requests\n\n",
+		logger.Red, snap.Errors, logger.Reset, errRate, errPct)
+
+	// Queue
+	fmt.Fprintf(&sb, "%sQueue%s  %d queued, %d dequeued", bold, logger.Reset, snap.Queue["size"], snap.Queue["totalDequeued"])
+	if n := snap.Queue["dropped"]; n > 0 {
+		fmt.Fprintf(&sb, ", %s%d dropped%s", logger.Red, n, logger.Reset)
+	}
+	if n := snap.Queue["spillPending"]; n > 0 {
+		fmt.Fprintf(&sb, ", %d spilled to disk", n)
+	}
+	sb.WriteString("\n")
+	if _, ok := snap.Queue["highBuffer"]; ok {
+		barWidth := width - 24
+		largest := max(snap.Queue["highBuffer"], snap.Queue["normalBuffer"], snap.Queue["lowBuffer"], 1)
+		for _, p := range []struct{ name, key string }{{"high", "highBuffer"}, {"normal", "normalBuffer"}, {"low", "lowBuffer"}} {
+			n := snap.Queue[p.key]
+			fmt.Fprintf(&sb, "  %-7s %8d %s\n", p.name, n, bar(float64(n)/float64(largest), barWidth))
+		}
+	}
+	sb.WriteString("\n")
+
+	// Hosts with the most crawled pages
+	hosts := append([]HostProgress(nil), snap.Hosts...)
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Crawled != hosts[j].Crawled {
+			return hosts[i].Crawled > hosts[j].Crawled
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	fmt.Fprintf(&sb, "%sHosts%s  %d known\n", bold, logger.Reset, len(hosts))
+	hostWidth := min(32, width/3)
+	for i, h := range hosts {
+		if i == maxHosts {
+			fmt.Fprintf(&sb, "  ... %d more\n", len(hosts)-maxHosts)
+			break
+		}
+		limit := "∞"
+		progress := ""
+		if h.MaxPages > 0 {
+			limit = strconv.Itoa(h.MaxPages)
+			progress = bar(float64(h.Admitted)/float64(h.MaxPages), width-hostWidth-46)
+		}
+		fmt.Fprintf(&sb, "  %-*s %7d crawled %s%5d err%s %7d/%-6s %s\n", hostWidth, truncate(h.Host, hostWidth),
+			h.Crawled, logger.Red, h.Errors, logger.Reset, h.Admitted, limit, progress)
+	}
+	sb.WriteString("\n")
+
+	// Workers
+	counts := make(map[string]int)
+	for _, w := range snap.Workers {
+		counts[w.State]++
+	}
+	fmt.Fprintf(&sb, "%sWorkers%s  %d fetching, %d idle, %d paused\n", bold, logger.Reset, counts["fetching"], counts["idle"], counts["paused"])
+	now := time.Now()
+	shown := 0
+	for _, w := range snap.Workers {
+		// Idle workers are only counted, they'd crowd out the busy ones
+		if w.State == "idle" {
+			continue
+		}
+		if shown == maxWorkers {
+			fmt.Fprintf(&sb, "  ... %d more\n", len(snap.Workers)-counts["idle"]-maxWorkers)
+			break
+		}
+		shown++
+		color := logger.Green
+		if w.State == "paused" {
+			color = logger.Yellow
+		}
+		fmt.Fprintf(&sb, "  #%-3d %s%-8s%s %6s  %s\n", w.ID, color, w.State, logger.Reset,
+			now.Sub(w.Since).Truncate(100*time.Millisecond), truncate(w.URL, width-26))
+	}
+
+	sb.WriteString("\nPress Ctrl+C to stop the crawl\n")
+	return sb.String()
+}
+
+// bar draws a progress bar of the given fill ratio
+func bar(ratio float64, width int) string {
+	if width < 10 {
+		width = 10
+	}
+	ratio = max(0, min(ratio, 1))
+	filled := int(ratio * float64(width))
+	return logger.Green + strings.Repeat("█", filled) + logger.Reset + strings.Repeat("░", width-filled)
+}
+
+// truncate shortens s to n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 1 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,119 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ansi matches the escape sequences used for colors and cursor control
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// plain strips ANSI escapes from s
+func plain(s string) string {
+	return ansi.ReplaceAllString(s, "")
+}
+
+func TestRender(t *testing.T) {
+	snap := Snapshot{
+		Elapsed:   10 * time.Second,
+		Pages:     50,
+		Errors:    50,
+		Paused:    true,
+		RateLimit: 250 * time.Millisecond,
+		Queue:     map[string]int64{"size": 7, "totalDequeued": 3, "dropped": 12, "highBuffer": 4, "normalBuffer": 2, "lowBuffer": 1},
+		Hosts: []HostProgress{
+			{Host: "b.com", Crawled: 5, Admitted: 5, MaxPages: 10},
+			{Host: "a.com", Crawled: 20, Errors: 2, Admitted: 25},
+		},
+		Workers: []WorkerState{
+			{ID: 1, State: "fetching", URL: "https://a.com/page", Since: time.Now()},
+			{ID: 2, State: "idle"},
+		},
+	}
+	out := plain(Render(snap, 2.5, 0, 100))
+
+	for _, want := range []string{
+		"paused",
+		"5.0/s avg",
+		"50.0% of requests",
+		"7 queued, 3 dequeued, 12 dropped",
+		"1 fetching, 1 idle, 0 paused",
+		"https://a.com/page",
+		"5/10",
+		"25/∞",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered dashboard lacks %q:\n%s", want, out)
+		}
+	}
+	// Busiest host first, idle workers only counted
+	if strings.Index(out, "a.com") > strings.Index(out, "b.com") {
+		t.Errorf("hosts not ordered by crawled pages:\n%s", out)
+	}
+	if strings.Contains(out, "#2") {
+		t.Errorf("idle worker listed:\n%s", out)
+	}
+}
+
+func TestRenderLimitsHosts(t *testing.T) {
+	var snap Snapshot
+	for i := 0; i < maxHosts+3; i++ {
+		snap.Hosts = append(snap.Hosts, HostProgress{Host: fmt.Sprintf("host%02d.com", i), Crawled: int64(i)})
+	}
+	out := plain(Render(snap, 0, 0, 100))
+	if !strings.Contains(out, "... 3 more") || strings.Contains(out, "host00.com") {
+		t.Fatalf("host table not capped at the busiest %d:\n%s", maxHosts, out)
+	}
+}
+
+func TestBarAndTruncate(t *testing.T) {
+	if got := plain(bar(0.5, 10)); got != "█████░░░░░" {
+		t.Errorf("bar(0.5) = %q", got)
+	}
+	if got := plain(bar(2, 10)); got != strings.Repeat("█", 10) {
+		t.Errorf("bar(2) = %q", got)
+	}
+	if got := truncate("example.com", 5); got != "exam…" {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate("a.com", 5); got != "a.com" {
+		t.Errorf("truncate() of a short string = %q", got)
+	}
+}
+
+// source counts the snapshots taken
+type source struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *source) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return Snapshot{Pages: int64(s.calls)}
+}
+
+func TestRunRedrawsUntilDone(t *testing.T) {
+	src := &source{}
+	var out bytes.Buffer
+	d := New(src, &out, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.Run(ctx)
+
+	if src.calls < 2 {
+		t.Fatalf("dashboard drew %d frames, want several", src.calls)
+	}
+	s := out.String()
+	if !strings.HasPrefix(s, hideCursor+clearScreen) || !strings.HasSuffix(s, showCursor) {
+		t.Fatal("dashboard doesn't hide the cursor while running and restore it after")
+	}
+}
@@ -0,0 +1,118 @@
+// Package debug serves the net/http/pprof profiles and expvar variables of
+// the process on an address of their own, to profile long-running crawls
+// without rebuilding
+package debug
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the debug package
+var log = logger.For("debug")
+
+// Server serves /debug/pprof/ and /debug/vars
+type Server struct {
+	cfg    config.DebugConfig
+	server *http.Server
+}
+
+// NewServer creates a debug server listening on cfg.Addr. The endpoints
+// expose the process internals, so the address should not be reachable from
+// outside.
+func NewServer(cfg config.DebugConfig) *Server {
+	mux := http.NewServeMux()
+	// Index also serves the named profiles: heap, goroutine, allocs, block,
+	// mutex and threadcreate
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return &Server{
+		cfg: cfg,
+		// No write timeout, CPU profiles and traces take as long as asked
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// Start turns on the configured block and mutex profiling, then listens on
+// the server address and serves in the background
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for debug endpoints: %w", err)
+	}
+	if s.cfg.BlockRate > 0 {
+		runtime.SetBlockProfileRate(s.cfg.BlockRate)
+	}
+	if s.cfg.MutexFraction > 0 {
+		runtime.SetMutexProfileFraction(s.cfg.MutexFraction)
+	}
+	log.Warn("Debug endpoints listening on http://%s/debug/pprof/", ln.Addr())
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Debug endpoints stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the debug server
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down debug server: %w", err)
+	}
+	return nil
+}
+
+var (
+	publishedMu sync.Mutex
+	published   = make(map[string]*funcVar)
+)
+
+// funcVar is an expvar.Func whose function can be replaced
+type funcVar struct {
+	mu sync.RWMutex
+	fn func() interface{}
+}
+
+func (v *funcVar) String() string {
+	v.mu.RLock()
+	fn := v.fn
+	v.mu.RUnlock()
+	return expvar.Func(fn).String()
+}
+
+// Publish exposes what fn returns, as JSON, under name in /debug/vars.
+// Unlike expvar.Publish, publishing a name again replaces its function, so a
+// new crawl in the same process takes over the name.
+func Publish(name string, fn func() interface{}) {
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+	if v, ok := published[name]; ok {
+		v.mu.Lock()
+		v.fn = fn
+		v.mu.Unlock()
+		return
+	}
+	v := &funcVar{fn: fn}
+	expvar.Publish(name, v)
+	published[name] = v
+}
@@ -0,0 +1,68 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func get(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s = %d: %s", url, resp.StatusCode, body)
+	}
+	return string(body)
+}
+
+func TestServer(t *testing.T) {
+	ts := httptest.NewServer(NewServer(config.DebugConfig{Addr: "127.0.0.1:0"}).server.Handler)
+	defer ts.Close()
+
+	if body := get(t, ts.URL+"/debug/pprof/"); !strings.Contains(body, "goroutine") {
+		t.Errorf("pprof index lists no goroutine profile:\n%s", body)
+	}
+	if body := get(t, ts.URL+"/debug/pprof/heap?debug=1"); !strings.Contains(body, "heap profile") {
+		t.Errorf("heap profile = %.100s", body)
+	}
+
+	Publish("debug_test", func() interface{} { return map[string]int{"pages": 1} })
+	Publish("debug_test", func() interface{} { return map[string]int{"pages": 2} })
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(get(t, ts.URL+"/debug/vars")), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vars["memstats"]; !ok {
+		t.Error("no memstats in /debug/vars")
+	}
+	if got := string(vars["debug_test"]); got != `{"pages":2}` {
+		t.Errorf("published var = %s, want the replaced function's value", got)
+	}
+}
+
+func TestStart(t *testing.T) {
+	s := NewServer(config.DebugConfig{Addr: "127.0.0.1:0"})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewServer(config.DebugConfig{Addr: "256.0.0.1:0"}).Start(); err == nil {
+		t.Error("Start() listened on an invalid address")
+	}
+}
@@ -0,0 +1,91 @@
+package dedup
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+// BloomStore is a lock-free Bloom filter seen-set. It never reports a seen
+// URL as new, but may report a small fraction of new URLs as already seen.
+type BloomStore struct {
+	bits  []uint64
+	m     uint64 // Number of bits
+	k     uint64 // Number of hash functions
+	count int64
+}
+
+// NewBloomStore sizes a Bloom filter for n expected items at false positive rate p
+func NewBloomStore(n uint64, p float64) *BloomStore {
+	if n == 0 {
+		n = 1000000
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.001
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomStore{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// hashes returns the two base hashes used for double hashing
+func (b *BloomStore) hashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h.Write([]byte{0xff})
+	h2 := h.Sum64() | 1 // Must be odd so probes cover the whole table
+	return h1, h2
+}
+
+// Add records key and reports whether it was (probably) not present before
+func (b *BloomStore) Add(ctx context.Context, key string) (bool, error) {
+	h1, h2 := b.hashes(key)
+
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		mask := uint64(1) << (bit % 64)
+		if atomic.OrUint64(&b.bits[bit/64], mask)&mask == 0 {
+			added = true
+		}
+	}
+
+	if added {
+		atomic.AddInt64(&b.count, 1)
+	}
+	return added, nil
+}
+
+// Contains reports whether key has (probably) been recorded
+func (b *BloomStore) Contains(ctx context.Context, key string) (bool, error) {
+	h1, h2 := b.hashes(key)
+
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if atomic.LoadUint64(&b.bits[bit/64])&(uint64(1)<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Len returns the number of keys added
+func (b *BloomStore) Len(ctx context.Context) (int64, error) {
+	return atomic.LoadInt64(&b.count), nil
+}
+
+// Close is a no-op for the in-memory filter
+func (b *BloomStore) Close() error {
+	return nil
+}
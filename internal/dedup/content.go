@@ -0,0 +1,150 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"web-crawler/pkg/utils"
+)
+
+// shingleSize is the number of consecutive words hashed together
+const shingleSize = 3
+
+// ContentHasher detects near-duplicate pages by comparing 64-bit SimHash
+// fingerprints of their visible text within a maximum Hamming distance
+type ContentHasher struct {
+	maxDistance int
+	bands       int
+	bandBits    int
+
+	mu     sync.RWMutex
+	index  []map[uint64][]uint64 // Band value -> fingerprints sharing it
+	hashes int64
+
+	// Counters
+	checked    int64
+	duplicates int64
+}
+
+// NewContentHasher creates a hasher that treats pages within maxDistance
+// differing bits as duplicates
+func NewContentHasher(maxDistance int) *ContentHasher {
+	if maxDistance < 0 {
+		maxDistance = 0
+	}
+	if maxDistance > 15 {
+		maxDistance = 15
+	}
+
+	// With d+1 bands, two fingerprints within distance d share at least one band
+	bands := maxDistance + 1
+	index := make([]map[uint64][]uint64, bands)
+	for i := range index {
+		index[i] = make(map[uint64][]uint64)
+	}
+
+	return &ContentHasher{
+		maxDistance: maxDistance,
+		bands:       bands,
+		bandBits:    (64 + bands - 1) / bands,
+		index:       index,
+	}
+}
+
+// SimHash computes the 64-bit SimHash fingerprint of the HTML content's text
+func SimHash(content string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(utils.ExtractText(content)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	addFeature := func(feature string) {
+		h.Reset()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		addFeature(strings.Join(words, " "))
+	} else {
+		for i := 0; i+shingleSize <= len(words); i++ {
+			addFeature(strings.Join(words[i:i+shingleSize], " "))
+		}
+	}
+
+	var fingerprint uint64
+	for i, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+	return fingerprint
+}
+
+// Distance returns the Hamming distance between two fingerprints
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// band extracts the i-th band of a fingerprint
+func (c *ContentHasher) band(fingerprint uint64, i int) uint64 {
+	shift := uint(i * c.bandBits)
+	if shift >= 64 {
+		return 0
+	}
+	return (fingerprint >> shift) & ((1 << uint(c.bandBits)) - 1)
+}
+
+// IsDuplicate computes the content's fingerprint and reports whether a near
+// duplicate was seen before. New fingerprints are remembered.
+func (c *ContentHasher) IsDuplicate(content string) (uint64, bool) {
+	atomic.AddInt64(&c.checked, 1)
+	fingerprint := SimHash(content)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := 0; i < c.bands; i++ {
+		for _, candidate := range c.index[i][c.band(fingerprint, i)] {
+			if Distance(candidate, fingerprint) <= c.maxDistance {
+				atomic.AddInt64(&c.duplicates, 1)
+				return fingerprint, true
+			}
+		}
+	}
+
+	for i := 0; i < c.bands; i++ {
+		key := c.band(fingerprint, i)
+		c.index[i][key] = append(c.index[i][key], fingerprint)
+	}
+	c.hashes++
+	return fingerprint, false
+}
+
+// GetStats returns content deduplication statistics for monitoring
+func (c *ContentHasher) GetStats() map[string]int64 {
+	c.mu.RLock()
+	hashes := c.hashes
+	c.mu.RUnlock()
+
+	return map[string]int64{
+		"checked":      atomic.LoadInt64(&c.checked),
+		"duplicates":   atomic.LoadInt64(&c.duplicates),
+		"fingerprints": hashes,
+	}
+}
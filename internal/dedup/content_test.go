@@ -0,0 +1,50 @@
+package dedup
+
+import (
+	"strings"
+	"testing"
+)
+
+const article = `<html><body><h1>Crawling the web</h1><p>A web crawler starts from a set of
+seed URLs, fetches each page, extracts its links and queues the ones it has not
+seen before. Politeness rules limit how often a host is requested, and robots.txt
+tells the crawler which paths it may fetch at all.</p></body></html>`
+
+func TestSimHash(t *testing.T) {
+	if SimHash(article) != SimHash(strings.ReplaceAll(article, "<p>", "<p class=\"x\">")) {
+		t.Fatal("markup changes alter the fingerprint")
+	}
+	if SimHash("<p></p>") != 0 {
+		t.Fatal("page without text has a fingerprint")
+	}
+	near := strings.Replace(article, "often", "frequently", 1)
+	if d := Distance(SimHash(article), SimHash(near)); d > 10 {
+		t.Fatalf("one changed word moved the fingerprint by %d bits", d)
+	}
+}
+
+func TestContentHasher(t *testing.T) {
+	c := NewContentHasher(10)
+
+	if _, dup := c.IsDuplicate(article); dup {
+		t.Fatal("first page is a duplicate")
+	}
+	if _, dup := c.IsDuplicate(strings.Replace(article, "often", "frequently", 1)); !dup {
+		t.Fatal("near duplicate not detected")
+	}
+	other := `<p>Bread needs flour, water, salt and yeast. Knead the dough, let it rise
+overnight in a cool place and bake it in a hot oven until the crust is dark.</p>`
+	if _, dup := c.IsDuplicate(other); dup {
+		t.Fatal("unrelated page is a duplicate")
+	}
+
+	if stats := c.GetStats(); stats["checked"] != 3 || stats["duplicates"] != 1 || stats["fingerprints"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if d := Distance(0b1011, 0b0001); d != 2 {
+		t.Fatalf("Distance() = %d, want 2", d)
+	}
+}
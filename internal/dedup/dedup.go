@@ -0,0 +1,154 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the dedup package
+var log = logger.For("dedup")
+
+// Store defines the interface for a set of already-seen URLs
+type Store interface {
+	// Add records key and reports whether it was not present before
+	Add(ctx context.Context, key string) (bool, error)
+	// Contains reports whether key has been recorded
+	Contains(ctx context.Context, key string) (bool, error)
+	// Len returns the (approximate) number of recorded keys
+	Len(ctx context.Context) (int64, error)
+	Close() error
+}
+
+// Lister is implemented by stores that can enumerate their keys. Bloom filters
+// can't, and Redis sets are already durable, so only the memory store does.
+type Lister interface {
+	Keys(ctx context.Context) ([]string, error)
+}
+
+// ErrNotListable is returned by Visited when the store can't enumerate its keys
+var ErrNotListable = errors.New("dedup store can't list its keys")
+
+// NewStore creates the Store selected by the configured backend
+func NewStore(cfg config.DedupConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "bloom":
+		return NewBloomStore(cfg.ExpectedItems, cfg.FalsePositiveRate), nil
+	case "redis":
+		return NewRedisStore(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("unknown dedup backend: %s", cfg.Backend)
+	}
+}
+
+// URLFilter normalizes URLs and consults a Store to skip ones that were already queued
+type URLFilter struct {
+	store Store
+
+	// Counters
+	checked    int64
+	duplicates int64
+	errors     int64
+}
+
+// NewURLFilter creates a URL filter backed by store
+func NewURLFilter(store Store) *URLFilter {
+	return &URLFilter{store: store}
+}
+
+// IsNew reports whether rawURL has not been seen before and marks it as seen.
+// Store errors are logged and treated as new so the crawl keeps going.
+func (f *URLFilter) IsNew(ctx context.Context, rawURL string) bool {
+	atomic.AddInt64(&f.checked, 1)
+
+	added, err := f.store.Add(ctx, Normalize(rawURL))
+	if err != nil {
+		atomic.AddInt64(&f.errors, 1)
+		log.Error("Dedup store error for %s: %v", rawURL, err)
+		return true
+	}
+	if !added {
+		atomic.AddInt64(&f.duplicates, 1)
+	}
+	return added
+}
+
+// MarkSeen records additional URLs for an already-fetched page, such as its
+// canonical and post-redirect URLs, so links to them are not crawled again
+func (f *URLFilter) MarkSeen(ctx context.Context, urls ...string) {
+	for _, u := range urls {
+		if u == "" {
+			continue
+		}
+		if _, err := f.store.Add(ctx, Normalize(u)); err != nil {
+			atomic.AddInt64(&f.errors, 1)
+		}
+	}
+}
+
+// Seen reports whether rawURL has been seen without marking it
+func (f *URLFilter) Seen(ctx context.Context, rawURL string) bool {
+	seen, err := f.store.Contains(ctx, Normalize(rawURL))
+	if err != nil {
+		atomic.AddInt64(&f.errors, 1)
+		return false
+	}
+	return seen
+}
+
+// Visited returns the normalized URLs recorded so far
+func (f *URLFilter) Visited(ctx context.Context) ([]string, error) {
+	lister, ok := f.store.(Lister)
+	if !ok {
+		return nil, ErrNotListable
+	}
+	return lister.Keys(ctx)
+}
+
+// GetStats returns deduplication statistics for monitoring
+func (f *URLFilter) GetStats() map[string]int64 {
+	size, _ := f.store.Len(context.Background())
+	return map[string]int64{
+		"checked":    atomic.LoadInt64(&f.checked),
+		"duplicates": atomic.LoadInt64(&f.duplicates),
+		"errors":     atomic.LoadInt64(&f.errors),
+		"seen":       size,
+	}
+}
+
+// Close closes the underlying store
+func (f *URLFilter) Close() error {
+	return f.store.Close()
+}
+
+// Normalize returns a canonical form of rawURL used as the dedup key: the
+// fragment is dropped, scheme and host are lowercased, and default ports removed
+func Normalize(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	if (u.Scheme == "http" && strings.HasSuffix(u.Host, ":80")) ||
+		(u.Scheme == "https" && strings.HasSuffix(u.Host, ":443")) {
+		u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
+}
@@ -0,0 +1,119 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"HTTP://Example.COM:80/a#top":    "http://example.com/a",
+		"https://example.com:443":        "https://example.com/",
+		"https://example.com:8443/a?b=1": "https://example.com:8443/a?b=1",
+		"http://example.com:443/":        "http://example.com:443/",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestURLFilter(t *testing.T) {
+	ctx := context.Background()
+	f := NewURLFilter(NewMemoryStore())
+
+	if !f.IsNew(ctx, "https://example.com/a") {
+		t.Fatal("first URL isn't new")
+	}
+	if f.IsNew(ctx, "https://EXAMPLE.com:443/a#section") {
+		t.Fatal("URL differing only in normalization is new")
+	}
+	if f.Seen(ctx, "https://example.com/b") {
+		t.Fatal("unseen URL reported as seen")
+	}
+	f.MarkSeen(ctx, "https://example.com/b", "")
+	if !f.Seen(ctx, "https://example.com/b") || f.IsNew(ctx, "https://example.com/b") {
+		t.Fatal("marked URL isn't seen")
+	}
+
+	visited, err := f.Visited(ctx)
+	if err != nil || len(visited) != 2 {
+		t.Fatalf("Visited() = %v, %v", visited, err)
+	}
+	if stats := f.GetStats(); stats["checked"] != 3 || stats["duplicates"] != 2 || stats["seen"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+// failingStore fails every operation
+type failingStore struct{ *MemoryStore }
+
+func (*failingStore) Add(ctx context.Context, key string) (bool, error) {
+	return false, errors.New("down")
+}
+
+func TestURLFilterStoreErrorsCountAsNew(t *testing.T) {
+	f := NewURLFilter(&failingStore{NewMemoryStore()})
+	if !f.IsNew(context.Background(), "https://example.com/") {
+		t.Fatal("URL isn't new when the store fails")
+	}
+	if f.GetStats()["errors"] != 1 {
+		t.Fatal("store error not counted")
+	}
+}
+
+func TestBloomStore(t *testing.T) {
+	ctx := context.Background()
+	b := NewBloomStore(10000, 0.01)
+
+	for i := 0; i < 10000; i++ {
+		b.Add(ctx, fmt.Sprintf("https://example.com/%d", i))
+	}
+	// Never a false negative
+	for i := 0; i < 10000; i++ {
+		if ok, _ := b.Contains(ctx, fmt.Sprintf("https://example.com/%d", i)); !ok {
+			t.Fatalf("added key %d not contained", i)
+		}
+	}
+	// False positives stay near the configured rate
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if ok, _ := b.Contains(ctx, fmt.Sprintf("https://other.com/%d", i)); ok {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Fatalf("%d false positives in 10000, want about 100", falsePositives)
+	}
+
+	f := NewURLFilter(b)
+	if _, err := f.Visited(ctx); !errors.Is(err, ErrNotListable) {
+		t.Fatalf("Visited() of a Bloom filter = %v, want ErrNotListable", err)
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	if s, err := NewStore(config.DedupConfig{}); err != nil || s == nil {
+		t.Fatalf("NewStore() default = %v, %v", s, err)
+	}
+	if _, ok := mustStore(t, config.DedupConfig{Backend: "bloom"}).(*BloomStore); !ok {
+		t.Fatal("bloom backend isn't a BloomStore")
+	}
+	if _, err := NewStore(config.DedupConfig{Backend: "disk"}); err == nil {
+		t.Fatal("unknown backend accepted")
+	}
+}
+
+func mustStore(t *testing.T, cfg config.DedupConfig) Store {
+	t.Helper()
+	s, err := NewStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package dedup
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an exact in-memory seen-set
+type MemoryStore struct {
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seen: make(map[string]struct{}),
+	}
+}
+
+// Add records key and reports whether it was not present before
+func (m *MemoryStore) Add(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.seen[key]; ok {
+		return false, nil
+	}
+	m.seen[key] = struct{}{}
+	return true, nil
+}
+
+// Contains reports whether key has been recorded
+func (m *MemoryStore) Contains(ctx context.Context, key string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.seen[key]
+	return ok, nil
+}
+
+// Len returns the number of recorded keys
+func (m *MemoryStore) Len(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.seen)), nil
+}
+
+// Close releases the stored keys
+func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	m.seen = make(map[string]struct{})
+	m.mu.Unlock()
+	return nil
+}
+
+// Keys returns all recorded keys, e.g. for checkpointing
+func (m *MemoryStore) Keys(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.seen))
+	for key := range m.seen {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
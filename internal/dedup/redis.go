@@ -0,0 +1,60 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/redis"
+)
+
+// RedisStore is a seen-set kept in a Redis set, shared between crawler instances
+type RedisStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisStore connects to Redis and uses cfg.Key as the set name
+func NewRedisStore(cfg config.RedisConfig) (*RedisStore, error) {
+	client, err := redis.NewClient(redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+		Timeout:  cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := cfg.KeyPrefix + "seen"
+	return &RedisStore{client: client, key: key}, nil
+}
+
+// Add records key with SADD and reports whether it was not present before
+func (r *RedisStore) Add(ctx context.Context, key string) (bool, error) {
+	n, err := r.client.Int(ctx, "SADD", r.key, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to add seen url: %w", err)
+	}
+	return n == 1, nil
+}
+
+// Contains reports whether key is a member of the set
+func (r *RedisStore) Contains(ctx context.Context, key string) (bool, error) {
+	n, err := r.client.Int(ctx, "SISMEMBER", r.key, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to check seen url: %w", err)
+	}
+	return n == 1, nil
+}
+
+// Len returns the size of the set
+func (r *RedisStore) Len(ctx context.Context) (int64, error) {
+	return r.client.Int(ctx, "SCARD", r.key)
+}
+
+// Close closes the Redis connections
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
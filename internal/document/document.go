@@ -0,0 +1,178 @@
+// Package document extracts the text of PDF, Word and other documents so
+// that they can be stored like pages
+package document
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+var log = logger.For("document")
+
+// Media types with a built-in extractor
+const (
+	TypePDF  = "application/pdf"
+	TypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// Document is the text extracted from a document
+type Document struct {
+	Title string
+	Text  string
+	Links []string // URLs the document links to
+}
+
+// Extractor extracts the text of one kind of document
+type Extractor interface {
+	Extract(ctx context.Context, data []byte) (*Document, error)
+}
+
+// builtins are the extractors used for a type without a command
+var builtins = map[string]Extractor{
+	TypePDF:  PDF{},
+	TypeDOCX: DOCX{},
+}
+
+// extensions are the file extensions of document types, let through the
+// URL filter for the types that are extracted
+var extensions = map[string][]string{
+	TypePDF:              {".pdf"},
+	TypeDOCX:             {".docx"},
+	"application/msword": {".doc"},
+	"application/rtf":    {".rtf"},
+	"application/vnd.oasis.opendocument.text":                                   {".odt"},
+	"application/vnd.ms-excel":                                                  {".xls"},
+	"application/vnd.ms-powerpoint":                                             {".ppt"},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         {".xlsx"},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": {".pptx"},
+}
+
+// Extractors picks the extractor of a document by its media type
+type Extractors struct {
+	timeout time.Duration
+	byType  map[string]Extractor
+
+	// Counters
+	extracted int64
+	failed    int64
+}
+
+// New creates the extractors of the configured types: an extra one if given
+// for the type, else its command, else the built-in one. Extra types are
+// extracted too. It returns nil if documents are disabled.
+func New(cfg config.DocumentsConfig, extra map[string]Extractor) (*Extractors, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	e := &Extractors{timeout: cfg.Timeout, byType: make(map[string]Extractor)}
+	for _, mediaType := range cfg.Types {
+		mediaType = strings.ToLower(mediaType)
+		if args, ok := cfg.Commands[mediaType]; ok {
+			e.byType[mediaType] = Command{Args: args}
+			continue
+		}
+		builtin, ok := builtins[mediaType]
+		if !ok && extra[mediaType] == nil {
+			return nil, fmt.Errorf("no extractor for document type %s, set documents.commands for it", mediaType)
+		}
+		e.byType[mediaType] = builtin
+	}
+	for mediaType, x := range extra {
+		e.byType[strings.ToLower(mediaType)] = x
+	}
+	return e, nil
+}
+
+// Types returns the media types that are extracted
+func (e *Extractors) Types() []string {
+	types := make([]string, 0, len(e.byType))
+	for mediaType := range e.byType {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Extensions returns the file extensions of the types that are extracted
+func (e *Extractors) Extensions() []string {
+	var exts []string
+	for _, mediaType := range e.Types() {
+		exts = append(exts, extensions[mediaType]...)
+	}
+	return exts
+}
+
+// Supports reports whether a Content-Type header denotes a document that is
+// extracted
+func (e *Extractors) Supports(contentType string) bool {
+	return e.byType[mediaType(contentType)] != nil
+}
+
+// Extract extracts the text of a document within the timeout
+func (e *Extractors) Extract(ctx context.Context, contentType string, data []byte) (*Document, error) {
+	x := e.byType[mediaType(contentType)]
+	if x == nil {
+		return nil, fmt.Errorf("no extractor for %s", mediaType(contentType))
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	doc, err := x.Extract(ctx, data)
+	if err != nil {
+		atomic.AddInt64(&e.failed, 1)
+		return nil, fmt.Errorf("failed to extract text: %w", err)
+	}
+	doc.Title = strings.TrimSpace(doc.Title)
+	doc.Text = strings.TrimSpace(doc.Text)
+	atomic.AddInt64(&e.extracted, 1)
+	return doc, nil
+}
+
+// GetStats returns the number of documents extracted and failed
+func (e *Extractors) GetStats() map[string]int64 {
+	return map[string]int64{
+		"extracted": atomic.LoadInt64(&e.extracted),
+		"failed":    atomic.LoadInt64(&e.failed),
+	}
+}
+
+// mediaType returns the lowercased media type of a Content-Type header
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// Command extracts text with an external program, such as pdftotext or
+// antiword, that reads the document on stdin and writes its text to stdout
+type Command struct {
+	Args []string
+}
+
+// Extract runs the command on data
+func (c Command) Extract(ctx context.Context, data []byte) (*Document, error) {
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			log.Debug("%s: %s", c.Args[0], msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", c.Args[0], err)
+	}
+	return &Document{Text: stdout.String()}, nil
+}
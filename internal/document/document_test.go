@@ -0,0 +1,119 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func documentsConfig() config.DocumentsConfig {
+	return config.DocumentsConfig{
+		Enabled:  true,
+		Types:    []string{TypePDF, TypeDOCX},
+		Commands: map[string][]string{},
+		Timeout:  5 * time.Second,
+	}
+}
+
+func TestNew(t *testing.T) {
+	if e, err := New(config.DocumentsConfig{}, nil); e != nil || err != nil {
+		t.Fatalf("New(disabled) = %v, %v", e, err)
+	}
+
+	cfg := documentsConfig()
+	cfg.Types = append(cfg.Types, "application/msword")
+	if _, err := New(cfg, nil); err == nil {
+		t.Fatal("New() accepted a type without an extractor")
+	}
+
+	cfg.Commands["application/msword"] = []string{"antiword", "-"}
+	e, err := New(cfg, map[string]Extractor{"application/rtf": PDF{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"application/msword", TypePDF, "application/rtf", TypeDOCX}; !reflect.DeepEqual(e.Types(), want) {
+		t.Errorf("Types() = %v, want %v", e.Types(), want)
+	}
+	if want := []string{".doc", ".pdf", ".rtf", ".docx"}; !reflect.DeepEqual(e.Extensions(), want) {
+		t.Errorf("Extensions() = %v, want %v", e.Extensions(), want)
+	}
+	if !e.Supports("Application/PDF; charset=binary") || e.Supports("text/html") {
+		t.Error("Supports() doesn't go by the media type")
+	}
+	if _, ok := e.byType["application/msword"].(Command); !ok {
+		t.Error("command not used for its type")
+	}
+}
+
+func TestExtractCommand(t *testing.T) {
+	if _, err := exec.LookPath("tr"); err != nil {
+		t.Skip("tr not installed")
+	}
+	cfg := documentsConfig()
+	cfg.Types = []string{"text/x-shout"}
+	cfg.Commands = map[string][]string{"text/x-shout": {"tr", "a-z", "A-Z"}}
+	e, err := New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := e.Extract(context.Background(), "text/x-shout", []byte("  quiet words\n"))
+	if err != nil || doc.Text != "QUIET WORDS" {
+		t.Fatalf("Extract() = %+v, %v", doc, err)
+	}
+
+	if _, err := e.Extract(context.Background(), "text/html", nil); err == nil {
+		t.Fatal("Extract() of an unsupported type succeeded")
+	}
+	e.byType["text/x-shout"] = Command{Args: []string{"false"}}
+	if _, err := e.Extract(context.Background(), "text/x-shout", nil); err == nil {
+		t.Fatal("Extract() with a failing command succeeded")
+	}
+	if stats := e.GetStats(); stats["extracted"] != 1 || stats["failed"] != 1 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Quarterly</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve"> results &amp; outlook</w:t></w:r></w:p>
+<w:p><w:r><w:t>Line one</w:t><w:br/><w:t>Line two</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Normal"/></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t>Bold</w:t></w:r></w:p>
+</w:body></w:document>`,
+		"docProps/core.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Q3 Report</dc:title></cp:coreProperties>`,
+		"word/_rels/document.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/q3" TargetMode="External"/>
+</Relationships>`,
+	}
+	for name, content := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	doc, err := DOCX{}.Extract(context.Background(), buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Quarterly\t results & outlook\nLine one\nLine two\nBold"
+	if strings.TrimSpace(doc.Text) != want || doc.Title != "Q3 Report" || !reflect.DeepEqual(doc.Links, []string{"https://example.com/q3"}) {
+		t.Fatalf("Extract() = %+v", doc)
+	}
+
+	if _, err := (DOCX{}).Extract(context.Background(), []byte("not a zip")); err == nil {
+		t.Fatal("Extract() of a non-DOCX file succeeded")
+	}
+}
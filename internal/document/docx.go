@@ -0,0 +1,146 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOCX extracts the paragraphs of Word documents (Office Open XML), with the
+// title from the document properties and the targets of hyperlinks
+type DOCX struct{}
+
+// Extract reads the document part of a DOCX file
+func (DOCX) Extract(ctx context.Context, data []byte) (*Document, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a DOCX file: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		parts[f.Name] = f
+	}
+	body, ok := parts["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("not a DOCX file: no word/document.xml")
+	}
+
+	doc := &Document{}
+	if err := readPart(body, func(d *xml.Decoder) (err error) {
+		doc.Text, err = docxText(ctx, d)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if core, ok := parts["docProps/core.xml"]; ok {
+		readPart(core, func(d *xml.Decoder) error {
+			doc.Title = elementText(d, "title")
+			return nil
+		})
+	}
+	if rels, ok := parts["word/_rels/document.xml.rels"]; ok {
+		readPart(rels, func(d *xml.Decoder) error {
+			doc.Links = hyperlinks(d)
+			return nil
+		})
+	}
+	return doc, nil
+}
+
+// readPart decodes one part of the package with fn
+func readPart(f *zip.File, fn func(*xml.Decoder) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return fn(xml.NewDecoder(rc))
+}
+
+// docxText returns the text of the runs, one line per paragraph
+func docxText(ctx context.Context, d *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	inText := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		token, err := d.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// elementText returns the text of the first element named local
+func elementText(d *xml.Decoder, local string) string {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == local {
+			var text string
+			d.DecodeElement(&text, &start)
+			return text
+		}
+	}
+}
+
+// hyperlinks returns the external targets of the hyperlink relationships
+func hyperlinks(d *xml.Decoder) []string {
+	var links []string
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return links
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Relationship" {
+			continue
+		}
+		var target, mode, relType string
+		for _, a := range start.Attr {
+			switch a.Name.Local {
+			case "Target":
+				target = a.Value
+			case "TargetMode":
+				mode = a.Value
+			case "Type":
+				relType = a.Value
+			}
+		}
+		if mode == "External" && strings.HasSuffix(relType, "/hyperlink") {
+			links = append(links, target)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF extracts the text shown by the text operators of a PDF file's page
+// content, uncompressed or Flate encoded, with the title from the document
+// information and the targets of URI actions. Text set in fonts with custom
+// encodings comes out garbled; a command such as pdftotext handles those.
+type PDF struct{}
+
+// skippedStreams mark stream dictionaries that hold no page text
+var skippedStreams = [][]byte{
+	[]byte("/Image"), []byte("/XRef"), []byte("/Metadata"), []byte("/EmbeddedFile"),
+	[]byte("/Length1"), []byte("/Length2"), []byte("/Length3"), []byte("/FontFile"),
+	[]byte("/Type1C"), []byte("/CIDFontType0C"), []byte("/OpenType"),
+}
+
+// Extract reads the content streams of a PDF file
+func (PDF) Extract(ctx context.Context, data []byte) (*Document, error) {
+	head := data[:min(len(data), 1024)]
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, errors.New("encrypted PDF")
+	}
+
+	var text strings.Builder
+	objects := [][]byte{data} // Searched for the title and links
+	for _, s := range pdfStreams(data) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content := s.decode()
+		switch {
+		case content == nil:
+		case bytes.Contains(s.dict, []byte("/ObjStm")):
+			objects = append(objects, content)
+		default:
+			pdfText(content, &text)
+		}
+	}
+
+	doc := &Document{Text: cleanLines(text.String())}
+	seen := make(map[string]bool)
+	for _, obj := range objects {
+		if doc.Title == "" {
+			if titles := pdfValues(obj, "/Title"); len(titles) > 0 {
+				doc.Title = titles[0]
+			}
+		}
+		for _, link := range pdfValues(obj, "/URI") {
+			if !seen[link] {
+				seen[link] = true
+				doc.Links = append(doc.Links, link)
+			}
+		}
+	}
+	return doc, nil
+}
+
+// pdfStream is a stream object of a PDF file
+type pdfStream struct {
+	dict []byte // From the object header to the stream keyword
+	data []byte
+}
+
+// pdfStreams finds the streams of a PDF file without parsing its objects:
+// each stream keyword ends the dictionary that started after "obj"
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	pos := 0
+	for {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			return streams
+		}
+		start := pos + i
+		pos = start + len("stream")
+		// endstream also contains the keyword
+		if start > 0 && isLetter(data[start-1]) {
+			continue
+		}
+		body := pos
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+
+		header := data[:start]
+		if obj := bytes.LastIndex(header, []byte("obj")); obj >= 0 {
+			header = header[obj:]
+		}
+		streams = append(streams, pdfStream{dict: header, data: data[body : body+end]})
+		pos = body + end + len("endstream")
+	}
+}
+
+// decode returns the data of a stream that may hold text, nil for images,
+// fonts, cross references and filters other than Flate
+func (s pdfStream) decode() []byte {
+	for _, mark := range skippedStreams {
+		if bytes.Contains(s.dict, mark) {
+			return nil
+		}
+	}
+	if !bytes.Contains(s.dict, []byte("/Filter")) {
+		return s.data
+	}
+	if bytes.Count(s.dict, []byte("Decode")) != 1 || !bytes.Contains(s.dict, []byte("/FlateDecode")) {
+		return nil
+	}
+	r, err := zlib.NewReader(bytes.NewReader(s.data))
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	// Streams cut short still give their text so far
+	content, _ := io.ReadAll(r)
+	return content
+}
+
+// pdfItem is an operand of a content stream operator: a string, a number
+// or an array of them
+type pdfItem struct {
+	str   []byte
+	isStr bool
+	num   float64
+	array []pdfItem
+}
+
+// pdfText appends the text shown by the operators of a content stream. Line
+// moves and text objects start new lines; wide gaps in TJ arrays are spaces.
+func pdfText(content []byte, sb *strings.Builder) {
+	newline := func() {
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteByte('\n')
+		}
+	}
+	show := func(item pdfItem) {
+		if item.isStr {
+			sb.WriteString(pdfString(item.str))
+		}
+	}
+
+	var operands []pdfItem
+	var stack [][]pdfItem // Arrays being read
+	push := func(item pdfItem) {
+		if len(stack) > 0 {
+			stack[len(stack)-1] = append(stack[len(stack)-1], item)
+		} else {
+			operands = append(operands, item)
+		}
+	}
+	last := func() pdfItem {
+		if len(operands) == 0 {
+			return pdfItem{}
+		}
+		return operands[len(operands)-1]
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			str, next := readLiteral(content, i)
+			push(pdfItem{str: str, isStr: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			str, next := readHex(content, i)
+			push(pdfItem{str: str, isStr: true})
+			i = next
+		case c == '[':
+			stack = append(stack, nil)
+			i++
+		case c == ']':
+			if len(stack) > 0 {
+				array := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				push(pdfItem{array: array})
+			}
+			i++
+		case c == '/' || c == '{' || c == '}' || c == '>' || c == ')':
+			i = skipToken(content, i+1)
+		default:
+			end := skipToken(content, i+1)
+			word := string(content[i:end])
+			i = end
+			if num, err := strconv.ParseFloat(word, 64); err == nil {
+				push(pdfItem{num: num})
+				continue
+			}
+			switch word {
+			case "Tj":
+				show(last())
+			case "'", `"`:
+				newline()
+				show(last())
+			case "TJ":
+				for _, item := range last().array {
+					if item.isStr {
+						show(item)
+					} else if item.num < -250 {
+						sb.WriteByte(' ')
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1].num != 0 {
+					newline()
+				} else {
+					sb.WriteByte(' ')
+				}
+			case "T*", "Tm", "ET":
+				newline()
+			case "BI":
+				// Inline image data runs up to EI
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	newline()
+}
+
+// readLiteral reads a (string) starting at i, with nested parentheses and
+// escapes. It returns the string and the position after it.
+func readLiteral(data []byte, i int) ([]byte, int) {
+	var str []byte
+	depth := 0
+	for i++; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return str, i + 1
+			}
+			depth--
+		case '\\':
+			i++
+			if i == len(data) {
+				return str, i
+			}
+			switch e := data[i]; e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// Line continuation
+				if e == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+					i++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; j++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					i--
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		str = append(str, c)
+	}
+	return str, i
+}
+
+// readHex reads a <hex string> starting at i
+func readHex(data []byte, i int) ([]byte, int) {
+	var digits []byte
+	for i++; i < len(data) && data[i] != '>'; i++ {
+		if c := data[i]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	str := make([]byte, len(digits)/2)
+	for j := range str {
+		n, _ := strconv.ParseUint(string(digits[2*j:2*j+2]), 16, 8)
+		str[j] = byte(n)
+	}
+	return str, i + 1
+}
+
+// pdfString decodes a text string: UTF-16BE with a byte order mark or two
+// bytes per ASCII character, else PDFDocEncoding, taken as Latin-1
+func pdfString(str []byte) string {
+	wide := bytes.HasPrefix(str, []byte{0xfe, 0xff})
+	if wide {
+		str = str[2:]
+	} else if len(str) >= 2 && len(str)%2 == 0 {
+		wide = true
+		for j := 0; j < len(str); j += 2 {
+			if str[j] != 0 {
+				wide = false
+				break
+			}
+		}
+	}
+
+	var sb strings.Builder
+	if wide {
+		units := make([]uint16, len(str)/2)
+		for j := range units {
+			units[j] = uint16(str[2*j])<<8 | uint16(str[2*j+1])
+		}
+		for _, r := range utf16.Decode(units) {
+			if r >= ' ' || r == '\n' || r == '\t' {
+				sb.WriteRune(r)
+			}
+		}
+		return sb.String()
+	}
+	for _, b := range str {
+		if b >= ' ' || b == '\n' || b == '\t' {
+			sb.WriteRune(rune(b))
+		}
+	}
+	return sb.String()
+}
+
+// pdfValues returns the strings following every occurrence of key
+func pdfValues(data []byte, key string) []string {
+	var values []string
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte(key))
+		if i < 0 {
+			return values
+		}
+		j := pos + i + len(key)
+		pos = j
+		// A longer name, such as /URIs, isn't the key
+		if j < len(data) && isLetter(data[j]) {
+			continue
+		}
+		for j < len(data) && isSpace(data[j]) {
+			j++
+		}
+		var str []byte
+		switch {
+		case j < len(data) && data[j] == '(':
+			str, pos = readLiteral(data, j)
+		case j+1 < len(data) && data[j] == '<' && data[j+1] != '<':
+			str, pos = readHex(data, j)
+		default:
+			continue
+		}
+		if value := strings.TrimSpace(pdfString(str)); value != "" {
+			values = append(values, value)
+		}
+	}
+}
+
+// skipToken returns the end of the regular characters starting at i
+func skipToken(data []byte, i int) int {
+	for i < len(data) && !isSpace(data[i]) && !strings.ContainsRune("()<>[]{}/%", rune(data[i])) {
+		i++
+	}
+	return i
+}
+
+// cleanLines collapses the whitespace of every line and drops empty lines
+func cleanLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isSpace reports whether c is PDF whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// isLetter reports whether c is an ASCII letter
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
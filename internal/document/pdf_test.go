@@ -0,0 +1,106 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// buildPDF writes objects as a PDF file. Streams are given as [dict, data].
+func buildPDF(objects ...interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		switch o := obj.(type) {
+		case string:
+			buf.WriteString(o)
+		case [2]string:
+			fmt.Fprintf(&buf, "%s\nstream\r\n%s\nendstream", o[0], o[1])
+		}
+		buf.WriteString("\nendobj\n")
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+// flate compresses a content stream
+func flate(content string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(content))
+	w.Close()
+	return buf.String()
+}
+
+func TestPDF(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		[2]string{"<< /Length 99 /Filter /FlateDecode >>",
+			flate("BT /F1 12 Tf 72 700 Td [(Hel) -20 (lo) -300 (World)] TJ 0 -14 Td <FEFF00E9007400E9> Tj T* (caf\\351) Tj ET")},
+		[2]string{"<< /Length 44 >>", "BT 72 680 Td (Plain \\(text\\)) Tj\n% comment (hidden) Tj\nET"},
+		[2]string{"<< /Subtype /Image /Width 1 /Length 9 >>", "(X) Tj"},
+		[2]string{"<< /Filter /DCTDecode /Length 9 >>", "(Y) Tj"},
+		"<< /Title (Annual \\(2024\\) Report) /Producer (test) >>",
+		"<< /A << /S /URI /URI (https://example.com/more) >> >>",
+	)
+
+	doc, err := PDF{}.Extract(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Hello World\nété\ncafé\nPlain (text)"; doc.Text != want {
+		t.Errorf("Text = %q, want %q", doc.Text, want)
+	}
+	if doc.Title != "Annual (2024) Report" {
+		t.Errorf("Title = %q", doc.Title)
+	}
+	if !reflect.DeepEqual(doc.Links, []string{"https://example.com/more"}) {
+		t.Errorf("Links = %v", doc.Links)
+	}
+}
+
+func TestPDFObjectStreams(t *testing.T) {
+	data := buildPDF(
+		[2]string{"<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode >>",
+			flate("5 0 << /Title <FEFF0054006900740072006500> /URI (https://example.com/x) >>")},
+		[2]string{"<< /Length 20 >>", "BT (Body) Tj ET"},
+	)
+	doc, err := PDF{}.Extract(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "Body" || doc.Title != "Titre" || !reflect.DeepEqual(doc.Links, []string{"https://example.com/x"}) {
+		t.Fatalf("Extract() = %+v", doc)
+	}
+}
+
+func TestPDFErrors(t *testing.T) {
+	if _, err := (PDF{}).Extract(context.Background(), []byte("<html></html>")); err == nil {
+		t.Error("Extract() of HTML succeeded")
+	}
+	encrypted := buildPDF("<< /Type /Catalog >>")
+	encrypted = append(encrypted, "trailer << /Encrypt 9 0 R >>"...)
+	if _, err := (PDF{}).Extract(context.Background(), encrypted); err == nil {
+		t.Error("Extract() of an encrypted PDF succeeded")
+	}
+}
+
+func TestReadLiteral(t *testing.T) {
+	tests := map[string]string{
+		`(simple)`:            "simple",
+		`(nested (paren) ok)`: "nested (paren) ok",
+		`(esc\n\t\\\)x)`:      "esc\n\t\\)x",
+		`(octal \101\102C)`:   "octal ABC",
+		"(line\\\ncont)":      "linecont",
+	}
+	for in, want := range tests {
+		got, next := readLiteral([]byte(in+" rest"), 0)
+		if string(got) != want || next != len(in) {
+			t.Errorf("readLiteral(%q) = %q, %d, want %q, %d", in, got, next, want, len(in))
+		}
+	}
+}
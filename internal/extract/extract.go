@@ -0,0 +1,121 @@
+package extract
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"web-crawler/pkg/utils"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article is the main content of a page with its detected metadata
+type Article struct {
+	Title     string
+	Author    string
+	Published time.Time // Zero when no date was found
+	Text      string    // Main text, one line per block element
+}
+
+// Extract finds the article in HTML content, dropping navigation, ads and
+// other boilerplate. It returns nil if the page has no body text.
+func Extract(content string) *Article {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	meta := readMetadata(doc)
+	title := meta.title
+	if title == "" {
+		// Before boilerplate removal drops headers holding the <h1>
+		title = headline(doc, meta.documentTitle)
+	}
+	main := mainContent(doc)
+	if main == nil {
+		return nil
+	}
+	text := nodeText(main)
+	if text == "" {
+		return nil
+	}
+
+	return &Article{
+		Title:     title,
+		Author:    meta.author,
+		Published: meta.published,
+		Text:      text,
+	}
+}
+
+// nodeText returns the visible text of n and its descendants
+func nodeText(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return ""
+	}
+	return utils.CleanText(buf.String())
+}
+
+// innerText returns the text of n with whitespace collapsed
+func innerText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// attr returns the value of the named attribute of n
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// find returns the first element below n for which match is true
+func find(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := find(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every element below n for which match is true
+func findAll(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var nodes []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && match(c) {
+				nodes = append(nodes, c)
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return nodes
+}
+
+// isElement matches elements of type a
+func isElement(a atom.Atom) func(*html.Node) bool {
+	return func(n *html.Node) bool { return n.DataAtom == a }
+}
@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+
+	"web-crawler/internal/config"
+)
+
+// JSONRules picks the title and the links to crawl of JSON responses with
+// JSONPath expressions
+type JSONRules struct {
+	types map[string]bool
+	rules []jsonRule
+}
+
+type jsonRule struct {
+	url   *regexp.Regexp // Nil for every response
+	links []*JSONPath
+	title *JSONPath
+}
+
+// NewJSONRules compiles the JSON rules. It returns nil if JSON responses
+// aren't crawled.
+func NewJSONRules(cfg config.JSONConfig) (*JSONRules, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &JSONRules{types: make(map[string]bool)}
+	for _, t := range cfg.Types {
+		r.types[strings.ToLower(t)] = true
+	}
+	for i, rc := range cfg.Rules {
+		var compiled jsonRule
+		var err error
+		if rc.URL != "" {
+			if compiled.url, err = regexp.Compile(rc.URL); err != nil {
+				return nil, fmt.Errorf("invalid url pattern of json rule %d: %w", i+1, err)
+			}
+		}
+		for _, expr := range rc.Links {
+			path, err := CompileJSONPath(expr)
+			if err != nil {
+				return nil, fmt.Errorf("json rule %d: %w", i+1, err)
+			}
+			compiled.links = append(compiled.links, path)
+		}
+		if rc.Title != "" {
+			if compiled.title, err = CompileJSONPath(rc.Title); err != nil {
+				return nil, fmt.Errorf("json rule %d: %w", i+1, err)
+			}
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// Types returns the media types parsed as JSON
+func (r *JSONRules) Types() []string {
+	types := make([]string, 0, len(r.types))
+	for t := range r.types {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Supports reports whether a Content-Type header denotes JSON: one of the
+// configured types or any structured +json type
+func (r *JSONRules) Supports(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return r.types[mediaType] || strings.HasSuffix(mediaType, "+json")
+}
+
+// Apply runs the rules whose URL pattern matches pageURL on a decoded JSON
+// document. It returns the title of the first rule that finds one and the
+// links of every rule without duplicates, as written in the document.
+func (r *JSONRules) Apply(pageURL string, doc interface{}) (string, []string) {
+	var title string
+	var links []string
+	seen := make(map[string]bool)
+	for _, rl := range r.rules {
+		if rl.url != nil && !rl.url.MatchString(pageURL) {
+			continue
+		}
+		if title == "" && rl.title != nil {
+			if values := rl.title.Strings(doc); len(values) > 0 {
+				title = strings.TrimSpace(values[0])
+			}
+		}
+		for _, path := range rl.links {
+			for _, link := range path.Strings(doc) {
+				if link = strings.TrimSpace(link); link != "" && !seen[link] {
+					seen[link] = true
+					links = append(links, link)
+				}
+			}
+		}
+	}
+	return title, links
+}
@@ -0,0 +1,457 @@
+package extract
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled JSONPath expression over decoded JSON: objects as
+// map[string]interface{}, arrays as []interface{}, strings, float64, bool and
+// nil. Supported are the root $, child names (.name or ['name']), wildcards
+// (.* or [*]), recursive descent (..name, ..*, ..[0]), array indexes counting
+// back from the end when negative, slices [start:end:step], unions ([0,2] or
+// ['a','b']) and filters [?(@.field)] or [?(@.field op literal)] with the
+// operators ==, !=, <, <=, > and >=.
+type JSONPath struct {
+	steps []jstep
+}
+
+// jstep selects children of each node, or of each node and its descendants
+type jstep struct {
+	recursive bool
+	wildcard  bool
+	names     []string
+	indexes   []int
+	slice     *jslice
+	filter    *jfilter
+}
+
+// jslice is an array slice; nil bounds are open
+type jslice struct {
+	start, end *int
+	step       int
+}
+
+// jfilter keeps the children for which path selects something, or a value
+// comparing true with the literal
+type jfilter struct {
+	path    *JSONPath // Relative to the child, written with @
+	op      string
+	literal interface{}
+}
+
+// CompileJSONPath parses a JSONPath expression
+func CompileJSONPath(s string) (*JSONPath, error) {
+	p, err := parseJSONPath(strings.TrimSpace(s), '$')
+	if err != nil {
+		return nil, fmt.Errorf("invalid jsonpath %q: %w", s, err)
+	}
+	return p, nil
+}
+
+// parseJSONPath parses a path starting with root, $ or @ in filters
+func parseJSONPath(s string, root byte) (*JSONPath, error) {
+	if s == "" || s[0] != root {
+		return nil, fmt.Errorf("must start with %c", root)
+	}
+	p := &JSONPath{}
+	for i := 1; i < len(s); {
+		var st jstep
+		switch {
+		case strings.HasPrefix(s[i:], ".."):
+			st.recursive = true
+			i += 2
+		case s[i] == '.':
+			i++
+		case s[i] == '[':
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", s[i], i)
+		}
+
+		var err error
+		if i < len(s) && s[i] == '[' {
+			end := closingBracket(s, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ at %d", i)
+			}
+			if err = st.parseBracket(strings.TrimSpace(s[i+1 : end])); err != nil {
+				return nil, err
+			}
+			i = end + 1
+		} else {
+			end := i
+			for end < len(s) && s[end] != '.' && s[end] != '[' {
+				end++
+			}
+			switch name := s[i:end]; name {
+			case "":
+				return nil, fmt.Errorf("missing name at %d", i)
+			case "*":
+				st.wildcard = true
+			default:
+				st.names = []string{name}
+			}
+			i = end
+		}
+		p.steps = append(p.steps, st)
+	}
+	return p, nil
+}
+
+// closingBracket returns the index of the ] closing the [ at i, skipping
+// quoted strings and parentheses
+func closingBracket(s string, i int) int {
+	depth := 0
+	for j := i + 1; j < len(s); j++ {
+		switch c := s[j]; c {
+		case '\'', '"':
+			end := strings.IndexByte(s[j+1:], c)
+			if end < 0 {
+				return -1
+			}
+			j += end + 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ']':
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// parseBracket parses the inside of [...]
+func (st *jstep) parseBracket(inner string) error {
+	switch {
+	case inner == "*":
+		st.wildcard = true
+		return nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		f, err := parseFilter(strings.TrimSpace(inner[2 : len(inner)-1]))
+		st.filter = f
+		return err
+	case strings.Contains(inner, ":") && !strings.ContainsAny(inner, `'"`):
+		return st.parseSlice(inner)
+	}
+
+	for _, part := range splitUnion(inner) {
+		part = strings.TrimSpace(part)
+		if name, ok := unquote(part); ok {
+			st.names = append(st.names, name)
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid index %q", part)
+		}
+		st.indexes = append(st.indexes, index)
+	}
+	return nil
+}
+
+// parseSlice parses start:end:step
+func (st *jstep) parseSlice(inner string) error {
+	parts := strings.Split(inner, ":")
+	if len(parts) > 3 {
+		return fmt.Errorf("invalid slice %q", inner)
+	}
+	st.slice = &jslice{step: 1}
+	bounds := []**int{&st.slice.start, &st.slice.end}
+	for i, part := range parts {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return fmt.Errorf("invalid slice %q", inner)
+		}
+		if i == 2 {
+			if n <= 0 {
+				return fmt.Errorf("slice step must be positive, got %d", n)
+			}
+			st.slice.step = n
+		} else {
+			*bounds[i] = &n
+		}
+	}
+	return nil
+}
+
+// splitUnion splits a union at commas outside quotes
+func splitUnion(s string) []string {
+	var parts []string
+	start := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the content of a '...' or "..." string
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	return "", false
+}
+
+// jsonOperators are the filter comparisons, longest first
+var jsonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// parseFilter parses "@.path" or "@.path op literal"
+func parseFilter(expr string) (*jfilter, error) {
+	f := &jfilter{}
+	path := expr
+	for _, op := range jsonOperators {
+		if i := strings.Index(expr, op); i > 0 {
+			path, f.op = strings.TrimSpace(expr[:i]), op
+			literal, err := parseLiteral(strings.TrimSpace(expr[i+len(op):]))
+			if err != nil {
+				return nil, err
+			}
+			f.literal = literal
+			break
+		}
+	}
+	var err error
+	f.path, err = parseJSONPath(path, '@')
+	return f, err
+}
+
+// parseLiteral parses a quoted string, number, true, false or null
+func parseLiteral(s string) (interface{}, error) {
+	if str, ok := unquote(s); ok {
+		return str, nil
+	}
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %q", s)
+	}
+	return f, nil
+}
+
+// Select returns the values the expression selects in doc, in document
+// order with object members sorted by name
+func (p *JSONPath) Select(doc interface{}) []interface{} {
+	nodes := []interface{}{doc}
+	for _, st := range p.steps {
+		var next []interface{}
+		for _, node := range nodes {
+			if !st.recursive {
+				next = append(next, st.apply(node)...)
+				continue
+			}
+			for _, d := range descendants(node, nil) {
+				next = append(next, st.apply(d)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Strings returns the scalar values the expression selects as strings.
+// Selected arrays contribute their scalar elements; objects are skipped.
+func (p *JSONPath) Strings(doc interface{}) []string {
+	var values []string
+	var add func(v interface{}, nested bool)
+	add = func(v interface{}, nested bool) {
+		switch v := v.(type) {
+		case string:
+			values = append(values, v)
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			values = append(values, strconv.FormatBool(v))
+		case []interface{}:
+			if !nested {
+				for _, e := range v {
+					add(e, true)
+				}
+			}
+		}
+	}
+	for _, v := range p.Select(doc) {
+		add(v, false)
+	}
+	return values
+}
+
+// apply returns the children of node the step selects
+func (st jstep) apply(node interface{}) []interface{} {
+	var out []interface{}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		switch {
+		case st.wildcard:
+			for _, key := range sortedKeys(n) {
+				out = append(out, n[key])
+			}
+		case st.filter != nil:
+			for _, key := range sortedKeys(n) {
+				if st.filter.matches(n[key]) {
+					out = append(out, n[key])
+				}
+			}
+		default:
+			for _, name := range st.names {
+				if v, ok := n[name]; ok {
+					out = append(out, v)
+				}
+			}
+		}
+	case []interface{}:
+		switch {
+		case st.wildcard:
+			out = append(out, n...)
+		case st.filter != nil:
+			for _, e := range n {
+				if st.filter.matches(e) {
+					out = append(out, e)
+				}
+			}
+		case st.slice != nil:
+			start, end := st.slice.bounds(len(n))
+			for i := start; i < end; i += st.slice.step {
+				out = append(out, n[i])
+			}
+		default:
+			for _, i := range st.indexes {
+				if i < 0 {
+					i += len(n)
+				}
+				if i >= 0 && i < len(n) {
+					out = append(out, n[i])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// bounds returns the slice bounds within an array of length n
+func (s *jslice) bounds(n int) (int, int) {
+	clamp := func(b *int, open int) int {
+		if b == nil {
+			return open
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return max(0, min(i, n))
+	}
+	return clamp(s.start, 0), clamp(s.end, n)
+}
+
+// matches reports whether the filter keeps v
+func (f *jfilter) matches(v interface{}) bool {
+	selected := f.path.Select(v)
+	if f.op == "" {
+		return len(selected) > 0
+	}
+	for _, s := range selected {
+		if compareJSON(s, f.op, f.literal) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareJSON compares a value with a literal: numbers and strings by
+// order, other values only for equality
+func compareJSON(v interface{}, op string, literal interface{}) bool {
+	var cmp int
+	switch a := v.(type) {
+	case float64:
+		b, ok := literal.(float64)
+		if !ok {
+			return op == "!="
+		}
+		cmp = compareOrdered(a, b)
+	case string:
+		b, ok := literal.(string)
+		if !ok {
+			return op == "!="
+		}
+		cmp = strings.Compare(a, b)
+	default:
+		equal := v == literal
+		return (op == "==" && equal) || (op == "!=" && !equal)
+	}
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// compareOrdered returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// descendants appends node and everything under it, in document order
+func descendants(node interface{}, out []interface{}) []interface{} {
+	out = append(out, node)
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(n) {
+			out = descendants(n[key], out)
+		}
+	case []interface{}:
+		for _, e := range n {
+			out = descendants(e, out)
+		}
+	}
+	return out
+}
+
+// sortedKeys returns the member names of an object in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
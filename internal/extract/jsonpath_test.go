@@ -0,0 +1,159 @@
+package extract
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+const jsonFixture = `{
+	"title": "Store",
+	"next": "/api/products?page=2",
+	"products": [
+		{"id": 1, "name": "Pen", "price": 2.5, "url": "/p/1", "tags": ["office", "sale"]},
+		{"id": 2, "name": "Desk", "price": 120, "url": "/p/2", "stock": 0},
+		{"id": 3, "name": "Lamp", "price": 30, "url": "https://other.com/p/3", "sale": true}
+	],
+	"meta": {"total": 3, "links": {"self": "/api/products", "last": "/api/products?page=9"}}
+}`
+
+func decodeFixture(t *testing.T) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(jsonFixture), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestJSONPathStrings(t *testing.T) {
+	doc := decodeFixture(t)
+	tests := map[string]string{
+		"$.title":                                 "Store",
+		"$['title']":                              "Store",
+		"$.products[*].name":                      "Pen|Desk|Lamp",
+		"$.products[0].url":                       "/p/1",
+		"$.products[-1].id":                       "3",
+		"$.products[0:2].name":                    "Pen|Desk",
+		"$.products[::2].name":                    "Pen|Lamp",
+		"$.products[-2:].name":                    "Desk|Lamp",
+		"$.products[0,2]['name','id']":            "Pen|1|Lamp|3",
+		"$.products[0].tags":                      "office|sale",
+		"$.products[0].tags[1]":                   "sale",
+		"$.meta.links.*":                          "/api/products?page=9|/api/products",
+		"$..self":                                 "/api/products",
+		"$..url":                                  "/p/1|/p/2|https://other.com/p/3",
+		"$..links[*]":                             "/api/products?page=9|/api/products",
+		"$.products[?(@.sale)].name":              "Lamp",
+		"$.products[?(@.price > 10)].name":        "Desk|Lamp",
+		"$.products[?(@.price <= 2.5)].name":      "Pen",
+		"$.products[?(@.name == 'Desk')].url":     "/p/2",
+		"$.products[?(@.name != \"Desk\")].id":    "1|3",
+		"$.products[?(@.stock == 0)].name":        "Desk",
+		"$.products[?(@.sale == true)].id":        "3",
+		"$.products[?(@.tags[0] == 'office')].id": "1",
+		"$.missing":                               "",
+		"$.products[7].name":                      "",
+		"$.meta":                                  "", // Objects aren't strings
+	}
+	for expr, want := range tests {
+		p, err := CompileJSONPath(expr)
+		if err != nil {
+			t.Errorf("CompileJSONPath(%q) = %v", expr, err)
+			continue
+		}
+		if got := strings.Join(p.Strings(doc), "|"); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestJSONPathSelect(t *testing.T) {
+	doc := decodeFixture(t)
+	p, err := CompileJSONPath("$.products[?(@.price >= 30)]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := p.Select(doc)
+	if len(selected) != 2 {
+		t.Fatalf("Select() = %v", selected)
+	}
+	if name := selected[1].(map[string]interface{})["name"]; name != "Lamp" {
+		t.Errorf("second = %v", name)
+	}
+	if root, _ := CompileJSONPath("$"); len(root.Select(doc)) != 1 {
+		t.Error("$ doesn't select the document")
+	}
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"title",
+		"$.",
+		"$.a[",
+		"$.a[x]",
+		"$.a[1:2:0]",
+		"$.a[1:2:3:4]",
+		"$.a[?(@.b == nope)]",
+		"$.a[?(b)]",
+		"$a",
+	} {
+		if _, err := CompileJSONPath(expr); err == nil {
+			t.Errorf("CompileJSONPath(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestJSONRules(t *testing.T) {
+	doc := decodeFixture(t)
+	rules, err := NewJSONRules(config.JSONConfig{
+		Enabled: true,
+		Types:   []string{"application/json"},
+		Rules: []config.JSONRule{
+			{URL: `/api/other`, Title: "$.next"},
+			{URL: `/api/products`, Links: []string{"$.products[*].url", "$.next"}, Title: "$.title"},
+			{Links: []string{"$..url", "$.meta.links.last"}, Title: "$.products[0].name"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, links := rules.Apply("https://shop.example.com/api/products", doc)
+	if title != "Store" {
+		t.Errorf("title = %q", title)
+	}
+	want := "/p/1 /p/2 https://other.com/p/3 /api/products?page=2 /api/products?page=9"
+	if got := strings.Join(links, " "); got != want {
+		t.Errorf("links = %s, want %s", got, want)
+	}
+
+	for contentType, want := range map[string]bool{
+		"application/json; charset=utf-8": true,
+		"application/ld+json":             true,
+		"text/json":                       false,
+		"text/html":                       false,
+		"":                                false,
+	} {
+		if got := rules.Supports(contentType); got != want {
+			t.Errorf("Supports(%q) = %v", contentType, got)
+		}
+	}
+}
+
+func TestNewJSONRules(t *testing.T) {
+	if r, err := NewJSONRules(config.JSONConfig{Rules: []config.JSONRule{{Links: []string{"bad"}}}}); r != nil || err != nil {
+		t.Errorf("disabled = %v, %v", r, err)
+	}
+	for _, rule := range []config.JSONRule{
+		{URL: "(", Links: []string{"$.a"}},
+		{Links: []string{"$.a", "a"}},
+		{Title: "$["},
+	} {
+		if _, err := NewJSONRules(config.JSONConfig{Enabled: true, Rules: []config.JSONRule{rule}}); err == nil {
+			t.Errorf("NewJSONRules(%+v) succeeded", rule)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package extract
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// Sample texts from which the trigram profiles of Latin-script languages are
+// built, one file per ISO 639-1 code
+//
+//go:embed languages/*.txt
+var languageSamples embed.FS
+
+const (
+	profileSize   = 300  // Trigrams kept per profile
+	maxDetectText = 4096 // Bytes of page text used for detection
+	minDetectText = 40   // Letters needed for a guess
+)
+
+var (
+	profilesOnce sync.Once
+	profiles     map[string]map[string]int // Language -> trigram -> rank
+)
+
+// scriptLanguages identifies languages written in their own script
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+}
+
+// Language returns the lowercased primary language of a page: its <html lang>,
+// else the meta or header Content-Language, else a guess from the text. It
+// returns "" if the text is too short to guess.
+func Language(content, contentLanguage string) string {
+	if lang := declaredLanguage(content); lang != "" {
+		return lang
+	}
+	if lang := primaryLanguage(contentLanguage); lang != "" {
+		return lang
+	}
+
+	text := nodeTextOf(content)
+	if len(text) > maxDetectText {
+		text = text[:maxDetectText]
+	}
+	return DetectLanguage(text)
+}
+
+// primaryLanguage reduces a language tag such as en-US, or the first of a
+// Content-Language list, to its primary subtag
+func primaryLanguage(tag string) string {
+	tag, _, _ = strings.Cut(tag, ",")
+	tag, _, _ = strings.Cut(strings.TrimSpace(tag), "-")
+	tag, _, _ = strings.Cut(tag, "_")
+	tag = strings.ToLower(tag)
+	if len(tag) < 2 || len(tag) > 3 {
+		return ""
+	}
+	return tag
+}
+
+// declaredLanguage reads <html lang> or <meta http-equiv="content-language">
+func declaredLanguage(content string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				return ""
+			case "html":
+				for _, a := range token.Attr {
+					if a.Key == "lang" || a.Key == "xml:lang" {
+						if lang := primaryLanguage(a.Val); lang != "" {
+							return lang
+						}
+					}
+				}
+			case "meta":
+				var equiv, value string
+				for _, a := range token.Attr {
+					switch a.Key {
+					case "http-equiv":
+						equiv = strings.ToLower(a.Val)
+					case "content":
+						value = a.Val
+					}
+				}
+				if equiv == "content-language" {
+					if lang := primaryLanguage(value); lang != "" {
+						return lang
+					}
+				}
+			}
+		}
+	}
+}
+
+// nodeTextOf returns the visible text of HTML content
+func nodeTextOf(content string) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	return nodeText(doc)
+}
+
+// DetectLanguage guesses the language of plain text. Non-Latin scripts are
+// recognized by their characters; Latin-script text is matched against
+// trigram profiles by rank distance (Cavnar and Trenkle). It returns "" if
+// the text has too few letters.
+func DetectLanguage(text string) string {
+	letters, latin := 0, 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.table, r) {
+				scripts[s.lang]++
+				break
+			}
+		}
+	}
+	if letters < minDetectText {
+		return ""
+	}
+
+	if latin*2 < letters {
+		// Kana marks Japanese even though most of its characters are Han
+		if scripts["ja"] > 0 {
+			return "ja"
+		}
+		// Cyrillic letters used in Ukrainian but not Russian
+		if scripts["ru"] > 0 && strings.ContainsAny(text, "іїєґІЇЄҐ") {
+			scripts["uk"], scripts["ru"] = scripts["ru"], 0
+		}
+		best, count := "", 0
+		for lang, n := range scripts {
+			if n > count || (n == count && lang < best) {
+				best, count = lang, n
+			}
+		}
+		return best
+	}
+
+	profilesOnce.Do(loadProfiles)
+	doc := trigramRanks(text)
+	best, bestDistance := "", 0
+	for lang, profile := range profiles {
+		distance := 0
+		for gram, rank := range doc {
+			if r, ok := profile[gram]; ok {
+				distance += abs(rank - r)
+			} else {
+				distance += profileSize
+			}
+		}
+		if best == "" || distance < bestDistance || (distance == bestDistance && lang < best) {
+			best, bestDistance = lang, distance
+		}
+	}
+	return best
+}
+
+// loadProfiles builds the trigram profiles from the embedded samples
+func loadProfiles() {
+	profiles = make(map[string]map[string]int)
+	files, _ := languageSamples.ReadDir("languages")
+	for _, f := range files {
+		data, err := languageSamples.ReadFile(path.Join("languages", f.Name()))
+		if err != nil {
+			continue
+		}
+		profiles[strings.TrimSuffix(f.Name(), ".txt")] = trigramRanks(string(data))
+	}
+}
+
+// trigramRanks ranks the most frequent letter trigrams of text, with words
+// padded by spaces so that prefixes and suffixes count
+func trigramRanks(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			counts[string(runes[i:i+3])]++
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	ranks := make(map[string]int, len(grams))
+	for i, gram := range grams {
+		ranks[gram] = i
+	}
+	return ranks
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
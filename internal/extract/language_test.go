@@ -0,0 +1,62 @@
+package extract
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en": "The weather was cold this morning, so we stayed inside and read the newspaper until the rain stopped.",
+		"de": "Das Wetter war heute Morgen kalt, deshalb sind wir zu Hause geblieben und haben die Zeitung gelesen.",
+		"fr": "Il faisait froid ce matin, alors nous sommes restés à la maison pour lire le journal jusqu'à la fin de la pluie.",
+		"es": "Hacía frío esta mañana, así que nos quedamos en casa leyendo el periódico hasta que dejó de llover.",
+		"it": "Questa mattina faceva freddo, quindi siamo rimasti a casa a leggere il giornale finché non ha smesso di piovere.",
+		"nl": "Het was vanochtend koud, dus we bleven binnen en lazen de krant tot het ophield met regenen.",
+		"ru": "Сегодня утром было холодно, поэтому мы остались дома и читали газету, пока не закончился дождь.",
+		"uk": "Сьогодні вранці було холодно, тому ми залишилися вдома і читали газету, поки не припинився дощ.",
+		"ja": "今朝は寒かったので、雨が止むまで家で新聞を読んでいました。とても静かな朝でした。本当に寒い日でしたね。",
+		"zh": "今天早上很冷，所以我们待在家里看报纸，直到雨停了为止。这是一个非常安静的早晨，我们都很喜欢。",
+		"el": "Σήμερα το πρωί έκανε κρύο, οπότε μείναμε στο σπίτι και διαβάσαμε την εφημερίδα μέχρι να σταματήσει η βροχή.",
+	}
+	for want, text := range tests {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%s sample) = %q", want, got)
+		}
+	}
+	if got := DetectLanguage("Too short to tell"); got != "" {
+		t.Errorf("DetectLanguage() of a short text = %q, want none", got)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	body := "<body><p>Das Wetter war heute Morgen kalt, deshalb sind wir zu Hause geblieben und haben die Zeitung gelesen.</p></body>"
+	tests := []struct {
+		name, content, header, want string
+	}{
+		{"html lang", `<html lang="pt-BR"><head></head>` + body, "fr", "pt"},
+		{"meta", `<html><head><meta http-equiv="Content-Language" content="sv"></head>` + body, "", "sv"},
+		{"header", "<html>" + body, "nl-NL, en", "nl"},
+		{"detected", "<html>" + body, "", "de"},
+		{"invalid tag", `<html lang="x">` + body, "", "de"},
+		{"lang in body ignored", `<html><body><div lang="fi">` + body[6:], "", "de"},
+	}
+	for _, tt := range tests {
+		if got := Language(tt.content, tt.header); got != tt.want {
+			t.Errorf("%s: Language() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPrimaryLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en-US":     "en",
+		"de_AT":     "de",
+		" FR , en ": "fr",
+		"haw":       "haw",
+		"x":         "",
+		"":          "",
+	}
+	for tag, want := range tests {
+		if got := primaryLanguage(tag); got != want {
+			t.Errorf("primaryLanguage(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
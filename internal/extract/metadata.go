@@ -0,0 +1,194 @@
+package extract
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// metadata is what the page says about itself in meta tags, JSON-LD and markup
+type metadata struct {
+	title         string
+	author        string
+	published     time.Time
+	documentTitle string // Contents of <title>
+}
+
+// dateLayouts are the formats tried for publication dates
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+}
+
+// parseDate parses a publication date, returning the zero time if the format is unknown
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// readMetadata collects metadata in order of reliability: JSON-LD, Open
+// Graph and article meta tags, then bylines and <time> in the markup
+func readMetadata(doc *html.Node) metadata {
+	var m metadata
+	if title := find(doc, isElement(atom.Title)); title != nil {
+		m.documentTitle = innerText(title)
+	}
+
+	for _, script := range findAll(doc, isElement(atom.Script)) {
+		if strings.EqualFold(attr(script, "type"), "application/ld+json") && script.FirstChild != nil {
+			m.fromJSONLD(script.FirstChild.Data)
+		}
+	}
+
+	metas := make(map[string]string)
+	for _, n := range findAll(doc, isElement(atom.Meta)) {
+		key := strings.ToLower(attr(n, "property"))
+		if key == "" {
+			key = strings.ToLower(attr(n, "name"))
+		}
+		if key == "" {
+			key = strings.ToLower(attr(n, "itemprop"))
+		}
+		if value := strings.TrimSpace(attr(n, "content")); key != "" && value != "" {
+			if _, ok := metas[key]; !ok {
+				metas[key] = value
+			}
+		}
+	}
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if v := metas[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if m.title == "" {
+		m.title = first("og:title", "twitter:title", "dc.title", "headline")
+	}
+	if m.author == "" {
+		// article:author is often a profile URL
+		if author := first("author", "article:author", "dc.creator", "byl", "parsely-author"); !strings.Contains(author, "://") {
+			m.author = strings.TrimPrefix(author, "By ")
+		}
+	}
+	if m.author == "" {
+		m.author = byline(doc)
+	}
+	if m.published.IsZero() {
+		m.published = parseDate(first("article:published_time", "datepublished", "date", "dc.date", "pubdate", "publish-date", "sailthru.date"))
+	}
+	if m.published.IsZero() {
+		if t := find(doc, func(n *html.Node) bool { return n.DataAtom == atom.Time && attr(n, "datetime") != "" }); t != nil {
+			m.published = parseDate(attr(t, "datetime"))
+		}
+	}
+	return m
+}
+
+// fromJSONLD fills unset fields from a schema.org Article in a JSON-LD block
+func (m *metadata) fromJSONLD(data string) {
+	for _, obj := range jsonLDItems(data) {
+		if !isArticleType(obj["@type"]) {
+			continue
+		}
+		if s, ok := obj["headline"].(string); ok && m.title == "" {
+			m.title = strings.TrimSpace(s)
+		}
+		if m.author == "" {
+			m.author = personName(obj["author"])
+		}
+		if s, ok := obj["datePublished"].(string); ok && m.published.IsZero() {
+			m.published = parseDate(s)
+		}
+	}
+}
+
+// isArticleType reports whether a JSON-LD @type is Article or one of its subtypes
+func isArticleType(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.HasSuffix(v, "Article") || v == "BlogPosting" || v == "Report"
+	case []interface{}:
+		for _, t := range v {
+			if isArticleType(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// personName returns the name of a JSON-LD author, joining several authors
+func personName(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			return strings.TrimSpace(name)
+		}
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := personName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// byline returns the text of a rel=author link or an element marked as the author
+func byline(doc *html.Node) string {
+	n := find(doc, func(n *html.Node) bool {
+		if strings.Contains(" "+attr(n, "rel")+" ", " author ") || attr(n, "itemprop") == "author" {
+			return innerText(n) != ""
+		}
+		class := strings.ToLower(attr(n, "class"))
+		return (strings.Contains(class, "byline") || strings.Contains(class, "author")) && innerText(n) != ""
+	})
+	if n == nil {
+		return ""
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(innerText(n), "By "))
+	// Long matches are author bios rather than names
+	if len(name) > 100 {
+		return ""
+	}
+	return name
+}
+
+// headline picks the article title when no metadata names it: the only <h1>,
+// or else the document title without a trailing site name
+func headline(doc *html.Node, documentTitle string) string {
+	if h1s := findAll(doc, isElement(atom.H1)); len(h1s) == 1 {
+		if text := innerText(h1s[0]); text != "" {
+			return text
+		}
+	}
+	for _, sep := range []string{" | ", " - ", " — ", " · "} {
+		if i := strings.LastIndex(documentTitle, sep); i > 0 {
+			return strings.TrimSpace(documentTitle[:i])
+		}
+	}
+	return documentTitle
+}
@@ -0,0 +1,204 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Class and id patterns of boilerplate and of likely content, after Arc90's
+// readability
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)ad-|ads|advert|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tweet|twitter|widget`)
+	maybePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativePattern = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// boilerplateElements never hold article text
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Aside: true, atom.Footer: true, atom.Form: true,
+	atom.Iframe: true, atom.Svg: true, atom.Button: true, atom.Select: true,
+	atom.Input: true, atom.Textarea: true, atom.Dialog: true,
+}
+
+// paragraphElements are scored by their text
+var paragraphElements = map[atom.Atom]bool{
+	atom.P: true, atom.Pre: true, atom.Td: true, atom.Blockquote: true,
+	atom.H2: true, atom.H3: true, atom.Li: true,
+}
+
+// minParagraph is the shortest text that counts as a paragraph
+const minParagraph = 25
+
+// mainContent returns the element holding the article text. Boilerplate is
+// removed from doc, paragraphs add a score to their parent and grandparent,
+// and the element with the best score discounted by link density wins.
+func mainContent(doc *html.Node) *html.Node {
+	body := find(doc, isElement(atom.Body))
+	if body == nil {
+		return nil
+	}
+	removeBoilerplate(body)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	for _, p := range findAll(body, func(n *html.Node) bool { return paragraphElements[n.DataAtom] }) {
+		text := innerText(p)
+		if len(text) < minParagraph {
+			continue
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		addScore(p.Parent, score)
+		if p.Parent != nil {
+			addScore(p.Parent.Parent, score/2)
+		}
+	}
+
+	var top *html.Node
+	best := 0.0
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if scores[n] > best {
+			top, best = n, scores[n]
+		}
+	}
+	if top == nil {
+		return body
+	}
+	return withSiblings(top, best, scores)
+}
+
+// removeBoilerplate detaches elements that are never article content
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type == html.ElementNode && isBoilerplate(c):
+			n.RemoveChild(c)
+		default:
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+// isBoilerplate reports whether an element is navigation, an ad or similar
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.DataAtom] {
+		return true
+	}
+	if attr(n, "hidden") != "" || attr(n, "aria-hidden") == "true" || strings.Contains(attr(n, "style"), "display:none") {
+		return true
+	}
+	switch attr(n, "role") {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog", "alert":
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Article, atom.Main, atom.Body, atom.A:
+		return false
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match)
+}
+
+// initialScore rates an element by its tag and its class and id
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativePattern.MatchString(value) {
+			score -= 25
+		}
+		if positivePattern.MatchString(value) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of an element's text inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for _, a := range findAll(n, isElement(atom.A)) {
+		linked += len(innerText(a))
+	}
+	return float64(linked) / float64(total)
+}
+
+// withSiblings wraps top together with siblings that also look like content,
+// such as paragraphs split across several containers
+func withSiblings(top *html.Node, best float64, scores map[*html.Node]float64) *html.Node {
+	parent := top.Parent
+	if parent == nil {
+		return top
+	}
+	threshold := max(10, best*0.2)
+
+	var keep []*html.Node
+	for s := parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if s == top {
+			keep = append(keep, s)
+			continue
+		}
+		if score, ok := scores[s]; ok && score >= threshold {
+			keep = append(keep, s)
+			continue
+		}
+		if s.DataAtom == atom.P {
+			text := innerText(s)
+			density := linkDensity(s)
+			if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.HasSuffix(text, ".")) {
+				keep = append(keep, s)
+			}
+		}
+	}
+	if len(keep) == 1 {
+		return top
+	}
+
+	article := &html.Node{Type: html.ElementNode, DataAtom: atom.Div, Data: "div"}
+	for _, n := range keep {
+		parent.RemoveChild(n)
+		article.AppendChild(n)
+	}
+	return article
+}
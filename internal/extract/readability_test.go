@@ -0,0 +1,124 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const articlePage = `<html><head>
+<title>Rivers are rising | Daily News</title>
+<meta property="og:title" content="Rivers Are Rising Across the Valley">
+<meta name="author" content="By Jane Doe">
+<meta property="article:published_time" content="2024-03-05T08:30:00Z">
+</head><body>
+<header class="site-header"><a href="/">Daily News</a></header>
+<nav><a href="/world">World</a> <a href="/sports">Sports</a></nav>
+<div class="cookie-banner">We use cookies to improve your experience on this site.</div>
+<div id="main-content">
+  <article class="post">
+    <h1>Rivers are rising</h1>
+    <p>Heavy rain over the weekend pushed rivers across the valley to their highest level in a decade, officials said.</p>
+    <p>Several roads near the water were closed, and residents of low-lying areas were asked to move their cars to higher ground.</p>
+    <p>Forecasters expect the rain to ease by Tuesday, though the rivers may keep rising for another day as water flows down from the hills.</p>
+  </article>
+</div>
+<aside class="sidebar"><p>Most read: ten recipes you should try this summer, and more.</p></aside>
+<div class="related"><a href="/a">Related story with a long link title about something else entirely</a></div>
+<footer>Copyright Daily News, all rights reserved, since forever and a day.</footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	a := Extract(articlePage)
+	if a == nil {
+		t.Fatal("Extract() found no article")
+	}
+	if a.Title != "Rivers Are Rising Across the Valley" || a.Author != "Jane Doe" {
+		t.Errorf("title %q, author %q", a.Title, a.Author)
+	}
+	if want := time.Date(2024, 3, 5, 8, 30, 0, 0, time.UTC); !a.Published.Equal(want) {
+		t.Errorf("published %s, want %s", a.Published, want)
+	}
+
+	for _, want := range []string{"Heavy rain over the weekend", "Several roads", "Forecasters expect"} {
+		if !strings.Contains(a.Text, want) {
+			t.Errorf("text lacks %q:\n%s", want, a.Text)
+		}
+	}
+	for _, boilerplate := range []string{"World", "cookies", "Most read", "Related story", "Copyright"} {
+		if strings.Contains(a.Text, boilerplate) {
+			t.Errorf("text has boilerplate %q:\n%s", boilerplate, a.Text)
+		}
+	}
+	if lines := strings.Split(a.Text, "\n"); len(lines) < 3 {
+		t.Errorf("paragraphs aren't on lines of their own:\n%s", a.Text)
+	}
+}
+
+func TestExtractWithoutBody(t *testing.T) {
+	if a := Extract(`<html><body><nav><a href="/">Home</a></nav></body></html>`); a != nil {
+		t.Fatalf("Extract() of a page without text = %+v", a)
+	}
+}
+
+func TestMetadataFromJSONLD(t *testing.T) {
+	page := `<html><head><title>Ignored</title>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "WebSite", "name": "Site"},
+  {"@type": ["NewsArticle"], "headline": "From JSON-LD", "datePublished": "2023-11-02",
+   "author": [{"@type": "Person", "name": "Ann"}, {"@type": "Person", "name": "Bob"}]}
+]}
+</script>
+<meta property="og:title" content="From Open Graph">
+</head><body><p>Body</p></body></html>`
+
+	a := Extract(page)
+	if a == nil {
+		t.Fatal("Extract() found no article")
+	}
+	if a.Title != "From JSON-LD" || a.Author != "Ann, Bob" || !a.Published.Equal(time.Date(2023, 11, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("article = %+v", a)
+	}
+}
+
+func TestMetadataFromMarkup(t *testing.T) {
+	page := `<html><head><title>Quiet streets - The Town Paper</title>
+<meta property="article:author" content="https://example.com/staff/jo"></head><body>
+<div class="post"><span class="byline">By Jo Smith</span>
+<time datetime="2022-07-01T10:00">July 1</time>
+<p>The streets of the town were quiet on Sunday as most people stayed home for the holiday.</p>
+<h1>First heading</h1><h1>Second heading</h1>
+</div></body></html>`
+
+	a := Extract(page)
+	if a == nil {
+		t.Fatal("Extract() found no article")
+	}
+	// Two <h1>s don't name the article, so the site name is cut from <title>
+	if a.Title != "Quiet streets" || a.Author != "Jo Smith" {
+		t.Errorf("title %q, author %q", a.Title, a.Author)
+	}
+	if !a.Published.Equal(time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("published %s", a.Published)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := map[string]string{
+		"2024-01-02T03:04:05+02:00":     "2024-01-02T01:04:05Z",
+		"2024-01-02T03:04:05+0200":      "2024-01-02T01:04:05Z",
+		" 2024-01-02 ":                  "2024-01-02T00:00:00Z",
+		"Tue, 02 Jan 2024 03:04:05 GMT": "2024-01-02T03:04:05Z",
+		"January 2, 2024":               "2024-01-02T00:00:00Z",
+		"2 January 2024":                "2024-01-02T00:00:00Z",
+	}
+	for s, want := range tests {
+		if got := parseDate(s).UTC().Format(time.RFC3339); got != want {
+			t.Errorf("parseDate(%q) = %s, want %s", s, got, want)
+		}
+	}
+	if got := parseDate("yesterday"); !got.IsZero() {
+		t.Errorf("parseDate(yesterday) = %s", got)
+	}
+}
@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"web-crawler/internal/config"
+
+	"golang.org/x/net/html"
+)
+
+// Rules extracts user-defined fields from pages with CSS selectors or XPath
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	config.ExtractionRule
+	url      *regexp.Regexp // Nil for every page
+	selector *Selector
+	xpath    *XPath
+}
+
+// NewRules compiles the extraction rules. It returns nil if there are none.
+func NewRules(cfg config.ExtractionConfig) (*Rules, error) {
+	if len(cfg.Rules) == 0 {
+		return nil, nil
+	}
+	r := &Rules{}
+	for _, rc := range cfg.Rules {
+		compiled := rule{ExtractionRule: rc}
+		var err error
+		if rc.URL != "" {
+			if compiled.url, err = regexp.Compile(rc.URL); err != nil {
+				return nil, fmt.Errorf("invalid url pattern of rule %s: %w", rc.Name, err)
+			}
+		}
+		if rc.Selector != "" {
+			if compiled.selector, err = CompileSelector(rc.Selector); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", rc.Name, err)
+			}
+		} else if compiled.xpath, err = CompileXPath(rc.XPath); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rc.Name, err)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// Apply runs the rules whose URL pattern matches pageURL. Values are
+// strings, or lists of strings for rules with all set; rules without a match
+// are left out. Relative href and src values are resolved against base. It
+// returns nil if no rule applies.
+func (r *Rules) Apply(pageURL, content string, base *url.URL) map[string]interface{} {
+	if r == nil {
+		return nil
+	}
+	var doc *html.Node
+	var extracted map[string]interface{}
+	for _, rl := range r.rules {
+		if rl.url != nil && !rl.url.MatchString(pageURL) {
+			continue
+		}
+		if _, done := extracted[rl.Name]; done {
+			continue // An earlier rule of the same name matched
+		}
+		if doc == nil {
+			var err error
+			if doc, err = html.Parse(strings.NewReader(content)); err != nil {
+				return nil
+			}
+		}
+
+		values := rl.values(doc, base)
+		if len(values) == 0 {
+			continue
+		}
+		if extracted == nil {
+			extracted = make(map[string]interface{})
+		}
+		if rl.All {
+			extracted[rl.Name] = values
+		} else {
+			extracted[rl.Name] = values[0]
+		}
+	}
+	return extracted
+}
+
+// values returns the non-empty values the rule selects, in document order
+func (rl *rule) values(doc *html.Node, base *url.URL) []string {
+	var values []string
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if base != nil && (key == "href" || key == "src") {
+			if u, err := base.Parse(value); err == nil {
+				value = u.String()
+			}
+		}
+		values = append(values, value)
+	}
+
+	if rl.selector != nil {
+		for _, n := range rl.selector.MatchAll(doc) {
+			if rl.Attr != "" {
+				add(rl.Attr, strings.TrimSpace(attr(n, rl.Attr)))
+			} else {
+				add("", innerText(n))
+			}
+			if len(values) > 0 && !rl.All {
+				break
+			}
+		}
+		return values
+	}
+
+	result := rl.xpath.expr.eval(xcontext{node: xnode{n: doc}, pos: 1, size: 1})
+	nodes, ok := result.([]xnode)
+	if !ok {
+		// A string, number or boolean expression such as count(//a)
+		add("", toString(result))
+		return values
+	}
+	for _, node := range nodes {
+		switch {
+		case rl.Attr != "" && node.attr == nil:
+			add(rl.Attr, strings.TrimSpace(attr(node.n, rl.Attr)))
+		case node.attr != nil:
+			add(node.attr.Key, strings.TrimSpace(node.attr.Val))
+		default:
+			add("", node.value())
+		}
+		if len(values) > 0 && !rl.All {
+			break
+		}
+	}
+	return values
+}
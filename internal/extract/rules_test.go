@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestRulesApply(t *testing.T) {
+	r, err := NewRules(config.ExtractionConfig{Rules: []config.ExtractionRule{
+		{Name: "heading", Selector: "h2"},
+		{Name: "links", Selector: "li a", Attr: "href", All: true},
+		{Name: "logo", XPath: "//img/@src"},
+		{Name: "items", XPath: "count(//li)"},
+		{Name: "sale", XPath: "//li[contains(@class, 'sale')]", Attr: "id"},
+		{Name: "missing", Selector: ".nothing"},
+		{Name: "shop_only", URL: `^https://shop\.`, Selector: "h2"},
+		// The first rule of a name that matches wins
+		{Name: "note", Selector: "p.none"},
+		{Name: "note", Selector: "p[title]"},
+		{Name: "note", Selector: "p"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/catalog/")
+
+	got := r.Apply("https://example.com/catalog/", fixturePage, base)
+	data, _ := json.Marshal(got)
+	want := `{"heading":"Products","items":"4",` +
+		`"links":["https://example.com/p/1","https://other.com/2","https://example.com/p/3"],` +
+		`"logo":"https://example.com/logo.png","note":"Returns within 30 days.","sale":"li3"}`
+	if string(data) != want {
+		t.Fatalf("Apply()\n got %s\nwant %s", data, want)
+	}
+
+	if got := r.Apply("https://shop.example.com/", "<h2>Shop</h2>", nil); got["shop_only"] != "Shop" {
+		t.Fatalf("URL pattern rule not applied: %v", got)
+	}
+}
+
+func TestRulesNone(t *testing.T) {
+	r, err := NewRules(config.ExtractionConfig{})
+	if r != nil || err != nil {
+		t.Fatalf("NewRules() = %v, %v without rules", r, err)
+	}
+	if got := r.Apply("https://example.com/", fixturePage, nil); got != nil {
+		t.Fatalf("nil rules extracted %v", got)
+	}
+}
+
+func TestRulesErrors(t *testing.T) {
+	for _, rule := range []config.ExtractionRule{
+		{Name: "url", URL: "(", Selector: "a"},
+		{Name: "selector", Selector: "a["},
+		{Name: "xpath", XPath: "//a["},
+	} {
+		if _, err := NewRules(config.ExtractionConfig{Rules: []config.ExtractionRule{rule}}); err == nil {
+			t.Errorf("NewRules() accepted the invalid %s rule", rule.Name)
+		}
+	}
+}
@@ -0,0 +1,491 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector group. Supported are type, universal,
+// #id, .class and attribute selectors ([a], =, ~=, |=, ^=, $=, *=), the
+// descendant, child (>), adjacent (+) and sibling (~) combinators, and the
+// pseudo-classes :first-child, :last-child, :only-child, :nth-child(an+b),
+// :nth-last-child(an+b), :first-of-type, :last-of-type, :empty and :not().
+type Selector struct {
+	groups []complexSelector
+}
+
+// complexSelector is a chain of compound selectors, right-most last
+type complexSelector struct {
+	parts       []compound
+	combinators []byte // combinators[i] joins parts[i] and parts[i+1]
+}
+
+// compound is a sequence of simple selectors matching one element
+type compound struct {
+	tag     string // Empty or * for any
+	id      string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudo
+}
+
+type attrSelector struct {
+	name, op, value string
+}
+
+type pseudo struct {
+	name string
+	a, b int       // For nth-child(an+b)
+	not  *compound // For :not()
+}
+
+// CompileSelector parses a CSS selector group
+func CompileSelector(s string) (*Selector, error) {
+	p := &selectorParser{s: s}
+	sel := &Selector{}
+	for {
+		p.skipSpace()
+		complex, err := p.complex()
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+		}
+		sel.groups = append(sel.groups, complex)
+		p.skipSpace()
+		if p.eof() {
+			return sel, nil
+		}
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("invalid selector %q: unexpected %q at %d", s, p.peek(), p.pos)
+		}
+		p.pos++
+	}
+}
+
+// MatchAll returns the elements under root matching the selector, in document order
+func (sel *Selector) MatchAll(root *html.Node) []*html.Node {
+	return findAll(root, sel.Match)
+}
+
+// Match reports whether n matches any selector of the group
+func (sel *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, c := range sel.groups {
+		if c.match(n, len(c.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// match checks parts[:i+1] against n and its ancestors or preceding siblings
+func (c complexSelector) match(n *html.Node, i int) bool {
+	if !c.parts[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinators[i-1] {
+	case '>':
+		p := parentElement(n)
+		return p != nil && c.match(p, i-1)
+	case '+':
+		s := prevElement(n)
+		return s != nil && c.match(s, i-1)
+	case '~':
+		for s := prevElement(n); s != nil; s = prevElement(s) {
+			if c.match(s, i-1) {
+				return true
+			}
+		}
+	default: // Descendant
+		for p := parentElement(n); p != nil; p = parentElement(p) {
+			if c.match(p, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *compound) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	if len(c.classes) > 0 {
+		classes := strings.Fields(attr(n, "class"))
+		for _, want := range c.classes {
+			if !contains(classes, want) {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.match(n) {
+			return false
+		}
+	}
+	for _, p := range c.pseudos {
+		if !p.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a attrSelector) match(n *html.Node) bool {
+	value, ok := attrOK(n, a.name)
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		return contains(strings.Fields(value), a.value)
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+func (p pseudo) match(n *html.Node) bool {
+	switch p.name {
+	case "first-child":
+		return prevElement(n) == nil
+	case "last-child":
+		return nextElement(n) == nil
+	case "only-child":
+		return prevElement(n) == nil && nextElement(n) == nil
+	case "first-of-type":
+		return siblingIndex(n, prevElement, true) == 1
+	case "last-of-type":
+		return siblingIndex(n, nextElement, true) == 1
+	case "nth-child":
+		return nth(p.a, p.b, siblingIndex(n, prevElement, false))
+	case "nth-last-child":
+		return nth(p.a, p.b, siblingIndex(n, nextElement, false))
+	case "empty":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode || c.Type == html.TextNode && c.Data != "" {
+				return false
+			}
+		}
+		return true
+	case "not":
+		return !p.not.match(n)
+	}
+	return false
+}
+
+// nth reports whether the 1-based position is an+b for some n >= 0
+func nth(a, b, position int) bool {
+	if a == 0 {
+		return position == b
+	}
+	diff := position - b
+	return diff%a == 0 && diff/a >= 0
+}
+
+// siblingIndex returns the 1-based position of n counted with step,
+// optionally among siblings of the same type only
+func siblingIndex(n *html.Node, step func(*html.Node) *html.Node, sameType bool) int {
+	i := 1
+	for s := step(n); s != nil; s = step(s) {
+		if !sameType || s.Data == n.Data {
+			i++
+		}
+	}
+	return i
+}
+
+func parentElement(n *html.Node) *html.Node {
+	if p := n.Parent; p != nil && p.Type == html.ElementNode {
+		return p
+	}
+	return nil
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selectorParser is a recursive descent parser over a selector string
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func (p *selectorParser) eof() bool  { return p.pos >= len(p.s) }
+func (p *selectorParser) peek() byte { return p.s[p.pos] }
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && strings.IndexByte(" \t\r\n\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) complex() (complexSelector, error) {
+	var c complexSelector
+	for {
+		part, err := p.compound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, part)
+
+		space := p.skipSpace()
+		if p.eof() || p.peek() == ',' || p.peek() == ')' {
+			return c, nil
+		}
+		combinator := byte(' ')
+		if strings.IndexByte(">+~", p.peek()) >= 0 {
+			combinator = p.peek()
+			p.pos++
+			p.skipSpace()
+		} else if !space {
+			return c, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+		}
+		c.combinators = append(c.combinators, combinator)
+	}
+}
+
+func (p *selectorParser) compound() (compound, error) {
+	var c compound
+	start := p.pos
+	if !p.eof() && p.peek() == '*' {
+		c.tag = "*"
+		p.pos++
+	} else if name := p.ident(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+
+	for !p.eof() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if c.id = p.ident(); c.id == "" {
+				return c, fmt.Errorf("expected id at %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, fmt.Errorf("expected class at %d", p.pos)
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			a, err := p.attribute()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			ps, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, ps)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("unexpected %q at %d", p.peek(), p.pos)
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, fmt.Errorf("empty selector")
+	}
+	return c, nil
+}
+
+// ident reads a name of letters, digits, hyphens, underscores and escapes
+func (p *selectorParser) ident() string {
+	var sb strings.Builder
+	for !p.eof() {
+		ch := p.peek()
+		switch {
+		case ch == '\\' && p.pos+1 < len(p.s):
+			sb.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+		case ch == '-' || ch == '_' || ch >= 0x80 ||
+			'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9':
+			sb.WriteByte(ch)
+			p.pos++
+		default:
+			return sb.String()
+		}
+	}
+	return sb.String()
+}
+
+func (p *selectorParser) attribute() (attrSelector, error) {
+	p.pos++ // [
+	p.skipSpace()
+	var a attrSelector
+	if a.name = strings.ToLower(p.ident()); a.name == "" {
+		return a, fmt.Errorf("expected attribute name at %d", p.pos)
+	}
+	p.skipSpace()
+	if p.eof() {
+		return a, fmt.Errorf("unterminated attribute selector")
+	}
+	if p.peek() == ']' {
+		p.pos++
+		return a, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, fmt.Errorf("unknown attribute operator at %d", p.pos)
+	}
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return a, err
+	}
+	a.value = value
+	p.skipSpace()
+	if p.eof() || p.peek() != ']' {
+		return a, fmt.Errorf("expected ] at %d", p.pos)
+	}
+	p.pos++
+	return a, nil
+}
+
+// value reads a quoted string or an identifier
+func (p *selectorParser) value() (string, error) {
+	if p.eof() {
+		return "", fmt.Errorf("expected value")
+	}
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		return p.ident(), nil
+	}
+	end := strings.IndexByte(p.s[p.pos+1:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at %d", p.pos)
+	}
+	value := p.s[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return value, nil
+}
+
+func (p *selectorParser) pseudo() (pseudo, error) {
+	p.pos++ // :
+	ps := pseudo{name: strings.ToLower(p.ident())}
+	switch ps.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "empty":
+		return ps, nil
+	case "nth-child", "nth-last-child", "not":
+	default:
+		return ps, fmt.Errorf("unsupported pseudo-class :%s", ps.name)
+	}
+
+	if p.eof() || p.peek() != '(' {
+		return ps, fmt.Errorf("expected ( after :%s", ps.name)
+	}
+	p.pos++
+	p.skipSpace()
+	if ps.name == "not" {
+		not, err := p.compound()
+		if err != nil {
+			return ps, err
+		}
+		ps.not = &not
+	} else {
+		end := strings.IndexByte(p.s[p.pos:], ')')
+		if end < 0 {
+			return ps, fmt.Errorf("unterminated :%s", ps.name)
+		}
+		var err error
+		if ps.a, ps.b, err = parseNth(p.s[p.pos : p.pos+end]); err != nil {
+			return ps, err
+		}
+		p.pos += end
+	}
+	p.skipSpace()
+	if p.eof() || p.peek() != ')' {
+		return ps, fmt.Errorf("expected ) at %d", p.pos)
+	}
+	p.pos++
+	return ps, nil
+}
+
+// parseNth parses an+b, odd or even
+func parseNth(s string) (a, b int, err error) {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	switch s {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	i := strings.IndexByte(s, 'n')
+	if i < 0 {
+		b, err = strconv.Atoi(s)
+		return 0, b, err
+	}
+	switch coef := s[:i]; coef {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(coef); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+	}
+	if rest := s[i+1:]; rest != "" {
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, fmt.Errorf("invalid nth expression %q", s)
+		}
+	}
+	return a, b, nil
+}
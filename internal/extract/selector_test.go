@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// fixturePage is the document the selector, XPath and rule tests run against
+const fixturePage = `<html><body>
+<div id="main" class="content wide" data-kind="list">
+  <h2 id="h" lang="en-GB">Products</h2>
+  <ul id="list">
+    <li id="li1" class="item first"><a id="a1" href="/p/1">One</a></li>
+    <li id="li2" class="item"><a id="a2" href="https://other.com/2" rel="nofollow">Two</a></li>
+    <li id="li3" class="item sale"><a id="a3" href="/p/3">Three</a> <span id="s3">-20%</span></li>
+    <li id="li4" class="item"></li>
+  </ul>
+  <p id="p1">Shipping is free.</p>
+  <p id="p2" title="note">Returns within 30 days.</p>
+</div>
+<img id="img" src="/logo.png" alt="Logo">
+</body></html>`
+
+func parseFixture(t *testing.T) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(fixturePage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// ids joins the id attributes of nodes
+func ids(nodes []*html.Node) string {
+	var list []string
+	for _, n := range nodes {
+		list = append(list, attr(n, "id"))
+	}
+	return strings.Join(list, " ")
+}
+
+func TestSelector(t *testing.T) {
+	doc := parseFixture(t)
+	tests := map[string]string{
+		"li":                          "li1 li2 li3 li4",
+		"#main > p":                   "p1 p2",
+		".item.sale a":                "a3",
+		"ul a, img":                   "a1 a2 a3 img",
+		"h2 + ul":                     "list",
+		"h2 ~ p":                      "p1 p2",
+		"[data-kind]":                 "main",
+		"[class~=wide]":               "main",
+		"[lang|=en]":                  "h",
+		`a[href^="/p/"]`:              "a1 a3",
+		`a[href$="2"]`:                "a2",
+		`[title*=ot]`:                 "p2",
+		"li:first-child":              "li1",
+		"li:last-child":               "li4",
+		"li:nth-child(2n)":            "li2 li4",
+		"li:nth-child(odd)":           "li1 li3",
+		"li:nth-last-child(2)":        "li3",
+		"p:first-of-type":             "p1",
+		"p:last-of-type":              "p2",
+		"li:empty":                    "li4",
+		"li:not(.item)":               "",
+		"li:not(:first-child) > a":    "a2 a3",
+		"a:only-child":                "a1 a2",
+		"div *:not(li):not(a):not(p)": "h list s3",
+	}
+	for s, want := range tests {
+		sel, err := CompileSelector(s)
+		if err != nil {
+			t.Errorf("CompileSelector(%q) = %v", s, err)
+			continue
+		}
+		if got := ids(sel.MatchAll(doc)); got != want {
+			t.Errorf("%q matched %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestSelectorErrors(t *testing.T) {
+	for _, s := range []string{"", "a,", "[href", `[href="x]`, "li:nth-child(x)", "li:hover", "a >", "a!"} {
+		if _, err := CompileSelector(s); err == nil {
+			t.Errorf("CompileSelector(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package extract
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Structured is the schema.org style data embedded in a page, by syntax.
+// Microdata and RDFa items are converted to JSON-LD shaped maps with @type,
+// @id and one key per property; repeated properties become lists.
+type Structured struct {
+	JSONLD    []map[string]interface{}
+	Microdata []map[string]interface{}
+	RDFa      []map[string]interface{}
+}
+
+// Empty reports whether no structured data was found
+func (s *Structured) Empty() bool {
+	return len(s.JSONLD) == 0 && len(s.Microdata) == 0 && len(s.RDFa) == 0
+}
+
+// StructuredData parses the JSON-LD blocks, microdata and RDFa Lite items of
+// HTML content. URLs in microdata and RDFa are resolved against base.
+func StructuredData(content string, base *url.URL) *Structured {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return &Structured{}
+	}
+
+	s := &Structured{}
+	for _, script := range findAll(doc, isElement(atom.Script)) {
+		if strings.EqualFold(strings.TrimSpace(attr(script, "type")), "application/ld+json") && script.FirstChild != nil {
+			s.JSONLD = append(s.JSONLD, jsonLDItems(script.FirstChild.Data)...)
+		}
+	}
+
+	micro := itemSyntax{scope: "itemscope", prop: "itemprop", base: base}
+	rdfa := itemSyntax{scope: "typeof", prop: "property", base: base, rdfa: true}
+	for _, n := range findAll(doc, func(n *html.Node) bool { return hasAttr(n, "itemscope") && !hasAttr(n, "itemprop") }) {
+		s.Microdata = append(s.Microdata, micro.item(n))
+	}
+	for _, n := range findAll(doc, func(n *html.Node) bool { return hasAttr(n, "typeof") && !hasAttr(n, "property") }) {
+		s.RDFa = append(s.RDFa, rdfa.item(n))
+	}
+	return s
+}
+
+// jsonLDItems returns the top-level objects of a JSON-LD block, with the
+// nodes of an @graph as separate objects sharing its @context. Invalid
+// blocks are skipped.
+func jsonLDItems(data string) []map[string]interface{} {
+	var root interface{}
+	if err := json.Unmarshal([]byte(data), &root); err != nil {
+		return nil
+	}
+
+	var items []map[string]interface{}
+	var collect func(v interface{}, context interface{})
+	collect = func(v interface{}, context interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				collect(item, context)
+			}
+		case map[string]interface{}:
+			if ctx, ok := v["@context"]; ok {
+				context = ctx
+			}
+			graph, ok := v["@graph"]
+			if !ok {
+				if _, ok := v["@context"]; !ok && context != nil {
+					v["@context"] = context
+				}
+				items = append(items, v)
+				return
+			}
+			collect(graph, context)
+		}
+	}
+	collect(root, nil)
+	return items
+}
+
+// itemSyntax reads items in microdata (itemscope/itemprop) or RDFa Lite
+// (typeof/property) markup
+type itemSyntax struct {
+	scope string // Attribute starting an item
+	prop  string // Attribute naming a property
+	base  *url.URL
+	rdfa  bool
+}
+
+// item converts the item rooted at n
+func (s itemSyntax) item(n *html.Node) map[string]interface{} {
+	item := make(map[string]interface{})
+
+	typeAttr, idAttr := "itemtype", "itemid"
+	if s.rdfa {
+		typeAttr, idAttr = "typeof", "resource"
+		if vocab := s.vocab(n); vocab != "" {
+			item["@context"] = vocab
+		}
+	}
+	if types := strings.Fields(attr(n, typeAttr)); len(types) == 1 {
+		item["@type"] = types[0]
+	} else if len(types) > 1 {
+		item["@type"] = types
+	}
+	if id := strings.TrimSpace(attr(n, idAttr)); id != "" {
+		item["@id"] = id
+	}
+
+	s.properties(n, item)
+	return item
+}
+
+// properties adds the properties below n to item without entering nested items
+func (s itemSyntax) properties(n *html.Node, item map[string]interface{}) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		names := strings.Fields(attr(c, s.prop))
+		nested := hasAttr(c, s.scope)
+		if len(names) > 0 {
+			var value interface{}
+			if nested {
+				value = s.item(c)
+			} else {
+				value = s.value(c)
+			}
+			for _, name := range names {
+				addProperty(item, name, value)
+			}
+		}
+		if !nested {
+			s.properties(c, item)
+		}
+	}
+}
+
+// value returns the property value of an element that is not an item
+func (s itemSyntax) value(n *html.Node) string {
+	if s.rdfa {
+		if content, ok := attrOK(n, "content"); ok {
+			return strings.TrimSpace(content)
+		}
+		for _, name := range []string{"resource", "href", "src"} {
+			if v := attr(n, name); v != "" {
+				return s.resolve(v)
+			}
+		}
+	}
+
+	switch n.DataAtom {
+	case atom.Meta:
+		return strings.TrimSpace(attr(n, "content"))
+	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		return s.resolve(attr(n, "src"))
+	case atom.A, atom.Area, atom.Link:
+		return s.resolve(attr(n, "href"))
+	case atom.Object:
+		return s.resolve(attr(n, "data"))
+	case atom.Data, atom.Meter:
+		return strings.TrimSpace(attr(n, "value"))
+	case atom.Time:
+		if datetime := attr(n, "datetime"); datetime != "" {
+			return strings.TrimSpace(datetime)
+		}
+	}
+	if content, ok := attrOK(n, "content"); ok {
+		return strings.TrimSpace(content)
+	}
+	return innerText(n)
+}
+
+// vocab returns the RDFa vocabulary in effect at n
+func (s itemSyntax) vocab(n *html.Node) string {
+	for ; n != nil; n = n.Parent {
+		if vocab := attr(n, "vocab"); vocab != "" {
+			return vocab
+		}
+	}
+	return ""
+}
+
+// resolve makes a URL attribute absolute
+func (s itemSyntax) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if s.base == nil || href == "" {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return s.base.ResolveReference(ref).String()
+}
+
+// addProperty sets a property, turning it into a list when repeated
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	switch existing := item[name].(type) {
+	case nil:
+		item[name] = value
+	case []interface{}:
+		item[name] = append(existing, value)
+	default:
+		item[name] = []interface{}{existing, value}
+	}
+}
+
+// hasAttr reports whether n has the named attribute, even if empty
+func hasAttr(n *html.Node, name string) bool {
+	_, ok := attrOK(n, name)
+	return ok
+}
+
+// attrOK returns the named attribute of n and whether it is present
+func attrOK(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
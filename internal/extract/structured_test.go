@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+// asJSON encodes items with sorted keys, for comparing with an expected document
+func asJSON(t *testing.T, items []map[string]interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestJSONLDItems(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"object", `{"@context":"https://schema.org","@type":"Thing"}`, `[{"@context":"https://schema.org","@type":"Thing"}]`},
+		{"array", `[{"@type":"A"},{"@type":"B"}]`, `[{"@type":"A"},{"@type":"B"}]`},
+		{"graph", `{"@context":"https://schema.org","@graph":[{"@type":"A"},{"@type":"B","@context":"x"}]}`,
+			`[{"@context":"https://schema.org","@type":"A"},{"@context":"x","@type":"B"}]`},
+		{"invalid", `{"@type":`, `null`},
+	}
+	for _, tt := range tests {
+		if got := asJSON(t, jsonLDItems(tt.data)); got != tt.want {
+			t.Errorf("%s: jsonLDItems() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMicrodata(t *testing.T) {
+	page := `<html><body>
+<div itemscope itemtype="https://schema.org/Product" itemid="urn:sku:42">
+  <h1 itemprop="name">Kettle</h1>
+  <img itemprop="image" src="/img/kettle.jpg">
+  <a itemprop="url" href="kettle">Details</a>
+  <meta itemprop="sku" content=" 42 ">
+  <span itemprop="color">Red</span><span itemprop="color">Blue</span>
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+    <data itemprop="price" value="19.99">$19.99</data>
+    <time itemprop="validFrom" datetime="2024-01-01">New Year</time>
+  </div>
+</div>
+</body></html>`
+	base, _ := url.Parse("https://shop.example.com/p/")
+
+	s := StructuredData(page, base)
+	want := `[{"@id":"urn:sku:42","@type":"https://schema.org/Product",` +
+		`"color":["Red","Blue"],` +
+		`"image":"https://shop.example.com/img/kettle.jpg",` +
+		`"name":"Kettle",` +
+		`"offers":{"@type":"https://schema.org/Offer","price":"19.99","validFrom":"2024-01-01"},` +
+		`"sku":"42",` +
+		`"url":"https://shop.example.com/p/kettle"}]`
+	if got := asJSON(t, s.Microdata); got != want {
+		t.Fatalf("microdata\n got %s\nwant %s", got, want)
+	}
+	if len(s.JSONLD) != 0 || len(s.RDFa) != 0 || s.Empty() {
+		t.Fatalf("StructuredData() = %+v", s)
+	}
+}
+
+func TestRDFa(t *testing.T) {
+	page := `<html><body vocab="https://schema.org/">
+<div typeof="Person" resource="#me">
+  <span property="name">Alice</span>
+  <a property="url sameAs" href="/alice">Home</a>
+  <span property="jobTitle" content="Engineer">Builds things</span>
+  <div property="address" typeof="PostalAddress">
+    <span property="addressLocality">Lisbon</span>
+  </div>
+</div>
+</body></html>`
+	base, _ := url.Parse("https://example.com/")
+
+	s := StructuredData(page, base)
+	want := `[{"@context":"https://schema.org/","@id":"#me","@type":"Person",` +
+		`"address":{"@context":"https://schema.org/","@type":"PostalAddress","addressLocality":"Lisbon"},` +
+		`"jobTitle":"Engineer","name":"Alice",` +
+		`"sameAs":"https://example.com/alice","url":"https://example.com/alice"}]`
+	if got := asJSON(t, s.RDFa); got != want {
+		t.Fatalf("RDFa\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestStructuredDataJSONLD(t *testing.T) {
+	page := `<script type=" application/ld+json ">{"@type":"Event","name":"Launch"}</script>
+<script type="application/ld+json">not json</script>`
+	s := StructuredData(page, nil)
+	if got := asJSON(t, s.JSONLD); got != `[{"@type":"Event","name":"Launch"}]` {
+		t.Fatalf("JSON-LD = %s", got)
+	}
+	if empty := StructuredData("<p>plain</p>", nil); !empty.Empty() {
+		t.Fatalf("plain page has structured data %+v", empty)
+	}
+}
@@ -0,0 +1,885 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// XPath is a compiled XPath 1.0 expression. Supported are location paths
+// with the child, descendant, descendant-or-self, parent, ancestor,
+// ancestor-or-self, following-sibling, preceding-sibling, self and attribute
+// axes and their abbreviations (//, ., .., @), predicates, unions, the
+// comparison and boolean operators, and the functions last, position,
+// count, string, concat, contains, starts-with, ends-with, normalize-space,
+// string-length, name and not.
+type XPath struct {
+	expr xexpr
+}
+
+// xnode is a node of the data model: an HTML node or an attribute of one
+type xnode struct {
+	n    *html.Node
+	attr *html.Attribute
+}
+
+// xcontext is the evaluation context of an expression
+type xcontext struct {
+	node      xnode
+	pos, size int
+}
+
+// Values are []xnode, string, float64 or bool
+type xvalue interface{}
+
+type xexpr interface {
+	eval(ctx xcontext) xvalue
+}
+
+// CompileXPath parses an XPath expression
+func CompileXPath(s string) (*XPath, error) {
+	tokens, err := lexXPath(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath %q: %w", s, err)
+	}
+	p := &xpathParser{tokens: tokens}
+	expr, err := p.orExpr()
+	if err == nil && !p.eof() {
+		err = fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath %q: %w", s, err)
+	}
+	return &XPath{expr: expr}, nil
+}
+
+// Evaluate returns the string values of the expression over a document:
+// one per node for node sets, else the single value
+func (x *XPath) Evaluate(doc *html.Node) []string {
+	switch v := x.expr.eval(xcontext{node: xnode{n: doc}, pos: 1, size: 1}).(type) {
+	case []xnode:
+		values := make([]string, 0, len(v))
+		for _, node := range v {
+			values = append(values, node.value())
+		}
+		return values
+	default:
+		return []string{toString(v)}
+	}
+}
+
+// Select returns the elements the expression selects
+func (x *XPath) Select(doc *html.Node) []*html.Node {
+	nodes, _ := x.expr.eval(xcontext{node: xnode{n: doc}, pos: 1, size: 1}).([]xnode)
+	var elements []*html.Node
+	for _, node := range nodes {
+		if node.attr == nil && node.n.Type == html.ElementNode {
+			elements = append(elements, node.n)
+		}
+	}
+	return elements
+}
+
+// stringValue is the XPath string value: the concatenated text of an element
+func (x xnode) stringValue() string {
+	if x.attr != nil {
+		return x.attr.Val
+	}
+	if x.n.Type == html.TextNode {
+		return x.n.Data
+	}
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(x.n)
+	return sb.String()
+}
+
+// value is the extracted value of a node: attributes as is, text with
+// whitespace collapsed
+func (x xnode) value() string {
+	if x.attr != nil {
+		return x.attr.Val
+	}
+	if x.n.Type == html.TextNode {
+		return strings.Join(strings.Fields(x.n.Data), " ")
+	}
+	return innerText(x.n)
+}
+
+// Lexer
+
+type xtoken struct {
+	kind byte // 'n' name, 's' string, '#' number, else the operator's first byte
+	text string
+}
+
+func lexXPath(s string) ([]xtoken, error) {
+	var tokens []xtoken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, xtoken{'s', s[i+1 : i+1+end]})
+			i += end + 2
+		case '0' <= c && c <= '9' || c == '.' && i+1 < len(s) && '0' <= s[i+1] && s[i+1] <= '9':
+			j := i
+			for j < len(s) && ('0' <= s[j] && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, xtoken{'#', s[i:j]})
+			i = j
+		case isNameStart(rune(c)) || c >= 0x80:
+			j := i
+			for j < len(s) {
+				r := rune(s[j])
+				if isNameStart(r) || '0' <= r && r <= '9' || r == '-' || r == '.' || r >= 0x80 ||
+					r == ':' && j+1 < len(s) && s[j+1] != ':' && (j == 0 || s[j-1] != ':') {
+					j++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, xtoken{'n', s[i:j]})
+			i = j
+		default:
+			op := operator(s[i:])
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, xtoken{op[0], op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// operator returns the operator at the start of s, empty if there is none
+func operator(s string) string {
+	for _, op := range []string{"//", "::", "..", "!=", "<=", ">=", "/", "[", "]", "(", ")", "@", ",", "|", "=", "<", ">", ".", "*"} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// Parser
+
+type xpathParser struct {
+	tokens []xtoken
+	pos    int
+}
+
+func (p *xpathParser) eof() bool { return p.pos >= len(p.tokens) }
+
+func (p *xpathParser) peek() xtoken {
+	if p.eof() {
+		return xtoken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) peekAt(offset int) xtoken {
+	if p.pos+offset >= len(p.tokens) {
+		return xtoken{}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+// accept consumes the next token if its text is one of ops
+func (p *xpathParser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	for _, op := range ops {
+		if t.text == op && (t.kind != 'n' || op == "and" || op == "or") && t.kind != 's' {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *xpathParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		if p.eof() {
+			return fmt.Errorf("expected %q at end", op)
+		}
+		return fmt.Errorf("expected %q, got %q", op, p.peek().text)
+	}
+	return nil
+}
+
+func (p *xpathParser) binary(next func() (xexpr, error), ops ...string) (xexpr, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op, left, right}
+	}
+}
+
+func (p *xpathParser) orExpr() (xexpr, error)  { return p.binary(p.andExpr, "or") }
+func (p *xpathParser) andExpr() (xexpr, error) { return p.binary(p.eqExpr, "and") }
+func (p *xpathParser) eqExpr() (xexpr, error)  { return p.binary(p.relExpr, "=", "!=") }
+func (p *xpathParser) relExpr() (xexpr, error) {
+	return p.binary(p.unionExpr, "<=", ">=", "<", ">")
+}
+func (p *xpathParser) unionExpr() (xexpr, error) { return p.binary(p.pathExpr, "|") }
+
+// pathExpr parses a location path, or a primary expression with optional
+// predicates and a trailing relative path
+func (p *xpathParser) pathExpr() (xexpr, error) {
+	t := p.peek()
+	isFunction := t.kind == 'n' && p.peekAt(1).kind == '(' && !isNodeType(t.text)
+	if t.kind != 's' && t.kind != '#' && t.kind != '(' && !isFunction {
+		return p.locationPath()
+	}
+
+	primary, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	f := &filterExpr{primary: primary}
+	for p.peek().kind == '[' {
+		pred, err := p.predicate()
+		if err != nil {
+			return nil, err
+		}
+		f.preds = append(f.preds, pred)
+	}
+	if op, ok := p.accept("/", "//"); ok {
+		rest, err := p.relativePath(op == "//")
+		if err != nil {
+			return nil, err
+		}
+		f.steps = rest
+	}
+	if len(f.preds) == 0 && len(f.steps) == 0 {
+		return primary, nil
+	}
+	return f, nil
+}
+
+func (p *xpathParser) primary() (xexpr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == 's':
+		p.pos++
+		return literal{t.text}, nil
+	case t.kind == '#':
+		p.pos++
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return number{f}, nil
+	case t.kind == '(':
+		p.pos++
+		expr, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	p.pos += 2 // Name and (
+	call := functionCall{name: t.text}
+	if _, ok := p.accept(")"); ok {
+		return call, call.check()
+	}
+	for {
+		arg, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return call, call.check()
+}
+
+func (p *xpathParser) locationPath() (xexpr, error) {
+	path := &locationPath{}
+	if op, ok := p.accept("/", "//"); ok {
+		path.absolute = true
+		if op == "/" && !p.startsStep() {
+			return path, nil // The root alone
+		}
+		steps, err := p.relativePath(op == "//")
+		if err != nil {
+			return nil, err
+		}
+		path.steps = steps
+		return path, nil
+	}
+	steps, err := p.relativePath(false)
+	if err != nil {
+		return nil, err
+	}
+	path.steps = steps
+	return path, nil
+}
+
+// startsStep reports whether the next token can begin a step
+func (p *xpathParser) startsStep() bool {
+	t := p.peek()
+	return t.kind == 'n' || t.text == "*" || t.text == "@" || t.text == "." || t.text == ".."
+}
+
+// relativePath parses steps separated by / or //. descendant prepends a
+// descendant-or-self::node() step for a leading //.
+func (p *xpathParser) relativePath(descendant bool) ([]step, error) {
+	var steps []step
+	for {
+		if descendant {
+			steps = append(steps, step{axis: "descendant-or-self", test: "node()"})
+		}
+		s, err := p.step()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+
+		op, ok := p.accept("/", "//")
+		if !ok {
+			return steps, nil
+		}
+		descendant = op == "//"
+	}
+}
+
+func (p *xpathParser) step() (step, error) {
+	if _, ok := p.accept(".."); ok {
+		return step{axis: "parent", test: "node()"}, nil
+	}
+	if _, ok := p.accept("."); ok {
+		return step{axis: "self", test: "node()"}, nil
+	}
+
+	s := step{axis: "child"}
+	if _, ok := p.accept("@"); ok {
+		s.axis = "attribute"
+	} else if t := p.peek(); t.kind == 'n' && p.peekAt(1).text == "::" {
+		if !validAxes[t.text] {
+			return s, fmt.Errorf("unsupported axis %s", t.text)
+		}
+		s.axis = t.text
+		p.pos += 2
+	}
+
+	t := p.peek()
+	switch {
+	case t.text == "*" && t.kind == '*':
+		p.pos++
+		s.test = "*"
+	case t.kind == 'n' && isNodeType(t.text) && p.peekAt(1).kind == '(':
+		p.pos += 2
+		if err := p.expect(")"); err != nil {
+			return s, err
+		}
+		s.test = t.text + "()"
+	case t.kind == 'n':
+		p.pos++
+		s.test = strings.ToLower(t.text)
+	default:
+		if p.eof() {
+			return s, fmt.Errorf("expected a step at end")
+		}
+		return s, fmt.Errorf("expected a step, got %q", t.text)
+	}
+
+	for p.peek().kind == '[' {
+		pred, err := p.predicate()
+		if err != nil {
+			return s, err
+		}
+		s.preds = append(s.preds, pred)
+	}
+	return s, nil
+}
+
+func (p *xpathParser) predicate() (xexpr, error) {
+	p.pos++ // [
+	expr, err := p.orExpr()
+	if err != nil {
+		return nil, err
+	}
+	return expr, p.expect("]")
+}
+
+func isNodeType(name string) bool {
+	return name == "text" || name == "node" || name == "comment"
+}
+
+var validAxes = map[string]bool{
+	"child": true, "descendant": true, "descendant-or-self": true, "parent": true,
+	"ancestor": true, "ancestor-or-self": true, "following-sibling": true,
+	"preceding-sibling": true, "self": true, "attribute": true,
+}
+
+// Expressions
+
+type literal struct{ s string }
+
+func (l literal) eval(xcontext) xvalue { return l.s }
+
+type number struct{ f float64 }
+
+func (n number) eval(xcontext) xvalue { return n.f }
+
+type step struct {
+	axis  string
+	test  string // Name, *, text(), node() or comment()
+	preds []xexpr
+}
+
+type locationPath struct {
+	absolute bool
+	steps    []step
+}
+
+func (l *locationPath) eval(ctx xcontext) xvalue {
+	start := ctx.node
+	if l.absolute {
+		root := ctx.node.n
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		start = xnode{n: root}
+	}
+	return applySteps([]xnode{start}, l.steps)
+}
+
+type filterExpr struct {
+	primary xexpr
+	preds   []xexpr
+	steps   []step
+}
+
+func (f *filterExpr) eval(ctx xcontext) xvalue {
+	nodes, ok := f.primary.eval(ctx).([]xnode)
+	if !ok {
+		return []xnode{}
+	}
+	for _, pred := range f.preds {
+		nodes = filter(nodes, pred)
+	}
+	return applySteps(nodes, f.steps)
+}
+
+// applySteps evaluates steps from each node, keeping every node once in
+// document order
+func applySteps(nodes []xnode, steps []step) []xnode {
+	for _, s := range steps {
+		var next []xnode
+		seen := make(map[xnode]bool)
+		for _, node := range nodes {
+			for _, candidate := range s.apply(node) {
+				if !seen[candidate] {
+					seen[candidate] = true
+					next = append(next, candidate)
+				}
+			}
+		}
+		nodes = documentOrder(next)
+	}
+	return nodes
+}
+
+// documentOrder sorts nodes as they appear in the document, attributes
+// right after their element
+func documentOrder(nodes []xnode) []xnode {
+	if len(nodes) < 2 {
+		return nodes
+	}
+	root := nodes[0].n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	order := make(map[*html.Node]int)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		order[n] = len(order)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	attrIndex := func(x xnode) int {
+		for i := range x.n.Attr {
+			if &x.n.Attr[i] == x.attr {
+				return i
+			}
+		}
+		return -1
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.n != b.n {
+			return order[a.n] < order[b.n]
+		}
+		return attrIndex(a) < attrIndex(b)
+	})
+	return nodes
+}
+
+// apply selects the nodes along the axis that pass the test and predicates
+func (s step) apply(ctx xnode) []xnode {
+	var candidates []xnode
+	add := func(n *html.Node) {
+		if s.matches(n) {
+			candidates = append(candidates, xnode{n: n})
+		}
+	}
+	n := ctx.n
+	if ctx.attr != nil {
+		// Attributes only have the parent and self axes
+		switch s.axis {
+		case "parent", "ancestor-or-self", "ancestor":
+			if s.axis == "ancestor-or-self" && s.test == "node()" {
+				candidates = append(candidates, ctx)
+			}
+			for p := n; p != nil && (p == n || s.axis != "parent"); p = p.Parent {
+				add(p)
+			}
+		case "self":
+			if s.test == "node()" || s.test == "*" || s.test == ctx.attr.Key {
+				candidates = append(candidates, ctx)
+			}
+		}
+		return filterAll(candidates, s.preds)
+	}
+
+	switch s.axis {
+	case "child":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			add(c)
+		}
+	case "descendant", "descendant-or-self":
+		if s.axis == "descendant-or-self" {
+			add(n)
+		}
+		var walk func(*html.Node)
+		walk = func(n *html.Node) {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				add(c)
+				walk(c)
+			}
+		}
+		walk(n)
+	case "parent":
+		if n.Parent != nil {
+			add(n.Parent)
+		}
+	case "ancestor", "ancestor-or-self":
+		if s.axis == "ancestor-or-self" {
+			add(n)
+		}
+		for p := n.Parent; p != nil; p = p.Parent {
+			add(p)
+		}
+	case "following-sibling":
+		for c := n.NextSibling; c != nil; c = c.NextSibling {
+			add(c)
+		}
+	case "preceding-sibling":
+		for c := n.PrevSibling; c != nil; c = c.PrevSibling {
+			add(c)
+		}
+	case "self":
+		add(n)
+	case "attribute":
+		if n.Type == html.ElementNode {
+			for i := range n.Attr {
+				if s.test == "*" || s.test == "node()" || s.test == n.Attr[i].Key {
+					candidates = append(candidates, xnode{n: n, attr: &n.Attr[i]})
+				}
+			}
+		}
+	}
+	return filterAll(candidates, s.preds)
+}
+
+// matches applies the node test to an HTML node
+func (s step) matches(n *html.Node) bool {
+	switch s.test {
+	case "node()":
+		return n.Type != html.DoctypeNode
+	case "text()":
+		return n.Type == html.TextNode
+	case "comment()":
+		return n.Type == html.CommentNode
+	case "*":
+		return n.Type == html.ElementNode
+	}
+	return n.Type == html.ElementNode && n.Data == s.test
+}
+
+func filterAll(nodes []xnode, preds []xexpr) []xnode {
+	for _, pred := range preds {
+		nodes = filter(nodes, pred)
+	}
+	return nodes
+}
+
+// filter keeps the nodes for which the predicate holds. A number predicate
+// selects by position.
+func filter(nodes []xnode, pred xexpr) []xnode {
+	var kept []xnode
+	for i, node := range nodes {
+		ctx := xcontext{node: node, pos: i + 1, size: len(nodes)}
+		v := pred.eval(ctx)
+		if f, ok := v.(float64); ok {
+			if f == float64(ctx.pos) {
+				kept = append(kept, node)
+			}
+		} else if toBool(v) {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+type binaryExpr struct {
+	op          string
+	left, right xexpr
+}
+
+func (b binaryExpr) eval(ctx xcontext) xvalue {
+	switch b.op {
+	case "or":
+		return toBool(b.left.eval(ctx)) || toBool(b.right.eval(ctx))
+	case "and":
+		return toBool(b.left.eval(ctx)) && toBool(b.right.eval(ctx))
+	case "|":
+		left, _ := b.left.eval(ctx).([]xnode)
+		right, _ := b.right.eval(ctx).([]xnode)
+		union := append([]xnode(nil), left...)
+		seen := make(map[xnode]bool, len(left))
+		for _, node := range left {
+			seen[node] = true
+		}
+		for _, node := range right {
+			if !seen[node] {
+				union = append(union, node)
+			}
+		}
+		return documentOrder(union)
+	}
+	return compare(b.op, b.left.eval(ctx), b.right.eval(ctx))
+}
+
+// compare implements the comparison operators; node sets compare true if
+// any of their nodes does
+func compare(op string, left, right xvalue) bool {
+	if nodes, ok := left.([]xnode); ok {
+		for _, node := range nodes {
+			if compare(op, node.stringValue(), right) {
+				return true
+			}
+		}
+		return false
+	}
+	if nodes, ok := right.([]xnode); ok {
+		for _, node := range nodes {
+			if compare(op, left, node.stringValue()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch op {
+	case "=", "!=":
+		var equal bool
+		switch {
+		case isBool(left) || isBool(right):
+			equal = toBool(left) == toBool(right)
+		case isNumber(left) || isNumber(right):
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		return equal == (op == "=")
+	}
+	l, r := toNumber(left), toNumber(right)
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+type functionCall struct {
+	name string
+	args []xexpr
+}
+
+// functionArity is the minimum and maximum arguments of each function, -1
+// for any number
+var functionArity = map[string][2]int{
+	"last": {0, 0}, "position": {0, 0}, "count": {1, 1}, "string": {0, 1},
+	"concat": {2, -1}, "contains": {2, 2}, "starts-with": {2, 2}, "ends-with": {2, 2},
+	"normalize-space": {0, 1}, "string-length": {0, 1}, "name": {0, 1}, "not": {1, 1},
+}
+
+func (f functionCall) check() error {
+	arity, ok := functionArity[f.name]
+	if !ok {
+		return fmt.Errorf("unsupported function %s()", f.name)
+	}
+	if len(f.args) < arity[0] || arity[1] >= 0 && len(f.args) > arity[1] {
+		return fmt.Errorf("wrong number of arguments to %s()", f.name)
+	}
+	return nil
+}
+
+func (f functionCall) eval(ctx xcontext) xvalue {
+	arg := func(i int) xvalue {
+		if i < len(f.args) {
+			return f.args[i].eval(ctx)
+		}
+		return []xnode{ctx.node} // Defaults to the context node
+	}
+	switch f.name {
+	case "last":
+		return float64(ctx.size)
+	case "position":
+		return float64(ctx.pos)
+	case "count":
+		nodes, _ := arg(0).([]xnode)
+		return float64(len(nodes))
+	case "string":
+		return toString(arg(0))
+	case "concat":
+		var sb strings.Builder
+		for i := range f.args {
+			sb.WriteString(toString(arg(i)))
+		}
+		return sb.String()
+	case "contains":
+		return strings.Contains(toString(arg(0)), toString(arg(1)))
+	case "starts-with":
+		return strings.HasPrefix(toString(arg(0)), toString(arg(1)))
+	case "ends-with":
+		return strings.HasSuffix(toString(arg(0)), toString(arg(1)))
+	case "normalize-space":
+		return strings.Join(strings.Fields(toString(arg(0))), " ")
+	case "string-length":
+		return float64(len([]rune(toString(arg(0)))))
+	case "name":
+		nodes, _ := arg(0).([]xnode)
+		if len(nodes) == 0 {
+			return ""
+		}
+		if nodes[0].attr != nil {
+			return nodes[0].attr.Key
+		}
+		if nodes[0].n.Type == html.ElementNode {
+			return nodes[0].n.Data
+		}
+		return ""
+	case "not":
+		return !toBool(arg(0))
+	}
+	return nil
+}
+
+// Conversions
+
+func isBool(v xvalue) bool   { _, ok := v.(bool); return ok }
+func isNumber(v xvalue) bool { _, ok := v.(float64); return ok }
+
+func toBool(v xvalue) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case []xnode:
+		return len(v) > 0
+	}
+	return false
+}
+
+func toNumber(v xvalue) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+func toString(v xvalue) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []xnode:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestXPathEvaluate(t *testing.T) {
+	doc := parseFixture(t)
+	tests := map[string]string{
+		"//li/a":                                      "One|Two|Three",
+		"//a/@href":                                   "/p/1|https://other.com/2|/p/3",
+		"//li[3]/a":                                   "Three",
+		"//li[last()]/@id":                            "li4",
+		"//li[position() > 2]/@id":                    "li3|li4",
+		"//li[@class='item']/@id":                     "li2|li4",
+		"//li[contains(@class, 'sale')]//span":        "-20%",
+		"//a[starts-with(@href, 'https')]":            "Two",
+		"//a[ends-with(@href, '/3')]/text()":          "Three",
+		"//a[not(@rel)]/@id":                          "a1|a3",
+		"//span/..":                                   "Three -20%",
+		"//span/ancestor::div/@id":                    "main",
+		"//h2/following-sibling::p[1]":                "Shipping is free.",
+		"//p[2]/preceding-sibling::*[1]/@id":          "p1",
+		"//h2 | //img/@alt":                           "Products|Logo",
+		"count(//li)":                                 "4",
+		"count(//li[a]) = 3":                          "true",
+		"string-length(//h2)":                         "8",
+		"concat(//h2, ': ', count(//a))":              "Products: 3",
+		"normalize-space('  a   b ')":                 "a b",
+		"name(//*[@id='list'])":                       "ul",
+		"//li[a = 'Two' or @id = 'li4']/@id":          "li2|li4",
+		"//li[a and span]/@id":                        "li3",
+		"//p[not(@title = 'note')]/@id":               "p1",
+		"//p[@title != 'note']/@id":                   "", // No title compares false
+		"//*[@id='main']/descendant-or-self::div/@id": "main",
+		"//p/self::p[. = 'Returns within 30 days.']":  "Returns within 30 days.",
+	}
+	for expr, want := range tests {
+		x, err := CompileXPath(expr)
+		if err != nil {
+			t.Errorf("CompileXPath(%q) = %v", expr, err)
+			continue
+		}
+		if got := strings.Join(x.Evaluate(doc), "|"); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestXPathSelect(t *testing.T) {
+	doc := parseFixture(t)
+	x, err := CompileXPath("//li[a] | //a/@href")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Attributes aren't elements
+	if got := ids(x.Select(doc)); got != "li1 li2 li3" {
+		t.Fatalf("Select() = %q", got)
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	for _, expr := range []string{"", "//", "//li[", "//li]", "unknown(1)", "count()", "//a/@", "'unterminated", "//li foo"} {
+		if _, err := CompileXPath(expr); err == nil {
+			t.Errorf("CompileXPath(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// bufferSizes are the capacities of the pooled read buffers, smallest first
+var bufferSizes = [...]int{4 << 10, 32 << 10, 128 << 10, 1 << 20}
+
+// bufferPool keeps the buffers bodies are read into. A body is read into a
+// pooled buffer, moving to a larger one as it grows, and copied out once at
+// its exact size, so reading allocates nothing but the body itself. Bodies
+// larger than the largest size are read into buffers that aren't pooled.
+type bufferPool struct {
+	pools     [len(bufferSizes)]sync.Pool // Of *[]byte, one per size
+	gets      int64
+	allocated int64 // Buffers the pools had to create
+	oversized int64 // Bodies that outgrew the pooled buffers
+}
+
+// bodyBuffers is shared by every fetcher, so buffers are reused across crawls
+var bodyBuffers = newBufferPool()
+
+// newBufferPool creates an empty pool
+func newBufferPool() *bufferPool {
+	p := &bufferPool{}
+	for i, size := range bufferSizes {
+		p.pools[i].New = func() interface{} {
+			atomic.AddInt64(&p.allocated, 1)
+			buf := make([]byte, 0, size)
+			return &buf
+		}
+	}
+	return p
+}
+
+// get returns an empty buffer of the smallest size holding sizeHint bytes,
+// the largest size for larger hints
+func (p *bufferPool) get(sizeHint int64) []byte {
+	i := 0
+	for i < len(bufferSizes)-1 && int64(bufferSizes[i]) < sizeHint {
+		i++
+	}
+	atomic.AddInt64(&p.gets, 1)
+	return (*p.pools[i].Get().(*[]byte))[:0]
+}
+
+// put returns a buffer to the pool of its size, dropping buffers of other sizes
+func (p *bufferPool) put(buf []byte) {
+	for i, size := range bufferSizes {
+		if cap(buf) == size {
+			buf = buf[:0]
+			p.pools[i].Put(&buf)
+			return
+		}
+	}
+}
+
+// grow returns buf with its contents in a buffer of the next size, or twice
+// its capacity once past the largest size. buf is returned to the pool.
+func (p *bufferPool) grow(buf []byte) []byte {
+	var next []byte
+	if cap(buf) < bufferSizes[len(bufferSizes)-1] {
+		next = p.get(int64(cap(buf)) + 1)
+	} else {
+		if cap(buf) == bufferSizes[len(bufferSizes)-1] {
+			atomic.AddInt64(&p.oversized, 1)
+		}
+		next = make([]byte, 0, 2*cap(buf))
+	}
+	next = append(next, buf...)
+	p.put(buf)
+	return next
+}
+
+// read reads r into pooled buffers and returns a copy of exactly the bytes
+// read. sizeHint is the expected size, e.g. the Content-Length, or <= 0 if
+// unknown. Reading fails instead of truncating bodies larger than maxSize;
+// maxSize <= 0 disables the limit.
+func (p *bufferPool) read(r io.Reader, maxSize, sizeHint int64) ([]byte, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+		sizeHint = min(sizeHint, maxSize)
+	}
+	buf := p.get(sizeHint)
+	defer func() { p.put(buf) }()
+
+	for {
+		if len(buf) == cap(buf) {
+			buf = p.grow(buf)
+		}
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if maxSize > 0 && int64(len(buf)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, maxSize)
+	}
+	body := make([]byte, len(buf))
+	copy(body, buf)
+	return body, nil
+}
+
+// GetStats returns how many read buffers were taken from the pool and how
+// many of them it had to allocate, and the bodies too large for them
+func (p *bufferPool) GetStats() map[string]int64 {
+	return map[string]int64{
+		"bufferGets":       atomic.LoadInt64(&p.gets),
+		"buffersAllocated": atomic.LoadInt64(&p.allocated),
+		"buffersOversized": atomic.LoadInt64(&p.oversized),
+	}
+}
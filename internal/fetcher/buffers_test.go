@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBufferPoolRead(t *testing.T) {
+	p := newBufferPool()
+	for _, size := range []int{0, 100, 4 << 10, 40 << 10, 2 << 20} {
+		want := bytes.Repeat([]byte("x"), size)
+		// One byte per read, so the body grows through every buffer size
+		got, err := p.read(iotest.OneByteReader(bytes.NewReader(want)), 0, 0)
+		if err != nil {
+			t.Fatalf("read(%d bytes): %v", size, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("read(%d bytes) returned %d bytes", size, len(got))
+		}
+		if cap(got) != size {
+			t.Errorf("read(%d bytes) returned capacity %d, want the exact size", size, cap(got))
+		}
+	}
+	if got := p.GetStats()["buffersOversized"]; got != 1 {
+		t.Errorf("buffersOversized = %d, want 1", got)
+	}
+
+	if _, err := p.read(bytes.NewReader(make([]byte, 101)), 100, 101); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("read over maxSize: err = %v, want ErrBodyTooLarge", err)
+	}
+	if got, err := p.read(bytes.NewReader(make([]byte, 100)), 100, -1); err != nil || len(got) != 100 {
+		t.Errorf("read at maxSize = %d bytes, %v", len(got), err)
+	}
+}
+
+func TestBufferPoolGetPut(t *testing.T) {
+	p := newBufferPool()
+	for _, tt := range []struct {
+		hint int64
+		want int
+	}{
+		{-1, 4 << 10},
+		{4 << 10, 4 << 10},
+		{4<<10 + 1, 32 << 10},
+		{200 << 10, 1 << 20},
+		{1 << 30, 1 << 20},
+	} {
+		if buf := p.get(tt.hint); cap(buf) != tt.want || len(buf) != 0 {
+			t.Errorf("get(%d) = len %d cap %d, want empty with cap %d", tt.hint, len(buf), cap(buf), tt.want)
+		}
+	}
+
+	if got := p.grow(append(p.get(0), "data"...)); string(got) != "data" || cap(got) != 32<<10 {
+		t.Errorf("grow() = %q with cap %d, want the data with cap %d", got, cap(got), 32<<10)
+	}
+	// Buffers of other sizes aren't pooled
+	p.put(make([]byte, 10))
+	if buf := p.get(0); cap(buf) != 4<<10 {
+		t.Errorf("get() after putting an odd size returned cap %d", cap(buf))
+	}
+}
+
+func BenchmarkReadBody(b *testing.B) {
+	body := bytes.Repeat([]byte("<p>page</p>"), 20000)
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			readBody(bytes.NewReader(body), 0, -1)
+		}
+	})
+	b.Run("ReadAll", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			io.ReadAll(bytes.NewReader(body))
+		}
+	})
+}
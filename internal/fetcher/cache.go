@@ -0,0 +1,132 @@
+package fetcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// cacheEntry is the on-disk representation of a cached response
+type cacheEntry struct {
+	StoredAt time.Time `json:"stored_at"`
+	Response *Response `json:"response"`
+}
+
+// ResponseCache stores responses on disk so repeated development crawls replay
+// them instead of hitting live sites
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+
+	hits    int64
+	misses  int64
+	expired int64
+	stores  int64
+}
+
+// NewResponseCache creates the cache directory. Returns nil when caching is disabled.
+func NewResponseCache(cfg config.CacheConfig) (*ResponseCache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &ResponseCache{dir: cfg.Dir, ttl: cfg.TTL}, nil
+}
+
+// key derives the cache file name from the URL and the request validators
+func (c *ResponseCache) key(rawURL string, validators *Validators) string {
+	h := sha256.New()
+	h.Write([]byte(rawURL))
+	if validators != nil {
+		h.Write([]byte("\n" + validators.ETag + "\n" + validators.LastModified))
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	// Two-level fan-out keeps directories small
+	return filepath.Join(c.dir, sum[:2], sum+".json")
+}
+
+// Get returns a cached response that hasn't outlived the TTL
+func (c *ResponseCache) Get(rawURL string, validators *Validators) (*Response, bool) {
+	data, err := os.ReadFile(c.key(rawURL, validators))
+	if err != nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(entry.StoredAt) > c.ttl {
+		atomic.AddInt64(&c.expired, 1)
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	entry.Response.Cached = true
+	return entry.Response, true
+}
+
+// Cacheable reports whether a response may be replayed later: only successful
+// answers and 304s are, so errors and throttling are always retried live
+func Cacheable(resp *Response) bool {
+	return resp != nil && (resp.StatusCode >= 200 && resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified)
+}
+
+// Put stores a cacheable response, writing to a temp file first so readers
+// never see partial entries. Other responses are ignored.
+func (c *ResponseCache) Put(rawURL string, validators *Validators, resp *Response) error {
+	if !Cacheable(resp) {
+		return nil
+	}
+	path := c.key(rawURL, validators)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Response: resp})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+
+	atomic.AddInt64(&c.stores, 1)
+	return nil
+}
+
+// GetStats returns cache hit/miss statistics
+func (c *ResponseCache) GetStats() map[string]int64 {
+	return map[string]int64{
+		"cacheHits":    atomic.LoadInt64(&c.hits),
+		"cacheMisses":  atomic.LoadInt64(&c.misses),
+		"cacheExpired": atomic.LoadInt64(&c.expired),
+		"cacheStores":  atomic.LoadInt64(&c.stores),
+	}
+}
@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// newCachingFetcher returns a fetcher with the response cache in a temp dir
+func newCachingFetcher(t *testing.T, ttl time.Duration) *Fetcher {
+	t.Helper()
+	cfg := config.DefaultConfig().HTTP
+	cfg.Cache = config.CacheConfig{Enabled: true, Dir: t.TempDir(), TTL: ttl}
+	cfg.Retry = config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryStatuses: []int{503}}
+	f, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestResponseCacheReplaysSuccess(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html>ok</html>")
+	}))
+	defer server.Close()
+
+	f := newCachingFetcher(t, 0)
+	for i := 0; i < 2; i++ {
+		resp, err := f.Fetch(context.Background(), server.URL+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Cached != (i == 1) || string(resp.Body) != "<html>ok</html>" {
+			t.Fatalf("fetch %d: Cached = %v, body %q", i, resp.Cached, resp.Body)
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Fatalf("server saw %d requests, want 1", n)
+	}
+}
+
+func TestResponseCacheSkipsErrors(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := newCachingFetcher(t, 0)
+	for i := 0; i < 2; i++ {
+		resp, err := f.Fetch(context.Background(), server.URL+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Cached {
+			t.Fatal("404 was replayed from the cache")
+		}
+	}
+	if n := atomic.LoadInt64(&requests); n != 2 {
+		t.Fatalf("server saw %d requests, want 2", n)
+	}
+	if stores := f.cache.GetStats()["cacheStores"]; stores != 0 {
+		t.Fatalf("cached %d error responses", stores)
+	}
+}
+
+func TestRetriesBypassCache(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html>ok</html>")
+	}))
+	defer server.Close()
+
+	f := newCachingFetcher(t, 0)
+	resp, err := f.FetchWithRetry(context.Background(), server.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Cached {
+		t.Fatalf("FetchWithRetry() = %d, cached %v", resp.StatusCode, resp.Cached)
+	}
+	stats := f.cache.GetStats()
+	if stats["cacheMisses"] != 1 || stats["cacheStores"] != 1 {
+		t.Fatalf("cache stats = %v, want one lookup and one store", stats)
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	c, err := NewResponseCache(config.CacheConfig{Enabled: true, Dir: t.TempDir(), TTL: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &Response{URL: "https://example.com/", StatusCode: http.StatusNotModified}
+	if err := c.Put(resp.URL, &Validators{ETag: `"v1"`}, resp); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get(resp.URL, &Validators{ETag: `"v1"`}); ok {
+		t.Fatal("expired entry was replayed")
+	}
+	if stats := c.GetStats(); stats["cacheStores"] != 1 || stats["cacheExpired"] != 1 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
@@ -0,0 +1,76 @@
+package fetcher
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"strings"
+)
+
+var (
+	// ErrContentType is returned when the response Content-Type is not in the allowlist
+	ErrContentType = errors.New("content type not allowed")
+	// ErrBodyTooLarge is returned when the response body exceeds the configured maximum size
+	ErrBodyTooLarge = errors.New("response body too large")
+)
+
+// mediaType returns the lowercased media type of a Content-Type header without parameters
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// IsHTML reports whether a Content-Type header denotes an HTML document
+func IsHTML(contentType string) bool {
+	switch mediaType(contentType) {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+	return false
+}
+
+// contentTypeAllowed checks a Content-Type header against the allowlist.
+// Entries may be exact media types or wildcards such as "text/*". A missing
+// Content-Type is allowed so the body can be sniffed later.
+func contentTypeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 || contentType == "" {
+		return true
+	}
+
+	mt := mediaType(contentType)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == "*/*" || a == mt {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mt, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sizeLimitFor returns the size limit of the most specific maxSizes entry
+// matching contentType, and false if no entry matches
+func sizeLimitFor(contentType string, maxSizes map[string]int64) (int64, bool) {
+	mt := mediaType(contentType)
+	if limit, ok := maxSizes[mt]; ok {
+		return limit, true
+	}
+	if major, _, ok := strings.Cut(mt, "/"); ok {
+		if limit, ok := maxSizes[major+"/*"]; ok {
+			return limit, true
+		}
+	}
+	limit, ok := maxSizes["*/*"]
+	return limit, ok
+}
+
+// readBody reads at most maxSize bytes and fails instead of truncating larger bodies.
+// maxSize <= 0 disables the limit. sizeHint is the expected size, <= 0 if unknown.
+func readBody(r io.Reader, maxSize, sizeHint int64) ([]byte, error) {
+	return bodyBuffers.read(r, maxSize, sizeHint)
+}
@@ -0,0 +1,467 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"web-crawler/internal/config"
+)
+
+// upstream is a DNS server queried over UDP, TCP or HTTPS (DoH)
+type upstream struct {
+	network string // udp, tcp or https
+	addr    string // host:port, or the URL of a DoH server
+}
+
+// parseUpstream parses host[:port], udp://host[:port], tcp://host[:port] or
+// an https:// DoH URL. Port 53 is the default.
+func parseUpstream(server string) (upstream, error) {
+	if strings.HasPrefix(server, "https://") {
+		return upstream{network: "https", addr: server}, nil
+	}
+	network := "udp"
+	if scheme, rest, ok := strings.Cut(server, "://"); ok {
+		if scheme != "udp" && scheme != "tcp" {
+			return upstream{}, fmt.Errorf("unsupported DNS server scheme %q", scheme)
+		}
+		network, server = scheme, rest
+	}
+	if server == "" {
+		return upstream{}, errors.New("empty DNS server address")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return upstream{network: network, addr: server}, nil
+}
+
+// dnsEntry is a cached lookup. ready is closed once the lookup finished, so
+// concurrent lookups of a host wait for the first.
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+	ready   chan struct{}
+}
+
+// Resolver resolves host names for the fetcher's dialer. Answers are cached
+// for their TTL, failures for the negative TTL. Without upstream servers it
+// asks the system resolver, which doesn't report TTLs.
+type Resolver struct {
+	cfg       config.DNSConfig
+	upstreams []upstream
+	doh       *http.Client
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+
+	failMu   sync.Mutex
+	failures map[string]int64 // Failed lookups per host
+
+	// Counters
+	lookups int64
+	hits    int64
+	errors  int64
+}
+
+// NewResolver creates a resolver with the configured upstream servers
+func NewResolver(cfg config.DNSConfig) (*Resolver, error) {
+	r := &Resolver{
+		cfg:      cfg,
+		doh:      &http.Client{Timeout: cfg.Timeout},
+		now:      time.Now,
+		entries:  make(map[string]*dnsEntry),
+		failures: make(map[string]int64),
+	}
+	for _, server := range cfg.Servers {
+		up, err := parseUpstream(server)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DNS server %q: %w", server, err)
+		}
+		r.upstreams = append(r.upstreams, up)
+	}
+	return r, nil
+}
+
+// DialContext returns a dial function that resolves host names with r and
+// tries every address of a host in turn
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, address)
+		}
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, addr := range addrs {
+			ip := net.ParseIP(addr)
+			if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if lastErr == nil {
+			lastErr = &net.DNSError{Err: "no suitable address", Name: host}
+		}
+		return nil, lastErr
+	}
+}
+
+// LookupHost returns the addresses of host, IPv4 first, from the cache if
+// they haven't expired
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	atomic.AddInt64(&r.lookups, 1)
+	if !r.cfg.Cache {
+		addrs, _, err := r.resolve(ctx, host)
+		r.observe(host, err)
+		return addrs, err
+	}
+
+	r.mu.Lock()
+	entry, ok := r.entries[host]
+	if ok {
+		select {
+		case <-entry.ready:
+			ok = r.now().Before(entry.expires)
+		default:
+			// Another worker is resolving host
+			r.mu.Unlock()
+			select {
+			case <-entry.ready:
+				atomic.AddInt64(&r.hits, 1)
+				return entry.addrs, entry.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	if ok {
+		r.mu.Unlock()
+		atomic.AddInt64(&r.hits, 1)
+		return entry.addrs, entry.err
+	}
+	entry = &dnsEntry{ready: make(chan struct{})}
+	r.entries[host] = entry
+	r.mu.Unlock()
+
+	addrs, ttl, err := r.resolve(ctx, host)
+	r.observe(host, err)
+	entry.addrs, entry.err = addrs, err
+	switch {
+	case ctx.Err() != nil:
+		// Nothing learned about host, let the next lookup try again
+	case err != nil:
+		entry.expires = r.now().Add(r.cfg.NegativeTTL)
+	default:
+		entry.expires = r.now().Add(r.clampTTL(ttl))
+	}
+	close(entry.ready)
+	return addrs, err
+}
+
+// observe counts a failed lookup of host. Cancelled lookups don't count.
+func (r *Resolver) observe(host string, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	atomic.AddInt64(&r.errors, 1)
+	r.failMu.Lock()
+	r.failures[host]++
+	r.failMu.Unlock()
+}
+
+// clampTTL keeps a record TTL between the configured minimum and maximum
+func (r *Resolver) clampTTL(ttl time.Duration) time.Duration {
+	if ttl < r.cfg.MinTTL {
+		ttl = r.cfg.MinTTL
+	}
+	if r.cfg.MaxTTL > 0 && ttl > r.cfg.MaxTTL {
+		ttl = r.cfg.MaxTTL
+	}
+	return ttl
+}
+
+// resolve looks host up with the upstream servers in order, or the system
+// resolver if there are none
+func (r *Resolver) resolve(ctx context.Context, host string) ([]string, time.Duration, error) {
+	if len(r.upstreams) == 0 {
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, 0, err
+		}
+		return sortAddrs(ips), r.cfg.DefaultTTL, nil
+	}
+
+	var lastErr error
+	for _, up := range r.upstreams {
+		addrs, ttl, err := r.query(ctx, up, host)
+		if err == nil {
+			return addrs, ttl, nil
+		}
+		lastErr = err
+		var dnsErr *net.DNSError
+		if ctx.Err() != nil || (errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+			break // An authoritative answer, no use asking the next server
+		}
+	}
+	return nil, 0, lastErr
+}
+
+// sortAddrs returns the addresses of ips, IPv4 first
+func sortAddrs(ips []net.IPAddr) []string {
+	var v4, v6 []string
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip.IP.String())
+		} else {
+			v6 = append(v6, ip.IP.String())
+		}
+	}
+	return append(v4, v6...)
+}
+
+// query asks up for the A and AAAA records of host. The TTL is the lowest of
+// the address records.
+func (r *Resolver) query(ctx context.Context, up upstream, host string) ([]string, time.Duration, error) {
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	type answer struct {
+		ips []net.IPAddr
+		ttl time.Duration
+		err error
+	}
+	answers := make([]answer, 2)
+	var wg sync.WaitGroup
+	for i, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := &answers[i]
+			a.ips, a.ttl, a.err = r.exchange(ctx, up, host, qtype)
+		}()
+	}
+	wg.Wait()
+
+	// Like net.Resolver, the addresses of one family are enough when the
+	// query for the other fails
+	var ips []net.IPAddr
+	var ttl time.Duration
+	var err error
+	for _, a := range answers {
+		if a.err != nil {
+			if err == nil {
+				err = a.err
+			}
+			continue
+		}
+		if len(a.ips) > 0 && (ttl == 0 || a.ttl < ttl) {
+			ttl = a.ttl
+		}
+		ips = append(ips, a.ips...)
+	}
+	switch {
+	case len(ips) > 0:
+		return sortAddrs(ips), ttl, nil
+	case err != nil:
+		return nil, 0, err
+	}
+	return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: up.addr, IsNotFound: true}
+}
+
+// exchange sends one question to up and parses the answer
+func (r *Resolver) exchange(ctx context.Context, up upstream, host string, qtype dnsmessage.Type) ([]net.IPAddr, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: "invalid host name", Name: host}
+	}
+	id := uint16(rand.Intn(1 << 16))
+	if up.network == "https" {
+		id = 0 // RFC 8484 asks for 0 so answers can be cached by HTTP caches
+	}
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to encode DNS query: %w", err)
+	}
+
+	var resp []byte
+	switch up.network {
+	case "https":
+		resp, err = r.exchangeHTTPS(ctx, up.addr, query)
+	case "tcp":
+		resp, err = exchangeTCP(ctx, up.addr, query)
+	default:
+		resp, err = exchangeUDP(ctx, up.addr, query)
+	}
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: host, Server: up.addr, IsTimeout: ctx.Err() != nil}
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(resp); err != nil {
+		return nil, 0, &net.DNSError{Err: "invalid DNS answer: " + err.Error(), Name: host, Server: up.addr}
+	}
+	if answer.Header.ID != id || !answer.Header.Response {
+		return nil, 0, &net.DNSError{Err: "DNS answer doesn't match the query", Name: host, Server: up.addr}
+	}
+	if answer.Header.Truncated && up.network == "udp" {
+		return r.exchange(ctx, upstream{network: "tcp", addr: up.addr}, host, qtype)
+	}
+	switch answer.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, 0, &net.DNSError{Err: "no such host", Name: host, Server: up.addr, IsNotFound: true}
+	default:
+		return nil, 0, &net.DNSError{Err: "server answered " + answer.Header.RCode.String(), Name: host, Server: up.addr, IsTemporary: true}
+	}
+
+	var ips []net.IPAddr
+	var ttl time.Duration
+	for _, rr := range answer.Answers {
+		var ip net.IP
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			ip = net.IP(body.A[:])
+		case *dnsmessage.AAAAResource:
+			ip = net.IP(body.AAAA[:])
+		default:
+			continue // CNAMEs lead to the address records in the same answer
+		}
+		if d := time.Duration(rr.Header.TTL) * time.Second; len(ips) == 0 || d < ttl {
+			ttl = d
+		}
+		ips = append(ips, net.IPAddr{IP: ip})
+	}
+	return ips, ttl, nil
+}
+
+// exchangeUDP sends query in one datagram and reads the answer
+func exchangeUDP(ctx context.Context, addr string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// exchangeTCP sends query with the two byte length prefix of DNS over TCP
+func exchangeTCP(ctx context.Context, addr string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(query)))); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// exchangeHTTPS posts query to a DoH server (RFC 8484)
+func (r *Resolver) exchangeHTTPS(ctx context.Context, url string, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.doh.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+// Failures returns the number of failed lookups per host
+func (r *Resolver) Failures() map[string]int64 {
+	r.failMu.Lock()
+	defer r.failMu.Unlock()
+
+	failures := make(map[string]int64, len(r.failures))
+	for host, n := range r.failures {
+		failures[host] = n
+	}
+	return failures
+}
+
+// GetStats returns DNS statistics for monitoring, with the failed lookups of
+// every host under dnsFailures.<host>
+func (r *Resolver) GetStats() map[string]int64 {
+	stats := map[string]int64{
+		"dnsLookups":   atomic.LoadInt64(&r.lookups),
+		"dnsCacheHits": atomic.LoadInt64(&r.hits),
+		"dnsErrors":    atomic.LoadInt64(&r.errors),
+	}
+	for host, n := range r.Failures() {
+		stats["dnsFailures."+host] = n
+	}
+	return stats
+}
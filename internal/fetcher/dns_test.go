@@ -0,0 +1,306 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"web-crawler/internal/config"
+)
+
+// fakeZone answers queries for example.com with one A and one AAAA record,
+// and NXDOMAIN for every other name
+func fakeZone(query []byte, truncate bool) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil
+	}
+	q := msg.Questions[0]
+	msg.Header.Response = true
+	msg.Header.RecursionAvailable = true
+	switch {
+	case truncate:
+		msg.Header.Truncated = true
+	case q.Name.String() != "example.com.":
+		msg.Header.RCode = dnsmessage.RCodeNameError
+	case q.Type == dnsmessage.TypeA:
+		msg.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 300},
+			Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+		}}
+	case q.Type == dnsmessage.TypeAAAA:
+		msg.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+			Body:   &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}},
+		}}
+	}
+	resp, _ := msg.Pack()
+	return resp
+}
+
+// newUDPServer serves fakeZone over UDP and counts the queries
+func newUDPServer(t *testing.T, truncate bool) (string, *int64) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var queries int64
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&queries, 1)
+			conn.WriteTo(fakeZone(buf[:n], truncate), addr)
+		}
+	}()
+	return conn.LocalAddr().String(), &queries
+}
+
+// newTCPServer serves fakeZone over TCP on addr, the port of a UDP server
+func newTCPServer(t *testing.T, addr string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("port of the UDP server is taken for TCP: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					query, err := exchangeFrame(conn)
+					if err != nil {
+						return
+					}
+					resp := fakeZone(query, false)
+					conn.Write(append([]byte{byte(len(resp) >> 8), byte(len(resp))}, resp...))
+				}
+			}()
+		}
+	}()
+}
+
+// exchangeFrame reads one length-prefixed DNS message
+func exchangeFrame(conn net.Conn) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, int(size[0])<<8|int(size[1]))
+	_, err := io.ReadFull(conn, msg)
+	return msg, err
+}
+
+func dnsConfig(servers ...string) config.DNSConfig {
+	return config.DNSConfig{
+		Cache:       true,
+		Servers:     servers,
+		MinTTL:      10 * time.Second,
+		MaxTTL:      time.Hour,
+		NegativeTTL: 30 * time.Second,
+		Timeout:     2 * time.Second,
+	}
+}
+
+func TestResolverCachesForTTL(t *testing.T) {
+	addr, queries := newUDPServer(t, false)
+	r, err := NewResolver(dnsConfig(addr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	addrs, err := r.LookupHost(context.Background(), "Example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(addrs, " ") != "127.0.0.1 ::1" || atomic.LoadInt64(queries) != 2 {
+		t.Fatalf("LookupHost() = %v after %d queries", addrs, atomic.LoadInt64(queries))
+	}
+
+	// The lowest record TTL applies
+	now = now.Add(59 * time.Second)
+	r.LookupHost(context.Background(), "example.com")
+	if n := atomic.LoadInt64(queries); n != 2 {
+		t.Fatalf("cached lookup sent %d queries", n-2)
+	}
+	now = now.Add(time.Second)
+	r.LookupHost(context.Background(), "example.com")
+	if n := atomic.LoadInt64(queries); n != 4 {
+		t.Fatalf("expired lookup sent %d queries, want 2", n-2)
+	}
+
+	// Failures are cached for the negative TTL and counted per host
+	for i := 0; i < 2; i++ {
+		_, err := r.LookupHost(context.Background(), "missing.com")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			t.Fatalf("LookupHost(missing.com) = %v, want not found", err)
+		}
+	}
+	if n := atomic.LoadInt64(queries); n != 6 {
+		t.Fatalf("negative answer cached after %d queries, want 6", n)
+	}
+	stats := r.GetStats()
+	if stats["dnsLookups"] != 5 || stats["dnsCacheHits"] != 2 || stats["dnsErrors"] != 1 || stats["dnsFailures.missing.com"] != 1 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestResolverWithoutCache(t *testing.T) {
+	addr, queries := newUDPServer(t, false)
+	cfg := dnsConfig(addr)
+	cfg.Cache = false
+	r, _ := NewResolver(cfg)
+
+	r.LookupHost(context.Background(), "example.com")
+	r.LookupHost(context.Background(), "example.com")
+	if n := atomic.LoadInt64(queries); n != 4 {
+		t.Fatalf("uncached lookups sent %d queries, want 4", n)
+	}
+}
+
+func TestResolverTruncatedFallsBackToTCP(t *testing.T) {
+	addr, _ := newUDPServer(t, true)
+	newTCPServer(t, addr)
+	r, _ := NewResolver(dnsConfig(addr))
+
+	addrs, err := r.LookupHost(context.Background(), "example.com")
+	if err != nil || len(addrs) != 2 {
+		t.Fatalf("LookupHost() = %v, %v", addrs, err)
+	}
+}
+
+func TestResolverDoH(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(fakeZone(query, false))
+	}))
+	defer srv.Close()
+
+	// parseUpstream only takes https URLs as DoH servers
+	r, _ := NewResolver(dnsConfig())
+	r.upstreams = []upstream{{network: "https", addr: srv.URL}}
+	addrs, err := r.LookupHost(context.Background(), "example.com")
+	if err != nil || strings.Join(addrs, " ") != "127.0.0.1 ::1" {
+		t.Fatalf("LookupHost() = %v, %v", addrs, err)
+	}
+}
+
+func TestResolverOneFamilyFails(t *testing.T) {
+	// The server fails the AAAA query, and both queries of failboth.com
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := io.ReadAll(r.Body)
+		var msg dnsmessage.Message
+		if err := msg.Unpack(query); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		resp := fakeZone(query, false)
+		if q := msg.Questions[0]; q.Type == dnsmessage.TypeAAAA || q.Name.String() == "failboth.com." {
+			msg.Header.Response = true
+			msg.Header.RCode = dnsmessage.RCodeServerFailure
+			resp, _ = msg.Pack()
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(resp)
+	}))
+	defer srv.Close()
+
+	r, _ := NewResolver(dnsConfig())
+	r.upstreams = []upstream{{network: "https", addr: srv.URL}}
+	addrs, err := r.LookupHost(context.Background(), "example.com")
+	if err != nil || strings.Join(addrs, " ") != "127.0.0.1" {
+		t.Fatalf("LookupHost() = %v, %v, want the A record", addrs, err)
+	}
+
+	var dnsErr *net.DNSError
+	if _, err := r.LookupHost(context.Background(), "failboth.com"); !errors.As(err, &dnsErr) || dnsErr.IsNotFound {
+		t.Fatalf("LookupHost() with both queries failing = %v", err)
+	}
+}
+
+func TestResolverFallsBackToNextServer(t *testing.T) {
+	// Nothing listens on the first server, so its query fails
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead.Close()
+	addr, _ := newUDPServer(t, false)
+
+	cfg := dnsConfig(dead.LocalAddr().String(), "udp://"+addr)
+	cfg.Timeout = 200 * time.Millisecond
+	r, _ := NewResolver(cfg)
+	if addrs, err := r.LookupHost(context.Background(), "example.com"); err != nil || len(addrs) != 2 {
+		t.Fatalf("LookupHost() = %v, %v", addrs, err)
+	}
+}
+
+func TestResolverDial(t *testing.T) {
+	addr, _ := newUDPServer(t, false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+
+	r, _ := NewResolver(dnsConfig(addr))
+	client := &http.Client{Transport: &http.Transport{DialContext: r.DialContext(&net.Dialer{})}}
+	// ::1 is tried after 127.0.0.1 answered
+	resp, err := client.Get("http://example.com:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf("body = %q", body)
+	}
+}
+
+func TestParseUpstream(t *testing.T) {
+	tests := map[string]upstream{
+		"1.1.1.1":                              {"udp", "1.1.1.1:53"},
+		"udp://9.9.9.9:5353":                   {"udp", "9.9.9.9:5353"},
+		"tcp://8.8.8.8":                        {"tcp", "8.8.8.8:53"},
+		"[2606:4700::1111]":                    {"udp", "[2606:4700::1111]:53"},
+		"https://cloudflare-dns.com/dns-query": {"https", "https://cloudflare-dns.com/dns-query"},
+	}
+	for server, want := range tests {
+		if got, err := parseUpstream(server); err != nil || got != want {
+			t.Errorf("parseUpstream(%q) = %+v, %v, want %+v", server, got, err, want)
+		}
+	}
+	for _, server := range []string{"", "tls://1.1.1.1", "udp://"} {
+		if _, err := parseUpstream(server); err == nil {
+			t.Errorf("parseUpstream(%q) succeeded", server)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+)
+
+// log is the logger of the fetcher package
+var log = logger.For("fetcher")
+
+// Validators are the cache validators from a previous crawl of a URL
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// RedirectHop is one redirect response followed while fetching a URL
+type RedirectHop struct {
+	URL        string
+	StatusCode int
+}
+
+// Response is the result of fetching a single URL
+type Response struct {
+	RequestedURL string
+	URL          string        // Final URL after redirects
+	Redirects    []RedirectHop // Redirect responses in the order they were followed
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	ContentType  string // Declared Content-Type, or the detected one when the body is mislabeled
+	DeclaredType string // Content-Type header, only set while sniffing
+	DetectedType string // Media type detected from the body, only set while sniffing
+	ETag         string
+	LastModified string
+	NotModified  bool // Server answered 304 to a conditional request
+	Rendered     bool // Body is the DOM serialized by the headless browser
+	Cached       bool // Replayed from the response cache
+	Latency      time.Duration
+	Raw          *RawExchange // Exact request and response, only while capturing raw responses
+}
+
+// Fetcher performs HTTP requests with the crawler's client settings
+type Fetcher struct {
+	client   *http.Client
+	cfg      config.HTTPConfig
+	proxies  *ProxyPool
+	renderer *Renderer
+	cache    *ResponseCache
+	retry    *RetryPolicy
+	resolver *Resolver // Nil without DNS caching or upstream servers
+	conns    *connCounter
+	tls      *TLSRecorder // Nil unless certificates are captured or some domains are insecure
+
+	deadLetters queue.DeadLetterStore
+	captureRaw  bool
+	headers     func(host string) map[string]string // Extra request headers, nil for none
+
+	// Counters for skipped downloads
+	rejectedType int64
+	tooLarge     int64
+	rawCaptured  int64
+	mislabeled   int64 // Bodies parsed as their detected type
+
+	redirectsBlocked int64 // Redirects refused by the redirect policy
+}
+
+// New creates a fetcher with the configured transport
+func New(cfg config.HTTPConfig) (*Fetcher, error) {
+	proxies, err := NewProxyPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := NewRenderer(cfg.Render, cfg.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := NewResponseCache(cfg.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.Timeout,
+		KeepAlive: cfg.Transport.KeepAlive,
+	}
+	dial := dialer.DialContext
+	var resolver *Resolver
+	if cfg.DNS.Cache || len(cfg.DNS.Servers) > 0 {
+		if resolver, err = NewResolver(cfg.DNS); err != nil {
+			return nil, fmt.Errorf("failed to create resolver: %w", err)
+		}
+		dial = resolver.DialContext(dialer)
+	}
+
+	transport := newTransport(cfg.Transport, dial)
+	var next http.RoundTripper = transport
+	tlsRecorder := newTLSRecorder(cfg.TLS, transport, newTransport(cfg.Transport, dial))
+	if tlsRecorder != nil {
+		next = tlsRecorder
+	}
+	conns := &connCounter{next: next}
+	client := &http.Client{
+		Transport: conns,
+		Timeout:   cfg.Timeout,
+	}
+
+	f := &Fetcher{
+		client:   client,
+		cfg:      cfg,
+		proxies:  proxies,
+		renderer: renderer,
+		cache:    cache,
+		retry:    NewRetryPolicy(cfg.Retry),
+		resolver: resolver,
+		conns:    conns,
+		tls:      tlsRecorder,
+
+		deadLetters: queue.NewMemoryDeadLetters(),
+	}
+	client.CheckRedirect = f.checkRedirect
+	return f, nil
+}
+
+// Proxies returns the proxy pool, or nil when no proxies are configured
+func (f *Fetcher) Proxies() *ProxyPool {
+	return f.proxies
+}
+
+// TLS returns the TLS recorder, or nil when certificates are neither
+// captured nor accepted invalid for some domains
+func (f *Fetcher) TLS() *TLSRecorder {
+	return f.tls
+}
+
+// SetCookieJar makes requests send and keep the cookies of jar, such as
+// the session of a login
+func (f *Fetcher) SetCookieJar(jar http.CookieJar) {
+	f.client.Jar = jar
+}
+
+// SetHeaders makes requests to a host send the headers returned for it, over
+// the default User-Agent and Accept
+func (f *Fetcher) SetHeaders(headers func(host string) map[string]string) {
+	f.headers = headers
+}
+
+// Client returns the underlying HTTP client, e.g. for robots.txt fetching
+func (f *Fetcher) Client() *http.Client {
+	return f.client
+}
+
+// Fetch downloads rawURL. When validators from a previous crawl are given and
+// conditional requests are enabled, If-None-Match/If-Modified-Since are sent
+// and a 304 answer is reported as NotModified with an empty body.
+// For hosts selected for rendering, successful HTML responses are re-loaded in
+// the headless browser; the plain HTTP body is kept if rendering fails.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, validators *Validators) (*Response, error) {
+	return f.fetchPage(ctx, rawURL, validators, true)
+}
+
+// fetchPage downloads a page like Fetch. With cached false the response cache
+// is not consulted, e.g. for retries, but a fresh answer is still stored.
+func (f *Fetcher) fetchPage(ctx context.Context, rawURL string, validators *Validators, cached bool) (*Response, error) {
+	if f.cache != nil && cached {
+		if resp, ok := f.cache.Get(rawURL, validators); ok {
+			return resp, nil
+		}
+	}
+
+	resp, err := f.fetch(ctx, rawURL, validators, "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8", func(contentType string) (int64, bool) {
+		return f.cfg.MaxBodySize, contentTypeAllowed(contentType, f.cfg.AllowedContentTypes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if f.renderer != nil && resp.StatusCode == http.StatusOK && IsHTML(resp.ContentType) {
+		if u, err := url.Parse(resp.URL); err == nil && f.renderer.Applies(u.Host) {
+			body, err := f.renderer.Render(ctx, resp.URL)
+			if err != nil {
+				log.Warn("Falling back to plain HTTP body: %v", err)
+			} else {
+				resp.Body = body
+				resp.Rendered = true
+			}
+		}
+	}
+	f.storeCached(rawURL, validators, resp)
+	return resp, nil
+}
+
+// storeCached saves a response to the cache when caching is enabled
+func (f *Fetcher) storeCached(rawURL string, validators *Validators, resp *Response) {
+	if f.cache == nil {
+		return
+	}
+	if err := f.cache.Put(rawURL, validators, resp); err != nil {
+		log.Warn("Failed to cache %s: %v", rawURL, err)
+	}
+}
+
+// DeadLetters returns the store receiving URLs that failed after all retries
+func (f *Fetcher) DeadLetters() queue.DeadLetterStore {
+	return f.deadLetters
+}
+
+// SetDeadLetters replaces the default in-memory dead-letter store
+func (f *Fetcher) SetDeadLetters(store queue.DeadLetterStore) {
+	f.deadLetters = store
+}
+
+// Renderer returns the headless browser renderer, or nil when rendering is disabled
+func (f *Fetcher) Renderer() *Renderer {
+	return f.renderer
+}
+
+// FetchAsset downloads a binary asset. maxSizes maps media types or wildcards
+// such as "image/*" to their size limit; types not listed are rejected with ErrContentType.
+func (f *Fetcher) FetchAsset(ctx context.Context, rawURL string, maxSizes map[string]int64) (*Response, error) {
+	return f.fetch(ctx, rawURL, nil, "*/*", func(contentType string) (int64, bool) {
+		return sizeLimitFor(contentType, maxSizes)
+	})
+}
+
+// fetch performs the request. limit returns the body size limit for a
+// Content-Type and whether the type may be downloaded at all.
+func (f *Fetcher) fetch(ctx context.Context, rawURL string, validators *Validators, accept string, limit func(contentType string) (int64, bool)) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var proxy *url.URL
+	if f.proxies != nil {
+		proxy = f.proxies.Pick(req.URL.Host)
+		req = req.WithContext(WithProxy(ctx, proxy))
+	}
+
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", accept)
+	if f.headers != nil {
+		for name, value := range f.headers(req.URL.Host) {
+			req.Header.Set(name, value)
+		}
+	}
+	if f.captureRaw {
+		// Setting it keeps the transport from decompressing the body
+		req.Header.Set("Accept-Encoding", rawAcceptEncoding)
+	}
+
+	if f.cfg.ConditionalRequests && validators != nil {
+		if validators.ETag != "" {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+
+	start := time.Now()
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if proxy != nil && !errors.Is(err, ErrRedirectBlocked) {
+			f.proxies.ReportFailure(proxy)
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if proxy != nil {
+		// Proxies answer 407 or 5xx on their own failures
+		if resp.StatusCode == http.StatusProxyAuthRequired || resp.StatusCode == http.StatusBadGateway {
+			f.proxies.ReportFailure(proxy)
+		} else {
+			f.proxies.ReportSuccess(proxy)
+		}
+	}
+
+	result := &Response{
+		RequestedURL: rawURL,
+		URL:          resp.Request.URL.String(),
+		Redirects:    redirectChain(resp),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.Latency = time.Since(start)
+		return result, nil
+	}
+
+	// Check headers before pulling the body into memory
+	maxSize, ok := limit(result.ContentType)
+	if !ok && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		// Error pages are reported by status without downloading their body
+		result.Latency = time.Since(start)
+		return result, nil
+	}
+	if !ok {
+		atomic.AddInt64(&f.rejectedType, 1)
+		return nil, fmt.Errorf("%w: %s", ErrContentType, mediaType(result.ContentType))
+	}
+	if maxSize > 0 && resp.ContentLength > maxSize {
+		atomic.AddInt64(&f.tooLarge, 1)
+		return nil, fmt.Errorf("%w: Content-Length %d exceeds %d", ErrBodyTooLarge, resp.ContentLength, maxSize)
+	}
+
+	var body []byte
+	if f.captureRaw {
+		body, err = f.readRaw(resp, result, maxSize)
+	} else {
+		body, err = readBody(resp.Body, maxSize, resp.ContentLength)
+	}
+	if err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			atomic.AddInt64(&f.tooLarge, 1)
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	result.Body = body
+	result.Latency = time.Since(start)
+	if f.cfg.SniffContent && len(body) > 0 {
+		f.sniff(result)
+	}
+
+	return result, nil
+}
+
+// sniff records the declared and detected types of a response and replaces
+// its Content-Type when the body is mislabeled
+func (f *Fetcher) sniff(resp *Response) {
+	resp.DeclaredType = resp.ContentType
+	resp.DetectedType = DetectType(resp.Body)
+	if ct := effectiveType(resp.ContentType, resp.DetectedType); ct != resp.ContentType {
+		atomic.AddInt64(&f.mislabeled, 1)
+		log.Debug("%s is labeled %q but looks like %s", resp.URL, resp.ContentType, resp.DetectedType)
+		resp.ContentType = ct
+	}
+}
+
+// GetStats returns counts of downloads skipped by content type or size and cache statistics
+func (f *Fetcher) GetStats() map[string]int64 {
+	stats := map[string]int64{
+		"rejectedContentType": atomic.LoadInt64(&f.rejectedType),
+		"bodyTooLarge":        atomic.LoadInt64(&f.tooLarge),
+		"rawCaptured":         atomic.LoadInt64(&f.rawCaptured),
+		"mislabeledContent":   atomic.LoadInt64(&f.mislabeled),
+		"redirectsBlocked":    atomic.LoadInt64(&f.redirectsBlocked),
+	}
+	if f.cache != nil {
+		for key, value := range f.cache.GetStats() {
+			stats[key] = value
+		}
+	}
+	for key, value := range f.retry.GetStats() {
+		stats[key] = value
+	}
+	for key, value := range f.conns.GetStats() {
+		stats[key] = value
+	}
+	for key, value := range bodyBuffers.GetStats() {
+		stats[key] = value
+	}
+	if f.resolver != nil {
+		for key, value := range f.resolver.GetStats() {
+			stats[key] = value
+		}
+	}
+	if f.tls != nil {
+		for key, value := range f.tls.GetStats() {
+			stats[key] = value
+		}
+	}
+	return stats
+}
+
+// redirectChain walks back from the final response to list every redirect hop
+func redirectChain(resp *http.Response) []RedirectHop {
+	var hops []RedirectHop
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		hops = append(hops, RedirectHop{
+			URL:        r.Request.URL.String(),
+			StatusCode: r.StatusCode,
+		})
+	}
+
+	// Hops were collected from last to first
+	for i, j := 0, len(hops)-1; i < j; i, j = i+1, j-1 {
+		hops[i], hops[j] = hops[j], hops[i]
+	}
+	return hops
+}
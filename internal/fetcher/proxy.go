@@ -0,0 +1,253 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Proxy rotation strategies
+const (
+	RotationRoundRobin   = "round_robin"
+	RotationSticky       = "sticky"
+	RotationFailureAware = "failure_aware"
+)
+
+// proxyKey is the context key carrying the proxy picked for a request
+type proxyKey struct{}
+
+// proxyState tracks the health of one proxy
+type proxyState struct {
+	url          *url.URL
+	failures     int // Consecutive failures
+	disabledTill time.Time
+	requests     int64
+	errors       int64
+}
+
+// ProxyPool selects a proxy per request using a rotation strategy
+type ProxyPool struct {
+	strategy    string
+	maxFailures int
+	cooldown    time.Duration
+
+	mu      sync.Mutex
+	proxies []*proxyState
+	next    uint64
+}
+
+// NewProxyPool builds a pool from the single proxy and/or proxy list file in cfg.
+// Returns nil when no proxy is configured.
+func NewProxyPool(cfg config.HTTPConfig) (*ProxyPool, error) {
+	var raw []string
+	if cfg.Proxy != "" {
+		raw = append(raw, cfg.Proxy)
+	}
+	if cfg.ProxyFile != "" {
+		lines, err := readProxyFile(cfg.ProxyFile)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, lines...)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	pool := &ProxyPool{
+		strategy:    cfg.ProxyRotation,
+		maxFailures: cfg.ProxyMaxFailures,
+		cooldown:    cfg.ProxyCooldown,
+	}
+	if pool.strategy == "" {
+		pool.strategy = RotationRoundRobin
+	}
+	if pool.maxFailures <= 0 {
+		pool.maxFailures = 3
+	}
+	if pool.cooldown <= 0 {
+		pool.cooldown = time.Minute
+	}
+
+	for _, r := range raw {
+		u, err := ParseProxyURL(r)
+		if err != nil {
+			return nil, err
+		}
+		pool.proxies = append(pool.proxies, &proxyState{url: u})
+	}
+
+	log.Info("Using %d proxies with %s rotation", len(pool.proxies), pool.strategy)
+	return pool, nil
+}
+
+// readProxyFile reads one proxy per line, ignoring blank lines and comments
+func readProxyFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proxy file: %w", err)
+	}
+	defer file.Close()
+
+	var proxies []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read proxy file: %w", err)
+	}
+	return proxies, nil
+}
+
+// ParseProxyURL parses an http, https, socks5, or socks5h proxy address.
+// Addresses without a scheme are treated as HTTP proxies.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+	return u, nil
+}
+
+// available reports whether a proxy may be used. Caller must hold mu.
+func (p *ProxyPool) available(s *proxyState, now time.Time) bool {
+	return p.strategy != RotationFailureAware || now.After(s.disabledTill)
+}
+
+// Pick selects the proxy to use for a request to host
+func (p *ProxyPool) Pick(host string) *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := len(p.proxies)
+	now := time.Now()
+
+	start := int(p.next % uint64(n))
+	if p.strategy == RotationSticky {
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		start = int(h.Sum32() % uint32(n))
+	} else {
+		p.next++
+	}
+
+	for i := 0; i < n; i++ {
+		s := p.proxies[(start+i)%n]
+		if p.available(s, now) {
+			s.requests++
+			return s.url
+		}
+	}
+
+	// Every proxy is cooling down, use the one that recovers first
+	best := p.proxies[0]
+	for _, s := range p.proxies[1:] {
+		if s.disabledTill.Before(best.disabledTill) {
+			best = s
+		}
+	}
+	best.requests++
+	return best.url
+}
+
+// find returns the state for a proxy URL. Caller must hold mu.
+func (p *ProxyPool) find(u *url.URL) *proxyState {
+	for _, s := range p.proxies {
+		if s.url == u {
+			return s
+		}
+	}
+	return nil
+}
+
+// ReportSuccess resets the failure count of a proxy
+func (p *ProxyPool) ReportSuccess(u *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s := p.find(u); s != nil {
+		s.failures = 0
+	}
+}
+
+// ReportFailure records a failed request and disables the proxy for the
+// cooldown period after too many consecutive failures
+func (p *ProxyPool) ReportFailure(u *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.find(u)
+	if s == nil {
+		return
+	}
+	s.failures++
+	s.errors++
+	if s.failures >= p.maxFailures {
+		s.disabledTill = time.Now().Add(p.cooldown)
+		s.failures = 0
+		if p.strategy == RotationFailureAware {
+			log.Warn("Proxy %s disabled for %s after repeated failures", u.Redacted(), p.cooldown)
+		}
+	}
+}
+
+// WithProxy returns a context that makes the transport use proxy u
+func WithProxy(ctx context.Context, u *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey{}, u)
+}
+
+// proxyFunc is the Transport.Proxy hook: it uses the proxy picked for the
+// request if any, falling back to the environment settings
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	if u, ok := req.Context().Value(proxyKey{}).(*url.URL); ok && u != nil {
+		return u, nil
+	}
+	return http.ProxyFromEnvironment(req)
+}
+
+// GetStats returns per-proxy request and error counts
+func (p *ProxyPool) GetStats() map[string]int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make(map[string]int64)
+	now := time.Now()
+	var healthy int64
+	for _, s := range p.proxies {
+		name := s.url.Redacted()
+		stats[name+".requests"] = s.requests
+		stats[name+".errors"] = s.errors
+		if now.After(s.disabledTill) {
+			healthy++
+		}
+	}
+	stats["proxies"] = int64(len(p.proxies))
+	stats["healthy"] = healthy
+	return stats
+}
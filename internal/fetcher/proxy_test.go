@@ -0,0 +1,151 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// newTestProxyPool builds a pool of three proxies listed in a file
+func newTestProxyPool(t *testing.T, rotation string) *ProxyPool {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "proxies.txt")
+	list := "# Backup proxies\nhttp://p2:8080\n\nsocks5://user:secret@p3:1080\n"
+	if err := os.WriteFile(path, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewProxyPool(config.HTTPConfig{
+		Proxy:            "p1:3128",
+		ProxyFile:        path,
+		ProxyRotation:    rotation,
+		ProxyMaxFailures: 2,
+		ProxyCooldown:    time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+// picks returns the hosts of the next n proxies picked for host
+func picks(p *ProxyPool, host string, n int) []string {
+	var hosts []string
+	for i := 0; i < n; i++ {
+		hosts = append(hosts, p.Pick(host).Host)
+	}
+	return hosts
+}
+
+func TestProxyPoolRoundRobin(t *testing.T) {
+	p := newTestProxyPool(t, "")
+	got := picks(p, "a.com", 4)
+	want := []string{"p1:3128", "p2:8080", "p3:1080", "p1:3128"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("picks = %v, want %v", got, want)
+		}
+	}
+
+	// Round robin ignores failures
+	for i := 0; i < 2; i++ {
+		p.ReportFailure(p.proxies[1].url)
+	}
+	if got := picks(p, "a.com", 2); got[0] != "p2:8080" {
+		t.Errorf("picks after failures = %v, want p2 still used", got)
+	}
+}
+
+func TestProxyPoolSticky(t *testing.T) {
+	p := newTestProxyPool(t, RotationSticky)
+	seen := make(map[string]bool)
+	for _, host := range []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com"} {
+		first := p.Pick(host).Host
+		for _, h := range picks(p, host, 5) {
+			if h != first {
+				t.Fatalf("%s moved from %s to %s", host, first, h)
+			}
+		}
+		seen[first] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("every host got the same proxy: %v", seen)
+	}
+}
+
+func TestProxyPoolFailureAware(t *testing.T) {
+	p := newTestProxyPool(t, RotationFailureAware)
+	p2 := p.proxies[1].url
+
+	// A success resets the count of consecutive failures
+	p.ReportFailure(p2)
+	p.ReportSuccess(p2)
+	p.ReportFailure(p2)
+	if got := picks(p, "a.com", 3); got[1] != "p2:8080" {
+		t.Fatalf("picks = %v, want p2 still used", got)
+	}
+
+	p.ReportFailure(p2)
+	for _, h := range picks(p, "a.com", 6) {
+		if h == "p2:8080" {
+			t.Fatal("ejected proxy picked")
+		}
+	}
+	if stats := p.GetStats(); stats["healthy"] != 2 || stats["proxies"] != 3 || stats["http://p2:8080.errors"] != 3 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+
+	// With every proxy ejected, the first to recover is used
+	for _, s := range p.proxies {
+		p.ReportFailure(s.url)
+		p.ReportFailure(s.url)
+	}
+	p.proxies[2].disabledTill = time.Now().Add(time.Second)
+	if got := p.Pick("a.com").Host; got != "p3:1080" {
+		t.Errorf("Pick() with all proxies ejected = %s, want p3", got)
+	}
+
+	// A proxy is back once its cooldown is over
+	p.proxies[1].disabledTill = time.Now().Add(-time.Second)
+	for _, h := range picks(p, "a.com", 3) {
+		if h != "p2:8080" {
+			t.Fatalf("picked %s, want the recovered p2", h)
+		}
+	}
+}
+
+func TestNewProxyPool(t *testing.T) {
+	if p, err := NewProxyPool(config.HTTPConfig{}); p != nil || err != nil {
+		t.Errorf("NewProxyPool() without proxies = %v, %v", p, err)
+	}
+	for _, cfg := range []config.HTTPConfig{
+		{Proxy: "ftp://p1:21"},
+		{Proxy: "http://"},
+		{ProxyFile: filepath.Join(t.TempDir(), "missing.txt")},
+	} {
+		if _, err := NewProxyPool(cfg); err == nil {
+			t.Errorf("NewProxyPool(%+v) succeeded", cfg)
+		}
+	}
+
+	p := newTestProxyPool(t, "")
+	if p.strategy != RotationRoundRobin || p.maxFailures != 2 || len(p.proxies) != 3 {
+		t.Fatalf("pool = %s rotation, %d max failures, %d proxies", p.strategy, p.maxFailures, len(p.proxies))
+	}
+	if u := p.proxies[2].url; u.Scheme != "socks5" || u.Redacted() != "socks5://user:xxxxx@p3:1080" {
+		t.Errorf("proxy from file = %s", u.Redacted())
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	p := newTestProxyPool(t, "")
+	picked := p.Pick("a.com")
+	req, _ := http.NewRequestWithContext(WithProxy(context.Background(), picked), http.MethodGet, "http://a.com/", nil)
+	if u, err := proxyFunc(req); err != nil || u != picked {
+		t.Errorf("proxyFunc() = %v, %v, want %v", u, err, picked)
+	}
+}
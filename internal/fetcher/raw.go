@@ -0,0 +1,89 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// rawAcceptEncoding is sent instead of the transport's own Accept-Encoding
+// while capturing raw responses, limited to what decodeBody can decode
+const rawAcceptEncoding = "gzip, deflate"
+
+// RawExchange is a request and its response as sent and received
+type RawExchange struct {
+	Request  []byte // Request line and headers
+	Response []byte // Status line and headers
+	Body     []byte // Body as received, before content decoding
+}
+
+// SetCaptureRaw makes fetched pages carry their raw exchange. Bodies are then
+// decompressed by the fetcher rather than the transport.
+func (f *Fetcher) SetCaptureRaw(capture bool) {
+	f.captureRaw = capture
+}
+
+// rawExchange records the final request of resp and its response head
+func rawExchange(resp *http.Response, body []byte) *RawExchange {
+	req := resp.Request
+	var request bytes.Buffer
+	fmt.Fprintf(&request, "%s %s %s\r\n", req.Method, req.URL.RequestURI(), req.Proto)
+	fmt.Fprintf(&request, "Host: %s\r\n", req.Host)
+	req.Header.Write(&request)
+	request.WriteString("\r\n")
+
+	var response bytes.Buffer
+	fmt.Fprintf(&response, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&response)
+	response.WriteString("\r\n")
+
+	return &RawExchange{Request: request.Bytes(), Response: response.Bytes(), Body: body}
+}
+
+// decodeBody undoes the Content-Encoding of a raw body, keeping the decoded
+// body within maxSize
+func decodeBody(body []byte, encoding string, maxSize int64) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "deflate":
+		// Servers send zlib streams as well as bare deflate data
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			r = zr
+		} else {
+			r = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	// Compressed bodies are a fraction of their decoded size
+	return readBody(r, maxSize, 4*int64(len(body)))
+}
+
+// readRaw reads the body of resp as received and decodes it
+func (f *Fetcher) readRaw(resp *http.Response, result *Response, maxSize int64) ([]byte, error) {
+	raw, err := readBody(resp.Body, maxSize, resp.ContentLength)
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeBody(raw, resp.Header.Get("Content-Encoding"), maxSize)
+	if err != nil {
+		return nil, err
+	}
+	result.Raw = rawExchange(resp, raw)
+	atomic.AddInt64(&f.rawCaptured, 1)
+	return body, nil
+}
@@ -0,0 +1,82 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+const rawPage = "<html><body>raw</body></html>"
+
+func compress(t *testing.T, encoding string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	switch encoding {
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(rawPage))
+		w.Close()
+	case "deflate":
+		w := zlib.NewWriter(&buf)
+		w.Write([]byte(rawPage))
+		w.Close()
+	default:
+		buf.WriteString(rawPage)
+	}
+	return buf.Bytes()
+}
+
+func TestCaptureRaw(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate", ""} {
+		body := compress(t, encoding)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Accept-Encoding"); got != rawAcceptEncoding {
+				t.Errorf("Accept-Encoding = %q", got)
+			}
+			w.Header().Set("Content-Type", "text/html")
+			if encoding != "" {
+				w.Header().Set("Content-Encoding", encoding)
+			}
+			w.Write(body)
+		}))
+
+		f, err := New(config.DefaultConfig().HTTP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.SetCaptureRaw(true)
+		resp, err := f.Fetch(context.Background(), srv.URL+"/page?x=1", nil)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Body) != rawPage {
+			t.Errorf("%q: decoded body = %q", encoding, resp.Body)
+		}
+		if resp.Raw == nil || !bytes.Equal(resp.Raw.Body, body) {
+			t.Fatalf("%q: raw exchange = %+v", encoding, resp.Raw)
+		}
+		if !strings.HasPrefix(string(resp.Raw.Request), "GET /page?x=1 HTTP/1.1\r\nHost: ") {
+			t.Errorf("%q: raw request = %q", encoding, resp.Raw.Request)
+		}
+		if !strings.HasPrefix(string(resp.Raw.Response), "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(string(resp.Raw.Response), "\r\n\r\n") {
+			t.Errorf("%q: raw response head = %q", encoding, resp.Raw.Response)
+		}
+	}
+}
+
+func TestDecodeBodyErrors(t *testing.T) {
+	if _, err := decodeBody([]byte("x"), "br", 1<<20); err == nil {
+		t.Error("brotli body decoded")
+	}
+	if _, err := decodeBody([]byte("not gzip"), "gzip", 1<<20); err == nil {
+		t.Error("corrupt gzip body decoded")
+	}
+}
@@ -0,0 +1,52 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrRedirectBlocked is returned when a redirect leaves the domain or
+// downgrades HTTPS to HTTP against the redirect policy
+var ErrRedirectBlocked = errors.New("redirect not allowed")
+
+// checkRedirect applies the redirect policy to req, the redirect following
+// the requests in via
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !f.cfg.FollowRedirect {
+		return http.ErrUseLastResponse
+	}
+	if len(via) >= f.cfg.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", f.cfg.MaxRedirects)
+	}
+
+	prev := via[len(via)-1].URL
+	switch {
+	case !f.cfg.HTTPSDowngrade && prev.Scheme == "https" && req.URL.Scheme == "http":
+		atomic.AddInt64(&f.redirectsBlocked, 1)
+		return fmt.Errorf("%w: %s downgrades to %s", ErrRedirectBlocked, prev, req.URL)
+	case !f.cfg.CrossDomainRedirect && registrableDomain(prev.Hostname()) != registrableDomain(req.URL.Hostname()):
+		atomic.AddInt64(&f.redirectsBlocked, 1)
+		return fmt.Errorf("%w: %s leaves the domain for %s", ErrRedirectBlocked, prev, req.URL)
+	}
+	return nil
+}
+
+// registrableDomain returns the domain a host is registered under, e.g.
+// example.co.uk for www.example.co.uk, or the host itself for IPs and
+// unknown suffixes
+func registrableDomain(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
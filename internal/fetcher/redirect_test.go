@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer target.Close()
+	// Same host on another name, which is another domain
+	other := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+
+	redirects := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, target.URL+"/page", http.StatusMovedPermanently)
+		case "/other":
+			http.Redirect(w, r, "/hop", http.StatusFound)
+		case "/hop":
+			http.Redirect(w, r, other+"/page", http.StatusTemporaryRedirect)
+		}
+	}
+	plain := httptest.NewServer(http.HandlerFunc(redirects))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(redirects))
+	defer secure.Close()
+
+	tests := []struct {
+		name        string
+		url         string
+		crossDomain bool
+		downgrade   bool
+		blocked     bool
+		hops        []RedirectHop
+	}{
+		{"same domain", plain.URL + "/same", false, false, false, []RedirectHop{{plain.URL + "/same", 301}}},
+		{"cross domain", plain.URL + "/other", true, true, false, []RedirectHop{{plain.URL + "/other", 302}, {plain.URL + "/hop", 307}}},
+		{"cross domain blocked", plain.URL + "/other", false, true, true, nil},
+		{"downgrade", secure.URL + "/same", true, true, false, []RedirectHop{{secure.URL + "/same", 301}}},
+		{"downgrade blocked", secure.URL + "/same", true, false, true, nil},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig().HTTP
+		cfg.CrossDomainRedirect = tt.crossDomain
+		cfg.HTTPSDowngrade = tt.downgrade
+		cfg.TLS.InsecureDomains = []string{"127.0.0.1"}
+		f, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := f.Fetch(context.Background(), tt.url, nil)
+		if tt.blocked {
+			if !errors.Is(err, ErrRedirectBlocked) {
+				t.Errorf("%s: Fetch() = %v, want ErrRedirectBlocked", tt.name, err)
+			}
+			if f.GetStats()["redirectsBlocked"] != 1 {
+				t.Errorf("%s: stats = %v", tt.name, f.GetStats())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Fetch() = %v", tt.name, err)
+			continue
+		}
+		if len(resp.Redirects) != len(tt.hops) {
+			t.Errorf("%s: redirects = %+v, want %+v", tt.name, resp.Redirects, tt.hops)
+			continue
+		}
+		for i, hop := range tt.hops {
+			if resp.Redirects[i] != hop {
+				t.Errorf("%s: hop %d = %+v, want %+v", tt.name, i, resp.Redirects[i], hop)
+			}
+		}
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.example.com":    "example.com",
+		"Blog.Example.co.uk": "example.co.uk",
+		"example.com":        "example.com",
+		"localhost":          "localhost",
+		"127.0.0.1":          "127.0.0.1",
+		"::1":                "::1",
+	}
+	for host, want := range tests {
+		if got := registrableDomain(host); got != want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// browserCandidates are the binaries looked up in PATH when no browser path is configured
+var browserCandidates = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// Renderer renders pages with a headless Chrome/Chromium so that content and
+// links produced by JavaScript are visible to extraction
+type Renderer struct {
+	cfg       config.RenderConfig
+	browser   string
+	userAgent string
+	slots     chan struct{} // Limits concurrent browser processes
+
+	rendered int64
+	failures int64
+}
+
+// NewRenderer creates a renderer. Returns nil when rendering is not configured
+// or, unless a browser path was given explicitly, when no browser is installed.
+func NewRenderer(cfg config.RenderConfig, userAgent string) (*Renderer, error) {
+	if !cfg.Enabled && len(cfg.Domains) == 0 {
+		return nil, nil
+	}
+
+	browser := cfg.BrowserPath
+	if browser != "" {
+		if _, err := exec.LookPath(browser); err != nil {
+			return nil, fmt.Errorf("failed to find browser %q: %w", browser, err)
+		}
+	} else {
+		for _, candidate := range browserCandidates {
+			if path, err := exec.LookPath(candidate); err == nil {
+				browser = path
+				break
+			}
+		}
+		if browser == "" {
+			log.Warn("No headless browser found, JavaScript rendering disabled")
+			return nil, nil
+		}
+	}
+
+	concurrency := cfg.MaxConcurrent
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	log.Info("Rendering JavaScript with %s", browser)
+	return &Renderer{
+		cfg:       cfg,
+		browser:   browser,
+		userAgent: userAgent,
+		slots:     make(chan struct{}, concurrency),
+	}, nil
+}
+
+// Applies reports whether pages on host should be rendered. Excluded
+// domains are never rendered.
+func (r *Renderer) Applies(host string) bool {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	if inDomains(host, r.cfg.Exclude) {
+		return false
+	}
+	return r.cfg.Enabled || inDomains(host, r.cfg.Domains)
+}
+
+// inDomains reports whether host is one of domains or one of their subdomains
+func inDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render loads rawURL in the headless browser and returns the serialized DOM
+// after scripts have run
+func (r *Renderer) Render(ctx context.Context, rawURL string) ([]byte, error) {
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	args := []string{
+		"--headless=new",
+		"--disable-gpu",
+		"--no-first-run",
+		"--mute-audio",
+		"--hide-scrollbars",
+		"--user-agent=" + r.userAgent,
+		"--dump-dom",
+	}
+	if r.cfg.NoSandbox {
+		args = append(args, "--no-sandbox")
+	}
+	if r.cfg.Budget > 0 {
+		args = append(args, fmt.Sprintf("--virtual-time-budget=%d", r.cfg.Budget.Milliseconds()))
+	}
+	args = append(args, rawURL)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.browser, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Chrome's child processes can keep the output open after it was killed
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		atomic.AddInt64(&r.failures, 1)
+		return nil, fmt.Errorf("failed to render %s: %w: %s", rawURL, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		atomic.AddInt64(&r.failures, 1)
+		return nil, fmt.Errorf("failed to render %s: empty DOM", rawURL)
+	}
+
+	atomic.AddInt64(&r.rendered, 1)
+	return stdout.Bytes(), nil
+}
+
+// GetStats returns rendering counters
+func (r *Renderer) GetStats() map[string]int64 {
+	return map[string]int64{
+		"rendered":       atomic.LoadInt64(&r.rendered),
+		"renderFailures": atomic.LoadInt64(&r.failures),
+	}
+}
@@ -0,0 +1,145 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// fakeBrowser writes a shell script standing in for Chrome and returns its
+// path. The script runs body with the browser arguments in "$@".
+func fakeBrowser(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chrome")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// dumpDOM prints the arguments and the URL, the last one, as a rendered page
+const dumpDOM = `for last; do :; done
+echo "<html><body><p>rendered $last</p><p>args: $*</p></body></html>"`
+
+func TestNewRendererDisabled(t *testing.T) {
+	r, err := NewRenderer(config.RenderConfig{}, "bot")
+	if r != nil || err != nil {
+		t.Fatalf("NewRenderer() without config = %v, %v", r, err)
+	}
+	if _, err := NewRenderer(config.RenderConfig{Enabled: true, BrowserPath: "/no/such/browser"}, "bot"); err == nil {
+		t.Fatal("NewRenderer() with a missing browser succeeded")
+	}
+}
+
+func TestRendererApplies(t *testing.T) {
+	r, err := NewRenderer(config.RenderConfig{Domains: []string{"App.example.com"}, Exclude: []string{"old.app.example.com"}, BrowserPath: fakeBrowser(t, dumpDOM)}, "bot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"app.example.com":        true,
+		"APP.example.com:8443":   true,
+		"v2.app.example.com":     true,
+		"example.com":            false,
+		"notapp.example.com":     false,
+		"old.app.example.com":    false,
+		"v1.old.app.example.com": false,
+	}
+	for host, want := range tests {
+		if got := r.Applies(host); got != want {
+			t.Errorf("Applies(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestRendererRender(t *testing.T) {
+	r, err := NewRenderer(config.RenderConfig{
+		Enabled:     true,
+		BrowserPath: fakeBrowser(t, dumpDOM),
+		Budget:      1500 * time.Millisecond,
+		NoSandbox:   true,
+	}, "test-bot/1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dom, err := r.Render(context.Background(), "https://example.com/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"rendered https://example.com/app",
+		"--headless=new", "--dump-dom", "--no-sandbox",
+		"--user-agent=test-bot/1.0", "--virtual-time-budget=1500",
+	} {
+		if !strings.Contains(string(dom), want) {
+			t.Errorf("rendered DOM %q lacks %q", dom, want)
+		}
+	}
+	if stats := r.GetStats(); stats["rendered"] != 1 || stats["renderFailures"] != 0 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestRendererFailures(t *testing.T) {
+	tests := map[string]struct {
+		script  string
+		timeout time.Duration
+		want    string
+	}{
+		"exit status": {script: `echo "crashed" >&2; exit 1`, want: "crashed"},
+		"empty DOM":   {script: `exit 0`, want: "empty DOM"},
+		"timeout":     {script: `sleep 5`, timeout: 100 * time.Millisecond, want: "killed"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewRenderer(config.RenderConfig{Enabled: true, BrowserPath: fakeBrowser(t, tt.script), Timeout: tt.timeout}, "bot")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = r.Render(context.Background(), "https://example.com/")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Render() error = %v, want it to mention %q", err, tt.want)
+			}
+			if r.GetStats()["renderFailures"] != 1 {
+				t.Fatal("failure not counted")
+			}
+		})
+	}
+}
+
+func TestFetchRendersAndFallsBack(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>plain</body></html>")
+	}))
+	defer server.Close()
+
+	for name, script := range map[string]string{"rendered": dumpDOM, "fallback": "exit 1"} {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.DefaultConfig().HTTP
+			cfg.Render = config.RenderConfig{Enabled: true, BrowserPath: fakeBrowser(t, script)}
+			f, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := f.Fetch(context.Background(), server.URL+"/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := name == "rendered"
+			if resp.Rendered != rendered || strings.Contains(string(resp.Body), "plain") == rendered {
+				t.Fatalf("Rendered = %v, body %q", resp.Rendered, resp.Body)
+			}
+		})
+	}
+}
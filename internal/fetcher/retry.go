@@ -0,0 +1,252 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/ratelimit"
+)
+
+// RetryError is returned when a URL still fails after all retry attempts
+type RetryError struct {
+	URL        string
+	Attempts   int
+	StatusCode int // Last HTTP status, 0 for transport errors
+	Err        error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up on %s after %d attempts: %v", e.URL, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// hostBudget counts the retries spent on a host in the current window
+type hostBudget struct {
+	windowStart time.Time
+	used        int
+}
+
+// RetryPolicy decides which failures are retried and how long to back off
+type RetryPolicy struct {
+	cfg      config.RetryConfig
+	statuses map[int]bool
+
+	mu      sync.Mutex
+	budgets map[string]*hostBudget
+	rng     *rand.Rand
+
+	retries      int64
+	exhausted    int64
+	budgetDenied int64
+}
+
+// NewRetryPolicy creates a retry policy from configuration
+func NewRetryPolicy(cfg config.RetryConfig) *RetryPolicy {
+	statuses := make(map[int]bool, len(cfg.RetryStatuses))
+	for _, code := range cfg.RetryStatuses {
+		statuses[code] = true
+	}
+	return &RetryPolicy{
+		cfg:      cfg,
+		statuses: statuses,
+		budgets:  make(map[string]*hostBudget),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Retryable reports whether a failed attempt is transient: timeouts,
+// connection resets, or one of the configured status codes
+func (p *RetryPolicy) Retryable(resp *Response, err error) bool {
+	if err == nil {
+		return resp != nil && p.statuses[resp.StatusCode]
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrContentType) || errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrRedirectBlocked) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// Error classes reported by ErrorClass
+const (
+	ErrorTimeout    = "timeout"
+	ErrorDNS        = "dns"
+	ErrorConnection = "connection"
+	ErrorHTTP4xx    = "http_4xx"
+	ErrorHTTP5xx    = "http_5xx"
+	ErrorTooLarge   = "too_large"
+	ErrorOther      = "other"
+)
+
+// ErrorClass groups a fetch error for metrics
+func ErrorClass(err error) string {
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) && retryErr.StatusCode > 0 {
+		return StatusClass(retryErr.StatusCode)
+	}
+
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return ErrorTooLarge
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF), errors.As(err, &netErr):
+		return ErrorConnection
+	}
+	return ErrorOther
+}
+
+// StatusClass groups an HTTP error status for metrics
+func StatusClass(status int) string {
+	if status >= 500 {
+		return ErrorHTTP5xx
+	}
+	return ErrorHTTP4xx
+}
+
+// Backoff returns the delay before retry number attempt (starting at 1):
+// exponential from the base delay, capped, with random jitter
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.cfg.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.cfg.MaxDelay > 0 && delay > float64(p.cfg.MaxDelay) {
+		delay = float64(p.cfg.MaxDelay)
+	}
+
+	if p.cfg.Jitter > 0 {
+		p.mu.Lock()
+		r := p.rng.Float64()
+		p.mu.Unlock()
+		// Spread the delay over [delay*(1-jitter), delay]
+		delay -= delay * p.cfg.Jitter * r
+	}
+	return time.Duration(delay)
+}
+
+// spend takes one retry from the host's budget, returning false when it's used up
+func (p *RetryPolicy) spend(host string) bool {
+	if p.cfg.HostBudget <= 0 {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	b, ok := p.budgets[host]
+	if !ok || now.Sub(b.windowStart) > p.cfg.BudgetWindow {
+		b = &hostBudget{windowStart: now}
+		p.budgets[host] = b
+	}
+	if b.used >= p.cfg.HostBudget {
+		return false
+	}
+	b.used++
+	return true
+}
+
+// GetStats returns retry statistics
+func (p *RetryPolicy) GetStats() map[string]int64 {
+	return map[string]int64{
+		"retries":           atomic.LoadInt64(&p.retries),
+		"retriesExhausted":  atomic.LoadInt64(&p.exhausted),
+		"retryBudgetDenied": atomic.LoadInt64(&p.budgetDenied),
+	}
+}
+
+// FetchWithRetry fetches rawURL, retrying transient failures with backoff.
+// Retries always go to the network, never to the response cache. URLs that
+// still fail are added to the dead-letter list and a *RetryError is returned.
+func (f *Fetcher) FetchWithRetry(ctx context.Context, rawURL string, validators *Validators) (*Response, error) {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	maxAttempts := f.cfg.Retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := f.fetchPage(ctx, rawURL, validators, attempt == 1)
+		if !f.retry.Retryable(resp, err) {
+			return resp, err
+		}
+
+		if attempt >= maxAttempts || !f.retry.spend(host) {
+			if attempt < maxAttempts {
+				atomic.AddInt64(&f.retry.budgetDenied, 1)
+			}
+			atomic.AddInt64(&f.retry.exhausted, 1)
+			return nil, f.giveUp(ctx, rawURL, host, attempt, resp, err)
+		}
+		atomic.AddInt64(&f.retry.retries, 1)
+
+		delay := f.retry.Backoff(attempt)
+		if resp != nil {
+			// Servers asking us to slow down get at least what they asked for
+			if after := ratelimit.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); after > delay {
+				delay = after
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// giveUp records a permanently failed URL in the dead-letter list
+func (f *Fetcher) giveUp(ctx context.Context, rawURL, host string, attempts int, resp *Response, err error) error {
+	retryErr := &RetryError{URL: rawURL, Attempts: attempts, Err: err}
+	if resp != nil {
+		retryErr.StatusCode = resp.StatusCode
+		retryErr.Err = fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	entry := queue.DeadLetter{
+		URL:        rawURL,
+		Host:       host,
+		Error:      retryErr.Err.Error(),
+		Attempts:   attempts,
+		StatusCode: retryErr.StatusCode,
+		FailedAt:   time.Now(),
+	}
+	if err := f.deadLetters.Add(ctx, entry); err != nil {
+		log.Error("Failed to record dead letter %s: %v", rawURL, err)
+	}
+	return retryErr
+}
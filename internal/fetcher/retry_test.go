@@ -0,0 +1,200 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func retryConfig() config.RetryConfig {
+	return config.RetryConfig{
+		MaxAttempts:   3,
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      time.Second,
+		HostBudget:    2,
+		BudgetWindow:  time.Minute,
+		RetryStatuses: []int{429, 503},
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	p := NewRetryPolicy(retryConfig())
+	urlErr := func(err error) error { return &url.Error{Op: "Get", URL: "https://a.com/", Err: err} }
+
+	tests := []struct {
+		name string
+		resp *Response
+		err  error
+		want bool
+	}{
+		{"retry status", &Response{StatusCode: 503}, nil, true},
+		{"other status", &Response{StatusCode: 500}, nil, false},
+		{"success", &Response{StatusCode: 200}, nil, false},
+		{"no response", nil, nil, false},
+		{"timeout", nil, urlErr(os.ErrDeadlineExceeded), true},
+		{"connection reset", nil, urlErr(syscall.ECONNRESET), true},
+		{"connection refused", nil, urlErr(syscall.ECONNREFUSED), true},
+		{"unexpected EOF", nil, fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"temporary DNS", nil, &net.DNSError{Err: "server misbehaving", IsTemporary: true}, true},
+		{"host not found", nil, &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"canceled", nil, urlErr(context.Canceled), false},
+		{"content type", nil, fmt.Errorf("image/png: %w", ErrContentType), false},
+		{"body too large", nil, fmt.Errorf("read body: %w", ErrBodyTooLarge), false},
+		{"redirect blocked", nil, urlErr(ErrRedirectBlocked), false},
+		{"other error", nil, errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := p.Retryable(tt.resp, tt.err); got != tt.want {
+			t.Errorf("%s: Retryable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&RetryError{StatusCode: 503, Err: errors.New("status 503")}, ErrorHTTP5xx},
+		{&RetryError{StatusCode: 429, Err: errors.New("status 429")}, ErrorHTTP4xx},
+		{&RetryError{Err: &url.Error{Op: "Get", Err: os.ErrDeadlineExceeded}}, ErrorTimeout},
+		{context.DeadlineExceeded, ErrorTimeout},
+		{&net.DNSError{Err: "no such host", IsNotFound: true}, ErrorDNS},
+		{&url.Error{Op: "Get", Err: syscall.ECONNRESET}, ErrorConnection},
+		{io.EOF, ErrorConnection},
+		{fmt.Errorf("read body: %w", ErrBodyTooLarge), ErrorTooLarge},
+		{ErrRedirectBlocked, ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := NewRetryPolicy(retryConfig())
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// Without a cap the delay keeps doubling
+	cfg := retryConfig()
+	cfg.MaxDelay = 0
+	if got := NewRetryPolicy(cfg).Backoff(8); got != 12800*time.Millisecond {
+		t.Errorf("uncapped Backoff(8) = %v", got)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	cfg := retryConfig()
+	cfg.Jitter = 0.5
+	p := NewRetryPolicy(cfg)
+	p.rng = rand.New(rand.NewSource(1))
+
+	for attempt, base := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		lowest, highest := base, time.Duration(0)
+		for i := 0; i < 200; i++ {
+			d := p.Backoff(attempt)
+			if d < base/2 || d > base {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, d, base/2, base)
+			}
+			lowest, highest = min(lowest, d), max(highest, d)
+		}
+		// The delays are spread, not all the same
+		if highest-lowest < base/4 {
+			t.Errorf("Backoff(%d) ranged over [%v, %v] only", attempt, lowest, highest)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	p := NewRetryPolicy(retryConfig())
+	for i := 0; i < 2; i++ {
+		if !p.spend("a.com") {
+			t.Fatalf("retry %d denied within the budget", i+1)
+		}
+	}
+	if p.spend("a.com") {
+		t.Fatal("retry allowed over the budget")
+	}
+	// Budgets are per host
+	if !p.spend("b.com") {
+		t.Fatal("retry of another host denied")
+	}
+
+	// A new window starts with the full budget
+	p.budgets["a.com"].windowStart = time.Now().Add(-2 * time.Minute)
+	if !p.spend("a.com") {
+		t.Fatal("retry denied in a new window")
+	}
+
+	cfg := retryConfig()
+	cfg.HostBudget = 0
+	unlimited := NewRetryPolicy(cfg)
+	for i := 0; i < 100; i++ {
+		if !unlimited.spend("a.com") {
+			t.Fatal("retry denied without a budget")
+		}
+	}
+}
+
+func TestFetchWithRetry(t *testing.T) {
+	var requests int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		if r.URL.Path == "/down" || n <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html></html>")
+	}))
+	defer srv.Close()
+
+	cfg := config.DefaultConfig().HTTP
+	cfg.Retry = retryConfig()
+	cfg.Retry.BaseDelay = time.Millisecond
+	cfg.Retry.HostBudget = 3
+	f, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := f.FetchWithRetry(context.Background(), srv.URL+"/", nil)
+	if err != nil || resp.StatusCode != 200 || atomic.LoadInt64(&requests) != 3 {
+		t.Fatalf("FetchWithRetry() = %v after %d requests", err, atomic.LoadInt64(&requests))
+	}
+
+	// One retry is left in the budget, so the second attempt is the last
+	_, err = f.FetchWithRetry(context.Background(), srv.URL+"/down", nil)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 2 || retryErr.StatusCode != 503 {
+		t.Fatalf("FetchWithRetry() = %v, want a RetryError after 2 attempts", err)
+	}
+	entries, _ := f.DeadLetters().List(context.Background())
+	if len(entries) != 1 || entries[0].URL != srv.URL+"/down" || entries[0].Attempts != 2 {
+		t.Fatalf("dead letters = %+v", entries)
+	}
+
+	stats := f.retry.GetStats()
+	if stats["retries"] != 3 || stats["retriesExhausted"] != 1 || stats["retryBudgetDenied"] != 1 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+}
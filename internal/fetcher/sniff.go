@@ -0,0 +1,84 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// htmlMarkers found near the start of a body make it HTML even after leading
+// text, which http.DetectContentType does not skip
+var htmlMarkers = [][]byte{[]byte("<!doctype html"), []byte("<html"), []byte("<head"), []byte("<body")}
+
+// genericTypes say nothing about a body beyond it being text or bytes
+var genericTypes = map[string]bool{
+	"":                         true,
+	"text/plain":               true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+}
+
+// DetectType returns the media type of a body judged from its content with
+// http.DetectContentType, which is told apart from JSON and from HTML behind
+// leading text. It returns an empty string for an empty body.
+func DetectType(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mt := mediaType(http.DetectContentType(body))
+	if mt != "text/plain" {
+		return mt
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return "application/json"
+	}
+	head := trimmed
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	head = bytes.ToLower(head)
+	for _, marker := range htmlMarkers {
+		if bytes.Contains(head, marker) {
+			return "text/html"
+		}
+	}
+	return mt
+}
+
+// typeFamily groups media types by how the crawler parses them: html, json,
+// text for other text and XML, or binary
+func typeFamily(mt string) string {
+	switch {
+	case mt == "text/html" || mt == "application/xhtml+xml":
+		return "html"
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		return "json"
+	case strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "xml"):
+		return "text"
+	}
+	return "binary"
+}
+
+// effectiveType returns the Content-Type a body is parsed as. The declared
+// one is kept unless it is missing or generic, or it claims HTML or JSON for
+// a body detected as the other or as binary. Text sniffed as plain never
+// overrides a declared type. The declared charset carries over to text.
+func effectiveType(declared, detected string) string {
+	if genericTypes[detected] {
+		return declared
+	}
+	mt := mediaType(declared)
+	from, to := typeFamily(mt), typeFamily(detected)
+	if !genericTypes[mt] && (from != "html" && from != "json" || to == "text" || to == from) {
+		return declared
+	}
+
+	if _, params, err := mime.ParseMediaType(declared); err == nil && params["charset"] != "" && to != "binary" {
+		return mime.FormatMediaType(detected, map[string]string{"charset": params["charset"]})
+	}
+	return detected
+}
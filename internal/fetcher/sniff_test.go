@@ -0,0 +1,115 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"", ""},
+		{"<!DOCTYPE html><html><body>Hi</body></html>", "text/html"},
+		{"Welcome!\n<html><body>Hi</body></html>", "text/html"},
+		{`  {"name": "value", "list": [1, 2]}`, "application/json"},
+		{"[1, 2, 3]", "application/json"},
+		{"{not json", "text/plain"},
+		{"Just some words", "text/plain"},
+		{"%PDF-1.7\n", "application/pdf"},
+		{`<?xml version="1.0"?><rss></rss>`, "text/xml"},
+	}
+	for _, tt := range tests {
+		if got := DetectType([]byte(tt.body)); got != tt.want {
+			t.Errorf("DetectType(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveType(t *testing.T) {
+	tests := []struct {
+		declared string
+		detected string
+		want     string
+	}{
+		{"text/html; charset=utf-8", "text/html", "text/html; charset=utf-8"},
+		{"", "text/html", "text/html"},
+		{"text/plain; charset=iso-8859-1", "text/html", "text/html; charset=iso-8859-1"},
+		{"application/octet-stream", "application/pdf", "application/pdf"},
+		{"text/html", "application/json", "application/json"},
+		{"application/json", "text/html", "text/html"},
+		{"text/html; charset=utf-8", "image/png", "image/png"},
+		// Plain or XML text is not telling enough to override
+		{"text/html", "text/plain", "text/html"},
+		{"application/xhtml+xml", "text/xml", "application/xhtml+xml"},
+		{"text/css", "text/html", "text/css"},
+		{"application/ld+json", "application/json", "application/ld+json"},
+		{"text/plain", "text/plain", "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := effectiveType(tt.declared, tt.detected); got != tt.want {
+			t.Errorf("effectiveType(%q, %q) = %q, want %q", tt.declared, tt.detected, got, tt.want)
+		}
+	}
+}
+
+func TestFetchSniffsContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, "<html><head><title>Mislabeled</title></head></html>")
+		case "/api":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `{"items": []}`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "Hello <b>there</b>")
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		sniff    bool
+		want     string
+		detected string
+	}{
+		{"/page.txt", true, "text/html; charset=utf-8", "text/html"},
+		{"/api", true, "application/json", "application/json"},
+		{"/text", true, "text/html", "text/plain"},
+		{"/page.txt", false, "text/plain; charset=utf-8", ""},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig().HTTP
+		cfg.AllowedContentTypes = []string{"*/*"}
+		cfg.SniffContent = tt.sniff
+		f, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := f.Fetch(context.Background(), srv.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.ContentType != tt.want || resp.DetectedType != tt.detected {
+			t.Errorf("%s: type %q detected %q, want %q detected %q", tt.path, resp.ContentType, resp.DetectedType, tt.want, tt.detected)
+		}
+		if tt.sniff && resp.DeclaredType != resp.Header.Get("Content-Type") {
+			t.Errorf("%s: declared type %q", tt.path, resp.DeclaredType)
+		}
+		mislabeled := int64(0)
+		if resp.ContentType != resp.Header.Get("Content-Type") {
+			mislabeled = 1
+		}
+		if got := f.GetStats()["mislabeledContent"]; got != mislabeled {
+			t.Errorf("%s: mislabeledContent = %d, want %d", tt.path, got, mislabeled)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// TLSInfo is the TLS connection and certificate of a host
+type TLSInfo struct {
+	Host      string    `json:"host"`
+	Version   string    `json:"version,omitempty"` // e.g. TLS 1.3, empty if the certificate was rejected
+	Cipher    string    `json:"cipher,omitempty"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	Valid     bool      `json:"valid"`           // The chain verified for the host
+	Error     string    `json:"error,omitempty"` // Why it did not
+	Insecure  bool      `json:"insecure"`        // Invalid certificates of the host are accepted
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// TLSRecorder sends requests to the insecure domains through a transport
+// that accepts invalid certificates, and records the TLS connection and
+// certificate of the first response from each host
+type TLSRecorder struct {
+	cfg      config.TLSConfig
+	secure   http.RoundTripper
+	insecure http.RoundTripper // Skips certificate verification
+
+	mu    sync.Mutex
+	hosts map[string]*TLSInfo
+
+	// Counters
+	invalid  int64 // Hosts with a certificate that did not verify
+	expiring int64 // Hosts with a certificate expiring within expiry_warning
+	rejected int64 // Requests failed on an invalid certificate
+}
+
+// newTLSRecorder wraps the transports. It returns nil unless certificates
+// are captured or some domains are insecure.
+func newTLSRecorder(cfg config.TLSConfig, secure, insecure *http.Transport) *TLSRecorder {
+	if !cfg.Capture && len(cfg.InsecureDomains) == 0 {
+		return nil
+	}
+	insecure.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &TLSRecorder{cfg: cfg, secure: secure, insecure: insecure, hosts: make(map[string]*TLSInfo)}
+}
+
+// RoundTrip sends req through the transport of its host and records the
+// certificate it was answered with, or rejected for
+func (r *TLSRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Hostname())
+	insecure := inDomains(host, r.cfg.InsecureDomains)
+	next := r.secure
+	if insecure {
+		next = r.insecure
+	}
+	resp, err := next.RoundTrip(req)
+
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case err == nil && resp.TLS != nil && r.cfg.Capture && !r.seen(host):
+		r.record(host, *resp.TLS, resp.TLS.PeerCertificates, insecure)
+	case errors.As(err, &certErr):
+		atomic.AddInt64(&r.rejected, 1)
+		if r.cfg.Capture && !r.seen(host) {
+			r.record(host, tls.ConnectionState{}, certErr.UnverifiedCertificates, insecure)
+		}
+	}
+	return resp, err
+}
+
+// seen reports whether host is recorded
+func (r *TLSRecorder) seen(host string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.hosts[host]
+	return ok
+}
+
+// record verifies the certificate chain of host like the default TLS
+// verification, keeps it unless host was recorded meanwhile and warns about
+// invalid or expiring certificates. Without a handshake, cs is empty.
+func (r *TLSRecorder) record(host string, cs tls.ConnectionState, certs []*x509.Certificate, insecure bool) {
+	if len(certs) == 0 {
+		return
+	}
+	leaf := certs[0]
+	opts := x509.VerifyOptions{DNSName: host, Intermediates: x509.NewCertPool()}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, verifyErr := leaf.Verify(opts)
+
+	info := &TLSInfo{
+		Host:      host,
+		Subject:   leaf.Subject.String(),
+		Issuer:    leaf.Issuer.String(),
+		DNSNames:  leaf.DNSNames,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		Valid:     verifyErr == nil,
+		Insecure:  insecure,
+		SeenAt:    time.Now(),
+	}
+	if cs.Version != 0 {
+		info.Version = tls.VersionName(cs.Version)
+		info.Cipher = tls.CipherSuiteName(cs.CipherSuite)
+	}
+	if verifyErr != nil {
+		info.Error = verifyErr.Error()
+	}
+
+	r.mu.Lock()
+	if _, ok := r.hosts[host]; ok {
+		r.mu.Unlock()
+		return
+	}
+	r.hosts[host] = info
+	r.mu.Unlock()
+
+	switch left := time.Until(leaf.NotAfter); {
+	case verifyErr != nil:
+		atomic.AddInt64(&r.invalid, 1)
+		log.Warn("Invalid TLS certificate for %s: %v", host, verifyErr)
+	case left < r.cfg.ExpiryWarning:
+		atomic.AddInt64(&r.expiring, 1)
+		log.Warn("TLS certificate for %s expires in %s, on %s", host, left.Round(time.Hour), leaf.NotAfter.Format(time.RFC3339))
+	}
+}
+
+// Hosts returns the recorded hosts sorted by name
+func (r *TLSRecorder) Hosts() []TLSInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	hosts := make([]TLSInfo, 0, len(r.hosts))
+	for _, info := range r.hosts {
+		hosts = append(hosts, *info)
+	}
+	r.mu.Unlock()
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// Export writes the recorded hosts to the configured output file as JSON
+func (r *TLSRecorder) Export() error {
+	if r == nil || !r.cfg.Capture || r.cfg.Output == "" {
+		return nil
+	}
+	hosts := r.Hosts()
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(r.cfg.Output); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create TLS report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(r.cfg.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write TLS report: %w", err)
+	}
+	log.Info("Exported TLS details of %d hosts to %s", len(hosts), r.cfg.Output)
+	return nil
+}
+
+// GetStats returns the hosts recorded, those with invalid or expiring
+// certificates, and the handshakes rejected
+func (r *TLSRecorder) GetStats() map[string]int64 {
+	r.mu.Lock()
+	hosts := len(r.hosts)
+	r.mu.Unlock()
+	return map[string]int64{
+		"tlsHosts":    int64(hosts),
+		"tlsInvalid":  atomic.LoadInt64(&r.invalid),
+		"tlsExpiring": atomic.LoadInt64(&r.expiring),
+		"tlsRejected": atomic.LoadInt64(&r.rejected),
+	}
+}
@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestTLSRecorder(t *testing.T) {
+	// The test server's certificate is not signed by a trusted root
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html></html>")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		insecure []string
+		fetched  bool
+		stats    map[string]int64
+	}{
+		{"rejected", nil, false, map[string]int64{"tlsHosts": 1, "tlsInvalid": 1, "tlsRejected": 2}},
+		{"insecure", []string{"127.0.0.1"}, true, map[string]int64{"tlsHosts": 1, "tlsInvalid": 1, "tlsRejected": 0}},
+		{"other domain insecure", []string{"example.com"}, false, map[string]int64{"tlsHosts": 1, "tlsInvalid": 1, "tlsRejected": 2}},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig().HTTP
+		cfg.TLS.Capture = true
+		cfg.TLS.Output = filepath.Join(t.TempDir(), "tls.json")
+		cfg.TLS.InsecureDomains = tt.insecure
+		f, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			_, err = f.Fetch(context.Background(), srv.URL+"/", nil)
+		}
+		if fetched := err == nil; fetched != tt.fetched {
+			t.Errorf("%s: Fetch() = %v", tt.name, err)
+		}
+		stats := f.GetStats()
+		for key, want := range tt.stats {
+			if stats[key] != want {
+				t.Errorf("%s: %s = %d, want %d", tt.name, key, stats[key], want)
+			}
+		}
+
+		if err := f.TLS().Export(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(cfg.TLS.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var hosts []TLSInfo
+		if err := json.Unmarshal(data, &hosts); err != nil {
+			t.Fatal(err)
+		}
+		if len(hosts) != 1 {
+			t.Fatalf("%s: exported %s", tt.name, data)
+		}
+		h := hosts[0]
+		if h.Host != "127.0.0.1" || h.Valid || h.Error == "" || h.Issuer == "" || h.NotAfter.IsZero() || h.Insecure != tt.fetched {
+			t.Errorf("%s: host = %+v", tt.name, h)
+		}
+		// Only a completed handshake tells the version and cipher
+		if (h.Version != "" && h.Cipher != "") != tt.fetched {
+			t.Errorf("%s: version %q, cipher %q", tt.name, h.Version, h.Cipher)
+		}
+	}
+}
+
+func TestTLSRecorderDisabled(t *testing.T) {
+	f, err := New(config.DefaultConfig().HTTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.TLS() != nil {
+		t.Fatal("TLS recorder created without capture or insecure domains")
+	}
+	if f.TLS().Hosts() != nil || f.TLS().Export() != nil {
+		t.Fatal("nil recorder reported hosts")
+	}
+
+	// Without a recorder certificates are verified as usual
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	var certErr *tls.CertificateVerificationError
+	if _, err := f.Fetch(context.Background(), srv.URL+"/", nil); err == nil || !errors.As(err, &certErr) {
+		t.Fatalf("Fetch() = %v, want a certificate error", err)
+	}
+}
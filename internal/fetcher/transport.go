@@ -0,0 +1,81 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// newTransport builds the HTTP transport from the transport settings
+func newTransport(cfg config.TransportConfig, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           dial,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     cfg.HTTP2,
+		WriteBufferSize:       64 * 1024,
+		ReadBufferSize:        64 * 1024,
+		// Let Go's HTTP client handle compression automatically
+		DisableCompression: false,
+	}
+	if !cfg.HTTP2 {
+		// A non-nil empty map keeps the transport from upgrading to HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport
+}
+
+// connCounter counts how requests got their connection, to verify that
+// connections are pooled
+type connCounter struct {
+	next http.RoundTripper
+
+	// Counters
+	created int64 // Requests on a new connection
+	reused  int64 // Requests on a pooled connection
+	idle    int64 // Reused connections that were idle in the pool
+	http2   int64 // Responses over HTTP/2
+}
+
+// RoundTrip sends req with a trace recording the connection it got
+func (c *connCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				atomic.AddInt64(&c.created, 1)
+				return
+			}
+			atomic.AddInt64(&c.reused, 1)
+			if info.WasIdle {
+				atomic.AddInt64(&c.idle, 1)
+			}
+		},
+	}
+	resp, err := c.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err == nil && resp.ProtoMajor == 2 {
+		atomic.AddInt64(&c.http2, 1)
+	}
+	return resp, err
+}
+
+// GetStats returns connection statistics for monitoring
+func (c *connCounter) GetStats() map[string]int64 {
+	return map[string]int64{
+		"connsNew":        atomic.LoadInt64(&c.created),
+		"connsReused":     atomic.LoadInt64(&c.reused),
+		"connsReusedIdle": atomic.LoadInt64(&c.idle),
+		"http2Responses":  atomic.LoadInt64(&c.http2),
+	}
+}
@@ -0,0 +1,77 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html></html>")
+	}))
+	defer srv.Close()
+
+	for _, disable := range []bool{false, true} {
+		cfg := config.DefaultConfig().HTTP
+		cfg.Transport.DisableKeepAlives = disable
+		f, err := New(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := f.Fetch(context.Background(), srv.URL+"/", nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		stats := f.GetStats()
+		wantNew, wantReused := int64(1), int64(2)
+		if disable {
+			wantNew, wantReused = 3, 0
+		}
+		if stats["connsNew"] != wantNew || stats["connsReused"] != wantReused || stats["connsReusedIdle"] != wantReused {
+			t.Errorf("keep-alives disabled %v: GetStats() = %v", disable, stats)
+		}
+	}
+}
+
+func TestHTTP2Toggle(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	trusted := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	for _, enabled := range []bool{true, false} {
+		cfg := config.DefaultConfig().HTTP.Transport
+		cfg.HTTP2 = enabled
+		transport := newTransport(cfg, (&net.Dialer{Timeout: time.Second}).DialContext)
+		transport.TLSClientConfig = trusted.Clone()
+		conns := &connCounter{next: transport}
+
+		resp, err := (&http.Client{Transport: conns}).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proto, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		want := "HTTP/1.1"
+		if enabled {
+			want = "HTTP/2.0"
+		}
+		if string(proto) != want || (conns.GetStats()["http2Responses"] == 1) != enabled {
+			t.Errorf("http2 %v: server saw %s, stats %v", enabled, proto, conns.GetStats())
+		}
+	}
+}
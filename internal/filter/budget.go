@@ -0,0 +1,165 @@
+package filter
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+)
+
+// Budget rejection reasons
+const (
+	ReasonDepth = "depth"
+	ReasonPages = "host_pages"
+)
+
+// HostUsage reports how much of its budget a host has consumed
+type HostUsage struct {
+	Pages    int `json:"pages"`
+	MaxPages int `json:"max_pages"` // 0 = unlimited
+	MaxDepth int `json:"max_depth"` // 0 = unlimited
+}
+
+// Budget enforces global and per-host depth and page limits when URLs are queued
+type Budget struct {
+	maxDepth int
+	limits   map[string]config.HostLimit // Exact hosts, "*.domain" wildcards, and "*"
+
+	mu    sync.Mutex
+	pages map[string]int
+
+	rejectedDepth int64
+	rejectedPages int64
+}
+
+// NewBudget creates a budget from the global MaxDepth and per-host HostLimits
+func NewBudget(cfg config.CrawlerConfig) *Budget {
+	limits := make(map[string]config.HostLimit, len(cfg.HostLimits))
+	for pattern, limit := range cfg.HostLimits {
+		limits[strings.ToLower(pattern)] = limit
+	}
+	return &Budget{
+		maxDepth: cfg.MaxDepth,
+		limits:   limits,
+		pages:    make(map[string]int),
+	}
+}
+
+// LimitFor returns the limit for host: an exact entry wins, then the longest
+// matching "*.domain" wildcard, then "*"
+func (b *Budget) LimitFor(host string) (config.HostLimit, bool) {
+	host = normalizeHost(host)
+	if limit, ok := b.limits[host]; ok {
+		return limit, true
+	}
+
+	// Walk up the labels so the most specific wildcard matches first
+	for rest := host; ; {
+		i := strings.IndexByte(rest, '.')
+		if i < 0 {
+			break
+		}
+		if limit, ok := b.limits["*"+rest[i:]]; ok {
+			return limit, true
+		}
+		rest = rest[i+1:]
+	}
+
+	limit, ok := b.limits["*"]
+	return limit, ok
+}
+
+// Check reports whether a URL at depth is within the budget without counting
+// it, so duplicates can be dropped before they use up a host's pages
+func (b *Budget) Check(rawURL string, depth int) (bool, string) {
+	return b.admit(rawURL, depth, false)
+}
+
+// Admit reports whether a URL at depth may be queued and, if so, counts it
+// against its host's page budget. Only call it for URLs that are new.
+func (b *Budget) Admit(rawURL string, depth int) (bool, string) {
+	return b.admit(rawURL, depth, true)
+}
+
+// admit checks the depth and page limits of rawURL and counts it if count is set
+func (b *Budget) admit(rawURL string, depth int, count bool) (bool, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, ReasonInvalid
+	}
+	host := normalizeHost(u.Host)
+
+	maxDepth := b.maxDepth
+	limit, hasLimit := b.LimitFor(host)
+	if hasLimit && limit.MaxDepth > 0 {
+		maxDepth = limit.MaxDepth
+	}
+	if maxDepth > 0 && depth > maxDepth {
+		atomic.AddInt64(&b.rejectedDepth, 1)
+		return false, ReasonDepth
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if hasLimit && limit.MaxPages > 0 && b.pages[host] >= limit.MaxPages {
+		atomic.AddInt64(&b.rejectedPages, 1)
+		return false, ReasonPages
+	}
+	if count {
+		b.pages[host]++
+	}
+	return true, ""
+}
+
+// Usage returns the budget consumption of every host seen so far
+func (b *Budget) Usage() map[string]HostUsage {
+	b.mu.Lock()
+	pages := make(map[string]int, len(b.pages))
+	for host, n := range b.pages {
+		pages[host] = n
+	}
+	b.mu.Unlock()
+
+	usage := make(map[string]HostUsage, len(pages))
+	for host, n := range pages {
+		limit, _ := b.LimitFor(host)
+		maxDepth := limit.MaxDepth
+		if maxDepth == 0 {
+			maxDepth = b.maxDepth
+		}
+		usage[host] = HostUsage{Pages: n, MaxPages: limit.MaxPages, MaxDepth: maxDepth}
+	}
+	return usage
+}
+
+// ExhaustedHosts returns the hosts that have used up their page budget, sorted
+func (b *Budget) ExhaustedHosts() []string {
+	var hosts []string
+	for host, u := range b.Usage() {
+		if u.MaxPages > 0 && u.Pages >= u.MaxPages {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// GetStats returns budget rejection counts and per-host page consumption
+func (b *Budget) GetStats() map[string]int64 {
+	stats := map[string]int64{
+		"rejected.depth":      atomic.LoadInt64(&b.rejectedDepth),
+		"rejected.host_pages": atomic.LoadInt64(&b.rejectedPages),
+	}
+
+	usage := b.Usage()
+	stats["hosts"] = int64(len(usage))
+	for host, u := range usage {
+		stats["pages."+host] = int64(u.Pages)
+	}
+	stats["exhaustedHosts"] = int64(len(b.ExhaustedHosts()))
+	return stats
+}
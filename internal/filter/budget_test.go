@@ -0,0 +1,48 @@
+package filter
+
+import (
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestBudgetCheckDoesNotCount(t *testing.T) {
+	b := NewBudget(config.CrawlerConfig{
+		HostLimits: map[string]config.HostLimit{"example.com": {MaxPages: 1}},
+	})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.Check("https://example.com/a", 0); !ok {
+			t.Fatal("Check() rejected a host with pages left")
+		}
+	}
+	if ok, _ := b.Admit("https://example.com/a", 0); !ok {
+		t.Fatal("Admit() rejected the first page")
+	}
+	if ok, reason := b.Check("https://example.com/b", 0); ok || reason != ReasonPages {
+		t.Fatalf("Check() after the budget ran out = %v, %q", ok, reason)
+	}
+	if ok, reason := b.Admit("https://example.com/b", 0); ok || reason != ReasonPages {
+		t.Fatalf("Admit() after the budget ran out = %v, %q", ok, reason)
+	}
+	if u := b.Usage()["example.com"]; u.Pages != 1 {
+		t.Fatalf("host used %d pages, want 1", u.Pages)
+	}
+}
+
+func TestBudgetDepth(t *testing.T) {
+	b := NewBudget(config.CrawlerConfig{
+		MaxDepth:   3,
+		HostLimits: map[string]config.HostLimit{"*.example.com": {MaxDepth: 1}},
+	})
+
+	if ok, reason := b.Admit("https://docs.example.com/", 2); ok || reason != ReasonDepth {
+		t.Fatalf("Admit() beyond the host depth = %v, %q", ok, reason)
+	}
+	if ok, _ := b.Admit("https://other.com/", 3); !ok {
+		t.Fatal("Admit() rejected a page within the global depth")
+	}
+	if len(b.Usage()) != 1 {
+		t.Fatalf("rejected page was counted: %v", b.Usage())
+	}
+}
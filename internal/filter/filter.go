@@ -0,0 +1,452 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Crawl scopes of the allowed domains and seed hosts
+const (
+	ScopeHost   = "host"   // The hosts themselves
+	ScopeDomain = "domain" // Every host of their registrable domains
+)
+
+// Rejection reasons reported by Check
+const (
+	ReasonInvalid        = "invalid_url"
+	ReasonScheme         = "scheme"
+	ReasonDomain         = "domain"
+	ReasonPath           = "excluded_path"
+	ReasonExtension      = "excluded_extension"
+	ReasonIncludePattern = "include_pattern"
+	ReasonExcludePattern = "exclude_pattern"
+	ReasonTrap           = "trap"
+	ReasonLanguage       = "language"
+	ReasonCustom         = "custom"
+	ReasonBlocklist      = "blocklist"
+)
+
+// pattern is a compiled include/exclude regex with its match counter
+type pattern struct {
+	expr string
+	re   *regexp.Regexp
+	hits int64
+}
+
+// rules is the compiled form of FiltersConfig. It is never modified after
+// creation; a reload swaps in a new one.
+type rules struct {
+	schemes        map[string]bool
+	allowedDomains map[string]bool // Hosts, or registrable domains in the domain scope
+	wildcards      []string        // Domains of "*.domain" entries, which cover their subdomains
+	domainScope    bool
+	excludedPaths  []string
+	excludedExts   map[string]bool
+	include        []*pattern
+	exclude        []*pattern
+	skipRels       []string
+	languages      map[string]bool
+}
+
+// Filter decides which discovered URLs may be queued, following FiltersConfig
+type Filter struct {
+	traps *TrapDetector
+
+	mu        sync.RWMutex
+	rules     *rules
+	seedHosts map[string]bool
+	seedSites map[string]bool            // Registrable domains of the seed hosts
+	funcs     []func(rawURL string) bool // Added by embedders, kept across reloads
+	keptExts  map[string]bool            // Extensions let through despite excluded_extensions
+	lists     *DomainLists               // Block and allow lists, nil without any
+
+	// Counters
+	allowed    int64
+	rejections map[string]*int64
+}
+
+// New compiles a filter from the configuration
+func New(cfg config.FiltersConfig) (*Filter, error) {
+	r, err := compileRules(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Filter{
+		rules:      r,
+		seedHosts:  make(map[string]bool),
+		seedSites:  make(map[string]bool),
+		keptExts:   make(map[string]bool),
+		rejections: make(map[string]*int64),
+		traps:      NewTrapDetector(cfg.Traps),
+	}
+
+	for _, reason := range []string{
+		ReasonInvalid, ReasonScheme, ReasonDomain, ReasonPath,
+		ReasonExtension, ReasonIncludePattern, ReasonExcludePattern, ReasonTrap, ReasonLanguage, ReasonCustom,
+		ReasonBlocklist,
+	} {
+		f.rejections[reason] = new(int64)
+	}
+
+	return f, nil
+}
+
+// Reload replaces the filter rules and trap thresholds. If cfg doesn't
+// compile the current rules stay in place. Seed hosts, counters, and the
+// hit counts of patterns kept across the reload are preserved.
+func (f *Filter) Reload(cfg config.FiltersConfig) error {
+	r, err := compileRules(cfg, f.current())
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.rules = r
+	f.mu.Unlock()
+
+	f.traps.SetConfig(cfg.Traps)
+	return nil
+}
+
+// current returns the rules in effect
+func (f *Filter) current() *rules {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.rules
+}
+
+// SkipLinkRels returns the rel values whose links are not followed
+func (f *Filter) SkipLinkRels() []string {
+	return f.current().skipRels
+}
+
+// compileRules builds the rules for cfg, reusing the patterns of prev that are still listed
+func compileRules(cfg config.FiltersConfig, prev *rules) (*rules, error) {
+	r := &rules{
+		schemes:        make(map[string]bool),
+		allowedDomains: make(map[string]bool),
+		excludedPaths:  cfg.ExcludedPaths,
+		excludedExts:   make(map[string]bool),
+		skipRels:       cfg.SkipLinkRels,
+		languages:      make(map[string]bool),
+		domainScope:    cfg.Scope == ScopeDomain,
+	}
+
+	for _, s := range cfg.AllowedSchemes {
+		r.schemes[strings.ToLower(s)] = true
+	}
+	for _, d := range cfg.AllowedDomains {
+		d = normalizeHost(d)
+		switch {
+		case r.domainScope:
+			r.allowedDomains[registrableDomain(strings.TrimPrefix(d, "*."))] = true
+		case strings.HasPrefix(d, "*."):
+			r.wildcards = append(r.wildcards, d[2:])
+		default:
+			r.allowedDomains[d] = true
+		}
+	}
+	for _, ext := range cfg.ExcludedExtensions {
+		r.excludedExts[strings.ToLower(ext)] = true
+	}
+	for _, lang := range cfg.Languages {
+		r.languages[strings.ToLower(lang)] = true
+	}
+
+	var prevInclude, prevExclude []*pattern
+	if prev != nil {
+		prevInclude, prevExclude = prev.include, prev.exclude
+	}
+
+	var err error
+	if r.include, err = compilePatterns(cfg.IncludePatterns, prevInclude); err != nil {
+		return nil, err
+	}
+	if r.exclude, err = compilePatterns(cfg.ExcludePatterns, prevExclude); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// compilePatterns compiles regex patterns, reporting the first invalid one.
+// Patterns already in prev are reused so their hit counts carry over.
+func compilePatterns(exprs []string, prev []*pattern) ([]*pattern, error) {
+	patterns := make([]*pattern, 0, len(exprs))
+	for _, expr := range exprs {
+		if p := findPattern(prev, expr); p != nil {
+			patterns = append(patterns, p)
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, &pattern{expr: expr, re: re})
+	}
+	return patterns, nil
+}
+
+// findPattern returns the pattern with the given expression, or nil
+func findPattern(patterns []*pattern, expr string) *pattern {
+	for _, p := range patterns {
+		if p.expr == expr {
+			return p
+		}
+	}
+	return nil
+}
+
+// normalizeHost lowercases a host and strips the port and www prefix
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok && !strings.HasPrefix(host, "[") {
+		host = h
+	}
+	return strings.TrimPrefix(host, "www.")
+}
+
+// registrableDomain returns the domain of host one label below its public
+// suffix, e.g. example.co.uk for shop.example.co.uk. Hosts without one, such
+// as IP addresses and localhost, are their own domain.
+func registrableDomain(host string) string {
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// AddSeedHost registers the host of a seed URL. When no allowed domains are
+// configured the crawl stays on the seed hosts, or their registrable
+// domains in the domain scope.
+func (f *Filter) AddSeedHost(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	host = normalizeHost(host)
+	f.seedHosts[host] = true
+	f.seedSites[registrableDomain(host)] = true
+}
+
+// AddFunc adds a check that every URL must pass to be queued
+func (f *Filter) AddFunc(fn func(rawURL string) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.funcs = append(f.funcs, fn)
+}
+
+// SetLists makes the filter reject hosts on the blocklists and allow the
+// hosts on the allowlists besides the allowed domains
+func (f *Filter) SetLists(lists *DomainLists) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lists = lists
+}
+
+// domainLists returns the block and allow lists, nil without any
+func (f *Filter) domainLists() *DomainLists {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.lists
+}
+
+// KeepExtensions lets URLs with the given extensions through even when
+// excluded_extensions lists them, across reloads
+func (f *Filter) KeepExtensions(exts ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, ext := range exts {
+		f.keptExts[strings.ToLower(ext)] = true
+	}
+}
+
+// Allow reports whether rawURL may be queued
+func (f *Filter) Allow(rawURL string) bool {
+	ok, _ := f.Check(rawURL)
+	return ok
+}
+
+// InScope reports whether rawURL has an allowed scheme and domain, without
+// the path and pattern rules or counting anything
+func (f *Filter) InScope(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	r := f.current()
+	if len(r.schemes) > 0 && !r.schemes[strings.ToLower(u.Scheme)] {
+		return false
+	}
+	return !f.domainLists().Blocked(normalizeHost(u.Host)) && f.domainAllowed(r, u.Host)
+}
+
+// Check reports whether rawURL may be queued and, if not, why
+func (f *Filter) Check(rawURL string) (bool, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return f.reject(ReasonInvalid)
+	}
+
+	r := f.current()
+	if len(r.schemes) > 0 && !r.schemes[strings.ToLower(u.Scheme)] {
+		return f.reject(ReasonScheme)
+	}
+
+	if f.domainLists().Blocked(normalizeHost(u.Host)) {
+		return f.reject(ReasonBlocklist)
+	}
+	if !f.domainAllowed(r, u.Host) {
+		return f.reject(ReasonDomain)
+	}
+
+	lowerPath := strings.ToLower(u.Path)
+	for _, p := range r.excludedPaths {
+		if strings.HasPrefix(lowerPath, strings.ToLower(p)) {
+			return f.reject(ReasonPath)
+		}
+	}
+
+	if ext := path.Ext(lowerPath); ext != "" && r.excludedExts[ext] && !f.keptExtension(ext) {
+		return f.reject(ReasonExtension)
+	}
+
+	if len(r.include) > 0 {
+		matched := false
+		for _, p := range r.include {
+			if p.re.MatchString(rawURL) {
+				atomic.AddInt64(&p.hits, 1)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return f.reject(ReasonIncludePattern)
+		}
+	}
+
+	for _, p := range r.exclude {
+		if p.re.MatchString(rawURL) {
+			atomic.AddInt64(&p.hits, 1)
+			return f.reject(ReasonExcludePattern)
+		}
+	}
+
+	f.mu.RLock()
+	funcs := f.funcs
+	f.mu.RUnlock()
+	for _, fn := range funcs {
+		if !fn(rawURL) {
+			return f.reject(ReasonCustom)
+		}
+	}
+
+	if f.traps.Check(rawURL, u) != "" {
+		return f.reject(ReasonTrap)
+	}
+
+	atomic.AddInt64(&f.allowed, 1)
+	return true, ""
+}
+
+// CheckLanguage reports whether a page in lang may be stored and followed.
+// Pages whose language is unknown are allowed.
+func (f *Filter) CheckLanguage(lang string) (bool, string) {
+	r := f.current()
+	if len(r.languages) == 0 || lang == "" || r.languages[lang] {
+		return true, ""
+	}
+	return f.reject(ReasonLanguage)
+}
+
+// domainAllowed checks a host against the allowlists and the allowed
+// domains, or the seed hosts if none are configured
+func (f *Filter) domainAllowed(r *rules, host string) bool {
+	host = normalizeHost(host)
+	if f.domainLists().Allowed(host) {
+		return true
+	}
+	if r.domainScope {
+		site := registrableDomain(host)
+		if len(r.allowedDomains) > 0 {
+			return r.allowedDomains[site]
+		}
+
+		f.mu.RLock()
+		defer f.mu.RUnlock()
+
+		return len(f.seedSites) == 0 || f.seedSites[site]
+	}
+
+	if len(r.allowedDomains) > 0 || len(r.wildcards) > 0 {
+		if r.allowedDomains[host] {
+			return true
+		}
+		for _, domain := range r.wildcards {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return len(f.seedHosts) == 0 || f.seedHosts[host]
+}
+
+// keptExtension reports whether ext was passed to KeepExtensions
+func (f *Filter) keptExtension(ext string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.keptExts[ext]
+}
+
+// reject counts a rejection
+func (f *Filter) reject(reason string) (bool, string) {
+	atomic.AddInt64(f.rejections[reason], 1)
+	return false, reason
+}
+
+// GetStats returns rejection counts by reason and match counts per pattern.
+// Include pattern counts are URLs let through by that pattern, exclude
+// pattern counts are URLs rejected by it.
+func (f *Filter) GetStats() map[string]int64 {
+	stats := map[string]int64{
+		"allowed": atomic.LoadInt64(&f.allowed),
+	}
+	for reason, count := range f.rejections {
+		stats["rejected."+reason] = atomic.LoadInt64(count)
+	}
+	r := f.current()
+	for _, p := range r.include {
+		stats["include_pattern:"+p.expr] = atomic.LoadInt64(&p.hits)
+	}
+	for _, p := range r.exclude {
+		stats["exclude_pattern:"+p.expr] = atomic.LoadInt64(&p.hits)
+	}
+	for key, count := range f.traps.GetStats() {
+		stats[key] = count
+	}
+	if lists := f.domainLists(); lists != nil {
+		for key, count := range lists.GetStats() {
+			stats[key] = count
+		}
+	}
+	return stats
+}
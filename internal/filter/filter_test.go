@@ -0,0 +1,178 @@
+package filter
+
+import (
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestFilterReload(t *testing.T) {
+	f, err := New(config.FiltersConfig{
+		AllowedDomains:  []string{"a.com"},
+		ExcludePatterns: []string{`/private/`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Allow("https://a.com/") || f.Allow("https://b.com/") {
+		t.Fatal("initial domains not applied")
+	}
+
+	if err := f.Reload(config.FiltersConfig{
+		AllowedDomains:  []string{"b.com"},
+		ExcludePatterns: []string{`/private/`},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if f.Allow("https://a.com/") || !f.Allow("https://b.com/") {
+		t.Fatal("reloaded domains not applied")
+	}
+	if ok, reason := f.Check("https://b.com/private/x"); ok || reason != ReasonExcludePattern {
+		t.Fatalf("Check() = %v, %q, want the kept exclude pattern to match", ok, reason)
+	}
+
+	// An invalid pattern rejects the whole reload
+	if err := f.Reload(config.FiltersConfig{
+		AllowedDomains:  []string{"c.com"},
+		ExcludePatterns: []string{`(`},
+	}); err == nil {
+		t.Fatal("Reload() accepted an invalid pattern")
+	}
+	if !f.Allow("https://b.com/") || f.Allow("https://c.com/") {
+		t.Fatal("rejected reload changed the rules")
+	}
+}
+
+func TestFilterInScope(t *testing.T) {
+	f, err := New(config.FiltersConfig{
+		AllowedDomains:  []string{"a.com"},
+		ExcludePatterns: []string{`/private/`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"https://a.com/":          true,
+		"https://www.a.com/x":     true,
+		"https://a.com/private/x": true,
+		"https://b.com/":          false,
+		"mailto:someone@a.com":    false,
+		"not a url with no host":  false,
+	}
+	for rawURL, want := range tests {
+		if got := f.InScope(rawURL); got != want {
+			t.Errorf("InScope(%q) = %v, want %v", rawURL, got, want)
+		}
+	}
+	if stats := f.GetStats(); stats["rejected."+ReasonDomain] != 0 {
+		t.Errorf("InScope() counted rejections: %v", stats)
+	}
+}
+
+func TestFilterKeepExtensions(t *testing.T) {
+	f, err := New(config.FiltersConfig{ExcludedExtensions: []string{".pdf", ".zip"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.KeepExtensions(".PDF")
+	if err := f.Reload(config.FiltersConfig{ExcludedExtensions: []string{".pdf", ".zip"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Allow("https://a.com/report.pdf") {
+		t.Error("kept extension rejected after a reload")
+	}
+	if ok, reason := f.Check("https://a.com/files.zip"); ok || reason != ReasonExtension {
+		t.Errorf("Check(.zip) = %v, %q", ok, reason)
+	}
+}
+
+func TestFilterCheckLanguage(t *testing.T) {
+	f, err := New(config.FiltersConfig{Languages: []string{"EN", "de"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lang := range []string{"en", "de", ""} {
+		if ok, _ := f.CheckLanguage(lang); !ok {
+			t.Errorf("CheckLanguage(%q) rejected", lang)
+		}
+	}
+	if ok, reason := f.CheckLanguage("fr"); ok || reason != ReasonLanguage {
+		t.Fatalf("CheckLanguage(fr) = %v, %q", ok, reason)
+	}
+
+	all, err := New(config.FiltersConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := all.CheckLanguage("fr"); !ok {
+		t.Fatal("CheckLanguage() rejected with no languages configured")
+	}
+}
+
+func TestFilterDomainScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.FiltersConfig
+		seeds   []string
+		allowed map[string]bool
+	}{
+		{
+			name:  "seed hosts",
+			seeds: []string{"shop.example.co.uk"},
+			allowed: map[string]bool{
+				"https://shop.example.co.uk/":     true,
+				"https://www.shop.example.co.uk/": true,
+				"https://blog.example.co.uk/":     false,
+			},
+		},
+		{
+			name: "wildcard",
+			cfg:  config.FiltersConfig{AllowedDomains: []string{"a.com", "*.Example.org"}},
+			allowed: map[string]bool{
+				"https://a.com/":            true,
+				"https://sub.a.com/":        false,
+				"https://example.org/":      true,
+				"https://x.y.example.org/":  true,
+				"https://notexample.org/":   false,
+				"https://example.org.evil/": false,
+			},
+		},
+		{
+			name:  "seed domains",
+			cfg:   config.FiltersConfig{Scope: ScopeDomain},
+			seeds: []string{"shop.example.co.uk", "127.0.0.1:8080"},
+			allowed: map[string]bool{
+				"https://blog.example.co.uk/": true,
+				"https://example.co.uk/":      true,
+				"https://other.co.uk/":        false,
+				"http://127.0.0.1:9090/":      true,
+				"http://127.0.0.2/":           false,
+			},
+		},
+		{
+			name: "allowed domains",
+			cfg:  config.FiltersConfig{Scope: ScopeDomain, AllowedDomains: []string{"news.example.com", "*.blog.example.net"}},
+			allowed: map[string]bool{
+				"https://sport.example.com/": true,
+				"https://example.net/":       true,
+				"https://example.org/":       false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, seed := range tt.seeds {
+				f.AddSeedHost(seed)
+			}
+			for rawURL, want := range tt.allowed {
+				if got := f.InScope(rawURL); got != want {
+					t.Errorf("InScope(%q) = %v, want %v", rawURL, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+var log = logger.For("filter")
+
+// domainSet holds domains that match themselves and their subdomains
+type domainSet map[string]struct{}
+
+// match reports whether host or one of its parent domains is in the set.
+// It looks up each suffix of host, so it takes one lookup per label.
+func (s domainSet) match(host string) bool {
+	for {
+		if _, ok := s[host]; ok {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+// listSource is a loaded block or allow list
+type listSource struct {
+	domains domainSet
+	version string // ETag, Last-Modified or file modification time of the loaded list
+}
+
+// DomainLists are block and allow lists of domains loaded from files and
+// URLs, such as ad and tracker blocklists or the hosts of an earlier crawl.
+// Each list is kept as a hash set, and a host matches an entry for itself or
+// any domain above it. Lists are loaded again every refresh interval; one
+// that fails to load keeps its previous entries.
+type DomainLists struct {
+	cfg       config.DomainListConfig
+	client    *http.Client
+	userAgent string
+
+	mu    sync.RWMutex
+	block map[string]*listSource // By file or URL
+	allow map[string]*listSource
+
+	// Counters
+	loads    int64
+	failures int64
+}
+
+// NewDomainLists creates the lists of cfg, which are empty until loaded.
+// It returns nil if no list is configured.
+func NewDomainLists(cfg config.DomainListConfig, client *http.Client, userAgent string) *DomainLists {
+	if len(cfg.Block) == 0 && len(cfg.Allow) == 0 {
+		return nil
+	}
+	return &DomainLists{
+		cfg:       cfg,
+		client:    client,
+		userAgent: userAgent,
+		block:     make(map[string]*listSource),
+		allow:     make(map[string]*listSource),
+	}
+}
+
+// Load loads every list that changed since it was last loaded. It returns
+// the errors of the lists that failed, which keep their previous entries.
+func (l *DomainLists) Load(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	var errs []error
+	for _, kind := range []struct {
+		sources []string
+		lists   map[string]*listSource
+	}{{l.cfg.Block, l.block}, {l.cfg.Allow, l.allow}} {
+		for _, source := range kind.sources {
+			l.mu.RLock()
+			prev := kind.lists[source]
+			l.mu.RUnlock()
+
+			list, err := l.load(ctx, source, prev)
+			if err != nil {
+				atomic.AddInt64(&l.failures, 1)
+				errs = append(errs, fmt.Errorf("failed to load domain list %s: %w", source, err))
+				continue
+			}
+			atomic.AddInt64(&l.loads, 1)
+			if list == prev {
+				continue
+			}
+			l.mu.Lock()
+			kind.lists[source] = list
+			l.mu.Unlock()
+			log.Info("Loaded %d domains from %s", len(list.domains), source)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run loads the lists again every refresh interval until ctx is done
+func (l *DomainLists) Run(ctx context.Context) {
+	if l == nil || l.cfg.Refresh <= 0 {
+		return
+	}
+	ticker := time.NewTicker(l.cfg.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Load(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("%v", err)
+			}
+		}
+	}
+}
+
+// load reads a list from a URL or file. It returns prev if the list hasn't
+// changed since.
+func (l *DomainLists) load(ctx context.Context, source string, prev *listSource) (*listSource, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return l.download(ctx, source, prev)
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	version := info.ModTime().UTC().Format(time.RFC3339Nano)
+	if prev != nil && prev.version == version {
+		return prev, nil
+	}
+	domains, err := parseDomainList(file)
+	if err != nil {
+		return nil, err
+	}
+	return &listSource{domains: domains, version: version}, nil
+}
+
+// download fetches a list, asking the server to answer 304 if it hasn't changed
+func (l *DomainLists) download(ctx context.Context, rawURL string, prev *listSource) (*listSource, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if l.userAgent != "" {
+		req.Header.Set("User-Agent", l.userAgent)
+	}
+	if prev != nil {
+		if strings.HasPrefix(prev.version, "etag:") {
+			req.Header.Set("If-None-Match", strings.TrimPrefix(prev.version, "etag:"))
+		} else {
+			req.Header.Set("If-Modified-Since", prev.version)
+		}
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && prev != nil:
+		return prev, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	domains, err := parseDomainList(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	list := &listSource{domains: domains, version: resp.Header.Get("Last-Modified")}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		list.version = "etag:" + etag
+	}
+	return list, nil
+}
+
+// hostsFileNames are the names hosts files map to themselves rather than block
+var hostsFileNames = map[string]bool{
+	"localhost": true, "localhost.localdomain": true, "local": true,
+	"broadcasthost": true, "ip6-localhost": true, "ip6-loopback": true,
+}
+
+// parseDomainList reads a list of domains. Lines hold a domain, "*.domain",
+// a URL, a hosts file entry ("0.0.0.0 domain") or an Adblock domain rule
+// ("||domain^"). Comments start with # or !, and other Adblock rules are
+// skipped.
+func parseDomainList(r io.Reader) (domainSet, error) {
+	domains := make(domainSet)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		if net.ParseIP(fields[0]) != nil {
+			// Hosts file: an address followed by its names
+			for _, name := range fields[1:] {
+				if !hostsFileNames[strings.ToLower(name)] {
+					addListDomain(domains, name)
+				}
+			}
+			continue
+		}
+		if rule, ok := strings.CutPrefix(fields[0], "||"); ok {
+			// Only whole-domain rules, without options or a path
+			if domain, ok := strings.CutSuffix(rule, "^"); ok {
+				addListDomain(domains, domain)
+			}
+			continue
+		}
+		entry := fields[0]
+		if strings.Contains(entry, "://") {
+			u, err := url.Parse(entry)
+			if err != nil {
+				continue
+			}
+			entry = u.Host
+		}
+		addListDomain(domains, strings.TrimPrefix(entry, "*."))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// addListDomain adds a domain to a list unless it's malformed
+func addListDomain(domains domainSet, domain string) {
+	domain = strings.TrimSuffix(normalizeHost(domain), ".")
+	if domain == "" || strings.ContainsAny(domain, "/@*?=$|^ ") {
+		return
+	}
+	domains[domain] = struct{}{}
+}
+
+// matchAny reports whether host matches a list of lists
+func matchAny(lists map[string]*listSource, host string) bool {
+	for _, list := range lists {
+		if list.domains.match(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocked reports whether a normalized host is on a blocklist
+func (l *DomainLists) Blocked(host string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return matchAny(l.block, host)
+}
+
+// Allowed reports whether a normalized host is on an allowlist
+func (l *DomainLists) Allowed(host string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return matchAny(l.allow, host)
+}
+
+// GetStats returns the domains on the lists and how many loads succeeded and failed
+func (l *DomainLists) GetStats() map[string]int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	stats := map[string]int64{
+		"lists.loads":    atomic.LoadInt64(&l.loads),
+		"lists.failures": atomic.LoadInt64(&l.failures),
+		"lists.block":    0,
+		"lists.allow":    0,
+	}
+	for _, list := range l.block {
+		stats["lists.block"] += int64(len(list.domains))
+	}
+	for _, list := range l.allow {
+		stats["lists.allow"] += int64(len(list.domains))
+	}
+	return stats
+}
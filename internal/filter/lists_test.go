@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestParseDomainList(t *testing.T) {
+	list := `# Hosts file
+0.0.0.0 ads.example.com tracker.example.net # inline comment
+127.0.0.1 localhost
+! Adblock
+||Banner.Example.org^
+||example.org^$third-party
+/ads/*
+*.cdn.example.com
+https://www.Old-Site.com/page
+plain.example.
+`
+	domains, err := parseDomainList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ads.example.com", "tracker.example.net", "banner.example.org", "cdn.example.com", "old-site.com", "plain.example"}
+	if len(domains) != len(want) {
+		t.Errorf("parsed %d domains, want %d: %v", len(domains), len(want), domains)
+	}
+	for _, d := range want {
+		if _, ok := domains[d]; !ok {
+			t.Errorf("%s missing from %v", d, domains)
+		}
+	}
+
+	for host, match := range map[string]bool{
+		"ads.example.com":      true,
+		"img.ads.example.com":  true,
+		"example.com":          false,
+		"badads.example.com":   false,
+		"x.y.cdn.example.com":  true,
+		"banner.example.org.x": false,
+	} {
+		if got := domains.match(host); got != match {
+			t.Errorf("match(%s) = %v, want %v", host, got, match)
+		}
+	}
+}
+
+func TestDomainListsLoad(t *testing.T) {
+	var requests, notModified int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt64(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("ads.example\n"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "allow.txt")
+	if err := os.WriteFile(path, []byte("partner.example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewDomainLists(config.DomainListConfig{
+		Block:   []string{srv.URL},
+		Allow:   []string{path},
+		Timeout: 5 * time.Second,
+	}, srv.Client(), "test")
+	ctx := context.Background()
+	if err := l.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !l.Blocked("www.ads.example") || l.Blocked("partner.example") || !l.Allowed("shop.partner.example") {
+		t.Fatal("lists not loaded")
+	}
+
+	// An unchanged list is answered with 304, a failing one keeps its entries
+	if err := l.Load(ctx); err != nil || atomic.LoadInt64(&notModified) != 1 {
+		t.Fatalf("reload: %v, %d not modified", err, notModified)
+	}
+	os.Remove(path)
+	if err := l.Load(ctx); err == nil {
+		t.Fatal("Load() succeeded without the allow list file")
+	}
+	if !l.Allowed("partner.example") {
+		t.Error("failed reload dropped the allow list")
+	}
+	if stats := l.GetStats(); stats["lists.block"] != 1 || stats["lists.allow"] != 1 || stats["lists.failures"] != 1 || stats["lists.loads"] != 5 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+
+	if NewDomainLists(config.DomainListConfig{}, nil, "") != nil {
+		t.Error("NewDomainLists() without lists returned lists")
+	}
+}
+
+func TestFilterLists(t *testing.T) {
+	dir := t.TempDir()
+	block, allow := filepath.Join(dir, "block.txt"), filepath.Join(dir, "allow.txt")
+	os.WriteFile(block, []byte("ads.a.com\n"), 0644)
+	os.WriteFile(allow, []byte("b.com\n"), 0644)
+
+	f, err := New(config.FiltersConfig{AllowedDomains: []string{"*.a.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists := NewDomainLists(config.DomainListConfig{Block: []string{block}, Allow: []string{allow}}, nil, "")
+	if err := lists.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	f.SetLists(lists)
+
+	if ok, reason := f.Check("https://x.ads.a.com/"); ok || reason != ReasonBlocklist {
+		t.Errorf("Check(blocked) = %v, %q", ok, reason)
+	}
+	if f.InScope("https://ads.a.com/") {
+		t.Error("blocked host in scope")
+	}
+	for _, u := range []string{"https://www.a.com/", "https://shop.b.com/"} {
+		if !f.Allow(u) {
+			t.Errorf("Allow(%s) = false", u)
+		}
+	}
+	if f.Allow("https://c.com/") {
+		t.Error("host on neither list nor allowed domains allowed")
+	}
+	if got := f.GetStats()["rejected.blocklist"]; got != 1 {
+		t.Errorf("rejected.blocklist = %d, want 1", got)
+	}
+}
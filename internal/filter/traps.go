@@ -0,0 +1,229 @@
+package filter
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Trap kinds reported in stats
+const (
+	TrapURLLength       = "url_length"
+	TrapQueryParams     = "query_params"
+	TrapRepeatedSegment = "repeated_segment"
+	TrapCalendar        = "calendar"
+	TrapPagination      = "pagination"
+	TrapPermutations    = "permutations"
+)
+
+var (
+	// pathDatePattern finds a year followed by a month in a path, as in
+	// /2031/05/ or /2031-05-17
+	pathDatePattern = regexp.MustCompile(`(?:^|[/_-])((?:19|20|21)[0-9]{2})(?:/(?:0?[1-9]|1[0-2])(?:/|$)|-(?:0[1-9]|1[0-2])(?:[-/_.]|$))`)
+	// queryDatePattern matches a query value that is a year or a date
+	queryDatePattern = regexp.MustCompile(`^((?:19|20|21)[0-9]{2})(-(?:0[1-9]|1[0-2])(?:-[0-9]{2})?)?$`)
+	// pagePathPattern matches /page/N style pagination
+	pagePathPattern = regexp.MustCompile(`/(?:page|p)/([0-9]+)(?:/|$)`)
+)
+
+// pageParams are query parameters commonly used for pagination
+var pageParams = []string{"page", "p", "pg", "paged", "offset", "start"}
+
+// dateParams are query parameters whose bare year values are calendar dates
+var dateParams = map[string]bool{
+	"year": true, "y": true, "yr": true, "date": true, "month": true,
+	"ym": true, "cal": true, "calendar": true, "day": true,
+}
+
+// maxVariantPaths bounds the number of paths whose query variants are tracked
+const maxVariantPaths = 100000
+
+// TrapDetector detects URLs that are likely to be crawler traps
+type TrapDetector struct {
+	mu       sync.Mutex
+	cfg      config.TrapConfig
+	variants map[string]map[string]struct{} // host+path -> distinct query strings
+
+	trapped int64
+	byKind  map[string]*int64
+}
+
+// NewTrapDetector creates a detector with the configured thresholds. A zero threshold disables that check.
+func NewTrapDetector(cfg config.TrapConfig) *TrapDetector {
+	d := &TrapDetector{
+		cfg:      cfg,
+		variants: make(map[string]map[string]struct{}),
+		byKind:   make(map[string]*int64),
+	}
+	for _, kind := range []string{
+		TrapURLLength, TrapQueryParams, TrapRepeatedSegment,
+		TrapCalendar, TrapPagination, TrapPermutations,
+	} {
+		d.byKind[kind] = new(int64)
+	}
+	return d
+}
+
+// Check returns the kind of trap u looks like, or "" if it looks fine
+func (d *TrapDetector) Check(rawURL string, u *url.URL) string {
+	kind := d.check(rawURL, u)
+	if kind != "" {
+		atomic.AddInt64(&d.trapped, 1)
+		atomic.AddInt64(d.byKind[kind], 1)
+	}
+	return kind
+}
+
+func (d *TrapDetector) check(rawURL string, u *url.URL) string {
+	d.mu.Lock()
+	cfg := d.cfg
+	d.mu.Unlock()
+
+	if cfg.MaxURLLength > 0 && len(rawURL) > cfg.MaxURLLength {
+		return TrapURLLength
+	}
+
+	query := u.Query()
+	if cfg.MaxQueryParams > 0 && len(query) > cfg.MaxQueryParams {
+		return TrapQueryParams
+	}
+
+	if cfg.MaxSegmentRepeats > 0 && repeatedSegments(u.Path) > cfg.MaxSegmentRepeats {
+		return TrapRepeatedSegment
+	}
+
+	if cfg.CalendarYearRange > 0 && calendarTrap(u, cfg.CalendarYearRange) {
+		return TrapCalendar
+	}
+
+	if cfg.MaxPageNumber > 0 && pageNumber(u, query) > cfg.MaxPageNumber {
+		return TrapPagination
+	}
+
+	if cfg.MaxQueryVariants > 0 && u.RawQuery != "" && d.tooManyVariants(u) {
+		return TrapPermutations
+	}
+
+	return ""
+}
+
+// repeatedSegments returns the highest number of times a single path segment repeats
+func repeatedSegments(p string) int {
+	counts := make(map[string]int)
+	highest := 0
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" {
+			continue
+		}
+		counts[seg]++
+		if counts[seg] > highest {
+			highest = counts[seg]
+		}
+	}
+	return highest
+}
+
+// calendarTrap reports whether the URL contains a date too far from the current
+// year, the typical sign of "next month"/"previous month" links on calendars.
+// Only years with date context count: a month after the year in the path, a
+// full date in a query value, or a year in a date parameter such as ?year=.
+// A bare year like /wiki/1989 is not a date.
+func calendarTrap(u *url.URL, yearRange int) bool {
+	current := time.Now().Year()
+	outOfRange := func(s string) bool {
+		year, _ := strconv.Atoi(s)
+		return year > current+1 || year < current-yearRange
+	}
+
+	for _, m := range pathDatePattern.FindAllStringSubmatch(u.Path, -1) {
+		if outOfRange(m[1]) {
+			return true
+		}
+	}
+	for name, values := range u.Query() {
+		for _, value := range values {
+			m := queryDatePattern.FindStringSubmatch(value)
+			if m == nil || (m[2] == "" && !dateParams[strings.ToLower(name)]) {
+				continue
+			}
+			if outOfRange(m[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pageNumber returns the pagination index found in the URL, or 0
+func pageNumber(u *url.URL, query url.Values) int {
+	highest := 0
+	for _, name := range pageParams {
+		if n, err := strconv.Atoi(query.Get(name)); err == nil && n > highest {
+			highest = n
+		}
+	}
+	if m := pagePathPattern.FindStringSubmatch(strings.ToLower(u.Path)); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// tooManyVariants records the query string for the URL's path and reports
+// whether the path already has more distinct query strings than allowed
+func (d *TrapDetector) tooManyVariants(u *url.URL) bool {
+	key := strings.ToLower(u.Host) + u.Path
+
+	// Sort parameters so reordered permutations count as the same variant
+	variant := u.Query().Encode()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen, ok := d.variants[key]
+	if !ok {
+		// Forget an arbitrary path rather than grow without bound
+		if len(d.variants) >= maxVariantPaths {
+			for old := range d.variants {
+				delete(d.variants, old)
+				break
+			}
+		}
+		seen = make(map[string]struct{})
+		d.variants[key] = seen
+	}
+	if _, ok := seen[variant]; ok {
+		return false
+	}
+	if d.cfg.MaxQueryVariants > 0 && len(seen) >= d.cfg.MaxQueryVariants {
+		return true
+	}
+	seen[variant] = struct{}{}
+	return false
+}
+
+// SetConfig replaces the thresholds, keeping the query variants seen so far
+func (d *TrapDetector) SetConfig(cfg config.TrapConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cfg = cfg
+}
+
+// GetStats returns the number of trapped URLs in total and by kind
+func (d *TrapDetector) GetStats() map[string]int64 {
+	stats := map[string]int64{
+		"trapped": atomic.LoadInt64(&d.trapped),
+	}
+	for kind, count := range d.byKind {
+		stats["trap."+kind] = atomic.LoadInt64(count)
+	}
+	return stats
+}
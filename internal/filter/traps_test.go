@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+// checkTrap runs d on rawURL
+func checkTrap(t *testing.T, d *TrapDetector, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.Check(rawURL, u)
+}
+
+func TestTrapDetectorCalendar(t *testing.T) {
+	d := NewTrapDetector(config.TrapConfig{CalendarYearRange: 10})
+
+	tests := map[string]string{
+		"https://en.wikipedia.org/wiki/1989":              "",
+		"https://example.com/history/1950-1999":           "",
+		"https://example.com/product?id=1999":             "",
+		"https://example.com/events/1950/01/":             TrapCalendar,
+		"https://example.com/events/2150/12":              TrapCalendar,
+		"https://example.com/posts/1950-03-14-hello.html": TrapCalendar,
+		"https://example.com/calendar?year=2150":          TrapCalendar,
+		"https://example.com/calendar?when=2150-06":       TrapCalendar,
+		"https://example.com/calendar?date=1950-06-01":    TrapCalendar,
+	}
+	for rawURL, want := range tests {
+		if got := checkTrap(t, d, rawURL); got != want {
+			t.Errorf("Check(%s) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestTrapDetectorThresholds(t *testing.T) {
+	d := NewTrapDetector(config.TrapConfig{
+		MaxURLLength:      60,
+		MaxQueryParams:    2,
+		MaxSegmentRepeats: 2,
+		MaxPageNumber:     100,
+	})
+
+	tests := map[string]string{
+		"https://example.com/a/b/c":                      "",
+		"https://example.com/" + strings.Repeat("a", 60): TrapURLLength,
+		"https://example.com/?a=1&b=2&c=3":               TrapQueryParams,
+		"https://example.com/a/b/a/b/a":                  TrapRepeatedSegment,
+		"https://example.com/list?page=101":              TrapPagination,
+		"https://example.com/blog/page/500/":             TrapPagination,
+		"https://example.com/blog/page/50/":              "",
+	}
+	for rawURL, want := range tests {
+		if got := checkTrap(t, d, rawURL); got != want {
+			t.Errorf("Check(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+	if stats := d.GetStats(); stats["trapped"] != 5 || stats["trap."+TrapPagination] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestTrapDetectorQueryVariants(t *testing.T) {
+	d := NewTrapDetector(config.TrapConfig{MaxQueryVariants: 2})
+
+	for _, rawURL := range []string{
+		"https://example.com/search?a=1&b=2",
+		"https://example.com/search?b=2&a=1", // Same variant reordered
+		"https://example.com/search?a=2",
+	} {
+		if kind := checkTrap(t, d, rawURL); kind != "" {
+			t.Fatalf("Check(%s) = %q", rawURL, kind)
+		}
+	}
+	if kind := checkTrap(t, d, "https://example.com/search?a=3"); kind != TrapPermutations {
+		t.Fatalf("third variant = %q, want %q", kind, TrapPermutations)
+	}
+	if kind := checkTrap(t, d, "https://example.com/other?a=3"); kind != "" {
+		t.Fatalf("variant of another path = %q", kind)
+	}
+}
+
+func TestTrapDetectorBoundsTrackedPaths(t *testing.T) {
+	d := NewTrapDetector(config.TrapConfig{MaxQueryVariants: 10})
+	for i := 0; i < maxVariantPaths+100; i++ {
+		u := &url.URL{Scheme: "https", Host: "example.com", Path: fmt.Sprintf("/p%d", i), RawQuery: "q=1"}
+		d.Check(u.String(), u)
+	}
+	if n := len(d.variants); n > maxVariantPaths {
+		t.Fatalf("tracking %d paths, want at most %d", n, maxVariantPaths)
+	}
+}
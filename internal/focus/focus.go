@@ -0,0 +1,188 @@
+// Package focus scores pages by their relevance to a topic so that a crawl
+// can follow the links of relevant pages first
+package focus
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+)
+
+// Scorer rates pages from 0 to 1 by the keywords they contain and by the
+// TF-IDF cosine similarity of their text to the topic description. Document
+// frequencies are learned from the pages scored so far.
+type Scorer struct {
+	cfg      config.FocusConfig
+	keywords [][]string // Tokens of each keyword or phrase
+	topic    map[string]float64
+
+	mu   sync.Mutex
+	df   map[string]int
+	docs int
+
+	// Counters
+	scored   int64
+	relevant int64
+	pruned   int64
+}
+
+// New creates a scorer. It returns nil if focused crawling is disabled.
+func New(cfg config.FocusConfig) *Scorer {
+	if !cfg.Enabled {
+		return nil
+	}
+	s := &Scorer{cfg: cfg, df: make(map[string]int)}
+	for _, keyword := range cfg.Keywords {
+		if tokens := tokenize(keyword); len(tokens) > 0 {
+			s.keywords = append(s.keywords, tokens)
+		}
+	}
+	if cfg.Topic != "" {
+		s.topic = termFrequencies(tokenize(cfg.Topic))
+	}
+	return s
+}
+
+// Score rates a page by its title and text and learns its terms. With both
+// keywords and a topic the score is the mean of the two.
+func (s *Scorer) Score(title, text string) float64 {
+	tokens := tokenize(title + " " + text)
+	tf := termFrequencies(tokens)
+
+	s.mu.Lock()
+	s.docs++
+	for term := range tf {
+		s.df[term]++
+	}
+	var similarity float64
+	if s.topic != nil {
+		similarity = s.cosine(tf)
+	}
+	s.mu.Unlock()
+
+	var score float64
+	switch {
+	case len(s.keywords) > 0 && s.topic != nil:
+		score = (s.coverage(tokens) + similarity) / 2
+	case len(s.keywords) > 0:
+		score = s.coverage(tokens)
+	default:
+		score = similarity
+	}
+
+	atomic.AddInt64(&s.scored, 1)
+	if score >= s.cfg.Threshold {
+		atomic.AddInt64(&s.relevant, 1)
+	}
+	return score
+}
+
+// LinkPriority returns the queue priority of links found on a page with the
+// given score: high on relevant pages, low on pages without any match
+func (s *Scorer) LinkPriority(score float64) int {
+	switch {
+	case score >= s.cfg.Threshold:
+		return queue.PriorityHigh
+	case score > 0:
+		return queue.PriorityNormal
+	}
+	return queue.PriorityLow
+}
+
+// Follow reports whether the links of a page with the given score are
+// queued, counting the pages whose links are pruned
+func (s *Scorer) Follow(score float64) bool {
+	if score < s.cfg.MinScore {
+		atomic.AddInt64(&s.pruned, 1)
+		return false
+	}
+	return true
+}
+
+// coverage returns the share of keywords found in the tokens
+func (s *Scorer) coverage(tokens []string) float64 {
+	found := 0
+	for _, keyword := range s.keywords {
+		if containsPhrase(tokens, keyword) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(s.keywords))
+}
+
+// cosine returns the TF-IDF cosine similarity of a page to the topic. Must
+// be called with mu held.
+func (s *Scorer) cosine(tf map[string]float64) float64 {
+	var dot, pageNorm, topicNorm float64
+	for term, freq := range tf {
+		w := freq * s.idf(term)
+		pageNorm += w * w
+		if t, ok := s.topic[term]; ok {
+			dot += w * t * s.idf(term)
+		}
+	}
+	for term, freq := range s.topic {
+		w := freq * s.idf(term)
+		topicNorm += w * w
+	}
+	if pageNorm == 0 || topicNorm == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(pageNorm*topicNorm)
+}
+
+// idf is the smoothed inverse document frequency of a term
+func (s *Scorer) idf(term string) float64 {
+	return math.Log(float64(1+s.docs)/float64(1+s.df[term])) + 1
+}
+
+// GetStats returns focused crawling statistics
+func (s *Scorer) GetStats() map[string]int64 {
+	return map[string]int64{
+		"scored":   atomic.LoadInt64(&s.scored),
+		"relevant": atomic.LoadInt64(&s.relevant),
+		"pruned":   atomic.LoadInt64(&s.pruned),
+	}
+}
+
+// tokenize lowercases text and splits it into words and numbers
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// termFrequencies returns the log-scaled frequency of every term
+func termFrequencies(tokens []string) map[string]float64 {
+	counts := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		counts[token]++
+	}
+	tf := make(map[string]float64, len(counts))
+	for term, n := range counts {
+		tf[term] = 1 + math.Log(float64(n))
+	}
+	return tf
+}
+
+// containsPhrase reports whether phrase occurs as consecutive tokens
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j, word := range phrase {
+			if tokens[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package focus
+
+import (
+	"math"
+	"testing"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+)
+
+func TestKeywordCoverage(t *testing.T) {
+	s := New(config.FocusConfig{Enabled: true, Keywords: []string{"Solar Panel", "battery", "  "}, Threshold: 0.5})
+
+	tests := []struct {
+		title, text string
+		want        float64
+	}{
+		{"Home solar panel guide", "Pick a battery that fits.", 1},
+		{"Batteries", "A solar farm with one panel and a battery.", 0.5}, // The phrase needs adjacent words
+		{"Cooking", "Bread and butter.", 0},
+	}
+	for _, tt := range tests {
+		if got := s.Score(tt.title, tt.text); got != tt.want {
+			t.Errorf("Score(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+	if stats := s.GetStats(); stats["scored"] != 3 || stats["relevant"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestTopicSimilarity(t *testing.T) {
+	s := New(config.FocusConfig{Enabled: true, Topic: "rust compiler borrow checker"})
+
+	// Learn some document frequencies first
+	s.Score("", "the weather today is sunny and the park is full")
+	s.Score("", "the market closed higher today")
+
+	related := s.Score("Borrow checker errors", "the rust compiler rejects code that breaks the borrow rules")
+	unrelated := s.Score("Gardening", "the roses need water today")
+	if related <= 0.3 || related > 1 {
+		t.Errorf("related page scored %v", related)
+	}
+	if unrelated != 0 {
+		t.Errorf("unrelated page scored %v", unrelated)
+	}
+
+	// A page with only the topic's words is as similar as it gets
+	if got := s.Score("", "rust compiler borrow checker"); math.Abs(got-1) > 1e-9 {
+		t.Errorf("topic text scored %v, want 1", got)
+	}
+}
+
+func TestKeywordsAndTopicAveraged(t *testing.T) {
+	s := New(config.FocusConfig{Enabled: true, Keywords: []string{"kayak"}, Topic: "kayak"})
+	if got := s.Score("", "kayak"); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("Score() = %v, want 1", got)
+	}
+	if got := s.Score("", "canoe"); got != 0 {
+		t.Fatalf("Score() = %v, want 0", got)
+	}
+}
+
+func TestLinkPriorityAndFollow(t *testing.T) {
+	s := New(config.FocusConfig{Enabled: true, Threshold: 0.4, MinScore: 0.1})
+	tests := []struct {
+		score    float64
+		priority int
+		follow   bool
+	}{
+		{0.9, queue.PriorityHigh, true},
+		{0.4, queue.PriorityHigh, true},
+		{0.2, queue.PriorityNormal, true},
+		{0.05, queue.PriorityNormal, false},
+		{0, queue.PriorityLow, false},
+	}
+	for _, tt := range tests {
+		if p := s.LinkPriority(tt.score); p != tt.priority {
+			t.Errorf("LinkPriority(%v) = %d, want %d", tt.score, p, tt.priority)
+		}
+		if follow := s.Follow(tt.score); follow != tt.follow {
+			t.Errorf("Follow(%v) = %v", tt.score, follow)
+		}
+	}
+	if s.GetStats()["pruned"] != 2 {
+		t.Fatalf("GetStats() = %v", s.GetStats())
+	}
+	if New(config.FocusConfig{}) != nil {
+		t.Fatal("New() returned a scorer while disabled")
+	}
+}
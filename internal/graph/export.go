@@ -0,0 +1,190 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Export writes the graph in the configured formats to the output
+// directory, along with metrics.csv and seeds.txt
+func (g *Graph) Export() error {
+	if g == nil {
+		return nil
+	}
+	dir := g.cfg.OutputDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create graph directory: %w", err)
+	}
+
+	metrics := g.Metrics()
+	for _, format := range g.cfg.Formats {
+		var err error
+		switch format {
+		case "csv":
+			err = writeFile(filepath.Join(dir, "edges.csv"), g.writeEdges)
+		case "graphml":
+			err = writeFile(filepath.Join(dir, "graph.graphml"), func(w *bufio.Writer) error { return g.writeGraphML(w, metrics) })
+		case "dot":
+			err = writeFile(filepath.Join(dir, "graph.dot"), g.writeDOT)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeFile(filepath.Join(dir, "metrics.csv"), func(w *bufio.Writer) error { return writeMetrics(w, metrics) }); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(dir, "seeds.txt"), func(w *bufio.Writer) error { return writeSeeds(w, metrics) }); err != nil {
+		return err
+	}
+
+	stats := g.GetStats()
+	log.Info("Exported link graph of %d nodes and %d edges to %s", stats["nodes"], stats["edges"], dir)
+	return nil
+}
+
+// writeFile creates path and fills it through a buffered writer
+func writeFile(path string, write func(*bufio.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeEdges writes one source,target row per link
+func (g *Graph) writeEdges(w *bufio.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"source", "target"})
+	for source, targets := range g.out {
+		for _, target := range targets {
+			cw.Write([]string{g.urls[source], g.urls[target]})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeGraphML writes the graph with the metrics as node attributes
+func (g *Graph) writeGraphML(w *bufio.Writer, metrics []Metrics) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	w.WriteString(xml.Header)
+	w.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	w.WriteString(`  <key id="url" for="node" attr.name="url" attr.type="string"/>` + "\n")
+	w.WriteString(`  <key id="crawled" for="node" attr.name="crawled" attr.type="boolean"/>` + "\n")
+	w.WriteString(`  <key id="in_degree" for="node" attr.name="in_degree" attr.type="int"/>` + "\n")
+	w.WriteString(`  <key id="out_degree" for="node" attr.name="out_degree" attr.type="int"/>` + "\n")
+	w.WriteString(`  <key id="pagerank" for="node" attr.name="pagerank" attr.type="double"/>` + "\n")
+	w.WriteString(`  <graph id="links" edgedefault="directed">` + "\n")
+	for i, m := range metrics {
+		fmt.Fprintf(w, `    <node id="n%d"><data key="url">`, i)
+		if err := xml.EscapeText(w, []byte(m.URL)); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, `</data><data key="crawled">%t</data><data key="in_degree">%d</data><data key="out_degree">%d</data><data key="pagerank">%s</data></node>`+"\n",
+			m.Crawled, m.InDegree, m.OutDegree, formatRank(m.PageRank))
+	}
+	for source, targets := range g.out {
+		for _, target := range targets {
+			fmt.Fprintf(w, `    <edge source="n%d" target="n%d"/>`+"\n", source, target)
+		}
+	}
+	w.WriteString("  </graph>\n</graphml>\n")
+	return nil
+}
+
+// writeDOT writes the graph in Graphviz DOT with URLs as labels
+func (g *Graph) writeDOT(w *bufio.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	w.WriteString("digraph links {\n")
+	for i, u := range g.urls {
+		fmt.Fprintf(w, "  n%d [label=%s];\n", i, dotQuote(u))
+	}
+	for source, targets := range g.out {
+		for _, target := range targets {
+			fmt.Fprintf(w, "  n%d -> n%d;\n", source, target)
+		}
+	}
+	w.WriteString("}\n")
+	return nil
+}
+
+// writeMetrics writes the metrics of every node, highest PageRank first
+func writeMetrics(w *bufio.Writer, metrics []Metrics) error {
+	ranked := byRank(metrics)
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "crawled", "in_degree", "out_degree", "pagerank"})
+	for _, m := range ranked {
+		cw.Write([]string{
+			m.URL,
+			strconv.FormatBool(m.Crawled),
+			strconv.Itoa(m.InDegree),
+			strconv.Itoa(m.OutDegree),
+			formatRank(m.PageRank),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// priorityNames are the seed file names of the queue priorities
+var priorityNames = [3]string{"high", "normal", "low"}
+
+// writeSeeds writes every node as a seed line, highest PageRank first, with
+// the priority of its Ranking tier
+func writeSeeds(w *bufio.Writer, metrics []Metrics) error {
+	ranked := byRank(metrics)
+	w.WriteString("# Seeds ranked by PageRank, for crawler.seed_file\n")
+	for i, m := range ranked {
+		// The seed file separates columns by commas and whitespace
+		if strings.ContainsAny(m.URL, ", \t") {
+			continue
+		}
+		fmt.Fprintf(w, "%s %s\n", m.URL, priorityNames[tier(i, len(ranked))])
+	}
+	return nil
+}
+
+// byRank returns the metrics sorted by descending PageRank, then URL
+func byRank(metrics []Metrics) []Metrics {
+	ranked := append([]Metrics(nil), metrics...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].PageRank != ranked[j].PageRank {
+			return ranked[i].PageRank > ranked[j].PageRank
+		}
+		return ranked[i].URL < ranked[j].URL
+	})
+	return ranked
+}
+
+func formatRank(rank float64) string {
+	return strconv.FormatFloat(rank, 'g', 6, 64)
+}
+
+// dotQuote quotes a DOT string, escaping quotes and backslashes
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,197 @@
+// Package graph records the link graph of a crawl and exports it with
+// degree and PageRank metrics
+package graph
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+var log = logger.For("graph")
+
+// Graph is a directed graph of pages and the links between them. Nodes are
+// crawled pages and the URLs they link to; edges are deduplicated per page.
+type Graph struct {
+	cfg config.GraphConfig
+
+	mu      sync.Mutex
+	ids     map[string]int32
+	urls    []string
+	crawled []bool
+	out     [][]int32 // Targets of each node
+	in      []int32   // In-degree of each node
+
+	// Counters
+	edges    int64
+	dropped  int64 // Links not recorded because max_nodes was reached
+	rankings int64
+}
+
+// Metrics are the link metrics of one node
+type Metrics struct {
+	URL       string
+	Crawled   bool
+	InDegree  int
+	OutDegree int
+	PageRank  float64
+}
+
+// New creates an empty graph. It returns nil if the link graph is disabled.
+func New(cfg config.GraphConfig) *Graph {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Graph{cfg: cfg, ids: make(map[string]int32)}
+}
+
+// AddPage records a crawled page and its links
+func (g *Graph) AddPage(from string, links []string) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	source, ok := g.node(from)
+	if !ok {
+		atomic.AddInt64(&g.dropped, int64(len(links)))
+		return
+	}
+	g.crawled[source] = true
+
+	seen := make(map[int32]bool, len(g.out[source])+len(links))
+	for _, target := range g.out[source] {
+		seen[target] = true
+	}
+	for _, link := range links {
+		target, ok := g.node(link)
+		if !ok {
+			atomic.AddInt64(&g.dropped, 1)
+			continue
+		}
+		if target == source || seen[target] {
+			continue
+		}
+		seen[target] = true
+		g.out[source] = append(g.out[source], target)
+		g.in[target]++
+		atomic.AddInt64(&g.edges, 1)
+	}
+}
+
+// node returns the id of a URL, adding it unless the graph is full
+func (g *Graph) node(u string) (int32, bool) {
+	if id, ok := g.ids[u]; ok {
+		return id, true
+	}
+	if len(g.urls) >= g.cfg.MaxNodes {
+		return 0, false
+	}
+	id := int32(len(g.urls))
+	g.ids[u] = id
+	g.urls = append(g.urls, u)
+	g.crawled = append(g.crawled, false)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, 0)
+	return id, true
+}
+
+// InDegree returns how many recorded pages link to a URL
+func (g *Graph) InDegree(u string) int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if id, ok := g.ids[u]; ok {
+		return int(g.in[id])
+	}
+	return 0
+}
+
+// Metrics computes in-degree, out-degree and PageRank of every node
+func (g *Graph) Metrics() []Metrics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := len(g.urls)
+	metrics := make([]Metrics, n)
+	for i, u := range g.urls {
+		metrics[i].URL = u
+		metrics[i].Crawled = g.crawled[i]
+		metrics[i].OutDegree = len(g.out[i])
+		metrics[i].InDegree = int(g.in[i])
+	}
+	for i, rank := range g.pageRank() {
+		metrics[i].PageRank = rank
+	}
+	return metrics
+}
+
+// pageRank runs the power iteration until the ranks change by less than
+// 1e-6 or the configured iterations are done. The rank of nodes without
+// outlinks is spread evenly over all nodes.
+func (g *Graph) pageRank() []float64 {
+	n := len(g.urls)
+	if n == 0 {
+		return nil
+	}
+	d := g.cfg.Damping
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	for iter := 0; iter < g.cfg.Iterations; iter++ {
+		dangling := 0.0
+		for i := range rank {
+			if len(g.out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-d)/float64(n) + d*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, targets := range g.out {
+			if len(targets) == 0 {
+				continue
+			}
+			share := d * rank[i] / float64(len(targets))
+			for _, target := range targets {
+				next[target] += share
+			}
+		}
+
+		delta := 0.0
+		for i := range rank {
+			if diff := next[i] - rank[i]; diff > 0 {
+				delta += diff
+			} else {
+				delta -= diff
+			}
+		}
+		rank, next = next, rank
+		if delta < 1e-6 {
+			break
+		}
+	}
+	return rank
+}
+
+// GetStats returns link graph statistics
+func (g *Graph) GetStats() map[string]int64 {
+	g.mu.Lock()
+	nodes := len(g.urls)
+	g.mu.Unlock()
+	return map[string]int64{
+		"nodes":    int64(nodes),
+		"edges":    atomic.LoadInt64(&g.edges),
+		"dropped":  atomic.LoadInt64(&g.dropped),
+		"rankings": atomic.LoadInt64(&g.rankings),
+	}
+}
@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func newTestGraph(t *testing.T, maxNodes int) *Graph {
+	t.Helper()
+	return New(config.GraphConfig{
+		Enabled:    true,
+		OutputDir:  t.TempDir(),
+		Formats:    []string{"csv", "graphml", "dot"},
+		MaxNodes:   maxNodes,
+		Damping:    0.85,
+		Iterations: 100,
+	})
+}
+
+// metricsByURL indexes metrics by URL
+func metricsByURL(metrics []Metrics) map[string]Metrics {
+	byURL := make(map[string]Metrics, len(metrics))
+	for _, m := range metrics {
+		byURL[m.URL] = m
+	}
+	return byURL
+}
+
+func TestAddPage(t *testing.T) {
+	g := newTestGraph(t, 3)
+	g.AddPage("a", []string{"b", "b", "a", "c", "d"})
+	g.AddPage("a", []string{"b"}) // Recrawl adds no duplicate edge
+	g.AddPage("b", []string{"a"})
+	g.AddPage("e", []string{"a"}) // No room left for the page
+
+	m := metricsByURL(g.Metrics())
+	if len(m) != 3 {
+		t.Fatalf("graph has %d nodes, want 3", len(m))
+	}
+	if a := m["a"]; !a.Crawled || a.OutDegree != 2 || a.InDegree != 1 {
+		t.Errorf("a = %+v", a)
+	}
+	if c := m["c"]; c.Crawled || c.InDegree != 1 || c.OutDegree != 0 {
+		t.Errorf("c = %+v", c)
+	}
+	if got := g.InDegree("b"); got != 1 {
+		t.Errorf("InDegree(b) = %d, want 1", got)
+	}
+	if got := g.InDegree("d"); got != 0 {
+		t.Errorf("InDegree(d) = %d for a dropped node, want 0", got)
+	}
+	if stats := g.GetStats(); stats["nodes"] != 3 || stats["edges"] != 3 || stats["dropped"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestDisabledGraph(t *testing.T) {
+	g := New(config.GraphConfig{})
+	if g != nil {
+		t.Fatal("New() returned a graph while disabled")
+	}
+	g.AddPage("a", []string{"b"})
+	if err := g.Export(); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.InDegree("b"); got != 0 {
+		t.Errorf("InDegree() = %d without a graph", got)
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages map[string][]string
+		want  map[string]float64
+	}{
+		{
+			name:  "cycle",
+			pages: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+			want:  map[string]float64{"a": 1.0 / 3, "b": 1.0 / 3, "c": 1.0 / 3},
+		},
+		{
+			// b has no outlinks, so its rank is spread over both nodes:
+			// a = 0.075 + 0.425 b and a + b = 1
+			name:  "dangling",
+			pages: map[string][]string{"a": {"b"}},
+			want:  map[string]float64{"a": 0.5 / 1.425, "b": 1 - 0.5/1.425},
+		},
+	}
+	for _, tt := range tests {
+		g := newTestGraph(t, 100)
+		for page, links := range tt.pages {
+			g.AddPage(page, links)
+		}
+		m := metricsByURL(g.Metrics())
+		for u, want := range tt.want {
+			if got := m[u].PageRank; math.Abs(got-want) > 1e-5 {
+				t.Errorf("%s: PageRank(%s) = %f, want %f", tt.name, u, got, want)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	g := newTestGraph(t, 100)
+	g.AddPage("https://a.com/", []string{"https://b.com/", `https://c.com/"q"`})
+	g.AddPage("https://b.com/", []string{"https://a.com/", "https://a.com/x?a=1,2"})
+	if err := g.Export(); err != nil {
+		t.Fatal(err)
+	}
+	dir := g.cfg.OutputDir
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	edges := read("edges.csv")
+	if !strings.HasPrefix(edges, "source,target\nhttps://a.com/,https://b.com/\n") || strings.Count(edges, "\n") != 5 {
+		t.Errorf("edges.csv =\n%s", edges)
+	}
+	if graphml := read("graph.graphml"); !strings.Contains(graphml, `<data key="url">https://c.com/&#34;q&#34;</data>`) ||
+		!strings.Contains(graphml, `<edge source="n0" target="n1"/>`) {
+		t.Errorf("graph.graphml =\n%s", graphml)
+	}
+	if dot := read("graph.dot"); !strings.Contains(dot, `n2 [label="https://c.com/\"q\""];`) || !strings.Contains(dot, "n1 -> n0;") {
+		t.Errorf("graph.dot =\n%s", dot)
+	}
+
+	// a.com and b.com link to each other and lead the ranking
+	metrics := strings.Split(read("metrics.csv"), "\n")
+	if metrics[0] != "url,crawled,in_degree,out_degree,pagerank" || !strings.HasPrefix(metrics[1], "https://a.com/,true,1,2,") {
+		t.Errorf("metrics.csv =\n%s", strings.Join(metrics, "\n"))
+	}
+	seeds := read("seeds.txt")
+	if !strings.Contains(seeds, "https://a.com/ high\n") || strings.Contains(seeds, "a=1,2") {
+		t.Errorf("seeds.txt =\n%s", seeds)
+	}
+}
@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"sync/atomic"
+
+	"web-crawler/internal/queue"
+)
+
+// Ranking maps URLs to frontier priorities by their PageRank: the top tenth
+// of the graph is high, the next four tenths normal and the rest low
+type Ranking struct {
+	priorities map[string]int
+}
+
+// Rank computes PageRank over the current graph and returns the priority tiers
+func (g *Graph) Rank() *Ranking {
+	ranked := byRank(g.Metrics())
+	r := &Ranking{priorities: make(map[string]int, len(ranked))}
+	for i, m := range ranked {
+		r.priorities[m.URL] = tier(i, len(ranked))
+	}
+	atomic.AddInt64(&g.rankings, 1)
+	return r
+}
+
+// Priority returns the priority of a URL, false if it is not in the graph
+func (r *Ranking) Priority(u string) (int, bool) {
+	if r == nil {
+		return 0, false
+	}
+	p, ok := r.priorities[u]
+	return p, ok
+}
+
+// tier returns the priority of the i-th of n URLs by descending PageRank
+func tier(i, n int) int {
+	switch {
+	case i < (n+9)/10:
+		return queue.PriorityHigh
+	case i < n/2:
+		return queue.PriorityNormal
+	}
+	return queue.PriorityLow
+}
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"web-crawler/internal/queue"
+)
+
+func TestTier(t *testing.T) {
+	counts := map[int]int{}
+	for i := 0; i < 20; i++ {
+		counts[tier(i, 20)]++
+	}
+	if counts[queue.PriorityHigh] != 2 || counts[queue.PriorityNormal] != 8 || counts[queue.PriorityLow] != 10 {
+		t.Fatalf("tiers of 20 URLs = %v", counts)
+	}
+	// A single URL is in the top tenth
+	if p := tier(0, 1); p != queue.PriorityHigh {
+		t.Fatalf("tier(0, 1) = %d", p)
+	}
+}
+
+func TestRank(t *testing.T) {
+	g := newTestGraph(t, 100)
+	// Every page links to the hub, which links back to the first one
+	for i := 0; i < 9; i++ {
+		g.AddPage(fmt.Sprintf("p%d", i), []string{"hub"})
+	}
+	g.AddPage("hub", []string{"p0"})
+
+	r := g.Rank()
+	if p, ok := r.Priority("hub"); !ok || p != queue.PriorityHigh {
+		t.Fatalf("Priority(hub) = %d, %v", p, ok)
+	}
+	if p, _ := r.Priority("p0"); p != queue.PriorityNormal {
+		t.Fatalf("Priority(p0) = %d", p)
+	}
+	if p, _ := r.Priority("p8"); p != queue.PriorityLow {
+		t.Fatalf("Priority(p8) = %d", p)
+	}
+	if _, ok := r.Priority("unknown"); ok {
+		t.Fatal("URL outside the graph has a priority")
+	}
+	if g.GetStats()["rankings"] != 1 {
+		t.Fatal("ranking not counted")
+	}
+
+	var none *Ranking
+	if _, ok := none.Priority("hub"); ok {
+		t.Fatal("nil ranking has a priority")
+	}
+}
@@ -0,0 +1,297 @@
+// Package graphql detects GraphQL endpoints on crawled hosts, reads their
+// schema by introspection and samples the queries it allows
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+var log = logger.For("graphql")
+
+// maxSampleFields is the most fields selected on the object a sampled query returns
+const maxSampleFields = 10
+
+// errNotJSON is returned for responses that can't come from a GraphQL server
+var errNotJSON = errors.New("response is not JSON")
+
+// detectQuery is answered by every GraphQL server with the root type name
+const detectQuery = "{ __typename }"
+
+// introspectionQuery asks for the whole schema, like GraphiQL does
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+// Endpoint is a detected GraphQL endpoint
+type Endpoint struct {
+	URL           string
+	StatusCode    int
+	Body          []byte // Response to the introspection query, or to detection without it
+	Introspection bool   // The server answered the introspection query
+	QueryType     string
+	MutationType  string
+	Types         int
+	Schema        map[string]interface{} // __schema of the introspection result
+	Samples       []Sample
+}
+
+// Sample is a query run on an endpoint and its result
+type Sample struct {
+	Query  string
+	Data   interface{}
+	Errors []string
+}
+
+// Prober probes hosts for GraphQL endpoints, once each
+type Prober struct {
+	client     *http.Client
+	userAgent  string
+	paths      []string
+	introspect bool
+	samples    int
+	timeout    time.Duration
+	maxSize    int64
+	wait       func(ctx context.Context, host string) error
+
+	mu     sync.Mutex
+	probed map[string]bool // Hosts, by scheme and host
+
+	// Counters
+	requests     int64
+	found        int64
+	introspected int64
+	sampled      int64
+	failed       int64
+}
+
+// New creates a prober sending requests with client, after wait lets them
+// through if set. Responses above maxSize bytes are dropped when it is
+// positive. It returns nil if GraphQL probing is disabled.
+func New(cfg config.GraphQLConfig, client *http.Client, userAgent string, maxSize int64, wait func(ctx context.Context, host string) error) *Prober {
+	if !cfg.Enabled {
+		return nil
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &Prober{
+		client:     client,
+		userAgent:  userAgent,
+		paths:      cfg.Paths,
+		introspect: cfg.Introspect,
+		samples:    cfg.Samples,
+		timeout:    cfg.Timeout,
+		maxSize:    maxSize,
+		wait:       wait,
+		probed:     make(map[string]bool),
+	}
+}
+
+// Candidates returns the URLs to probe on the host of u, nil once the host
+// was handed out before
+func (p *Prober) Candidates(u *url.URL) []string {
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.probed[origin] {
+		return nil
+	}
+	p.probed[origin] = true
+
+	urls := make([]string, len(p.paths))
+	for i, path := range p.paths {
+		urls[i] = origin + path
+	}
+	return urls
+}
+
+// Probe checks whether endpoint answers GraphQL queries. It returns nil
+// without an error if it doesn't; otherwise it reads the schema if
+// introspection is enabled and runs the sample queries.
+func (p *Prober) Probe(ctx context.Context, endpoint string) (*Endpoint, error) {
+	res, err := p.query(ctx, endpoint, detectQuery)
+	if errors.Is(err, errNotJSON) {
+		return nil, nil
+	}
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+		return nil, err
+	}
+	if !res.isGraphQL() {
+		return nil, nil
+	}
+	atomic.AddInt64(&p.found, 1)
+	log.Info("Found GraphQL endpoint %s", endpoint)
+	e := &Endpoint{URL: endpoint, StatusCode: res.status, Body: res.body}
+	if !p.introspect {
+		return e, nil
+	}
+
+	res, err = p.query(ctx, endpoint, introspectionQuery)
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+		log.Debug("Introspection of %s failed: %v", endpoint, err)
+		return e, nil
+	}
+	var data struct {
+		Schema map[string]interface{} `json:"__schema"`
+	}
+	if json.Unmarshal(res.Data, &data) != nil || data.Schema == nil {
+		log.Debug("Introspection of %s refused: %s", endpoint, strings.Join(res.messages(), "; "))
+		return e, nil
+	}
+	atomic.AddInt64(&p.introspected, 1)
+	e.StatusCode, e.Body = res.status, res.body
+	e.Introspection = true
+	e.Schema = data.Schema
+	e.QueryType = typeName(data.Schema["queryType"])
+	e.MutationType = typeName(data.Schema["mutationType"])
+	types, _ := data.Schema["types"].([]interface{})
+	e.Types = len(types)
+
+	for _, q := range SampleQueries(data.Schema, p.samples) {
+		res, err := p.query(ctx, endpoint, q)
+		if err != nil {
+			atomic.AddInt64(&p.failed, 1)
+			e.Samples = append(e.Samples, Sample{Query: q, Errors: []string{err.Error()}})
+			continue
+		}
+		atomic.AddInt64(&p.sampled, 1)
+		sample := Sample{Query: q, Errors: res.messages()}
+		json.Unmarshal(res.Data, &sample.Data)
+		e.Samples = append(e.Samples, sample)
+	}
+	return e, nil
+}
+
+// GetStats returns the probe requests sent and endpoints found
+func (p *Prober) GetStats() map[string]int64 {
+	return map[string]int64{
+		"requests":     atomic.LoadInt64(&p.requests),
+		"endpoints":    atomic.LoadInt64(&p.found),
+		"introspected": atomic.LoadInt64(&p.introspected),
+		"samples":      atomic.LoadInt64(&p.sampled),
+		"failed":       atomic.LoadInt64(&p.failed),
+	}
+}
+
+// result is the response to a query
+type result struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+
+	status int
+	body   []byte
+}
+
+// isGraphQL reports whether the response to detectQuery came from a GraphQL
+// server: the root type name, or errors in the GraphQL shape
+func (r *result) isGraphQL() bool {
+	var data map[string]interface{}
+	if json.Unmarshal(r.Data, &data) == nil {
+		if _, ok := data["__typename"].(string); ok {
+			return true
+		}
+	}
+	return len(r.Errors) > 0 && r.Errors[0].Message != ""
+}
+
+// messages returns the messages of the errors
+func (r *result) messages() []string {
+	var msgs []string
+	for _, e := range r.Errors {
+		msgs = append(msgs, e.Message)
+	}
+	return msgs
+}
+
+// query posts a query to endpoint. A response that isn't JSON is an error.
+func (p *Prober) query(ctx context.Context, endpoint, query string) (*result, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if p.wait != nil {
+		if err := p.wait(ctx, u.Host); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	payload, _ := json.Marshal(map[string]string{"query": query})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	if p.userAgent != "" {
+		req.Header.Set("User-Agent", p.userAgent)
+	}
+	atomic.AddInt64(&p.requests, 1)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	var body io.Reader = resp.Body
+	if p.maxSize > 0 {
+		body = io.LimitReader(resp.Body, p.maxSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s: %w", endpoint, err)
+	}
+	if p.maxSize > 0 && int64(len(data)) > p.maxSize {
+		return nil, fmt.Errorf("response of %s exceeds %d bytes", endpoint, p.maxSize)
+	}
+	res := &result{status: resp.StatusCode, body: data}
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, errNotJSON
+	}
+	return res, nil
+}
+
+// typeName returns the name of a { name } type reference
+func typeName(ref interface{}) string {
+	m, _ := ref.(map[string]interface{})
+	name, _ := m["name"].(string)
+	return name
+}
@@ -0,0 +1,192 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// schemaFixture is the introspection result of a small blog API
+const schemaFixture = `{"queryType": {"name": "Query"}, "mutationType": {"name": "Mutation"}, "types": [
+	{"kind": "OBJECT", "name": "Query", "fields": [
+		{"name": "posts", "args": [{"name": "first", "type": {"kind": "SCALAR", "name": "Int"}}],
+			"type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "OBJECT", "name": "Post"}}}},
+		{"name": "post", "args": [{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}],
+			"type": {"kind": "OBJECT", "name": "Post"}},
+		{"name": "search", "args": [{"name": "q", "defaultValue": "\"\"", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}],
+			"type": {"kind": "UNION", "name": "Result"}},
+		{"name": "viewer", "args": [], "type": {"kind": "OBJECT", "name": "Viewer"}},
+		{"name": "version", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+		{"name": "__type", "args": [], "type": {"kind": "OBJECT", "name": "__Type"}}
+	]},
+	{"kind": "OBJECT", "name": "Post", "fields": [
+		{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+		{"name": "title", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+		{"name": "status", "args": [], "type": {"kind": "ENUM", "name": "Status"}},
+		{"name": "author", "args": [], "type": {"kind": "OBJECT", "name": "Viewer"}},
+		{"name": "excerpt", "args": [{"name": "words", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "Int"}}}],
+			"type": {"kind": "SCALAR", "name": "String"}}
+	]},
+	{"kind": "OBJECT", "name": "Viewer", "fields": [
+		{"name": "posts", "args": [], "type": {"kind": "LIST", "ofType": {"kind": "OBJECT", "name": "Post"}}}
+	]},
+	{"kind": "UNION", "name": "Result"},
+	{"kind": "ENUM", "name": "Status"},
+	{"kind": "SCALAR", "name": "String"}
+]}`
+
+func TestSampleQueries(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaFixture), &schema); err != nil {
+		t.Fatal(err)
+	}
+	// post needs an id and viewer has no scalar fields
+	want := "{ posts { id title status } }|{ search { __typename } }|{ version }"
+	if got := strings.Join(SampleQueries(schema, 5), "|"); got != want {
+		t.Errorf("SampleQueries() = %s, want %s", got, want)
+	}
+	if got := SampleQueries(schema, 1); len(got) != 1 {
+		t.Errorf("SampleQueries(1) = %v", got)
+	}
+	if got := SampleQueries(map[string]interface{}{}, 5); len(got) != 0 {
+		t.Errorf("SampleQueries() without types = %v", got)
+	}
+}
+
+// graphQLServer answers queries at /graphql, refusing introspection unless
+// introspect is set
+func graphQLServer(t *testing.T, introspect bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var req struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Query == detectQuery:
+			w.Write([]byte(`{"data": {"__typename": "Query"}}`))
+		case strings.HasPrefix(req.Query, "query IntrospectionQuery"):
+			if !introspect {
+				w.Write([]byte(`{"errors": [{"message": "introspection is disabled"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"__schema": ` + schemaFixture + `}}`))
+		case strings.Contains(req.Query, "posts"):
+			w.Write([]byte(`{"data": {"posts": [{"id": "1", "title": "Hello", "status": "PUBLISHED"}]}}`))
+		default:
+			w.Write([]byte(`{"data": null, "errors": [{"message": "not implemented"}]}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testProber(t *testing.T, introspect bool) *Prober {
+	t.Helper()
+	cfg := config.GraphQLConfig{Enabled: true, Paths: []string{"/api/graphql", "/graphql"}, Introspect: introspect, Samples: 5, Timeout: 5 * time.Second}
+	return New(cfg, nil, "test-agent", 1<<20, nil)
+}
+
+func TestProbe(t *testing.T) {
+	srv := graphQLServer(t, true)
+	p := testProber(t, true)
+
+	u, _ := url.Parse(srv.URL + "/some/page")
+	candidates := p.Candidates(u)
+	if len(candidates) != 2 || candidates[1] != srv.URL+"/graphql" {
+		t.Fatalf("Candidates() = %v", candidates)
+	}
+	if again := p.Candidates(u); again != nil {
+		t.Fatalf("second Candidates() = %v", again)
+	}
+
+	if e, err := p.Probe(context.Background(), candidates[0]); e != nil || err != nil {
+		t.Errorf("Probe(%s) = %v, %v", candidates[0], e, err)
+	}
+	e, err := p.Probe(context.Background(), candidates[1])
+	if err != nil || e == nil {
+		t.Fatalf("Probe() = %v, %v", e, err)
+	}
+	if !e.Introspection || e.QueryType != "Query" || e.MutationType != "Mutation" || e.Types != 6 {
+		t.Errorf("endpoint = %+v", e)
+	}
+	if !strings.Contains(string(e.Body), "__schema") {
+		t.Errorf("body = %s", e.Body)
+	}
+	if len(e.Samples) != 3 {
+		t.Fatalf("samples = %+v", e.Samples)
+	}
+	posts, _ := e.Samples[0].Data.(map[string]interface{})["posts"].([]interface{})
+	if len(posts) != 1 || len(e.Samples[0].Errors) != 0 {
+		t.Errorf("posts sample = %+v", e.Samples[0])
+	}
+	if errs := e.Samples[1].Errors; len(errs) != 1 || errs[0] != "not implemented" {
+		t.Errorf("search sample = %+v", e.Samples[1])
+	}
+
+	stats := p.GetStats()
+	if stats["endpoints"] != 1 || stats["introspected"] != 1 || stats["samples"] != 3 || stats["requests"] != 6 || stats["failed"] != 0 {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestProbeWithoutIntrospection(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		serverIntrospect bool
+		introspect       bool
+		wantRequests     int64
+	}{
+		{"refused", false, true, 2},
+		{"disabled", true, false, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := graphQLServer(t, tt.serverIntrospect)
+			p := testProber(t, tt.introspect)
+			e, err := p.Probe(context.Background(), srv.URL+"/graphql")
+			if err != nil || e == nil {
+				t.Fatalf("Probe() = %v, %v", e, err)
+			}
+			if e.Introspection || e.Schema != nil || len(e.Samples) != 0 {
+				t.Errorf("endpoint = %+v", e)
+			}
+			if string(e.Body) != `{"data": {"__typename": "Query"}}` {
+				t.Errorf("body = %s", e.Body)
+			}
+			if got := p.GetStats()["requests"]; got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestProbeNotGraphQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"items": []}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	e, err := testProber(t, true).Probe(context.Background(), srv.URL+"/graphql")
+	if e != nil || err != nil {
+		t.Errorf("Probe() = %v, %v", e, err)
+	}
+}
+
+func TestNewDisabled(t *testing.T) {
+	if p := New(config.GraphQLConfig{}, nil, "", 0, nil); p != nil {
+		t.Errorf("New() = %v", p)
+	}
+}
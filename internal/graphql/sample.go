@@ -0,0 +1,109 @@
+package graphql
+
+import (
+	"strings"
+)
+
+// SampleQueries builds queries for up to n fields of the query type of an
+// introspected schema, in schema order. Fields with required arguments are
+// skipped since there is no value to pass. Object results select their
+// scalar and enum fields, abstract ones only __typename.
+func SampleQueries(schema map[string]interface{}, n int) []string {
+	types := make(map[string]map[string]interface{})
+	list, _ := schema["types"].([]interface{})
+	for _, t := range list {
+		if t, ok := t.(map[string]interface{}); ok {
+			if name, _ := t["name"].(string); name != "" {
+				types[name] = t
+			}
+		}
+	}
+	root := types[typeName(schema["queryType"])]
+
+	var queries []string
+	for _, field := range fields(root) {
+		if len(queries) >= n {
+			break
+		}
+		if selection := selectField(field, types); selection != "" {
+			queries = append(queries, "{ "+selection+" }")
+		}
+	}
+	return queries
+}
+
+// selectField returns the selection of a field without arguments, empty if
+// it has required ones or nothing to select
+func selectField(field map[string]interface{}, types map[string]map[string]interface{}) string {
+	name, _ := field["name"].(string)
+	if name == "" || strings.HasPrefix(name, "__") || hasRequiredArgs(field) {
+		return ""
+	}
+	kind, typ := namedType(field["type"])
+	switch kind {
+	case "SCALAR", "ENUM":
+		return name
+	case "INTERFACE", "UNION":
+		return name + " { __typename }"
+	case "OBJECT":
+		var leaves []string
+		for _, sub := range fields(types[typ]) {
+			subName, _ := sub["name"].(string)
+			if subKind, _ := namedType(sub["type"]); (subKind == "SCALAR" || subKind == "ENUM") && !hasRequiredArgs(sub) {
+				leaves = append(leaves, subName)
+			}
+			if len(leaves) == maxSampleFields {
+				break
+			}
+		}
+		if len(leaves) == 0 {
+			return ""
+		}
+		return name + " { " + strings.Join(leaves, " ") + " }"
+	}
+	return ""
+}
+
+// fields returns the fields of a type
+func fields(t map[string]interface{}) []map[string]interface{} {
+	list, _ := t["fields"].([]interface{})
+	out := make([]map[string]interface{}, 0, len(list))
+	for _, f := range list {
+		if f, ok := f.(map[string]interface{}); ok {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// hasRequiredArgs reports whether a field has a non-null argument without a
+// default value
+func hasRequiredArgs(field map[string]interface{}) bool {
+	args, _ := field["args"].([]interface{})
+	for _, a := range args {
+		arg, _ := a.(map[string]interface{})
+		typ, _ := arg["type"].(map[string]interface{})
+		if typ["kind"] == "NON_NULL" && arg["defaultValue"] == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// namedType unwraps the list and non-null wrappers of a type reference and
+// returns the kind and name of the named type
+func namedType(ref interface{}) (string, string) {
+	for depth := 0; depth < 10; depth++ {
+		t, ok := ref.(map[string]interface{})
+		if !ok {
+			return "", ""
+		}
+		kind, _ := t["kind"].(string)
+		if kind != "LIST" && kind != "NON_NULL" {
+			name, _ := t["name"].(string)
+			return kind, name
+		}
+		ref = t["ofType"]
+	}
+	return "", ""
+}
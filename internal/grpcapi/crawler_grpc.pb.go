@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: crawler.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CrawlerService_SubmitSeeds_FullMethodName        = "/crawler.v1.CrawlerService/SubmitSeeds"
+	CrawlerService_GetStatus_FullMethodName          = "/crawler.v1.CrawlerService/GetStatus"
+	CrawlerService_StreamCrawledPages_FullMethodName = "/crawler.v1.CrawlerService/StreamCrawledPages"
+	CrawlerService_Stop_FullMethodName               = "/crawler.v1.CrawlerService/Stop"
+)
+
+// CrawlerServiceClient is the client API for CrawlerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CrawlerService mirrors the REST control API for programmatic crawl control
+type CrawlerServiceClient interface {
+	// SubmitSeeds queues new seed URLs on the running crawl
+	SubmitSeeds(ctx context.Context, in *SubmitSeedsRequest, opts ...grpc.CallOption) (*SubmitSeedsResponse, error)
+	// GetStatus returns crawl progress and queue statistics
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// StreamCrawledPages streams every page as it is stored
+	StreamCrawledPages(ctx context.Context, in *StreamCrawledPagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WebPage], error)
+	// Stop shuts the crawl down gracefully
+	Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error)
+}
+
+type crawlerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrawlerServiceClient(cc grpc.ClientConnInterface) CrawlerServiceClient {
+	return &crawlerServiceClient{cc}
+}
+
+func (c *crawlerServiceClient) SubmitSeeds(ctx context.Context, in *SubmitSeedsRequest, opts ...grpc.CallOption) (*SubmitSeedsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitSeedsResponse)
+	err := c.cc.Invoke(ctx, CrawlerService_SubmitSeeds_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, CrawlerService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerServiceClient) StreamCrawledPages(ctx context.Context, in *StreamCrawledPagesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WebPage], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CrawlerService_ServiceDesc.Streams[0], CrawlerService_StreamCrawledPages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamCrawledPagesRequest, WebPage]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrawlerService_StreamCrawledPagesClient = grpc.ServerStreamingClient[WebPage]
+
+func (c *crawlerServiceClient) Stop(ctx context.Context, in *StopRequest, opts ...grpc.CallOption) (*StopResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopResponse)
+	err := c.cc.Invoke(ctx, CrawlerService_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CrawlerServiceServer is the server API for CrawlerService service.
+// All implementations must embed UnimplementedCrawlerServiceServer
+// for forward compatibility.
+//
+// CrawlerService mirrors the REST control API for programmatic crawl control
+type CrawlerServiceServer interface {
+	// SubmitSeeds queues new seed URLs on the running crawl
+	SubmitSeeds(context.Context, *SubmitSeedsRequest) (*SubmitSeedsResponse, error)
+	// GetStatus returns crawl progress and queue statistics
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// StreamCrawledPages streams every page as it is stored
+	StreamCrawledPages(*StreamCrawledPagesRequest, grpc.ServerStreamingServer[WebPage]) error
+	// Stop shuts the crawl down gracefully
+	Stop(context.Context, *StopRequest) (*StopResponse, error)
+	mustEmbedUnimplementedCrawlerServiceServer()
+}
+
+// UnimplementedCrawlerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCrawlerServiceServer struct{}
+
+func (UnimplementedCrawlerServiceServer) SubmitSeeds(context.Context, *SubmitSeedsRequest) (*SubmitSeedsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitSeeds not implemented")
+}
+func (UnimplementedCrawlerServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCrawlerServiceServer) StreamCrawledPages(*StreamCrawledPagesRequest, grpc.ServerStreamingServer[WebPage]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCrawledPages not implemented")
+}
+func (UnimplementedCrawlerServiceServer) Stop(context.Context, *StopRequest) (*StopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedCrawlerServiceServer) mustEmbedUnimplementedCrawlerServiceServer() {}
+func (UnimplementedCrawlerServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeCrawlerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrawlerServiceServer will
+// result in compilation errors.
+type UnsafeCrawlerServiceServer interface {
+	mustEmbedUnimplementedCrawlerServiceServer()
+}
+
+func RegisterCrawlerServiceServer(s grpc.ServiceRegistrar, srv CrawlerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCrawlerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CrawlerService_ServiceDesc, srv)
+}
+
+func _CrawlerService_SubmitSeeds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitSeedsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServiceServer).SubmitSeeds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlerService_SubmitSeeds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServiceServer).SubmitSeeds(ctx, req.(*SubmitSeedsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrawlerService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlerService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrawlerService_StreamCrawledPages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamCrawledPagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrawlerServiceServer).StreamCrawledPages(m, &grpc.GenericServerStream[StreamCrawledPagesRequest, WebPage]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrawlerService_StreamCrawledPagesServer = grpc.ServerStreamingServer[WebPage]
+
+func _CrawlerService_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServiceServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrawlerService_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServiceServer).Stop(ctx, req.(*StopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CrawlerService_ServiceDesc is the grpc.ServiceDesc for CrawlerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CrawlerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crawler.v1.CrawlerService",
+	HandlerType: (*CrawlerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitSeeds",
+			Handler:    _CrawlerService_SubmitSeeds_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _CrawlerService_GetStatus_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _CrawlerService_Stop_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCrawledPages",
+			Handler:       _CrawlerService_StreamCrawledPages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
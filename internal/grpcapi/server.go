@@ -0,0 +1,207 @@
+// Package grpcapi serves the CrawlerService of api/proto/crawler.proto.
+// crawler.pb.go and crawler_grpc.pb.go are generated from that file with
+// protoc-gen-go and protoc-gen-go-grpc.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"web-crawler/internal/api"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/storage"
+)
+
+// log is the logger of the grpcapi package
+var log = logger.For("grpcapi")
+
+// streamBuffer is how many stored pages a slow stream may fall behind before
+// it misses pages
+const streamBuffer = 256
+
+// Server is the gRPC control API for a running crawl
+type Server struct {
+	UnimplementedCrawlerServiceServer
+
+	addr   string
+	ctrl   api.Controller
+	pages  *storage.BroadcastArchiver
+	server *grpc.Server
+
+	done     chan struct{} // Closed on shutdown to end page streams
+	doneOnce sync.Once
+}
+
+// NewServer creates a gRPC server listening on addr. pages may be nil, then
+// StreamCrawledPages is unavailable.
+func NewServer(addr string, ctrl api.Controller, pages *storage.BroadcastArchiver) *Server {
+	s := &Server{
+		addr:   addr,
+		ctrl:   ctrl,
+		pages:  pages,
+		server: grpc.NewServer(),
+		done:   make(chan struct{}),
+	}
+	RegisterCrawlerServiceServer(s.server, s)
+	return s
+}
+
+// Start listens on the server address and serves in the background
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for grpc: %w", err)
+	}
+	s.Serve(ln)
+	return nil
+}
+
+// Serve serves on ln in the background
+func (s *Server) Serve(ln net.Listener) {
+	log.Info("gRPC API listening on %s", ln.Addr())
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Error("gRPC API stopped: %v", err)
+		}
+	}()
+}
+
+// Shutdown ends page streams and waits for pending calls, or stops the
+// server outright once ctx is done
+func (s *Server) Shutdown(ctx context.Context) {
+	s.doneOnce.Do(func() { close(s.done) })
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
+
+// SubmitSeeds queues new seed URLs on the running crawl
+func (s *Server) SubmitSeeds(ctx context.Context, req *SubmitSeedsRequest) (*SubmitSeedsResponse, error) {
+	if len(req.GetUrls()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "urls is required")
+	}
+	added, err := s.ctrl.AddSeeds(req.GetUrls())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Info("gRPC: queued %d seed URLs", added)
+	return &SubmitSeedsResponse{Added: int32(added)}, nil
+}
+
+// GetStatus returns crawl progress and queue statistics. Nested statistics
+// are JSON encoded.
+func (s *Server) GetStatus(ctx context.Context, req *GetStatusRequest) (*GetStatusResponse, error) {
+	stats := make(map[string]string)
+	for key, value := range s.ctrl.Stats() {
+		stats[key] = statString(value)
+	}
+	return &GetStatusResponse{
+		Paused:    s.ctrl.Paused(),
+		RateLimit: s.ctrl.RateLimit().String(),
+		Stats:     stats,
+	}, nil
+}
+
+// statString formats a statistic for the string map of GetStatusResponse
+func statString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case int, int32, int64, uint64, float64, bool:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// StreamCrawledPages sends every page as it is stored until the client goes
+// away or the crawl ends
+func (s *Server) StreamCrawledPages(req *StreamCrawledPagesRequest, stream CrawlerService_StreamCrawledPagesServer) error {
+	if s.pages == nil {
+		return status.Error(codes.Unavailable, "page streaming is not available")
+	}
+	pages, unsubscribe := s.pages.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	host := strings.ToLower(req.GetHost())
+	for {
+		select {
+		case page, ok := <-pages:
+			if !ok {
+				return nil
+			}
+			if host != "" && pageHost(page.URL) != host {
+				continue
+			}
+			if err := stream.Send(webPage(page, req.GetWithoutContent())); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+// pageHost returns the lowercased host of rawURL
+func pageHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// webPage converts a stored page to its protobuf message
+func webPage(page *storage.WebPage, withoutContent bool) *WebPage {
+	msg := &WebPage{
+		Url:             page.URL,
+		Title:           page.Title,
+		Links:           page.Links,
+		CrawledAtUnixMs: page.CrawledAt.UnixMilli(),
+		StatusCode:      int32(page.StatusCode),
+		ContentType:     page.ContentType,
+	}
+	if !withoutContent {
+		msg.Content = page.Content
+	}
+	return msg
+}
+
+// Stop shuts the crawl down gracefully after replying
+func (s *Server) Stop(ctx context.Context, req *StopRequest) (*StopResponse, error) {
+	go func() {
+		log.Warn("gRPC: graceful shutdown requested")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.ctrl.Shutdown(ctx); err != nil {
+			log.Error("Shutdown failed: %v", err)
+		}
+	}()
+	return &StopResponse{Status: "shutting down"}, nil
+}
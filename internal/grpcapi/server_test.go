@@ -0,0 +1,166 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"web-crawler/internal/queue"
+	"web-crawler/internal/storage"
+	"web-crawler/internal/subdomain"
+)
+
+// fakeCrawl is a Controller that records the calls of the gRPC server
+type fakeCrawl struct {
+	seeds    []string
+	shutdown chan struct{}
+}
+
+func (f *fakeCrawl) AddSeeds(urls []string) (int, error) {
+	for _, u := range urls {
+		if u == "not a url" {
+			return 0, errors.New("invalid seed URL")
+		}
+	}
+	f.seeds = append(f.seeds, urls...)
+	return len(urls), nil
+}
+func (f *fakeCrawl) Pause()                                                  {}
+func (f *fakeCrawl) Resume()                                                 {}
+func (f *fakeCrawl) Paused() bool                                            { return true }
+func (f *fakeCrawl) PauseHost(string, time.Duration) queue.PausedHost        { return queue.PausedHost{} }
+func (f *fakeCrawl) ResumeHost(string) (int, bool)                           { return 0, false }
+func (f *fakeCrawl) PausedHosts() []queue.PausedHost                         { return nil }
+func (f *fakeCrawl) SetRateLimit(time.Duration)                              {}
+func (f *fakeCrawl) RateLimit() time.Duration                                { return 500 * time.Millisecond }
+func (f *fakeCrawl) DeadLetters(context.Context) ([]queue.DeadLetter, error) { return nil, nil }
+func (f *fakeCrawl) RequeueDeadLetters(context.Context, []string) (int, error) {
+	return 0, nil
+}
+func (f *fakeCrawl) Subdomains() []subdomain.Domain           { return nil }
+func (f *fakeCrawl) QueueSnapshot(int) (queue.Snapshot, bool) { return queue.Snapshot{}, false }
+func (f *fakeCrawl) QueueItems(func(queue.URLItem) bool) bool { return false }
+func (f *fakeCrawl) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"pagesCrawled": int64(12),
+		"queue":        map[string]int64{"size": 3},
+	}
+}
+func (f *fakeCrawl) Shutdown(context.Context) error {
+	close(f.shutdown)
+	return nil
+}
+
+// dial starts s on an in-memory listener and returns a client for it
+func dial(t *testing.T, s *Server) CrawlerServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	s.Serve(ln)
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewCrawlerServiceClient(conn)
+}
+
+func TestServerSubmitSeedsAndStatus(t *testing.T) {
+	crawl := &fakeCrawl{}
+	client := dial(t, NewServer("", crawl, nil))
+	ctx := context.Background()
+
+	resp, err := client.SubmitSeeds(ctx, &SubmitSeedsRequest{Urls: []string{"https://a.com/", "https://b.com/"}})
+	if err != nil || resp.Added != 2 || len(crawl.seeds) != 2 {
+		t.Fatalf("SubmitSeeds() = %v, %v", resp, err)
+	}
+	if _, err := client.SubmitSeeds(ctx, &SubmitSeedsRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("SubmitSeeds() without URLs = %v, want InvalidArgument", err)
+	}
+	if _, err := client.SubmitSeeds(ctx, &SubmitSeedsRequest{Urls: []string{"not a url"}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("SubmitSeeds() with a bad URL = %v, want InvalidArgument", err)
+	}
+
+	st, err := client.GetStatus(ctx, &GetStatusRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Paused || st.RateLimit != "500ms" || st.Stats["pagesCrawled"] != "12" || st.Stats["queue"] != `{"size":3}` {
+		t.Fatalf("GetStatus() = %v", st)
+	}
+}
+
+func TestServerStreamCrawledPages(t *testing.T) {
+	pages := storage.NewBroadcastArchiver(nil)
+	client := dial(t, NewServer("", &fakeCrawl{}, pages))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamCrawledPages(ctx, &StreamCrawledPagesRequest{Host: "A.com", WithoutContent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Store until the stream has subscribed and received the page
+	received := make(chan *WebPage, 1)
+	go func() {
+		page, err := stream.Recv()
+		if err == nil {
+			received <- page
+		}
+	}()
+	crawledAt := time.UnixMilli(1700000000000)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		pages.Store(ctx, &storage.WebPage{URL: "https://b.com/", Title: "other host", CrawledAt: crawledAt})
+		pages.Store(ctx, &storage.WebPage{URL: "https://a.com/x", Title: "X", Content: "<html>", StatusCode: 200, CrawledAt: crawledAt})
+		select {
+		case page := <-received:
+			if page.Url != "https://a.com/x" || page.Title != "X" || page.Content != "" ||
+				page.StatusCode != 200 || page.CrawledAtUnixMs != 1700000000000 {
+				t.Fatalf("streamed %v", page)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no page streamed")
+		}
+	}
+}
+
+func TestServerStreamWithoutArchiver(t *testing.T) {
+	client := dial(t, NewServer("", &fakeCrawl{}, nil))
+	stream, err := client.StreamCrawledPages(context.Background(), &StreamCrawledPagesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv() = %v, want Unavailable", err)
+	}
+}
+
+func TestServerStop(t *testing.T) {
+	crawl := &fakeCrawl{shutdown: make(chan struct{})}
+	client := dial(t, NewServer("", crawl, nil))
+
+	resp, err := client.Stop(context.Background(), &StopRequest{})
+	if err != nil || resp.Status != "shutting down" {
+		t.Fatalf("Stop() = %v, %v", resp, err)
+	}
+	select {
+	case <-crawl.shutdown:
+	case <-time.After(time.Second):
+		t.Fatal("crawl was not shut down")
+	}
+}
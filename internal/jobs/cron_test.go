@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 10, 10, 31, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2024, 1, 10, 10, 45, 0, 0, time.Local)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 10, 13, 0, 0, 0, time.Local)},
+		{"5,10 3 * * *", time.Date(2024, 1, 11, 3, 5, 0, 0, time.Local)},
+		{"0 0 * * MON-FRI", time.Date(2024, 1, 11, 0, 0, 0, 0, time.Local)},
+		{"0 0 * * 7", time.Date(2024, 1, 14, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 mar *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)},
+		// Both day fields restricted: the 20th or any Friday
+		{"0 12 20 * fri", time.Date(2024, 1, 12, 12, 0, 0, 0, time.Local)},
+		{"@hourly", time.Date(2024, 1, 10, 11, 0, 0, 0, time.Local)},
+		{"@weekly", time.Date(2024, 1, 14, 0, 0, 0, 0, time.Local)},
+		{"@YEARLY", time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"@every 90s", from.Add(90 * time.Second)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) = %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: Next() = %v, want %v", tt.expr, got, tt.want)
+		}
+		if s.String() != tt.expr {
+			t.Errorf("String() = %q, want %q", s.String(), tt.expr)
+		}
+	}
+}
+
+func TestScheduleNextIsAfter(t *testing.T) {
+	s, err := ParseSchedule("30 10 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A schedule due at t fires next a day later, also from within the minute
+	from := time.Date(2024, 1, 10, 10, 30, 20, 0, time.Local)
+	if got, want := s.Next(from), time.Date(2024, 1, 11, 10, 30, 0, 0, time.Local); !got.Equal(want) {
+		t.Fatalf("Next() = %v, want %v", got, want)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@sometimes",
+		"@every 500ms",
+		"@every soon",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", expr)
+		}
+	}
+}
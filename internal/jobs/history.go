@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"web-crawler/internal/api"
+	"web-crawler/internal/config"
+	"web-crawler/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// History records the runs of jobs
+type History interface {
+	Add(ctx context.Context, run api.JobRun) error
+	// List returns the last limit runs of a job, newest first
+	List(ctx context.Context, job string, limit int) ([]api.JobRun, error)
+	Close() error
+}
+
+// NewHistory opens the history configured by cfg. The mongodb backend
+// shares the connection of mongo, which must not be nil.
+func NewHistory(ctx context.Context, cfg config.JobHistoryConfig, mongo *storage.MongoArchiver) (History, error) {
+	if cfg.Backend == "mongodb" {
+		if mongo == nil {
+			return nil, fmt.Errorf("mongodb job history requires a MongoDB connection")
+		}
+		return NewMongoHistory(ctx, mongo.Collection(cfg.Collection))
+	}
+	return NewFileHistory(cfg.Path)
+}
+
+// MemoryHistory keeps runs in memory, e.g. for tests and embedding
+type MemoryHistory struct {
+	mu   sync.Mutex
+	runs []api.JobRun
+}
+
+// NewMemoryHistory creates an empty in-memory history
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{}
+}
+
+// Add records a run
+func (h *MemoryHistory) Add(ctx context.Context, run api.JobRun) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.runs = append(h.runs, run)
+	return nil
+}
+
+// List returns the last limit runs of a job, newest first
+func (h *MemoryHistory) List(ctx context.Context, job string, limit int) ([]api.JobRun, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return lastRuns(h.runs, job, limit), nil
+}
+
+// Close does nothing
+func (h *MemoryHistory) Close() error {
+	return nil
+}
+
+// lastRuns returns the last limit runs of job in runs by start time, newest first
+func lastRuns(runs []api.JobRun, job string, limit int) []api.JobRun {
+	var result []api.JobRun
+	for _, run := range runs {
+		if run.Job == job {
+			result = append(result, run)
+		}
+	}
+	// Runs are recorded when they end, so a long run follows later ones
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Started.After(result[j].Started) })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// FileHistory appends runs to a JSON-lines file that survives restarts
+type FileHistory struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileHistory opens or creates the history file at path
+func NewFileHistory(path string) (*FileHistory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job history directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job history file: %w", err)
+	}
+	return &FileHistory{path: path, file: file}, nil
+}
+
+// Add appends a run to the file
+func (h *FileHistory) Add(ctx context.Context, run api.JobRun) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode job run: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write job run: %w", err)
+	}
+	return nil
+}
+
+// List reads the file and returns the last limit runs of a job, newest first
+func (h *FileHistory) List(ctx context.Context, job string, limit int) ([]api.JobRun, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.Open(h.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job history file: %w", err)
+	}
+	defer file.Close()
+
+	var runs []api.JobRun
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var run api.JobRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue // Skip a torn last line
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read job history file: %w", err)
+	}
+	return lastRuns(runs, job, limit), nil
+}
+
+// Close closes the history file
+func (h *FileHistory) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.file.Close()
+}
+
+// MongoHistory stores runs in a MongoDB collection
+type MongoHistory struct {
+	collection *mongo.Collection
+}
+
+// NewMongoHistory uses collection for the history, indexed by job and start time
+func NewMongoHistory(ctx context.Context, collection *mongo.Collection) (*MongoHistory, error) {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "job", Value: 1}, {Key: "started", Value: -1}},
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	return &MongoHistory{collection: collection}, nil
+}
+
+// Add inserts a run
+func (h *MongoHistory) Add(ctx context.Context, run api.JobRun) error {
+	if _, err := h.collection.InsertOne(ctx, run); err != nil {
+		return fmt.Errorf("failed to store job run: %w", err)
+	}
+	return nil
+}
+
+// List returns the last limit runs of a job, newest first
+func (h *MongoHistory) List(ctx context.Context, job string, limit int) ([]api.JobRun, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "started", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := h.collection.Find(ctx, bson.M{"job": job}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job runs: %w", err)
+	}
+
+	var runs []api.JobRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode job runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Close does nothing, the connection belongs to the archiver
+func (h *MongoHistory) Close() error {
+	return nil
+}
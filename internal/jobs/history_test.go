@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler/internal/api"
+)
+
+// addRuns records runs of two jobs, the longest run of news ending last
+func addRuns(t *testing.T, h History) {
+	t.Helper()
+	start := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	runs := []api.JobRun{
+		{Job: "news", State: StateFinished, Started: start.Add(2 * time.Hour)},
+		{Job: "blog", State: StateFinished, Started: start.Add(3 * time.Hour)},
+		{Job: "news", State: StateSkipped, Started: start.Add(3 * time.Hour)},
+		{Job: "news", State: StateFailed, Started: start.Add(time.Hour)},
+	}
+	for _, run := range runs {
+		if err := h.Add(context.Background(), run); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// states returns the states of runs in order
+func states(runs []api.JobRun) []string {
+	var result []string
+	for _, run := range runs {
+		result = append(result, run.State)
+	}
+	return result
+}
+
+func checkHistory(t *testing.T, h History) {
+	t.Helper()
+	runs, err := h.List(context.Background(), "news", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := states(runs); len(got) != 3 || got[0] != StateSkipped || got[1] != StateFinished || got[2] != StateFailed {
+		t.Fatalf("List() = %v, want newest first", got)
+	}
+	if runs, _ := h.List(context.Background(), "news", 1); len(runs) != 1 || runs[0].State != StateSkipped {
+		t.Fatalf("List() with limit 1 = %v", states(runs))
+	}
+	if runs, _ := h.List(context.Background(), "other", 10); len(runs) != 0 {
+		t.Fatalf("List() of a job without runs = %v", states(runs))
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	h := NewMemoryHistory()
+	addRuns(t, h)
+	checkHistory(t, h)
+}
+
+func TestFileHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs", "history.jsonl")
+	h, err := NewFileHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addRuns(t, h)
+	h.Close()
+
+	// Runs survive a restart and a torn last line is skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"job":"news","sta`)
+	file.Close()
+
+	reopened, err := NewFileHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	checkHistory(t, reopened)
+}
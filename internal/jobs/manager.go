@@ -0,0 +1,549 @@
+// Package jobs runs several named crawls side by side in one process, once
+// or on cron schedules. Every job has its own crawler with its own seeds,
+// filters, queue, dedup state, storage collection and output directories.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"web-crawler/internal/api"
+	"web-crawler/internal/config"
+	"web-crawler/internal/crawler"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the jobs package
+var log = logger.For("jobs")
+
+// Job and run states
+const (
+	StateScheduled = "scheduled" // Waiting for its first scheduled run
+	StateRunning   = "running"
+	StateFinished  = "finished"
+	StateFailed    = "failed"
+	StateSkipped   = "skipped" // A scheduled run found the previous one still running
+)
+
+// What started a run
+const (
+	TriggerAPI      = "api"
+	TriggerConfig   = "config"
+	TriggerSchedule = "schedule"
+)
+
+// Job is a named crawl of a Manager. A job without a schedule runs once
+// when it is created, a scheduled job whenever its schedule fires.
+type Job struct {
+	cfg      config.JobConfig
+	schedule *Schedule // nil for a job that runs once
+
+	mu      sync.Mutex
+	run     *run      // Latest run, nil before the first
+	next    time.Time // Next scheduled run, zero if there is none
+	skipped int64     // Scheduled runs skipped while the previous one was running
+}
+
+// run is one crawl of a job
+type run struct {
+	crawler *crawler.Crawler
+	trigger string
+	started time.Time
+	done    chan struct{}
+
+	// Guarded by the job's mu
+	state    string
+	err      error
+	finished time.Time
+}
+
+// Manager creates, schedules, tracks and stops crawl jobs. Jobs are derived
+// from a base configuration, see Config, and their runs are recorded in a
+// History.
+type Manager struct {
+	base     *config.Config
+	mongoURI string
+	history  History
+	wake     chan struct{}  // Tells Run that the schedules changed
+	runs     sync.WaitGroup // Runs not yet recorded
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewManager creates a manager whose jobs start from base and store pages
+// in MongoDB when mongoURI is set. Runs are kept in memory if history is nil.
+func NewManager(base *config.Config, mongoURI string, history History) *Manager {
+	if history == nil {
+		history = NewMemoryHistory()
+	}
+	return &Manager{
+		base:     base,
+		mongoURI: mongoURI,
+		history:  history,
+		wake:     make(chan struct{}, 1),
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// Config derives the configuration of a job from base. Seeds, filters and
+// limits set on the job replace those of base. Queue logs, checkpoints,
+// dead letters, content, benchmark, graph, subdomain and trace files go to a
+// directory named after the job, Redis keys get the job ID as prefix and
+// pages are stored in their own MongoDB collection. The control API,
+// dashboard and hot reload belong to the process and are turned off.
+func Config(base *config.Config, job config.JobConfig) *config.Config {
+	cfg := *base
+	cfg.Jobs = nil
+	cfg.Crawler.Seeds = job.Seeds
+	cfg.Crawler.SeedFile = ""
+	if job.Workers > 0 {
+		cfg.Crawler.Workers = job.Workers
+	}
+	if job.MaxDepth > 0 {
+		cfg.Crawler.MaxDepth = job.MaxDepth
+	}
+	if job.MaxPages > 0 {
+		cfg.Crawler.MaxPages = job.MaxPages
+	}
+	if len(job.AllowedDomains) > 0 {
+		cfg.Filters.AllowedDomains = job.AllowedDomains
+	}
+	if len(job.IncludePatterns) > 0 {
+		cfg.Filters.IncludePatterns = job.IncludePatterns
+	}
+	if len(job.ExcludePatterns) > 0 {
+		cfg.Filters.ExcludePatterns = job.ExcludePatterns
+	}
+
+	cfg.Storage.MongoDB.Collection = job.Collection
+	if job.Collection == "" {
+		cfg.Storage.MongoDB.Collection = base.Storage.MongoDB.Collection + "_" + job.ID
+	}
+	cfg.Queue.DeadLetter.Collection = base.Queue.DeadLetter.Collection + "_" + job.ID
+	cfg.Queue.Redis.KeyPrefix = base.Queue.Redis.KeyPrefix + job.ID + ":"
+	cfg.Dedup.Redis.KeyPrefix = base.Dedup.Redis.KeyPrefix + job.ID + ":"
+
+	cfg.Queue.Path = jobPath(base.Queue.Path, job.ID)
+	cfg.Queue.DeadLetter.Path = jobPath(base.Queue.DeadLetter.Path, job.ID)
+	cfg.Queue.Overflow.Path = jobPath(base.Queue.Overflow.Path, job.ID)
+	cfg.Checkpoint.Path = jobPath(base.Checkpoint.Path, job.ID)
+	cfg.Logging.Trace.Path = jobPath(base.Logging.Trace.Path, job.ID)
+	cfg.Storage.Publish.FailedPath = jobPath(base.Storage.Publish.FailedPath, job.ID)
+	cfg.ContentSaver.OutputDir = filepath.Join(base.ContentSaver.OutputDir, job.ID)
+	cfg.Benchmark.OutputDir = filepath.Join(base.Benchmark.OutputDir, job.ID)
+	cfg.Graph.OutputDir = filepath.Join(base.Graph.OutputDir, job.ID)
+	cfg.Subdomains.Output = jobPath(base.Subdomains.Output, job.ID)
+	cfg.HTTP.TLS.Output = jobPath(base.HTTP.TLS.Output, job.ID)
+	cfg.LinkCheck.OutputDir = filepath.Join(base.LinkCheck.OutputDir, job.ID)
+	cfg.SEO.OutputDir = filepath.Join(base.SEO.OutputDir, job.ID)
+	cfg.A11y.OutputDir = filepath.Join(base.A11y.OutputDir, job.ID)
+	cfg.Storage.Search.Path = filepath.Join(base.Storage.Search.Path, job.ID)
+	cfg.Storage.Raw.Dir = filepath.Join(base.Storage.Raw.Dir, job.ID)
+	cfg.Storage.Raw.Bucket = base.Storage.Raw.Bucket + "_" + job.ID
+	if base.Storage.Object.Prefix != "" {
+		cfg.Storage.Object.Prefix = base.Storage.Object.Prefix + job.ID + "/"
+	} else {
+		cfg.Storage.Object.Prefix = job.ID + "/"
+	}
+
+	cfg.API.Enabled = false
+	cfg.Debug.Enabled = false // The serve command profiles the whole process
+	cfg.Dashboard.Enabled = false
+	cfg.Reload.Enabled = false
+	return &cfg
+}
+
+// jobPath moves a file into a directory named after the job, e.g.
+// queue_data/frontier.log becomes queue_data/news/frontier.log
+func jobPath(path, id string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), id, filepath.Base(path))
+}
+
+// Load adds the jobs of the config file
+func (m *Manager) Load(jobs []config.JobConfig) error {
+	for _, job := range jobs {
+		if err := m.add(job, TriggerConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds a job through the API. A job without a schedule starts right
+// away and may replace a finished job with the same ID.
+func (m *Manager) Create(job config.JobConfig) error {
+	return m.add(job, TriggerAPI)
+}
+
+// add validates a job and starts or schedules it
+func (m *Manager) add(cfg config.JobConfig, trigger string) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	j := &Job{cfg: cfg}
+	if cfg.Schedule != "" {
+		var err error
+		if j.schedule, err = ParseSchedule(cfg.Schedule); err != nil {
+			return &config.ValidationError{Errors: []config.FieldError{{Path: "job.schedule", Message: err.Error()}}}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.jobs[cfg.ID]; ok && (existing.schedule != nil || existing.running()) {
+		return fmt.Errorf("%w: %s", api.ErrJobExists, cfg.ID)
+	}
+	if j.schedule != nil {
+		j.next = j.schedule.Next(time.Now())
+		m.jobs[cfg.ID] = j
+		m.notify()
+		log.WithJob(cfg.ID).Info("Scheduled job %q, next run at %s", j.schedule, j.next.Format(time.RFC3339))
+		return nil
+	}
+	if err := m.start(j, trigger); err != nil {
+		return err
+	}
+	m.jobs[cfg.ID] = j
+	return nil
+}
+
+// notify wakes Run to recompute the next due job
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start builds a crawler for the job, queues its seeds and runs it in the
+// background
+func (m *Manager) start(j *Job, trigger string) error {
+	cfg := Config(m.base, j.cfg)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	c, err := crawler.New(cfg, crawler.Options{MongoURI: m.mongoURI, JobID: j.cfg.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create job %s: %w", j.cfg.ID, err)
+	}
+	if _, err := c.AddSeeds(j.cfg.Seeds); err != nil {
+		return err
+	}
+
+	r := &run{
+		crawler: c,
+		trigger: trigger,
+		started: time.Now(),
+		done:    make(chan struct{}),
+		state:   StateRunning,
+	}
+	j.mu.Lock()
+	j.run = r
+	j.mu.Unlock()
+
+	m.runs.Add(1)
+	go m.execute(j, r)
+	log.WithJob(j.cfg.ID).Info("Started job with %d seeds", len(j.cfg.Seeds))
+	return nil
+}
+
+// execute crawls until the run finishes or is stopped and records it
+func (m *Manager) execute(j *Job, r *run) {
+	defer m.runs.Done()
+	err := r.crawler.Run(context.Background())
+
+	j.mu.Lock()
+	r.finished = time.Now()
+	r.err = err
+	r.state = StateFinished
+	if err != nil {
+		r.state = StateFailed
+	}
+	j.mu.Unlock()
+	close(r.done)
+
+	if err != nil {
+		log.WithJob(j.cfg.ID).Error("Job failed: %v", err)
+	} else {
+		log.WithJob(j.cfg.ID).Success("Job finished")
+	}
+
+	stats := r.crawler.Stats()
+	record := api.JobRun{
+		Job:          j.cfg.ID,
+		Trigger:      r.trigger,
+		State:        r.state,
+		Started:      r.started,
+		Finished:     r.finished,
+		PagesCrawled: counter(stats, "pagesCrawled"),
+		PagesStored:  counter(stats, "pagesStored"),
+		Errors:       counter(stats, "errors"),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	m.record(record)
+}
+
+// record adds a run to the history, logging failures
+func (m *Manager) record(run api.JobRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := m.history.Add(ctx, run); err != nil {
+		log.WithJob(run.Job).Warn("Failed to record job run: %v", err)
+	}
+}
+
+// counter reads an int64 counter from crawl stats
+func counter(stats map[string]interface{}, key string) int64 {
+	n, _ := stats[key].(int64)
+	return n
+}
+
+// Run starts scheduled jobs when they are due until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(m.untilDue(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-m.wake:
+			timer.Stop()
+		case now := <-timer.C:
+			m.fire(now)
+		}
+	}
+}
+
+// untilDue returns the time until the next scheduled run, or an hour if
+// nothing is scheduled
+func (m *Manager) untilDue(now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wait := time.Hour
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		next := j.next
+		j.mu.Unlock()
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = max(next.Sub(now), 0)
+		}
+	}
+	return wait
+}
+
+// fire starts the scheduled jobs that are due. A job whose previous run is
+// still going is skipped until its next time.
+func (m *Manager) fire(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, j := range m.jobs {
+		j.mu.Lock()
+		due := !j.next.IsZero() && !j.next.After(now)
+		if due {
+			j.next = j.schedule.Next(now)
+		}
+		j.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		if j.running() {
+			j.mu.Lock()
+			j.skipped++
+			j.mu.Unlock()
+			log.WithJob(j.cfg.ID).Warn("Skipped scheduled run, the previous run is still going")
+			m.record(api.JobRun{Job: j.cfg.ID, Trigger: TriggerSchedule, State: StateSkipped, Started: now, Finished: now})
+			continue
+		}
+		if err := m.start(j, TriggerSchedule); err != nil {
+			log.WithJob(j.cfg.ID).Error("Failed to start scheduled run: %v", err)
+			m.record(api.JobRun{Job: j.cfg.ID, Trigger: TriggerSchedule, State: StateFailed, Started: now, Finished: now, Error: err.Error()})
+		}
+	}
+}
+
+// running reports whether the job's latest run is still crawling
+func (j *Job) running() bool {
+	j.mu.Lock()
+	r := j.run
+	j.mu.Unlock()
+	if r == nil {
+		return false
+	}
+
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// status returns the state of the job with the statistics of its latest run
+func (j *Job) status() map[string]interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := map[string]interface{}{
+		"id":    j.cfg.ID,
+		"state": StateScheduled,
+		"seeds": j.cfg.Seeds,
+	}
+	if j.schedule != nil {
+		status["schedule"] = j.schedule.String()
+		status["skipped"] = j.skipped
+		if !j.next.IsZero() {
+			status["next_run"] = j.next
+		}
+	}
+	if r := j.run; r != nil {
+		status["state"] = r.state
+		status["trigger"] = r.trigger
+		status["started"] = r.started
+		status["stats"] = r.crawler.Stats()
+		if !r.finished.IsZero() {
+			status["finished"] = r.finished
+		}
+		if r.err != nil {
+			status["error"] = r.err.Error()
+		}
+	}
+	return status
+}
+
+// stop ends the latest run and waits until it has drained
+func (j *Job) stop(ctx context.Context) error {
+	if !j.running() {
+		return nil
+	}
+	j.mu.Lock()
+	r := j.run
+	j.mu.Unlock()
+	return r.crawler.Shutdown(ctx)
+}
+
+// job returns a job by ID
+func (m *Manager) job(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	return j, ok
+}
+
+// Job returns the crawler of a job's latest run
+func (m *Manager) Job(id string) (api.Controller, bool) {
+	j, ok := m.job(id)
+	if !ok {
+		return nil, false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.run == nil {
+		return nil, false
+	}
+	return j.run.crawler, true
+}
+
+// Status returns the state and statistics of a job
+func (m *Manager) Status(id string) (map[string]interface{}, bool) {
+	j, ok := m.job(id)
+	if !ok {
+		return nil, false
+	}
+	return j.status(), true
+}
+
+// List returns the status of every job, ordered by ID
+func (m *Manager) List() []map[string]interface{} {
+	jobs := m.all()
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].cfg.ID < jobs[b].cfg.ID })
+	list := make([]map[string]interface{}, len(jobs))
+	for i, j := range jobs {
+		list[i] = j.status()
+	}
+	return list
+}
+
+// all returns every job
+func (m *Manager) all() []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// History returns the last limit runs of a job, newest first
+func (m *Manager) History(ctx context.Context, id string, limit int) ([]api.JobRun, error) {
+	return m.history.List(ctx, id, limit)
+}
+
+// Remove unschedules a job, stops it if it is running and forgets it
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	delete(m.jobs, id)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", api.ErrJobNotFound, id)
+	}
+	m.notify()
+
+	if err := j.stop(ctx); err != nil {
+		return fmt.Errorf("failed to stop job %s: %w", id, err)
+	}
+	return nil
+}
+
+// Shutdown stops every running job and waits until they have drained and
+// their runs are recorded. Scheduled jobs stop firing once the context of
+// Run is cancelled.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	jobs := m.all()
+	var wg sync.WaitGroup
+	errs := make([]error, len(jobs))
+	for i, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = j.stop(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	recorded := make(chan struct{})
+	go func() {
+		m.runs.Wait()
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,215 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler/internal/api"
+	"web-crawler/internal/config"
+)
+
+// testBase returns a fast base configuration writing only below a temporary directory
+func testBase(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Crawler.Workers = 2
+	cfg.Crawler.RateLimit = time.Millisecond
+	cfg.Crawler.Timeout = 5 * time.Second
+	cfg.API.Enabled = false
+	cfg.ContentSaver.Enabled = false
+	cfg.Benchmark.Enabled = false
+	cfg.Dashboard.Enabled = false
+	cfg.Checkpoint.Path = filepath.Join(dir, "queue_data", "checkpoint.json")
+	cfg.Queue.Path = filepath.Join(dir, "queue_data", "frontier.log")
+	cfg.Queue.DeadLetter.Path = filepath.Join(dir, "queue_data", "dead_letters.jsonl")
+	return cfg
+}
+
+// testSite serves two linked pages
+func testSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/next">Next</a></body></html>`))
+		case "/next":
+			w.Write([]byte(`<html><body><p>Done</p></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// waitRuns waits until the history holds n runs of job
+func waitRuns(t *testing.T, m *Manager, job string, n int) []api.JobRun {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		runs, err := m.History(context.Background(), job, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) >= n {
+			return runs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s recorded %d runs, want %d", job, len(runs), n)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	base := config.DefaultConfig()
+	base.Filters.AllowedDomains = []string{"base.com"}
+	base.Storage.Object.Prefix = "crawl/"
+
+	cfg := Config(base, config.JobConfig{ID: "news", Seeds: []string{"https://news.com/"}, MaxPages: 5})
+	if cfg.Crawler.MaxPages != 5 || cfg.Crawler.MaxDepth != base.Crawler.MaxDepth || cfg.Crawler.Seeds[0] != "https://news.com/" {
+		t.Errorf("crawler config = %+v", cfg.Crawler)
+	}
+	if len(cfg.Filters.AllowedDomains) != 1 || cfg.Filters.AllowedDomains[0] != "base.com" {
+		t.Errorf("allowed domains = %v, want those of the base", cfg.Filters.AllowedDomains)
+	}
+	if want := filepath.Join("queue_data", "news", "frontier.log"); cfg.Queue.Path != want {
+		t.Errorf("queue path = %s, want %s", cfg.Queue.Path, want)
+	}
+	if cfg.Storage.MongoDB.Collection != base.Storage.MongoDB.Collection+"_news" ||
+		cfg.Queue.Redis.KeyPrefix != base.Queue.Redis.KeyPrefix+"news:" ||
+		cfg.Storage.Object.Prefix != "crawl/news/" {
+		t.Errorf("job storage isn't separated: %s, %s, %s", cfg.Storage.MongoDB.Collection, cfg.Queue.Redis.KeyPrefix, cfg.Storage.Object.Prefix)
+	}
+	if cfg.API.Enabled || cfg.Reload.Enabled || cfg.Dashboard.Enabled {
+		t.Error("job config keeps process-wide features")
+	}
+	// The base stays untouched
+	if base.Crawler.MaxPages == 5 || base.Queue.Path == cfg.Queue.Path {
+		t.Error("Config() changed the base config")
+	}
+
+	if named := Config(base, config.JobConfig{ID: "x", Collection: "pages_x"}); named.Storage.MongoDB.Collection != "pages_x" {
+		t.Errorf("collection = %s, want pages_x", named.Storage.MongoDB.Collection)
+	}
+}
+
+func TestRunJobOnce(t *testing.T) {
+	srv := testSite(t)
+	m := NewManager(testBase(t), "", nil)
+
+	job := config.JobConfig{ID: "site", Seeds: []string{srv.URL + "/"}}
+	if err := m.Create(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Job("site"); !ok {
+		t.Fatal("Job() didn't find the running job")
+	}
+
+	runs := waitRuns(t, m, "site", 1)
+	if r := runs[0]; r.State != StateFinished || r.Trigger != TriggerAPI || r.PagesCrawled != 2 {
+		t.Fatalf("run = %+v", r)
+	}
+	status, ok := m.Status("site")
+	if !ok || status["state"] != StateFinished {
+		t.Fatalf("Status() = %v", status)
+	}
+
+	// A finished job can be created again
+	if err := m.Create(job); err != nil {
+		t.Fatalf("recreating a finished job: %v", err)
+	}
+	waitRuns(t, m, "site", 2)
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	m := NewManager(testBase(t), "", nil)
+
+	var verr *config.ValidationError
+	if err := m.Create(config.JobConfig{ID: "bad id!", Seeds: []string{"https://a.com/"}}); !errors.As(err, &verr) {
+		t.Errorf("invalid ID: Create() = %v, want a validation error", err)
+	}
+	if err := m.Create(config.JobConfig{ID: "cron", Seeds: []string{"https://a.com/"}, Schedule: "61 * * * *"}); !errors.As(err, &verr) {
+		t.Errorf("invalid schedule: Create() = %v, want a validation error", err)
+	}
+
+	scheduled := config.JobConfig{ID: "nightly", Seeds: []string{"https://a.com/"}, Schedule: "@daily"}
+	if err := m.Create(scheduled); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Create(scheduled); !errors.Is(err, api.ErrJobExists) {
+		t.Errorf("duplicate job: Create() = %v, want ErrJobExists", err)
+	}
+	if status, _ := m.Status("nightly"); status["state"] != StateScheduled || status["next_run"] == nil {
+		t.Errorf("scheduled job status = %v", status)
+	}
+	// A scheduled job has no crawl until it first runs
+	if _, ok := m.Job("nightly"); ok {
+		t.Error("scheduled job has a crawl before its first run")
+	}
+
+	if err := m.Remove(context.Background(), "nightly"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove(context.Background(), "nightly"); !errors.Is(err, api.ErrJobNotFound) {
+		t.Errorf("Remove() of a removed job = %v, want ErrJobNotFound", err)
+	}
+	if list := m.List(); len(list) != 0 {
+		t.Errorf("List() = %v after removing every job", list)
+	}
+}
+
+func TestFireSkipsRunningJob(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body></body></html>`))
+	}))
+	defer srv.Close()
+
+	history := NewMemoryHistory()
+	m := NewManager(testBase(t), "", history)
+	if err := m.Create(config.JobConfig{ID: "slow", Seeds: []string{srv.URL + "/"}, Schedule: "@every 1m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if wait := m.untilDue(now); wait <= 0 || wait > time.Minute {
+		t.Fatalf("untilDue() = %v, want within a minute", wait)
+	}
+	m.fire(now.Add(time.Minute))
+	if _, ok := m.Job("slow"); !ok {
+		t.Fatal("due job didn't start")
+	}
+	// The first run still waits for its page when the job is due again
+	m.fire(now.Add(2 * time.Minute))
+	m.fire(now.Add(2 * time.Minute)) // Not due yet
+
+	runs := waitRuns(t, m, "slow", 1)
+	if runs[0].State != StateSkipped || runs[0].Trigger != TriggerSchedule {
+		t.Fatalf("run = %+v, want a skipped scheduled run", runs[0])
+	}
+	if status, _ := m.Status("slow"); status["skipped"] != int64(1) || status["state"] != StateRunning {
+		t.Fatalf("Status() = %v", status)
+	}
+
+	close(release)
+	// The skipped run started later and comes first
+	if runs := waitRuns(t, m, "slow", 2); runs[1].State != StateFinished {
+		t.Fatalf("runs = %v, want the first run to finish", states(runs))
+	}
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
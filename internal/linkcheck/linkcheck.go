@@ -0,0 +1,242 @@
+// Package linkcheck finds broken links: links answered with 4xx or 5xx or
+// not at all, with the pages that contain them
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/logger"
+)
+
+var log = logger.For("linkcheck")
+
+// pendingChecks is how many filtered links may wait for a check
+const pendingChecks = 1024
+
+// link is what is known about one link target
+type link struct {
+	referrers []string // Pages linking to it, up to max_referrers
+	count     int      // Links to it, including those past max_referrers
+	scheduled bool     // Handed to the checkers
+	broken    bool
+	status    int
+	reason    string
+}
+
+// Checker records the referrers of every link and the outcome of the URLs
+// crawled, and checks filtered links with HEAD requests if configured
+type Checker struct {
+	cfg       config.LinkCheckConfig
+	client    *http.Client
+	userAgent string
+	wait      func(ctx context.Context, host string) error
+
+	mu    sync.Mutex
+	links map[string]*link
+
+	pending chan string // Filtered links to check, nil unless check_filtered
+	wg      sync.WaitGroup
+
+	// Counters
+	checked int64
+	broken  int64
+}
+
+// New creates a checker sending its requests with client, after wait lets
+// them through if set. It returns nil if link checking is disabled.
+func New(cfg config.LinkCheckConfig, client *http.Client, userAgent string, wait func(ctx context.Context, host string) error) *Checker {
+	if !cfg.Enabled {
+		return nil
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	c := &Checker{
+		cfg:       cfg,
+		client:    client,
+		userAgent: userAgent,
+		wait:      wait,
+		links:     make(map[string]*link),
+	}
+	if cfg.CheckFiltered {
+		c.pending = make(chan string, pendingChecks)
+	}
+	return c
+}
+
+// Start runs the checkers of filtered links until Close. Links still
+// pending once ctx is done are dropped.
+func (c *Checker) Start(ctx context.Context) {
+	if c == nil || c.pending == nil {
+		return
+	}
+	for i := 0; i < c.cfg.Workers; i++ {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			for rawURL := range c.pending {
+				if ctx.Err() == nil {
+					c.check(ctx, rawURL)
+				}
+			}
+		}()
+	}
+}
+
+// Close waits for the pending checks
+func (c *Checker) Close() {
+	if c == nil || c.pending == nil {
+		return
+	}
+	close(c.pending)
+	c.wg.Wait()
+}
+
+// Link records a link from page from to rawURL
+func (c *Checker) Link(from, rawURL string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := c.get(rawURL)
+	l.count++
+	if len(l.referrers) >= c.cfg.MaxReferrers {
+		return
+	}
+	for _, r := range l.referrers {
+		if r == from {
+			return
+		}
+	}
+	l.referrers = append(l.referrers, from)
+}
+
+// Result records the outcome of fetching a crawled URL: its status, or the
+// error if there was no usable response. Bodies over the size limit were
+// answered and don't make a link broken.
+func (c *Checker) Result(rawURL string, status int, err error) {
+	if c == nil || errors.Is(err, fetcher.ErrBodyTooLarge) {
+		return
+	}
+	var retryErr *fetcher.RetryError
+	if errors.As(err, &retryErr) && retryErr.StatusCode > 0 {
+		status, err = retryErr.StatusCode, nil
+	}
+	switch {
+	case err != nil:
+		c.record(rawURL, 0, fetcher.ErrorClass(err))
+	case status >= 400:
+		c.record(rawURL, status, "")
+	}
+}
+
+// Check checks a link the filters kept out of the crawl, once per URL
+func (c *Checker) Check(ctx context.Context, rawURL string) {
+	if c == nil || c.pending == nil {
+		return
+	}
+	c.mu.Lock()
+	l := c.get(rawURL)
+	scheduled := l.scheduled
+	l.scheduled = true
+	c.mu.Unlock()
+	if scheduled {
+		return
+	}
+	select {
+	case c.pending <- rawURL:
+	case <-ctx.Done():
+	}
+}
+
+// GetStats returns the links seen, checked and found broken
+func (c *Checker) GetStats() map[string]int64 {
+	c.mu.Lock()
+	links := len(c.links)
+	c.mu.Unlock()
+	return map[string]int64{
+		"links":   int64(links),
+		"checked": atomic.LoadInt64(&c.checked),
+		"broken":  atomic.LoadInt64(&c.broken),
+	}
+}
+
+// get returns the link to rawURL, adding it. Caller must hold mu.
+func (c *Checker) get(rawURL string) *link {
+	l, ok := c.links[rawURL]
+	if !ok {
+		l = &link{}
+		c.links[rawURL] = l
+	}
+	return l
+}
+
+// record marks a link as broken
+func (c *Checker) record(rawURL string, status int, reason string) {
+	c.mu.Lock()
+	l := c.get(rawURL)
+	wasBroken := l.broken
+	l.broken, l.status, l.reason = true, status, reason
+	c.mu.Unlock()
+	if !wasBroken {
+		atomic.AddInt64(&c.broken, 1)
+		log.Debug("Broken link %s: status %d %s", rawURL, status, reason)
+	}
+}
+
+// check requests a filtered link with HEAD, or GET if the server doesn't
+// allow HEAD, and records it if broken
+func (c *Checker) check(ctx context.Context, rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	if c.wait != nil {
+		if err := c.wait(ctx, u.Host); err != nil {
+			return
+		}
+	}
+	atomic.AddInt64(&c.checked, 1)
+	status, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	c.Result(rawURL, status, err)
+}
+
+// request sends one request and returns the status of the response
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// url.Error is a net.Error itself, classify what it wraps
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return 0, err
+	}
+	// Drain a little so the connection can be reused
+	io.CopyN(io.Discard, resp.Body, 64<<10)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
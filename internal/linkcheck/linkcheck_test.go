@@ -0,0 +1,152 @@
+package linkcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/fetcher"
+)
+
+func TestCheckerResult(t *testing.T) {
+	c := New(config.LinkCheckConfig{Enabled: true, MaxReferrers: 2}, nil, "", nil)
+	for i := 0; i < 3; i++ {
+		c.Link(fmt.Sprintf("https://example.com/page%d", i), "https://example.com/gone")
+	}
+	c.Link("https://example.com/page0", "https://example.com/gone")
+	c.Link("https://example.com/", "https://other.com/down")
+
+	tests := []struct {
+		url    string
+		status int
+		err    error
+	}{
+		{"https://example.com/gone", 404, nil},
+		{"https://example.com/ok", 200, nil},
+		{"https://example.com/moved", 301, nil},
+		{"https://other.com/down", 0, &fetcher.RetryError{URL: "https://other.com/down", Attempts: 3, StatusCode: 503, Err: errors.New("HTTP 503")}},
+		{"https://nowhere.invalid/", 0, &net.DNSError{Err: "no such host", Name: "nowhere.invalid", IsNotFound: true}},
+		{"https://example.com/huge", 0, fetcher.ErrBodyTooLarge},
+	}
+	for _, tt := range tests {
+		c.Result(tt.url, tt.status, tt.err)
+	}
+
+	want := []Domain{
+		{Domain: "example.com", Broken: []Broken{
+			{URL: "https://example.com/gone", Status: 404, Links: 4, Referrers: []string{"https://example.com/page0", "https://example.com/page1"}},
+		}},
+		{Domain: "nowhere.invalid", Broken: []Broken{
+			{URL: "https://nowhere.invalid/", Error: fetcher.ErrorDNS, Referrers: []string{}},
+		}},
+		{Domain: "other.com", Broken: []Broken{
+			{URL: "https://other.com/down", Status: 503, Links: 1, Referrers: []string{"https://example.com/"}},
+		}},
+	}
+	if got := c.Report(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("report = %+v, want %+v", got, want)
+	}
+	if stats := c.GetStats(); stats["broken"] != 3 {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	var heads, gets int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+		}
+		if r.Method == http.MethodHead {
+			atomic.AddInt64(&heads, 1)
+		} else {
+			atomic.AddInt64(&gets, 1)
+		}
+		switch {
+		case r.URL.Path == "/head-only-get" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/head-only-get":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(config.LinkCheckConfig{Enabled: true, CheckFiltered: true, Workers: 1, MaxReferrers: 10}, srv.Client(), "test-agent", nil)
+	ctx := context.Background()
+	c.Start(ctx)
+	for _, path := range []string{"/ok", "/missing", "/missing", "/head-only-get"} {
+		c.Check(ctx, srv.URL+path)
+	}
+	c.Close()
+
+	var got []string
+	for _, d := range c.Report() {
+		for _, b := range d.Broken {
+			got = append(got, fmt.Sprintf("%s %d", strings.TrimPrefix(b.URL, srv.URL), b.Status))
+		}
+	}
+	if want := []string{"/head-only-get 404", "/missing 410"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("broken = %v, want %v", got, want)
+	}
+	if h, g := atomic.LoadInt64(&heads), atomic.LoadInt64(&gets); h != 3 || g != 1 {
+		t.Errorf("%d HEAD and %d GET requests, want 3 and 1", h, g)
+	}
+	if stats := c.GetStats(); stats["checked"] != 3 {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestCheckerExport(t *testing.T) {
+	if New(config.LinkCheckConfig{}, nil, "", nil) != nil {
+		t.Fatal("New() returned a checker while disabled")
+	}
+	var disabled *Checker
+	disabled.Link("https://example.com/", "https://example.com/a")
+	disabled.Check(context.Background(), "https://example.com/a")
+	if disabled.Report() != nil || disabled.Export() != nil {
+		t.Fatal("nil checker reported links")
+	}
+
+	dir := filepath.Join(t.TempDir(), "report")
+	c := New(config.LinkCheckConfig{Enabled: true, OutputDir: dir, Formats: []string{"json", "html"}, MaxReferrers: 10}, nil, "", nil)
+	c.Link("https://example.com/<home>", "https://example.com/gone")
+	c.Result("https://example.com/gone", 404, nil)
+	if err := c.Export(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "broken-links.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report []Domain
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 || report[0].Broken[0].URL != "https://example.com/gone" {
+		t.Fatalf("exported %s", data)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "broken-links.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1 broken links on 1 hosts", `<a href="https://example.com/gone">`, "https://example.com/&lt;home&gt;"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("HTML report lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package linkcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Domain is a host and its broken links
+type Domain struct {
+	Domain string   `json:"domain"`
+	Broken []Broken `json:"broken"`
+}
+
+// Broken is a broken link and the pages that contain it
+type Broken struct {
+	URL       string   `json:"url"`
+	Status    int      `json:"status,omitempty"` // Last HTTP status, 0 if there was no response
+	Error     string   `json:"error,omitempty"`  // dns, timeout, connection or other without a usable response
+	Links     int      `json:"links"`            // Links to it, seeds have none
+	Referrers []string `json:"referrers"`        // Pages linking to it, up to max_referrers
+}
+
+// Report lists the broken links by host, both sorted
+func (c *Checker) Report() []Domain {
+	if c == nil {
+		return nil
+	}
+	byHost := make(map[string][]Broken)
+	c.mu.Lock()
+	for rawURL, l := range c.links {
+		if !l.broken {
+			continue
+		}
+		host := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			host = strings.ToLower(u.Hostname())
+		}
+		byHost[host] = append(byHost[host], Broken{
+			URL:       rawURL,
+			Status:    l.status,
+			Error:     l.reason,
+			Links:     l.count,
+			Referrers: append([]string{}, l.referrers...),
+		})
+	}
+	c.mu.Unlock()
+
+	report := make([]Domain, 0, len(byHost))
+	for host, broken := range byHost {
+		sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+		report = append(report, Domain{Domain: host, Broken: broken})
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Domain < report[j].Domain })
+	return report
+}
+
+// Export writes the report in the configured formats to the output
+// directory, as broken-links.json and broken-links.html
+func (c *Checker) Export() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.cfg.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create link check directory: %w", err)
+	}
+	report := c.Report()
+	for _, format := range c.cfg.Formats {
+		var err error
+		switch format {
+		case "json":
+			err = writeJSON(filepath.Join(c.cfg.OutputDir, "broken-links.json"), report)
+		case "html":
+			err = writeHTML(filepath.Join(c.cfg.OutputDir, "broken-links.html"), report)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Exported %d broken links to %s", c.GetStats()["broken"], c.cfg.OutputDir)
+	return nil
+}
+
+// writeJSON writes the report as indented JSON
+func writeJSON(path string, report []Domain) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// reportPage is the HTML report, one table per host
+var reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Broken links</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+ul { margin: 0; padding-left: 1.2em; }
+</style>
+</head>
+<body>
+<h1>Broken links</h1>
+<p>Generated {{.Generated}}: {{.Total}} broken links on {{len .Domains}} hosts.</p>
+{{range .Domains}}
+<h2 id="{{.Domain}}">{{.Domain}}</h2>
+<table>
+<tr><th>Link</th><th>Status</th><th>Links</th><th>Found on</th></tr>
+{{range .Broken}}
+<tr>
+<td><a href="{{.URL}}">{{.URL}}</a></td>
+<td>{{if .Status}}{{.Status}}{{end}}{{if .Error}} {{.Error}}{{end}}</td>
+<td>{{.Links}}</td>
+<td><ul>{{range .Referrers}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul></td>
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTML writes the report as a page
+func writeHTML(path string, report []Domain) error {
+	total := 0
+	for _, d := range report {
+		total += len(d.Broken)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	data := struct {
+		Generated string
+		Total     int
+		Domains   []Domain
+	}{time.Now().Format(time.RFC1123), total, report}
+	if err := reportPage.Execute(f, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
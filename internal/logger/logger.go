@@ -1,7 +1,13 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,12 +24,159 @@
 
 // Log levels
 const (
+	LevelDebug   = "DEBUG"
 	LevelInfo    = "INFO"
 	LevelError   = "ERROR"
 	LevelSuccess = "SUCCESS"
 	LevelWarn    = "WARN"
 )
 
+// Output formats
+const (
+	FormatConsole = "console" // Colored human-readable lines
+	FormatJSON    = "json"    // One JSON object per line
+)
+
+// slogSuccess sits between info and warn so success messages pass an info threshold
+const slogSuccess = slog.LevelInfo + 1
+
+// Options configures where and how messages are logged
+type Options struct {
+	Format     string            // console or json
+	Level      string            // debug, info, warn, or error
+	File       string            // Log file, stdout if empty
+	MaxSize    int64             // Rotate the file after this many bytes, 0 = never
+	MaxBackups int               // Rotated files to keep
+	Modules    map[string]string // Per-module level overrides
+}
+
+// output holds the active logging setup
+type output struct {
+	mu      sync.Mutex
+	format  string
+	level   slog.Level
+	modules map[string]slog.Level
+	w       io.Writer
+	closer  io.Closer
+	color   bool
+	json    *slog.Logger
+}
+
+var out = &output{
+	format: FormatConsole,
+	level:  slog.LevelInfo,
+	w:      os.Stdout,
+	color:  true,
+}
+
+// ParseLevel converts a level name to its slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// Configure applies opts to all loggers. The previous log file, if any, is closed.
+func Configure(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	modules := make(map[string]slog.Level, len(opts.Modules))
+	for module, name := range opts.Modules {
+		if modules[module], err = ParseLevel(name); err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+	}
+
+	format := strings.ToLower(opts.Format)
+	switch format {
+	case "":
+		format = FormatConsole
+	case FormatConsole, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	var w io.Writer = os.Stdout
+	var closer io.Closer
+	if opts.File != "" {
+		file, err := openRotating(opts.File, opts.MaxSize, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		w, closer = file, file
+	}
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	if out.closer != nil {
+		out.closer.Close()
+	}
+	out.format = format
+	out.level = level
+	out.modules = modules
+	out.w = w
+	out.closer = closer
+	out.color = opts.File == ""
+	out.json = slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       slog.LevelDebug, // Filtering happens in enabled
+		ReplaceAttr: replaceLevel,
+	}))
+	return nil
+}
+
+// Close flushes and closes the log file, if one is configured
+func Close() error {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	if out.closer == nil {
+		return nil
+	}
+	err := out.closer.Close()
+	out.closer = nil
+	out.w = os.Stdout
+	out.color = true
+	out.json = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: replaceLevel}))
+	return err
+}
+
+// replaceLevel names the success level in JSON output
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == slogSuccess {
+			return slog.String(slog.LevelKey, LevelSuccess)
+		}
+	}
+	return a
+}
+
+// levelName returns the console label of a level
+func levelName(level slog.Level) string {
+	switch {
+	case level == slogSuccess:
+		return LevelSuccess
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
 // getColorByLevel returns the ANSI color code for a log level
 func getColorByLevel(level string) string {
 	switch level {
@@ -35,59 +188,133 @@ func getColorByLevel(level string) string {
 		return Green
 	case LevelWarn:
 		return Yellow
+	case LevelDebug:
+		return Cyan
 	default:
 		return Reset
 	}
 }
 
+// paint wraps s in color when writing to a terminal
+func paint(color, s string) string {
+	if !out.color {
+		return s
+	}
+	return color + s + Reset
+}
+
 // formatMessage formats a log message with timestamp and level
 func formatMessage(level, msg string) string {
 	timestamp := time.Now().Format("2006/01/02 15:04:05")
+	if !out.color {
+		return fmt.Sprintf("[%s] %s %s", timestamp, level, msg)
+	}
 	color := getColorByLevel(level)
 	return fmt.Sprintf("%s[%s] %s%s%s %s",
 		Purple, timestamp, color, level, Reset, msg)
 }
 
+// enabled reports whether a message of level from module should be logged.
+// Caller must hold out.mu.
+func (o *output) enabled(module string, level slog.Level) bool {
+	threshold, ok := o.modules[module]
+	if !ok {
+		threshold = o.level
+	}
+	return level >= threshold
+}
+
+// log writes one message. attrs are only used by the JSON format; console
+// lines carry the same information in msg, built by console.
+func log(module, job string, level slog.Level, console func() string, msg string, attrs ...slog.Attr) {
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	if !out.enabled(module, level) {
+		return
+	}
+
+	if out.format == FormatJSON {
+		if module != "" {
+			attrs = append(attrs, slog.String("module", module))
+		}
+		if job != "" {
+			attrs = append(attrs, slog.String("job", job))
+		}
+		out.json.LogAttrs(context.Background(), level, msg, attrs...)
+		return
+	}
+
+	line := msg
+	if console != nil {
+		line = console()
+	}
+	if job != "" {
+		line = paint(Cyan, "["+job+"]") + " " + line
+	}
+	if module != "" {
+		line = paint(Purple, "["+module+"]") + " " + line
+	}
+	fmt.Fprintln(out.w, formatMessage(levelName(level), line))
+}
+
+// Debug logs a debug message
+func Debug(format string, args ...interface{}) {
+	log("", "", slog.LevelDebug, nil, fmt.Sprintf(format, args...))
+}
+
 // Info logs an informational message
 func Info(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	fmt.Println(formatMessage(LevelInfo, msg))
+	log("", "", slog.LevelInfo, nil, fmt.Sprintf(format, args...))
 }
 
 // Error logs an error message
 func Error(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	fmt.Println(formatMessage(LevelError, msg))
+	log("", "", slog.LevelError, nil, fmt.Sprintf(format, args...))
 }
 
 // Success logs a success message
 func Success(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	fmt.Println(formatMessage(LevelSuccess, msg))
+	log("", "", slogSuccess, nil, fmt.Sprintf(format, args...))
 }
 
 // Warn logs a warning message
 func Warn(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	fmt.Println(formatMessage(LevelWarn, msg))
+	log("", "", slog.LevelWarn, nil, fmt.Sprintf(format, args...))
 }
 
 // CrawlStatus logs the current crawling status
 func CrawlStatus(url string, linksFound int, totalPages, queueSize int) {
-	msg := fmt.Sprintf("Crawled: %s%s%s | Links found: %s%d%s | Total pages: %s%d%s | Queue size: %s%d%s",
-		Cyan, url, Reset,
-		Green, linksFound, Reset,
-		Yellow, totalPages, Reset,
-		Purple, queueSize, Reset)
-	fmt.Println(formatMessage(LevelInfo, msg))
+	crawlStatus("", "", url, linksFound, totalPages, queueSize)
+}
+
+func crawlStatus(module, job, url string, linksFound int, totalPages, queueSize int) {
+	console := func() string {
+		return fmt.Sprintf("Crawled: %s | Links found: %s | Total pages: %s | Queue size: %s",
+			paint(Cyan, url),
+			paint(Green, fmt.Sprint(linksFound)),
+			paint(Yellow, fmt.Sprint(totalPages)),
+			paint(Purple, fmt.Sprint(queueSize)))
+	}
+	log(module, job, slog.LevelInfo, console, "crawled",
+		slog.String("url", url),
+		slog.Int("links", linksFound),
+		slog.Int("totalPages", totalPages),
+		slog.Int("queueSize", queueSize))
 }
 
 // StorageStatus logs MongoDB storage operations
 func StorageStatus(url string, isUpdate bool) {
+	storageStatus("", "", url, isUpdate)
+}
+
+func storageStatus(module, job, url string, isUpdate bool) {
 	action := "Stored"
 	if isUpdate {
 		action = "Updated"
 	}
-	msg := fmt.Sprintf("%s page: %s%s%s", action, Cyan, url, Reset)
-	fmt.Println(formatMessage(LevelSuccess, msg))
+	console := func() string {
+		return fmt.Sprintf("%s page: %s", action, paint(Cyan, url))
+	}
+	log(module, job, slogSuccess, console, strings.ToLower(action)+" page", slog.String("url", url))
 }
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configure logs to a file in a temporary directory and restores console
+// logging when the test ends
+func configure(t *testing.T, opts Options) string {
+	t.Helper()
+	opts.File = filepath.Join(t.TempDir(), "crawler.log")
+	if err := Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Close()
+		Configure(Options{})
+	})
+	return opts.File
+}
+
+// readJSON returns the JSON lines written to path
+func readJSON(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid JSON line %q", scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"", "debug", "INFO", "warning", "error"} {
+		if _, err := ParseLevel(name); err != nil {
+			t.Errorf("ParseLevel(%q) = %v", name, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+	if err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("Configure accepted an unknown format")
+	}
+	if err := Configure(Options{Modules: map[string]string{"queue": "loud"}}); err == nil {
+		t.Error("Configure accepted an unknown module level")
+	}
+}
+
+func TestJSONLogging(t *testing.T) {
+	path := configure(t, Options{
+		Format:  FormatJSON,
+		Level:   "info",
+		Modules: map[string]string{"fetcher": "debug", "queue": "error"},
+	})
+
+	For("fetcher").Debug("fetching %s", "https://a.com/")
+	For("queue").Warn("dropped") // Below the queue override
+	For("crawler").Debug("hidden")
+	For("crawler").WithJob("job-1").CrawlStatus("https://a.com/", 3, 10, 7)
+	StorageStatus("https://a.com/", true)
+
+	lines := readJSON(t, path)
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want 3: %v", len(lines), lines)
+	}
+	if l := lines[0]; l["level"] != "DEBUG" || l["module"] != "fetcher" || l["msg"] != "fetching https://a.com/" {
+		t.Errorf("module debug line = %v", l)
+	}
+	if l := lines[1]; l["msg"] != "crawled" || l["job"] != "job-1" || l["links"] != 3.0 || l["queueSize"] != 7.0 {
+		t.Errorf("crawl status line = %v", l)
+	}
+	if l := lines[2]; l["level"] != LevelSuccess || l["msg"] != "updated page" || l["module"] != nil {
+		t.Errorf("storage status line = %v", l)
+	}
+}
+
+func TestConsoleLoggingToFile(t *testing.T) {
+	path := configure(t, Options{Level: "warn"})
+
+	For("robots").WithJob("job-2").Warn("slow host %s", "a.com")
+	Info("hidden")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(string(data))
+	if strings.Contains(line, "\n") || !strings.HasSuffix(line, "WARN [robots] [job-2] slow host a.com") {
+		t.Fatalf("log file = %q", data)
+	}
+	// Files get no color codes
+	if strings.Contains(line, "\033[") {
+		t.Fatalf("log file has color codes: %q", line)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler.log")
+	r, err := openRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	}
+	for file, content := range want {
+		if data, err := os.ReadFile(file); err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v, want %q", filepath.Base(file), data, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("more backups kept than configured")
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "crawler.log")
+	r, err := openRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("123456"))
+	r.Close()
+
+	// A reopened file counts what it already holds
+	r, err = openRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Write([]byte("78901"))
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "123456" {
+		t.Fatalf("backup = %q", data)
+	}
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Logger logs on behalf of one module. Its messages are tagged with the
+// module name and filtered by the module's level override, if any.
+type Logger struct {
+	module string
+	job    string // Crawl job the messages belong to, if any
+}
+
+// For returns the logger of a module, e.g. "fetcher" or "queue"
+func For(module string) *Logger {
+	return &Logger{module: module}
+}
+
+// WithJob returns a logger of the same module whose messages are also
+// tagged with a crawl job ID
+func (l *Logger) WithJob(id string) *Logger {
+	return &Logger{module: l.module, job: id}
+}
+
+// Debug logs a debug message
+func (l *Logger) Debug(format string, args ...interface{}) {
+	log(l.module, l.job, slog.LevelDebug, nil, fmt.Sprintf(format, args...))
+}
+
+// Info logs an informational message
+func (l *Logger) Info(format string, args ...interface{}) {
+	log(l.module, l.job, slog.LevelInfo, nil, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message
+func (l *Logger) Warn(format string, args ...interface{}) {
+	log(l.module, l.job, slog.LevelWarn, nil, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message
+func (l *Logger) Error(format string, args ...interface{}) {
+	log(l.module, l.job, slog.LevelError, nil, fmt.Sprintf(format, args...))
+}
+
+// Success logs a success message
+func (l *Logger) Success(format string, args ...interface{}) {
+	log(l.module, l.job, slogSuccess, nil, fmt.Sprintf(format, args...))
+}
+
+// CrawlStatus logs the current crawling status
+func (l *Logger) CrawlStatus(url string, linksFound int, totalPages, queueSize int) {
+	crawlStatus(l.module, l.job, url, linksFound, totalPages, queueSize)
+}
+
+// StorageStatus logs MongoDB storage operations
+func (l *Logger) StorageStatus(url string, isUpdate bool) {
+	storageStatus(l.module, l.job, url, isUpdate)
+}
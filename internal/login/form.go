@@ -0,0 +1,236 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/extract"
+
+	"golang.org/x/net/html"
+)
+
+// maxPageSize bounds the login page and the response to the login
+const maxPageSize = 10 << 20
+
+// submit posts the login fields, through the form on the login page if one
+// is configured, and checks the response
+func submit(ctx context.Context, client *http.Client, userAgent string, l config.LoginConfig) error {
+	method, action := http.MethodPost, l.URL
+	values := url.Values{}
+	if l.Form != "" {
+		page, final, err := get(ctx, client, userAgent, l.URL)
+		if err != nil {
+			return err
+		}
+		if method, action, values, err = formValues(page, final, l.Form); err != nil {
+			return err
+		}
+	}
+	for name, value := range l.Fields {
+		values.Set(name, os.ExpandEnv(value))
+	}
+
+	var body io.Reader
+	if method == http.MethodGet {
+		u, err := url.Parse(action)
+		if err != nil {
+			return err
+		}
+		u.RawQuery = values.Encode()
+		action = u.String()
+	} else {
+		body = strings.NewReader(values.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, action, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	page, err := do(client, req, userAgent)
+	if err != nil {
+		return err
+	}
+	if l.Check != "" && !strings.Contains(page, l.Check) {
+		return fmt.Errorf("response to the login doesn't contain %q", l.Check)
+	}
+	return nil
+}
+
+// get downloads the login page and returns it with its final URL
+func get(ctx context.Context, client *http.Client, userAgent, pageURL string) (string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	body, err := do(client, req, userAgent)
+	if err != nil {
+		return "", nil, err
+	}
+	return body, req.URL, nil
+}
+
+// do sends a request and returns the body of a successful response. req.URL
+// is updated to the final URL after redirects.
+func do(client *http.Client, req *http.Request, userAgent string) (string, error) {
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	req.URL = resp.Request.URL
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("%s %s: HTTP %d", req.Method, req.URL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", req.URL, err)
+	}
+	return string(body), nil
+}
+
+// formValues finds the form matching selector on a page and returns its
+// method, its action resolved against base and the values it would submit
+// as is: hidden fields such as CSRF tokens and the defaults of the others
+func formValues(page string, base *url.URL, selector string) (string, string, url.Values, error) {
+	sel, err := extract.CompileSelector(selector)
+	if err != nil {
+		return "", "", nil, err
+	}
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to parse login page: %w", err)
+	}
+	var form *html.Node
+	for _, n := range sel.MatchAll(doc) {
+		if n.Data == "form" {
+			form = n
+			break
+		}
+	}
+	if form == nil {
+		return "", "", nil, fmt.Errorf("no form matches %s on the login page", selector)
+	}
+
+	method := http.MethodPost
+	if strings.EqualFold(attr(form, "method"), "get") {
+		method = http.MethodGet
+	}
+	action := base.String()
+	if a := strings.TrimSpace(attr(form, "action")); a != "" {
+		u, err := base.Parse(a)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("invalid form action %q: %w", a, err)
+		}
+		action = u.String()
+	}
+
+	values := url.Values{}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if name := attr(n, "name"); name != "" && !hasAttr(n, "disabled") {
+				if value, ok := fieldValue(n); ok {
+					values.Add(name, value)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(form)
+	return method, action, values, nil
+}
+
+// fieldValue returns the value a form control submits by default, false for
+// controls that submit nothing
+func fieldValue(n *html.Node) (string, bool) {
+	switch n.Data {
+	case "input":
+		switch strings.ToLower(attr(n, "type")) {
+		case "submit", "button", "image", "reset", "file":
+			return "", false
+		case "checkbox", "radio":
+			if !hasAttr(n, "checked") {
+				return "", false
+			}
+			if v := attr(n, "value"); v != "" {
+				return v, true
+			}
+			return "on", true
+		}
+		return attr(n, "value"), true
+	case "textarea":
+		return text(n), true
+	case "select":
+		var first, selected *html.Node
+		var walk func(*html.Node)
+		walk = func(c *html.Node) {
+			if c.Type == html.ElementNode && c.Data == "option" {
+				if first == nil {
+					first = c
+				}
+				if selected == nil && hasAttr(c, "selected") {
+					selected = c
+				}
+			}
+			for d := c.FirstChild; d != nil; d = d.NextSibling {
+				walk(d)
+			}
+		}
+		walk(n)
+		if selected == nil {
+			selected = first
+		}
+		if selected == nil {
+			return "", false
+		}
+		if v, ok := attrOK(selected, "value"); ok {
+			return v, true
+		}
+		return strings.TrimSpace(text(selected)), true
+	}
+	return "", false
+}
+
+// text returns the text directly inside an element
+func text(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+// attr returns the value of an attribute, empty if missing
+func attr(n *html.Node, name string) string {
+	v, _ := attrOK(n, name)
+	return v
+}
+
+// hasAttr reports whether an element has an attribute
+func hasAttr(n *html.Node, name string) bool {
+	_, ok := attrOK(n, name)
+	return ok
+}
+
+// attrOK returns the value of an attribute and whether it is present
+func attrOK(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
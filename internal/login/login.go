@@ -0,0 +1,151 @@
+// Package login logs in to sites before a crawl and keeps their session
+// cookies for the requests to them
+package login
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+var log = logger.For("login")
+
+// scriptTimeout bounds a login script, which may drive a browser
+const scriptTimeout = 2 * time.Minute
+
+// Session logs in to the configured sites and holds their cookies
+type Session struct {
+	logins []config.LoginConfig
+	jar    *cookiejar.Jar
+
+	// Counters
+	succeeded int64
+	failed    int64
+}
+
+// New creates the session of the logins. It returns nil if there are none.
+func New(logins []config.LoginConfig) *Session {
+	if len(logins) == 0 {
+		return nil
+	}
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &Session{logins: logins, jar: jar}
+}
+
+// SetCookies keeps the cookies of a response from a login domain
+func (s *Session) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if s.covers(u.Hostname()) {
+		s.jar.SetCookies(u, cookies)
+	}
+}
+
+// Cookies returns the session cookies to send to u, none outside the login
+// domains so that other sites are crawled without a session
+func (s *Session) Cookies(u *url.URL) []*http.Cookie {
+	if !s.covers(u.Hostname()) {
+		return nil
+	}
+	return s.jar.Cookies(u)
+}
+
+// covers reports whether host is a login domain or one of its subdomains
+func (s *Session) covers(host string) bool {
+	host = strings.ToLower(host)
+	for _, l := range s.logins {
+		domain := strings.ToLower(l.Domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Login logs in to every site with client, whose Jar must be the session,
+// and stops at the first login that fails
+func (s *Session) Login(ctx context.Context, client *http.Client, userAgent string) error {
+	for _, l := range s.logins {
+		var err error
+		if len(l.Script) > 0 {
+			err = s.runScript(ctx, l)
+		} else {
+			err = submit(ctx, client, userAgent, l)
+		}
+		if err != nil {
+			atomic.AddInt64(&s.failed, 1)
+			return fmt.Errorf("failed to log in to %s: %w", l.Domain, err)
+		}
+		atomic.AddInt64(&s.succeeded, 1)
+		log.Info("Logged in to %s", l.Domain)
+	}
+	return nil
+}
+
+// GetStats returns the number of logins that succeeded and failed
+func (s *Session) GetStats() map[string]int64 {
+	return map[string]int64{
+		"succeeded": atomic.LoadInt64(&s.succeeded),
+		"failed":    atomic.LoadInt64(&s.failed),
+	}
+}
+
+// runScript runs a login script and keeps the cookies it prints, one per
+// line as name=value or in the Set-Cookie syntax. The script gets the login
+// URL and domain in LOGIN_URL and LOGIN_DOMAIN, and the fields as
+// LOGIN_<NAME> variables.
+func (s *Session) runScript(ctx context.Context, l config.LoginConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, scriptTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, l.Script[0], l.Script[1:]...)
+	cmd.Env = append(os.Environ(), "LOGIN_URL="+l.URL, "LOGIN_DOMAIN="+l.Domain)
+	for name, value := range l.Fields {
+		cmd.Env = append(cmd.Env, "LOGIN_"+strings.ToUpper(name)+"="+os.ExpandEnv(value))
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", l.Script[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	target := l.URL
+	if target == "" {
+		target = "https://" + l.Domain + "/"
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil {
+			return fmt.Errorf("invalid cookie %q printed by %s: %w", line, l.Script[0], err)
+		}
+		cookies = append(cookies, cookie)
+	}
+	if len(cookies) == 0 {
+		return fmt.Errorf("%s printed no cookies", l.Script[0])
+	}
+	s.SetCookies(u, cookies)
+	return nil
+}
@@ -0,0 +1,188 @@
+package login
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+// loginPage has a CSRF token, a checked box and a select next to the credentials
+const loginPage = `<html><body>
+<form id="search" action="/search"><input name="q"></form>
+<form id="login" action="/session" method="post">
+	<input type="hidden" name="csrf" value="t0k3n">
+	<input name="username" value="">
+	<input type="password" name="password">
+	<input type="checkbox" name="remember" checked>
+	<input type="checkbox" name="newsletter" value="yes">
+	<input name="legacy" value="x" disabled>
+	<select name="lang"><option value="en">English</option><option value="de" selected>Deutsch</option></select>
+	<textarea name="note">hi</textarea>
+	<input type="submit" name="go" value="Sign in">
+</form></body></html>`
+
+// loginServer accepts alice/secret with the form's token and sets a session
+// cookie that /private requires
+func loginServer(t *testing.T, posted *url.Values) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(loginPage))
+		case "/session", "/api/login":
+			r.ParseForm()
+			if posted != nil {
+				*posted = r.PostForm
+			}
+			if r.PostForm.Get("username") != "alice" || r.PostForm.Get("password") != "secret" {
+				w.Write([]byte("Wrong password"))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s3ss10n", Path: "/"})
+			http.Redirect(w, r, "/home", http.StatusSeeOther)
+		case "/home":
+			w.Write([]byte("Welcome back. Sign out"))
+		case "/private":
+			if c, err := r.Cookie("sid"); err != nil || c.Value != "s3ss10n" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("secret page"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sessionGet fetches a URL with the session's cookies
+func sessionGet(t *testing.T, s *Session, rawURL string) int {
+	t.Helper()
+	resp, err := (&http.Client{Jar: s}).Get(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestFormLogin(t *testing.T) {
+	var posted url.Values
+	srv := loginServer(t, &posted)
+	t.Setenv("TEST_LOGIN_PASSWORD", "secret")
+	s := New([]config.LoginConfig{{
+		Domain: "127.0.0.1",
+		URL:    srv.URL + "/login",
+		Form:   "#login",
+		Fields: map[string]string{"username": "alice", "password": "${TEST_LOGIN_PASSWORD}"},
+		Check:  "Sign out",
+	}})
+	client := &http.Client{Jar: s}
+	if err := s.Login(context.Background(), client, "test-agent"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "csrf=t0k3n&lang=de&note=hi&password=secret&remember=on&username=alice"
+	if got := posted.Encode(); got != want {
+		t.Errorf("posted %s, want %s", got, want)
+	}
+	if status := sessionGet(t, s, srv.URL+"/private"); status != http.StatusOK {
+		t.Errorf("private page = %d", status)
+	}
+	if stats := s.GetStats(); stats["succeeded"] != 1 || stats["failed"] != 0 {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestPostLogin(t *testing.T) {
+	srv := loginServer(t, nil)
+	s := New([]config.LoginConfig{{
+		Domain: "127.0.0.1",
+		URL:    srv.URL + "/api/login",
+		Fields: map[string]string{"username": "alice", "password": "secret"},
+	}})
+	if err := s.Login(context.Background(), &http.Client{Jar: s}, "test-agent"); err != nil {
+		t.Fatal(err)
+	}
+	if status := sessionGet(t, s, srv.URL+"/private"); status != http.StatusOK {
+		t.Errorf("private page = %d", status)
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	srv := loginServer(t, nil)
+	for _, tt := range []struct {
+		name  string
+		login config.LoginConfig
+		want  string
+	}{
+		{"wrong password", config.LoginConfig{URL: srv.URL + "/login", Form: "#login", Fields: map[string]string{"username": "alice", "password": "nope"}, Check: "Sign out"}, `doesn't contain "Sign out"`},
+		{"missing form", config.LoginConfig{URL: srv.URL + "/login", Form: "form.signin", Fields: map[string]string{"username": "alice"}}, "no form matches"},
+		{"not found", config.LoginConfig{URL: srv.URL + "/private", Fields: map[string]string{"username": "alice"}}, "HTTP 403"},
+		{"script fails", config.LoginConfig{Script: []string{"sh", "-c", "echo broken >&2; exit 3"}}, "broken"},
+		{"script prints nothing", config.LoginConfig{Script: []string{"true"}}, "printed no cookies"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.login.Domain = "127.0.0.1"
+			s := New([]config.LoginConfig{tt.login})
+			err := s.Login(context.Background(), &http.Client{Jar: s}, "test-agent")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Login() = %v, want %q", err, tt.want)
+			}
+			if s.GetStats()["failed"] != 1 {
+				t.Errorf("stats = %v", s.GetStats())
+			}
+		})
+	}
+}
+
+func TestScriptLogin(t *testing.T) {
+	srv := loginServer(t, nil)
+	s := New([]config.LoginConfig{{
+		Domain: "127.0.0.1",
+		URL:    srv.URL + "/login",
+		Fields: map[string]string{"user": "alice"},
+		Script: []string{"sh", "-c", `echo "# cookies for $LOGIN_DOMAIN"; echo "sid=s3ss10n; Path=/"; echo "user=$LOGIN_USER"`},
+	}})
+	if err := s.Login(context.Background(), &http.Client{Jar: s}, "test-agent"); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(srv.URL + "/private")
+	var names []string
+	for _, c := range s.Cookies(u) {
+		names = append(names, c.Name+"="+c.Value)
+	}
+	if got := strings.Join(names, " "); got != "sid=s3ss10n user=alice" {
+		t.Errorf("cookies = %s", got)
+	}
+	if status := sessionGet(t, s, srv.URL+"/private"); status != http.StatusOK {
+		t.Errorf("private page = %d", status)
+	}
+}
+
+func TestSessionDomains(t *testing.T) {
+	s := New([]config.LoginConfig{{Domain: "Example.com"}})
+	cookie := []*http.Cookie{{Name: "sid", Value: "1", Path: "/"}}
+	for _, tt := range []struct {
+		url  string
+		kept bool
+	}{
+		{"https://example.com/", true},
+		{"https://app.example.com/", true},
+		{"https://notexample.com/", false},
+		{"https://other.org/", false},
+	} {
+		u, _ := url.Parse(tt.url)
+		s.SetCookies(u, cookie)
+		if got := len(s.Cookies(u)) > 0; got != tt.kept {
+			t.Errorf("%s: kept cookie = %v", tt.url, got)
+		}
+	}
+	if New(nil) != nil {
+		t.Error("New(nil) isn't nil")
+	}
+}
@@ -0,0 +1,96 @@
+package publish
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"web-crawler/internal/storage"
+)
+
+// AvroSchema is the schema of pages published with format avro, in Parsing
+// Canonical Form. crawled_at is in milliseconds since the Unix epoch.
+const AvroSchema = `{"name":"webcrawler.WebPage","type":"record","fields":[` +
+	`{"name":"url","type":"string"},` +
+	`{"name":"final_url","type":"string"},` +
+	`{"name":"canonical_url","type":"string"},` +
+	`{"name":"title","type":"string"},` +
+	`{"name":"status_code","type":"int"},` +
+	`{"name":"content_type","type":"string"},` +
+	`{"name":"charset","type":"string"},` +
+	`{"name":"language","type":"string"},` +
+	`{"name":"crawled_at","type":"long"},` +
+	`{"name":"content","type":"string"},` +
+	`{"name":"text","type":"string"},` +
+	`{"name":"links","type":{"type":"array","items":"string"}},` +
+	`{"name":"metadata","type":{"type":"map","values":"string"}}]}`
+
+// avroFingerprint is the CRC-64-AVRO fingerprint of AvroSchema
+var avroFingerprint = fingerprint(AvroSchema)
+
+// encodeAvro encodes a page with Avro's single-object encoding: a two-byte
+// marker and the schema fingerprint followed by the binary record
+func encodeAvro(page *storage.WebPage) ([]byte, error) {
+	buf := make([]byte, 0, 256+len(page.Content)+len(page.Text))
+	buf = append(buf, 0xc3, 0x01)
+	buf = binary.LittleEndian.AppendUint64(buf, avroFingerprint)
+
+	for _, s := range []string{page.URL, page.FinalURL, page.CanonicalURL, page.Title} {
+		buf = appendAvroString(buf, s)
+	}
+	buf = binary.AppendVarint(buf, int64(page.StatusCode))
+	for _, s := range []string{page.ContentType, page.Charset, page.Language} {
+		buf = appendAvroString(buf, s)
+	}
+	buf = binary.AppendVarint(buf, page.CrawledAt.UnixMilli())
+	buf = appendAvroString(buf, page.Content)
+	buf = appendAvroString(buf, page.Text)
+
+	if len(page.Links) > 0 {
+		buf = binary.AppendVarint(buf, int64(len(page.Links)))
+		for _, link := range page.Links {
+			buf = appendAvroString(buf, link)
+		}
+	}
+	buf = binary.AppendVarint(buf, 0)
+
+	if len(page.Metadata) > 0 {
+		keys := make([]string, 0, len(page.Metadata))
+		for k := range page.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = binary.AppendVarint(buf, int64(len(keys)))
+		for _, k := range keys {
+			buf = appendAvroString(buf, k)
+			buf = appendAvroString(buf, page.Metadata[k])
+		}
+	}
+	buf = binary.AppendVarint(buf, 0)
+	return buf, nil
+}
+
+// appendAvroString appends a length-prefixed string; Avro lengths are
+// zig-zag varints like binary.AppendVarint writes
+func appendAvroString(buf []byte, s string) []byte {
+	buf = binary.AppendVarint(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+// fingerprint computes the CRC-64-AVRO (Rabin) fingerprint of a schema
+func fingerprint(schema string) uint64 {
+	const empty = 0xc15d213aa4d7a795
+	var table [256]uint64
+	for i := range table {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (empty & -(fp & 1))
+		}
+		table[i] = fp
+	}
+
+	fp := uint64(empty)
+	for i := 0; i < len(schema); i++ {
+		fp = (fp >> 8) ^ table[byte(fp)^schema[i]]
+	}
+	return fp
+}
@@ -0,0 +1,631 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Kafka API keys and the versions of them the producer speaks
+const (
+	apiProduce      = 0
+	apiMetadata     = 3
+	produceVersion  = 3 // Record batches (magic 2), Kafka 0.11+
+	metadataVersion = 1
+	kafkaClientID   = "web-crawler"
+	batchOverhead   = 61 // Record batch header bytes
+)
+
+// Kafka error codes the producer handles specially
+const (
+	errUnknownTopic      = 3
+	errLeaderUnavailable = 5
+	errNotLeader         = 6
+	errMessageTooLarge   = 10
+	errNetworkException  = 13
+	errRecordsTooLarge   = 18
+	errTopicAuthFailed   = 29
+	errInvalidRecord     = 87
+)
+
+// kafkaErrors names common error codes for logs
+var kafkaErrors = map[int16]string{
+	1:                    "OFFSET_OUT_OF_RANGE",
+	2:                    "CORRUPT_MESSAGE",
+	errUnknownTopic:      "UNKNOWN_TOPIC_OR_PARTITION",
+	errLeaderUnavailable: "LEADER_NOT_AVAILABLE",
+	errNotLeader:         "NOT_LEADER_OR_FOLLOWER",
+	7:                    "REQUEST_TIMED_OUT",
+	8:                    "BROKER_NOT_AVAILABLE",
+	errMessageTooLarge:   "MESSAGE_TOO_LARGE",
+	errNetworkException:  "NETWORK_EXCEPTION",
+	errRecordsTooLarge:   "RECORD_LIST_TOO_LARGE",
+	19:                   "NOT_ENOUGH_REPLICAS",
+	20:                   "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	errTopicAuthFailed:   "TOPIC_AUTHORIZATION_FAILED",
+	errInvalidRecord:     "INVALID_RECORD",
+}
+
+// kafkaError is an error code returned by a broker
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	if name, ok := kafkaErrors[int16(e)]; ok {
+		return "kafka: " + name
+	}
+	return fmt.Sprintf("kafka: error code %d", int16(e))
+}
+
+// permanent reports whether retrying the same records can't succeed
+func (e kafkaError) permanent() bool {
+	switch e {
+	case errMessageTooLarge, errRecordsTooLarge, errTopicAuthFailed, errInvalidRecord:
+		return true
+	}
+	return false
+}
+
+// staleMetadata reports whether the partition leaders must be looked up again
+func (e kafkaError) staleMetadata() bool {
+	switch e {
+	case errUnknownTopic, errLeaderUnavailable, errNotLeader, errNetworkException:
+		return true
+	}
+	return false
+}
+
+// castagnoli is the CRC-32C table of record batch checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaPartition is a partition of the topic and its leader broker
+type kafkaPartition struct {
+	id     int32
+	leader int32 // Node ID, -1 while no leader is elected
+}
+
+// kafkaProducer writes record batches to partition leaders with the Kafka
+// wire protocol. Messages are partitioned by key like Kafka's default
+// partitioner, so pages of a host land on the same partition.
+type kafkaProducer struct {
+	brokers  []string
+	topic    string
+	maxBytes int
+	timeout  time.Duration
+
+	addrs         map[int32]string     // Node ID -> host:port
+	conns         map[int32]*kafkaConn // Node ID -> open connection
+	partitions    []kafkaPartition     // Sorted by ID
+	stale         bool                 // Metadata must be refreshed before the next produce
+	correlationID int32
+}
+
+func newKafkaProducer(cfg config.PublishConfig) (*kafkaProducer, error) {
+	p := &kafkaProducer{
+		brokers:  cfg.Brokers,
+		topic:    cfg.Topic,
+		maxBytes: cfg.MaxMessageBytes,
+		timeout:  cfg.Timeout,
+		conns:    make(map[int32]*kafkaConn),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err := p.refresh(ctx); err != nil {
+		return nil, err
+	}
+	log.Info("Kafka topic %s has %d partitions on %d brokers", p.topic, len(p.partitions), len(p.addrs))
+	return p, nil
+}
+
+// refresh looks up the partitions of the topic and their leaders from the
+// first bootstrap broker that answers
+func (p *kafkaProducer) refresh(ctx context.Context) error {
+	var lastErr error
+	for _, addr := range p.brokers {
+		conn, err := dialKafka(ctx, addr, p.timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		w := &kafkaWriter{}
+		w.int32(1)
+		w.string(p.topic)
+		p.correlationID++
+		resp, err := conn.roundTrip(ctx, p.correlationID, apiMetadata, metadataVersion, w.buf)
+		conn.close()
+		if err == nil {
+			err = p.readMetadata(resp)
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		p.stale = false
+		return nil
+	}
+	return fmt.Errorf("failed to fetch kafka metadata: %w", lastErr)
+}
+
+// readMetadata decodes a Metadata v1 response
+func (p *kafkaProducer) readMetadata(resp []byte) error {
+	r := &kafkaReader{buf: resp}
+	addrs := make(map[int32]string)
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // Rack
+		addrs[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.int32() // Controller
+
+	var partitions []kafkaPartition
+	var topicErr error
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		code := r.int16()
+		name := r.string()
+		r.bool() // Internal
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			r.int16() // Partition error, a missing leader shows as -1
+			id := r.int32()
+			leader := r.int32()
+			r.int32s() // Replicas
+			r.int32s() // In-sync replicas
+			if name == p.topic {
+				partitions = append(partitions, kafkaPartition{id: id, leader: leader})
+			}
+		}
+		if name == p.topic && code != 0 {
+			topicErr = kafkaError(code)
+		}
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to decode kafka metadata: %w", r.err)
+	}
+	if topicErr != nil {
+		return fmt.Errorf("kafka topic %s: %w", p.topic, topicErr)
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("kafka topic %s has no partitions", p.topic)
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].id < partitions[j].id })
+
+	// Drop connections to brokers that moved or left
+	for id, conn := range p.conns {
+		if addrs[id] != p.addrs[id] {
+			conn.close()
+			delete(p.conns, id)
+		}
+	}
+	p.addrs = addrs
+	p.partitions = partitions
+	return nil
+}
+
+// publish sends the messages to the leaders of their partitions, one
+// Produce request per broker
+func (p *kafkaProducer) publish(ctx context.Context, msgs []*message) error {
+	if p.stale {
+		if err := p.refresh(ctx); err != nil {
+			for _, m := range msgs {
+				m.err = err
+			}
+			return err
+		}
+	}
+
+	var firstErr error
+	byLeader := make(map[int32]map[int32][]*message)
+	for _, m := range msgs {
+		partition := p.partitions[partitionFor(m.key, len(p.partitions))]
+		if partition.leader < 0 {
+			m.err = fmt.Errorf("kafka partition %d: %w", partition.id, kafkaError(errLeaderUnavailable))
+			firstErr = m.err
+			p.stale = true
+			continue
+		}
+		if byLeader[partition.leader] == nil {
+			byLeader[partition.leader] = make(map[int32][]*message)
+		}
+		byLeader[partition.leader][partition.id] = append(byLeader[partition.leader][partition.id], m)
+	}
+
+	for leader, partitions := range byLeader {
+		if err := p.produce(ctx, leader, partitions); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// produce writes the messages of some partitions to their leader and waits
+// for all in-sync replicas to acknowledge them
+func (p *kafkaProducer) produce(ctx context.Context, node int32, partitions map[int32][]*message) error {
+	failAll := func(err error) error {
+		for _, msgs := range partitions {
+			for _, m := range msgs {
+				if m.err == nil {
+					m.err = err
+				}
+			}
+		}
+		return err
+	}
+
+	ids := make([]int32, 0, len(partitions))
+	for id := range partitions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var tooLarge error
+	sets := make(map[int32][]byte, len(ids))
+	for _, id := range ids {
+		set, err := p.recordSet(partitions[id])
+		if err != nil {
+			tooLarge = err
+		}
+		if len(set) > 0 {
+			sets[id] = set
+		}
+	}
+	if len(sets) == 0 {
+		return tooLarge
+	}
+
+	w := &kafkaWriter{}
+	w.int16(-1) // No transactional ID
+	w.int16(-1) // acks=all
+	w.int32(int32(p.timeout / time.Millisecond))
+	w.int32(1)
+	w.string(p.topic)
+	w.int32(int32(len(sets)))
+	for _, id := range ids {
+		if set, ok := sets[id]; ok {
+			w.int32(id)
+			w.bytes(set)
+		}
+	}
+
+	conn, err := p.conn(ctx, node)
+	if err != nil {
+		p.stale = true
+		return failAll(err)
+	}
+	p.correlationID++
+	resp, err := conn.roundTrip(ctx, p.correlationID, apiProduce, produceVersion, w.buf)
+	if err != nil {
+		conn.close()
+		delete(p.conns, node)
+		p.stale = true
+		return failAll(err)
+	}
+
+	answered := make(map[int32]bool)
+	var firstErr error
+	r := &kafkaReader{buf: resp}
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		r.string() // Topic
+		for m := r.int32(); m > 0 && r.err == nil; m-- {
+			id := r.int32()
+			code := r.int16()
+			r.int64() // Base offset
+			r.int64() // Log append time
+			answered[id] = true
+			if code == 0 || r.err != nil {
+				continue
+			}
+			kerr := kafkaError(code)
+			if kerr.staleMetadata() {
+				p.stale = true
+			}
+			err := fmt.Errorf("kafka partition %d: %w", id, kerr)
+			for _, msg := range partitions[id] {
+				if msg.err == nil {
+					msg.err, msg.permanent = err, kerr.permanent()
+				}
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if r.err != nil {
+		return failAll(fmt.Errorf("failed to decode kafka produce response: %w", r.err))
+	}
+	for id := range sets {
+		if !answered[id] {
+			err := fmt.Errorf("kafka partition %d: missing from produce response", id)
+			for _, msg := range partitions[id] {
+				msg.err = err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr == nil {
+		firstErr = tooLarge
+	}
+	return firstErr
+}
+
+// recordSet encodes messages as record batches of at most maxBytes each.
+// Messages that don't fit a batch on their own fail permanently.
+func (p *kafkaProducer) recordSet(msgs []*message) ([]byte, error) {
+	var set []byte
+	var batch [][]byte
+	var tooLarge error
+	size := batchOverhead
+	timestamp := time.Now().UnixMilli()
+
+	for _, m := range msgs {
+		rec := record(len(batch), m)
+		if batchOverhead+len(rec) > p.maxBytes {
+			m.err = fmt.Errorf("message of %d bytes exceeds max_message_bytes: %w", len(m.value), kafkaError(errMessageTooLarge))
+			m.permanent = true
+			tooLarge = m.err
+			continue
+		}
+		if size+len(rec) > p.maxBytes {
+			set = appendRecordBatch(set, batch, timestamp)
+			batch, size = nil, batchOverhead
+			rec = record(0, m)
+		}
+		batch = append(batch, rec)
+		size += len(rec)
+	}
+	if len(batch) > 0 {
+		set = appendRecordBatch(set, batch, timestamp)
+	}
+	return set, tooLarge
+}
+
+// record encodes a message as the record at offsetDelta of its batch
+func record(offsetDelta int, m *message) []byte {
+	var body []byte
+	body = append(body, 0)              // Attributes
+	body = binary.AppendVarint(body, 0) // Timestamp delta
+	body = binary.AppendVarint(body, int64(offsetDelta))
+	body = binary.AppendVarint(body, int64(len(m.key)))
+	body = append(body, m.key...)
+	body = binary.AppendVarint(body, int64(len(m.value)))
+	body = append(body, m.value...)
+	body = binary.AppendVarint(body, 0) // Headers
+
+	rec := binary.AppendVarint(make([]byte, 0, len(body)+5), int64(len(body)))
+	return append(rec, body...)
+}
+
+// appendRecordBatch appends an uncompressed v2 record batch holding records
+func appendRecordBatch(set []byte, records [][]byte, timestamp int64) []byte {
+	var body []byte
+	body = binary.BigEndian.AppendUint16(body, 0) // Attributes: no compression
+	body = binary.BigEndian.AppendUint32(body, uint32(len(records)-1))
+	body = binary.BigEndian.AppendUint64(body, uint64(timestamp))
+	body = binary.BigEndian.AppendUint64(body, uint64(timestamp))
+	body = binary.BigEndian.AppendUint64(body, ^uint64(0)) // Producer ID -1
+	body = binary.BigEndian.AppendUint16(body, ^uint16(0)) // Producer epoch -1
+	body = binary.BigEndian.AppendUint32(body, ^uint32(0)) // Base sequence -1
+	body = binary.BigEndian.AppendUint32(body, uint32(len(records)))
+	for _, rec := range records {
+		body = append(body, rec...)
+	}
+
+	set = binary.BigEndian.AppendUint64(set, 0)                       // Base offset, assigned by the broker
+	set = binary.BigEndian.AppendUint32(set, uint32(4+1+4+len(body))) // Batch length
+	set = binary.BigEndian.AppendUint32(set, ^uint32(0))              // Partition leader epoch -1
+	set = append(set, 2)                                              // Magic
+	set = binary.BigEndian.AppendUint32(set, crc32.Checksum(body, castagnoli))
+	return append(set, body...)
+}
+
+// partitionFor maps a key to a partition index like Kafka's default partitioner
+func partitionFor(key string, partitions int) int {
+	return int(uint32(murmur2([]byte(key)))&0x7fffffff) % partitions
+}
+
+// murmur2 is the hash of Kafka's default partitioner
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// conn returns the connection to a broker, dialing it if needed
+func (p *kafkaProducer) conn(ctx context.Context, node int32) (*kafkaConn, error) {
+	if conn, ok := p.conns[node]; ok {
+		return conn, nil
+	}
+	addr, ok := p.addrs[node]
+	if !ok {
+		return nil, fmt.Errorf("unknown kafka broker %d", node)
+	}
+	conn, err := dialKafka(ctx, addr, p.timeout)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[node] = conn
+	return conn, nil
+}
+
+func (p *kafkaProducer) close() error {
+	for id, conn := range p.conns {
+		conn.close()
+		delete(p.conns, id)
+	}
+	return nil
+}
+
+// kafkaConn is a connection to one broker carrying one request at a time
+type kafkaConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+}
+
+func dialKafka(ctx context.Context, addr string, timeout time.Duration) (*kafkaConn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to kafka broker %s: %w", addr, err)
+	}
+	return &kafkaConn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// roundTrip sends a request and returns the response body after its header
+func (c *kafkaConn) roundTrip(ctx context.Context, correlationID int32, apiKey, version int16, body []byte) ([]byte, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	c.conn.SetDeadline(deadline)
+
+	header := &kafkaWriter{}
+	header.int32(0) // Size, filled in below
+	header.int16(apiKey)
+	header.int16(version)
+	header.int32(correlationID)
+	header.string(kafkaClientID)
+	binary.BigEndian.PutUint32(header.buf, uint32(len(header.buf)-4+len(body)))
+
+	if _, err := c.conn.Write(append(header.buf, body...)); err != nil {
+		return nil, fmt.Errorf("failed to send kafka request: %w", err)
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(c.reader, size[:]); err != nil {
+		return nil, fmt.Errorf("failed to read kafka response: %w", err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(c.reader, resp); err != nil {
+		return nil, fmt.Errorf("failed to read kafka response: %w", err)
+	}
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != correlationID {
+		return nil, fmt.Errorf("kafka response does not match request %d", correlationID)
+	}
+	return resp[4:], nil
+}
+
+func (c *kafkaConn) close() {
+	c.conn.Close()
+}
+
+// kafkaWriter encodes the big-endian primitives of the Kafka protocol
+type kafkaWriter struct {
+	buf []byte
+}
+
+func (w *kafkaWriter) int16(v int16) { w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(v)) }
+func (w *kafkaWriter) int32(v int32) { w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(v)) }
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+func (w *kafkaWriter) bytes(b []byte) {
+	w.int32(int32(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+// errShortResponse reports a response that ends in the middle of a field
+var errShortResponse = errors.New("response too short")
+
+// kafkaReader decodes the big-endian primitives of the Kafka protocol. After
+// the first error every read returns zero and err is kept.
+type kafkaReader struct {
+	buf []byte
+	err error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil || n < 0 || len(r.buf) < n {
+		r.err = errShortResponse
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *kafkaReader) bool() bool {
+	b := r.next(1)
+	return b != nil && b[0] != 0
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a string, nullable strings read as ""
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) int32s() {
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		r.int32()
+	}
+}
@@ -0,0 +1,313 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Hashes of Kafka's own murmur2 tests (UtilsTest.testMurmur2)
+func TestMurmur2(t *testing.T) {
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, want := range tests {
+		if got := murmur2([]byte(key)); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", key, got, want)
+		}
+	}
+	// The sign bit is masked off before taking the modulo
+	if got := partitionFor("foobar", 7); got != (-790332482&0x7fffffff)%7 {
+		t.Errorf("partitionFor() = %d", got)
+	}
+}
+
+func TestCRC32C(t *testing.T) {
+	// Standard check value of CRC-32C
+	if got := crc32.Checksum([]byte("123456789"), castagnoli); got != 0xe3069283 {
+		t.Fatalf("crc32c(123456789) = %#x", got)
+	}
+}
+
+func TestRecordBatchEncoding(t *testing.T) {
+	// One record with key "k" and value "v" at 2023-11-14T22:13:20Z, encoded
+	// by hand from the record batch v2 layout
+	want, _ := hex.DecodeString("" +
+		"0000000000000000" + // Base offset
+		"0000003a" + // Batch length
+		"ffffffff" + // Partition leader epoch
+		"02" + // Magic
+		"e99b8dd8" + // CRC-32C of the rest
+		"0000" + // Attributes
+		"00000000" + // Last offset delta
+		"0000018bcfe56800" + // First timestamp
+		"0000018bcfe56800" + // Max timestamp
+		"ffffffffffffffff" + // Producer ID
+		"ffff" + // Producer epoch
+		"ffffffff" + // Base sequence
+		"00000001" + // Records
+		"10" + "00" + "00" + "00" + "02" + "6b" + "02" + "76" + "00") // Record
+
+	rec := record(0, &message{key: "k", value: []byte("v")})
+	got := appendRecordBatch(nil, [][]byte{rec}, 1700000000000)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("record batch\n got %x\nwant %x", got, want)
+	}
+	if len(got) != batchOverhead+len(rec) {
+		t.Fatalf("batch of %d bytes, batchOverhead says %d", len(got), batchOverhead+len(rec))
+	}
+}
+
+// be appends big-endian Kafka primitives, independent of kafkaWriter
+type be []byte
+
+func (b be) u8(v byte) be   { return append(b, v) }
+func (b be) i16(v int16) be { return binary.BigEndian.AppendUint16(b, uint16(v)) }
+func (b be) i32(v int32) be { return binary.BigEndian.AppendUint32(b, uint32(v)) }
+func (b be) i64(v int64) be { return binary.BigEndian.AppendUint64(b, uint64(v)) }
+func (b be) str(s string) be {
+	return append(b.i16(int16(len(s))), s...)
+}
+
+// kafkaRequest is a request received by the fake broker
+type kafkaRequest struct {
+	apiKey, version int16
+	correlationID   int32
+	clientID        string
+	body            []byte
+}
+
+// fakeBroker answers Metadata with one partition led by itself and Produce
+// with the error code in produceErr
+type fakeBroker struct {
+	ln         net.Listener
+	mu         sync.Mutex
+	requests   []kafkaRequest
+	produceErr int16
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{ln: ln}
+	go b.serve()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		buf := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		req := kafkaRequest{
+			apiKey:        int16(binary.BigEndian.Uint16(buf)),
+			version:       int16(binary.BigEndian.Uint16(buf[2:])),
+			correlationID: int32(binary.BigEndian.Uint32(buf[4:])),
+		}
+		n := int(binary.BigEndian.Uint16(buf[8:]))
+		req.clientID = string(buf[10 : 10+n])
+		req.body = buf[10+n:]
+
+		b.mu.Lock()
+		b.requests = append(b.requests, req)
+		produceErr := b.produceErr
+		b.mu.Unlock()
+
+		var resp be
+		switch req.apiKey {
+		case apiMetadata:
+			host, port, _ := net.SplitHostPort(b.ln.Addr().String())
+			p, _ := strconv.Atoi(port)
+			resp = resp.i32(1).i32(1).str(host).i32(int32(p)).i16(-1) // Broker 1, no rack
+			resp = resp.i32(1)                                        // Controller
+			resp = resp.i32(1).i16(0).str("pages").u8(0)              // Topic, not internal
+			resp = resp.i32(1).i16(0).i32(0).i32(1)                   // Partition 0 led by 1
+			resp = resp.i32(1).i32(1).i32(1).i32(1)                   // Replicas and ISR
+		case apiProduce:
+			resp = resp.i32(1).str("pages")
+			resp = resp.i32(1).i32(0).i16(produceErr).i64(42).i64(-1)
+			resp = resp.i32(0) // Throttle time
+		}
+		out := be(nil).i32(int32(4 + len(resp))).i32(req.correlationID)
+		conn.Write(append(out, resp...))
+	}
+}
+
+func (b *fakeBroker) produceRequests() []kafkaRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var reqs []kafkaRequest
+	for _, req := range b.requests {
+		if req.apiKey == apiProduce {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+func newTestKafkaProducer(t *testing.T, b *fakeBroker) *kafkaProducer {
+	t.Helper()
+	p, err := newKafkaProducer(config.PublishConfig{
+		Brokers:         []string{b.ln.Addr().String()},
+		Topic:           "pages",
+		MaxMessageBytes: 1 << 20,
+		Timeout:         2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.close() })
+	return p
+}
+
+func TestKafkaMetadataRequest(t *testing.T) {
+	b := newFakeBroker(t)
+	newTestKafkaProducer(t, b)
+
+	b.mu.Lock()
+	req := b.requests[0]
+	b.mu.Unlock()
+	if req.apiKey != apiMetadata || req.version != 1 || req.correlationID != 1 || req.clientID != kafkaClientID {
+		t.Fatalf("metadata request header = %+v", req)
+	}
+	if want := be(nil).i32(1).str("pages"); !bytes.Equal(req.body, want) {
+		t.Fatalf("metadata request body = %x, want %x", req.body, []byte(want))
+	}
+}
+
+func TestKafkaProduceFraming(t *testing.T) {
+	b := newFakeBroker(t)
+	p := newTestKafkaProducer(t, b)
+
+	msgs := []*message{
+		{key: "example.com", value: []byte(`{"url":"a"}`)},
+		{key: "example.com", value: []byte(`{"url":"b"}`)},
+	}
+	if err := p.publish(context.Background(), msgs); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range msgs {
+		if m.err != nil {
+			t.Fatalf("acked message has error %v", m.err)
+		}
+	}
+
+	reqs := b.produceRequests()
+	if len(reqs) != 1 {
+		t.Fatalf("broker got %d produce requests, want 1", len(reqs))
+	}
+	req := reqs[0]
+	if req.version != produceVersion || req.correlationID != 2 {
+		t.Fatalf("produce request header = %+v", req)
+	}
+
+	// Transactional ID null, acks=all, timeout, one topic with one partition
+	head := be(nil).i16(-1).i16(-1).i32(2000).i32(1).str("pages").i32(1).i32(0)
+	if !bytes.HasPrefix(req.body, head) {
+		t.Fatalf("produce request starts %x, want %x", req.body[:len(head)], []byte(head))
+	}
+	set := req.body[len(head):]
+	setLen := int(binary.BigEndian.Uint32(set))
+	set = set[4:]
+	if setLen != len(set) {
+		t.Fatalf("record set length %d, %d bytes follow", setLen, len(set))
+	}
+
+	batchLen := int(binary.BigEndian.Uint32(set[8:]))
+	if 12+batchLen != len(set) {
+		t.Fatalf("batch length %d doesn't cover the %d byte record set", batchLen, len(set))
+	}
+	if set[16] != 2 {
+		t.Fatalf("magic = %d, want 2", set[16])
+	}
+	if crc := binary.BigEndian.Uint32(set[17:]); crc != crc32.Checksum(set[21:], castagnoli) {
+		t.Fatalf("batch CRC %#x doesn't match its contents", crc)
+	}
+	if delta := binary.BigEndian.Uint32(set[23:]); delta != 1 {
+		t.Fatalf("last offset delta = %d, want 1", delta)
+	}
+	if count := binary.BigEndian.Uint32(set[57:]); count != 2 {
+		t.Fatalf("record count = %d, want 2", count)
+	}
+	if !bytes.Contains(set, []byte(`{"url":"b"}`)) {
+		t.Fatal("record set lacks the second message")
+	}
+}
+
+func TestKafkaProduceErrors(t *testing.T) {
+	tests := []struct {
+		code      int16
+		permanent bool
+		stale     bool
+	}{
+		{errMessageTooLarge, true, false},
+		{errNotLeader, false, true},
+	}
+	for _, tt := range tests {
+		b := newFakeBroker(t)
+		p := newTestKafkaProducer(t, b)
+		b.mu.Lock()
+		b.produceErr = tt.code
+		b.mu.Unlock()
+
+		m := &message{key: "example.com", value: []byte("{}")}
+		err := p.publish(context.Background(), []*message{m})
+		var kerr kafkaError
+		if !errors.As(err, &kerr) || int16(kerr) != tt.code {
+			t.Fatalf("publish() = %v, want error code %d", err, tt.code)
+		}
+		if m.err == nil || m.permanent != tt.permanent || p.stale != tt.stale {
+			t.Errorf("code %d: err %v, permanent %v, stale metadata %v", tt.code, m.err, m.permanent, p.stale)
+		}
+	}
+}
+
+func TestKafkaRejectsOversizedMessage(t *testing.T) {
+	b := newFakeBroker(t)
+	p := newTestKafkaProducer(t, b)
+	p.maxBytes = 100
+
+	m := &message{key: "example.com", value: bytes.Repeat([]byte("x"), 100)}
+	if err := p.publish(context.Background(), []*message{m}); err == nil || !m.permanent {
+		t.Fatalf("publish() = %v, permanent %v", err, m.permanent)
+	}
+	if len(b.produceRequests()) != 0 {
+		t.Fatal("oversized message was sent")
+	}
+}
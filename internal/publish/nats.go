@@ -0,0 +1,225 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// natsInfo is the part of the server's INFO message the producer uses
+type natsInfo struct {
+	MaxPayload  int  `json:"max_payload"`
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect is the CONNECT message
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// natsProducer publishes messages to the subject <topic>.<host> with the
+// NATS text protocol. A batch is followed by a PING; the server's PONG
+// confirms that it processed every PUB before it.
+type natsProducer struct {
+	addr    string
+	subject string
+	timeout time.Duration
+	auth    natsConnect
+
+	conn       net.Conn
+	reader     *bufio.Reader
+	writer     *bufio.Writer
+	maxPayload int
+}
+
+func newNATSProducer(cfg config.PublishConfig) (*natsProducer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats url: %w", err)
+	}
+	p := &natsProducer{
+		addr:    u.Host,
+		subject: cfg.Topic,
+		timeout: cfg.Timeout,
+		auth:    natsConnect{Name: "web-crawler", Lang: "go", Protocol: 1},
+	}
+	if u.Port() == "" {
+		p.addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			p.auth.User, p.auth.Pass = u.User.Username(), pass
+		} else {
+			p.auth.Token = u.User.Username()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err := p.connect(ctx); err != nil {
+		return nil, err
+	}
+	log.Info("Connected to NATS server %s", p.addr)
+	return p, nil
+}
+
+// connect opens a connection and completes the INFO/CONNECT handshake
+func (p *natsProducer) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats server %s: %w", p.addr, err)
+	}
+	p.conn, p.reader, p.writer = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+	p.setDeadline(ctx)
+
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		p.close()
+		return fmt.Errorf("failed to read nats server info: %w", err)
+	}
+	infoJSON, ok := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	if !ok {
+		p.close()
+		return fmt.Errorf("unexpected nats greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		p.close()
+		return fmt.Errorf("failed to decode nats server info: %w", err)
+	}
+	if info.TLSRequired {
+		p.close()
+		return fmt.Errorf("nats server %s requires TLS, which is not supported", p.addr)
+	}
+	p.maxPayload = info.MaxPayload
+
+	connect, err := json.Marshal(p.auth)
+	if err != nil {
+		p.close()
+		return fmt.Errorf("failed to encode nats connect: %w", err)
+	}
+	fmt.Fprintf(p.writer, "CONNECT %s\r\nPING\r\n", connect)
+	if err := p.sync(); err != nil {
+		p.close()
+		return fmt.Errorf("nats handshake failed: %w", err)
+	}
+	return nil
+}
+
+// publish sends every message followed by a PING and waits for the PONG
+func (p *natsProducer) publish(ctx context.Context, msgs []*message) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			for _, m := range msgs {
+				m.err = err
+			}
+			return err
+		}
+	}
+	p.setDeadline(ctx)
+
+	var firstErr error
+	sent := make([]*message, 0, len(msgs))
+	for _, m := range msgs {
+		if p.maxPayload > 0 && len(m.value) > p.maxPayload {
+			m.err = fmt.Errorf("message of %d bytes exceeds the server's max_payload of %d", len(m.value), p.maxPayload)
+			m.permanent = true
+			firstErr = m.err
+			continue
+		}
+		fmt.Fprintf(p.writer, "PUB %s %d\r\n", p.subjectFor(m.key), len(m.value))
+		p.writer.Write(m.value)
+		p.writer.WriteString("\r\n")
+		sent = append(sent, m)
+	}
+	if len(sent) == 0 {
+		return firstErr
+	}
+
+	p.writer.WriteString("PING\r\n")
+	if err := p.sync(); err != nil {
+		// Which PUBs the server processed is unknown, so all are retried
+		p.close()
+		for _, m := range sent {
+			m.err = err
+		}
+		return err
+	}
+	return firstErr
+}
+
+// sync flushes the writer and reads until the server's PONG, answering its
+// PINGs. Errors the server reports on the way are returned.
+func (p *natsProducer) sync() error {
+	if err := p.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write to nats: %w", err)
+	}
+
+	var errs []error
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to read from nats: %w", err))...)
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return errors.Join(errs...)
+		case line == "PING":
+			p.writer.WriteString("PONG\r\n")
+			if err := p.writer.Flush(); err != nil {
+				return fmt.Errorf("failed to write to nats: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			errs = append(errs, fmt.Errorf("nats: %s", strings.Trim(strings.TrimSpace(line[4:]), "'")))
+		}
+	}
+}
+
+// subjectFor appends the host to the subject prefix as a single token
+func (p *natsProducer) subjectFor(host string) string {
+	if host == "" {
+		return p.subject + ".unknown"
+	}
+	token := strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, host)
+	return p.subject + "." + token
+}
+
+func (p *natsProducer) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(p.timeout)
+	}
+	p.conn.SetDeadline(deadline)
+}
+
+func (p *natsProducer) close() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
@@ -0,0 +1,184 @@
+package publish
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// natsServer scripts the server side of one NATS connection. It sends INFO,
+// then hands every line it reads to reply, which returns what to send back.
+type natsServer struct {
+	ln       net.Listener
+	received chan string
+}
+
+func newNATSServer(t *testing.T, info string, reply func(line string) string) *natsServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &natsServer{ln: ln, received: make(chan string, 100)}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "INFO "+info+"\r\n")
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			// A PUB line is followed by its payload line
+			if strings.HasPrefix(line, "PUB ") {
+				payload, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				line += payload
+			}
+			s.received <- line
+			io.WriteString(conn, reply(line))
+		}
+	}()
+	return s
+}
+
+// next returns the next line the server received
+func (s *natsServer) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case line := <-s.received:
+		return line
+	case <-time.After(2 * time.Second):
+		t.Fatal("server received nothing")
+		return ""
+	}
+}
+
+// pong answers PINGs and ignores everything else
+func pong(line string) string {
+	if line == "PING\r\n" {
+		return "PONG\r\n"
+	}
+	return ""
+}
+
+func newTestNATSProducer(t *testing.T, s *natsServer, userinfo string) *natsProducer {
+	t.Helper()
+	p, err := newNATSProducer(config.PublishConfig{
+		URL:     "nats://" + userinfo + s.ln.Addr().String(),
+		Topic:   "crawl",
+		Timeout: 2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.close() })
+	return p
+}
+
+func TestNATSHandshake(t *testing.T) {
+	s := newNATSServer(t, `{"server_id":"test","max_payload":1048576}`, pong)
+	p := newTestNATSProducer(t, s, "crawler:secret@")
+
+	connect, ok := strings.CutPrefix(s.next(t), "CONNECT ")
+	if !ok {
+		t.Fatal("first line isn't CONNECT")
+	}
+	var got natsConnect
+	if err := json.Unmarshal([]byte(connect), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.User != "crawler" || got.Pass != "secret" || got.Verbose || got.Lang != "go" {
+		t.Fatalf("CONNECT = %+v", got)
+	}
+	if line := s.next(t); line != "PING\r\n" {
+		t.Fatalf("handshake sent %q after CONNECT, want PING", line)
+	}
+	if p.maxPayload != 1048576 {
+		t.Fatalf("max payload = %d", p.maxPayload)
+	}
+}
+
+func TestNATSPublishFraming(t *testing.T) {
+	s := newNATSServer(t, `{"max_payload":16}`, pong)
+	p := newTestNATSProducer(t, s, "")
+	s.next(t) // CONNECT
+	s.next(t) // PING
+
+	msgs := []*message{
+		{key: "www.example.com", value: []byte("hello")},
+		{key: "", value: []byte("x")},
+		{key: "big.com", value: []byte(strings.Repeat("x", 17))},
+	}
+	err := p.publish(context.Background(), msgs)
+	if err == nil || !msgs[2].permanent {
+		t.Fatalf("publish() = %v, oversized message permanent %v", err, msgs[2].permanent)
+	}
+	if msgs[0].err != nil || msgs[1].err != nil {
+		t.Fatalf("delivered messages have errors %v, %v", msgs[0].err, msgs[1].err)
+	}
+
+	for _, want := range []string{
+		"PUB crawl.www_example_com 5\r\nhello\r\n",
+		"PUB crawl.unknown 1\r\nx\r\n",
+		"PING\r\n",
+	} {
+		if line := s.next(t); line != want {
+			t.Fatalf("server received %q, want %q", line, want)
+		}
+	}
+}
+
+func TestNATSServerErrorFailsBatch(t *testing.T) {
+	s := newNATSServer(t, `{}`, func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "PUB "):
+			// The server checks on us before answering
+			return "PING\r\n-ERR 'Permissions Violation for Publish to crawl.a_com'\r\n"
+		case line == "PING\r\n":
+			return "PONG\r\n"
+		}
+		return ""
+	})
+	p := newTestNATSProducer(t, s, "token@")
+	s.next(t)
+	s.next(t)
+
+	m := &message{key: "a.com", value: []byte("{}")}
+	err := p.publish(context.Background(), []*message{m})
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") || m.err == nil {
+		t.Fatalf("publish() = %v, message error %v", err, m.err)
+	}
+	s.next(t) // PUB
+	s.next(t) // PING ending the batch
+	if line := s.next(t); line != "PONG\r\n" {
+		t.Fatalf("server PING answered with %q", line)
+	}
+	if p.conn != nil {
+		t.Fatal("connection kept after a failed batch")
+	}
+}
+
+func TestNATSRejectsTLS(t *testing.T) {
+	s := newNATSServer(t, `{"tls_required":true}`, pong)
+	_, err := newNATSProducer(config.PublishConfig{URL: "nats://" + s.ln.Addr().String(), Topic: "crawl", Timeout: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "TLS") {
+		t.Fatalf("newNATSProducer() = %v, want a TLS error", err)
+	}
+}
@@ -0,0 +1,313 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/storage"
+)
+
+// log is the logger of the publish package
+var log = logger.For("publish")
+
+// message is an encoded page on its way to the broker
+type message struct {
+	page      *storage.WebPage
+	key       string // Host of the page, selects the partition or subject
+	value     []byte
+	err       error // Why the last attempt failed, nil once delivered
+	permanent bool  // The failure will not go away by retrying
+}
+
+// producer delivers messages to a broker
+type producer interface {
+	// publish sends msgs and sets err on those that were not delivered,
+	// returning the first such error
+	publish(ctx context.Context, msgs []*message) error
+	close() error
+}
+
+// failure is a line of the failed-pages file
+type failure struct {
+	FailedAt time.Time        `json:"failed_at"`
+	Error    string           `json:"error"`
+	Page     *storage.WebPage `json:"page"`
+}
+
+// Publisher streams stored pages to Kafka or NATS. It implements
+// storage.Archiver; pages are queued and published in batches from a single
+// goroutine, retried with backoff, and written to a file when they can't be
+// delivered.
+type Publisher struct {
+	cfg      config.PublishConfig
+	producer producer
+	encode   func(*storage.WebPage) ([]byte, error)
+	failures *os.File
+
+	pages   chan *storage.WebPage
+	flushes chan chan error
+	done    chan struct{}
+	closeMu sync.RWMutex
+	closed  bool
+
+	// Counters
+	published int64
+	failed    int64
+	retries   int64
+	batches   int64
+}
+
+// New connects to the configured broker. It returns nil if publishing is disabled.
+func New(cfg config.PublishConfig) (*Publisher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	p := &Publisher{
+		cfg:     cfg,
+		encode:  encodeJSON,
+		pages:   make(chan *storage.WebPage, cfg.BufferSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+	}
+	if cfg.Format == "avro" {
+		p.encode = encodeAvro
+	}
+
+	var err error
+	switch cfg.Backend {
+	case "kafka":
+		p.producer, err = newKafkaProducer(cfg)
+	case "nats":
+		p.producer, err = newNATSProducer(cfg)
+	default:
+		err = fmt.Errorf("unknown publish backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.FailedPath != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.FailedPath), 0755); err != nil {
+			p.producer.close()
+			return nil, fmt.Errorf("failed to create publish failure directory: %w", err)
+		}
+		p.failures, err = os.OpenFile(cfg.FailedPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			p.producer.close()
+			return nil, fmt.Errorf("failed to open publish failure file: %w", err)
+		}
+	}
+
+	log.Info("Publishing pages to %s topic %s as %s", cfg.Backend, cfg.Topic, cfg.Format)
+	go p.run()
+	return p, nil
+}
+
+// Store queues a page for publishing, blocking while the buffer is full
+func (p *Publisher) Store(ctx context.Context, page *storage.WebPage) error {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		return fmt.Errorf("failed to publish webpage: publisher is closed")
+	}
+	select {
+	case p.pages <- page:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to publish webpage: %w", ctx.Err())
+	}
+}
+
+// Flush publishes every page stored so far
+func (p *Publisher) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case p.flushes <- reply:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+}
+
+// Close publishes the remaining pages and disconnects
+func (p *Publisher) Close(ctx context.Context) error {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.pages)
+	}
+	p.closeMu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+
+	err := p.producer.close()
+	if p.failures != nil {
+		p.failures.Close()
+	}
+	return err
+}
+
+// run collects pages into batches and publishes a batch when it is full,
+// when its oldest page has waited a flush interval, or on request
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	timer := time.NewTimer(p.cfg.FlushInterval)
+	timer.Stop()
+
+	batch := make([]*storage.WebPage, 0, p.cfg.BatchSize)
+	publish := func() error {
+		timer.Stop()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := p.deliver(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case page, ok := <-p.pages:
+			if !ok {
+				publish()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(p.cfg.FlushInterval)
+			}
+			batch = append(batch, page)
+			if len(batch) >= p.cfg.BatchSize {
+				publish()
+			}
+		case <-timer.C:
+			publish()
+		case reply := <-p.flushes:
+			// Pick up pages stored before the flush was requested
+			for n := len(p.pages); n > 0; n-- {
+				batch = append(batch, <-p.pages)
+			}
+			reply <- publish()
+		}
+	}
+}
+
+// deliver publishes a batch, retrying undelivered pages with doubling
+// backoff. Pages still undelivered after the last retry, or that can never
+// be delivered, are recorded as failures.
+func (p *Publisher) deliver(batch []*storage.WebPage) error {
+	atomic.AddInt64(&p.batches, 1)
+
+	msgs := make([]*message, 0, len(batch))
+	for _, page := range batch {
+		value, err := p.encode(page)
+		if err != nil {
+			p.fail(page, fmt.Errorf("failed to encode page: %w", err))
+			continue
+		}
+		msgs = append(msgs, &message{page: page, key: host(page.URL), value: value})
+	}
+
+	var lastErr error
+	backoff := p.cfg.RetryBackoff
+	for attempt := 0; len(msgs) > 0; attempt++ {
+		for _, m := range msgs {
+			m.err, m.permanent = nil, false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		err := p.producer.publish(ctx, msgs)
+		cancel()
+		if err == nil {
+			atomic.AddInt64(&p.published, int64(len(msgs)))
+			return lastErr
+		}
+		lastErr = err
+
+		retry := msgs[:0]
+		for _, m := range msgs {
+			switch {
+			case m.err == nil:
+				atomic.AddInt64(&p.published, 1)
+			case m.permanent || attempt >= p.cfg.MaxRetries:
+				p.fail(m.page, m.err)
+			default:
+				retry = append(retry, m)
+			}
+		}
+		msgs = retry
+		if len(msgs) == 0 {
+			break
+		}
+
+		atomic.AddInt64(&p.retries, 1)
+		log.Warn("Retrying %d pages in %s: %v", len(msgs), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	return fmt.Errorf("failed to publish webpages: %w", lastErr)
+}
+
+// fail records a page that could not be published
+func (p *Publisher) fail(page *storage.WebPage, err error) {
+	atomic.AddInt64(&p.failed, 1)
+	log.Error("Failed to publish webpage %s: %v", page.URL, err)
+	if p.failures == nil {
+		return
+	}
+
+	line, merr := json.Marshal(failure{FailedAt: time.Now(), Error: err.Error(), Page: page})
+	if merr != nil {
+		return
+	}
+	if _, werr := p.failures.Write(append(line, '\n')); werr != nil {
+		log.Warn("Failed to record undelivered webpage %s: %v", page.URL, werr)
+	}
+}
+
+// GetStats returns publisher statistics
+func (p *Publisher) GetStats() map[string]int64 {
+	return map[string]int64{
+		"buffered":  int64(len(p.pages)),
+		"published": atomic.LoadInt64(&p.published),
+		"failed":    atomic.LoadInt64(&p.failed),
+		"retries":   atomic.LoadInt64(&p.retries),
+		"batches":   atomic.LoadInt64(&p.batches),
+	}
+}
+
+// host returns the host of a page URL, the partition key of its message
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// encodeJSON encodes a page as the JSON document that export writes
+func encodeJSON(page *storage.WebPage) ([]byte, error) {
+	return json.Marshal(page)
+}
@@ -0,0 +1,272 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// DeadLetter records a URL that failed permanently
+type DeadLetter struct {
+	URL        string    `json:"url" bson:"url"`
+	Host       string    `json:"host" bson:"host"`
+	Error      string    `json:"error" bson:"error"`
+	Attempts   int       `json:"attempts" bson:"attempts"`
+	StatusCode int       `json:"status_code" bson:"status_code"` // Last HTTP status, 0 for transport errors
+	FailedAt   time.Time `json:"failed_at" bson:"failed_at"`
+}
+
+// DeadLetterStore keeps URLs that failed after all retries so they can be inspected and requeued
+type DeadLetterStore interface {
+	// Add records a failure, replacing an earlier entry for the same URL
+	Add(ctx context.Context, entry DeadLetter) error
+	List(ctx context.Context) ([]DeadLetter, error)
+	Remove(ctx context.Context, urls ...string) error
+	Len(ctx context.Context) (int, error)
+	Close() error
+}
+
+// NewDeadLetterStore creates the dead-letter backend selected in the queue config.
+// The mongodb backend is provided by storage.MongoArchiver.DeadLetters since it
+// shares the archiver's connection.
+func NewDeadLetterStore(cfg config.DeadLetterConfig) (DeadLetterStore, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemoryDeadLetters(), nil
+	case "file":
+		return NewFileDeadLetters(cfg.Path)
+	case "mongodb":
+		return nil, fmt.Errorf("mongodb dead letters must be created from the MongoDB archiver")
+	default:
+		return nil, fmt.Errorf("unknown dead letter backend %q", cfg.Backend)
+	}
+}
+
+// Requeue pushes the given dead letters (all of them if urls is empty) back
+// onto the queue with low priority and removes them from the store
+func Requeue(ctx context.Context, store DeadLetterStore, q URLQueue, urls ...string) (int, error) {
+	entries, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	wanted := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		wanted[u] = true
+	}
+
+	var requeued []string
+	for _, entry := range entries {
+		if len(wanted) > 0 && !wanted[entry.URL] {
+			continue
+		}
+		q.PushWithPriority(entry.URL, PriorityLow, entry.Host, 0)
+		requeued = append(requeued, entry.URL)
+	}
+
+	if len(requeued) == 0 {
+		return 0, nil
+	}
+	if err := store.Remove(ctx, requeued...); err != nil {
+		return 0, err
+	}
+	return len(requeued), nil
+}
+
+// MemoryDeadLetters keeps dead letters in memory, ordered by first failure
+type MemoryDeadLetters struct {
+	mu      sync.Mutex
+	entries map[string]DeadLetter
+	order   []string
+}
+
+// NewMemoryDeadLetters creates an empty in-memory dead-letter store
+func NewMemoryDeadLetters() *MemoryDeadLetters {
+	return &MemoryDeadLetters{entries: make(map[string]DeadLetter)}
+}
+
+// Add records a permanently failed URL
+func (d *MemoryDeadLetters) Add(ctx context.Context, entry DeadLetter) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.add(entry)
+	return nil
+}
+
+// add stores an entry, must be called with the lock held
+func (d *MemoryDeadLetters) add(entry DeadLetter) {
+	if _, ok := d.entries[entry.URL]; !ok {
+		d.order = append(d.order, entry.URL)
+	}
+	d.entries[entry.URL] = entry
+}
+
+// List returns all dead letters
+func (d *MemoryDeadLetters) List(ctx context.Context) ([]DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := make([]DeadLetter, 0, len(d.order))
+	for _, u := range d.order {
+		list = append(list, d.entries[u])
+	}
+	return list, nil
+}
+
+// Remove deletes dead letters by URL
+func (d *MemoryDeadLetters) Remove(ctx context.Context, urls ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.remove(urls)
+	return nil
+}
+
+// remove deletes entries, must be called with the lock held
+func (d *MemoryDeadLetters) remove(urls []string) {
+	for _, u := range urls {
+		delete(d.entries, u)
+	}
+	order := d.order[:0]
+	for _, u := range d.order {
+		if _, ok := d.entries[u]; ok {
+			order = append(order, u)
+		}
+	}
+	d.order = order
+}
+
+// Len returns the number of dead letters
+func (d *MemoryDeadLetters) Len(ctx context.Context) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries), nil
+}
+
+// Close is a no-op for the in-memory store
+func (d *MemoryDeadLetters) Close() error {
+	return nil
+}
+
+// FileDeadLetters keeps dead letters in memory backed by a JSON-lines file
+// that survives restarts
+type FileDeadLetters struct {
+	*MemoryDeadLetters
+	path string
+	file *os.File
+}
+
+// NewFileDeadLetters opens or creates the dead-letter file at path and loads its entries
+func NewFileDeadLetters(path string) (*FileDeadLetters, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+
+	d := &FileDeadLetters{MemoryDeadLetters: NewMemoryDeadLetters(), path: path}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	d.file = file
+	return d, nil
+}
+
+// load reads existing entries, later lines replacing earlier ones for the same URL
+func (d *FileDeadLetters) load() error {
+	file, err := os.Open(d.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip a torn last line
+		}
+		d.add(entry)
+	}
+	return scanner.Err()
+}
+
+// Add records a failure and appends it to the file
+func (d *FileDeadLetters) Add(ctx context.Context, entry DeadLetter) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	d.add(entry)
+	return nil
+}
+
+// Remove deletes entries and rewrites the file without them
+func (d *FileDeadLetters) Remove(ctx context.Context, urls ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.remove(urls)
+	return d.rewrite()
+}
+
+// rewrite replaces the file with the current entries, must be called with the lock held
+func (d *FileDeadLetters) rewrite() error {
+	tmpPath := d.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create dead letter file: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	for _, u := range d.order {
+		line, err := json.Marshal(d.entries[u])
+		if err != nil {
+			continue
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write dead letter file: %w", err)
+	}
+	tmp.Close()
+
+	d.file.Close()
+	if err := os.Rename(tmpPath, d.path); err != nil {
+		return fmt.Errorf("failed to replace dead letter file: %w", err)
+	}
+
+	d.file, err = os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	return nil
+}
+
+// Close closes the dead-letter file
+func (d *FileDeadLetters) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func deadLetter(url string, attempts int) DeadLetter {
+	return DeadLetter{
+		URL:        url,
+		Host:       "a.com",
+		Error:      "status 503",
+		Attempts:   attempts,
+		StatusCode: 503,
+		FailedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// listURLs lists the URLs of a store's entries in order
+func listURLs(t *testing.T, store DeadLetterStore) string {
+	t.Helper()
+	entries, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []string
+	for _, e := range entries {
+		list = append(list, e.URL)
+	}
+	return strings.Join(list, " ")
+}
+
+func TestMemoryDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	d := NewMemoryDeadLetters()
+	d.Add(ctx, deadLetter("https://a.com/1", 3))
+	d.Add(ctx, deadLetter("https://a.com/2", 3))
+	// A later failure replaces the entry but keeps its place
+	d.Add(ctx, deadLetter("https://a.com/1", 5))
+
+	if got := listURLs(t, d); got != "https://a.com/1 https://a.com/2" {
+		t.Fatalf("List() = %s", got)
+	}
+	entries, _ := d.List(ctx)
+	if entries[0].Attempts != 5 {
+		t.Errorf("replaced entry has %d attempts, want 5", entries[0].Attempts)
+	}
+
+	d.Remove(ctx, "https://a.com/1", "https://a.com/missing")
+	if n, _ := d.Len(ctx); n != 1 || listURLs(t, d) != "https://a.com/2" {
+		t.Errorf("after Remove: Len() = %d, List() = %s", n, listURLs(t, d))
+	}
+}
+
+func TestFileDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dead", "letters.jsonl")
+	d, err := NewFileDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range []string{"https://a.com/1", "https://a.com/2", "https://a.com/3"} {
+		if err := d.Add(ctx, deadLetter(u, i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Add(ctx, deadLetter("https://a.com/2", 7))
+	if err := d.Remove(ctx, "https://a.com/3"); err != nil {
+		t.Fatal(err)
+	}
+	d.Add(ctx, deadLetter("https://a.com/4", 1))
+	d.Close()
+
+	// A crash may leave a torn last line behind
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"url":"https://a.com/torn`)
+	f.Close()
+
+	d, err = NewFileDeadLetters(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if got := listURLs(t, d); got != "https://a.com/1 https://a.com/2 https://a.com/4" {
+		t.Fatalf("reopened List() = %s", got)
+	}
+	entries, _ := d.List(ctx)
+	if want := deadLetter("https://a.com/2", 7); entries[1] != want {
+		t.Errorf("reopened entry = %+v, want %+v", entries[1], want)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name      string
+		urls      []string
+		requeued  int
+		remaining string
+	}{
+		{"all", nil, 3, ""},
+		{"some", []string{"https://a.com/3", "https://a.com/1", "https://a.com/missing"}, 2, "https://a.com/2"},
+		{"none", []string{"https://a.com/missing"}, 0, "https://a.com/1 https://a.com/2 https://a.com/3"},
+	} {
+		d := NewMemoryDeadLetters()
+		for _, u := range []string{"https://a.com/1", "https://a.com/2", "https://a.com/3"} {
+			d.Add(ctx, deadLetter(u, 3))
+		}
+		q, _ := NewPriorityQueue(config.QueueConfig{Capacity: 10})
+
+		n, err := Requeue(ctx, d, q, tt.urls...)
+		if err != nil || n != tt.requeued || q.Size() != tt.requeued {
+			t.Errorf("%s: Requeue() = %d, %v with %d queued, want %d", tt.name, n, err, q.Size(), tt.requeued)
+		}
+		if got := listURLs(t, d); got != tt.remaining {
+			t.Errorf("%s: left %q, want %q", tt.name, got, tt.remaining)
+		}
+		if item, ok := q.Pop(); ok && (item.Priority != PriorityLow || item.Host != "a.com") {
+			t.Errorf("%s: requeued %+v", tt.name, item)
+		}
+		q.Close()
+	}
+}
+
+func TestNewDeadLetterStore(t *testing.T) {
+	if _, err := NewDeadLetterStore(config.DeadLetterConfig{Backend: "memory"}); err != nil {
+		t.Errorf("memory backend: %v", err)
+	}
+	store, err := NewDeadLetterStore(config.DeadLetterConfig{Backend: "file", Path: filepath.Join(t.TempDir(), "dead.jsonl")})
+	if _, ok := store.(*FileDeadLetters); err != nil || !ok {
+		t.Errorf("file backend = %T, %v", store, err)
+	} else {
+		store.Close()
+	}
+	for _, backend := range []string{"mongodb", "s3"} {
+		if _, err := NewDeadLetterStore(config.DeadLetterConfig{Backend: backend}); err == nil {
+			t.Errorf("%s backend created without an archiver", backend)
+		}
+	}
+}
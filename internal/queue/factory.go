@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"fmt"
+
+	"web-crawler/internal/config"
+)
+
+// NewFromConfig creates the queue implementation selected in the configuration.
+// resume rehydrates a persistent queue from its log. Settings that can't be
+// combined are an error, since library callers may skip config validation.
+func NewFromConfig(cfg config.QueueConfig, resume bool) (URLQueue, error) {
+	switch cfg.Backend {
+	case "redis":
+		if cfg.Persistent || cfg.HostAware {
+			return nil, fmt.Errorf("the redis queue backend can't be persistent or host aware")
+		}
+		return NewRedisQueue(cfg.Redis, cfg.InstanceID, cfg.Instances)
+	case "", "memory":
+	default:
+		return nil, fmt.Errorf("unknown queue backend: %s", cfg.Backend)
+	}
+	if cfg.Persistent && cfg.HostAware {
+		return nil, fmt.Errorf("a queue can't be both persistent and host aware")
+	}
+
+	switch {
+	case cfg.Persistent:
+		return NewPersistentQueue(cfg, resume)
+	case cfg.HostAware:
+		return NewHostAwareQueue(cfg.HostDelay, 0), nil
+	default:
+		return NewPriorityQueue(cfg)
+	}
+}
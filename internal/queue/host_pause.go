@@ -0,0 +1,203 @@
+package queue
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PausedHost is a host whose URLs are held back while the rest of the crawl
+// goes on
+type PausedHost struct {
+	Host   string     `json:"host"`
+	Since  time.Time  `json:"since"`
+	Until  *time.Time `json:"until,omitempty"` // When a timed pause ends, unset for a pause that lasts until resumed
+	Parked int        `json:"parked"`          // URLs currently held back
+}
+
+// pausedHost is the state of one paused host
+type pausedHost struct {
+	since  time.Time
+	until  time.Time   // Zero for a pause without a duration
+	timer  *time.Timer // Ends a timed pause, nil otherwise
+	gen    uint64      // Bumped on every pause so a stale timer can't end a newer one
+	parked []URLItem
+}
+
+// HostPauses parks the items of paused hosts as workers pop them, so the
+// rest of the crawl keeps going, and hands them back when the host resumes
+type HostPauses struct {
+	mu     sync.Mutex
+	hosts  map[string]*pausedHost
+	paused int32 // len(hosts), read without the lock on every pop
+
+	// Counters
+	parked      int64 // Currently held back
+	totalParked int64
+}
+
+// NewHostPauses creates an empty set of paused hosts
+func NewHostPauses() *HostPauses {
+	return &HostPauses{hosts: make(map[string]*pausedHost)}
+}
+
+// normalizeHost lowercases a host so pauses match regardless of case
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSpace(host))
+}
+
+// itemHost returns the host of a queued item, parsing its URL if the queue
+// didn't record one
+func itemHost(item URLItem) string {
+	if item.Host != "" {
+		return normalizeHost(item.Host)
+	}
+	if u, err := url.Parse(item.URL); err == nil {
+		return normalizeHost(u.Host)
+	}
+	return ""
+}
+
+// Pause holds back host, for d or until Resume if d is 0. When a timed pause
+// runs out, expire is called with the host and its parked items. Pausing a
+// paused host again replaces its duration.
+func (p *HostPauses) Pause(host string, d time.Duration, expire func(host string, items []URLItem)) PausedHost {
+	host = normalizeHost(host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.hosts[host]
+	if !ok {
+		h = &pausedHost{since: time.Now()}
+		p.hosts[host] = h
+		atomic.StoreInt32(&p.paused, int32(len(p.hosts)))
+	}
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.gen++
+	h.until = time.Time{}
+	if d > 0 {
+		gen := h.gen
+		h.until = time.Now().Add(d)
+		h.timer = time.AfterFunc(d, func() {
+			if items, ok := p.expire(host, gen); ok && expire != nil {
+				expire(host, items)
+			}
+		})
+	}
+	return h.info(host)
+}
+
+// Park holds back item if its host is paused and reports whether it did
+func (p *HostPauses) Park(item URLItem) bool {
+	if atomic.LoadInt32(&p.paused) == 0 {
+		return false
+	}
+	host := itemHost(item)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.hosts[host]
+	if !ok {
+		return false
+	}
+	h.parked = append(h.parked, item)
+	atomic.AddInt64(&p.parked, 1)
+	atomic.AddInt64(&p.totalParked, 1)
+	return true
+}
+
+// Resume lifts the pause of host and returns its parked items. ok is false
+// if the host wasn't paused.
+func (p *HostPauses) Resume(host string) (items []URLItem, ok bool) {
+	host = normalizeHost(host)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.hosts[host]
+	if !ok {
+		return nil, false
+	}
+	return p.lift(host, h), true
+}
+
+// expire lifts the pause of host if it is still the timed pause gen
+func (p *HostPauses) expire(host string, gen uint64) ([]URLItem, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.hosts[host]
+	if !ok || h.gen != gen {
+		return nil, false
+	}
+	return p.lift(host, h), true
+}
+
+// lift removes the pause of host and returns its parked items. p.mu must be held.
+func (p *HostPauses) lift(host string, h *pausedHost) []URLItem {
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	delete(p.hosts, host)
+	atomic.StoreInt32(&p.paused, int32(len(p.hosts)))
+	atomic.AddInt64(&p.parked, -int64(len(h.parked)))
+	return h.parked
+}
+
+// Drain lifts every pause and returns all parked items, e.g. so they end up
+// in the checkpoint
+func (p *HostPauses) Drain() []URLItem {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var items []URLItem
+	for host, h := range p.hosts {
+		items = append(items, p.lift(host, h)...)
+	}
+	return items
+}
+
+// Parked returns the number of held back items
+func (p *HostPauses) Parked() int {
+	return int(atomic.LoadInt64(&p.parked))
+}
+
+// List returns the paused hosts ordered by name
+func (p *HostPauses) List() []PausedHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := make([]PausedHost, 0, len(p.hosts))
+	for host, h := range p.hosts {
+		list = append(list, h.info(host))
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Host < list[b].Host })
+	return list
+}
+
+// info describes a paused host
+func (h *pausedHost) info(host string) PausedHost {
+	info := PausedHost{Host: host, Since: h.since, Parked: len(h.parked)}
+	if !h.until.IsZero() {
+		until := h.until
+		info.Until = &until
+	}
+	return info
+}
+
+// GetStats returns pause statistics for monitoring
+func (p *HostPauses) GetStats() map[string]int64 {
+	return map[string]int64{
+		"hosts":       int64(atomic.LoadInt32(&p.paused)),
+		"parked":      atomic.LoadInt64(&p.parked),
+		"totalParked": atomic.LoadInt64(&p.totalParked),
+	}
+}
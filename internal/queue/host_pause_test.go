@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostPausesParkAndResume(t *testing.T) {
+	p := NewHostPauses()
+	p.Pause("Example.com", 0, nil)
+
+	if !p.Park(URLItem{URL: "https://example.com/a", Host: "example.com"}) {
+		t.Fatal("item of a paused host was not parked")
+	}
+	// Without a recorded host the URL decides
+	if !p.Park(URLItem{URL: "https://EXAMPLE.com/b"}) {
+		t.Fatal("item without host was not parked")
+	}
+	if p.Park(URLItem{URL: "https://other.com/", Host: "other.com"}) {
+		t.Fatal("item of another host was parked")
+	}
+	if got := p.Parked(); got != 2 {
+		t.Fatalf("Parked() = %d, want 2", got)
+	}
+
+	list := p.List()
+	if len(list) != 1 || list[0].Host != "example.com" || list[0].Parked != 2 || list[0].Until != nil {
+		t.Fatalf("List() = %+v", list)
+	}
+
+	items, ok := p.Resume("example.com")
+	if !ok || len(items) != 2 || items[0].URL != "https://example.com/a" {
+		t.Fatalf("Resume() = %v, %v", items, ok)
+	}
+	if p.Parked() != 0 || len(p.List()) != 0 {
+		t.Fatal("resumed host is still paused")
+	}
+	if p.Park(URLItem{URL: "https://example.com/c", Host: "example.com"}) {
+		t.Fatal("item of a resumed host was parked")
+	}
+	if _, ok := p.Resume("example.com"); ok {
+		t.Fatal("resuming a host that isn't paused succeeded")
+	}
+}
+
+func TestHostPausesExpire(t *testing.T) {
+	p := NewHostPauses()
+	expired := make(chan []URLItem, 1)
+	info := p.Pause("example.com", 20*time.Millisecond, func(host string, items []URLItem) {
+		expired <- items
+	})
+	if info.Until == nil {
+		t.Fatal("timed pause has no end")
+	}
+	p.Park(URLItem{URL: "https://example.com/a", Host: "example.com"})
+
+	select {
+	case items := <-expired:
+		if len(items) != 1 {
+			t.Fatalf("expired with %d items, want 1", len(items))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed pause never expired")
+	}
+	if len(p.List()) != 0 || p.Parked() != 0 {
+		t.Fatal("expired host is still paused")
+	}
+}
+
+func TestHostPausesRepauseOutlivesOldTimer(t *testing.T) {
+	p := NewHostPauses()
+	expired := make(chan string, 2)
+	expire := func(host string, items []URLItem) { expired <- host }
+
+	p.Pause("example.com", 20*time.Millisecond, expire)
+	// Pausing again without a duration must not be ended by the first timer
+	p.Pause("example.com", 0, expire)
+
+	// Stand in for a first timer that already fired but lost the race for the lock
+	p.mu.Lock()
+	gen := p.hosts["example.com"].gen - 1
+	p.mu.Unlock()
+	if _, ok := p.expire("example.com", gen); ok {
+		t.Fatal("stale timer lifted a newer pause")
+	}
+
+	select {
+	case <-expired:
+		t.Fatal("replaced timed pause expired")
+	case <-time.After(60 * time.Millisecond):
+	}
+	if len(p.List()) != 1 {
+		t.Fatal("host is no longer paused")
+	}
+}
+
+func TestHostPausesDrain(t *testing.T) {
+	p := NewHostPauses()
+	p.Pause("a.com", 0, nil)
+	p.Pause("b.com", time.Hour, nil)
+	p.Park(URLItem{URL: "https://a.com/", Host: "a.com"})
+	p.Park(URLItem{URL: "https://b.com/", Host: "b.com"})
+
+	if items := p.Drain(); len(items) != 2 {
+		t.Fatalf("Drain() returned %d items, want 2", len(items))
+	}
+	stats := p.GetStats()
+	if stats["hosts"] != 0 || stats["parked"] != 0 || stats["totalParked"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
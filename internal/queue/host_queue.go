@@ -0,0 +1,368 @@
+package queue
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hostBucket holds the queued items for a single host, split by priority
+type hostBucket struct {
+	items     [3][]URLItem
+	lastFetch time.Time
+}
+
+// size returns the number of queued items for the host
+func (b *hostBucket) size() int {
+	return len(b.items[PriorityHigh]) + len(b.items[PriorityNormal]) + len(b.items[PriorityLow])
+}
+
+// pop removes the highest priority item for the host
+func (b *hostBucket) pop() (URLItem, bool) {
+	for p := range b.items {
+		if len(b.items[p]) > 0 {
+			item := b.items[p][0]
+			b.items[p][0] = URLItem{}
+			b.items[p] = b.items[p][1:]
+			return item, true
+		}
+	}
+	return URLItem{}, false
+}
+
+// HostAwareQueue shards URLs by host and dequeues round-robin across hosts,
+// enforcing a minimum delay between two items of the same host
+type HostAwareQueue struct {
+	mu        sync.Mutex
+	hosts     map[string]*hostBucket
+	order     []string // Hosts with queued items in round-robin order
+	next      int
+	hostDelay time.Duration
+	maxSize   int
+	notify    chan struct{}
+	done      chan struct{}
+	size      int64
+	closed    int64
+
+	// Performance counters
+	totalQueued   int64
+	totalDequeued int64
+	dropped       int64
+	delayed       int64
+}
+
+// NewHostAwareQueue creates a host-sharded queue. hostDelay is the minimum time
+// between two dequeues for the same host and maxSize caps the total number of
+// queued items (0 means unbounded).
+func NewHostAwareQueue(hostDelay time.Duration, maxSize int) *HostAwareQueue {
+	return &HostAwareQueue{
+		hosts:     make(map[string]*hostBucket),
+		hostDelay: hostDelay,
+		maxSize:   maxSize,
+		notify:    make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// Push adds a URL with normal priority
+func (q *HostAwareQueue) Push(url string) {
+	q.PushWithPriority(url, PriorityNormal, "", 0)
+}
+
+// PushWithPriority adds a URL to its host's sub-queue
+func (q *HostAwareQueue) PushWithPriority(rawURL string, priority int, host string, depth int) {
+	if atomic.LoadInt64(&q.closed) == 1 {
+		return
+	}
+
+	if host == "" {
+		if parsed, err := url.Parse(rawURL); err == nil {
+			host = parsed.Host
+		}
+	}
+	if priority < PriorityHigh || priority > PriorityLow {
+		priority = PriorityNormal
+	}
+
+	item := URLItem{
+		URL:      rawURL,
+		Priority: priority,
+		Host:     host,
+		Depth:    depth,
+		QueuedAt: time.Now(),
+	}
+
+	q.mu.Lock()
+	if q.maxSize > 0 && int(atomic.LoadInt64(&q.size)) >= q.maxSize {
+		q.mu.Unlock()
+		atomic.AddInt64(&q.dropped, 1)
+		return
+	}
+
+	bucket, ok := q.hosts[host]
+	if !ok {
+		bucket = &hostBucket{}
+		q.hosts[host] = bucket
+	}
+	if bucket.size() == 0 {
+		q.order = append(q.order, host)
+	}
+	bucket.items[priority] = append(bucket.items[priority], item)
+	atomic.AddInt64(&q.size, 1)
+	atomic.AddInt64(&q.totalQueued, 1)
+	q.mu.Unlock()
+
+	q.wake()
+}
+
+// wake signals a blocked PopBlocking call without blocking the caller
+func (q *HostAwareQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pop returns the next item from the first host whose delay has elapsed.
+// Returns empty URLItem and false if no host is ready.
+func (q *HostAwareQueue) Pop() (URLItem, bool) {
+	item, _, ok := q.tryPop(time.Now())
+	return item, ok
+}
+
+// tryPop walks the hosts round-robin and pops from the first ready one. When
+// nothing is ready it returns how long until the earliest host becomes ready
+// (or -1 if the queue is empty).
+func (q *HostAwareQueue) tryPop(now time.Time) (URLItem, time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return URLItem{}, -1, false
+	}
+
+	wait := time.Duration(-1)
+	for i := 0; i < len(q.order); i++ {
+		idx := (q.next + i) % len(q.order)
+		host := q.order[idx]
+		bucket := q.hosts[host]
+
+		readyAt := bucket.lastFetch.Add(q.hostDelay)
+		if q.hostDelay > 0 && now.Before(readyAt) {
+			if until := readyAt.Sub(now); wait < 0 || until < wait {
+				wait = until
+			}
+			continue
+		}
+
+		item, _ := bucket.pop()
+		bucket.lastFetch = now
+
+		if bucket.size() == 0 {
+			// Remove the host from the rotation but keep its last fetch time
+			q.order = append(q.order[:idx], q.order[idx+1:]...)
+			if len(q.order) > 0 {
+				q.next = idx % len(q.order)
+			} else {
+				q.next = 0
+			}
+		} else {
+			q.next = (idx + 1) % len(q.order)
+		}
+
+		atomic.AddInt64(&q.size, -1)
+		atomic.AddInt64(&q.totalDequeued, 1)
+		return item, 0, true
+	}
+
+	atomic.AddInt64(&q.delayed, 1)
+	return URLItem{}, wait, false
+}
+
+// PopBlocking waits until a host is ready and returns its next item.
+// Returns false once the queue has been closed or ctx is done.
+func (q *HostAwareQueue) PopBlocking(ctx context.Context) (URLItem, bool) {
+	for {
+		if atomic.LoadInt64(&q.closed) == 1 || ctx.Err() != nil {
+			return URLItem{}, false
+		}
+
+		item, wait, ok := q.tryPop(time.Now())
+		if ok {
+			return item, true
+		}
+
+		if wait < 0 {
+			select {
+			case <-q.notify:
+			case <-q.done:
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-q.notify:
+		case <-q.done:
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// PopBatch returns up to maxItems items that are ready right now
+func (q *HostAwareQueue) PopBatch(maxItems int) []URLItem {
+	items := make([]URLItem, 0, maxItems)
+
+	for i := 0; i < maxItems; i++ {
+		item, ok := q.Pop()
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// DrainAll removes and returns every queued item, ignoring host delays
+func (q *HostAwareQueue) DrainAll() []URLItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := make([]URLItem, 0, atomic.LoadInt64(&q.size))
+	for _, host := range q.order {
+		bucket := q.hosts[host]
+		for p := range bucket.items {
+			items = append(items, bucket.items[p]...)
+			bucket.items[p] = nil
+		}
+	}
+	q.order = nil
+	q.next = 0
+	atomic.AddInt64(&q.totalDequeued, int64(len(items)))
+	atomic.StoreInt64(&q.size, 0)
+	return items
+}
+
+// Reprioritize moves queued items to their new priority within their host
+func (q *HostAwareQueue) Reprioritize(priority func(URLItem) int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	moved := 0
+	for _, bucket := range q.hosts {
+		var items [3][]URLItem
+		for p := range bucket.items {
+			for _, item := range bucket.items[p] {
+				if np := priority(item); np != item.Priority && np >= PriorityHigh && np <= PriorityLow {
+					item.Priority = np
+					moved++
+				}
+				items[item.Priority] = append(items[item.Priority], item)
+			}
+		}
+		bucket.items = items
+	}
+	return moved
+}
+
+// Size returns the number of queued items across all hosts
+func (q *HostAwareQueue) Size() int {
+	return int(atomic.LoadInt64(&q.size))
+}
+
+// HostSizes returns the number of queued items per host
+func (q *HostAwareQueue) HostSizes() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	sizes := make(map[string]int, len(q.order))
+	for _, host := range q.order {
+		sizes[host] = q.hosts[host].size()
+	}
+	return sizes
+}
+
+// items returns copies of the queued items by priority, oldest first within
+// a priority. Pops take turns between hosts, so this is not their order.
+func (q *HostAwareQueue) items() []URLItem {
+	q.mu.Lock()
+	items := make([]URLItem, 0, atomic.LoadInt64(&q.size))
+	for _, host := range q.order {
+		for _, queued := range q.hosts[host].items {
+			items = append(items, queued...)
+		}
+	}
+	q.mu.Unlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Priority != items[j].Priority {
+			return items[i].Priority < items[j].Priority
+		}
+		return items[i].QueuedAt.Before(items[j].QueuedAt)
+	})
+	return items
+}
+
+// Snapshot describes the queued items, ordered by priority and age
+func (q *HostAwareQueue) Snapshot(top int) Snapshot {
+	return newSnapshot(q.items(), top, time.Now())
+}
+
+// Walk calls fn with every queued item by priority and age until fn returns false
+func (q *HostAwareQueue) Walk(fn func(URLItem) bool) {
+	for _, item := range q.items() {
+		if !fn(item) {
+			return
+		}
+	}
+}
+
+// GetStats returns queue statistics for monitoring
+func (q *HostAwareQueue) GetStats() map[string]int64 {
+	q.mu.Lock()
+	activeHosts := int64(len(q.order))
+	knownHosts := int64(len(q.hosts))
+	var buffered [3]int64
+	for _, b := range q.hosts {
+		for p := range b.items {
+			buffered[p] += int64(len(b.items[p]))
+		}
+	}
+	q.mu.Unlock()
+
+	return map[string]int64{
+		"size":          atomic.LoadInt64(&q.size),
+		"totalQueued":   atomic.LoadInt64(&q.totalQueued),
+		"totalDequeued": atomic.LoadInt64(&q.totalDequeued),
+		"dropped":       atomic.LoadInt64(&q.dropped),
+		"delayed":       atomic.LoadInt64(&q.delayed),
+		"activeHosts":   activeHosts,
+		"knownHosts":    knownHosts,
+		"hostDelayMs":   q.hostDelay.Milliseconds(),
+		"highBuffer":    buffered[PriorityHigh],
+		"normalBuffer":  buffered[PriorityNormal],
+		"lowBuffer":     buffered[PriorityLow],
+	}
+}
+
+// IsFull checks if the queue is approaching its size limit
+func (q *HostAwareQueue) IsFull() bool {
+	if q.maxSize <= 0 {
+		return false
+	}
+	return q.Size() > q.maxSize*9/10 // 90% full
+}
+
+// Close closes the queue and wakes any blocked consumers
+func (q *HostAwareQueue) Close() {
+	if atomic.CompareAndSwapInt64(&q.closed, 0, 1) {
+		close(q.done)
+	}
+}
@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"sort"
+	"time"
+)
+
+// bandNames name the priority bands in snapshots
+var bandNames = [3]string{"high", "normal", "low"}
+
+// Inspector is implemented by queues whose contents can be looked at for
+// debugging without popping them
+type Inspector interface {
+	// Snapshot describes the queue with up to top items per priority band
+	// and the top busiest hosts
+	Snapshot(top int) Snapshot
+	// Walk calls fn with every item in memory, in pop order as far as the
+	// queue has one, until fn returns false. The items are copied first, so
+	// fn doesn't hold up the queue.
+	Walk(fn func(URLItem) bool)
+}
+
+// Snapshot describes the contents of a queue at one point in time
+type Snapshot struct {
+	Size      int                  `json:"size"`
+	Delayed   int                  `json:"delayed"`            // Held back until their NotBefore time
+	Spilled   int                  `json:"spilled"`            // On disk, not part of the snapshot
+	OldestAge float64              `json:"oldest_age_seconds"` // How long the longest queued item has waited
+	Oldest    *URLItem             `json:"oldest,omitempty"`
+	Top       map[string][]URLItem `json:"top"`        // Next items to pop by priority band
+	Hosts     []HostCount          `json:"hosts"`      // Busiest hosts, most items first
+	HostTotal int                  `json:"host_total"` // Hosts with queued items
+}
+
+// HostCount is the number of queued items of a host
+type HostCount struct {
+	Host  string `json:"host"`
+	Items int    `json:"items"`
+}
+
+// newSnapshot describes items, which must be in pop order
+func newSnapshot(items []URLItem, top int, now time.Time) Snapshot {
+	s := Snapshot{
+		Size: len(items),
+		Top:  make(map[string][]URLItem, len(bandNames)),
+	}
+	for _, name := range bandNames {
+		s.Top[name] = []URLItem{}
+	}
+
+	counts := make(map[string]int)
+	for i, item := range items {
+		name := bandNames[band(item.Priority)]
+		if len(s.Top[name]) < top {
+			s.Top[name] = append(s.Top[name], item)
+		}
+		counts[item.Host]++
+		if !item.NotBefore.IsZero() && item.NotBefore.After(now) {
+			s.Delayed++
+		}
+		if s.Oldest == nil || item.QueuedAt.Before(s.Oldest.QueuedAt) {
+			s.Oldest = &items[i]
+		}
+	}
+	if s.Oldest != nil {
+		s.OldestAge = now.Sub(s.Oldest.QueuedAt).Seconds()
+	}
+
+	s.HostTotal = len(counts)
+	s.Hosts = make([]HostCount, 0, len(counts))
+	for host, n := range counts {
+		s.Hosts = append(s.Hosts, HostCount{Host: host, Items: n})
+	}
+	sort.Slice(s.Hosts, func(i, j int) bool {
+		if s.Hosts[i].Items != s.Hosts[j].Items {
+			return s.Hosts[i].Items > s.Hosts[j].Items
+		}
+		return s.Hosts[i].Host < s.Hosts[j].Host
+	})
+	if len(s.Hosts) > top {
+		s.Hosts = s.Hosts[:top]
+	}
+	return s
+}
+
+// items returns copies of the items in memory in pop order: the heap by key,
+// then the held items by NotBefore time
+func (q *PriorityQueue) items() []URLItem {
+	q.mu.Lock()
+	ready := copyEntries(q.heap)
+	held := copyEntries(q.delayed.entryHeap)
+	q.mu.Unlock()
+
+	// Sorting copies leaves the indexes of the queued entries alone
+	sort.Slice(ready, func(i, j int) bool { return ready.Less(i, j) })
+	sort.Slice(held, func(i, j int) bool { return delayHeap{held}.Less(i, j) })
+	items := make([]URLItem, 0, len(ready)+len(held))
+	for _, e := range append(ready, held...) {
+		items = append(items, e.item)
+	}
+	return items
+}
+
+// copyEntries returns copies of entries, which change while queued
+func copyEntries(entries entryHeap) entryHeap {
+	copies := make(entryHeap, len(entries))
+	for i, e := range entries {
+		c := *e
+		copies[i] = &c
+	}
+	return copies
+}
+
+// Snapshot describes the items in memory. Spilled items are only counted.
+func (q *PriorityQueue) Snapshot(top int) Snapshot {
+	s := newSnapshot(q.items(), top, time.Now())
+	s.Spilled = int(q.spillPending())
+	s.Size += s.Spilled
+	return s
+}
+
+// Walk calls fn with every item in memory in pop order until fn returns false
+func (q *PriorityQueue) Walk(fn func(URLItem) bool) {
+	for _, item := range q.items() {
+		if !fn(item) {
+			return
+		}
+	}
+}
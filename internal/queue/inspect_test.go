@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	queues := map[string]interface {
+		URLQueue
+		Inspector
+	}{
+		"priority": NewURLQueue(),
+		"host":     NewHostAwareQueue(0, 0),
+	}
+	for name, q := range queues {
+		q.PushWithPriority("https://a.com/1", PriorityNormal, "a.com", 1)
+		q.PushWithPriority("https://b.com/1", PriorityLow, "b.com", 1)
+		q.PushWithPriority("https://a.com/2", PriorityHigh, "a.com", 1)
+		q.PushWithPriority("https://a.com/3", PriorityNormal, "a.com", 1)
+		q.PushWithPriority("https://c.com/1", PriorityNormal, "c.com", 1)
+		time.Sleep(10 * time.Millisecond)
+
+		s := q.Snapshot(2)
+		if s.Size != 5 || s.HostTotal != 3 || s.Oldest == nil || s.Oldest.URL != "https://a.com/1" || s.OldestAge < 0.01 {
+			t.Errorf("%s: snapshot %+v", name, s)
+		}
+		top := map[string]string{"high": "https://a.com/2", "normal": "https://a.com/1", "low": "https://b.com/1"}
+		for band, first := range top {
+			if len(s.Top[band]) == 0 || s.Top[band][0].URL != first {
+				t.Errorf("%s: top %s = %+v, want %s first", name, band, s.Top[band], first)
+			}
+		}
+		if n := len(s.Top["normal"]); n != 2 {
+			t.Errorf("%s: %d normal items, want the top 2", name, n)
+		}
+		if len(s.Hosts) != 2 || s.Hosts[0] != (HostCount{"a.com", 3}) || s.Hosts[1] != (HostCount{"b.com", 1}) {
+			t.Errorf("%s: hosts %+v", name, s.Hosts)
+		}
+
+		walked := 0
+		q.Walk(func(item URLItem) bool {
+			walked++
+			return walked < 3
+		})
+		if walked != 3 || q.Size() != 5 {
+			t.Errorf("%s: walked %d items, size %d", name, walked, q.Size())
+		}
+		q.Close()
+	}
+}
+
+func TestSnapshotHeldItems(t *testing.T) {
+	q := NewURLQueue()
+	defer q.Close()
+	q.Push("https://a.com/")
+	q.Push("https://b.com/")
+	a, _ := q.Pop()
+	q.Defer(a, time.Now().Add(time.Hour))
+
+	s := q.Snapshot(10)
+	if s.Size != 2 || s.Delayed != 1 {
+		t.Fatalf("snapshot %+v", s)
+	}
+	// Held items come after the ones ready to pop
+	if normal := s.Top["normal"]; len(normal) != 2 || normal[1].URL != "https://a.com/" {
+		t.Fatalf("top normal = %+v", normal)
+	}
+}
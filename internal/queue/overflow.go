@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow policies of a PriorityQueue
+const (
+	OverflowDrop  = "drop"  // Count and discard the item
+	OverflowBlock = "block" // Wait for room up to the timeout, then drop
+	OverflowSpill = "spill" // Write the item to disk until there is room
+)
+
+// refillBatch is how many spilled items one pop moves back into memory at most
+const refillBatch = 256
+
+// overflow handles an item that found the queue full by the overflow
+// policy. It reports whether the item was kept. Unless wait is true, the
+// block policy drops the item right away.
+func (q *PriorityQueue) overflow(item URLItem, wait bool) bool {
+	switch q.overflowCfg.Policy {
+	case OverflowBlock:
+		if wait && q.waitPlace(item) {
+			return true
+		}
+	case OverflowSpill:
+		if err := q.spill.write(item); err != nil {
+			log.Warn("Failed to spill %s to disk: %v", item.URL, err)
+			break
+		}
+		atomic.AddInt64(&q.size, 1)
+		atomic.AddInt64(&q.spilled, 1)
+		// Workers may have emptied the queue since the item found it full
+		q.refill()
+		return true
+	}
+
+	if atomic.AddInt64(&q.dropped, 1) == 1 {
+		log.Warn("Queue is full, dropping URLs. Set queue.overflow.policy to block or spill to keep them.")
+	}
+	return false
+}
+
+// waitPlace waits for a pop to make room for item, up to the overflow timeout
+func (q *PriorityQueue) waitPlace(item URLItem) bool {
+	atomic.AddInt64(&q.blocked, 1)
+	timer := time.NewTimer(q.overflowCfg.Timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-q.room:
+		case <-timer.C:
+			return false
+		case <-q.done:
+			return false
+		}
+		if atomic.LoadInt64(&q.closed) == 1 {
+			return false
+		}
+		if q.place(item) {
+			// Pass the wakeup on in case more items were popped meanwhile
+			q.signalRoom()
+			return true
+		}
+	}
+}
+
+// signalRoom wakes a push waiting for room without blocking the caller
+func (q *PriorityQueue) signalRoom() {
+	select {
+	case q.room <- struct{}{}:
+	default:
+	}
+}
+
+// refill moves spilled items back into memory once the queue is less than
+// half full. Spilled items are already counted in the size.
+func (q *PriorityQueue) refill() {
+	if q.spill == nil || q.spill.len() == 0 || q.buffered() >= q.capacity()/2 {
+		return
+	}
+	for i := 0; i < refillBatch; i++ {
+		item, ok := q.spill.read()
+		if !ok {
+			return
+		}
+		if q.place(item) {
+			atomic.AddInt64(&q.size, -1)
+			continue
+		}
+		// Filled up meanwhile, back to the end of the file
+		if err := q.spill.write(item); err != nil {
+			log.Warn("Failed to spill %s to disk: %v", item.URL, err)
+			atomic.AddInt64(&q.size, -1)
+			atomic.AddInt64(&q.dropped, 1)
+		}
+		return
+	}
+}
+
+// spillFile keeps queue items that did not fit in memory in a JSON-lines
+// file and hands them back in the order they were written
+type spillFile struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File // Appended to
+	writer  *bufio.Writer
+	input   *os.File // Read from where the last read stopped
+	reader  *bufio.Reader
+	pending int64
+}
+
+// openSpill creates an empty spill file at path, replacing an earlier one
+func openSpill(path string) (*spillFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	s := &spillFile{path: path}
+	if err := s.reset(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reset truncates the file. s.mu must be held unless s is not shared yet.
+func (s *spillFile) reset() error {
+	s.closeFiles()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	input, err := os.Open(s.path)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open spill file: %w", err)
+	}
+	s.file = file
+	s.writer = bufio.NewWriterSize(file, 64*1024)
+	s.input = input
+	s.reader = bufio.NewReaderSize(input, 64*1024)
+	return nil
+}
+
+// write appends an item
+func (s *spillFile) write(item URLItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		return fmt.Errorf("spill file is closed")
+	}
+	s.writer.Write(data)
+	if err := s.writer.WriteByte('\n'); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.pending, 1)
+	return nil
+}
+
+// read returns the oldest item not read yet. The file is truncated once all
+// of its items were read.
+func (s *spillFile) read() (URLItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.writer != nil && atomic.LoadInt64(&s.pending) > 0 {
+		if err := s.writer.Flush(); err != nil {
+			log.Warn("Failed to write spill file: %v", err)
+			return URLItem{}, false
+		}
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil {
+			log.Warn("Failed to read spill file: %v", err)
+			return URLItem{}, false
+		}
+		if atomic.AddInt64(&s.pending, -1) == 0 {
+			if err := s.reset(); err != nil {
+				log.Warn("Failed to truncate spill file: %v", err)
+			}
+		}
+		var item URLItem
+		if err := json.Unmarshal(line, &item); err == nil {
+			return item, true
+		}
+	}
+	return URLItem{}, false
+}
+
+// len returns the number of items written and not read yet
+func (s *spillFile) len() int64 {
+	return atomic.LoadInt64(&s.pending)
+}
+
+// close removes the file. Items still in it are lost.
+func (s *spillFile) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeFiles()
+	os.Remove(s.path)
+}
+
+// closeFiles closes both handles of the file
+func (s *spillFile) closeFiles() {
+	if s.file != nil {
+		s.file.Close()
+		s.input.Close()
+		s.file, s.writer, s.input, s.reader = nil, nil, nil, nil
+	}
+}
@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// fill pushes normal priority URLs until the queue is full
+func fill(q *PriorityQueue) int {
+	n := q.capacity()
+	for i := 0; i < n; i++ {
+		q.PushWithPriority(fmt.Sprintf("https://a.com/%d", i), PriorityNormal, "a.com", 1)
+	}
+	return n
+}
+
+func TestOverflowDrop(t *testing.T) {
+	q, err := NewPriorityQueue(config.QueueConfig{Capacity: 10, Overflow: config.OverflowConfig{Policy: OverflowDrop}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	n := fill(q)
+	q.Push("https://a.com/extra")
+	q.PushWithPriority("https://a.com/low", PriorityLow, "a.com", 1)
+	if stats := q.GetStats(); q.Size() != n || stats["dropped"] != 2 || stats["totalQueued"] != int64(n) {
+		t.Errorf("size %d, stats %v", q.Size(), stats)
+	}
+}
+
+func TestOverflowSpill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spill.jsonl")
+	q, err := NewPriorityQueue(config.QueueConfig{Capacity: 10, Overflow: config.OverflowConfig{Policy: OverflowSpill, Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := fill(q)
+	for i := 0; i < 3; i++ {
+		q.PushWithPriority(fmt.Sprintf("https://a.com/spilled/%d", i), PriorityLow, "a.com", 2)
+	}
+	stats := q.GetStats()
+	if q.Size() != n+3 || stats["spilled"] != 3 || stats["spillPending"] != 3 || stats["dropped"] != 0 {
+		t.Fatalf("size %d, stats %v", q.Size(), stats)
+	}
+
+	seen := make(map[string]URLItem)
+	for {
+		item, ok := q.Pop()
+		if !ok {
+			break
+		}
+		seen[item.URL] = item
+	}
+	if len(seen) != n+3 || q.Size() != 0 {
+		t.Fatalf("popped %d of %d items, size %d", len(seen), n+3, q.Size())
+	}
+	if item := seen["https://a.com/spilled/2"]; item.Priority != PriorityLow || item.Depth != 2 || item.Host != "a.com" {
+		t.Errorf("spilled item came back as %+v", item)
+	}
+
+	// The file is truncated when drained and reused
+	q.Push("https://a.com/after")
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("spill file after draining: %v, %v", info, err)
+	}
+	q.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("spill file left after Close: %v", err)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		pop     bool
+		dropped int64
+	}{
+		{"room made", 5 * time.Second, true, 0},
+		{"timed out", 10 * time.Millisecond, false, 1},
+	}
+	for _, tt := range tests {
+		q, err := NewPriorityQueue(config.QueueConfig{Capacity: 10, Overflow: config.OverflowConfig{Policy: OverflowBlock, Timeout: tt.timeout}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := fill(q)
+
+		pushed := make(chan struct{})
+		go func() {
+			q.Push("https://a.com/waiting")
+			close(pushed)
+		}()
+		if tt.pop {
+			time.Sleep(10 * time.Millisecond)
+			q.Pop()
+			n--
+		}
+		select {
+		case <-pushed:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: push still blocked", tt.name)
+		}
+
+		stats := q.GetStats()
+		if stats["blocked"] != 1 || stats["dropped"] != tt.dropped || int64(q.Size()) != int64(n)+1-tt.dropped {
+			t.Errorf("%s: size %d, stats %v", tt.name, q.Size(), stats)
+		}
+		q.Close()
+	}
+}
+
+func TestOverflowBlockNoWait(t *testing.T) {
+	q, err := NewPriorityQueue(config.QueueConfig{Capacity: 10, Overflow: config.OverflowConfig{Policy: OverflowBlock, Timeout: time.Minute}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	n := fill(q)
+
+	// Nobody pops, so a push that waited would hang for the timeout
+	pushed := make(chan struct{})
+	go func() {
+		PushNoWait(q, URLItem{URL: "https://a.com/link"})
+		item, _ := q.Pop()
+		Repush(q, item)
+		Repush(q, URLItem{URL: "https://a.com/back"})
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("push without waiting blocked")
+	}
+
+	stats := q.GetStats()
+	if stats["blocked"] != 0 || stats["dropped"] != 2 || q.Size() != n {
+		t.Errorf("size %d, stats %v", q.Size(), stats)
+	}
+}
+
+func TestOverflowSpillPersistent(t *testing.T) {
+	cfg := config.QueueConfig{
+		Capacity: 10,
+		Path:     filepath.Join(t.TempDir(), "queue.log"),
+		Overflow: config.OverflowConfig{Policy: OverflowSpill, Path: filepath.Join(t.TempDir(), "spill.jsonl")},
+	}
+	if _, err := NewPersistentQueue(cfg, false); err == nil {
+		t.Fatal("NewPersistentQueue() accepted the spill policy")
+	}
+}
+
+func TestOverflowUnknownPolicy(t *testing.T) {
+	if _, err := NewPriorityQueue(config.QueueConfig{Overflow: config.OverflowConfig{Policy: "wait"}}); err == nil {
+		t.Fatal("NewPriorityQueue() accepted an unknown policy")
+	}
+}
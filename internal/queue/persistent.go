@@ -0,0 +1,403 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Journal operations recorded in the append-only log
+const (
+	opPush = "push"
+	opAck  = "ack"
+	opPop  = "pop" // Written by earlier versions when an item was popped, replayed like an ack
+)
+
+// DefaultCompactAfter is how many acks the log collects before it is
+// rewritten with only the pending items
+const DefaultCompactAfter = 10000
+
+// journalEntry is a single line of the append-only queue log
+type journalEntry struct {
+	Op       string    `json:"op"`
+	URL      string    `json:"url"`
+	Priority int       `json:"priority,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Depth    int       `json:"depth,omitempty"`
+	Score    float64   `json:"score,omitempty"`
+	QueuedAt time.Time `json:"queued_at,omitempty"`
+}
+
+// PersistentQueue wraps PriorityQueue with an append-only log on disk so an
+// interrupted crawl can resume with the URLs that were still queued. Popping
+// an item doesn't remove it from the log, Ack does once the item has been
+// processed, so URLs that were being fetched during a crash are replayed too.
+type PersistentQueue struct {
+	*PriorityQueue
+
+	path         string
+	compactAfter int
+	mu           sync.Mutex
+	file         *os.File
+	writer       *bufio.Writer
+	live         map[string][]URLItem // Pushed but not acked, by URL in push order
+	acked        int                  // Acks written since the log was last compacted
+	compactions  int64
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewPersistentQueue opens a disk-backed queue at cfg.Path. When resume is true
+// the existing log is replayed and the remaining URLs are pushed back onto the
+// queue, otherwise any previous log is discarded. A positive sync interval
+// flushes the log to disk in the background at that interval. The log is
+// compacted after compact_after acks, DefaultCompactAfter if it is 0. Items are
+// held in memory like in NewPriorityQueue. The spill policy isn't supported,
+// since the log keeps every pending item in memory anyway.
+func NewPersistentQueue(cfg config.QueueConfig, resume bool) (*PersistentQueue, error) {
+	if cfg.Overflow.Policy == OverflowSpill {
+		return nil, fmt.Errorf("the %s overflow policy can't be used with a persistent queue", OverflowSpill)
+	}
+	path, compactAfter := cfg.Path, cfg.CompactAfter
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	memory, err := NewPriorityQueue(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []URLItem
+	if resume {
+		items, err := replayJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		pending = items
+	}
+
+	// Compact into a temporary log so a crash here never loses the old one
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue log: %w", err)
+	}
+
+	if compactAfter <= 0 {
+		compactAfter = DefaultCompactAfter
+	}
+	pq := &PersistentQueue{
+		PriorityQueue: memory,
+		path:          path,
+		compactAfter:  compactAfter,
+		file:          file,
+		writer:        bufio.NewWriterSize(file, 64*1024),
+		live:          make(map[string][]URLItem),
+		done:          make(chan struct{}),
+	}
+
+	// Nothing pops yet, so the replay can't wait for room
+	for _, item := range pending {
+		pq.pushItem(item, false)
+	}
+	if err := pq.Sync(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to replace queue log: %w", err)
+	}
+
+	if cfg.SyncInterval > 0 {
+		go pq.syncLoop(cfg.SyncInterval)
+	}
+
+	return pq, nil
+}
+
+// syncLoop periodically flushes the log until the queue is closed
+func (pq *PersistentQueue) syncLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pq.Sync()
+		case <-pq.done:
+			return
+		}
+	}
+}
+
+// replayJournal reads the log and returns the items that were pushed but never acked
+func replayJournal(path string) ([]URLItem, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open queue log: %w", err)
+	}
+	defer file.Close()
+
+	var items []URLItem
+	acked := make(map[string]int)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn write at the end of the log is expected after a crash
+			continue
+		}
+		switch entry.Op {
+		case opPush:
+			items = append(items, URLItem{
+				URL:      entry.URL,
+				Priority: entry.Priority,
+				Host:     entry.Host,
+				Depth:    entry.Depth,
+				Score:    entry.Score,
+				QueuedAt: entry.QueuedAt,
+			})
+		case opAck, opPop:
+			acked[entry.URL]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queue log: %w", err)
+	}
+
+	// Drop acked items in FIFO order so duplicates are handled correctly
+	remaining := items[:0]
+	for _, item := range items {
+		if acked[item.URL] > 0 {
+			acked[item.URL]--
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+
+	return remaining, nil
+}
+
+// Push adds a URL to the queue and records it in the log
+func (pq *PersistentQueue) Push(url string) {
+	pq.PushWithPriority(url, PriorityNormal, "", 0)
+}
+
+// PushWithPriority adds a URL with specific priority and metadata and records it in the log
+func (pq *PersistentQueue) PushWithPriority(url string, priority int, host string, depth int) {
+	pq.pushItem(URLItem{
+		URL:      url,
+		Priority: priority,
+		Host:     host,
+		Depth:    depth,
+		QueuedAt: time.Now(),
+	}, true)
+}
+
+// PushItem adds an item with all its fields and records it in the log
+func (pq *PersistentQueue) PushItem(item URLItem) {
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
+	}
+	pq.pushItem(item, true)
+}
+
+// PushNoWait adds an item like PushItem without waiting for room, and
+// records it in the log if it was kept
+func (pq *PersistentQueue) PushNoWait(item URLItem) {
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
+	}
+	pq.pushItem(item, false)
+}
+
+// pushItem enqueues an item, journaling it only if the in-memory queue accepted it
+func (pq *PersistentQueue) pushItem(item URLItem, wait bool) {
+	if !pq.PriorityQueue.pushItem(item, wait) {
+		return
+	}
+	pq.journal(item)
+}
+
+// Defer queues a popped item again until notBefore and records it in the
+// log like a push, since the worker acks the popped item afterwards. The
+// NotBefore time is not logged, so a resumed crawl fetches the item at once.
+func (pq *PersistentQueue) Defer(item URLItem, notBefore time.Time) bool {
+	if !pq.PriorityQueue.Defer(item, notBefore) {
+		return false
+	}
+	pq.journal(item)
+	return true
+}
+
+// journal records a queued item in the log
+func (pq *PersistentQueue) journal(item URLItem) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.writer == nil {
+		return
+	}
+	pq.live[item.URL] = append(pq.live[item.URL], item)
+	pq.write(pushEntry(item))
+}
+
+// pushEntry returns the journal entry recording item as queued
+func pushEntry(item URLItem) journalEntry {
+	return journalEntry{
+		Op:       opPush,
+		URL:      item.URL,
+		Priority: item.Priority,
+		Host:     item.Host,
+		Depth:    item.Depth,
+		Score:    item.Score,
+		QueuedAt: item.QueuedAt,
+	}
+}
+
+// Ack records that a popped item has been processed, so it is not replayed
+// on resume. The log is compacted once enough acks have been written.
+func (pq *PersistentQueue) Ack(item URLItem) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pending := pq.live[item.URL]
+	if pq.writer == nil || len(pending) == 0 {
+		return
+	}
+	if len(pending) == 1 {
+		delete(pq.live, item.URL)
+	} else {
+		pq.live[item.URL] = pending[1:]
+	}
+	pq.write(journalEntry{Op: opAck, URL: item.URL})
+
+	pq.acked++
+	if pq.acked >= pq.compactAfter {
+		if err := pq.compact(); err != nil {
+			log.Warn("Failed to compact queue log: %v", err)
+		}
+	}
+}
+
+// compact rewrites the log with the pending items only. pq.mu must be held.
+func (pq *PersistentQueue) compact() error {
+	tmpPath := pq.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open queue log: %w", err)
+	}
+	writer := bufio.NewWriterSize(file, 64*1024)
+	for _, items := range pq.live {
+		for _, item := range items {
+			data, err := json.Marshal(pushEntry(item))
+			if err != nil {
+				continue
+			}
+			writer.Write(data)
+			writer.WriteByte('\n')
+		}
+	}
+	err = writer.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write queue log: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, pq.path); err != nil {
+		// Keep appending to the old log, it still holds every pending item
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace queue log: %w", err)
+	}
+	// Entries still buffered for the old log are covered by the new one
+	pq.file.Close()
+	pq.file = file
+	pq.writer = writer
+	pq.acked = 0
+	pq.compactions++
+	return nil
+}
+
+// write appends a journal entry to the buffered log writer. pq.mu must be held.
+func (pq *PersistentQueue) write(entry journalEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	pq.writer.Write(data)
+	pq.writer.WriteByte('\n')
+}
+
+// GetStats returns queue statistics including the state of the log
+func (pq *PersistentQueue) GetStats() map[string]int64 {
+	stats := pq.PriorityQueue.GetStats()
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	var pending int64
+	for _, items := range pq.live {
+		pending += int64(len(items))
+	}
+	stats["journalPending"] = pending
+	stats["journalCompactions"] = pq.compactions
+	return stats
+}
+
+// Sync flushes buffered log entries and fsyncs the log file
+func (pq *PersistentQueue) Sync() error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.writer == nil {
+		return nil
+	}
+	if err := pq.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush queue log: %w", err)
+	}
+	if err := pq.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync queue log: %w", err)
+	}
+	return nil
+}
+
+// Path returns the location of the queue log on disk
+func (pq *PersistentQueue) Path() string {
+	return pq.path
+}
+
+// Close flushes the log and closes the queue. Closing twice is a no-op.
+func (pq *PersistentQueue) Close() {
+	pq.closeOnce.Do(func() {
+		close(pq.done)
+		pq.Sync()
+
+		pq.mu.Lock()
+		if pq.file != nil {
+			pq.file.Close()
+			pq.file = nil
+			pq.writer = nil
+		}
+		pq.mu.Unlock()
+
+		pq.PriorityQueue.Close()
+	})
+}
@@ -0,0 +1,158 @@
+package queue
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// logLines returns the number of entries in the queue log at path
+func logLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
+
+// popAll pops every queued URL, sorted
+func popAll(q URLQueue) []string {
+	var urls []string
+	for _, item := range q.PopBatch(1000) {
+		urls = append(urls, item.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestPersistentQueueReplaysUnackedItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+	pq, err := NewPersistentQueue(config.QueueConfig{Path: path}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq.PushWithPriority("https://a.com/", PriorityHigh, "a.com", 0)
+	pq.PushWithPriority("https://b.com/", PriorityNormal, "b.com", 1)
+	pq.PushWithPriority("https://c.com/", PriorityLow, "c.com", 2)
+
+	// a.com is done, b.com was being fetched when the crawler died
+	a, _ := pq.Pop()
+	pq.Ack(a)
+	pq.Pop()
+	pq.Close()
+
+	resumed, err := NewPersistentQueue(config.QueueConfig{Path: path}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	got := popAll(resumed)
+	want := []string{"https://b.com/", "https://c.com/"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestPersistentQueueKeepsDeferredItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+	pq, err := NewPersistentQueue(config.QueueConfig{Path: path}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq.PushWithPriority("https://a.com/", PriorityNormal, "a.com", 0)
+
+	// The worker acks the item after handing it back to wait for its host
+	a, _ := pq.Pop()
+	pq.Defer(a, time.Now().Add(time.Hour))
+	pq.Ack(a)
+	pq.Close()
+
+	resumed, err := NewPersistentQueue(config.QueueConfig{Path: path}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	if got := popAll(resumed); len(got) != 1 || got[0] != "https://a.com/" {
+		t.Fatalf("replayed %v, want the deferred item", got)
+	}
+}
+
+func TestPersistentQueueCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+	pq, err := NewPersistentQueue(config.QueueConfig{Path: path, CompactAfter: 5}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		pq.Push("https://example.com/" + string(rune('a'+i)))
+	}
+	for i := 0; i < 5; i++ {
+		item, _ := pq.Pop()
+		pq.Ack(item)
+	}
+	if err := pq.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the five pending pushes are left after compacting
+	if n := logLines(t, path); n != 5 {
+		t.Fatalf("log has %d entries after compaction, want 5", n)
+	}
+	if stats := pq.GetStats(); stats["journalCompactions"] != 1 || stats["journalPending"] != 5 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+	pq.Close()
+
+	resumed, err := NewPersistentQueue(config.QueueConfig{Path: path, CompactAfter: 5}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if n := resumed.Size(); n != 5 {
+		t.Fatalf("resumed %d items, want 5", n)
+	}
+}
+
+func TestPersistentQueueReplaysLegacyPops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frontier.log")
+	log := `{"op":"push","url":"https://a.com/"}
+{"op":"push","url":"https://b.com/"}
+{"op":"pop","url":"https://a.com/"}
+{"op":"push","url":"https://c.c`
+	if err := os.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pq, err := NewPersistentQueue(config.QueueConfig{Path: path}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pq.Close()
+	if got := popAll(pq); len(got) != 1 || got[0] != "https://b.com/" {
+		t.Fatalf("replayed %v, want [https://b.com/]", got)
+	}
+}
+
+func TestPersistentQueueCloseTwice(t *testing.T) {
+	pq, err := NewPersistentQueue(config.QueueConfig{Path: filepath.Join(t.TempDir(), "frontier.log")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pq.Close()
+	pq.Close()
+}
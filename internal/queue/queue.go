@@ -1,8 +1,15 @@
 package queue
 
 import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"web-crawler/internal/config"
 )
 
 // Priority levels for URL crawling
@@ -14,20 +21,258 @@
 
 // URLItem represents a URL with priority and metadata
 type URLItem struct {
-	URL      string
-	Priority int
-	Host     string
-	Depth    int
-	QueuedAt time.Time // For performance tracking
+	URL      string    `json:"url"`
+	Priority int       `json:"priority"`
+	Host     string    `json:"host"`
+	Depth    int       `json:"depth"`
+	Score    float64   `json:"score,omitempty"` // Relevance of the linking page or the score expression's, for best-first order
+	QueuedAt time.Time `json:"queued_at"`       // For performance tracking
+	// Earliest time the item may be popped, e.g. when its host's rate limit
+	// allows the next request. Zero means right away.
+	NotBefore time.Time `json:"not_before,omitempty"`
+}
+
+// Pop orders of a PriorityQueue
+const (
+	OrderPriority     = "priority"      // By priority, then queue time
+	OrderBreadthFirst = "breadth_first" // By depth, then priority
+	OrderBestFirst    = "best_first"    // By score, highest first, then priority
+)
+
+// URLQueue is the frontier interface shared by all queue implementations
+type URLQueue interface {
+	Push(url string)
+	PushWithPriority(url string, priority int, host string, depth int)
+	Pop() (URLItem, bool)
+	PopBlocking(ctx context.Context) (URLItem, bool)
+	PopBatch(maxItems int) []URLItem
+	Size() int
+	GetStats() map[string]int64
+	IsFull() bool
+	Close()
+}
+
+// Drainer is implemented by queues that can hand out every queued item at once,
+// regardless of politeness delays
+type Drainer interface {
+	DrainAll() []URLItem
+}
+
+// Delayer is implemented by queues that can hold an item back until a given
+// time, so workers don't sit out politeness delays. Defer queues a popped
+// item again to be popped no earlier than notBefore; it returns false if the
+// queue is closed.
+type Delayer interface {
+	Defer(item URLItem, notBefore time.Time) bool
+}
+
+// Orderer is implemented by queues whose pop order can be changed to one
+// of the Order constants
+type Orderer interface {
+	SetOrder(order string) error
+}
+
+// ItemPusher is implemented by queues that keep item fields
+// PushWithPriority has no parameter for, such as the score
+type ItemPusher interface {
+	PushItem(item URLItem)
+}
+
+// PushItem queues item with all its fields if q keeps them, or with its
+// priority, host and depth otherwise
+func PushItem(q URLQueue, item URLItem) {
+	if p, ok := q.(ItemPusher); ok {
+		p.PushItem(item)
+		return
+	}
+	q.PushWithPriority(item.URL, item.Priority, item.Host, item.Depth)
+}
+
+// NoWaitPusher is implemented by queues whose pushes may wait for room.
+// PushNoWait queues an item without waiting, dropping it if there is none.
+type NoWaitPusher interface {
+	PushNoWait(item URLItem)
+}
+
+// PushNoWait queues item like PushItem, but never waits for room in q. The
+// queue's consumers push this way, since nobody else would make room.
+func PushNoWait(q URLQueue, item URLItem) {
+	if p, ok := q.(NoWaitPusher); ok {
+		p.PushNoWait(item)
+		return
+	}
+	PushItem(q, item)
+}
+
+// Repush queues a popped item again, keeping its score. Its queue and
+// NotBefore times start over. Like PushNoWait, it never waits for room.
+func Repush(q URLQueue, item URLItem) {
+	item.QueuedAt = time.Time{}
+	item.NotBefore = time.Time{}
+	PushNoWait(q, item)
+}
+
+// Reprioritizer is implemented by queues whose items can change priority
+// while queued. priority returns the new priority of an item; Reprioritize
+// returns how many items changed priority.
+type Reprioritizer interface {
+	Reprioritize(priority func(URLItem) int) int
+}
+
+// Updater is implemented by queues that can change the priority of a single
+// queued URL. Update returns false if the URL is not queued.
+type Updater interface {
+	Update(url string, priority func(URLItem) int) bool
+}
+
+// Sharer is implemented by queues shared with other crawler instances.
+// Pending counts the items queued or being processed by any instance, since
+// each of those may still queue more.
+type Sharer interface {
+	Pending() int
+}
+
+// Pending returns the number of items that keep the crawl from being done:
+// all items of a shared queue, or the size of a local one
+func Pending(q URLQueue) int {
+	if s, ok := q.(Sharer); ok {
+		return s.Pending()
+	}
+	return q.Size()
+}
+
+// Acker is implemented by queues that must be told when a popped item has
+// been processed, e.g. to drop it from a journal
+type Acker interface {
+	Ack(item URLItem)
+}
+
+// Ack tells q that item has been processed, if q keeps track of that
+func Ack(q URLQueue, item URLItem) {
+	if a, ok := q.(Acker); ok {
+		a.Ack(item)
+	}
+}
+
+// Drain removes and returns all items left in q, e.g. for checkpointing
+func Drain(q URLQueue) []URLItem {
+	if d, ok := q.(Drainer); ok {
+		return d.DrainAll()
+	}
+
+	var items []URLItem
+	for {
+		batch := q.PopBatch(1000)
+		if len(batch) == 0 {
+			return items
+		}
+		items = append(items, batch...)
+	}
 }
 
-// URLQueue is a high-performance priority queue using channels with enhanced buffering
-type URLQueue struct {
-	highPriority   chan URLItem
-	normalPriority chan URLItem
-	lowPriority    chan URLItem
-	size           int64
-	closed         int64
+// DefaultCapacity is how many items a PriorityQueue holds in memory unless
+// configured otherwise
+const DefaultCapacity = 32000
+
+// entry is a queued item and its place in the heap
+type entry struct {
+	item  URLItem
+	rank  float64 // Depth or negated score by the pop order, compared before key
+	key   float64 // Priority less the levels gained by waiting, lowest first
+	seq   uint64  // Push order, first in first out among equal keys
+	index int
+	held  bool // In the delayed heap until its NotBefore time
+}
+
+// entryHeap orders entries by key for container/heap
+type entryHeap []*entry
+
+func (h entryHeap) Len() int { return len(h) }
+
+func (h entryHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	if h[i].key != h[j].key {
+		return h[i].key < h[j].key
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// delayHeap orders held entries by NotBefore time, earliest first
+type delayHeap struct{ entryHeap }
+
+func (h delayHeap) Less(i, j int) bool {
+	a, b := h.entryHeap[i], h.entryHeap[j]
+	if !a.item.NotBefore.Equal(b.item.NotBefore) {
+		return a.item.NotBefore.Before(b.item.NotBefore)
+	}
+	return a.seq < b.seq
+}
+
+// next returns the NotBefore time of the entry due first. h must not be empty.
+func (h delayHeap) next() time.Time {
+	return h.entryHeap[0].item.NotBefore
+}
+
+// band returns the stats band of a priority: 0 for high, 1 for normal and 2
+// for low, with priorities beyond high or low counted in their band
+func band(priority int) int {
+	switch {
+	case priority <= PriorityHigh:
+		return 0
+	case priority >= PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// PriorityQueue is a heap of URLs ordered by numeric priority, lowest first,
+// and by push order within a priority. Breadth-first and best-first orders
+// sort by depth or score before that. With aging, an item gains one
+// priority level for every aging interval it waits, so old low priority items
+// eventually surface. Items can change priority while queued, and items with
+// a NotBefore time are held back until then.
+type PriorityQueue struct {
+	mu       sync.Mutex
+	heap     entryHeap
+	delayed  delayHeap         // Items held back until their NotBefore time
+	byURL    map[string]*entry // Latest entry of each queued URL
+	seq      uint64
+	maxItems int
+	aging    time.Duration
+	epoch    time.Time
+	order    string
+	buffers  [3]int64 // Queued items by band
+
+	size   int64 // Including spilled items
+	closed int64
+	notify chan struct{} // Signaled on pushes, for PopBlocking
+
+	overflowCfg config.OverflowConfig
+	spill       *spillFile    // Nil unless the overflow policy is spill
+	room        chan struct{} // Signaled on pops, for pushes blocked on a full queue
+	done        chan struct{}
 
 	// Performance counters
 	totalQueued   int64
@@ -35,150 +280,355 @@ type URLQueue struct {
 	highCount     int64
 	normalCount   int64
 	lowCount      int64
+	updated       int64 // Priority changes of queued items
+	deferred      int64 // Popped items queued again to wait for their NotBefore time
+	dropped       int64 // Items discarded because the queue was full
+	blocked       int64 // Pushes that waited for room
+	spilled       int64 // Items written to disk because the queue was full
 }
 
-// NewURLQueue creates a new high-performance URL queue with enhanced buffering
-func NewURLQueue() *URLQueue {
-	return &URLQueue{
-		// Significantly increased buffer sizes for better performance
-		highPriority:   make(chan URLItem, 2000),  // Increased from 1000
-		normalPriority: make(chan URLItem, 20000), // Increased from 10000
-		lowPriority:    make(chan URLItem, 10000), // Increased from 5000
+// NewURLQueue creates a priority queue of DefaultCapacity without aging.
+// URLs pushed while it is full are dropped.
+func NewURLQueue() *PriorityQueue {
+	q, _ := NewPriorityQueue(config.QueueConfig{})
+	return q
+}
+
+// NewPriorityQueue creates a priority queue with the capacity, aging and
+// overflow policy of cfg. A capacity of 0 is DefaultCapacity and an empty
+// overflow policy drops.
+func NewPriorityQueue(cfg config.QueueConfig) (*PriorityQueue, error) {
+	q := &PriorityQueue{
+		byURL:       make(map[string]*entry),
+		maxItems:    cfg.Capacity,
+		aging:       cfg.Aging,
+		epoch:       time.Now(),
+		notify:      make(chan struct{}, 1),
+		overflowCfg: cfg.Overflow,
+		room:        make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	if q.maxItems <= 0 {
+		q.maxItems = DefaultCapacity
 	}
+	switch cfg.Overflow.Policy {
+	case "", OverflowDrop, OverflowBlock:
+	case OverflowSpill:
+		spill, err := openSpill(cfg.Overflow.Path)
+		if err != nil {
+			return nil, err
+		}
+		q.spill = spill
+	default:
+		return nil, fmt.Errorf("unknown queue overflow policy: %s", cfg.Overflow.Policy)
+	}
+	return q, nil
 }
 
-// Push adds a URL to the appropriate priority queue
-func (q *URLQueue) Push(url string) {
+// Push adds a URL with normal priority
+func (q *PriorityQueue) Push(url string) {
 	q.PushWithPriority(url, PriorityNormal, "", 0)
 }
 
-// PushWithPriority adds a URL with specific priority and metadata (enhanced)
-func (q *URLQueue) PushWithPriority(url string, priority int, host string, depth int) {
-	if atomic.LoadInt64(&q.closed) == 1 {
-		return
-	}
-
-	item := URLItem{
+// PushWithPriority adds a URL with any numeric priority, lower first
+func (q *PriorityQueue) PushWithPriority(url string, priority int, host string, depth int) {
+	q.pushItem(URLItem{
 		URL:      url,
 		Priority: priority,
 		Host:     host,
 		Depth:    depth,
 		QueuedAt: time.Now(),
+	}, true)
+}
+
+// PushItem adds an item with all its fields. A zero queue time is now.
+func (q *PriorityQueue) PushItem(item URLItem) {
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
 	}
+	q.pushItem(item, true)
+}
 
-	// Enhanced non-blocking push with improved fallback strategy
-	switch priority {
-	case PriorityHigh:
-		select {
-		case q.highPriority <- item:
-			atomic.AddInt64(&q.size, 1)
-			atomic.AddInt64(&q.totalQueued, 1)
-			atomic.AddInt64(&q.highCount, 1)
-		default:
-			// High priority queue full, try urgent fallback to normal
-			select {
-			case q.normalPriority <- item:
-				atomic.AddInt64(&q.size, 1)
-				atomic.AddInt64(&q.totalQueued, 1)
-				atomic.AddInt64(&q.normalCount, 1)
-			default:
-				// Both full, drop to prevent blocking (rare case)
-			}
+// PushNoWait adds an item like PushItem, but drops it rather than wait for
+// room under the block policy
+func (q *PriorityQueue) PushNoWait(item URLItem) {
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
+	}
+	q.pushItem(item, false)
+}
+
+// pushItem queues an item, or hands it to the overflow policy when the queue
+// is full, and reports whether it was kept
+func (q *PriorityQueue) pushItem(item URLItem, wait bool) bool {
+	if atomic.LoadInt64(&q.closed) == 1 {
+		return false
+	}
+	if !q.place(item) && !q.overflow(item, wait) {
+		return false
+	}
+	atomic.AddInt64(&q.totalQueued, 1)
+	switch band(item.Priority) {
+	case 0:
+		atomic.AddInt64(&q.highCount, 1)
+	case 1:
+		atomic.AddInt64(&q.normalCount, 1)
+	default:
+		atomic.AddInt64(&q.lowCount, 1)
+	}
+	return true
+}
+
+// place puts an item on the heap unless it is at capacity
+func (q *PriorityQueue) place(item URLItem) bool {
+	q.mu.Lock()
+	if len(q.heap)+q.delayed.Len() >= q.maxItems {
+		q.mu.Unlock()
+		return false
+	}
+	q.insert(item)
+	q.mu.Unlock()
+
+	atomic.AddInt64(&q.size, 1)
+	q.wake()
+	return true
+}
+
+// insert puts an item on the heap, or on the delayed heap if its NotBefore
+// time is yet to come. q.mu must be held.
+func (q *PriorityQueue) insert(item URLItem) {
+	q.seq++
+	e := &entry{item: item, rank: q.rank(item), key: q.key(item), seq: q.seq}
+	if item.NotBefore.After(time.Now()) {
+		e.held = true
+		heap.Push(&q.delayed, e)
+	} else {
+		heap.Push(&q.heap, e)
+	}
+	q.byURL[item.URL] = e
+	q.buffers[band(item.Priority)]++
+}
+
+// Defer queues a popped item again to be popped no earlier than notBefore,
+// keeping its priority and queue time. The item was taken out of the queue
+// moments ago, so it is put back even if the queue has filled up since.
+func (q *PriorityQueue) Defer(item URLItem, notBefore time.Time) bool {
+	if atomic.LoadInt64(&q.closed) == 1 {
+		return false
+	}
+	item.NotBefore = notBefore
+	q.mu.Lock()
+	q.insert(item)
+	q.mu.Unlock()
+
+	atomic.AddInt64(&q.size, 1)
+	atomic.AddInt64(&q.deferred, 1)
+	q.wake()
+	return true
+}
+
+// release moves the held items whose NotBefore time has come to the heap.
+// q.mu must be held.
+func (q *PriorityQueue) release(now time.Time) {
+	for q.delayed.Len() > 0 && !q.delayed.next().After(now) {
+		q.unhold()
+	}
+}
+
+// unhold moves the held item due first to the heap. q.mu must be held.
+func (q *PriorityQueue) unhold() {
+	e := heap.Pop(&q.delayed).(*entry)
+	e.held = false
+	heap.Push(&q.heap, e)
+}
+
+// nextDue returns how long until the first held item is due, and false if
+// no item is held
+func (q *PriorityQueue) nextDue() (time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.delayed.Len() == 0 {
+		return 0, false
+	}
+	return time.Until(q.delayed.next()), true
+}
+
+// key returns the heap key of an item. Waiting lowers the priority of all
+// items at the same rate, so the order only depends on when they were queued.
+func (q *PriorityQueue) key(item URLItem) float64 {
+	if q.aging <= 0 {
+		return float64(item.Priority)
+	}
+	return float64(item.Priority) + float64(item.QueuedAt.Sub(q.epoch))/float64(q.aging)
+}
+
+// rank returns the part of the heap order that the pop order puts before
+// the priority
+func (q *PriorityQueue) rank(item URLItem) float64 {
+	switch q.order {
+	case OrderBreadthFirst:
+		return float64(item.Depth)
+	case OrderBestFirst:
+		// Seeds have no score but come first
+		if item.Depth == 0 {
+			return math.Inf(-1)
 		}
-	case PriorityLow:
-		select {
-		case q.lowPriority <- item:
-			atomic.AddInt64(&q.size, 1)
-			atomic.AddInt64(&q.totalQueued, 1)
-			atomic.AddInt64(&q.lowCount, 1)
-		default:
-			// Low priority queue full, just drop (acceptable for low priority)
+		return -item.Score
+	}
+	return 0
+}
+
+// SetOrder changes the pop order of the queued items and the ones to come
+func (q *PriorityQueue) SetOrder(order string) error {
+	switch order {
+	case "", OrderPriority, OrderBreadthFirst, OrderBestFirst:
+	default:
+		return fmt.Errorf("unknown frontier order: %s", order)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.order = order
+	for _, entries := range []entryHeap{q.heap, q.delayed.entryHeap} {
+		for _, e := range entries {
+			e.rank = q.rank(e.item)
 		}
-	default: // PriorityNormal
-		select {
-		case q.normalPriority <- item:
-			atomic.AddInt64(&q.size, 1)
-			atomic.AddInt64(&q.totalQueued, 1)
-			atomic.AddInt64(&q.normalCount, 1)
-		default:
-			// Normal queue full, try low priority as fallback
-			select {
-			case q.lowPriority <- item:
-				atomic.AddInt64(&q.size, 1)
-				atomic.AddInt64(&q.totalQueued, 1)
-				atomic.AddInt64(&q.lowCount, 1)
-			default:
-				// Both full, drop to prevent blocking
-			}
+	}
+	heap.Init(&q.heap)
+	return nil
+}
+
+// setPriority changes the priority of a queued entry and reports whether it
+// changed. The heap must be fixed afterwards. q.mu must be held.
+func (q *PriorityQueue) setPriority(e *entry, priority int) bool {
+	if priority == e.item.Priority {
+		return false
+	}
+	q.buffers[band(e.item.Priority)]--
+	q.buffers[band(priority)]++
+	e.item.Priority = priority
+	e.key = q.key(e.item)
+	return true
+}
+
+// Update changes the priority of a queued URL in place. priority returns the
+// new priority of its item. It returns false if the URL is not queued.
+func (q *PriorityQueue) Update(url string, priority func(URLItem) int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.byURL[url]
+	if !ok {
+		return false
+	}
+	if q.setPriority(e, priority(e.item)) {
+		// Held items are ordered by time, their new key applies once released
+		if !e.held {
+			heap.Fix(&q.heap, e.index)
 		}
+		atomic.AddInt64(&q.updated, 1)
 	}
+	return true
 }
 
-// Pop removes and returns the highest priority URL available (enhanced)
-// Returns empty URLItem and false if no URLs are available
-func (q *URLQueue) Pop() (URLItem, bool) {
-	// Try high priority first (with higher probability)
-	select {
-	case item := <-q.highPriority:
-		atomic.AddInt64(&q.size, -1)
-		atomic.AddInt64(&q.totalDequeued, 1)
-		return item, true
-	default:
+// Reprioritize changes the priority of every queued item to the one returned
+// for it. Items that keep their priority keep their place among equals.
+func (q *PriorityQueue) Reprioritize(priority func(URLItem) int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	moved := 0
+	for _, entries := range []entryHeap{q.heap, q.delayed.entryHeap} {
+		for _, e := range entries {
+			if q.setPriority(e, priority(e.item)) {
+				moved++
+			}
+		}
+	}
+	if moved > 0 {
+		heap.Init(&q.heap)
+		atomic.AddInt64(&q.updated, int64(moved))
 	}
+	return moved
+}
 
-	// Try normal priority with higher frequency than low
+// wake signals a blocked PopBlocking call without blocking the caller
+func (q *PriorityQueue) wake() {
 	select {
-	case item := <-q.normalPriority:
-		atomic.AddInt64(&q.size, -1)
-		atomic.AddInt64(&q.totalDequeued, 1)
-		return item, true
+	case q.notify <- struct{}{}:
 	default:
-		// Only check low priority if normal is empty
-		select {
-		case item := <-q.lowPriority:
-			atomic.AddInt64(&q.size, -1)
-			atomic.AddInt64(&q.totalDequeued, 1)
-			return item, true
-		default:
-		}
 	}
+}
 
-	return URLItem{}, false
+// taken counts an item popped off the heap and wakes a push waiting for room
+func (q *PriorityQueue) taken() {
+	atomic.AddInt64(&q.size, -1)
+	atomic.AddInt64(&q.totalDequeued, 1)
+	q.signalRoom()
 }
 
-// PopBlocking waits for a URL to become available with enhanced priority ordering
-func (q *URLQueue) PopBlocking() (URLItem, bool) {
-	if atomic.LoadInt64(&q.closed) == 1 {
+// Pop removes and returns the item with the lowest priority after aging,
+// skipping items whose NotBefore time is yet to come. Returns empty URLItem
+// and false if no URLs are available.
+func (q *PriorityQueue) Pop() (URLItem, bool) {
+	q.refill()
+
+	q.mu.Lock()
+	q.release(time.Now())
+	if len(q.heap) == 0 {
+		q.mu.Unlock()
 		return URLItem{}, false
 	}
+	e := heap.Pop(&q.heap).(*entry)
+	if q.byURL[e.item.URL] == e {
+		delete(q.byURL, e.item.URL)
+	}
+	q.buffers[band(e.item.Priority)]--
+	more := len(q.heap) > 0
+	q.mu.Unlock()
 
-	// Enhanced priority selection with weighted approach
-	select {
-	case item := <-q.highPriority:
-		atomic.AddInt64(&q.size, -1)
-		atomic.AddInt64(&q.totalDequeued, 1)
-		return item, true
-	default:
-		// Weighted selection between normal and low priority
-		select {
-		case item := <-q.highPriority:
-			atomic.AddInt64(&q.size, -1)
-			atomic.AddInt64(&q.totalDequeued, 1)
-			return item, true
-		case item := <-q.normalPriority:
-			atomic.AddInt64(&q.size, -1)
-			atomic.AddInt64(&q.totalDequeued, 1)
-			return item, true
-		case item := <-q.lowPriority:
-			atomic.AddInt64(&q.size, -1)
-			atomic.AddInt64(&q.totalDequeued, 1)
+	q.taken()
+	if more {
+		// Pass the wakeup on to another waiting worker
+		q.wake()
+	}
+	return e.item, true
+}
+
+// PopBlocking waits for a URL to become available or a held one to become
+// due. Returns false once the queue has been closed or ctx is done.
+func (q *PriorityQueue) PopBlocking(ctx context.Context) (URLItem, bool) {
+	for {
+		if atomic.LoadInt64(&q.closed) == 1 {
+			return URLItem{}, false
+		}
+		if item, ok := q.Pop(); ok {
 			return item, true
 		}
+
+		var due <-chan time.Time
+		var timer *time.Timer
+		if wait, ok := q.nextDue(); ok {
+			timer = time.NewTimer(wait)
+			due = timer.C
+		}
+		select {
+		case <-q.notify:
+		case <-due:
+		case <-q.done:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return URLItem{}, false
+		}
 	}
 }
 
-// PopBatch returns multiple items at once for batch processing (new method)
-func (q *URLQueue) PopBatch(maxItems int) []URLItem {
+// PopBatch returns multiple items at once for batch processing
+func (q *PriorityQueue) PopBatch(maxItems int) []URLItem {
 	items := make([]URLItem, 0, maxItems)
 
 	for i := 0; i < maxItems; i++ {
@@ -192,39 +642,92 @@ func (q *URLQueue) PopBatch(maxItems int) []URLItem {
 	return items
 }
 
-// Size returns the current approximate size of all queues
-func (q *URLQueue) Size() int {
+// DrainAll removes and returns every queued item, including held and
+// spilled ones, regardless of NotBefore times
+func (q *PriorityQueue) DrainAll() []URLItem {
+	q.mu.Lock()
+	for q.delayed.Len() > 0 {
+		q.unhold()
+	}
+	q.mu.Unlock()
+
+	var items []URLItem
+	for {
+		batch := q.PopBatch(1000)
+		if len(batch) == 0 {
+			return items
+		}
+		items = append(items, batch...)
+	}
+}
+
+// Size returns the number of queued items, including spilled ones
+func (q *PriorityQueue) Size() int {
 	return int(atomic.LoadInt64(&q.size))
 }
 
-// GetStats returns detailed queue statistics for monitoring
-func (q *URLQueue) GetStats() map[string]int64 {
+// buffered returns the number of items in memory, held ones included
+func (q *PriorityQueue) buffered() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.heap) + q.delayed.Len()
+}
+
+// capacity returns the number of items held in memory at most
+func (q *PriorityQueue) capacity() int {
+	return q.maxItems
+}
+
+// GetStats returns detailed queue statistics for monitoring. The buffers
+// count the queued items by priority band.
+func (q *PriorityQueue) GetStats() map[string]int64 {
+	q.mu.Lock()
+	buffers := q.buffers
+	delayed := q.delayed.Len()
+	q.mu.Unlock()
+
 	return map[string]int64{
 		"size":          atomic.LoadInt64(&q.size),
+		"capacity":      int64(q.maxItems),
 		"totalQueued":   atomic.LoadInt64(&q.totalQueued),
 		"totalDequeued": atomic.LoadInt64(&q.totalDequeued),
 		"highCount":     atomic.LoadInt64(&q.highCount),
 		"normalCount":   atomic.LoadInt64(&q.normalCount),
 		"lowCount":      atomic.LoadInt64(&q.lowCount),
-		"highBuffer":    int64(len(q.highPriority)),
-		"normalBuffer":  int64(len(q.normalPriority)),
-		"lowBuffer":     int64(len(q.lowPriority)),
+		"highBuffer":    buffers[0],
+		"normalBuffer":  buffers[1],
+		"lowBuffer":     buffers[2],
+		"updated":       atomic.LoadInt64(&q.updated),
+		"delayed":       int64(delayed),
+		"deferred":      atomic.LoadInt64(&q.deferred),
+		"dropped":       atomic.LoadInt64(&q.dropped),
+		"blocked":       atomic.LoadInt64(&q.blocked),
+		"spilled":       atomic.LoadInt64(&q.spilled),
+		"spillPending":  q.spillPending(),
 	}
 }
 
-// IsFull checks if any queue is approaching capacity
-func (q *URLQueue) IsFull() bool {
-	highFull := len(q.highPriority) > cap(q.highPriority)*9/10 // 90% full
-	normalFull := len(q.normalPriority) > cap(q.normalPriority)*9/10
-	lowFull := len(q.lowPriority) > cap(q.lowPriority)*9/10
+// spillPending returns the number of items waiting on disk
+func (q *PriorityQueue) spillPending() int64 {
+	if q.spill == nil {
+		return 0
+	}
+	return q.spill.len()
+}
 
-	return highFull || normalFull || lowFull
+// IsFull checks if the queue is approaching capacity
+func (q *PriorityQueue) IsFull() bool {
+	return q.buffered() > q.maxItems*9/10 // 90% full
 }
 
-// Close closes the queue and prevents new items from being added
-func (q *URLQueue) Close() {
-	atomic.StoreInt64(&q.closed, 1)
-	close(q.highPriority)
-	close(q.normalPriority)
-	close(q.lowPriority)
+// Close stops the queue from taking new items and wakes blocked callers.
+// Closing twice is a no-op.
+func (q *PriorityQueue) Close() {
+	if !atomic.CompareAndSwapInt64(&q.closed, 0, 1) {
+		return
+	}
+	close(q.done)
+	if q.spill != nil {
+		q.spill.close()
+	}
 }
@@ -0,0 +1,283 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestReprioritize(t *testing.T) {
+	// The host-aware queue only knows the three priority levels and ignores 7
+	queues := map[string]struct {
+		q interface {
+			URLQueue
+			Reprioritizer
+		}
+		moved int
+		order string
+	}{
+		"priority": {NewURLQueue(), 3, "up same down invalid"},
+		"host":     {NewHostAwareQueue(0, 0), 2, "up same invalid down"},
+	}
+	for name, tt := range queues {
+		q := tt.q
+		q.PushWithPriority("https://a.com/up", PriorityLow, "a.com", 1)
+		q.PushWithPriority("https://a.com/down", PriorityHigh, "a.com", 1)
+		q.PushWithPriority("https://a.com/same", PriorityNormal, "a.com", 1)
+		q.PushWithPriority("https://a.com/invalid", PriorityNormal, "a.com", 1)
+
+		moved := q.Reprioritize(func(item URLItem) int {
+			switch {
+			case strings.HasSuffix(item.URL, "/up"):
+				return PriorityHigh
+			case strings.HasSuffix(item.URL, "/down"):
+				return PriorityLow
+			case strings.HasSuffix(item.URL, "/invalid"):
+				return 7
+			}
+			return item.Priority
+		})
+		if moved != tt.moved {
+			t.Errorf("%s: Reprioritize() = %d, want %d", name, moved, tt.moved)
+		}
+		if q.Size() != 4 {
+			t.Errorf("%s: Size() = %d after reprioritizing", name, q.Size())
+		}
+
+		var order []string
+		for i := 0; i < 4; i++ {
+			item, ok := q.Pop()
+			if !ok {
+				t.Fatalf("%s: queue emptied early", name)
+			}
+			order = append(order, item.URL[len("https://a.com/"):])
+		}
+		if got := strings.Join(order, " "); got != tt.order {
+			t.Errorf("%s: popped %s", name, got)
+		}
+		q.Close()
+	}
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	q := NewURLQueue()
+	defer q.Close()
+	for _, p := range []struct {
+		path     string
+		priority int
+	}{{"five", 5}, {"first", 1}, {"minus", -3}, {"second", 1}, {"zero", 0}} {
+		q.PushWithPriority("https://a.com/"+p.path, p.priority, "a.com", 1)
+	}
+
+	var order []string
+	for _, item := range q.PopBatch(10) {
+		order = append(order, item.URL[len("https://a.com/"):])
+	}
+	if got := strings.Join(order, " "); got != "minus zero first second five" {
+		t.Errorf("popped %s", got)
+	}
+}
+
+func TestPriorityQueueUpdate(t *testing.T) {
+	q := NewURLQueue()
+	defer q.Close()
+	q.PushWithPriority("https://a.com/linked", PriorityLow, "a.com", 1)
+	q.PushWithPriority("https://a.com/other", PriorityNormal, "a.com", 1)
+
+	raise := func(item URLItem) int { return item.Priority - 2 }
+	if !q.Update("https://a.com/linked", raise) {
+		t.Fatal("Update() did not find a queued URL")
+	}
+	if q.Update("https://a.com/missing", raise) {
+		t.Error("Update() found a URL that is not queued")
+	}
+	stats := q.GetStats()
+	if stats["updated"] != 1 || stats["highBuffer"] != 1 || stats["normalBuffer"] != 1 || stats["lowBuffer"] != 0 {
+		t.Errorf("stats = %v", stats)
+	}
+
+	item, _ := q.Pop()
+	if item.URL != "https://a.com/linked" || item.Priority != PriorityHigh {
+		t.Errorf("Pop() = %+v", item)
+	}
+	// Popped URLs can't be updated anymore
+	if q.Update("https://a.com/linked", raise) {
+		t.Error("Update() found a popped URL")
+	}
+}
+
+func TestPriorityQueueAging(t *testing.T) {
+	q, err := NewPriorityQueue(config.QueueConfig{Aging: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	now := time.Now()
+	q.PushItem(URLItem{URL: "https://a.com/new", Priority: PriorityHigh, QueuedAt: now})
+	q.PushItem(URLItem{URL: "https://a.com/old", Priority: PriorityLow, QueuedAt: now.Add(-3 * time.Hour)})
+	q.PushItem(URLItem{URL: "https://a.com/older", Priority: PriorityLow, QueuedAt: now.Add(-90 * time.Minute)})
+
+	var order []string
+	for _, item := range q.PopBatch(10) {
+		order = append(order, item.URL[len("https://a.com/"):])
+	}
+	// Three hours made up two levels and then some, ninety minutes did not
+	if got := strings.Join(order, " "); got != "old new older" {
+		t.Errorf("popped %s", got)
+	}
+}
+
+func TestPriorityQueueDefer(t *testing.T) {
+	q := NewURLQueue()
+	defer q.Close()
+	q.PushWithPriority("https://a.com/", PriorityHigh, "a.com", 0)
+	q.PushWithPriority("https://b.com/", PriorityLow, "b.com", 0)
+
+	a, _ := q.Pop()
+	notBefore := time.Now().Add(100 * time.Millisecond)
+	if !q.Defer(a, notBefore) {
+		t.Fatal("Defer() = false on an open queue")
+	}
+	// The high priority item waits, the low priority one goes first
+	if item, _ := q.Pop(); item.URL != "https://b.com/" {
+		t.Fatalf("Pop() = %+v while a.com is held", item)
+	}
+	if item, ok := q.Pop(); ok {
+		t.Fatalf("Pop() = %+v before the held item is due", item)
+	}
+	stats := q.GetStats()
+	if q.Size() != 1 || stats["delayed"] != 1 || stats["deferred"] != 1 {
+		t.Fatalf("size %d, stats %v", q.Size(), stats)
+	}
+
+	item, ok := q.PopBlocking(context.Background())
+	if !ok || item.URL != "https://a.com/" || !item.NotBefore.Equal(notBefore) {
+		t.Fatalf("PopBlocking() = %+v, %v", item, ok)
+	}
+	if time.Now().Before(notBefore) {
+		t.Fatal("PopBlocking() returned a held item early")
+	}
+
+	q.Close()
+	if q.Defer(item, notBefore) {
+		t.Fatal("Defer() = true on a closed queue")
+	}
+}
+
+func TestPriorityQueueDrainAllIncludesHeld(t *testing.T) {
+	q := NewURLQueue()
+	defer q.Close()
+	q.Push("https://a.com/")
+	q.Push("https://b.com/")
+	b, _ := q.Pop()
+	q.Defer(b, time.Now().Add(time.Hour))
+
+	if items := q.DrainAll(); len(items) != 2 || q.Size() != 0 {
+		t.Fatalf("DrainAll() = %+v, size %d", items, q.Size())
+	}
+}
+
+func TestPopBlockingStops(t *testing.T) {
+	queues := map[string]func() URLQueue{
+		"priority": func() URLQueue { return NewURLQueue() },
+		"host":     func() URLQueue { return NewHostAwareQueue(0, 0) },
+	}
+	for name, newQueue := range queues {
+		// A cancelled context and a closed queue both end the wait
+		q := newQueue()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		if item, ok := q.PopBlocking(ctx); ok {
+			t.Errorf("%s: PopBlocking() = %+v on an empty queue", name, item)
+		}
+		cancel()
+
+		done := make(chan bool)
+		go func() {
+			_, ok := q.PopBlocking(context.Background())
+			done <- ok
+		}()
+		time.Sleep(20 * time.Millisecond)
+		q.Close()
+		select {
+		case ok := <-done:
+			if ok {
+				t.Errorf("%s: PopBlocking() popped from a closed queue", name)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: PopBlocking() still blocked after Close()", name)
+		}
+	}
+}
+
+func TestPopBlockingPriorityOrder(t *testing.T) {
+	q, err := NewPriorityQueue(config.QueueConfig{Aging: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	// A waiting worker gets the first item pushed, then strict priority order
+	got := make(chan URLItem)
+	go func() {
+		item, _ := q.PopBlocking(context.Background())
+		got <- item
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.PushWithPriority("https://a.com/low", PriorityLow, "a.com", 1)
+	if item := <-got; item.URL != "https://a.com/low" {
+		t.Fatalf("PopBlocking() = %+v", item)
+	}
+
+	for _, p := range []int{PriorityLow, PriorityNormal, PriorityLow, PriorityHigh, PriorityNormal} {
+		q.PushWithPriority("https://a.com/"+bandNames[p], p, "a.com", 1)
+	}
+	var order []string
+	for q.Size() > 0 {
+		item, _ := q.PopBlocking(context.Background())
+		order = append(order, item.URL[len("https://a.com/"):])
+	}
+	if got := strings.Join(order, " "); got != "high normal normal low low" {
+		t.Fatalf("popped %s", got)
+	}
+}
+
+func TestFrontierOrder(t *testing.T) {
+	tests := []struct {
+		order string
+		want  string
+	}{
+		{OrderPriority, "seed deep-high shallow-normal shallow-best deep-low"},
+		{OrderBreadthFirst, "seed shallow-normal shallow-best deep-high deep-low"},
+		// Seeds come first, equal scores fall back to priority
+		{OrderBestFirst, "seed shallow-best deep-high shallow-normal deep-low"},
+	}
+	for _, tt := range tests {
+		q := NewURLQueue()
+		q.PushItem(URLItem{URL: "seed", Priority: PriorityHigh})
+		q.PushItem(URLItem{URL: "shallow-normal", Priority: PriorityNormal, Depth: 1, Score: 0.2})
+		q.PushItem(URLItem{URL: "deep-low", Priority: PriorityLow, Depth: 2, Score: 0.2})
+		q.PushItem(URLItem{URL: "deep-high", Priority: PriorityHigh, Depth: 2, Score: 0.5})
+		q.PushItem(URLItem{URL: "shallow-best", Priority: PriorityNormal, Depth: 1, Score: 0.9})
+
+		// Changing the order sorts the queued items again
+		if err := q.SetOrder(tt.order); err != nil {
+			t.Fatal(err)
+		}
+		var order []string
+		for _, item := range q.PopBatch(10) {
+			order = append(order, item.URL)
+		}
+		if got := strings.Join(order, " "); got != tt.want {
+			t.Errorf("%s: popped %s, want %s", tt.order, got, tt.want)
+		}
+		q.Close()
+	}
+
+	if err := NewURLQueue().SetOrder("random"); err == nil {
+		t.Error("SetOrder() accepted an unknown order")
+	}
+}
@@ -0,0 +1,399 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/redis"
+)
+
+// log is the logger of the queue package
+var log = logger.For("queue")
+
+// priorityNames maps priority levels to Redis key suffixes
+var priorityNames = [3]string{"high", "normal", "low"}
+
+// RedisQueue is a frontier shared by several crawler instances through Redis.
+// Hosts are partitioned across instances so each host is only ever fetched
+// by the instance that owns it, keeping per-host politeness local. Every
+// instance counts its popped but unacknowledged items in Redis, so the others
+// know it may still queue more.
+type RedisQueue struct {
+	client     *redis.Client
+	prefix     string
+	instanceID int
+	instances  int
+	popTimeout time.Duration
+	closed     int64
+	done       chan struct{}
+
+	// Performance counters
+	totalQueued   int64
+	totalDequeued int64
+	errors        int64
+}
+
+// NewRedisQueue connects to Redis and joins the shared frontier as instanceID of instances
+func NewRedisQueue(cfg config.RedisConfig, instanceID, instances int) (*RedisQueue, error) {
+	if instances < 1 {
+		instances = 1
+	}
+	if instanceID < 0 || instanceID >= instances {
+		return nil, fmt.Errorf("instance id %d out of range for %d instances", instanceID, instances)
+	}
+
+	client, err := redis.NewClient(redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+		Timeout:  cfg.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	q := &RedisQueue{
+		client:     client,
+		prefix:     cfg.KeyPrefix,
+		instanceID: instanceID,
+		instances:  instances,
+		popTimeout: time.Second,
+		done:       make(chan struct{}),
+	}
+
+	// Items in flight before a restart of this instance are gone
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Do(ctx, "DEL", q.inFlightKey(instanceID)); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to reset in-flight count: %w", err)
+	}
+
+	log.Info("Joined shared Redis frontier as instance %d/%d", instanceID, instances)
+	return q, nil
+}
+
+// Partition returns the instance that owns host
+func Partition(host string, instances int) int {
+	if instances <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return int(h.Sum32() % uint32(instances))
+}
+
+// key returns the Redis list holding a partition's items of one priority
+func (q *RedisQueue) key(partition, priority int) string {
+	return q.prefix + "frontier:" + strconv.Itoa(partition) + ":" + priorityNames[priority]
+}
+
+// inFlightKey returns the counter of an instance's popped, unacknowledged items
+func (q *RedisQueue) inFlightKey(instance int) string {
+	return q.prefix + "inflight:" + strconv.Itoa(instance)
+}
+
+// ownKeys returns this instance's lists in priority order
+func (q *RedisQueue) ownKeys() []string {
+	keys := make([]string, len(priorityNames))
+	for p := range priorityNames {
+		keys[p] = q.key(q.instanceID, p)
+	}
+	return keys
+}
+
+// Push adds a URL with normal priority
+func (q *RedisQueue) Push(url string) {
+	q.PushWithPriority(url, PriorityNormal, "", 0)
+}
+
+// PushWithPriority adds a URL to the list of the instance owning its host
+func (q *RedisQueue) PushWithPriority(rawURL string, priority int, host string, depth int) {
+	if atomic.LoadInt64(&q.closed) == 1 {
+		return
+	}
+
+	if host == "" {
+		if parsed, err := url.Parse(rawURL); err == nil {
+			host = parsed.Host
+		}
+	}
+	if priority < PriorityHigh || priority > PriorityLow {
+		priority = PriorityNormal
+	}
+
+	data, err := json.Marshal(URLItem{
+		URL:      rawURL,
+		Priority: priority,
+		Host:     host,
+		Depth:    depth,
+		QueuedAt: time.Now(),
+	})
+	if err != nil {
+		atomic.AddInt64(&q.errors, 1)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key := q.key(Partition(host, q.instances), priority)
+	if _, err := q.client.Do(ctx, "LPUSH", key, string(data)); err != nil {
+		atomic.AddInt64(&q.errors, 1)
+		log.Error("Failed to push %s to redis frontier: %v", rawURL, err)
+		return
+	}
+	atomic.AddInt64(&q.totalQueued, 1)
+}
+
+// decode parses a queued item and updates counters
+func (q *RedisQueue) decode(data string) (URLItem, bool) {
+	var item URLItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		atomic.AddInt64(&q.errors, 1)
+		return URLItem{}, false
+	}
+	atomic.AddInt64(&q.totalDequeued, 1)
+	return item, true
+}
+
+// take decodes a popped item and counts it as in flight until it is acked
+func (q *RedisQueue) take(data string) (URLItem, bool) {
+	item, ok := q.decode(data)
+	if !ok {
+		return URLItem{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := q.client.Do(ctx, "INCR", q.inFlightKey(q.instanceID)); err != nil {
+		atomic.AddInt64(&q.errors, 1)
+	}
+	return item, true
+}
+
+// Ack marks a popped item as processed
+func (q *RedisQueue) Ack(item URLItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := q.client.Do(ctx, "DECR", q.inFlightKey(q.instanceID)); err != nil {
+		atomic.AddInt64(&q.errors, 1)
+	}
+}
+
+// pop removes the highest priority item owned by this instance and returns
+// its encoded form
+func (q *RedisQueue) pop(ctx context.Context) (string, bool) {
+	for _, key := range q.ownKeys() {
+		data, err := q.client.String(ctx, "RPOP", key)
+		if err != nil {
+			if !errors.Is(err, redis.ErrNil) {
+				atomic.AddInt64(&q.errors, 1)
+			}
+			continue
+		}
+		return data, true
+	}
+	return "", false
+}
+
+// Pop removes and returns the highest priority URL owned by this instance
+func (q *RedisQueue) Pop() (URLItem, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, ok := q.pop(ctx)
+	if !ok {
+		return URLItem{}, false
+	}
+	return q.take(data)
+}
+
+// PopBlocking waits for a URL owned by this instance. BRPOP checks the lists
+// in the given order, so higher priorities are always served first. Returns
+// false once the queue has been closed or ctx is done, which a BRPOP in
+// flight notices within the pop timeout.
+func (q *RedisQueue) PopBlocking(ctx context.Context) (URLItem, bool) {
+	args := append([]string{"BRPOP"}, q.ownKeys()...)
+	args = append(args, strconv.Itoa(int(q.popTimeout.Seconds())))
+
+	for atomic.LoadInt64(&q.closed) == 0 && ctx.Err() == nil {
+		popCtx, cancel := context.WithTimeout(ctx, q.popTimeout+5*time.Second)
+		reply, err := q.client.Strings(popCtx, args...)
+		cancel()
+
+		if err != nil {
+			if !errors.Is(err, redis.ErrNil) && ctx.Err() == nil {
+				atomic.AddInt64(&q.errors, 1)
+				timer := time.NewTimer(q.popTimeout)
+				select {
+				case <-timer.C:
+				case <-q.done:
+				case <-ctx.Done():
+				}
+				timer.Stop()
+			}
+			continue
+		}
+		if len(reply) == 2 {
+			if item, ok := q.take(reply[1]); ok {
+				return item, true
+			}
+		}
+	}
+	return URLItem{}, false
+}
+
+// PopBatch returns multiple items at once for batch processing
+func (q *RedisQueue) PopBatch(maxItems int) []URLItem {
+	items := make([]URLItem, 0, maxItems)
+
+	for i := 0; i < maxItems; i++ {
+		item, ok := q.Pop()
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// lengths returns the list lengths of a partition by priority
+func (q *RedisQueue) lengths(ctx context.Context, partition int) [3]int64 {
+	var lens [3]int64
+	for p := range priorityNames {
+		n, err := q.client.Int(ctx, "LLEN", q.key(partition, p))
+		if err == nil {
+			lens[p] = n
+		}
+	}
+	return lens
+}
+
+// DrainAll removes and returns the items of this instance's partition, e.g.
+// for a checkpoint. The partitions of other instances are left alone.
+func (q *RedisQueue) DrainAll() []URLItem {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var items []URLItem
+	for {
+		data, ok := q.pop(ctx)
+		if !ok {
+			return items
+		}
+		if item, ok := q.decode(data); ok {
+			items = append(items, item)
+		}
+	}
+}
+
+// Size returns the number of items waiting for this instance
+func (q *RedisQueue) Size() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lens := q.lengths(ctx, q.instanceID)
+	return int(lens[0] + lens[1] + lens[2])
+}
+
+// Pending returns the items waiting for any instance plus those being
+// processed by any instance. An unreachable Redis counts as pending so no
+// instance mistakes it for the end of the crawl.
+func (q *RedisQueue) Pending() int {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pending := 0
+	keys := []string{"MGET"}
+	for i := 0; i < q.instances; i++ {
+		lens, err := q.partitionSize(ctx, i)
+		if err != nil {
+			atomic.AddInt64(&q.errors, 1)
+			return 1
+		}
+		pending += int(lens)
+		keys = append(keys, q.inFlightKey(i))
+	}
+
+	counts, err := q.client.Strings(ctx, keys...)
+	if err != nil {
+		atomic.AddInt64(&q.errors, 1)
+		return 1
+	}
+	for _, count := range counts {
+		if n, err := strconv.Atoi(count); err == nil && n > 0 {
+			pending += n
+		}
+	}
+	return pending
+}
+
+// partitionSize returns the number of items queued for an instance
+func (q *RedisQueue) partitionSize(ctx context.Context, partition int) (int64, error) {
+	var total int64
+	for p := range priorityNames {
+		n, err := q.client.Int(ctx, "LLEN", q.key(partition, p))
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// GetStats returns queue statistics for this instance and the shared frontier
+func (q *RedisQueue) GetStats() map[string]int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	own := q.lengths(ctx, q.instanceID)
+	var global int64
+	for i := 0; i < q.instances; i++ {
+		lens := q.lengths(ctx, i)
+		global += lens[0] + lens[1] + lens[2]
+	}
+
+	return map[string]int64{
+		"size":          own[0] + own[1] + own[2],
+		"globalSize":    global,
+		"totalQueued":   atomic.LoadInt64(&q.totalQueued),
+		"totalDequeued": atomic.LoadInt64(&q.totalDequeued),
+		"errors":        atomic.LoadInt64(&q.errors),
+		"highBuffer":    own[PriorityHigh],
+		"normalBuffer":  own[PriorityNormal],
+		"lowBuffer":     own[PriorityLow],
+		"instanceID":    int64(q.instanceID),
+		"instances":     int64(q.instances),
+	}
+}
+
+// IsFull always reports false, the Redis frontier is bounded by server memory
+func (q *RedisQueue) IsFull() bool {
+	return false
+}
+
+// Close stops consuming and closes the Redis connections. Queued items stay
+// in Redis for the other instances or a later run; items still in flight no
+// longer hold the other instances back.
+func (q *RedisQueue) Close() {
+	if atomic.CompareAndSwapInt64(&q.closed, 0, 1) {
+		close(q.done)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		q.client.Do(ctx, "DEL", q.inFlightKey(q.instanceID))
+		cancel()
+		q.client.Close()
+	}
+}
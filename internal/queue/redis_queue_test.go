@@ -0,0 +1,224 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// fakeRedis is an in-memory server for the list and counter commands the
+// Redis queue uses
+type fakeRedis struct {
+	mu      sync.Mutex
+	lists   map[string][]string
+	strings map[string]string
+}
+
+// startFakeRedis serves a fakeRedis on a local port until the test ends
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{lists: make(map[string][]string), strings: make(map[string]string)}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(nc)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+// serve answers the commands sent on one connection
+func (f *fakeRedis) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			r.ReadString('\n')
+			arg, _ := r.ReadString('\n')
+			args[i] = strings.TrimSuffix(arg, "\r\n")
+		}
+		nc.Write([]byte(f.do(args)))
+	}
+}
+
+// do runs one command and returns its RESP reply
+func (f *fakeRedis) do(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "DEL":
+		delete(f.lists, args[1])
+		delete(f.strings, args[1])
+		return ":1\r\n"
+	case "LPUSH":
+		f.lists[args[1]] = append([]string{args[2]}, f.lists[args[1]]...)
+		return fmt.Sprintf(":%d\r\n", len(f.lists[args[1]]))
+	case "RPOP":
+		list := f.lists[args[1]]
+		if len(list) == 0 {
+			return "$-1\r\n"
+		}
+		f.lists[args[1]] = list[:len(list)-1]
+		return bulk(list[len(list)-1])
+	case "BRPOP":
+		for _, key := range args[1 : len(args)-1] {
+			if list := f.lists[key]; len(list) > 0 {
+				f.lists[key] = list[:len(list)-1]
+				return "*2\r\n" + bulk(key) + bulk(list[len(list)-1])
+			}
+		}
+		return "*-1\r\n"
+	case "LLEN":
+		return fmt.Sprintf(":%d\r\n", len(f.lists[args[1]]))
+	case "INCR", "DECR":
+		n, _ := strconv.Atoi(f.strings[args[1]])
+		if strings.ToUpper(args[0]) == "INCR" {
+			n++
+		} else {
+			n--
+		}
+		f.strings[args[1]] = strconv.Itoa(n)
+		return fmt.Sprintf(":%d\r\n", n)
+	case "MGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			if v, ok := f.strings[key]; ok {
+				reply += bulk(v)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// newTestRedisQueue joins the frontier at addr as instance id of instances
+func newTestRedisQueue(t *testing.T, addr string, id, instances int) *RedisQueue {
+	t.Helper()
+	q, err := NewRedisQueue(config.RedisConfig{Addr: addr, Timeout: time.Second, KeyPrefix: "test:"}, id, instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(q.Close)
+	return q
+}
+
+// hostOf returns a host owned by instance of instances
+func hostOf(instance, instances int) string {
+	for i := 0; ; i++ {
+		host := fmt.Sprintf("host%d.com", i)
+		if Partition(host, instances) == instance {
+			return host
+		}
+	}
+}
+
+func TestRedisQueuePendingCountsOtherInstances(t *testing.T) {
+	_, addr := startFakeRedis(t)
+	a := newTestRedisQueue(t, addr, 0, 2)
+	b := newTestRedisQueue(t, addr, 1, 2)
+
+	host := hostOf(1, 2)
+	a.PushWithPriority("https://"+host+"/", PriorityNormal, host, 0)
+	if a.Size() != 0 || a.Pending() != 1 {
+		t.Fatalf("Size() = %d, Pending() = %d, want 0 and 1", a.Size(), a.Pending())
+	}
+
+	// While b processes the item, a must not consider the crawl done
+	item, ok := b.Pop()
+	if !ok || item.Host != host {
+		t.Fatalf("Pop() = %v, %v", item, ok)
+	}
+	if n := a.Pending(); n != 1 {
+		t.Fatalf("Pending() with an item in flight = %d, want 1", n)
+	}
+	b.Ack(item)
+	if n := a.Pending(); n != 0 {
+		t.Fatalf("Pending() after ack = %d, want 0", n)
+	}
+	if n := Pending(NewURLQueue()); n != 0 {
+		t.Fatalf("Pending() of a local queue = %d", n)
+	}
+}
+
+func TestRedisQueueCloseReleasesInFlight(t *testing.T) {
+	_, addr := startFakeRedis(t)
+	a := newTestRedisQueue(t, addr, 0, 2)
+	b := newTestRedisQueue(t, addr, 1, 2)
+
+	host := hostOf(1, 2)
+	b.PushWithPriority("https://"+host+"/", PriorityNormal, host, 0)
+	if _, ok := b.PopBlocking(context.Background()); !ok {
+		t.Fatal("PopBlocking() found nothing")
+	}
+	b.Close()
+	if n := a.Pending(); n != 0 {
+		t.Fatalf("Pending() after the other instance closed = %d, want 0", n)
+	}
+}
+
+func TestRedisQueueDrainAll(t *testing.T) {
+	_, addr := startFakeRedis(t)
+	a := newTestRedisQueue(t, addr, 0, 2)
+	b := newTestRedisQueue(t, addr, 1, 2)
+
+	own, other := hostOf(0, 2), hostOf(1, 2)
+	a.PushWithPriority("https://"+own+"/low", PriorityLow, own, 0)
+	a.PushWithPriority("https://"+own+"/high", PriorityHigh, own, 0)
+	a.PushWithPriority("https://"+other+"/", PriorityNormal, other, 0)
+
+	items := Drain(a)
+	if len(items) != 2 || items[0].URL != "https://"+own+"/high" || items[1].URL != "https://"+own+"/low" {
+		t.Fatalf("DrainAll() = %v", items)
+	}
+	if a.Size() != 0 || b.Size() != 1 {
+		t.Fatalf("sizes after drain: %d and %d, want 0 and 1", a.Size(), b.Size())
+	}
+	// Drained items are not in flight
+	if n := a.Pending(); n != 1 {
+		t.Fatalf("Pending() after drain = %d, want 1", n)
+	}
+}
+
+func TestNewFromConfigRejectsConflicts(t *testing.T) {
+	tests := []config.QueueConfig{
+		{Persistent: true, HostAware: true, Path: "q.log"},
+		{Backend: "redis", Persistent: true},
+		{Backend: "redis", HostAware: true},
+		{Backend: "disk"},
+	}
+	for _, cfg := range tests {
+		if q, err := NewFromConfig(cfg, false); err == nil {
+			q.Close()
+			t.Errorf("NewFromConfig(%+v) succeeded", cfg)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the ratelimit package
+var log = logger.For("ratelimit")
+
+// AdaptiveLimiter adjusts per-host rates from server responses: it backs off
+// on 429/503 (honoring Retry-After) and speeds back up on fast responses
+type AdaptiveLimiter struct {
+	*HostLimiter
+
+	mu          sync.Mutex
+	cfg         config.AdaptiveRateConfig
+	pausedUntil map[string]time.Time
+}
+
+// NewAdaptiveLimiter creates an adaptive limiter from the rate limit configuration
+func NewAdaptiveLimiter(cfg config.RateLimitsConfig) *AdaptiveLimiter {
+	return &AdaptiveLimiter{
+		HostLimiter: NewHostLimiter(cfg),
+		cfg:         adaptiveDefaults(cfg.Adaptive),
+		pausedUntil: make(map[string]time.Time),
+	}
+}
+
+// adaptiveDefaults replaces out-of-range factors with the defaults
+func adaptiveDefaults(adaptive config.AdaptiveRateConfig) config.AdaptiveRateConfig {
+	if adaptive.DecreaseFactor <= 0 || adaptive.DecreaseFactor >= 1 {
+		adaptive.DecreaseFactor = 0.5
+	}
+	if adaptive.IncreaseFactor <= 1 {
+		adaptive.IncreaseFactor = 1.1
+	}
+	return adaptive
+}
+
+// Reload applies new rate limits. Hosts fall back to their configured rate,
+// dropping any adaptive adjustment; Retry-After pauses are kept.
+func (a *AdaptiveLimiter) Reload(cfg config.RateLimitsConfig) {
+	a.mu.Lock()
+	a.cfg = adaptiveDefaults(cfg.Adaptive)
+	a.mu.Unlock()
+
+	a.SetRules(cfg)
+}
+
+// config returns the adaptive settings in effect
+func (a *AdaptiveLimiter) config() config.AdaptiveRateConfig {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.cfg
+}
+
+// Wait blocks until host is out of any Retry-After pause and a token is available
+func (a *AdaptiveLimiter) Wait(ctx context.Context, host string) error {
+	a.mu.Lock()
+	until := a.pausedUntil[host]
+	a.mu.Unlock()
+
+	if delay := time.Until(until); delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	return a.HostLimiter.Wait(ctx, host)
+}
+
+// Reserve takes a token for a request to host and returns how long the
+// request must wait for it and for any Retry-After pause of host to end
+func (a *AdaptiveLimiter) Reserve(host string) time.Duration {
+	a.mu.Lock()
+	until := a.pausedUntil[host]
+	a.mu.Unlock()
+
+	return max(time.Until(until), a.HostLimiter.Reserve(host))
+}
+
+// maxRate returns the fastest rate host may be raised back to
+func (a *AdaptiveLimiter) maxRate(host string, cfg config.AdaptiveRateConfig) float64 {
+	if rate := a.RuleFor(host).RequestsPerSecond; rate > 0 {
+		return rate
+	}
+	return cfg.MaxRate
+}
+
+// Observe records a response from host and adjusts its rate, unless adaptive
+// rate limiting is disabled
+func (a *AdaptiveLimiter) Observe(host string, statusCode int, latency time.Duration, header http.Header) {
+	cfg := a.config()
+	if !cfg.Enabled {
+		return
+	}
+	bucket := a.Bucket(host)
+	rate := bucket.Rate()
+	maxRate := a.maxRate(host, cfg)
+
+	switch {
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		if rate <= 0 || rate > maxRate {
+			rate = maxRate
+		}
+		newRate := rate * cfg.DecreaseFactor
+		if newRate < cfg.MinRate {
+			newRate = cfg.MinRate
+		}
+		bucket.SetRate(newRate)
+
+		if wait := ParseRetryAfter(header.Get("Retry-After"), time.Now()); wait > 0 {
+			a.mu.Lock()
+			a.pausedUntil[host] = time.Now().Add(wait)
+			a.mu.Unlock()
+			log.Warn("Host %s returned %d, pausing for %s and slowing to %.2f req/s", host, statusCode, wait, newRate)
+		} else {
+			log.Warn("Host %s returned %d, slowing to %.2f req/s", host, statusCode, newRate)
+		}
+
+	case statusCode < 400 && cfg.FastResponse > 0 && latency < cfg.FastResponse:
+		if rate <= 0 || rate >= maxRate {
+			return
+		}
+		newRate := rate * cfg.IncreaseFactor
+		if newRate > maxRate {
+			newRate = maxRate
+		}
+		bucket.SetRate(newRate)
+	}
+}
+
+// Delays returns the current delay between requests for every known host,
+// including any remaining Retry-After pause
+func (a *AdaptiveLimiter) Delays() map[string]time.Duration {
+	delays := make(map[string]time.Duration)
+	for host, rate := range a.Rates() {
+		if rate > 0 {
+			delays[host] = time.Duration(float64(time.Second) / rate)
+		} else {
+			delays[host] = 0
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for host, until := range a.pausedUntil {
+		if remaining := until.Sub(now); remaining > 0 {
+			delays[host] += remaining
+		} else {
+			delete(a.pausedUntil, host)
+		}
+	}
+	return delays
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
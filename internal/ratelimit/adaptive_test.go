@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// adaptiveConfig returns rate limits of 4 req/s per host with adaptive
+// limiting set to enabled
+func adaptiveConfig(enabled bool) config.RateLimitsConfig {
+	return config.RateLimitsConfig{
+		Default: config.RateLimitRule{RequestsPerSecond: 4, Burst: 4},
+		Adaptive: config.AdaptiveRateConfig{
+			Enabled:        enabled,
+			MinRate:        0.5,
+			MaxRate:        10,
+			FastResponse:   500 * time.Millisecond,
+			DecreaseFactor: 0.5,
+			IncreaseFactor: 2,
+		},
+	}
+}
+
+func TestAdaptiveLimiterBacksOffAndRecovers(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(true))
+
+	a.Observe("example.com", http.StatusTooManyRequests, time.Second, http.Header{})
+	if rate := a.Bucket("example.com").Rate(); rate != 2 {
+		t.Fatalf("rate after 429 = %v, want 2", rate)
+	}
+	for i := 0; i < 5; i++ {
+		a.Observe("example.com", http.StatusServiceUnavailable, time.Second, http.Header{})
+	}
+	if rate := a.Bucket("example.com").Rate(); rate != 0.5 {
+		t.Fatalf("rate after repeated 503 = %v, want the 0.5 minimum", rate)
+	}
+
+	// Fast responses speed the host up, but not beyond its configured rate
+	for i := 0; i < 5; i++ {
+		a.Observe("example.com", http.StatusOK, 10*time.Millisecond, http.Header{})
+	}
+	if rate := a.Bucket("example.com").Rate(); rate != 4 {
+		t.Fatalf("rate after fast responses = %v, want 4", rate)
+	}
+}
+
+func TestAdaptiveLimiterCrawlDelay(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(true))
+
+	// A delay slower than the backed off rate applies
+	a.Observe("example.com", http.StatusTooManyRequests, time.Second, http.Header{})
+	a.SetCrawlDelay("example.com", time.Second)
+	if rate := a.Bucket("example.com").Rate(); rate != 1 {
+		t.Fatalf("rate with crawl delay = %v, want 1", rate)
+	}
+
+	// Changing or removing the delay keeps a slower backed off rate
+	a.Observe("example.com", http.StatusServiceUnavailable, time.Second, http.Header{})
+	a.SetCrawlDelay("example.com", 500*time.Millisecond)
+	if rate := a.Bucket("example.com").Rate(); rate != 0.5 {
+		t.Fatalf("rate after shorter crawl delay = %v, want the backed off 0.5", rate)
+	}
+	a.SetCrawlDelay("example.com", 0)
+	if rate := a.Bucket("example.com").Rate(); rate != 0.5 {
+		t.Fatalf("rate after removing the crawl delay = %v, want the backed off 0.5", rate)
+	}
+
+	// Without a backoff, removing the delay restores the configured rate
+	a.SetCrawlDelay("other.com", time.Second)
+	a.SetCrawlDelay("other.com", 0)
+	if rate := a.Bucket("other.com").Rate(); rate != 4 {
+		t.Fatalf("rate after removing the crawl delay = %v, want 4", rate)
+	}
+}
+
+func TestAdaptiveLimiterRetryAfterDelay(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(true))
+	a.Bucket("other.com")
+
+	a.Observe("example.com", http.StatusTooManyRequests, 0, http.Header{"Retry-After": {"30"}})
+	delays := a.Delays()
+	if d := delays["example.com"]; d < 29*time.Second || d > 31*time.Second {
+		t.Fatalf("delay with Retry-After = %v, want about 30.5s", d)
+	}
+	if d := delays["other.com"]; d != 250*time.Millisecond {
+		t.Fatalf("delay of an unaffected host = %v, want 250ms", d)
+	}
+}
+
+func TestAdaptiveLimiterReserve(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(true))
+
+	// The burst of 4 goes out at once, then one request per 250ms
+	for i := 0; i < 4; i++ {
+		if d := a.Reserve("example.com"); d != 0 {
+			t.Fatalf("reservation %d waits %v within the burst", i, d)
+		}
+	}
+	if d := a.Reserve("example.com"); d < 200*time.Millisecond || d > 250*time.Millisecond {
+		t.Fatalf("reservation after the burst waits %v, want about 250ms", d)
+	}
+	if d := a.Reserve("example.com"); d < 450*time.Millisecond || d > 500*time.Millisecond {
+		t.Fatalf("second reservation after the burst waits %v, want about 500ms", d)
+	}
+
+	a.Observe("other.com", http.StatusTooManyRequests, 0, http.Header{"Retry-After": {"30"}})
+	if d := a.Reserve("other.com"); d < 29*time.Second || d > 30*time.Second {
+		t.Fatalf("reservation during Retry-After waits %v, want about 30s", d)
+	}
+}
+
+func TestAdaptiveLimiterDisabled(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(false))
+
+	a.Observe("example.com", http.StatusTooManyRequests, 0, http.Header{"Retry-After": {"30"}})
+	if rate := a.Bucket("example.com").Rate(); rate != 4 {
+		t.Fatalf("disabled limiter changed the rate to %v", rate)
+	}
+	if d := a.Delays()["example.com"]; d != 250*time.Millisecond {
+		t.Fatalf("disabled limiter paused the host: delay %v", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-5":                            0,
+		"Mon, 01 Jan 2024 12:01:00 GMT": time.Minute,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range tests {
+		if got := ParseRetryAfter(value, now); got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestAdaptiveLimiterReload(t *testing.T) {
+	a := NewAdaptiveLimiter(adaptiveConfig(true))
+	a.Observe("example.com", http.StatusTooManyRequests, time.Second, http.Header{})
+
+	cfg := adaptiveConfig(true)
+	cfg.Default = config.RateLimitRule{RequestsPerSecond: 1, Burst: 1}
+	a.Reload(cfg)
+
+	// The adaptive adjustment is dropped and the host gets the new rate
+	if rate := a.Bucket("example.com").Rate(); rate != 1 {
+		t.Fatalf("rate after reload = %v, want 1", rate)
+	}
+}
@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Circuit states of a host
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// circuitIdle is how long a closed circuit is kept after its last request
+const circuitIdle = 10 * time.Minute
+
+// circuit is the breaker state of one host
+type circuit struct {
+	state     string
+	openUntil time.Time // End of the cooldown of an open circuit
+	failures  int       // Consecutive failures
+	window    []bool    // Outcomes of the latest requests, true for a failure
+	next      int       // Index of the oldest outcome once the window is full
+	last      time.Time // Of the latest outcome
+}
+
+// CircuitInfo describes a host whose circuit isn't closed
+type CircuitInfo struct {
+	Host  string     `json:"host"`
+	State string     `json:"state"`
+	Until *time.Time `json:"until,omitempty"` // End of the cooldown of an open circuit
+}
+
+// Breaker stops requests to hosts that keep failing. A host's circuit opens
+// after too many consecutive failures or too high an error rate, rejects
+// requests for the cooldown, then lets a single probe through.
+type Breaker struct {
+	mu    sync.Mutex
+	cfg   config.CircuitBreakerConfig
+	hosts map[string]*circuit
+	now   func() time.Time
+	swept time.Time // When idle circuits were last dropped
+
+	// Counters
+	opened   int64
+	reclosed int64
+	probes   int64
+	rejected int64
+}
+
+// NewBreaker creates a breaker with every circuit closed
+func NewBreaker(cfg config.CircuitBreakerConfig) *Breaker {
+	return &Breaker{cfg: cfg, hosts: make(map[string]*circuit), now: time.Now}
+}
+
+// Reload applies new breaker settings. Open circuits keep their cooldown,
+// and disabling the breaker closes them all.
+func (b *Breaker) Reload(cfg config.CircuitBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cfg = cfg
+	if !cfg.Enabled {
+		b.hosts = make(map[string]*circuit)
+	}
+}
+
+// Allow reports whether a request to host may go out, and whether it's the
+// probe of the host. Once the cooldown of an open circuit is over, the first
+// caller gets to send the probe and the circuit is half open until its
+// outcome is recorded, or the probe is released.
+func (b *Breaker) Allow(host string) (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok || c.state == CircuitClosed {
+		return true, false
+	}
+	if c.state == CircuitOpen && !b.now().Before(c.openUntil) {
+		c.state = CircuitHalfOpen
+		atomic.AddInt64(&b.probes, 1)
+		return true, true
+	}
+	atomic.AddInt64(&b.rejected, 1)
+	return false, false
+}
+
+// Release gives up the probe of host when its request doesn't go out. The
+// circuit is open again with its cooldown over, so the next request probes
+// the host instead.
+func (b *Breaker) Release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.hosts[host]; ok && c.state == CircuitHalfOpen {
+		c.state = CircuitOpen
+		c.openUntil = b.now()
+	}
+}
+
+// Record adds the outcome of a request to host and returns the state of its
+// circuit if the outcome changed it, or "" if it didn't
+func (b *Breaker) Record(host string, failed bool) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.cfg.Enabled {
+		return ""
+	}
+	now := b.now()
+	if now.Sub(b.swept) >= circuitIdle {
+		b.sweep(now)
+	}
+	c, ok := b.hosts[host]
+	if !ok {
+		if !failed && !b.rated() {
+			return "" // Without an error rate, healthy hosts need no state
+		}
+		c = &circuit{state: CircuitClosed}
+		b.hosts[host] = c
+	}
+	c.last = now
+
+	switch c.state {
+	case CircuitHalfOpen:
+		if failed {
+			b.open(c)
+			return CircuitOpen
+		}
+		delete(b.hosts, host)
+		atomic.AddInt64(&b.reclosed, 1)
+		return CircuitClosed
+	case CircuitOpen:
+		return "" // A request sent before the circuit opened
+	}
+
+	if failed {
+		c.failures++
+	} else {
+		c.failures = 0
+	}
+	if b.cfg.Window > 0 {
+		if len(c.window) < b.cfg.Window {
+			c.window = append(c.window, failed)
+		} else {
+			c.window[c.next] = failed
+			c.next = (c.next + 1) % len(c.window)
+		}
+	}
+	if b.tripped(c) {
+		b.open(c)
+		return CircuitOpen
+	}
+	return ""
+}
+
+// rated reports whether circuits open on their error rate, which counts
+// every request from the first. b.mu must be held.
+func (b *Breaker) rated() bool {
+	return b.cfg.ErrorRate > 0 && b.cfg.Window > 0
+}
+
+// sweep drops the circuits of hosts that stayed closed and had no request
+// for circuitIdle. b.mu must be held.
+func (b *Breaker) sweep(now time.Time) {
+	for host, c := range b.hosts {
+		if c.state == CircuitClosed && now.Sub(c.last) >= circuitIdle {
+			delete(b.hosts, host)
+		}
+	}
+	b.swept = now
+}
+
+// tripped reports whether a closed circuit must open. The error rate only
+// counts once the window is full.
+func (b *Breaker) tripped(c *circuit) bool {
+	if b.cfg.ConsecutiveFailures > 0 && c.failures >= b.cfg.ConsecutiveFailures {
+		return true
+	}
+	if !b.rated() || len(c.window) < b.cfg.Window {
+		return false
+	}
+	failures := 0
+	for _, failed := range c.window {
+		if failed {
+			failures++
+		}
+	}
+	return float64(failures)/float64(len(c.window)) >= b.cfg.ErrorRate
+}
+
+// open starts the cooldown of c. b.mu must be held.
+func (b *Breaker) open(c *circuit) {
+	c.state = CircuitOpen
+	c.openUntil = b.now().Add(b.cfg.Cooldown)
+	c.failures = 0
+	c.window = c.window[:0]
+	c.next = 0
+	atomic.AddInt64(&b.opened, 1)
+}
+
+// Remaining returns how long the circuit of host stays open, zero if it
+// isn't open
+func (b *Breaker) Remaining(host string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.hosts[host]
+	if !ok || c.state != CircuitOpen {
+		return 0
+	}
+	return max(c.openUntil.Sub(b.now()), 0)
+}
+
+// Circuits lists the hosts whose circuit is open or half open, ordered by name
+func (b *Breaker) Circuits() []CircuitInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := []CircuitInfo{}
+	for host, c := range b.hosts {
+		if c.state == CircuitClosed {
+			continue
+		}
+		info := CircuitInfo{Host: host, State: c.state}
+		if c.state == CircuitOpen {
+			until := c.openUntil
+			info.Until = &until
+		}
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Host < list[j].Host })
+	return list
+}
+
+// GetStats returns breaker statistics for monitoring
+func (b *Breaker) GetStats() map[string]int64 {
+	b.mu.Lock()
+	var open, halfOpen int64
+	for _, c := range b.hosts {
+		switch c.state {
+		case CircuitOpen:
+			open++
+		case CircuitHalfOpen:
+			halfOpen++
+		}
+	}
+	b.mu.Unlock()
+
+	return map[string]int64{
+		"open":     open,
+		"halfOpen": halfOpen,
+		"opened":   atomic.LoadInt64(&b.opened),
+		"reclosed": atomic.LoadInt64(&b.reclosed),
+		"probes":   atomic.LoadInt64(&b.probes),
+		"rejected": atomic.LoadInt64(&b.rejected),
+	}
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// newTestBreaker returns a breaker on a clock the test moves by hand
+func newTestBreaker(cfg config.CircuitBreakerConfig) (*Breaker, *time.Time) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	b := NewBreaker(cfg)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+// allowed reports whether b lets a request to host through, probe or not
+func allowed(b *Breaker, host string) bool {
+	ok, _ := b.Allow(host)
+	return ok
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	b, now := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 3, Cooldown: time.Minute})
+
+	// A success in between starts the count again
+	for _, failed := range []bool{true, true, false, true, true} {
+		if state := b.Record("a.com", failed); state != "" {
+			t.Fatalf("Record(%v) = %q before the threshold", failed, state)
+		}
+	}
+	if state := b.Record("a.com", true); state != CircuitOpen {
+		t.Fatalf("third failure in a row: Record() = %q, want open", state)
+	}
+	if allowed(b, "a.com") || !allowed(b, "b.com") {
+		t.Fatal("open circuit let a request through or held back another host")
+	}
+	if d := b.Remaining("a.com"); d != time.Minute {
+		t.Fatalf("Remaining() = %v, want 1m", d)
+	}
+	circuits := b.Circuits()
+	if len(circuits) != 1 || circuits[0].State != CircuitOpen || !circuits[0].Until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Circuits() = %+v", circuits)
+	}
+
+	// After the cooldown one probe goes out
+	*now = now.Add(time.Minute)
+	if ok, probe := b.Allow("a.com"); !ok || !probe {
+		t.Fatalf("Allow() = %v, %v after the cooldown, want the probe", ok, probe)
+	}
+	if allowed(b, "a.com") || b.Remaining("a.com") != 0 {
+		t.Fatal("half open circuit let a second request through")
+	}
+	if state := b.Record("a.com", true); state != CircuitOpen {
+		t.Fatalf("failed probe: Record() = %q, want open", state)
+	}
+
+	*now = now.Add(time.Minute)
+	allowed(b, "a.com")
+	if state := b.Record("a.com", false); state != CircuitClosed {
+		t.Fatalf("successful probe: Record() = %q, want closed", state)
+	}
+	if !allowed(b, "a.com") || len(b.Circuits()) != 0 {
+		t.Fatal("closed circuit holds back its host")
+	}
+
+	stats := b.GetStats()
+	if stats["opened"] != 2 || stats["reclosed"] != 1 || stats["probes"] != 2 || stats["rejected"] != 2 || stats["open"] != 0 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	b, _ := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ErrorRate: 0.5, Window: 4, Cooldown: time.Minute})
+
+	// Alternating outcomes never fail twice in a row but reach the rate once
+	// the window is full
+	outcomes := []bool{true, false, true}
+	for _, failed := range outcomes {
+		if state := b.Record("a.com", failed); state != "" {
+			t.Fatalf("Record() = %q before the window is full", state)
+		}
+	}
+	if state := b.Record("a.com", false); state != CircuitOpen {
+		t.Fatalf("2 failures of 4: Record() = %q, want open", state)
+	}
+
+	// The window slides over the latest outcomes
+	b2, _ := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ErrorRate: 0.75, Window: 4, Cooldown: time.Minute})
+	for _, failed := range []bool{true, true, false, false, false, true, true} {
+		if state := b2.Record("a.com", failed); state != "" {
+			t.Fatalf("Record() = %q below the error rate", state)
+		}
+	}
+	if state := b2.Record("a.com", true); state != CircuitOpen {
+		t.Fatalf("3 failures of the last 4: Record() = %q, want open", state)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b, _ := newTestBreaker(config.CircuitBreakerConfig{Enabled: false, ConsecutiveFailures: 1, Cooldown: time.Minute})
+	if state := b.Record("a.com", true); state != "" || !allowed(b, "a.com") {
+		t.Fatalf("disabled breaker: Record() = %q", state)
+	}
+
+	b.Reload(config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, Cooldown: time.Minute})
+	if b.Record("a.com", true) != CircuitOpen {
+		t.Fatal("reloaded breaker didn't open")
+	}
+	b.Reload(config.CircuitBreakerConfig{})
+	if !allowed(b, "a.com") {
+		t.Fatal("disabling the breaker kept its circuits open")
+	}
+}
+
+func TestBreakerErrorRateCountsSuccesses(t *testing.T) {
+	b, _ := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ErrorRate: 0.75, Window: 4, Cooldown: time.Minute})
+
+	// Successes before the first failure are part of the window, so it's
+	// full on the third failure
+	for _, failed := range []bool{false, false, true, true} {
+		if state := b.Record("a.com", failed); state != "" {
+			t.Fatalf("Record(%v) = %q below the error rate", failed, state)
+		}
+	}
+	if state := b.Record("a.com", true); state != CircuitOpen {
+		t.Fatalf("3 failures of the last 4: Record() = %q, want open", state)
+	}
+}
+
+func TestBreakerRelease(t *testing.T) {
+	b, now := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, Cooldown: time.Minute})
+	b.Record("a.com", true)
+	*now = now.Add(time.Minute)
+	if _, probe := b.Allow("a.com"); !probe {
+		t.Fatal("no probe after the cooldown")
+	}
+
+	// A probe that never went out lets the next request probe the host
+	b.Release("a.com")
+	if ok, probe := b.Allow("a.com"); !ok || !probe {
+		t.Fatalf("Allow() after Release = %v, %v, want a new probe", ok, probe)
+	}
+	if state := b.Record("a.com", false); state != CircuitClosed {
+		t.Fatalf("successful probe: Record() = %q, want closed", state)
+	}
+
+	// Releasing a closed circuit does nothing
+	b.Release("a.com")
+	if ok, probe := b.Allow("a.com"); !ok || probe {
+		t.Fatalf("Allow() = %v, %v on a closed circuit", ok, probe)
+	}
+}
+
+func TestBreakerDropsIdleCircuits(t *testing.T) {
+	b, now := newTestBreaker(config.CircuitBreakerConfig{Enabled: true, ErrorRate: 0.5, Window: 4, ConsecutiveFailures: 1, Cooldown: time.Minute})
+	b.Record("idle.com", false)
+	b.Record("open.com", true)
+
+	*now = now.Add(circuitIdle)
+	b.Record("busy.com", false)
+	if _, ok := b.hosts["idle.com"]; ok {
+		t.Fatal("idle closed circuit kept")
+	}
+	if _, ok := b.hosts["open.com"]; !ok {
+		t.Fatal("open circuit dropped")
+	}
+	if _, ok := b.hosts["busy.com"]; !ok {
+		t.Fatal("circuit of a host with a request dropped")
+	}
+}
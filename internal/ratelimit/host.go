@@ -0,0 +1,219 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// HostLimiter keeps one token bucket per host, using per-domain overrides
+// where configured and the default rate otherwise
+type HostLimiter struct {
+	rulesMu     sync.RWMutex
+	defaults    config.RateLimitRule
+	overrides   map[string]config.RateLimitRule
+	crawlDelays map[string]time.Duration // Crawl-delay from robots.txt, by host
+
+	mu      sync.RWMutex
+	buckets map[string]*TokenBucket
+}
+
+// NewHostLimiter creates a per-host limiter from the rate limit configuration
+func NewHostLimiter(cfg config.RateLimitsConfig) *HostLimiter {
+	return &HostLimiter{
+		defaults:    cfg.Default,
+		overrides:   lowerDomains(cfg.Domains),
+		crawlDelays: make(map[string]time.Duration),
+		buckets:     make(map[string]*TokenBucket),
+	}
+}
+
+// lowerDomains returns the per-domain rules keyed by lowercased domain
+func lowerDomains(domains map[string]config.RateLimitRule) map[string]config.RateLimitRule {
+	overrides := make(map[string]config.RateLimitRule, len(domains))
+	for domain, rule := range domains {
+		overrides[strings.ToLower(domain)] = rule
+	}
+	return overrides
+}
+
+// SetRules replaces the default and per-domain rules and applies them to the
+// buckets of hosts seen so far
+func (h *HostLimiter) SetRules(cfg config.RateLimitsConfig) {
+	h.rulesMu.Lock()
+	h.defaults = cfg.Default
+	h.overrides = lowerDomains(cfg.Domains)
+	h.rulesMu.Unlock()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for host, bucket := range h.buckets {
+		rule := h.RuleFor(host)
+		bucket.SetRate(rule.RequestsPerSecond)
+		bucket.SetBurst(rule.Burst)
+	}
+}
+
+// RuleFor returns the rule for host. An override for "example.com" also
+// applies to its subdomains; the most specific match wins. A Crawl-delay set
+// for the host lowers the rate to one request per delay.
+func (h *HostLimiter) RuleFor(host string) config.RateLimitRule {
+	h.rulesMu.RLock()
+	defer h.rulesMu.RUnlock()
+
+	rule := h.ruleFor(host)
+	if d := h.crawlDelays[host]; slows(d, rule) {
+		rule.RequestsPerSecond = float64(time.Second) / float64(d)
+		rule.Burst = 1
+	}
+	return rule
+}
+
+// slows reports whether a delay of d between requests is slower than rule
+func slows(d time.Duration, rule config.RateLimitRule) bool {
+	return d > 0 && (rule.RequestsPerSecond <= 0 || rule.RequestsPerSecond > float64(time.Second)/float64(d))
+}
+
+// describeRate formats a rate limit for the log
+func describeRate(rps float64) string {
+	if rps <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%.2f req/s", rps)
+}
+
+// ruleFor returns the configured rule for host. Caller must hold rulesMu.
+func (h *HostLimiter) ruleFor(host string) config.RateLimitRule {
+	host = strings.ToLower(host)
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	for domain := host; domain != ""; {
+		if rule, ok := h.overrides[domain]; ok {
+			return rule
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return h.defaults
+}
+
+// SetCrawlDelay makes requests to host at least d apart, as asked by its
+// robots.txt. The delay only ever slows a host down; 0 removes it. A rate
+// already lowered below the delay, e.g. by the adaptive limiter, is kept.
+// Changes to how fast the host is crawled are logged.
+func (h *HostLimiter) SetCrawlDelay(host string, d time.Duration) {
+	// Called for every page, so unchanged delays only take the read lock
+	h.rulesMu.RLock()
+	prev := h.crawlDelays[host]
+	h.rulesMu.RUnlock()
+	if prev == d {
+		return
+	}
+
+	bucket := h.Bucket(host)
+	h.rulesMu.Lock()
+	prev = h.crawlDelays[host]
+	if prev == d {
+		h.rulesMu.Unlock()
+		return
+	}
+	before := h.ruleFor(host)
+	if slows(prev, before) {
+		before.RequestsPerSecond = float64(time.Second) / float64(prev)
+	}
+	if d > 0 {
+		h.crawlDelays[host] = d
+	} else {
+		delete(h.crawlDelays, host)
+	}
+	configured := h.ruleFor(host)
+	h.rulesMu.Unlock()
+
+	// The rate in effect is kept if it was below the limit the old delay set
+	rule := h.RuleFor(host)
+	current := bucket.Rate()
+	rate := rule.RequestsPerSecond
+	if slower(current, before.RequestsPerSecond) && slower(current, rate) {
+		rate = current
+	}
+	bucket.SetRate(rate)
+	bucket.SetBurst(rule.Burst)
+
+	switch {
+	case slower(rate, current):
+		log.Info("robots.txt of %s asks for %s between requests, slowing it from %s to %s",
+			host, d, describeRate(current), describeRate(rate))
+	case !slower(current, rate):
+		// Unchanged, e.g. as the adaptive limiter already slowed the host more
+	case slows(d, configured):
+		log.Info("robots.txt of %s now asks for %s between requests, speeding it up from %s to %s",
+			host, d, describeRate(current), describeRate(rate))
+	default:
+		log.Info("robots.txt of %s no longer asks for more than its rate limit, back to %s",
+			host, describeRate(rate))
+	}
+}
+
+// slower reports whether rate a is slower than rate b, where <= 0 is unlimited
+func slower(a, b float64) bool {
+	return a > 0 && (b <= 0 || a < b)
+}
+
+// Bucket returns the token bucket for host, creating it on first use
+func (h *HostLimiter) Bucket(host string) *TokenBucket {
+	h.mu.RLock()
+	bucket, ok := h.buckets[host]
+	h.mu.RUnlock()
+	if ok {
+		return bucket
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if bucket, ok := h.buckets[host]; ok {
+		return bucket
+	}
+	rule := h.RuleFor(host)
+	bucket = NewTokenBucket(rule.RequestsPerSecond, rule.Burst)
+	h.buckets[host] = bucket
+	return bucket
+}
+
+// Wait blocks until a request to host is allowed or ctx is done
+func (h *HostLimiter) Wait(ctx context.Context, host string) error {
+	return h.Bucket(host).Wait(ctx)
+}
+
+// Reserve takes a token for a request to host and returns how long the
+// request must wait for it
+func (h *HostLimiter) Reserve(host string) time.Duration {
+	return h.Bucket(host).Reserve()
+}
+
+// Allow reports whether a request to host may be made right now
+func (h *HostLimiter) Allow(host string) bool {
+	return h.Bucket(host).Allow()
+}
+
+// Rates returns the current rate in requests per second for every known host
+func (h *HostLimiter) Rates() map[string]float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rates := make(map[string]float64, len(h.buckets))
+	for host, bucket := range h.buckets {
+		rates[host] = bucket.Rate()
+	}
+	return rates
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestHostLimiterRuleForSubdomains(t *testing.T) {
+	h := NewHostLimiter(config.RateLimitsConfig{
+		Default: config.RateLimitRule{RequestsPerSecond: 2, Burst: 4},
+		Domains: map[string]config.RateLimitRule{
+			"Example.com":     {RequestsPerSecond: 5, Burst: 5},
+			"api.example.com": {RequestsPerSecond: 1, Burst: 1},
+		},
+	})
+
+	tests := map[string]float64{
+		"example.com":         5,
+		"www.example.com:443": 5,
+		"api.example.com":     1,
+		"other.com":           2,
+	}
+	for host, want := range tests {
+		if got := h.RuleFor(host).RequestsPerSecond; got != want {
+			t.Errorf("RuleFor(%q) = %v rps, want %v", host, got, want)
+		}
+	}
+}
+
+func TestHostLimiterCrawlDelay(t *testing.T) {
+	h := NewHostLimiter(config.RateLimitsConfig{
+		Default: config.RateLimitRule{RequestsPerSecond: 10, Burst: 20},
+	})
+	h.Bucket("slow.com")
+	h.SetCrawlDelay("slow.com", 2*time.Second)
+
+	rule := h.RuleFor("slow.com")
+	if rule.RequestsPerSecond != 0.5 || rule.Burst != 1 {
+		t.Fatalf("RuleFor() = %+v, want 0.5 rps with burst 1", rule)
+	}
+	if rate := h.Bucket("slow.com").Rate(); rate != 0.5 {
+		t.Fatalf("bucket rate = %v, want 0.5", rate)
+	}
+	if rate := h.RuleFor("other.com").RequestsPerSecond; rate != 10 {
+		t.Fatalf("crawl delay leaked to another host: %v rps", rate)
+	}
+
+	// Reloading the rules keeps the delay
+	h.SetRules(config.RateLimitsConfig{Default: config.RateLimitRule{RequestsPerSecond: 50, Burst: 50}})
+	if rate := h.Bucket("slow.com").Rate(); rate != 0.5 {
+		t.Fatalf("bucket rate after reload = %v, want 0.5", rate)
+	}
+
+	// A delay never speeds a host up
+	h.SetCrawlDelay("slow.com", 10*time.Millisecond)
+	if rate := h.RuleFor("slow.com").RequestsPerSecond; rate != 50 {
+		t.Fatalf("short crawl delay raised rate to %v", rate)
+	}
+
+	h.SetCrawlDelay("slow.com", 0)
+	if rule := h.RuleFor("slow.com"); rule.RequestsPerSecond != 50 || rule.Burst != 50 {
+		t.Fatalf("RuleFor() after clearing = %+v", rule)
+	}
+}
+
+func TestHostLimiterCrawlDelayUnlimited(t *testing.T) {
+	h := NewHostLimiter(config.RateLimitsConfig{})
+	h.SetCrawlDelay("slow.com", 500*time.Millisecond)
+	if rate := h.Bucket("slow.com").Rate(); rate != 2 {
+		t.Fatalf("unlimited host with crawl delay runs at %v rps, want 2", rate)
+	}
+}
@@ -0,0 +1,290 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned when Redis replies with a nil bulk string or array
+var ErrNil = errors.New("redis: nil reply")
+
+// Error is an error reply sent by the Redis server
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// Options configures a Redis client
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	PoolSize int
+	Timeout  time.Duration
+}
+
+// conn is a single RESP connection
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+// Client is a minimal pooled Redis client speaking the RESP2 protocol
+type Client struct {
+	opts Options
+	pool chan *conn
+	mu   sync.Mutex
+	shut bool
+}
+
+// NewClient creates a Redis client and verifies the connection with PING
+func NewClient(opts Options) (*Client, error) {
+	if opts.Addr == "" {
+		opts.Addr = "localhost:6379"
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	c := &Client{
+		opts: opts,
+		pool: make(chan *conn, opts.PoolSize),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return c, nil
+}
+
+// dial opens a new connection and performs AUTH/SELECT
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: c.opts.Timeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{
+		netConn: nc,
+		reader:  bufio.NewReader(nc),
+		writer:  bufio.NewWriter(nc),
+	}
+
+	if c.opts.Password != "" {
+		if _, err := cn.do(ctx, c.opts.Timeout, "AUTH", c.opts.Password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do(ctx, c.opts.Timeout, "SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// get takes an idle connection from the pool or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.pool:
+		return cn, nil
+	default:
+		return c.dial(ctx)
+	}
+}
+
+// put returns a healthy connection to the pool
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.shut {
+		cn.netConn.Close()
+		return
+	}
+	select {
+	case c.pool <- cn:
+	default:
+		cn.netConn.Close()
+	}
+}
+
+// Do sends a command and returns the decoded reply: string, int64, []interface{}, or nil
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, c.opts.Timeout, args...)
+	var redisErr Error
+	if err != nil && !errors.As(err, &redisErr) && !errors.Is(err, ErrNil) {
+		// Network or protocol failure, the connection can't be reused
+		cn.netConn.Close()
+		return nil, err
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// Int runs a command that returns an integer reply
+func (c *Client) Int(ctx context.Context, args ...string) (int64, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected reply type %T", reply)
+	}
+	return n, nil
+}
+
+// String runs a command that returns a bulk or simple string reply
+func (c *Client) String(ctx context.Context, args ...string) (string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected reply type %T", reply)
+	}
+	return s, nil
+}
+
+// Strings runs a command that returns an array of strings
+func (c *Client) Strings(ctx context.Context, args ...string) ([]string, error) {
+	reply, err := c.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("redis: unexpected reply type %T", reply)
+	}
+
+	out := make([]string, 0, len(arr))
+	for _, v := range arr {
+		s, _ := v.(string)
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Close closes all pooled connections
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.shut = true
+	for {
+		select {
+		case cn := <-c.pool:
+			cn.netConn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do writes a command and reads its reply on this connection
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	cn.netConn.SetDeadline(deadline)
+
+	fmt.Fprintf(cn.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	return cn.readReply()
+}
+
+// readReply decodes one RESP value
+func (cn *conn) readReply() (interface{}, error) {
+	line, err := cn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: short reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		// An error element (e.g. from EXEC) must not stop the read, or the rest
+		// of the array is left on the pooled connection
+		arr := make([]interface{}, n)
+		var elemErr error
+		for i := range arr {
+			v, err := cn.readReply()
+			var redisErr Error
+			switch {
+			case err == nil, errors.Is(err, ErrNil):
+			case errors.As(err, &redisErr):
+				if elemErr == nil {
+					elemErr = err
+				}
+				v = redisErr
+			default:
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, elemErr
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
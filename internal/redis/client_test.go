@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer answers each command on a connection with the next scripted
+// reply, keyed by command name
+func fakeServer(t *testing.T, replies map[string]string) (addr string, conns *int64) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var accepted int64
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&accepted, 1)
+			go serveConn(nc, replies)
+		}
+	}()
+	return ln.Addr().String(), &accepted
+}
+
+// serveConn reads RESP commands from nc and writes the scripted replies
+func serveConn(nc net.Conn, replies map[string]string) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+		if err != nil {
+			return
+		}
+		var args []string
+		for i := 0; i < n; i++ {
+			r.ReadString('\n') // $len
+			arg, _ := r.ReadString('\n')
+			args = append(args, strings.TrimSuffix(arg, "\r\n"))
+		}
+		nc.Write([]byte(replies[strings.ToUpper(args[0])]))
+	}
+}
+
+func TestClientArrayWithErrorElement(t *testing.T) {
+	addr, conns := fakeServer(t, map[string]string{
+		"PING": "+PONG\r\n",
+		"EXEC": "*3\r\n:1\r\n-WRONGTYPE bad key\r\n$5\r\nhello\r\n",
+		"GET":  "$2\r\nok\r\n",
+	})
+	c, err := NewClient(Options{Addr: addr, PoolSize: 1, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	reply, err := c.Do(ctx, "EXEC")
+	var redisErr Error
+	if !errors.As(err, &redisErr) || !strings.Contains(string(redisErr), "WRONGTYPE") {
+		t.Fatalf("Do(EXEC) error = %v, want the WRONGTYPE element", err)
+	}
+	arr, ok := reply.([]interface{})
+	if !ok || len(arr) != 3 || arr[0] != int64(1) || arr[2] != "hello" {
+		t.Fatalf("Do(EXEC) = %#v", reply)
+	}
+
+	// The pooled connection must be clean for the next command
+	s, err := c.String(ctx, "GET", "key")
+	if err != nil || s != "ok" {
+		t.Fatalf("String(GET) = %q, %v after an error element", s, err)
+	}
+	if n := atomic.LoadInt64(conns); n != 1 {
+		t.Fatalf("client opened %d connections, want 1", n)
+	}
+}
+
+func TestClientNilReplies(t *testing.T) {
+	addr, _ := fakeServer(t, map[string]string{
+		"PING":  "+PONG\r\n",
+		"GET":   "$-1\r\n",
+		"MGET":  "*2\r\n$-1\r\n$1\r\nb\r\n",
+		"INCR":  ":42\r\n",
+		"BLPOP": "*-1\r\n",
+	})
+	c, err := NewClient(Options{Addr: addr, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.String(ctx, "GET", "missing"); !errors.Is(err, ErrNil) {
+		t.Fatalf("GET of a missing key = %v, want ErrNil", err)
+	}
+	if got, err := c.Strings(ctx, "MGET", "a", "b"); err != nil || len(got) != 2 || got[0] != "" || got[1] != "b" {
+		t.Fatalf("Strings(MGET) = %q, %v", got, err)
+	}
+	if n, err := c.Int(ctx, "INCR", "n"); err != nil || n != 42 {
+		t.Fatalf("Int(INCR) = %d, %v", n, err)
+	}
+	if _, err := c.Do(ctx, "BLPOP", "q", "1"); !errors.Is(err, ErrNil) {
+		t.Fatalf("BLPOP timeout = %v, want ErrNil", err)
+	}
+}
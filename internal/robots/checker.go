@@ -0,0 +1,189 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the robots package
+var log = logger.For("robots")
+
+// unreachableTTL is how long a DisallowAll result is cached after a failed fetch
+const unreachableTTL = 5 * time.Minute
+
+// cacheEntry holds the rules for one host and signals when they are ready
+type cacheEntry struct {
+	rules     *Rules
+	expiresAt time.Time
+	done      bool
+	ready     chan struct{}
+}
+
+// Checker downloads, caches, and evaluates robots.txt per host
+type Checker struct {
+	client     *http.Client
+	userAgent  string
+	ignore     bool
+	ignoreMeta bool
+	cacheTTL   time.Duration
+	maxSize    int64
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+
+	// Counters
+	fetches    int64
+	fetchFails int64
+	allowed    int64
+	blocked    int64
+}
+
+// NewChecker creates a robots.txt checker that fetches files with the given client
+func NewChecker(client *http.Client, cfg config.RobotsConfig, userAgent string) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Checker{
+		client:     client,
+		userAgent:  userAgent,
+		ignore:     cfg.Ignore,
+		ignoreMeta: cfg.IgnoreMeta,
+		cacheTTL:   cfg.CacheTTL,
+		maxSize:    cfg.MaxSize,
+		cache:      make(map[string]*cacheEntry),
+	}
+}
+
+// Allowed reports whether rawURL may be crawled according to its host's robots.txt
+func (c *Checker) Allowed(ctx context.Context, rawURL string) bool {
+	if c.ignore {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	rules := c.Rules(ctx, u)
+	if rules.Allowed(u.RequestURI()) {
+		atomic.AddInt64(&c.allowed, 1)
+		return true
+	}
+	atomic.AddInt64(&c.blocked, 1)
+	return false
+}
+
+// CrawlDelay returns the delay rawURL's host asks for between requests by
+// Crawl-delay or Request-rate, or zero if none applies
+func (c *Checker) CrawlDelay(ctx context.Context, rawURL string) time.Duration {
+	if c.ignore {
+		return 0
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return 0
+	}
+	return c.Rules(ctx, u).Delay()
+}
+
+// Rules returns the cached rules for the URL's host, fetching robots.txt if needed
+func (c *Checker) Rules(ctx context.Context, u *url.URL) *Rules {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	if ok && entry.done && time.Now().After(entry.expiresAt) {
+		ok = false
+	}
+	if !ok {
+		entry = &cacheEntry{ready: make(chan struct{})}
+		c.cache[key] = entry
+		c.mu.Unlock()
+
+		rules, ttl := c.fetch(ctx, key)
+		c.mu.Lock()
+		entry.rules = rules
+		entry.expiresAt = time.Now().Add(ttl)
+		entry.done = true
+		c.mu.Unlock()
+		close(entry.ready)
+		return rules
+	}
+	c.mu.Unlock()
+
+	// Another goroutine may still be fetching this host's robots.txt
+	select {
+	case <-entry.ready:
+		return entry.rules
+	case <-ctx.Done():
+		return AllowAll
+	}
+}
+
+// fetch downloads and parses robots.txt for a scheme://host origin
+func (c *Checker) fetch(ctx context.Context, origin string) (*Rules, time.Duration) {
+	atomic.AddInt64(&c.fetches, 1)
+
+	rules, err := c.download(ctx, origin)
+	if err != nil {
+		atomic.AddInt64(&c.fetchFails, 1)
+		log.Warn("Failed to fetch robots.txt for %s: %v", origin, err)
+		return DisallowAll, unreachableTTL
+	}
+	return rules, c.cacheTTL
+}
+
+// download performs the HTTP request and maps the status code to rules
+func (c *Checker) download(ctx context.Context, origin string) (*Rules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		var body io.Reader = resp.Body
+		if c.maxSize > 0 {
+			body = io.LimitReader(resp.Body, c.maxSize)
+		}
+		return Parse(body, c.userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// A missing robots.txt means there are no restrictions
+		return AllowAll, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// GetStats returns robots.txt statistics for monitoring
+func (c *Checker) GetStats() map[string]int64 {
+	c.mu.Lock()
+	hosts := int64(len(c.cache))
+	c.mu.Unlock()
+
+	return map[string]int64{
+		"hosts":      hosts,
+		"fetches":    atomic.LoadInt64(&c.fetches),
+		"fetchFails": atomic.LoadInt64(&c.fetchFails),
+		"allowed":    atomic.LoadInt64(&c.allowed),
+		"blocked":    atomic.LoadInt64(&c.blocked),
+	}
+}
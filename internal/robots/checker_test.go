@@ -0,0 +1,85 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestCheckerAllowedAndCrawlDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nCrawl-delay: 1.5\n")
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewChecker(server.Client(), config.RobotsConfig{CacheTTL: time.Hour, MaxSize: 1 << 20}, "test-bot")
+
+	if !c.Allowed(ctx, server.URL+"/public") {
+		t.Fatal("public page was blocked")
+	}
+	if c.Allowed(ctx, server.URL+"/private/page") {
+		t.Fatal("disallowed page was allowed")
+	}
+	if d := c.CrawlDelay(ctx, server.URL+"/public"); d != 1500*time.Millisecond {
+		t.Fatalf("CrawlDelay() = %v, want 1.5s", d)
+	}
+	if stats := c.GetStats(); stats["fetches"] != 1 {
+		t.Fatalf("robots.txt fetched %d times, want once", stats["fetches"])
+	}
+}
+
+func TestCheckerIgnore(t *testing.T) {
+	c := NewChecker(nil, config.RobotsConfig{Ignore: true}, "test-bot")
+	if !c.Allowed(context.Background(), "http://127.0.0.1:1/private") {
+		t.Fatal("ignored robots.txt blocked a page")
+	}
+	if d := c.CrawlDelay(context.Background(), "http://127.0.0.1:1/"); d != 0 {
+		t.Fatalf("ignored robots.txt has crawl delay %v", d)
+	}
+}
+
+func TestRequestRate(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"1/5":           5 * time.Second,
+		"1/10s":         10 * time.Second,
+		"30/1m":         2 * time.Second,
+		"1/1h":          time.Hour,
+		"2/3 0800-1700": 1500 * time.Millisecond,
+		"1":             0,
+		"0/5":           0,
+		"1/x":           0,
+		"1/0":           0,
+		"":              0,
+	} {
+		if got := parseRequestRate(value); got != want {
+			t.Errorf("parseRequestRate(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	robots := `User-agent: *
+Crawl-delay: 2
+
+User-agent: test-bot
+Crawl-delay: 1
+Request-rate: 1/10 0000-0600
+Request-rate: 1/5
+`
+	r := Parse(strings.NewReader(robots), "Test-Bot/1.0")
+	if r.CrawlDelay() != time.Second || r.RequestRate() != 10*time.Second || r.Delay() != 10*time.Second {
+		t.Errorf("test-bot: crawl delay %v, request rate %v, delay %v", r.CrawlDelay(), r.RequestRate(), r.Delay())
+	}
+	if r := Parse(strings.NewReader(robots), "other"); r.Delay() != 2*time.Second {
+		t.Errorf("other agent: delay %v, want the crawl delay of *", r.Delay())
+	}
+}
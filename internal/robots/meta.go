@@ -0,0 +1,112 @@
+package robots
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Directives are the page-level indexing rules from robots meta tags and X-Robots-Tag
+type Directives struct {
+	NoIndex  bool // Don't store the page
+	NoFollow bool // Don't extract links from the page
+}
+
+// merge applies a comma-separated directive list
+func (d *Directives) merge(list string) {
+	for _, token := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(token)) {
+		case "noindex":
+			d.NoIndex = true
+		case "nofollow":
+			d.NoFollow = true
+		case "none":
+			d.NoIndex = true
+			d.NoFollow = true
+		}
+	}
+}
+
+// productToken returns the lowercase product name of a user agent string
+func productToken(userAgent string) string {
+	product := strings.ToLower(userAgent)
+	if i := strings.IndexAny(product, "/ "); i >= 0 {
+		product = product[:i]
+	}
+	return product
+}
+
+// ParseHeader reads X-Robots-Tag header values. Values may be scoped to a
+// user agent ("otherbot: noindex"), which only apply if they name this crawler.
+func ParseHeader(header http.Header, userAgent string) Directives {
+	var d Directives
+	product := productToken(userAgent)
+
+	for _, value := range header.Values("X-Robots-Tag") {
+		if agent, rest, ok := strings.Cut(value, ":"); ok && !strings.Contains(agent, ",") {
+			agent = strings.ToLower(strings.TrimSpace(agent))
+			// "unavailable_after: <date>" is a directive, not a user agent
+			if agent != "unavailable_after" {
+				if agent == product {
+					d.merge(rest)
+				}
+				continue
+			}
+		}
+		d.merge(value)
+	}
+	return d
+}
+
+// ParseMeta reads <meta name="robots"> tags and tags named after this crawler from the document head
+func ParseMeta(content, userAgent string) Directives {
+	var d Directives
+	product := productToken(userAgent)
+
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return d
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				// Robots meta tags only appear in the head
+				return d
+			case "meta":
+				var name, value string
+				for _, attr := range token.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "name":
+						name = strings.ToLower(strings.TrimSpace(attr.Val))
+					case "content":
+						value = attr.Val
+					}
+				}
+				if name == "robots" || (product != "" && name == product) {
+					d.merge(value)
+				}
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				return d
+			}
+		}
+	}
+}
+
+// PageDirectives combines the X-Robots-Tag header and robots meta tags for a page.
+// Returns no restrictions when robots handling is ignored or meta handling is overridden.
+func (c *Checker) PageDirectives(header http.Header, content string) Directives {
+	if c.ignore || c.ignoreMeta {
+		return Directives{}
+	}
+
+	d := ParseHeader(header, c.userAgent)
+	meta := ParseMeta(content, c.userAgent)
+	d.NoIndex = d.NoIndex || meta.NoIndex
+	d.NoFollow = d.NoFollow || meta.NoFollow
+	return d
+}
@@ -0,0 +1,82 @@
+package robots
+
+import (
+	"net/http"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+const testAgent = "TestBot/1.0 (+https://example.com/bot)"
+
+func TestParseMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Directives
+	}{
+		{"no tags", `<html><head><title>Hi</title></head></html>`, Directives{}},
+		{"noindex", `<meta name="robots" content="noindex">`, Directives{NoIndex: true}},
+		{"nofollow", `<head><meta name="robots" content="index, nofollow"></head>`, Directives{NoFollow: true}},
+		{"none", `<meta name="ROBOTS" content="None">`, Directives{NoIndex: true, NoFollow: true}},
+		{"list", `<meta content=" NOINDEX ,NoFollow" name=" robots "/>`, Directives{NoIndex: true, NoFollow: true}},
+		{"several tags", `<meta name="robots" content="noindex"><meta name="robots" content="nofollow">`, Directives{NoIndex: true, NoFollow: true}},
+		{"this crawler", `<meta name="testbot" content="noindex">`, Directives{NoIndex: true}},
+		{"other crawler", `<meta name="googlebot" content="noindex, nofollow">`, Directives{}},
+		{"other meta", `<meta name="description" content="noindex">`, Directives{}},
+		{"tag in the body", `<head></head><body><meta name="robots" content="noindex"></body>`, Directives{}},
+		{"tag after the head", `<head><title>Hi</title></head><meta name="robots" content="noindex">`, Directives{}},
+		{"tag before the body", `<title>Hi</title><meta name="robots" content="nofollow"><body><meta name="robots" content="noindex">`, Directives{NoFollow: true}},
+	}
+	for _, tt := range tests {
+		if got := ParseMeta(tt.content, testAgent); got != tt.want {
+			t.Errorf("%s: ParseMeta() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   Directives
+	}{
+		{"no header", nil, Directives{}},
+		{"noindex", []string{"noindex"}, Directives{NoIndex: true}},
+		{"list", []string{"NoIndex, nofollow"}, Directives{NoIndex: true, NoFollow: true}},
+		{"none", []string{"none"}, Directives{NoIndex: true, NoFollow: true}},
+		{"multiple headers", []string{"noindex", "nofollow"}, Directives{NoIndex: true, NoFollow: true}},
+		{"this crawler", []string{"testbot: nofollow"}, Directives{NoFollow: true}},
+		{"this crawler in capitals", []string{"TestBot: none"}, Directives{NoIndex: true, NoFollow: true}},
+		{"other crawler", []string{"googlebot: noindex"}, Directives{}},
+		{"scoped and unscoped", []string{"googlebot: nofollow", "noindex"}, Directives{NoIndex: true}},
+		{"unavailable after", []string{"unavailable_after: 25 Jun 2030 15:00:00 PST"}, Directives{}},
+		{"list with a date", []string{"noindex, unavailable_after: 25 Jun 2030"}, Directives{NoIndex: true}},
+		{"unknown directives", []string{"noarchive, nosnippet"}, Directives{}},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		for _, v := range tt.values {
+			header.Add("X-Robots-Tag", v)
+		}
+		if got := ParseHeader(header, testAgent); got != tt.want {
+			t.Errorf("%s: ParseHeader() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPageDirectives(t *testing.T) {
+	header := http.Header{"X-Robots-Tag": {"nofollow"}}
+	content := `<meta name="robots" content="noindex">`
+
+	c := NewChecker(nil, config.RobotsConfig{}, testAgent)
+	if got := c.PageDirectives(header, content); got != (Directives{NoIndex: true, NoFollow: true}) {
+		t.Errorf("PageDirectives() = %+v, want the header and meta tag combined", got)
+	}
+	for _, cfg := range []config.RobotsConfig{{Ignore: true}, {IgnoreMeta: true}} {
+		c := NewChecker(nil, cfg, testAgent)
+		if got := c.PageDirectives(header, content); got != (Directives{}) {
+			t.Errorf("PageDirectives() with %+v = %+v, want none", cfg, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is a single Allow or Disallow directive
+type rule struct {
+	pattern string
+	allow   bool
+}
+
+// group holds the directives that apply to a set of user agents
+type group struct {
+	agents      []string
+	rules       []rule
+	crawlDelay  time.Duration
+	requestRate time.Duration // Interval between requests
+}
+
+// Rules represents the parsed robots.txt directives for one user agent
+type Rules struct {
+	rules       []rule
+	crawlDelay  time.Duration
+	requestRate time.Duration
+	Sitemaps    []string
+}
+
+// AllowAll is used when a host has no robots.txt (4xx responses)
+var AllowAll = &Rules{}
+
+// DisallowAll is used while robots.txt is unreachable (5xx or network errors)
+var DisallowAll = &Rules{rules: []rule{{pattern: "/", allow: false}}}
+
+// Parse reads a robots.txt body and returns the rules for the given user agent
+func Parse(r io.Reader, userAgent string) *Rules {
+	var groups []*group
+	var current *group
+	var sitemaps []string
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 512*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share one group
+			if current == nil || !lastWasAgent {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil {
+				// An empty Disallow means everything is allowed
+				if value != "" {
+					current.rules = append(current.rules, rule{pattern: value, allow: key == "allow"})
+				}
+			}
+		case "crawl-delay":
+			if current != nil {
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					current.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		case "request-rate":
+			// Of several rates, e.g. for different times of day, the slowest applies
+			if current != nil {
+				current.requestRate = max(current.requestRate, parseRequestRate(value))
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+		lastWasAgent = false
+	}
+
+	rules := &Rules{Sitemaps: sitemaps}
+	if g := matchGroup(groups, userAgent); g != nil {
+		rules.rules = g.rules
+		rules.crawlDelay = g.crawlDelay
+		rules.requestRate = g.requestRate
+	}
+	return rules
+}
+
+// parseRequestRate returns the interval between requests of a Request-rate
+// value: requests per period, e.g. "1/5" (seconds), "1/10s", "30/1m" or
+// "1/1h". A time window after the rate, as in "1/5 0800-1700", is ignored,
+// so the rate applies all day. It returns 0 for malformed values.
+func parseRequestRate(value string) time.Duration {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	count, period, ok := strings.Cut(fields[0], "/")
+	if !ok {
+		return 0
+	}
+	requests, err := strconv.ParseFloat(count, 64)
+	if err != nil || requests <= 0 {
+		return 0
+	}
+
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(period, "s"):
+		period = strings.TrimSuffix(period, "s")
+	case strings.HasSuffix(period, "m"):
+		period, unit = strings.TrimSuffix(period, "m"), time.Minute
+	case strings.HasSuffix(period, "h"):
+		period, unit = strings.TrimSuffix(period, "h"), time.Hour
+	}
+	n, err := strconv.ParseFloat(period, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return time.Duration(n * float64(unit) / requests)
+}
+
+// matchGroup selects the group with the most specific user-agent match, falling back to "*"
+func matchGroup(groups []*group, userAgent string) *group {
+	product := strings.ToLower(userAgent)
+	if i := strings.IndexAny(product, "/ "); i >= 0 {
+		product = product[:i]
+	}
+
+	var best, wildcard *group
+	bestLen := 0
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+				continue
+			}
+			if agent != "" && strings.Contains(product, agent) && len(agent) > bestLen {
+				best = g
+				bestLen = len(agent)
+			}
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+	return wildcard
+}
+
+// Allowed reports whether the given path (including query) may be crawled.
+// The longest matching rule wins and Allow wins ties, as in RFC 9309.
+func (r *Rules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	matchLen := -1
+	for _, rl := range r.rules {
+		if !matchPattern(rl.pattern, path) {
+			continue
+		}
+		n := len(rl.pattern)
+		if n > matchLen || (n == matchLen && rl.allow) {
+			allowed = rl.allow
+			matchLen = n
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay directive, or zero if none was given
+func (r *Rules) CrawlDelay() time.Duration {
+	return r.crawlDelay
+}
+
+// RequestRate returns the interval between requests of the Request-rate
+// directive, or zero if none was given
+func (r *Rules) RequestRate() time.Duration {
+	return r.requestRate
+}
+
+// Delay returns the time to leave between requests: the longer of the
+// Crawl-delay and the Request-rate interval
+func (r *Rules) Delay() time.Duration {
+	return max(r.crawlDelay, r.requestRate)
+}
+
+// matchPattern matches a robots.txt path pattern supporting "*" and a trailing "$"
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			// The last segment must match the end of the path
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
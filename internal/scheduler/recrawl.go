@@ -0,0 +1,245 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+)
+
+// log is the logger of the scheduler package
+var log = logger.For("scheduler")
+
+// Interval multipliers applied after each visit
+const (
+	changedFactor   = 0.5 // Content changed: revisit sooner
+	unchangedFactor = 1.5 // Content unchanged: revisit later
+)
+
+// recrawlEntry tracks the refresh schedule of one URL
+type recrawlEntry struct {
+	url      string
+	host     string
+	depth    int
+	hash     string
+	interval time.Duration
+	nextDue  time.Time
+	visits   int
+	changes  int
+	index    int // Position in the heap, -1 when not scheduled
+}
+
+// dueHeap orders entries by their next due time
+type dueHeap []*recrawlEntry
+
+func (h dueHeap) Len() int           { return len(h) }
+func (h dueHeap) Less(i, j int) bool { return h[i].nextDue.Before(h[j].nextDue) }
+func (h dueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *dueHeap) Push(x interface{}) {
+	e := x.(*recrawlEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *dueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// Recrawler re-queues previously crawled URLs when their refresh interval
+// elapses. Intervals adapt to how often a page's content hash changes.
+type Recrawler struct {
+	cfg   config.RecrawlConfig
+	queue queue.URLQueue
+
+	mu      sync.Mutex
+	entries map[string]*recrawlEntry
+	due     dueHeap
+
+	// Counters
+	requeued  int64
+	changed   int64
+	unchanged int64
+}
+
+// NewRecrawler creates a recrawl scheduler pushing due URLs onto q
+func NewRecrawler(cfg config.RecrawlConfig, q queue.URLQueue) *Recrawler {
+	return &Recrawler{
+		cfg:     cfg,
+		queue:   q,
+		entries: make(map[string]*recrawlEntry),
+	}
+}
+
+// baseInterval returns the configured interval for host, using the most specific domain override
+func (r *Recrawler) baseInterval(host string) time.Duration {
+	host = strings.ToLower(host)
+	for domain := host; domain != ""; {
+		if interval, ok := r.cfg.Domains[domain]; ok {
+			return interval
+		}
+		i := strings.Index(domain, ".")
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return r.cfg.Interval
+}
+
+// clamp keeps an interval within the configured bounds
+func (r *Recrawler) clamp(d time.Duration) time.Duration {
+	if r.cfg.MinInterval > 0 && d < r.cfg.MinInterval {
+		return r.cfg.MinInterval
+	}
+	if r.cfg.MaxInterval > 0 && d > r.cfg.MaxInterval {
+		return r.cfg.MaxInterval
+	}
+	return d
+}
+
+// Record registers a completed crawl of url with the hash of its content and
+// schedules the next visit
+func (r *Recrawler) Record(url, host string, depth int, contentHash string, crawledAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[url]
+	if !ok {
+		e = &recrawlEntry{
+			url:      url,
+			host:     host,
+			depth:    depth,
+			interval: r.clamp(r.baseInterval(host)),
+			index:    -1,
+		}
+		r.entries[url] = e
+	} else if r.cfg.Adaptive {
+		if e.hash != contentHash {
+			e.changes++
+			atomic.AddInt64(&r.changed, 1)
+			e.interval = r.clamp(time.Duration(float64(e.interval) * changedFactor))
+		} else {
+			atomic.AddInt64(&r.unchanged, 1)
+			e.interval = r.clamp(time.Duration(float64(e.interval) * unchangedFactor))
+		}
+	}
+
+	e.hash = contentHash
+	e.visits++
+	e.nextDue = crawledAt.Add(e.interval)
+
+	if e.index >= 0 {
+		heap.Fix(&r.due, e.index)
+	} else {
+		heap.Push(&r.due, e)
+	}
+}
+
+// Forget stops refreshing url
+func (r *Recrawler) Forget(url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, ok := r.entries[url]; ok {
+		if e.index >= 0 {
+			heap.Remove(&r.due, e.index)
+		}
+		delete(r.entries, url)
+	}
+}
+
+// requeueDue pushes every URL whose next visit is due and returns how many were queued.
+// Entries stay unscheduled until Record is called again for the new crawl.
+func (r *Recrawler) requeueDue(now time.Time) int {
+	r.mu.Lock()
+	var due []*recrawlEntry
+	for r.due.Len() > 0 && !r.due[0].nextDue.After(now) {
+		due = append(due, heap.Pop(&r.due).(*recrawlEntry))
+	}
+	r.mu.Unlock()
+
+	for _, e := range due {
+		r.queue.PushWithPriority(e.url, queue.PriorityLow, e.host, e.depth)
+	}
+	atomic.AddInt64(&r.requeued, int64(len(due)))
+	return len(due)
+}
+
+// Run checks for due URLs every CheckInterval until ctx is cancelled
+func (r *Recrawler) Run(ctx context.Context) {
+	interval := r.cfg.CheckInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := r.requeueDue(now); n > 0 {
+				log.Info("Recrawl scheduler re-queued %d URLs", n)
+			}
+		}
+	}
+}
+
+// NextDue returns when url will next be re-queued
+func (r *Recrawler) NextDue(url string) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[url]
+	if !ok || e.index < 0 {
+		return time.Time{}, false
+	}
+	return e.nextDue, true
+}
+
+// Hash returns the content hash recorded on the last crawl of url
+func (r *Recrawler) Hash(url string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.entries[url]
+	if !ok {
+		return "", false
+	}
+	return e.hash, true
+}
+
+// GetStats returns recrawl statistics for monitoring
+func (r *Recrawler) GetStats() map[string]int64 {
+	r.mu.Lock()
+	tracked := int64(len(r.entries))
+	scheduled := int64(r.due.Len())
+	r.mu.Unlock()
+
+	return map[string]int64{
+		"tracked":   tracked,
+		"scheduled": scheduled,
+		"requeued":  atomic.LoadInt64(&r.requeued),
+		"changed":   atomic.LoadInt64(&r.changed),
+		"unchanged": atomic.LoadInt64(&r.unchanged),
+	}
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/queue"
+)
+
+func TestBaseIntervalUsesMostSpecificDomain(t *testing.T) {
+	r := NewRecrawler(config.RecrawlConfig{
+		Interval: 24 * time.Hour,
+		Domains: map[string]time.Duration{
+			"example.com":      time.Hour,
+			"news.example.com": time.Minute,
+		},
+	}, queue.NewURLQueue())
+
+	tests := map[string]time.Duration{
+		"news.example.com":   time.Minute,
+		"a.news.example.com": time.Minute,
+		"WWW.Example.com":    time.Hour,
+		"example.org":        24 * time.Hour,
+		"notexample.com":     24 * time.Hour,
+	}
+	for host, want := range tests {
+		if got := r.baseInterval(host); got != want {
+			t.Errorf("baseInterval(%q) = %s, want %s", host, got, want)
+		}
+	}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	r := NewRecrawler(config.RecrawlConfig{
+		Interval:    time.Hour,
+		Adaptive:    true,
+		MinInterval: 20 * time.Minute,
+		MaxInterval: 2 * time.Hour,
+	}, queue.NewURLQueue())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	url := "https://example.com/"
+
+	steps := []struct {
+		hash string
+		want time.Duration
+	}{
+		{"a", time.Hour},
+		{"b", 30 * time.Minute}, // Changed
+		{"c", 20 * time.Minute}, // Changed, held at the minimum
+		{"c", 30 * time.Minute}, // Unchanged
+		{"c", 45 * time.Minute},
+		{"c", 67*time.Minute + 30*time.Second},
+		{"c", 101*time.Minute + 15*time.Second},
+		{"c", 2 * time.Hour}, // Held at the maximum
+	}
+	for i, step := range steps {
+		r.Record(url, "example.com", 1, step.hash, start)
+		due, ok := r.NextDue(url)
+		if !ok || due.Sub(start) != step.want {
+			t.Fatalf("visit %d: next due in %s, want %s", i+1, due.Sub(start), step.want)
+		}
+	}
+	if stats := r.GetStats(); stats["changed"] != 2 || stats["unchanged"] != 5 || stats["tracked"] != 1 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+	if hash, _ := r.Hash(url); hash != "c" {
+		t.Fatalf("Hash() = %q", hash)
+	}
+}
+
+func TestRequeueDue(t *testing.T) {
+	q := queue.NewURLQueue()
+	r := NewRecrawler(config.RecrawlConfig{Interval: time.Hour}, q)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r.Record("https://a.com/", "a.com", 2, "x", start)
+	r.Record("https://b.com/", "b.com", 0, "y", start.Add(time.Hour))
+	r.Record("https://c.com/", "c.com", 0, "z", start)
+	r.Forget("https://c.com/")
+
+	if n := r.requeueDue(start.Add(time.Hour)); n != 1 {
+		t.Fatalf("requeueDue() = %d, want 1", n)
+	}
+	item, ok := q.Pop()
+	if !ok || item.URL != "https://a.com/" || item.Host != "a.com" || item.Depth != 2 {
+		t.Fatalf("queued %+v", item)
+	}
+	// The URL waits for its next crawl before being scheduled again
+	if _, ok := r.NextDue("https://a.com/"); ok {
+		t.Fatal("re-queued URL is still scheduled")
+	}
+	if n := r.requeueDue(start.Add(3 * time.Hour)); n != 1 {
+		t.Fatalf("requeueDue() = %d, want 1", n)
+	}
+	if stats := r.GetStats(); stats["requeued"] != 2 || stats["scheduled"] != 0 || stats["tracked"] != 2 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestRunRequeuesOnTick(t *testing.T) {
+	q := queue.NewURLQueue()
+	r := NewRecrawler(config.RecrawlConfig{Interval: time.Millisecond, CheckInterval: 5 * time.Millisecond}, q)
+	r.Record("https://a.com/", "a.com", 0, "x", time.Now())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for q.Size() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if q.Size() != 1 {
+		t.Fatalf("queue has %d items, want 1", q.Size())
+	}
+}
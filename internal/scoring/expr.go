@@ -0,0 +1,525 @@
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Types of expression values
+type kind byte
+
+const (
+	kindNumber kind = 'n'
+	kindString kind = 's'
+	kindBool   kind = 'b'
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	}
+	return "bool"
+}
+
+// node is a compiled expression. Types are checked while compiling, so only
+// the function of its kind is set and evaluation can't fail.
+type node struct {
+	kind kind
+	num  func(f *Features) float64
+	str  func(f *Features) string
+	bool func(f *Features) bool
+}
+
+func number(fn func(f *Features) float64) node { return node{kind: kindNumber, num: fn} }
+func text(fn func(f *Features) string) node    { return node{kind: kindString, str: fn} }
+func boolean(fn func(f *Features) bool) node   { return node{kind: kindBool, bool: fn} }
+
+// variables are the URL features an expression can read
+var variables = map[string]node{
+	"url":      text(func(f *Features) string { return f.URL }),
+	"host":     text(func(f *Features) string { return f.Host }),
+	"path":     text(func(f *Features) string { return f.Path }),
+	"query":    text(func(f *Features) string { return f.Query }),
+	"ext":      text(func(f *Features) string { return f.Ext }),
+	"depth":    number(func(f *Features) float64 { return float64(f.Depth) }),
+	"segments": number(func(f *Features) float64 { return float64(f.Segments) }),
+	"params":   number(func(f *Features) float64 { return float64(len(f.Params)) }),
+	"inlinks":  number(func(f *Features) float64 { return float64(f.Inlinks) }),
+	"score":    number(func(f *Features) float64 { return f.Score }),
+	"true":     boolean(func(*Features) bool { return true }),
+	"false":    boolean(func(*Features) bool { return false }),
+}
+
+// Lexer
+
+type token struct {
+	kind byte // 'n' name, 's' string, '#' number, else the operator's first byte
+	text string
+	pos  int
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{'s', s[i+1 : i+1+end], i})
+			i += end + 2
+		case '0' <= c && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && ('0' <= s[j] && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{'#', s[i:j], i})
+			i = j
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			j := i
+			for j < len(s) && (s[j] == '_' || 'a' <= s[j] && s[j] <= 'z' || 'A' <= s[j] && s[j] <= 'Z' || '0' <= s[j] && s[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{'n', s[i:j], i})
+			i = j
+		default:
+			op := operator(s[i:])
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{op[0], op, i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// operator returns the operator at the start of s, empty if there is none
+func operator(s string) string {
+	for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "+", "-", "*", "/", "%", "!", "?", ":", "(", ")", ","} {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.eof() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+// accept consumes the next token if it's one of ops
+func (p *parser) accept(ops ...string) (string, bool) {
+	if p.eof() || p.peek().kind == 's' {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.peek().text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		return p.unexpected("expected " + op)
+	}
+	return nil
+}
+
+// unexpected returns an error about the next token
+func (p *parser) unexpected(what string) error {
+	if p.eof() {
+		return fmt.Errorf("%s at end of expression", what)
+	}
+	return fmt.Errorf("%s, found %q at %d", what, p.peek().text, p.peek().pos)
+}
+
+// expr parses cond ? a : b, the lowest precedence
+func (p *parser) expr() (node, error) {
+	cond, err := p.or()
+	if err != nil {
+		return node{}, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	a, err := p.expr()
+	if err != nil {
+		return node{}, err
+	}
+	if err := p.expect(":"); err != nil {
+		return node{}, err
+	}
+	b, err := p.expr()
+	if err != nil {
+		return node{}, err
+	}
+	if cond.kind != kindBool {
+		return node{}, fmt.Errorf("condition of ?: is a %s, not a bool", cond.kind)
+	}
+	if a.kind != b.kind {
+		return node{}, fmt.Errorf("branches of ?: are a %s and a %s", a.kind, b.kind)
+	}
+	c := cond.bool
+	switch a.kind {
+	case kindNumber:
+		return number(func(f *Features) float64 {
+			if c(f) {
+				return a.num(f)
+			}
+			return b.num(f)
+		}), nil
+	case kindString:
+		return text(func(f *Features) string {
+			if c(f) {
+				return a.str(f)
+			}
+			return b.str(f)
+		}), nil
+	}
+	return boolean(func(f *Features) bool {
+		if c(f) {
+			return a.bool(f)
+		}
+		return b.bool(f)
+	}), nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil {
+		if _, ok := p.accept("||"); !ok {
+			break
+		}
+		var right node
+		if right, err = p.and(); err == nil {
+			left, err = logical("||", left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.comparison()
+	for err == nil {
+		if _, ok := p.accept("&&"); !ok {
+			break
+		}
+		var right node
+		if right, err = p.comparison(); err == nil {
+			left, err = logical("&&", left, right)
+		}
+	}
+	return left, err
+}
+
+func logical(op string, left, right node) (node, error) {
+	if left.kind != kindBool || right.kind != kindBool {
+		return node{}, fmt.Errorf("%s needs bools, not a %s and a %s", op, left.kind, right.kind)
+	}
+	l, r := left.bool, right.bool
+	if op == "&&" {
+		return boolean(func(f *Features) bool { return l(f) && r(f) }), nil
+	}
+	return boolean(func(f *Features) bool { return l(f) || r(f) }), nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return node{}, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.sum()
+	if err != nil {
+		return node{}, err
+	}
+	if left.kind != right.kind {
+		return node{}, fmt.Errorf("%s compares a %s with a %s", op, left.kind, right.kind)
+	}
+	switch left.kind {
+	case kindNumber:
+		l, r := left.num, right.num
+		return boolean(func(f *Features) bool { return compare(op, l(f), r(f)) }), nil
+	case kindString:
+		l, r := left.str, right.str
+		return boolean(func(f *Features) bool { return compare(op, l(f), r(f)) }), nil
+	}
+	if op != "==" && op != "!=" {
+		return node{}, fmt.Errorf("%s can't compare bools", op)
+	}
+	l, r := left.bool, right.bool
+	return boolean(func(f *Features) bool { return (l(f) == r(f)) == (op == "==") }), nil
+}
+
+func compare[T float64 | string](op string, a, b T) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+func (p *parser) sum() (node, error) {
+	left, err := p.product()
+	for err == nil {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			break
+		}
+		var right node
+		if right, err = p.product(); err == nil {
+			left, err = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) product() (node, error) {
+	left, err := p.unary()
+	for err == nil {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			break
+		}
+		var right node
+		if right, err = p.unary(); err == nil {
+			left, err = arithmetic(op, left, right)
+		}
+	}
+	return left, err
+}
+
+func arithmetic(op string, left, right node) (node, error) {
+	if left.kind != kindNumber || right.kind != kindNumber {
+		return node{}, fmt.Errorf("%s needs numbers, not a %s and a %s", op, left.kind, right.kind)
+	}
+	l, r := left.num, right.num
+	switch op {
+	case "+":
+		return number(func(f *Features) float64 { return l(f) + r(f) }), nil
+	case "-":
+		return number(func(f *Features) float64 { return l(f) - r(f) }), nil
+	case "*":
+		return number(func(f *Features) float64 { return l(f) * r(f) }), nil
+	case "/":
+		return number(func(f *Features) float64 { return l(f) / r(f) }), nil
+	}
+	return number(func(f *Features) float64 { return math.Mod(l(f), r(f)) }), nil
+}
+
+func (p *parser) unary() (node, error) {
+	op, ok := p.accept("-", "!")
+	if !ok {
+		return p.primary()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return node{}, err
+	}
+	if op == "-" {
+		if operand.kind != kindNumber {
+			return node{}, fmt.Errorf("- needs a number, not a %s", operand.kind)
+		}
+		n := operand.num
+		return number(func(f *Features) float64 { return -n(f) }), nil
+	}
+	if operand.kind != kindBool {
+		return node{}, fmt.Errorf("! needs a bool, not a %s", operand.kind)
+	}
+	b := operand.bool
+	return boolean(func(f *Features) bool { return !b(f) }), nil
+}
+
+func (p *parser) primary() (node, error) {
+	if _, ok := p.accept("("); ok {
+		n, err := p.expr()
+		if err != nil {
+			return node{}, err
+		}
+		return n, p.expect(")")
+	}
+
+	t := p.peek()
+	switch t.kind {
+	case '#':
+		p.pos++
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return number(func(*Features) float64 { return v }), nil
+	case 's':
+		p.pos++
+		return text(func(*Features) string { return t.text }), nil
+	case 'n':
+		p.pos++
+		if _, ok := p.accept("("); ok {
+			return p.call(t)
+		}
+		if v, ok := variables[t.text]; ok {
+			return v, nil
+		}
+		return node{}, fmt.Errorf("unknown variable %q at %d", t.text, t.pos)
+	}
+	return node{}, p.unexpected("expected a value")
+}
+
+// call parses the arguments of a function call, after the opening parenthesis
+func (p *parser) call(name token) (node, error) {
+	var args []node
+	var literals []string // Of string literal arguments, empty for others
+	if _, ok := p.accept(")"); !ok {
+		for {
+			start := p.peek()
+			arg, err := p.expr()
+			if err != nil {
+				return node{}, err
+			}
+			literal := ""
+			if start.kind == 's' && p.tokens[p.pos-1] == start {
+				literal = start.text
+			}
+			args = append(args, arg)
+			literals = append(literals, literal)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return node{}, err
+		}
+	}
+	n, err := function(name.text, args, literals)
+	if err != nil {
+		return node{}, fmt.Errorf("%s() at %d: %w", name.text, name.pos, err)
+	}
+	return n, nil
+}
+
+// function returns a call of the named function with args. literals holds
+// the value of arguments written as string literals.
+func function(name string, args []node, literals []string) (node, error) {
+	kinds := func(want ...kind) error {
+		if len(args) != len(want) {
+			return fmt.Errorf("takes %d arguments, not %d", len(want), len(args))
+		}
+		for i, k := range want {
+			if args[i].kind != k {
+				return fmt.Errorf("argument %d is a %s, not a %s", i+1, args[i].kind, k)
+			}
+		}
+		return nil
+	}
+
+	switch name {
+	case "contains", "starts_with", "ends_with":
+		if err := kinds(kindString, kindString); err != nil {
+			return node{}, err
+		}
+		test := map[string]func(s, substr string) bool{
+			"contains":    strings.Contains,
+			"starts_with": strings.HasPrefix,
+			"ends_with":   strings.HasSuffix,
+		}[name]
+		s, sub := args[0].str, args[1].str
+		return boolean(func(f *Features) bool { return test(s(f), sub(f)) }), nil
+
+	case "matches":
+		if err := kinds(kindString, kindString); err != nil {
+			return node{}, err
+		}
+		if literals[1] == "" {
+			return node{}, fmt.Errorf("pattern must be a string literal")
+		}
+		re, err := regexp.Compile(literals[1])
+		if err != nil {
+			return node{}, err
+		}
+		s := args[0].str
+		return boolean(func(f *Features) bool { return re.MatchString(s(f)) }), nil
+
+	case "param", "has_param":
+		if err := kinds(kindString); err != nil {
+			return node{}, err
+		}
+		key := args[0].str
+		if name == "has_param" {
+			return boolean(func(f *Features) bool { return f.Params.Has(key(f)) }), nil
+		}
+		return text(func(f *Features) string { return f.Params.Get(key(f)) }), nil
+
+	case "len":
+		if err := kinds(kindString); err != nil {
+			return node{}, err
+		}
+		s := args[0].str
+		return number(func(f *Features) float64 { return float64(len(s(f))) }), nil
+
+	case "abs", "log", "sqrt":
+		if err := kinds(kindNumber); err != nil {
+			return node{}, err
+		}
+		fn := map[string]func(float64) float64{"abs": math.Abs, "log": math.Log, "sqrt": math.Sqrt}[name]
+		x := args[0].num
+		return number(func(f *Features) float64 { return fn(x(f)) }), nil
+
+	case "min", "max":
+		if len(args) < 2 {
+			return node{}, fmt.Errorf("takes at least 2 arguments")
+		}
+		for i, arg := range args {
+			if arg.kind != kindNumber {
+				return node{}, fmt.Errorf("argument %d is a %s, not a number", i+1, arg.kind)
+			}
+		}
+		pick := math.Min
+		if name == "max" {
+			pick = math.Max
+		}
+		return number(func(f *Features) float64 {
+			v := args[0].num(f)
+			for _, arg := range args[1:] {
+				v = pick(v, arg.num(f))
+			}
+			return v
+		}), nil
+	}
+	return node{}, fmt.Errorf("unknown function")
+}
@@ -0,0 +1,143 @@
+// Package scoring computes the score of queued URLs from an expression over
+// their features, so the best-first order can be changed in the config
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// Features are the properties of a URL an expression can use
+type Features struct {
+	URL      string
+	Host     string // Lowercased, without the port
+	Path     string
+	Query    string
+	Ext      string // Lowercased extension of the last path segment, without the dot
+	Depth    int
+	Segments int // Non-empty path segments
+	Params   url.Values
+	Inlinks  int     // Links to the URL found so far
+	Score    float64 // Relevance of the linking page
+}
+
+// FeaturesOf returns the features of u found at depth
+func FeaturesOf(u *url.URL, depth, inlinks int, score float64) Features {
+	f := Features{
+		URL:     u.String(),
+		Host:    strings.ToLower(u.Hostname()),
+		Path:    u.EscapedPath(),
+		Query:   u.RawQuery,
+		Ext:     strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), ".")),
+		Depth:   depth,
+		Params:  u.Query(),
+		Inlinks: inlinks,
+		Score:   score,
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			f.Segments++
+		}
+	}
+	return f
+}
+
+// Expression is a compiled scoring expression. It's a number, or a bool
+// that scores 1 when true and 0 when false.
+type Expression struct {
+	src  string
+	eval func(f *Features) float64
+}
+
+// Compile parses a scoring expression and checks its types
+func Compile(src string) (*Expression, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid score expression %q: %w", src, err)
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr()
+	if err == nil && !p.eof() {
+		err = p.unexpected("expected an operator")
+	}
+	if err == nil && n.kind == kindString {
+		err = fmt.Errorf("result is a string, not a number")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid score expression %q: %w", src, err)
+	}
+
+	e := &Expression{src: src, eval: n.num}
+	if n.kind == kindBool {
+		b := n.bool
+		e.eval = func(f *Features) float64 {
+			if b(f) {
+				return 1
+			}
+			return 0
+		}
+	}
+	return e, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval scores a URL by its features. Results that aren't finite, e.g. of a
+// division by zero, are clamped so they can be stored with the queue.
+func (e *Expression) Eval(f Features) float64 {
+	v := e.eval(&f)
+	switch {
+	case math.IsNaN(v):
+		return 0
+	case math.IsInf(v, 1):
+		return math.MaxFloat64
+	case math.IsInf(v, -1):
+		return -math.MaxFloat64
+	}
+	return v
+}
+
+// Scorer scores the URLs a crawl queues
+type Scorer struct {
+	expr *Expression
+
+	// Counters
+	scored int64
+}
+
+// New creates a scorer from an expression. It returns nil if the expression
+// is empty.
+func New(src string) (*Scorer, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+	expr, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Scorer{expr: expr}, nil
+}
+
+// Score returns the score of u found at depth, with inlinks links to it so
+// far, on a page of relevance score. Without a scorer it's score.
+func (s *Scorer) Score(u *url.URL, depth, inlinks int, score float64) float64 {
+	if s == nil {
+		return score
+	}
+	atomic.AddInt64(&s.scored, 1)
+	return s.expr.Eval(FeaturesOf(u, depth, inlinks, score))
+}
+
+// GetStats returns how many URLs were scored
+func (s *Scorer) GetStats() map[string]int64 {
+	return map[string]int64{
+		"scored": atomic.LoadInt64(&s.scored),
+	}
+}
@@ -0,0 +1,95 @@
+package scoring
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	u, _ := url.Parse("https://Example.com/docs/guide/Intro.HTML?page=2&sessionid=x")
+	f := FeaturesOf(u, 3, 4, 0.5)
+	if f.Host != "example.com" || f.Ext != "html" || f.Segments != 3 || f.Params.Get("page") != "2" {
+		t.Fatalf("FeaturesOf() = %+v", f)
+	}
+
+	for _, tt := range []struct {
+		expr string
+		want float64
+	}{
+		{"1 + 2 * 3 - 4 / 2", 5},
+		{"-(1 + 2) % 2", -1},
+		{"depth + segments + params + inlinks + score", 12.5},
+		{"contains(path, '/guide/')", 1},
+		{"starts_with(host, \"www.\") || ends_with(path, '.HTML')", 1},
+		{"!has_param('sessionid')", 0},
+		{"param('page') == '2' && query != ''", 1},
+		{"matches(url, '/docs/[a-z]+/')", 1},
+		{"ext == 'pdf' ? 10 : ext == 'html' ? 5 : 0", 5},
+		{"len(host) + min(depth, 2, 7) + max(1, abs(-3))", 16},
+		{"log(1) + sqrt(16)", 4},
+		{"(depth >= 3) == true", 1},
+		{"1 / 0", math.MaxFloat64},
+		{"0 / 0", 0},
+	} {
+		e, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := e.Eval(f); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr, err string
+	}{
+		{"depth +", "expected a value at end"},
+		{"(depth", "expected )"},
+		{"depth depth", "expected an operator"},
+		{"path", "result is a string"},
+		{"path + 1", "+ needs numbers"},
+		{"depth && true", "&& needs bools"},
+		{"path == 1", "compares a string with a number"},
+		{"depth ? 1 : 0", "condition of ?:"},
+		{"true ? 1 : 'a'", "branches of ?:"},
+		{"size", "unknown variable"},
+		{"lower(path)", "unknown function"},
+		{"contains(path)", "takes 2 arguments"},
+		{"matches(path, host)", "string literal"},
+		{"matches(path, '[')", "missing closing ]"},
+		{"min(1)", "at least 2"},
+		{"'open", "unterminated string"},
+		{"depth # 2", "unexpected"},
+	} {
+		if _, err := Compile(tt.expr); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestScorer(t *testing.T) {
+	u, _ := url.Parse("https://example.com/a")
+	var none *Scorer
+	if got := none.Score(u, 1, 1, 0.25); got != 0.25 {
+		t.Errorf("nil Score() = %v, want the linking page's score", got)
+	}
+	if s, err := New("  "); s != nil || err != nil {
+		t.Errorf("New(empty) = %v, %v, want nil", s, err)
+	}
+
+	s, err := New("score * 10 - depth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Score(u, 2, 1, 0.5); got != 3 {
+		t.Errorf("Score() = %v, want 3", got)
+	}
+	if got := s.GetStats()["scored"]; got != 1 {
+		t.Errorf("scored = %d, want 1", got)
+	}
+}
@@ -0,0 +1,295 @@
+package search
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/storage"
+	"web-crawler/pkg/utils"
+)
+
+// log is the logger of the search package
+var log = logger.For("search")
+
+// docsFile holds the indexed documents, one JSON object per line, in the index directory
+const docsFile = "docs.jsonl"
+
+// Indexed fields
+const (
+	fieldTitle = iota
+	fieldURL
+	fieldText
+	numFields
+)
+
+// fieldNames are the query prefixes of the fields
+var fieldNames = [numFields]string{"title", "url", "text"}
+
+// document is the indexed part of a stored page
+type document struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Text      string    `json:"text"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// entry is an indexed document with its term frequencies per field
+type entry struct {
+	doc    document
+	terms  [numFields]map[string]int
+	length [numFields]int
+}
+
+// Index is a full-text index of crawled pages, kept in memory and backed by
+// a JSON-lines file that is replayed on open. It implements storage.Archiver
+// so that pages are indexed as they are stored.
+type Index struct {
+	path     string
+	file     *os.File // Nil when opened read-only
+	mu       sync.RWMutex
+	entries  map[int]*entry
+	ids      map[string]int                    // URL -> entry ID
+	postings [numFields]map[string]map[int]int // Field -> term -> entry ID -> frequency
+	lengths  [numFields]int                    // Total terms per field, for average lengths
+	nextID   int
+	stale    int // Lines in the file replaced by a later version of the page
+
+	indexed int64
+	failed  int64
+}
+
+// New opens the configured index for writing. It returns nil if the index is disabled.
+func New(cfg config.SearchConfig) (*Index, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return Open(cfg.Path)
+}
+
+// Open opens or creates the index in dir for writing, compacting its file if
+// most of it holds replaced pages
+func Open(dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create search index directory: %w", err)
+	}
+	idx, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if idx.stale > len(idx.entries) {
+		if err := idx.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(idx.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	idx.file = file
+	log.Info("Search index %s holds %d pages", dir, len(idx.entries))
+	return idx, nil
+}
+
+// Load reads the index in dir for querying only, e.g. while a crawl is
+// still writing to it
+func Load(dir string) (*Index, error) {
+	idx := &Index{
+		path:    filepath.Join(dir, docsFile),
+		entries: make(map[int]*entry),
+		ids:     make(map[string]int),
+	}
+	for f := range idx.postings {
+		idx.postings[f] = make(map[string]map[int]int)
+	}
+
+	file, err := os.Open(idx.path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var doc document
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			continue // Skip a torn last line
+		}
+		idx.add(doc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search index: %w", err)
+	}
+	return idx, nil
+}
+
+// Store indexes the title, text and URL of a page. The text is the article
+// text if extracted, else the cleaned text, else the text of the content.
+func (idx *Index) Store(ctx context.Context, page *storage.WebPage) error {
+	if page.Kind != "" {
+		return nil // Only pages are searchable
+	}
+	doc := document{URL: page.URL, Title: page.Title, Text: page.Text, CrawledAt: page.CrawledAt}
+	if page.Article != nil {
+		doc.Text = page.Article.Text
+		if doc.Title == "" {
+			doc.Title = page.Article.Title
+		}
+	}
+	if doc.Text == "" && page.Content != "" {
+		doc.Text = utils.CleanText(page.Content)
+	}
+
+	line, err := json.Marshal(doc)
+	if err != nil {
+		atomic.AddInt64(&idx.failed, 1)
+		return fmt.Errorf("failed to encode search document: %w", err)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.file == nil {
+		return fmt.Errorf("search index is read-only")
+	}
+	if _, err := idx.file.Write(append(line, '\n')); err != nil {
+		atomic.AddInt64(&idx.failed, 1)
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	idx.add(doc)
+	atomic.AddInt64(&idx.indexed, 1)
+	return nil
+}
+
+// add indexes doc, replacing an earlier version of the page. Must be called
+// with the lock held or before the index is shared.
+func (idx *Index) add(doc document) {
+	if id, ok := idx.ids[doc.URL]; ok {
+		idx.remove(id)
+		idx.stale++
+	}
+
+	e := &entry{doc: doc}
+	values := [numFields]string{doc.Title, doc.URL, doc.Text}
+	id := idx.nextID
+	idx.nextID++
+	for f, value := range values {
+		e.terms[f] = make(map[string]int)
+		for _, term := range tokenize(value) {
+			e.terms[f][term]++
+			e.length[f]++
+		}
+		for term, n := range e.terms[f] {
+			docs := idx.postings[f][term]
+			if docs == nil {
+				docs = make(map[int]int)
+				idx.postings[f][term] = docs
+			}
+			docs[id] = n
+		}
+		idx.lengths[f] += e.length[f]
+	}
+	idx.entries[id] = e
+	idx.ids[doc.URL] = id
+}
+
+// remove drops an entry from the postings
+func (idx *Index) remove(id int) {
+	e := idx.entries[id]
+	for f := range e.terms {
+		for term := range e.terms[f] {
+			docs := idx.postings[f][term]
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(idx.postings[f], term)
+			}
+		}
+		idx.lengths[f] -= e.length[f]
+	}
+	delete(idx.entries, id)
+	delete(idx.ids, e.doc.URL)
+}
+
+// compact rewrites the file with only the current version of every page
+func (idx *Index) compact() error {
+	tmpPath := idx.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create search index file: %w", err)
+	}
+
+	ids := make([]int, 0, len(idx.entries))
+	for id := range idx.entries {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	writer := bufio.NewWriter(tmp)
+	for _, id := range ids {
+		line, err := json.Marshal(idx.entries[id].doc)
+		if err != nil {
+			continue
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write search index file: %w", err)
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmpPath, idx.path); err != nil {
+		return fmt.Errorf("failed to replace search index file: %w", err)
+	}
+	log.Info("Compacted search index to %d pages, dropped %d old versions", len(idx.entries), idx.stale)
+	idx.stale = 0
+	return nil
+}
+
+// Len returns the number of indexed pages
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// GetStats returns index statistics
+func (idx *Index) GetStats() map[string]int64 {
+	idx.mu.RLock()
+	documents, terms := len(idx.entries), len(idx.postings[fieldText])
+	idx.mu.RUnlock()
+
+	return map[string]int64{
+		"documents": int64(documents),
+		"terms":     int64(terms),
+		"indexed":   atomic.LoadInt64(&idx.indexed),
+		"failed":    atomic.LoadInt64(&idx.failed),
+	}
+}
+
+// Close closes the index file
+func (idx *Index) Close(ctx context.Context) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.file == nil {
+		return nil
+	}
+	err := idx.file.Close()
+	idx.file = nil
+	return err
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/storage"
+)
+
+// openIndex opens an index in a temp dir and stores pages in it
+func openIndex(t *testing.T, dir string, pages ...*storage.WebPage) *Index {
+	t.Helper()
+	idx, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range pages {
+		if err := idx.Store(context.Background(), page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return idx
+}
+
+// urls returns the URLs of results in order
+func urls(results []Result) []string {
+	var list []string
+	for _, r := range results {
+		list = append(list, r.URL)
+	}
+	return list
+}
+
+func TestSearchRanksAndFilters(t *testing.T) {
+	idx := openIndex(t, t.TempDir(),
+		&storage.WebPage{URL: "https://a.com/go", Title: "Go concurrency", Text: "Goroutines and channels in practice."},
+		&storage.WebPage{URL: "https://b.com/blog", Title: "Weekly notes", Text: "Some notes on go concurrency patterns and channels."},
+		&storage.WebPage{URL: "https://c.com/rust", Title: "Rust ownership", Text: "Borrowing rules explained."},
+	)
+	defer idx.Close(context.Background())
+
+	// Every term must match, title matches weigh more than text matches
+	results, total := idx.Search("concurrency channels", 10)
+	if total != 2 || strings.Join(urls(results), " ") != "https://a.com/go https://b.com/blog" {
+		t.Fatalf("Search() = %v (%d total)", urls(results), total)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Fatalf("scores not descending: %v, %v", results[0].Score, results[1].Score)
+	}
+
+	if results, _ := idx.Search("title:notes", 10); len(results) != 1 || results[0].URL != "https://b.com/blog" {
+		t.Fatalf("title: search = %v", urls(results))
+	}
+	if results, _ := idx.Search("url:rust", 10); len(results) != 1 || results[0].URL != "https://c.com/rust" {
+		t.Fatalf("url: search = %v", urls(results))
+	}
+	if results, _ := idx.Search("title:channels", 10); len(results) != 0 {
+		t.Fatalf("title: search matched text: %v", urls(results))
+	}
+
+	results, total = idx.Search("concurrency", 1)
+	if len(results) != 1 || total != 2 {
+		t.Fatalf("limited Search() returned %d of %d", len(results), total)
+	}
+	if results, total := idx.Search("  ", 10); results != nil || total != 0 {
+		t.Fatal("empty query matched pages")
+	}
+}
+
+func TestIndexReplacesAndReplays(t *testing.T) {
+	dir := t.TempDir()
+	idx := openIndex(t, dir,
+		&storage.WebPage{URL: "https://a.com/", Title: "Old title", Text: "first version"},
+		&storage.WebPage{URL: "https://a.com/", Title: "New title", Text: "second version"},
+		&storage.WebPage{URL: "https://b.com/", Content: "<p>Content <b>only</b></p>"},
+	)
+	if idx.Len() != 2 {
+		t.Fatalf("Len() = %d after storing a page twice, want 2", idx.Len())
+	}
+	if results, _ := idx.Search("first", 10); len(results) != 0 {
+		t.Fatal("replaced version is still indexed")
+	}
+	if results, _ := idx.Search("content only", 10); len(results) != 1 {
+		t.Fatal("page text wasn't taken from the content")
+	}
+	idx.Close(context.Background())
+
+	// A read-only load replays the file and rejects writes
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 2 {
+		t.Fatalf("replayed %d pages, want 2", loaded.Len())
+	}
+	if results, _ := loaded.Search("second", 10); len(results) != 1 || results[0].Title != "New title" {
+		t.Fatalf("replayed search = %+v", results)
+	}
+	if err := loaded.Store(context.Background(), &storage.WebPage{URL: "https://c.com/"}); err == nil {
+		t.Fatal("read-only index accepted a page")
+	}
+}
+
+func TestOpenCompactsReplacedPages(t *testing.T) {
+	dir := t.TempDir()
+	idx := openIndex(t, dir)
+	for i := 0; i < 3; i++ {
+		idx.Store(context.Background(), &storage.WebPage{URL: "https://a.com/", Text: "version"})
+	}
+	idx.Close(context.Background())
+
+	idx = openIndex(t, dir)
+	defer idx.Close(context.Background())
+	if idx.stale != 0 {
+		t.Fatalf("%d stale lines left after open", idx.stale)
+	}
+	if n := logLines(t, filepath.Join(dir, docsFile)); n != 1 {
+		t.Fatalf("index file has %d lines after compacting, want 1", n)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 20) + "the Needle, here " + strings.Repeat("tail ", 40)
+	s := snippet(text, parseQuery("needle"))
+	if !strings.HasPrefix(s, "...") || !strings.HasSuffix(s, "...") || !strings.Contains(s, "Needle,") {
+		t.Fatalf("snippet() = %q", s)
+	}
+	if got := snippet("short text", parseQuery("missing")); got != "short text" {
+		t.Fatalf("snippet() without a match = %q", got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Hello, WORLD! naïve-café 42 " + strings.Repeat("x", maxTermLength+1))
+	if strings.Join(got, " ") != "hello world naïve café 42" {
+		t.Fatalf("tokenize() = %q", got)
+	}
+}
+
+// logLines counts the lines of the file at path
+func logLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+const (
+	maxTermLength = 64 // Longer tokens are not indexed
+	snippetBefore = 10 // Words of context before the first match
+	snippetWords  = 30 // Words in a snippet
+)
+
+// fieldWeights scale the score of a match by field
+var fieldWeights = [numFields]float64{fieldTitle: 3, fieldURL: 1.5, fieldText: 1}
+
+// Result is a page matching a query
+type Result struct {
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Score     float64   `json:"score"`
+	Snippet   string    `json:"snippet,omitempty"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// clause is one query term, restricted to a field or matching any (-1)
+type clause struct {
+	field int
+	term  string
+}
+
+// Search returns up to limit pages containing every query term, best first,
+// and the total number of matches. Terms may be restricted to a field with
+// a title:, url: or text: prefix.
+func (idx *Index) Search(query string, limit int) ([]Result, int) {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return nil, 0
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var matches map[int]float64
+	for _, c := range clauses {
+		scores := idx.score(c)
+		if matches == nil {
+			matches = scores
+			continue
+		}
+		for id := range matches {
+			if s, ok := scores[id]; ok {
+				matches[id] += s
+			} else {
+				delete(matches, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(matches))
+	for id, score := range matches {
+		e := idx.entries[id]
+		results = append(results, Result{URL: e.doc.URL, Title: e.doc.Title, Score: score, CrawledAt: e.doc.CrawledAt})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].URL < results[j].URL
+	})
+
+	total := len(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	for i := range results {
+		results[i].Snippet = snippet(idx.entries[idx.ids[results[i].URL]].doc.Text, clauses)
+	}
+	return results, total
+}
+
+// score returns the weighted BM25 score of a clause for every page containing it
+func (idx *Index) score(c clause) map[int]float64 {
+	scores := make(map[int]float64)
+	n := float64(len(idx.entries))
+	for f := 0; f < numFields; f++ {
+		if c.field >= 0 && c.field != f {
+			continue
+		}
+		docs := idx.postings[f][c.term]
+		if len(docs) == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+		avgLength := float64(idx.lengths[f]) / n
+		for id, freq := range docs {
+			tf := float64(freq)
+			norm := 1 - b + b*float64(idx.entries[id].length[f])/avgLength
+			scores[id] += fieldWeights[f] * idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+	return scores
+}
+
+// parseQuery splits a query into terms, applying field prefixes to every
+// term of the word they precede
+func parseQuery(query string) []clause {
+	var clauses []clause
+	for _, word := range strings.Fields(query) {
+		field := -1
+		if name, rest, ok := strings.Cut(word, ":"); ok {
+			for f, fieldName := range fieldNames {
+				if strings.EqualFold(name, fieldName) {
+					field, word = f, rest
+					break
+				}
+			}
+		}
+		for _, term := range tokenize(word) {
+			clauses = append(clauses, clause{field: field, term: term})
+		}
+	}
+	return clauses
+}
+
+// tokenize splits text into lowercase terms of letters and digits
+func tokenize(text string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := terms[:0]
+	for _, term := range terms {
+		if len(term) <= maxTermLength {
+			kept = append(kept, term)
+		}
+	}
+	return kept
+}
+
+// snippet returns the words of text around the first query term, or its
+// beginning if no term is in the text
+func snippet(text string, clauses []clause) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	terms := make(map[string]bool, len(clauses))
+	for _, c := range clauses {
+		if c.field < 0 || c.field == fieldText {
+			terms[c.term] = true
+		}
+	}
+	start := 0
+	for i, word := range words {
+		if matchesAny(word, terms) {
+			start = max(0, i-snippetBefore)
+			break
+		}
+	}
+	end := min(len(words), start+snippetWords)
+
+	s := strings.Join(words[start:end], " ")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(words) {
+		s += "..."
+	}
+	return s
+}
+
+// matchesAny reports whether a word of text contains one of the terms
+func matchesAny(word string, terms map[string]bool) bool {
+	for _, term := range tokenize(word) {
+		if terms[term] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package seeds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"web-crawler/internal/dedup"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+)
+
+// log is the logger of the seeds package
+var log = logger.For("seeds")
+
+// Seed is a start URL with its queue priority and starting depth
+type Seed struct {
+	URL      string
+	Priority int
+	Depth    int
+}
+
+// Result holds the seeds accepted from a list together with load statistics
+type Result struct {
+	Seeds      []Seed
+	Invalid    int // Lines that could not be parsed
+	Duplicates int // URLs already listed earlier
+}
+
+// Load reads seeds from a file, or from stdin when path is "-"
+func Load(path string) (*Result, error) {
+	if path == "-" {
+		return Parse(os.Stdin, "stdin")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open seed file: %w", err)
+	}
+	defer file.Close()
+
+	return Parse(file, path)
+}
+
+// Parse reads one seed per line in the form "url [priority] [depth]", with
+// columns separated by whitespace, tabs or commas. Priority is high, normal,
+// low or 0-2. Blank lines and lines starting with # are ignored. Invalid lines
+// are logged and skipped; name identifies the source in log messages.
+func Parse(r io.Reader, name string) (*Result, error) {
+	result := &Result{}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		seed, err := parseLine(line)
+		if err != nil {
+			result.Invalid++
+			log.Warn("%s:%d: skipping seed: %v", name, lineNo, err)
+			continue
+		}
+
+		key := dedup.Normalize(seed.URL)
+		if seen[key] {
+			result.Duplicates++
+			continue
+		}
+		seen[key] = true
+		result.Seeds = append(result.Seeds, seed)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read seeds from %s: %w", name, err)
+	}
+
+	log.Info("Loaded %d seeds from %s (%d invalid, %d duplicates)",
+		len(result.Seeds), name, result.Invalid, result.Duplicates)
+	return result, nil
+}
+
+// parseLine parses and validates a single seed line
+func parseLine(line string) (Seed, error) {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	if len(fields) > 3 {
+		return Seed{}, fmt.Errorf("expected at most 3 columns, got %d", len(fields))
+	}
+
+	seed := Seed{URL: fields[0], Priority: queue.PriorityHigh}
+	if err := Validate(seed.URL); err != nil {
+		return Seed{}, err
+	}
+
+	if len(fields) > 1 {
+		p, err := parsePriority(fields[1])
+		if err != nil {
+			return Seed{}, err
+		}
+		seed.Priority = p
+	}
+	if len(fields) > 2 {
+		d, err := strconv.Atoi(fields[2])
+		if err != nil || d < 0 {
+			return Seed{}, fmt.Errorf("invalid depth %q", fields[2])
+		}
+		seed.Depth = d
+	}
+	return seed, nil
+}
+
+// parsePriority accepts a priority name or its numeric level
+func parsePriority(value string) (int, error) {
+	switch strings.ToLower(value) {
+	case "high", "0":
+		return queue.PriorityHigh, nil
+	case "normal", "1":
+		return queue.PriorityNormal, nil
+	case "low", "2":
+		return queue.PriorityLow, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q", value)
+}
+
+// Validate checks that rawURL is an absolute http(s) URL with a host
+func Validate(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: scheme must be http or https", rawURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid url %q: missing host", rawURL)
+	}
+	return nil
+}
+
+// Enqueue pushes the seeds onto q and returns how many were queued
+func Enqueue(q queue.URLQueue, list []Seed) int {
+	for _, seed := range list {
+		host := ""
+		if u, err := url.Parse(seed.URL); err == nil {
+			host = u.Host
+		}
+		q.PushWithPriority(seed.URL, seed.Priority, host, seed.Depth)
+	}
+	return len(list)
+}
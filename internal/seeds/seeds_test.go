@@ -0,0 +1,86 @@
+package seeds
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/queue"
+)
+
+func TestParse(t *testing.T) {
+	list := `# Seeds for the nightly crawl
+https://a.com/
+
+https://b.com/news, low, 2
+https://c.com/	normal	1
+https://A.com
+ftp://files.example.com/
+https://d.com/ urgent
+https://e.com/ high -1
+https://f.com/ 0 1 extra
+/relative/path
+`
+	result, err := Parse(strings.NewReader(list), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Seed{
+		{URL: "https://a.com/", Priority: queue.PriorityHigh},
+		{URL: "https://b.com/news", Priority: queue.PriorityLow, Depth: 2},
+		{URL: "https://c.com/", Priority: queue.PriorityNormal, Depth: 1},
+	}
+	if len(result.Seeds) != len(want) {
+		t.Fatalf("Parse() seeds = %+v, want %+v", result.Seeds, want)
+	}
+	for i := range want {
+		if result.Seeds[i] != want[i] {
+			t.Errorf("seed %d = %+v, want %+v", i, result.Seeds[i], want[i])
+		}
+	}
+	if result.Invalid != 5 || result.Duplicates != 1 {
+		t.Fatalf("Invalid = %d, Duplicates = %d, want 5 and 1", result.Invalid, result.Duplicates)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/":  true,
+		"http://example.com:80": true,
+		"mailto:a@example.com":  false,
+		"https:///path":         false,
+		"example.com":           false,
+		"http://[::1":           false,
+	}
+	for rawURL, valid := range tests {
+		if err := Validate(rawURL); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v", rawURL, err)
+		}
+	}
+}
+
+func TestLoadAndEnqueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seeds.txt")
+	if err := os.WriteFile(path, []byte("https://a.com/ low 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := queue.NewURLQueue()
+	if n := Enqueue(q, result.Seeds); n != 1 {
+		t.Fatalf("Enqueue() = %d, want 1", n)
+	}
+	item, ok := q.Pop()
+	if !ok || item.URL != "https://a.com/" || item.Host != "a.com" || item.Depth != 3 || item.Priority != queue.PriorityLow {
+		t.Fatalf("queued %+v", item)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("Load() of a missing file succeeded")
+	}
+}
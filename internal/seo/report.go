@@ -0,0 +1,248 @@
+package seo
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Report is the audit of all pages
+type Report struct {
+	Pages                 []Page         `json:"pages"`  // Sorted by URL
+	Issues                map[string]int `json:"issues"` // Pages flagged with each issue
+	DuplicateTitles       []Duplicate    `json:"duplicate_titles"`
+	DuplicateDescriptions []Duplicate    `json:"duplicate_descriptions"`
+}
+
+// Duplicate is a title or description shared by several pages
+type Duplicate struct {
+	Text string   `json:"text"`
+	URLs []string `json:"urls"`
+}
+
+// Report audits the pages against each other and returns every page with
+// its issues. Pages canonicalized to another URL are expected to repeat it
+// and are not flagged as duplicates.
+func (a *Auditor) Report() *Report {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	pages := make([]Page, 0, len(a.pages))
+	for _, p := range a.pages {
+		page := *p
+		page.Issues = append([]string{}, p.Issues...)
+		if page.Canonical != "" && page.Canonical != page.URL && a.redirected[page.Canonical] {
+			page.Issues = append(page.Issues, IssueCanonicalRedirect)
+		}
+		pages = append(pages, page)
+	}
+	a.mu.Unlock()
+	sort.Slice(pages, func(i, j int) bool { return pages[i].URL < pages[j].URL })
+
+	report := &Report{Pages: pages, Issues: make(map[string]int)}
+	report.DuplicateTitles = duplicates(pages, IssueDuplicateTitle, func(p *Page) string { return p.Title })
+	report.DuplicateDescriptions = duplicates(pages, IssueDuplicateDescription, func(p *Page) string { return p.Description })
+	for _, p := range pages {
+		for _, issue := range p.Issues {
+			report.Issues[issue]++
+		}
+	}
+	return report
+}
+
+// duplicates groups the pages sharing the text returned by field, flags them
+// with issue and returns the groups sorted by text
+func duplicates(pages []Page, issue string, field func(*Page) string) []Duplicate {
+	byText := make(map[string][]int)
+	for i := range pages {
+		p := &pages[i]
+		text := strings.ToLower(field(p))
+		if text == "" || (p.Canonical != "" && p.Canonical != p.URL) {
+			continue
+		}
+		byText[text] = append(byText[text], i)
+	}
+
+	dups := []Duplicate{}
+	for _, indexes := range byText {
+		if len(indexes) < 2 {
+			continue
+		}
+		dup := Duplicate{Text: field(&pages[indexes[0]])}
+		for _, i := range indexes {
+			pages[i].Issues = append(pages[i].Issues, issue)
+			dup.URLs = append(dup.URLs, pages[i].URL)
+		}
+		dups = append(dups, dup)
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].Text < dups[j].Text })
+	return dups
+}
+
+// Export writes the report in the configured formats to the output
+// directory, as seo-report.html and seo-pages.csv
+func (a *Auditor) Export() error {
+	if a == nil {
+		return nil
+	}
+	dir := a.cfg.OutputDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create SEO report directory: %w", err)
+	}
+	report := a.Report()
+	for _, format := range a.cfg.Formats {
+		var err error
+		switch format {
+		case "html":
+			err = writeFile(filepath.Join(dir, "seo-report.html"), func(w *bufio.Writer) error { return writeHTML(w, report) })
+		case "csv":
+			err = writeFile(filepath.Join(dir, "seo-pages.csv"), func(w *bufio.Writer) error { return writeCSV(w, report) })
+		}
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Exported SEO audit of %d pages, %d flagged, to %s", len(report.Pages), report.Flagged(), dir)
+	return nil
+}
+
+// Flagged returns the number of pages with issues
+func (r *Report) Flagged() int {
+	flagged := 0
+	for _, p := range r.Pages {
+		if len(p.Issues) > 0 {
+			flagged++
+		}
+	}
+	return flagged
+}
+
+// writeFile creates path and fills it through a buffered writer
+func writeFile(path string, write func(*bufio.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeCSV writes one row per page, issues separated by spaces
+func writeCSV(w *bufio.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "title", "title_length", "description_length", "h1", "words", "redirects", "canonical", "issues"})
+	for _, p := range report.Pages {
+		cw.Write([]string{
+			p.URL,
+			p.Title,
+			strconv.Itoa(len([]rune(p.Title))),
+			strconv.Itoa(len([]rune(p.Description))),
+			strconv.Itoa(p.H1),
+			strconv.Itoa(p.Words),
+			strconv.Itoa(p.Redirects),
+			p.Canonical,
+			strings.Join(p.Issues, " "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// issueCount is a row of the summary table
+type issueCount struct {
+	Issue string
+	Pages int
+}
+
+// reportPage is the HTML report: a summary, the duplicates and the flagged pages
+var reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SEO audit</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+ul { margin: 0; padding-left: 1.2em; }
+</style>
+</head>
+<body>
+<h1>SEO audit</h1>
+<p>Generated {{.Generated}}: {{.Report.Flagged}} of {{len .Report.Pages}} pages flagged.</p>
+<h2>Issues</h2>
+<table>
+<tr><th>Issue</th><th>Pages</th></tr>
+{{range .Summary}}<tr><td>{{.Issue}}</td><td>{{.Pages}}</td></tr>
+{{end}}
+</table>
+{{if .Report.DuplicateTitles}}
+<h2>Duplicate titles</h2>
+<table>
+<tr><th>Title</th><th>Pages</th></tr>
+{{range .Report.DuplicateTitles}}<tr><td>{{.Text}}</td><td><ul>{{range .URLs}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul></td></tr>
+{{end}}
+</table>
+{{end}}
+{{if .Report.DuplicateDescriptions}}
+<h2>Duplicate descriptions</h2>
+<table>
+<tr><th>Description</th><th>Pages</th></tr>
+{{range .Report.DuplicateDescriptions}}<tr><td>{{.Text}}</td><td><ul>{{range .URLs}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul></td></tr>
+{{end}}
+</table>
+{{end}}
+<h2>Pages</h2>
+<table>
+<tr><th>Page</th><th>Title</th><th>h1</th><th>Words</th><th>Redirects</th><th>Canonical</th><th>Issues</th></tr>
+{{range .Report.Pages}}{{if .Issues}}
+<tr>
+<td><a href="{{.URL}}">{{.URL}}</a></td>
+<td>{{.Title}}</td>
+<td>{{.H1}}</td>
+<td>{{.Words}}</td>
+<td>{{.Redirects}}</td>
+<td>{{.Canonical}}</td>
+<td><ul>{{range .Issues}}<li>{{.}}</li>{{end}}</ul></td>
+</tr>
+{{end}}{{end}}
+</table>
+</body>
+</html>
+`))
+
+// writeHTML writes the report as a page, issues by number of pages
+func writeHTML(w *bufio.Writer, report *Report) error {
+	summary := make([]issueCount, 0, len(report.Issues))
+	for issue, n := range report.Issues {
+		summary = append(summary, issueCount{issue, n})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Pages != summary[j].Pages {
+			return summary[i].Pages > summary[j].Pages
+		}
+		return summary[i].Issue < summary[j].Issue
+	})
+	return reportPage.Execute(w, struct {
+		Generated string
+		Summary   []issueCount
+		Report    *Report
+	}{time.Now().Format(time.RFC1123), summary, report})
+}
@@ -0,0 +1,184 @@
+// Package seo audits crawled pages for common SEO problems: titles,
+// descriptions, headings, canonicals, redirect chains and thin content
+package seo
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/storage"
+	"web-crawler/pkg/utils"
+)
+
+var log = logger.For("seo")
+
+// Issues a page can be flagged with
+const (
+	IssueMissingTitle         = "missing_title"
+	IssueLongTitle            = "long_title"
+	IssueDuplicateTitle       = "duplicate_title"
+	IssueMissingDescription   = "missing_description"
+	IssueLongDescription      = "long_description"
+	IssueDuplicateDescription = "duplicate_description"
+	IssueMissingH1            = "missing_h1"
+	IssueMultipleH1           = "multiple_h1"
+	IssueSkippedHeading       = "skipped_heading_level" // e.g. an h4 right after an h2
+	IssueMissingCanonical     = "missing_canonical"
+	IssueCanonicalElsewhere   = "canonical_elsewhere" // Canonical is another URL
+	IssueCanonicalOtherHost   = "canonical_other_host"
+	IssueCanonicalRedirect    = "canonical_redirect" // Canonical is a URL that redirects
+	IssueRedirectChain        = "redirect_chain"
+	IssueThinContent          = "thin_content"
+)
+
+// Page is the SEO signals of a page and the issues found on it
+type Page struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Canonical   string   `json:"canonical"`
+	H1          int      `json:"h1"`        // h1 elements
+	Headings    []int    `json:"headings"`  // Levels of the headings in document order
+	Words       int      `json:"words"`     // Words of the page text
+	Redirects   int      `json:"redirects"` // Hops followed to reach the page
+	Issues      []string `json:"issues"`
+}
+
+// Auditor collects the signals of crawled pages. Issues that depend on
+// other pages, like duplicate titles, are found by Report.
+type Auditor struct {
+	cfg config.SEOConfig
+
+	mu         sync.Mutex
+	pages      map[string]*Page
+	redirected map[string]bool // URLs that answered with a redirect
+
+	// Counters
+	dropped int64 // Pages not audited because max_pages was reached
+}
+
+// New creates an auditor. It returns nil if the SEO audit is disabled.
+func New(cfg config.SEOConfig) *Auditor {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Auditor{
+		cfg:        cfg,
+		pages:      make(map[string]*Page),
+		redirected: make(map[string]bool),
+	}
+}
+
+// Observe audits a crawled page, given its HTML content and plain text
+func (a *Auditor) Observe(page *storage.WebPage, content, text string) {
+	if a == nil {
+		return
+	}
+	p := &Page{
+		URL:         page.URL,
+		Title:       collapse(page.Title),
+		Description: collapse(page.Metadata["description"]),
+		Canonical:   page.CanonicalURL,
+		Words:       len(strings.Fields(text)),
+		Redirects:   len(page.Redirects),
+	}
+	for _, h := range utils.ExtractHeadings(content) {
+		if h.Level == 1 {
+			p.H1++
+		}
+		p.Headings = append(p.Headings, h.Level)
+	}
+	p.Issues = a.issues(p)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, hop := range page.Redirects {
+		a.redirected[hop.URL] = true
+	}
+	if _, ok := a.pages[p.URL]; !ok && a.cfg.MaxPages > 0 && len(a.pages) >= a.cfg.MaxPages {
+		atomic.AddInt64(&a.dropped, 1)
+		return
+	}
+	a.pages[p.URL] = p
+}
+
+// GetStats returns the pages audited
+func (a *Auditor) GetStats() map[string]int64 {
+	a.mu.Lock()
+	pages := len(a.pages)
+	a.mu.Unlock()
+	return map[string]int64{
+		"pages":   int64(pages),
+		"dropped": atomic.LoadInt64(&a.dropped),
+	}
+}
+
+// issues returns the issues found on a page on its own
+func (a *Auditor) issues(p *Page) []string {
+	var issues []string
+	switch n := utf8.RuneCountInString(p.Title); {
+	case n == 0:
+		issues = append(issues, IssueMissingTitle)
+	case n > a.cfg.MaxTitleLength:
+		issues = append(issues, IssueLongTitle)
+	}
+	switch n := utf8.RuneCountInString(p.Description); {
+	case n == 0:
+		issues = append(issues, IssueMissingDescription)
+	case n > a.cfg.MaxDescriptionLength:
+		issues = append(issues, IssueLongDescription)
+	}
+
+	switch {
+	case p.H1 == 0:
+		issues = append(issues, IssueMissingH1)
+	case p.H1 > 1:
+		issues = append(issues, IssueMultipleH1)
+	}
+	for i := 1; i < len(p.Headings); i++ {
+		if p.Headings[i] > p.Headings[i-1]+1 {
+			issues = append(issues, IssueSkippedHeading)
+			break
+		}
+	}
+
+	switch {
+	case p.Canonical == "":
+		issues = append(issues, IssueMissingCanonical)
+	case !sameHost(p.Canonical, p.URL):
+		issues = append(issues, IssueCanonicalOtherHost)
+	case p.Canonical != p.URL:
+		issues = append(issues, IssueCanonicalElsewhere)
+	}
+
+	if p.Redirects > a.cfg.MaxRedirects {
+		issues = append(issues, IssueRedirectChain)
+	}
+	if p.Words < a.cfg.MinWords {
+		issues = append(issues, IssueThinContent)
+	}
+	return issues
+}
+
+// sameHost reports whether two URLs are on the same host
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host)
+}
+
+// collapse trims s and collapses its runs of whitespace
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,174 @@
+package seo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/storage"
+)
+
+// testConfig flags titles over 20 characters and pages under 5 words
+func testConfig() config.SEOConfig {
+	return config.SEOConfig{
+		Enabled:              true,
+		MaxTitleLength:       20,
+		MaxDescriptionLength: 30,
+		MinWords:             5,
+		MaxRedirects:         1,
+	}
+}
+
+func TestAuditorIssues(t *testing.T) {
+	body := "<h1>Heading</h1><p>one two three four five</p>"
+	text := "Heading one two three four five"
+	tests := []struct {
+		name    string
+		page    storage.WebPage
+		content string
+		text    string
+		want    []string
+	}{
+		{
+			name:    "clean",
+			page:    storage.WebPage{URL: "https://example.com/a", Title: "Page A", CanonicalURL: "https://example.com/a", Metadata: map[string]string{"description": "About A"}},
+			content: body,
+			text:    text,
+		},
+		{
+			name:    "missing everything",
+			page:    storage.WebPage{URL: "https://example.com/b"},
+			content: "<p>short</p>",
+			text:    "short",
+			want:    []string{IssueMissingTitle, IssueMissingDescription, IssueMissingH1, IssueMissingCanonical, IssueThinContent},
+		},
+		{
+			name:    "too long",
+			page:    storage.WebPage{URL: "https://example.com/c", Title: "A title that goes on and on", CanonicalURL: "https://example.com/c", Metadata: map[string]string{"description": "A description longer than thirty characters"}},
+			content: body,
+			text:    text,
+			want:    []string{IssueLongTitle, IssueLongDescription},
+		},
+		{
+			name:    "headings",
+			page:    storage.WebPage{URL: "https://example.com/d", Title: "Page D", CanonicalURL: "https://example.com/d", Metadata: map[string]string{"description": "About D"}},
+			content: "<h1>One</h1><h2>Two</h2><h4>Four</h4><h1>Again</h1>",
+			text:    text,
+			want:    []string{IssueMultipleH1, IssueSkippedHeading},
+		},
+		{
+			name: "canonical and redirects",
+			page: storage.WebPage{
+				URL: "https://example.com/e", Title: "Page E", CanonicalURL: "https://example.com/", Metadata: map[string]string{"description": "About E"},
+				Redirects: []storage.RedirectHop{{URL: "http://example.com/e", StatusCode: 301}, {URL: "https://example.com/e/", StatusCode: 301}},
+			},
+			content: body,
+			text:    text,
+			want:    []string{IssueCanonicalElsewhere, IssueRedirectChain},
+		},
+		{
+			name:    "canonical on another host",
+			page:    storage.WebPage{URL: "https://example.com/f", Title: "Page F", CanonicalURL: "https://www.example.com/f", Metadata: map[string]string{"description": "About F"}},
+			content: body,
+			text:    text,
+			want:    []string{IssueCanonicalOtherHost},
+		},
+	}
+	for _, tt := range tests {
+		a := New(testConfig())
+		a.Observe(&tt.page, tt.content, tt.text)
+		got := a.Report().Pages[0].Issues
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: issues = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAuditorReport(t *testing.T) {
+	a := New(testConfig())
+	body := "<h1>Heading</h1>"
+	text := "one two three four five"
+	pages := []storage.WebPage{
+		{URL: "https://example.com/a", Title: "Shop", CanonicalURL: "https://example.com/a", Metadata: map[string]string{"description": "Buy things"}},
+		{URL: "https://example.com/b", Title: " shop ", CanonicalURL: "https://example.com/b", Metadata: map[string]string{"description": "Other things"}},
+		// Canonicalized to /a, so repeating its title is fine
+		{URL: "https://example.com/a?page=2", Title: "Shop", CanonicalURL: "https://example.com/a", Metadata: map[string]string{"description": "Buy things"}},
+		// Canonical to a URL that redirected to /b
+		{URL: "https://example.com/c", Title: "Contact", CanonicalURL: "https://example.com/old-b", Metadata: map[string]string{"description": "Write to us"}},
+		{URL: "https://example.com/b", Title: " shop ", CanonicalURL: "https://example.com/b", Metadata: map[string]string{"description": "Other things"},
+			Redirects: []storage.RedirectHop{{URL: "https://example.com/old-b", StatusCode: 301}}},
+	}
+	for i := range pages {
+		a.Observe(&pages[i], body, text)
+	}
+
+	report := a.Report()
+	if len(report.Pages) != 4 {
+		t.Fatalf("%d pages, want 4", len(report.Pages))
+	}
+	want := []Duplicate{{Text: "Shop", URLs: []string{"https://example.com/a", "https://example.com/b"}}}
+	if !reflect.DeepEqual(report.DuplicateTitles, want) {
+		t.Errorf("duplicate titles = %+v, want %+v", report.DuplicateTitles, want)
+	}
+	if len(report.DuplicateDescriptions) != 0 {
+		t.Errorf("duplicate descriptions = %+v", report.DuplicateDescriptions)
+	}
+	issues := map[string]int{IssueDuplicateTitle: 2, IssueCanonicalElsewhere: 2, IssueCanonicalRedirect: 1}
+	if !reflect.DeepEqual(report.Issues, issues) {
+		t.Errorf("issues = %v, want %v", report.Issues, issues)
+	}
+	if report.Flagged() != 4 {
+		t.Errorf("%d pages flagged, want 4", report.Flagged())
+	}
+}
+
+func TestAuditorExport(t *testing.T) {
+	if New(config.SEOConfig{}) != nil {
+		t.Fatal("New() returned an auditor while disabled")
+	}
+	var disabled *Auditor
+	disabled.Observe(&storage.WebPage{URL: "https://example.com/"}, "", "")
+	if disabled.Report() != nil || disabled.Export() != nil {
+		t.Fatal("nil auditor reported pages")
+	}
+
+	cfg := testConfig()
+	cfg.OutputDir = filepath.Join(t.TempDir(), "seo")
+	cfg.Formats = []string{"html", "csv"}
+	cfg.MaxPages = 1
+	a := New(cfg)
+	a.Observe(&storage.WebPage{URL: "https://example.com/", Title: "Fish & <Chips>"}, "", "")
+	a.Observe(&storage.WebPage{URL: "https://example.com/more"}, "", "")
+	if stats := a.GetStats(); stats["pages"] != 1 || stats["dropped"] != 1 {
+		t.Errorf("stats = %v", stats)
+	}
+	if err := a.Export(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "seo-pages.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "url,title,title_length,description_length,h1,words,redirects,canonical,issues\n" +
+		"https://example.com/,Fish & <Chips>,14,0,0,0,0,,missing_description missing_h1 missing_canonical thin_content\n"
+	if string(data) != wantCSV {
+		t.Errorf("CSV = %q, want %q", data, wantCSV)
+	}
+
+	page, err := os.ReadFile(filepath.Join(cfg.OutputDir, "seo-report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"1 of 1 pages flagged", "Fish &amp; &lt;Chips&gt;", "<td>missing_h1</td><td>1</td>"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("HTML report lacks %q", want)
+		}
+	}
+}
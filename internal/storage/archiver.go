@@ -4,6 +4,7 @@
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net/http"
 	"time"
 
 	"web-crawler/internal/config"
@@ -11,19 +12,126 @@
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// log is the logger of the storage package
+var log = logger.For("storage")
+
+// Outlink holds the metadata of a link found on a crawled page
+type Outlink struct {
+	URL     string   `json:"url" bson:"url"`
+	Text    string   `json:"text,omitempty" bson:"text,omitempty"`
+	Rel     []string `json:"rel,omitempty" bson:"rel,omitempty"`
+	Section string   `json:"section,omitempty" bson:"section,omitempty"`
+	Element string   `json:"element,omitempty" bson:"element,omitempty"`
+}
+
+// RedirectHop is one redirect followed before reaching the stored page
+type RedirectHop struct {
+	URL        string `json:"url" bson:"url"`
+	StatusCode int    `json:"status_code" bson:"status_code"`
+}
+
+// Article is the main content of a page found by readability extraction
+type Article struct {
+	Title     string    `json:"title,omitempty" bson:"title,omitempty"`
+	Author    string    `json:"author,omitempty" bson:"author,omitempty"`
+	Published time.Time `json:"published,omitempty" bson:"published,omitempty"`
+	Text      string    `json:"text" bson:"text"`
+}
+
+// StructuredData is the schema.org style data embedded in a page, by syntax.
+// Microdata and RDFa items are stored in the shape of JSON-LD objects.
+type StructuredData struct {
+	JSONLD    []map[string]interface{} `json:"json_ld,omitempty" bson:"json_ld,omitempty"`
+	Microdata []map[string]interface{} `json:"microdata,omitempty" bson:"microdata,omitempty"`
+	RDFa      []map[string]interface{} `json:"rdfa,omitempty" bson:"rdfa,omitempty"`
+}
+
+// Accessibility counts the accessibility problems found on a page
+type Accessibility struct {
+	Images          int `json:"images" bson:"images"`
+	ImagesNoAlt     int `json:"images_no_alt" bson:"images_no_alt"` // Images without an alt attribute
+	FormFields      int `json:"form_fields" bson:"form_fields"`
+	UnlabeledFields int `json:"unlabeled_fields" bson:"unlabeled_fields"` // Form fields without a label or ARIA name
+	Headings        int `json:"headings" bson:"headings"`
+	SkippedHeadings int `json:"skipped_headings" bson:"skipped_headings"` // Headings more than one level below the previous
+}
+
+// Issues returns the number of problems found
+func (a *Accessibility) Issues() int {
+	return a.ImagesNoAlt + a.UnlabeledFields + a.SkippedHeadings
+}
+
+// KindGraphQL marks records of GraphQL endpoints, stored next to the pages
+const KindGraphQL = "graphql"
+
+// GraphQLEndpoint is what probing a GraphQL endpoint found
+type GraphQLEndpoint struct {
+	Introspection bool            `json:"introspection" bson:"introspection"` // The server answered the introspection query
+	QueryType     string          `json:"query_type,omitempty" bson:"query_type,omitempty"`
+	MutationType  string          `json:"mutation_type,omitempty" bson:"mutation_type,omitempty"`
+	Types         int             `json:"types,omitempty" bson:"types,omitempty"`   // Named types of the schema, built-in ones included
+	Schema        interface{}     `json:"schema,omitempty" bson:"schema,omitempty"` // __schema of the introspection result
+	Samples       []GraphQLSample `json:"samples,omitempty" bson:"samples,omitempty"`
+}
+
+// GraphQLSample is a query run on a GraphQL endpoint and its result
+type GraphQLSample struct {
+	Query  string      `json:"query" bson:"query"`
+	Data   interface{} `json:"data,omitempty" bson:"data,omitempty"`
+	Errors []string    `json:"errors,omitempty" bson:"errors,omitempty"`
+}
+
+// ContentChange is an entry of the change history of a page
+type ContentChange struct {
+	At       time.Time `json:"at" bson:"at"`
+	Hash     string    `json:"hash" bson:"hash"`
+	Previous string    `json:"previous,omitempty" bson:"previous,omitempty"` // Hash before the change, empty for the first crawl
+}
+
 // WebPage represents a crawled web page
 type WebPage struct {
-	URL         string    `bson:"url"`
-	Title       string    `bson:"title"`
-	Content     string    `bson:"content"`
-	Links       []string  `bson:"links"`
-	CrawledAt   time.Time `bson:"crawled_at"`
-	StatusCode  int       `bson:"status_code"`
-	ContentType string    `bson:"content_type"`
+	URL          string                 `json:"url" bson:"url"`
+	Kind         string                 `json:"kind,omitempty" bson:"kind,omitempty"` // Empty for pages, KindGraphQL for GraphQL endpoints
+	RequestedURL string                 `json:"requested_url,omitempty" bson:"requested_url,omitempty"`
+	FinalURL     string                 `json:"final_url,omitempty" bson:"final_url,omitempty"`
+	CanonicalURL string                 `json:"canonical_url,omitempty" bson:"canonical_url,omitempty"`
+	Redirects    []RedirectHop          `json:"redirects,omitempty" bson:"redirects,omitempty"`
+	Title        string                 `json:"title" bson:"title"`
+	Content      string                 `json:"content" bson:"content"`
+	Text         string                 `json:"text,omitempty" bson:"text,omitempty"` // Cleaned plain text of the content
+	Article      *Article               `json:"article,omitempty" bson:"article,omitempty"`
+	Metadata     map[string]string      `json:"metadata,omitempty" bson:"metadata,omitempty"` // OpenGraph, Twitter card, description and keywords
+	Structured   *StructuredData        `json:"structured_data,omitempty" bson:"structured_data,omitempty"`
+	A11y         *Accessibility         `json:"accessibility,omitempty" bson:"accessibility,omitempty"`
+	Extracted    map[string]interface{} `json:"extracted,omitempty" bson:"extracted,omitempty"` // Fields of the extraction rules
+	JSON         interface{}            `json:"json,omitempty" bson:"json,omitempty"`           // Parsed body of JSON responses
+	GraphQL      *GraphQLEndpoint       `json:"graphql,omitempty" bson:"graphql,omitempty"`
+	Links        []string               `json:"links" bson:"links"`
+	Outlinks     []Outlink              `json:"outlinks,omitempty" bson:"outlinks,omitempty"`
+	CrawledAt    time.Time              `json:"crawled_at" bson:"crawled_at"`
+	StatusCode   int                    `json:"status_code" bson:"status_code"`
+	ContentType  string                 `json:"content_type" bson:"content_type"`
+	DeclaredType string                 `json:"declared_type,omitempty" bson:"declared_type,omitempty"`
+	DetectedType string                 `json:"detected_type,omitempty" bson:"detected_type,omitempty"`
+	Charset      string                 `json:"charset,omitempty" bson:"charset,omitempty"`     // Charset the body was transcoded from
+	Language     string                 `json:"language,omitempty" bson:"language,omitempty"`   // ISO 639 code, empty if unknown
+	Relevance    float64                `json:"relevance,omitempty" bson:"relevance,omitempty"` // Focused crawling score from 0 to 1
+	ETag         string                 `json:"etag,omitempty" bson:"etag,omitempty"`
+	LastModified string                 `json:"last_modified,omitempty" bson:"last_modified,omitempty"`
+	CheckedAt    time.Time              `json:"checked_at" bson:"checked_at"`                         // Last fetch, including 304 responses
+	ContentHash  string                 `json:"content_hash,omitempty" bson:"content_hash,omitempty"` // SHA-256 of the response body
+	Changed      bool                   `json:"changed" bson:"changed"`                               // Content differs from the previous crawl
+	ChangedAt    time.Time              `json:"changed_at,omitempty" bson:"changed_at,omitempty"`     // Last time the content was new
+	Changes      []ContentChange        `json:"changes,omitempty" bson:"changes,omitempty"`           // History, oldest first; only the new entries when storing
+	Headers      http.Header            `json:"headers,omitempty" bson:"headers,omitempty"`
+	Body         []byte                 `json:"-" bson:"-"`                                 // Raw response body, only kept for archivers that store it
+	Raw          *RawRecord             `json:"-" bson:"-"`                                 // Exact HTTP exchange, only kept while raw responses are archived
+	RawRef       string                 `json:"raw_ref,omitempty" bson:"raw_ref,omitempty"` // Where the raw exchange is stored
 }
 
 // Archiver defines the interface for storing crawled pages
@@ -32,15 +140,39 @@ type Archiver interface {
 	Close(ctx context.Context) error
 }
 
+// PageState is what the last crawl of a page stored
+type PageState struct {
+	ETag         string `bson:"etag"`
+	LastModified string `bson:"last_modified"`
+	ContentHash  string `bson:"content_hash"`
+}
+
+// ValidatorStore is implemented by archivers that can serve cache validators
+// for conditional recrawls and the content hash for change detection
+type ValidatorStore interface {
+	// State returns what was stored for url, nil if it was never stored
+	State(ctx context.Context, url string) (*PageState, error)
+	// MarkUnchanged records a 304 response without re-storing the content
+	MarkUnchanged(ctx context.Context, url string, checkedAt time.Time) error
+}
+
 // MongoArchiver implements the Archiver interface using MongoDB
 type MongoArchiver struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	codec      *contentCodec
+	history    int          // Change history entries kept per page
+	batch      *batchWriter // nil when every page is written synchronously
 }
 
 // NewMongoArchiver creates a new MongoDB archiver
 func NewMongoArchiver(uri string, cfg config.MongoDBConfig) (*MongoArchiver, error) {
-	logger.Info("Initializing MongoDB connection...")
+	log.Info("Initializing MongoDB connection...")
+
+	codec, err := newContentCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
@@ -66,12 +198,14 @@ func NewMongoArchiver(uri string, cfg config.MongoDBConfig) (*MongoArchiver, err
 		SetDirect(false).
 		SetCompressors([]string{"snappy"}).
 		SetReadPreference(readpref.Primary()).
-		SetHeartbeatInterval(10 * time.Second)
+		SetHeartbeatInterval(10 * time.Second).
+		// Decode nested structured data as maps rather than bson.D
+		SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
-		logger.Error("Failed to create MongoDB client: %v", err)
+		log.Error("Failed to create MongoDB client: %v", err)
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
@@ -80,7 +214,7 @@ func NewMongoArchiver(uri string, cfg config.MongoDBConfig) (*MongoArchiver, err
 	defer pingCancel()
 
 	if err := client.Ping(pingCtx, readpref.Primary()); err != nil {
-		logger.Error("Failed to ping MongoDB: %v", err)
+		log.Error("Failed to ping MongoDB: %v", err)
 		// Close the client if ping fails
 		closeCtx, closeCancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer closeCancel()
@@ -88,7 +222,7 @@ func NewMongoArchiver(uri string, cfg config.MongoDBConfig) (*MongoArchiver, err
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	logger.Success("Successfully connected to MongoDB")
+	log.Success("Successfully connected to MongoDB")
 
 	// Get collection and ensure index
 	collection := client.Database(cfg.Database).Collection(cfg.Collection)
@@ -102,56 +236,232 @@ func NewMongoArchiver(uri string, cfg config.MongoDBConfig) (*MongoArchiver, err
 	if _, err := collection.Indexes().CreateOne(ctx, indexModel); err != nil {
 		// If error is not because index already exists, return error
 		if !mongo.IsDuplicateKeyError(err) {
-			logger.Error("Failed to create index: %v", err)
+			log.Error("Failed to create index: %v", err)
 			return nil, fmt.Errorf("failed to create index: %w", err)
 		}
 	}
 
-	logger.Info("Using database: %s, collection: %s", cfg.Database, cfg.Collection)
-	return &MongoArchiver{
+	log.Info("Using database: %s, collection: %s", cfg.Database, cfg.Collection)
+	m := &MongoArchiver{
 		client:     client,
 		collection: collection,
-	}, nil
+		codec:      codec,
+		history:    cfg.ChangeHistory,
+	}
+	if codec.encoding != EncodingNone {
+		log.Info("Compressing page content with %s", codec.encoding)
+	}
+	if cfg.BatchSize > 1 {
+		m.batch = newBatchWriter(collection, codec, cfg)
+		log.Info("Writing pages in batches of %d, flushed every %s", cfg.BatchSize, cfg.FlushInterval)
+	}
+	return m, nil
 }
 
-// Store saves a webpage to MongoDB using upsert
+// pageFilter selects the stored document of a page
+func pageFilter(page *WebPage) bson.M {
+	return bson.M{"url": page.URL}
+}
+
+// pageUpdate sets the stored fields of a page, compressing its content with
+// codec. Optional fields left empty, e.g. by a storage projection, are unset
+// so they don't linger from an earlier crawl. New change history entries are
+// appended, keeping the latest history of them.
+func pageUpdate(page *WebPage, codec *contentCodec, history int) bson.M {
+	set := bson.M{
+		"requested_url": page.RequestedURL,
+		"final_url":     page.FinalURL,
+		"canonical_url": page.CanonicalURL,
+		"redirects":     page.Redirects,
+		"title":         page.Title,
+		"crawled_at":    page.CrawledAt,
+		"status_code":   page.StatusCode,
+		"content_type":  page.ContentType,
+		"charset":       page.Charset,
+		"language":      page.Language,
+		"etag":          page.ETag,
+		"last_modified": page.LastModified,
+		"checked_at":    page.CrawledAt,
+		"changed":       page.Changed,
+	}
+	unset := bson.M{}
+	optional := func(key string, value interface{}, empty bool) {
+		if empty {
+			unset[key] = ""
+		} else {
+			set[key] = value
+		}
+	}
+
+	if page.Content != "" {
+		content, encoding := codec.encode(page.Content)
+		set["content"] = content
+		optional("content_encoding", encoding, encoding == "")
+	} else {
+		unset["content"] = ""
+		unset["content_encoding"] = ""
+	}
+	optional("text", page.Text, page.Text == "")
+	optional("article", page.Article, page.Article == nil)
+	optional("metadata", page.Metadata, len(page.Metadata) == 0)
+	optional("structured_data", page.Structured, page.Structured == nil)
+	optional("accessibility", page.A11y, page.A11y == nil)
+	optional("declared_type", page.DeclaredType, page.DeclaredType == "")
+	optional("detected_type", page.DetectedType, page.DetectedType == "")
+	optional("links", page.Links, len(page.Links) == 0)
+	optional("outlinks", page.Outlinks, len(page.Outlinks) == 0)
+	optional("headers", page.Headers, len(page.Headers) == 0)
+	optional("raw_ref", page.RawRef, page.RawRef == "")
+	if page.ContentHash != "" {
+		set["content_hash"] = page.ContentHash
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if n := len(page.Changes); n > 0 {
+		set["changed_at"] = page.Changes[n-1].At
+		if history > 0 {
+			update["$push"] = bson.M{"changes": bson.M{"$each": page.Changes, "$slice": -history}}
+		}
+	}
+	return update
+}
+
+// Store saves a webpage to MongoDB using upsert. With batching the page is
+// queued and written in the background; write errors are logged and counted
+// in GetStats instead of being returned.
 func (m *MongoArchiver) Store(ctx context.Context, page *WebPage) error {
-	filter := bson.M{"url": page.URL}
-	update := bson.M{
-		"$set": bson.M{
-			"title":        page.Title,
-			"content":      page.Content,
-			"links":        page.Links,
-			"crawled_at":   page.CrawledAt,
-			"status_code":  page.StatusCode,
-			"content_type": page.ContentType,
-		},
+	if m.batch != nil {
+		return m.batch.add(ctx, page)
 	}
-	opts := options.Update().SetUpsert(true)
 
-	result, err := m.collection.UpdateOne(ctx, filter, update, opts)
+	opts := options.Update().SetUpsert(true)
+	result, err := m.collection.UpdateOne(ctx, pageFilter(page), pageUpdate(page, m.codec, m.history), opts)
 	if err != nil {
-		logger.Error("Failed to store/update webpage %s: %v", page.URL, err)
+		log.Error("Failed to store/update webpage %s: %v", page.URL, err)
 		return fmt.Errorf("failed to store/update webpage: %w", err)
 	}
 
 	// Log whether this was an insert or update
 	if result.UpsertedCount > 0 {
-		logger.StorageStatus(page.URL, false) // New document
+		log.StorageStatus(page.URL, false) // New document
 	} else {
-		logger.StorageStatus(page.URL, true) // Updated document
+		log.StorageStatus(page.URL, true) // Updated document
 	}
 
 	return nil
 }
 
-// Close closes the MongoDB connection
+// State returns the cache validators and content hash stored for a URL
+func (m *MongoArchiver) State(ctx context.Context, url string) (*PageState, error) {
+	var state PageState
+	opts := options.FindOne().SetProjection(bson.M{"etag": 1, "last_modified": 1, "content_hash": 1})
+	err := m.collection.FindOne(ctx, bson.M{"url": url}, opts).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load page state: %w", err)
+	}
+	return &state, nil
+}
+
+// MarkUnchanged updates only the check time of a page that answered 304
+func (m *MongoArchiver) MarkUnchanged(ctx context.Context, url string, checkedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"checked_at": checkedAt, "changed": false}}
+	if _, err := m.collection.UpdateOne(ctx, bson.M{"url": url}, update); err != nil {
+		log.Error("Failed to mark %s unchanged: %v", url, err)
+		return fmt.Errorf("failed to mark page unchanged: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of stored pages
+func (m *MongoArchiver) Count(ctx context.Context) (int64, error) {
+	n, err := m.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pages: %w", err)
+	}
+	return n, nil
+}
+
+// Each streams the pages matching query through a cursor, calling fn for
+// each until fn returns an error
+func (m *MongoArchiver) Each(ctx context.Context, query PageQuery, fn func(*WebPage) error) error {
+	opts := options.Find().SetBatchSize(500)
+	if projection := query.projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	cursor, err := m.collection.Find(ctx, query.filter(), opts)
+	if err != nil {
+		return fmt.Errorf("failed to query pages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var stored storedPage
+		if err := cursor.Decode(&stored); err != nil {
+			return fmt.Errorf("failed to decode page: %w", err)
+		}
+		page, err := stored.page()
+		if err != nil {
+			return fmt.Errorf("failed to decode page %s: %w", stored.URL, err)
+		}
+		if query.Changed {
+			query.changesBetween(page)
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Flush writes the pages queued for batching so far
+func (m *MongoArchiver) Flush(ctx context.Context) error {
+	if m.batch == nil {
+		return nil
+	}
+	return m.batch.flush(ctx)
+}
+
+// GetStats returns compression and, with batching, write statistics
+func (m *MongoArchiver) GetStats() map[string]int64 {
+	stats := m.codec.GetStats()
+	if m.batch != nil {
+		for k, v := range m.batch.GetStats() {
+			stats[k] = v
+		}
+	}
+	return stats
+}
+
+// Collection returns a collection of the archiver's database, sharing its
+// connection
+func (m *MongoArchiver) Collection(name string) *mongo.Collection {
+	return m.collection.Database().Collection(name)
+}
+
+// GridFS returns a GridFS bucket of the archiver's database
+func (m *MongoArchiver) GridFS(name string) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(m.collection.Database(), options.GridFSBucket().SetName(name))
+}
+
+// Close writes the queued pages and closes the MongoDB connection
 func (m *MongoArchiver) Close(ctx context.Context) error {
-	logger.Info("Closing MongoDB connection...")
+	if m.batch != nil {
+		if err := m.batch.close(ctx); err != nil {
+			log.Error("Failed to write queued pages: %v", err)
+		}
+	}
+
+	log.Info("Closing MongoDB connection...")
 	if err := m.client.Disconnect(ctx); err != nil {
-		logger.Error("Failed to disconnect from MongoDB: %v", err)
+		log.Error("Failed to disconnect from MongoDB: %v", err)
 		return fmt.Errorf("failed to disconnect: %w", err)
 	}
-	logger.Success("MongoDB connection closed")
+	log.Success("MongoDB connection closed")
 	return nil
 }
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"web-crawler/internal/benchmark"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRunStore keeps benchmark runs in a MongoDB collection, keyed by run ID
+type MongoRunStore struct {
+	collection *mongo.Collection
+}
+
+// BenchmarkRuns returns a benchmark run store using the given collection in
+// the archiver's database, sharing its connection
+func (m *MongoArchiver) BenchmarkRuns(collection string) *MongoRunStore {
+	return &MongoRunStore{collection: m.collection.Database().Collection(collection)}
+}
+
+// Save upserts the run
+func (s *MongoRunStore) Save(ctx context.Context, run benchmark.StoredRun) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.collection.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, opts); err != nil {
+		return fmt.Errorf("failed to store benchmark run: %w", err)
+	}
+	return nil
+}
+
+// Load returns the run whose ID starts with id
+func (s *MongoRunStore) Load(ctx context.Context, id string) (benchmark.StoredRun, error) {
+	if err := benchmark.CheckRunID(id); err != nil {
+		return benchmark.StoredRun{}, err
+	}
+	// IDs are hex, the prefix needs no escaping
+	cursor, err := s.collection.Find(ctx, bson.M{"_id": bson.M{"$regex": "^" + id}}, options.Find().SetLimit(2))
+	if err != nil {
+		return benchmark.StoredRun{}, fmt.Errorf("failed to find benchmark run: %w", err)
+	}
+	var runs []benchmark.StoredRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return benchmark.StoredRun{}, fmt.Errorf("failed to decode benchmark run: %w", err)
+	}
+	switch len(runs) {
+	case 0:
+		return benchmark.StoredRun{}, fmt.Errorf("no run %s in collection %s", id, s.collection.Name())
+	case 1:
+		return runs[0], nil
+	default:
+		return benchmark.StoredRun{}, fmt.Errorf("run ID %s is ambiguous", id)
+	}
+}
+
+// List returns every run without its metrics, oldest first
+func (s *MongoRunStore) List(ctx context.Context) ([]benchmark.StoredRun, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created", Value: 1}}).
+		SetProjection(bson.M{"run.metrics": 0})
+	cursor, err := s.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark runs: %w", err)
+	}
+	var runs []benchmark.StoredRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode benchmark runs: %w", err)
+	}
+	return runs, nil
+}
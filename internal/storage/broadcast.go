@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// BroadcastArchiver wraps another Archiver and fans out every stored page to
+// subscribers, e.g. for streaming results to API clients
+type BroadcastArchiver struct {
+	inner Archiver
+
+	mu          sync.RWMutex
+	subscribers map[chan *WebPage]struct{}
+}
+
+// NewBroadcastArchiver creates a broadcasting archiver. inner may be nil when
+// pages are only streamed and not persisted.
+func NewBroadcastArchiver(inner Archiver) *BroadcastArchiver {
+	return &BroadcastArchiver{
+		inner:       inner,
+		subscribers: make(map[chan *WebPage]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving stored pages and a function to unsubscribe.
+// Slow subscribers miss pages instead of blocking the crawl.
+func (b *BroadcastArchiver) Subscribe(buffer int) (<-chan *WebPage, func()) {
+	ch := make(chan *WebPage, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		// Close may already have closed the channel
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Store saves the page with the wrapped archiver and publishes it to subscribers
+func (b *BroadcastArchiver) Store(ctx context.Context, page *WebPage) error {
+	if b.inner != nil {
+		if err := b.inner.Store(ctx, page); err != nil {
+			return err
+		}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- page:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close closes the wrapped archiver and all subscriber channels
+func (b *BroadcastArchiver) Close(ctx context.Context) error {
+	b.mu.Lock()
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	b.mu.Unlock()
+
+	if b.inner != nil {
+		return b.inner.Close(ctx)
+	}
+	return nil
+}
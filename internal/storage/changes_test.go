@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPageUpdateChangeHistory(t *testing.T) {
+	codec, err := newContentCodec(EncodingNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	page := &WebPage{
+		URL:         "https://example.com/",
+		CrawledAt:   at,
+		ContentHash: "bbb",
+		Changed:     true,
+		Changes:     []ContentChange{{At: at, Hash: "bbb", Previous: "aaa"}},
+	}
+
+	update := pageUpdate(page, codec, 5)
+	set := update["$set"].(bson.M)
+	if set["content_hash"] != "bbb" || set["changed"] != true || set["changed_at"] != at {
+		t.Fatalf("$set = %v", set)
+	}
+	want := bson.M{"changes": bson.M{"$each": page.Changes, "$slice": -5}}
+	if !reflect.DeepEqual(update["$push"], want) {
+		t.Fatalf("$push = %v, want %v", update["$push"], want)
+	}
+
+	// An unchanged page keeps its history and change time
+	page.Changed, page.Changes = false, nil
+	update = pageUpdate(page, codec, 5)
+	if _, ok := update["$push"]; ok {
+		t.Fatal("unchanged page pushed a change")
+	}
+	if _, ok := update["$set"].(bson.M)["changed_at"]; ok {
+		t.Fatal("unchanged page moved changed_at")
+	}
+
+	// Without history only the change time is kept
+	page.Changes = []ContentChange{{At: at, Hash: "bbb"}}
+	if update = pageUpdate(page, codec, 0); update["$push"] != nil {
+		t.Fatalf("history 0 pushed %v", update["$push"])
+	}
+}
+
+func TestChangedQuery(t *testing.T) {
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	q := PageQuery{Since: since, Until: until, Changed: true}
+
+	want := bson.M{"changes": bson.M{"$elemMatch": bson.M{
+		"previous": bson.M{"$gt": ""},
+		"at":       bson.M{"$gte": since, "$lt": until},
+	}}}
+	if got := q.filter(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("filter() = %v, want %v", got, want)
+	}
+
+	page := &WebPage{Changes: []ContentChange{
+		{At: since.Add(-time.Hour), Hash: "a"},                  // First crawl
+		{At: since.Add(time.Hour), Hash: "b", Previous: "a"},    // In range
+		{At: until, Hash: "c", Previous: "b"},                   // Until is exclusive
+		{At: since.Add(2 * time.Hour), Hash: "d", Previous: ""}, // Not a change
+	}}
+	q.changesBetween(page)
+	if len(page.Changes) != 1 || page.Changes[0].Hash != "b" {
+		t.Fatalf("changes between = %+v", page.Changes)
+	}
+
+	// Fields of a changed query always include the history
+	q.Fields = []string{"title"}
+	if p := q.projection(); p["changes"] != 1 || p["title"] != 1 {
+		t.Fatalf("projection() = %v", p)
+	}
+}
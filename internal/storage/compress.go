@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"web-crawler/pkg/utils"
+
+	"github.com/klauspost/compress/zstd"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Content encodings of stored page bodies
+const (
+	EncodingNone = "none"
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// contentCodec compresses page content before it is written
+type contentCodec struct {
+	encoding string
+
+	// Counters
+	rawBytes    int64
+	storedBytes int64
+}
+
+func newContentCodec(encoding string) (*contentCodec, error) {
+	switch encoding {
+	case "", EncodingNone:
+		return &contentCodec{encoding: EncodingNone}, nil
+	case EncodingGzip, EncodingZstd:
+		return &contentCodec{encoding: encoding}, nil
+	default:
+		return nil, fmt.Errorf("unknown content compression %q", encoding)
+	}
+}
+
+// encode returns the stored form of content and its content_encoding.
+// Uncompressed content stays a string so existing documents and queries
+// keep working; compressed content is binary.
+func (c *contentCodec) encode(content string) (interface{}, string) {
+	atomic.AddInt64(&c.rawBytes, int64(len(content)))
+
+	var data []byte
+	switch c.encoding {
+	case EncodingGzip:
+		var buf bytes.Buffer
+		// Writes to a bytes.Buffer don't fail
+		zw := gzip.NewWriter(&buf)
+		zw.Write(utils.StringBytes(content))
+		zw.Close()
+		data = buf.Bytes()
+	case EncodingZstd:
+		data = zstdEncoder.EncodeAll(utils.StringBytes(content), nil)
+	default:
+		atomic.AddInt64(&c.storedBytes, int64(len(content)))
+		return content, ""
+	}
+
+	atomic.AddInt64(&c.storedBytes, int64(len(data)))
+	return data, c.encoding
+}
+
+// GetStats returns compression statistics
+func (c *contentCodec) GetStats() map[string]int64 {
+	return map[string]int64{
+		"contentBytes":       atomic.LoadInt64(&c.rawBytes),
+		"storedContentBytes": atomic.LoadInt64(&c.storedBytes),
+	}
+}
+
+// decodeContent restores the content of a stored document, compressed or not
+func decodeContent(raw bson.RawValue, encoding string) (string, error) {
+	switch raw.Type {
+	case 0, bsontype.Null:
+		return "", nil
+	case bsontype.String:
+		return raw.StringValue(), nil
+	case bsontype.Binary:
+	default:
+		return "", fmt.Errorf("unexpected content type %s", raw.Type)
+	}
+
+	_, data := raw.Binary()
+	switch encoding {
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress content: %w", err)
+		}
+		defer zr.Close()
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress content: %w", err)
+		}
+		return string(content), nil
+	case EncodingZstd:
+		content, err := zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress content: %w", err)
+		}
+		return string(content), nil
+	default:
+		return "", fmt.Errorf("unknown content encoding %q", encoding)
+	}
+}
+
+// storedPage is a WebPage as read back from MongoDB, before its content is
+// decompressed. The outer content field shadows the embedded one.
+type storedPage struct {
+	WebPage         `bson:",inline"`
+	Content         bson.RawValue `bson:"content"`
+	ContentEncoding string        `bson:"content_encoding,omitempty"`
+}
+
+// page returns the WebPage with its content restored
+func (s *storedPage) page() (*WebPage, error) {
+	content, err := decodeContent(s.Content, s.ContentEncoding)
+	if err != nil {
+		return nil, err
+	}
+	page := s.WebPage
+	page.Content = content
+	return &page, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// readBack marshals doc to BSON and reads it back as a stored page
+func readBack(t *testing.T, doc bson.M) (*WebPage, error) {
+	t.Helper()
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored storedPage
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored.page()
+}
+
+func TestContentCodecRoundTrip(t *testing.T) {
+	content := strings.Repeat("<p>Hello, world</p>\n", 200)
+	for _, encoding := range []string{"", EncodingNone, EncodingGzip, EncodingZstd} {
+		codec, err := newContentCodec(encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, storedEncoding := codec.encode(content)
+
+		doc := bson.M{"url": "https://example.com/", "title": "Hello", "content": stored}
+		if storedEncoding != "" {
+			doc["content_encoding"] = storedEncoding
+		}
+		page, err := readBack(t, doc)
+		if err != nil {
+			t.Fatalf("%q: %v", encoding, err)
+		}
+		if page.Content != content || page.URL != "https://example.com/" || page.Title != "Hello" {
+			t.Fatalf("%q: read back %q with %d bytes of content", encoding, page.URL, len(page.Content))
+		}
+
+		stats := codec.GetStats()
+		compressed := encoding == EncodingGzip || encoding == EncodingZstd
+		if stats["contentBytes"] != int64(len(content)) || compressed != (stats["storedContentBytes"] < stats["contentBytes"]) {
+			t.Errorf("%q: GetStats() = %v", encoding, stats)
+		}
+	}
+
+	if _, err := newContentCodec("brotli"); err == nil {
+		t.Error("newContentCodec() accepted an unknown compression")
+	}
+}
+
+func TestDecodeLegacyContent(t *testing.T) {
+	// Documents written before compression have plain string content
+	page, err := readBack(t, bson.M{"url": "https://example.com/", "content": "<html></html>"})
+	if err != nil || page.Content != "<html></html>" {
+		t.Fatalf("legacy page = %+v, %v", page, err)
+	}
+	// Projections may leave the content out
+	for _, doc := range []bson.M{{"url": "https://example.com/"}, {"url": "https://example.com/", "content": nil}} {
+		if page, err := readBack(t, doc); err != nil || page.Content != "" {
+			t.Errorf("page without content = %+v, %v", page, err)
+		}
+	}
+}
+
+func TestDecodeContentErrors(t *testing.T) {
+	tests := map[string]bson.M{
+		"corrupt gzip":     {"content": []byte("not gzip"), "content_encoding": EncodingGzip},
+		"corrupt zstd":     {"content": []byte("not zstd"), "content_encoding": EncodingZstd},
+		"unknown encoding": {"content": []byte("data"), "content_encoding": "brotli"},
+		"wrong type":       {"content": 42},
+	}
+	for name, doc := range tests {
+		if _, err := readBack(t, doc); err == nil {
+			t.Errorf("%s: read back without an error", name)
+		}
+	}
+}
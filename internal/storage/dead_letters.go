@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"web-crawler/internal/queue"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoDeadLetters stores permanently failed URLs in a MongoDB collection
+type MongoDeadLetters struct {
+	collection *mongo.Collection
+}
+
+// DeadLetters returns a dead-letter store using the given collection in the
+// archiver's database, sharing its connection
+func (m *MongoArchiver) DeadLetters(ctx context.Context, collection string) (*MongoDeadLetters, error) {
+	coll := m.collection.Database().Collection(collection)
+
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "url", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := coll.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	return &MongoDeadLetters{collection: coll}, nil
+}
+
+// Add upserts the dead letter for the URL
+func (d *MongoDeadLetters) Add(ctx context.Context, entry queue.DeadLetter) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := d.collection.ReplaceOne(ctx, bson.M{"url": entry.URL}, entry, opts); err != nil {
+		return fmt.Errorf("failed to store dead letter: %w", err)
+	}
+	return nil
+}
+
+// List returns all dead letters ordered by failure time
+func (d *MongoDeadLetters) List(ctx context.Context) ([]queue.DeadLetter, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "failed_at", Value: 1}})
+	cursor, err := d.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	var entries []queue.DeadLetter
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	return entries, nil
+}
+
+// Remove deletes dead letters by URL
+func (d *MongoDeadLetters) Remove(ctx context.Context, urls ...string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	if _, err := d.collection.DeleteMany(ctx, bson.M{"url": bson.M{"$in": urls}}); err != nil {
+		return fmt.Errorf("failed to remove dead letters: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of dead letters
+func (d *MongoDeadLetters) Len(ctx context.Context) (int, error) {
+	n, err := d.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return int(n), nil
+}
+
+// Close is a no-op, the connection is owned by the archiver
+func (d *MongoDeadLetters) Close() error {
+	return nil
+}
@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// exportFields reads the exportable fields of a page, keyed by their JSON and BSON name
+var exportFields = map[string]func(*WebPage) interface{}{
+	"url":             func(p *WebPage) interface{} { return p.URL },
+	"kind":            func(p *WebPage) interface{} { return p.Kind },
+	"requested_url":   func(p *WebPage) interface{} { return p.RequestedURL },
+	"final_url":       func(p *WebPage) interface{} { return p.FinalURL },
+	"canonical_url":   func(p *WebPage) interface{} { return p.CanonicalURL },
+	"redirects":       func(p *WebPage) interface{} { return p.Redirects },
+	"title":           func(p *WebPage) interface{} { return p.Title },
+	"content":         func(p *WebPage) interface{} { return p.Content },
+	"text":            func(p *WebPage) interface{} { return p.Text },
+	"article":         func(p *WebPage) interface{} { return p.Article },
+	"metadata":        func(p *WebPage) interface{} { return p.Metadata },
+	"structured_data": func(p *WebPage) interface{} { return p.Structured },
+	"accessibility":   func(p *WebPage) interface{} { return p.A11y },
+	"links":           func(p *WebPage) interface{} { return p.Links },
+	"outlinks":        func(p *WebPage) interface{} { return p.Outlinks },
+	"crawled_at":      func(p *WebPage) interface{} { return p.CrawledAt },
+	"status_code":     func(p *WebPage) interface{} { return p.StatusCode },
+	"content_type":    func(p *WebPage) interface{} { return p.ContentType },
+	"declared_type":   func(p *WebPage) interface{} { return p.DeclaredType },
+	"detected_type":   func(p *WebPage) interface{} { return p.DetectedType },
+	"charset":         func(p *WebPage) interface{} { return p.Charset },
+	"language":        func(p *WebPage) interface{} { return p.Language },
+	"etag":            func(p *WebPage) interface{} { return p.ETag },
+	"last_modified":   func(p *WebPage) interface{} { return p.LastModified },
+	"checked_at":      func(p *WebPage) interface{} { return p.CheckedAt },
+	"headers":         func(p *WebPage) interface{} { return p.Headers },
+	"content_hash":    func(p *WebPage) interface{} { return p.ContentHash },
+	"changed":         func(p *WebPage) interface{} { return p.Changed },
+	"changed_at":      func(p *WebPage) interface{} { return p.ChangedAt },
+	"changes":         func(p *WebPage) interface{} { return p.Changes },
+	"graphql":         func(p *WebPage) interface{} { return p.GraphQL },
+}
+
+// DefaultCSVFields are exported to CSV when no fields are selected
+var DefaultCSVFields = []string{"url", "title", "status_code", "content_type", "language", "crawled_at"}
+
+// ChangeFields are exported by default when listing changed pages
+var ChangeFields = []string{"url", "title", "content_hash", "changed_at", "changes"}
+
+// ExportFields returns the names of the exportable fields
+func ExportFields() []string {
+	names := make([]string, 0, len(exportFields))
+	for name := range exportFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PageQuery selects stored pages and the fields read from them
+type PageQuery struct {
+	Since   time.Time // Crawled at or after, unbounded if zero
+	Until   time.Time // Crawled before, unbounded if zero
+	Domains []string  // Hosts, each including its subdomains; all if empty
+	Fields  []string  // Fields to read, all if empty
+
+	// Changed selects pages whose content changed between Since and Until
+	// instead of pages crawled then, with only those changes in their history.
+	// The first crawl of a page isn't a change.
+	Changed bool
+}
+
+// filter returns the MongoDB filter of the query
+func (q PageQuery) filter() bson.M {
+	filter := bson.M{}
+	between := bson.M{}
+	if !q.Since.IsZero() {
+		between["$gte"] = q.Since
+	}
+	if !q.Until.IsZero() {
+		between["$lt"] = q.Until
+	}
+	if q.Changed {
+		change := bson.M{"previous": bson.M{"$gt": ""}}
+		if len(between) > 0 {
+			change["at"] = between
+		}
+		filter["changes"] = bson.M{"$elemMatch": change}
+	} else if len(between) > 0 {
+		filter["crawled_at"] = between
+	}
+	if len(q.Domains) > 0 {
+		quoted := make([]string, len(q.Domains))
+		for i, domain := range q.Domains {
+			quoted[i] = regexp.QuoteMeta(strings.ToLower(strings.TrimPrefix(domain, ".")))
+		}
+		pattern := `^https?://([^/?#@]*@)?([^/?#]*\.)?(` + strings.Join(quoted, "|") + `)(:[0-9]+)?([/?#]|$)`
+		filter["url"] = bson.M{"$regex": pattern, "$options": "i"}
+	}
+	return filter
+}
+
+// changesBetween keeps the changes of page that match a Changed query
+func (q PageQuery) changesBetween(page *WebPage) {
+	var kept []ContentChange
+	for _, change := range page.Changes {
+		if change.Previous == "" || change.At.Before(q.Since) || (!q.Until.IsZero() && !change.At.Before(q.Until)) {
+			continue
+		}
+		kept = append(kept, change)
+	}
+	page.Changes = kept
+}
+
+// projection returns the MongoDB projection of the query, nil for all fields
+func (q PageQuery) projection() bson.M {
+	if len(q.Fields) == 0 {
+		return nil
+	}
+	projection := bson.M{"url": 1}
+	if q.Changed {
+		projection["changes"] = 1
+	}
+	for _, field := range q.Fields {
+		projection[field] = 1
+		if field == "content" {
+			projection["content_encoding"] = 1
+		}
+	}
+	return projection
+}
+
+// Exporter writes pages as JSON lines or CSV rows
+type Exporter struct {
+	writer *bufio.Writer
+	csv    *csv.Writer // Nil for JSON lines
+	fields []string    // Nil writes whole pages as JSON
+	count  int
+}
+
+// NewExporter creates an exporter writing the selected fields in format
+// jsonl or csv. CSV without fields uses DefaultCSVFields.
+func NewExporter(w io.Writer, format string, fields []string) (*Exporter, error) {
+	for _, field := range fields {
+		if _, ok := exportFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(ExportFields(), ", "))
+		}
+	}
+
+	e := &Exporter{writer: bufio.NewWriterSize(w, 64*1024), fields: fields}
+	switch format {
+	case "jsonl":
+	case "csv":
+		if len(e.fields) == 0 {
+			e.fields = DefaultCSVFields
+		}
+		e.csv = csv.NewWriter(e.writer)
+		if err := e.csv.Write(e.fields); err != nil {
+			return nil, fmt.Errorf("failed to write csv header: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown export format %q, expected jsonl or csv", format)
+	}
+	return e, nil
+}
+
+// Fields returns the exported fields, nil if whole pages are exported
+func (e *Exporter) Fields() []string {
+	return e.fields
+}
+
+// Write exports one page
+func (e *Exporter) Write(page *WebPage) error {
+	e.count++
+	if e.csv != nil {
+		record := make([]string, len(e.fields))
+		for i, field := range e.fields {
+			value, err := csvValue(exportFields[field](page))
+			if err != nil {
+				return fmt.Errorf("failed to encode %s of %s: %w", field, page.URL, err)
+			}
+			record[i] = value
+		}
+		return e.csv.Write(record)
+	}
+
+	if len(e.fields) == 0 {
+		line, err := json.Marshal(page)
+		if err != nil {
+			return fmt.Errorf("failed to encode page %s: %w", page.URL, err)
+		}
+		e.writer.Write(line)
+		return e.writer.WriteByte('\n')
+	}
+
+	// Fields in the selected order, which a map would lose
+	e.writer.WriteByte('{')
+	for i, field := range e.fields {
+		value, err := json.Marshal(exportFields[field](page))
+		if err != nil {
+			return fmt.Errorf("failed to encode %s of %s: %w", field, page.URL, err)
+		}
+		if i > 0 {
+			e.writer.WriteByte(',')
+		}
+		e.writer.WriteString(strconv.Quote(field) + ":")
+		e.writer.Write(value)
+	}
+	e.writer.WriteString("}\n")
+	return nil
+}
+
+// Count returns the number of pages written
+func (e *Exporter) Count() int {
+	return e.count
+}
+
+// Close flushes the buffered output
+func (e *Exporter) Close() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+	if err := e.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// csvValue formats a field for a CSV cell: scalars as text, times as
+// RFC 3339 and everything else as JSON
+func csvValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case time.Time:
+		if v.IsZero() {
+			return "", nil
+		}
+		return v.UTC().Format(time.RFC3339), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if s := string(data); s != "null" {
+		return s, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bulkWriter is the part of a collection the batch writer uses
+type bulkWriter interface {
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// batchWriter buffers stored pages and upserts them with unordered
+// BulkWrites from a single goroutine
+type batchWriter struct {
+	collection bulkWriter
+	codec      *contentCodec
+	history    int
+	size       int
+	interval   time.Duration
+	timeout    time.Duration
+
+	pages   chan *WebPage
+	flushes chan chan error
+	done    chan struct{}
+	closeMu sync.RWMutex
+	closed  bool
+
+	// Counters
+	written int64
+	failed  int64
+	batches int64
+}
+
+func newBatchWriter(collection bulkWriter, codec *contentCodec, cfg config.MongoDBConfig) *batchWriter {
+	w := &batchWriter{
+		collection: collection,
+		codec:      codec,
+		history:    cfg.ChangeHistory,
+		size:       cfg.BatchSize,
+		interval:   cfg.FlushInterval,
+		timeout:    cfg.Timeout,
+		pages:      make(chan *WebPage, cfg.BufferSize),
+		flushes:    make(chan chan error),
+		done:       make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// add queues a page, blocking while the buffer is full
+func (w *batchWriter) add(ctx context.Context, page *WebPage) error {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+
+	if w.closed {
+		return fmt.Errorf("failed to store webpage: archiver is closed")
+	}
+	select {
+	case w.pages <- page:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to store webpage: %w", ctx.Err())
+	}
+}
+
+// flush writes every page added so far
+func (w *batchWriter) flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case w.flushes <- reply:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+}
+
+// close writes the remaining pages and stops the writer
+func (w *batchWriter) close(ctx context.Context) error {
+	w.closeMu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.pages)
+	}
+	w.closeMu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush pages: %w", ctx.Err())
+	}
+}
+
+// run collects pages into batches and writes a batch when it is full, when
+// its oldest page has waited a flush interval, or on request
+func (w *batchWriter) run() {
+	defer close(w.done)
+
+	timer := time.NewTimer(w.interval)
+	timer.Stop()
+
+	batch := make([]*WebPage, 0, w.size)
+	write := func() error {
+		timer.Stop()
+		if len(batch) == 0 {
+			return nil
+		}
+		err := w.write(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case page, ok := <-w.pages:
+			if !ok {
+				write()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(w.interval)
+			}
+			batch = append(batch, page)
+			if len(batch) >= w.size {
+				write()
+			}
+		case <-timer.C:
+			write()
+		case reply := <-w.flushes:
+			// Pick up pages added before the flush was requested
+			var err error
+			for n := len(w.pages); n > 0; n-- {
+				batch = append(batch, <-w.pages)
+				if len(batch) >= w.size {
+					err = errors.Join(err, write())
+				}
+			}
+			reply <- errors.Join(err, write())
+		}
+	}
+}
+
+// write upserts one batch. Pages that fail are logged and counted; the
+// rest of the batch is still written.
+func (w *batchWriter) write(batch []*WebPage) error {
+	models := make([]mongo.WriteModel, len(batch))
+	for i, page := range batch {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(pageFilter(page)).
+			SetUpdate(pageUpdate(page, w.codec, w.history)).
+			SetUpsert(true)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	atomic.AddInt64(&w.batches, 1)
+	result, err := w.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	failed := make(map[int]bool)
+	var bulkErr mongo.BulkWriteException
+	switch {
+	case err == nil:
+	case errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 && bulkErr.WriteConcernError == nil:
+		for _, we := range bulkErr.WriteErrors {
+			failed[we.Index] = true
+			log.Error("Failed to store/update webpage %s: %v", batch[we.Index].URL, we.Message)
+		}
+	default:
+		// Nothing in the batch is known to be written
+		atomic.AddInt64(&w.failed, int64(len(batch)))
+		log.Error("Failed to store %d webpages: %v", len(batch), err)
+		return fmt.Errorf("failed to store webpages: %w", err)
+	}
+
+	for i, page := range batch {
+		if failed[i] {
+			continue
+		}
+		_, inserted := result.UpsertedIDs[int64(i)]
+		log.StorageStatus(page.URL, !inserted)
+	}
+	atomic.AddInt64(&w.written, int64(len(batch)-len(failed)))
+	atomic.AddInt64(&w.failed, int64(len(failed)))
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to store %d of %d webpages: %w", len(failed), len(batch), err)
+	}
+	return nil
+}
+
+// GetStats returns batch writer statistics
+func (w *batchWriter) GetStats() map[string]int64 {
+	return map[string]int64{
+		"buffered": int64(len(w.pages)),
+		"written":  atomic.LoadInt64(&w.written),
+		"failed":   atomic.LoadInt64(&w.failed),
+		"batches":  atomic.LoadInt64(&w.batches),
+	}
+}
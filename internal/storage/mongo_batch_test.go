@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeCollection records the URLs of each BulkWrite
+type fakeCollection struct {
+	mu      sync.Mutex
+	batches []string
+	wrote   chan string
+	release chan struct{} // Writes wait for it unless nil
+	fail    map[string]bool
+}
+
+func newFakeCollection() *fakeCollection {
+	return &fakeCollection{wrote: make(chan string, 100), fail: make(map[string]bool)}
+}
+
+func (c *fakeCollection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if c.release != nil {
+		<-c.release
+	}
+	result := &mongo.BulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	var bulkErr mongo.BulkWriteException
+	urls := make([]string, len(models))
+	for i, model := range models {
+		urls[i] = model.(*mongo.UpdateOneModel).Filter.(bson.M)["url"].(string)
+		if c.fail[urls[i]] {
+			bulkErr.WriteErrors = append(bulkErr.WriteErrors, mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Message: "duplicate key"}})
+			continue
+		}
+		result.UpsertedIDs[int64(i)] = urls[i]
+	}
+
+	batch := strings.Join(urls, " ")
+	c.mu.Lock()
+	c.batches = append(c.batches, batch)
+	c.mu.Unlock()
+	c.wrote <- batch
+	if len(bulkErr.WriteErrors) > 0 {
+		return result, bulkErr
+	}
+	return result, nil
+}
+
+// next waits for the next batch written
+func (c *fakeCollection) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case batch := <-c.wrote:
+		return batch
+	case <-time.After(2 * time.Second):
+		t.Fatal("no batch written")
+		return ""
+	}
+}
+
+// written returns the batches written so far
+func (c *fakeCollection) written() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.batches...)
+}
+
+func newTestBatchWriter(t *testing.T, c *fakeCollection, size int, interval time.Duration) *batchWriter {
+	t.Helper()
+	codec, err := newContentCodec(EncodingNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newBatchWriter(c, codec, config.MongoDBConfig{
+		BatchSize:     size,
+		FlushInterval: interval,
+		BufferSize:    4,
+		Timeout:       time.Second,
+	})
+}
+
+func addPages(t *testing.T, w *batchWriter, urls ...string) {
+	t.Helper()
+	for _, u := range urls {
+		if err := w.add(context.Background(), &WebPage{URL: u}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBatchWriterFlushesFullBatch(t *testing.T) {
+	c := newFakeCollection()
+	w := newTestBatchWriter(t, c, 2, time.Hour)
+	defer w.close(context.Background())
+
+	addPages(t, w, "a", "b", "c")
+	if batch := c.next(t); batch != "a b" {
+		t.Fatalf("first batch = %q, want a b", batch)
+	}
+	// The last page waits for the batch to fill up
+	time.Sleep(20 * time.Millisecond)
+	if got := c.written(); len(got) != 1 {
+		t.Fatalf("batches = %q, want one", got)
+	}
+}
+
+func TestBatchWriterFlushesAfterInterval(t *testing.T) {
+	c := newFakeCollection()
+	w := newTestBatchWriter(t, c, 100, 20*time.Millisecond)
+	defer w.close(context.Background())
+
+	start := time.Now()
+	addPages(t, w, "a", "b")
+	if batch := c.next(t); batch != "a b" {
+		t.Fatalf("batch = %q, want a b", batch)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("batch written after %v, before the flush interval", waited)
+	}
+}
+
+func TestBatchWriterFlush(t *testing.T) {
+	c := newFakeCollection()
+	c.fail["c"] = true
+	w := newTestBatchWriter(t, c, 2, time.Hour)
+	defer w.close(context.Background())
+
+	// The full batch may be written before the flush, the last page only by it
+	addPages(t, w, "a", "b", "c")
+	err := w.flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to store 1 of 1 webpages") {
+		t.Fatalf("flush() = %v, want the failed page reported", err)
+	}
+	if got := fmt.Sprint(c.written()); got != "[a b c]" {
+		t.Fatalf("batches = %s", got)
+	}
+
+	// An empty flush writes nothing
+	if err := w.flush(context.Background()); err != nil || len(c.written()) != 2 {
+		t.Fatalf("empty flush() = %v with %d batches", err, len(c.written()))
+	}
+	stats := w.GetStats()
+	if stats["written"] != 2 || stats["failed"] != 1 || stats["batches"] != 2 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+}
+
+func TestBatchWriterClose(t *testing.T) {
+	c := newFakeCollection()
+	w := newTestBatchWriter(t, c, 10, time.Hour)
+
+	addPages(t, w, "a", "b")
+	if err := w.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(c.written()); got != "[a b]" {
+		t.Fatalf("batches after close = %s", got)
+	}
+	if err := w.add(context.Background(), &WebPage{URL: "c"}); err == nil {
+		t.Fatal("add() succeeded after close")
+	}
+	// Closing or flushing again returns right away
+	if err := w.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchWriterCanceled(t *testing.T) {
+	c := newFakeCollection()
+	c.release = make(chan struct{})
+	w := newTestBatchWriter(t, c, 1, time.Hour)
+
+	// The first page is being written, the next four fill the buffer
+	addPages(t, w, "a", "b", "c", "d", "e")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.add(ctx, &WebPage{URL: "f"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("add() to a full buffer = %v, want the context error", err)
+	}
+	if err := w.flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("flush() of a stuck writer = %v, want the context error", err)
+	}
+	if err := w.close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("close() of a stuck writer = %v, want the context error", err)
+	}
+
+	// The pages still get written once the collection answers
+	close(c.release)
+	if err := w.close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(c.written()); got != "[a b c d e]" {
+		t.Fatalf("batches = %s", got)
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiArchiver stores every page with several archivers, e.g. MongoDB and a
+// search index
+type MultiArchiver []Archiver
+
+// NewMultiArchiver combines the non-nil archivers. It returns nil if there are
+// none and the archiver itself if there is only one.
+func NewMultiArchiver(archivers ...Archiver) Archiver {
+	var m MultiArchiver
+	for _, a := range archivers {
+		if a != nil {
+			m = append(m, a)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// Store saves the page with every archiver, returning the errors of those that failed
+func (m MultiArchiver) Store(ctx context.Context, page *WebPage) error {
+	var errs []error
+	for _, a := range m {
+		if err := a.Store(ctx, page); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every archiver
+func (m MultiArchiver) Close(ctx context.Context) error {
+	var errs []error
+	for _, a := range m {
+		if err := a.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,346 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// ObjectArchiver writes every page as a JSON object, and optionally its raw
+// response body, to an S3-compatible bucket. Keys are
+// <prefix>/<host>/<path hash>/<timestamp>.json so that the versions of a URL
+// sort by crawl time.
+type ObjectArchiver struct {
+	cfg      config.ObjectConfig
+	endpoint *url.URL
+	region   string
+	creds    Credentials
+	client   *http.Client
+
+	// Counters
+	objects   int64
+	bytes     int64
+	multipart int64
+	retries   int64
+	failed    int64
+}
+
+// s3Error is the error document of the S3 API
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// NewObjectArchiver checks that the configured bucket is reachable. It
+// returns nil if object storage is disabled.
+func NewObjectArchiver(cfg config.ObjectConfig) (*ObjectArchiver, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	o := &ObjectArchiver{
+		cfg:    cfg,
+		region: cfg.Region,
+		creds: Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+		},
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+	if o.creds.AccessKeyID == "" && o.creds.SecretAccessKey == "" {
+		o.creds = Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	if o.creds.AccessKeyID == "" || o.creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("object storage needs access_key_id and secret_access_key, or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint := cfg.Endpoint
+	switch cfg.Provider {
+	case "gcs":
+		if o.region == "" {
+			o.region = "auto"
+		}
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		if o.region == "" {
+			o.region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = "https://s3." + o.region + ".amazonaws.com"
+		}
+	}
+	var err error
+	if o.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if _, err := o.do(ctx, http.MethodHead, "", nil, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to access bucket %s: %w", cfg.Bucket, err)
+	}
+	log.Info("Archiving pages to %s bucket %s under %s/", cfg.Provider, cfg.Bucket, cfg.Prefix)
+	return o, nil
+}
+
+// Key returns the key of a page without extension:
+// <prefix>/<host>/<first 16 hex digits of sha256(path?query)>/<crawl time>
+func (o *ObjectArchiver) Key(page *WebPage) string {
+	key := pageKey(page)
+	if prefix := strings.Trim(o.cfg.Prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	return key
+}
+
+// pageKey returns <host>/<first 16 hex digits of sha256(path?query)>/<crawl time>
+func pageKey(page *WebPage) string {
+	host, path := "_", page.URL
+	if u, err := url.Parse(page.URL); err == nil {
+		if u.Host != "" {
+			host = strings.ToLower(u.Host)
+		}
+		path = u.EscapedPath()
+		if u.RawQuery != "" {
+			path += "?" + u.RawQuery
+		}
+	}
+	sum := sha256.Sum256([]byte(path))
+	return host + "/" + hex.EncodeToString(sum[:8]) + "/" + page.CrawledAt.UTC().Format("20060102T150405Z")
+}
+
+// Store uploads the page as JSON and, with raw enabled, its response body
+func (o *ObjectArchiver) Store(ctx context.Context, page *WebPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("failed to encode webpage: %w", err)
+	}
+
+	key := o.Key(page)
+	if err := o.put(ctx, key+".json", data, "application/json"); err != nil {
+		atomic.AddInt64(&o.failed, 1)
+		return fmt.Errorf("failed to store webpage %s: %w", page.URL, err)
+	}
+	if o.cfg.Raw && len(page.Body) > 0 {
+		if err := o.put(ctx, key+".body", page.Body, page.ContentType); err != nil {
+			atomic.AddInt64(&o.failed, 1)
+			return fmt.Errorf("failed to store response body of %s: %w", page.URL, err)
+		}
+	}
+	return nil
+}
+
+// Raw reports whether response bodies are stored, so the crawler keeps them on the page
+func (o *ObjectArchiver) Raw() bool {
+	return o != nil && o.cfg.Raw
+}
+
+// put uploads an object, in parts if it reaches the multipart threshold
+func (o *ObjectArchiver) put(ctx context.Context, key string, data []byte, contentType string) error {
+	if int64(len(data)) >= o.cfg.MultipartThreshold {
+		if err := o.putMultipart(ctx, key, data, contentType); err != nil {
+			return err
+		}
+	} else {
+		header := http.Header{}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		if _, err := o.do(ctx, http.MethodPut, key, nil, header, data); err != nil {
+			return err
+		}
+	}
+	atomic.AddInt64(&o.objects, 1)
+	atomic.AddInt64(&o.bytes, int64(len(data)))
+	return nil
+}
+
+// putMultipart uploads an object in parts of part_size, aborting the
+// upload if a part fails so that no parts are left behind
+func (o *ObjectArchiver) putMultipart(ctx context.Context, key string, data []byte, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	body, err := o.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("failed to start multipart upload: no upload id in response")
+	}
+	uploadID := initiated.UploadID
+
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+
+	for offset, number := 0, 1; offset < len(data); offset, number = offset+int(o.cfg.PartSize), number+1 {
+		end := min(offset+int(o.cfg.PartSize), len(data))
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		header, _, err := o.request(ctx, http.MethodPut, key, query, nil, data[offset:end])
+		if err != nil {
+			o.abort(key, uploadID)
+			return fmt.Errorf("failed to upload part %d: %w", number, err)
+		}
+		complete.Parts = append(complete.Parts, part{PartNumber: number, ETag: header.Get("ETag")})
+	}
+
+	payload, err := xml.Marshal(complete)
+	if err != nil {
+		o.abort(key, uploadID)
+		return fmt.Errorf("failed to encode multipart completion: %w", err)
+	}
+	// A completion can fail after a 200 status, with an error document as body
+	body, err = o.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, payload)
+	if err == nil {
+		err = errorDocument(body)
+	}
+	if err != nil {
+		o.abort(key, uploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	atomic.AddInt64(&o.multipart, 1)
+	return nil
+}
+
+// abort discards the parts of a failed multipart upload
+func (o *ObjectArchiver) abort(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.cfg.Timeout)
+	defer cancel()
+	if _, err := o.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil); err != nil {
+		log.Warn("Failed to abort multipart upload of %s: %v", key, err)
+	}
+}
+
+// do sends a request and returns the response body
+func (o *ObjectArchiver) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) ([]byte, error) {
+	_, data, err := o.request(ctx, method, key, query, header, body)
+	return data, err
+}
+
+// request sends a signed request and returns the response headers and body,
+// retrying network errors, 5xx and 429 answers with doubling backoff
+func (o *ObjectArchiver) request(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (http.Header, []byte, error) {
+	u := o.objectURL(key)
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "uploads=", "uploads")
+	payloadHash := hashHex(body)
+
+	backoff := 200 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.URL.RawPath = canonicalPath(req.URL)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		signV4(req, o.creds, o.region, payloadHash, time.Now())
+
+		resp, err := o.client.Do(req)
+		var data []byte
+		if err == nil {
+			data, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		retryable := err != nil
+		if err == nil {
+			if resp.StatusCode < 300 {
+				return resp.Header, data, nil
+			}
+			err = statusError(resp.StatusCode, data)
+			retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		}
+		if !retryable || attempt >= o.cfg.MaxRetries || ctx.Err() != nil {
+			return nil, nil, err
+		}
+
+		atomic.AddInt64(&o.retries, 1)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// objectURL returns the URL of a key, or of the bucket if key is empty
+func (o *ObjectArchiver) objectURL(key string) *url.URL {
+	u := *o.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if o.cfg.PathStyle {
+		u.Path = base + "/" + o.cfg.Bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = o.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	return &u
+}
+
+// statusError describes an error status with the code and message of its error document
+func statusError(status int, body []byte) error {
+	var doc s3Error
+	if xml.Unmarshal(body, &doc) == nil && doc.Code != "" {
+		return fmt.Errorf("HTTP %d %s: %s", status, doc.Code, doc.Message)
+	}
+	return fmt.Errorf("HTTP %d", status)
+}
+
+// errorDocument returns the error in a 200 response body, if it holds one
+func errorDocument(body []byte) error {
+	var doc s3Error
+	if xml.Unmarshal(body, &doc) == nil && doc.Code != "" {
+		return fmt.Errorf("%s: %s", doc.Code, doc.Message)
+	}
+	return nil
+}
+
+// GetStats returns object storage statistics
+func (o *ObjectArchiver) GetStats() map[string]int64 {
+	return map[string]int64{
+		"objects":   atomic.LoadInt64(&o.objects),
+		"bytes":     atomic.LoadInt64(&o.bytes),
+		"multipart": atomic.LoadInt64(&o.multipart),
+		"retries":   atomic.LoadInt64(&o.retries),
+		"failed":    atomic.LoadInt64(&o.failed),
+	}
+}
+
+// Close is a no-op, uploads are synchronous
+func (o *ObjectArchiver) Close(ctx context.Context) error {
+	return nil
+}
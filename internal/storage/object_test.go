@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// fakeS3 records the requests it gets and fails the upload of part failPart
+type fakeS3 struct {
+	mu       sync.Mutex
+	requests []string // Method, path and query
+	bodies   map[string]string
+	failPart string
+	badSig   []string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	call := r.Method + " " + r.URL.Path
+	if r.URL.RawQuery != "" {
+		call += "?" + r.URL.RawQuery
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, call)
+	s.bodies[call] = string(body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) ||
+		!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		s.badSig = append(s.badSig, call)
+	}
+	failPart := s.failPart
+	s.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>up1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Get("partNumber") != "":
+		if query.Get("partNumber") == failPart {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<Error><Code>InvalidPart</Code><Message>bad part</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"done"</ETag></CompleteMultipartUploadResult>`)
+	}
+}
+
+func (s *fakeS3) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func newTestObjectArchiver(t *testing.T, s *fakeS3) *ObjectArchiver {
+	t.Helper()
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	o, err := NewObjectArchiver(config.ObjectConfig{
+		Enabled:            true,
+		Endpoint:           srv.URL,
+		Bucket:             "pages",
+		Prefix:             "crawl",
+		PathStyle:          true,
+		AccessKeyID:        "AKID",
+		SecretAccessKey:    "secret",
+		MultipartThreshold: 10,
+		PartSize:           4,
+		Timeout:            2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestObjectMultipartUpload(t *testing.T) {
+	s := &fakeS3{bodies: make(map[string]string)}
+	o := newTestObjectArchiver(t, s)
+
+	if err := o.put(context.Background(), "crawl/a.com/page.json", []byte("0123456789"), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"HEAD /pages",
+		"POST /pages/crawl/a.com/page.json?uploads",
+		"PUT /pages/crawl/a.com/page.json?partNumber=1&uploadId=up1",
+		"PUT /pages/crawl/a.com/page.json?partNumber=2&uploadId=up1",
+		"PUT /pages/crawl/a.com/page.json?partNumber=3&uploadId=up1",
+		"POST /pages/crawl/a.com/page.json?uploadId=up1",
+	}
+	if got := s.calls(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("requests\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if got := s.bodies[want[4]]; got != "89" {
+		t.Fatalf("last part = %q, want the remaining 2 bytes", got)
+	}
+	completion := `<CompleteMultipartUpload>` +
+		`<Part><PartNumber>1</PartNumber><ETag>&#34;etag-1&#34;</ETag></Part>` +
+		`<Part><PartNumber>2</PartNumber><ETag>&#34;etag-2&#34;</ETag></Part>` +
+		`<Part><PartNumber>3</PartNumber><ETag>&#34;etag-3&#34;</ETag></Part>` +
+		`</CompleteMultipartUpload>`
+	if got := s.bodies[want[5]]; got != completion {
+		t.Fatalf("completion body\n%s\nwant\n%s", got, completion)
+	}
+	if len(s.badSig) != 0 {
+		t.Fatalf("requests with a wrong payload hash or credential: %v", s.badSig)
+	}
+	if stats := o.GetStats(); stats["multipart"] != 1 || stats["objects"] != 1 || stats["bytes"] != 10 {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestObjectMultipartAbortsOnFailedPart(t *testing.T) {
+	s := &fakeS3{bodies: make(map[string]string), failPart: "2"}
+	o := newTestObjectArchiver(t, s)
+
+	err := o.put(context.Background(), "k", []byte("0123456789"), "")
+	if err == nil || !strings.Contains(err.Error(), "InvalidPart") {
+		t.Fatalf("put() = %v, want the part error", err)
+	}
+	calls := s.calls()
+	if last := calls[len(calls)-1]; last != "DELETE /pages/k?uploadId=up1" {
+		t.Fatalf("last request = %s, want the abort", last)
+	}
+	for _, call := range calls {
+		if strings.Contains(call, "partNumber=3") {
+			t.Fatal("parts were uploaded after a failed one")
+		}
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	o := &ObjectArchiver{cfg: config.ObjectConfig{Prefix: "/crawl/"}}
+	page := &WebPage{URL: "https://Example.com/a?b=1", CrawledAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+
+	sum := sha256.Sum256([]byte("/a?b=1"))
+	want := "crawl/example.com/" + hex.EncodeToString(sum[:8]) + "/20240301T120000Z"
+	if got := o.Key(page); got != want {
+		t.Fatalf("Key() = %s, want %s", got, want)
+	}
+}
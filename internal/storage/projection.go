@@ -0,0 +1,50 @@
+package storage
+
+// Projection selects the optional fields of a page that are persisted
+type Projection struct {
+	Content bool // Raw HTML
+	Text    bool // Cleaned plain text
+	Article bool // Main text and metadata without boilerplate
+	Links   bool // Links and outlinks
+	Headers bool // Response headers
+}
+
+// NewProjection creates a projection from the configured field names
+func NewProjection(fields []string) Projection {
+	var p Projection
+	for _, field := range fields {
+		switch field {
+		case "content":
+			p.Content = true
+		case "text":
+			p.Text = true
+		case "article":
+			p.Article = true
+		case "links":
+			p.Links = true
+		case "headers":
+			p.Headers = true
+		}
+	}
+	return p
+}
+
+// Apply clears the fields of page that are not persisted
+func (p Projection) Apply(page *WebPage) {
+	if !p.Content {
+		page.Content = ""
+	}
+	if !p.Text {
+		page.Text = ""
+	}
+	if !p.Article {
+		page.Article = nil
+	}
+	if !p.Links {
+		page.Links = nil
+		page.Outlinks = nil
+	}
+	if !p.Headers {
+		page.Headers = nil
+	}
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"web-crawler/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RawRecord is an HTTP exchange as it went over the wire
+type RawRecord struct {
+	Request  []byte // Request line and headers
+	Response []byte // Status line and headers
+	Body     []byte // Response body before content decoding
+}
+
+// Bytes returns the request followed by the response, as a client would
+// have written and read them
+func (r *RawRecord) Bytes() []byte {
+	return bytes.Join([][]byte{r.Request, r.Response, r.Body}, nil)
+}
+
+// RawStore keeps the raw exchanges of pages
+type RawStore interface {
+	// Put stores the raw exchange of page and returns a reference to it
+	Put(ctx context.Context, page *WebPage, record *RawRecord) (string, error)
+	GetStats() map[string]int64
+}
+
+// NewRawStore creates the configured raw store, or returns nil if raw
+// archiving is disabled. The gridfs backend needs a MongoDB connection.
+func NewRawStore(cfg config.RawConfig, mongo *MongoArchiver) (RawStore, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Backend == "gridfs" {
+		if mongo == nil {
+			return nil, fmt.Errorf("gridfs raw backend requires a MongoDB connection")
+		}
+		bucket, err := mongo.GridFS(cfg.Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GridFS bucket %s: %w", cfg.Bucket, err)
+		}
+		return &GridFSRawStore{bucket: bucket, name: cfg.Bucket}, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raw response directory: %w", err)
+	}
+	return &FileRawStore{dir: cfg.Dir}, nil
+}
+
+// rawCounters are the statistics of a raw store
+type rawCounters struct {
+	stored int64
+	bytes  int64
+	failed int64
+}
+
+func (c *rawCounters) count(n int, err error) {
+	if err != nil {
+		atomic.AddInt64(&c.failed, 1)
+		return
+	}
+	atomic.AddInt64(&c.stored, 1)
+	atomic.AddInt64(&c.bytes, int64(n))
+}
+
+// GetStats returns raw store statistics for monitoring
+func (c *rawCounters) GetStats() map[string]int64 {
+	return map[string]int64{
+		"stored": atomic.LoadInt64(&c.stored),
+		"bytes":  atomic.LoadInt64(&c.bytes),
+		"failed": atomic.LoadInt64(&c.failed),
+	}
+}
+
+// FileRawStore writes every exchange to <dir>/<host>/<path hash>/<crawl time>.http
+type FileRawStore struct {
+	rawCounters
+	dir string
+}
+
+// Put writes the exchange and returns the path of its file
+func (s *FileRawStore) Put(ctx context.Context, page *WebPage, record *RawRecord) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(strings.ReplaceAll(pageKey(page), ":", "_"))+".http")
+	data := record.Bytes()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	s.count(len(data), err)
+	if err != nil {
+		return "", fmt.Errorf("failed to write raw response: %w", err)
+	}
+	return path, nil
+}
+
+// GridFSRawStore uploads every exchange to a GridFS bucket. References have
+// the form gridfs:<bucket>/<file id>.
+type GridFSRawStore struct {
+	rawCounters
+	bucket *gridfs.Bucket
+	name   string
+}
+
+// Put uploads the exchange with the page URL and crawl time as metadata
+func (s *GridFSRawStore) Put(ctx context.Context, page *WebPage, record *RawRecord) (string, error) {
+	data := record.Bytes()
+	id := primitive.NewObjectID()
+	opts := options.GridFSUpload().SetMetadata(bson.M{"url": page.URL, "crawled_at": page.CrawledAt})
+	err := s.bucket.UploadFromStreamWithID(id, pageKey(page)+".http", bytes.NewReader(data), opts)
+	s.count(len(data), err)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload raw response: %w", err)
+	}
+	return "gridfs:" + s.name + "/" + id.Hex(), nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+func TestFileRawStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewRawStore(config.RawConfig{Enabled: true, Backend: "file", Dir: dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := &WebPage{URL: "https://example.com/a", CrawledAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	record := &RawRecord{
+		Request:  []byte("GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		Response: []byte("HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\n\r\n"),
+		Body:     []byte{0x1f, 0x8b, 0x08},
+	}
+
+	ref, err := store.Put(context.Background(), page, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, filepath.FromSlash(pageKey(page))+".http")
+	if ref != want || !strings.HasSuffix(ref, "20240301T120000Z.http") {
+		t.Fatalf("Put() = %s, want %s", ref, want)
+	}
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(record.Bytes()) || !strings.HasPrefix(string(data), "GET /a HTTP/1.1\r\n") {
+		t.Fatalf("file holds %q", data)
+	}
+	if stats := store.GetStats(); stats["stored"] != 1 || stats["bytes"] != int64(len(data)) {
+		t.Fatalf("GetStats() = %v", stats)
+	}
+}
+
+func TestNewRawStore(t *testing.T) {
+	if store, err := NewRawStore(config.RawConfig{Backend: "file"}, nil); store != nil || err != nil {
+		t.Fatalf("disabled NewRawStore() = %v, %v", store, err)
+	}
+	if _, err := NewRawStore(config.RawConfig{Enabled: true, Backend: "gridfs", Bucket: "raw"}, nil); err == nil {
+		t.Fatal("gridfs backend without MongoDB succeeded")
+	}
+}
@@ -0,0 +1,161 @@
+// Package subdomain records the hosts that crawled pages link to, grouped
+// by registrable domain, for reconnaissance and scoping
+package subdomain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+var log = logger.For("subdomain")
+
+// Domain is a registrable domain and the hosts of it seen so far
+type Domain struct {
+	Domain     string      `json:"domain"`
+	Subdomains []Subdomain `json:"subdomains"`
+}
+
+// Subdomain is a host links pointed to
+type Subdomain struct {
+	Host      string    `json:"host"`
+	InScope   bool      `json:"in_scope"` // Some link to it passed the domain filters
+	Links     int64     `json:"links"`
+	FoundOn   string    `json:"found_on"` // Page of the first link to it
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// Tracker records the hosts of links, including those filtered out
+type Tracker struct {
+	cfg config.SubdomainsConfig
+
+	mu    sync.Mutex
+	hosts map[string]*Subdomain
+
+	// Counters
+	dropped int64 // Hosts not recorded because max_hosts was reached
+}
+
+// New creates a tracker. It returns nil if subdomain tracking is disabled.
+func New(cfg config.SubdomainsConfig) *Tracker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Tracker{cfg: cfg, hosts: make(map[string]*Subdomain)}
+}
+
+// Observe records a link to rawURL found on page from, and whether it is
+// in the crawl scope. IP addresses have no subdomains and are ignored.
+func (t *Tracker) Observe(rawURL, from string, inScope bool) {
+	if t == nil {
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.hosts[host]
+	if !ok {
+		if t.cfg.MaxHosts > 0 && len(t.hosts) >= t.cfg.MaxHosts {
+			atomic.AddInt64(&t.dropped, 1)
+			return
+		}
+		s = &Subdomain{Host: host, FoundOn: from, FirstSeen: time.Now()}
+		t.hosts[host] = s
+		log.Debug("Discovered host %s on %s", host, from)
+	}
+	s.Links++
+	s.InScope = s.InScope || inScope
+}
+
+// Report lists the hosts seen by registrable domain, both sorted by name.
+// It returns nil if the tracker is nil and an empty list if nothing was seen.
+func (t *Tracker) Report() []Domain {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	byDomain := make(map[string][]Subdomain)
+	for host, s := range t.hosts {
+		domain := registrableDomain(host)
+		byDomain[domain] = append(byDomain[domain], *s)
+	}
+	t.mu.Unlock()
+
+	report := make([]Domain, 0, len(byDomain))
+	for domain, hosts := range byDomain {
+		sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+		report = append(report, Domain{Domain: domain, Subdomains: hosts})
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Domain < report[j].Domain })
+	return report
+}
+
+// Export writes the report to the configured output file as JSON
+func (t *Tracker) Export() error {
+	if t == nil || t.cfg.Output == "" {
+		return nil
+	}
+	report := t.Report()
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(t.cfg.Output); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create subdomain report directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(t.cfg.Output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subdomain report: %w", err)
+	}
+	stats := t.GetStats()
+	log.Info("Exported %d hosts of %d domains to %s", stats["hosts"], stats["domains"], t.cfg.Output)
+	return nil
+}
+
+// GetStats returns the hosts and registrable domains seen
+func (t *Tracker) GetStats() map[string]int64 {
+	t.mu.Lock()
+	domains := make(map[string]bool)
+	for host := range t.hosts {
+		domains[registrableDomain(host)] = true
+	}
+	hosts := len(t.hosts)
+	t.mu.Unlock()
+	return map[string]int64{
+		"hosts":   int64(hosts),
+		"domains": int64(len(domains)),
+		"dropped": atomic.LoadInt64(&t.dropped),
+	}
+}
+
+// registrableDomain returns the domain of host one label below its public
+// suffix, or host itself if it has none
+func registrableDomain(host string) string {
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
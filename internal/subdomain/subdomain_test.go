@@ -0,0 +1,85 @@
+package subdomain
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"web-crawler/internal/config"
+)
+
+func TestTrackerReport(t *testing.T) {
+	tr := New(config.SubdomainsConfig{Enabled: true, MaxHosts: 4})
+	links := []struct {
+		url     string
+		inScope bool
+	}{
+		{"https://www.example.co.uk/", true},
+		{"https://shop.example.co.uk/cart", false},
+		{"https://SHOP.example.co.uk:8443/", true},
+		{"https://example.co.uk/", false},
+		{"http://127.0.0.1/", false},
+		{"mailto:someone", false},
+		{"https://cdn.other.com/x.js", false},
+		{"https://api.other.com/", false},
+	}
+	for _, l := range links {
+		tr.Observe(l.url, "https://www.example.co.uk/", l.inScope)
+	}
+
+	var got []string
+	for _, d := range tr.Report() {
+		for _, s := range d.Subdomains {
+			got = append(got, d.Domain+" "+s.Host)
+		}
+	}
+	want := []string{
+		"example.co.uk example.co.uk",
+		"example.co.uk shop.example.co.uk",
+		"example.co.uk www.example.co.uk",
+		"other.com cdn.other.com",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("report = %v, want %v", got, want)
+	}
+	shop := tr.Report()[0].Subdomains[1]
+	if shop.Links != 2 || !shop.InScope || shop.FoundOn != "https://www.example.co.uk/" {
+		t.Errorf("shop.example.co.uk = %+v", shop)
+	}
+	if stats := tr.GetStats(); stats["hosts"] != 4 || stats["domains"] != 2 || stats["dropped"] != 1 {
+		t.Errorf("stats = %v", stats)
+	}
+}
+
+func TestTrackerExport(t *testing.T) {
+	if New(config.SubdomainsConfig{}) != nil {
+		t.Fatal("New() returned a tracker while disabled")
+	}
+	var disabled *Tracker
+	if disabled.Report() != nil || disabled.Export() != nil {
+		t.Fatal("nil tracker reported hosts")
+	}
+
+	path := filepath.Join(t.TempDir(), "report", "subdomains.json")
+	tr := New(config.SubdomainsConfig{Enabled: true, Output: path})
+	if report := tr.Report(); report == nil || len(report) != 0 {
+		t.Fatalf("Report() = %v, want an empty list", report)
+	}
+	tr.Observe("https://blog.example.com/", "https://example.com/", true)
+	if err := tr.Export(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report []Domain
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 || report[0].Domain != "example.com" || report[0].Subdomains[0].Host != "blog.example.com" {
+		t.Errorf("exported %s", data)
+	}
+}
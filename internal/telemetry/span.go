@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP
+const (
+	KindInternal = 1
+	KindClient   = 3
+)
+
+// statusError is the OTLP status code of a failed span
+const statusError = 2
+
+// Span is one timed stage of a page's journey through the pipeline. All
+// methods are safe to call on a nil Span, which is what unsampled pages get.
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []attr
+	status   int
+	message  string
+}
+
+// attr is a span attribute; value is a string, bool, int64 or float64
+type attr struct {
+	key   string
+	value interface{}
+}
+
+func newSpanID() [8]byte {
+	var id [8]byte
+	rand.Read(id[:])
+	return id
+}
+
+func newTraceID() [16]byte {
+	var id [16]byte
+	rand.Read(id[:])
+	return id
+}
+
+// Child starts a span for a stage nested in s
+func (s *Span) Child(name string, kind int) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		tracer:   s.tracer,
+		traceID:  s.traceID,
+		spanID:   newSpanID(),
+		parentID: s.spanID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+	}
+}
+
+// SetString sets a string attribute
+func (s *Span) SetString(key, value string) {
+	if s != nil {
+		s.attrs = append(s.attrs, attr{key, value})
+	}
+}
+
+// SetInt sets an integer attribute
+func (s *Span) SetInt(key string, value int64) {
+	if s != nil {
+		s.attrs = append(s.attrs, attr{key, value})
+	}
+}
+
+// SetBool sets a boolean attribute
+func (s *Span) SetBool(key string, value bool) {
+	if s != nil {
+		s.attrs = append(s.attrs, attr{key, value})
+	}
+}
+
+// SetError marks the span as failed
+func (s *Span) SetError(err error) {
+	if s != nil && err != nil {
+		s.status = statusError
+		s.message = err.Error()
+	}
+}
+
+// End finishes the span and hands it to the exporter
+func (s *Span) End() {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	s.tracer.export(s)
+}
@@ -0,0 +1,294 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/logger"
+)
+
+// log is the logger of the telemetry package
+var log = logger.For("telemetry")
+
+// queueSize is how many ended spans wait for export before new ones are dropped
+const queueSize = 4096
+
+// Tracer samples pages and exports their spans to an OTLP/HTTP endpoint in
+// the JSON encoding, which Jaeger and the OpenTelemetry Collector accept on
+// /v1/traces. All methods are safe to call on a nil Tracer, which traces nothing.
+type Tracer struct {
+	cfg    config.TelemetryConfig
+	client *http.Client
+
+	spans    chan *Span
+	done     chan struct{}
+	closeMu  sync.RWMutex
+	closed   bool
+	lastWarn time.Time
+
+	// Counters
+	sampled     int64
+	exported    int64
+	dropped     int64
+	exportFails int64
+}
+
+// New starts the exporter, or returns nil if telemetry is disabled
+func New(cfg config.TelemetryConfig) (*Tracer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("telemetry endpoint is not set")
+	}
+
+	t := &Tracer{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		spans:  make(chan *Span, queueSize),
+		done:   make(chan struct{}),
+	}
+	go t.run()
+	log.Info("Exporting spans of %.0f%% of pages to %s", cfg.SampleRate*100, cfg.Endpoint)
+	return t, nil
+}
+
+// StartPage starts the root span of a page, or returns nil if the page is
+// not sampled
+func (t *Tracer) StartPage(rawURL string) *Span {
+	if t == nil || rand.Float64() >= t.cfg.SampleRate {
+		return nil
+	}
+	atomic.AddInt64(&t.sampled, 1)
+
+	s := &Span{
+		tracer:  t,
+		traceID: newTraceID(),
+		spanID:  newSpanID(),
+		name:    "page",
+		kind:    KindInternal,
+		start:   time.Now(),
+	}
+	s.SetString("url.full", rawURL)
+	return s
+}
+
+// export queues an ended span, dropping it if the exporter can't keep up
+func (t *Tracer) export(s *Span) {
+	t.closeMu.RLock()
+	defer t.closeMu.RUnlock()
+
+	if t.closed {
+		return
+	}
+	select {
+	case t.spans <- s:
+	default:
+		atomic.AddInt64(&t.dropped, 1)
+	}
+}
+
+// run batches queued spans and sends them every flush interval or whenever
+// a batch is full
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			t.send(batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case s, ok := <-t.spans:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) >= t.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send posts one batch to the endpoint
+func (t *Tracer) send(batch []*Span) {
+	body, err := json.Marshal(t.encode(batch))
+	if err != nil {
+		t.failed(len(batch), err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		t.failed(len(batch), err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.failed(len(batch), err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.failed(len(batch), fmt.Errorf("endpoint answered %s", resp.Status))
+		return
+	}
+	atomic.AddInt64(&t.exported, int64(len(batch)))
+}
+
+// failed counts a lost batch and logs at most one warning a minute
+func (t *Tracer) failed(spans int, err error) {
+	atomic.AddInt64(&t.exportFails, 1)
+	atomic.AddInt64(&t.dropped, int64(spans))
+	if time.Since(t.lastWarn) > time.Minute {
+		t.lastWarn = time.Now()
+		log.Warn("Failed to export %d spans: %v", spans, err)
+	}
+}
+
+// Close exports the queued spans and stops the exporter
+func (t *Tracer) Close(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.closeMu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.spans)
+	}
+	t.closeMu.Unlock()
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush spans: %w", ctx.Err())
+	}
+}
+
+// GetStats returns exporter statistics
+func (t *Tracer) GetStats() map[string]int64 {
+	if t == nil {
+		return nil
+	}
+	return map[string]int64{
+		"sampledPages":  atomic.LoadInt64(&t.sampled),
+		"exportedSpans": atomic.LoadInt64(&t.exported),
+		"droppedSpans":  atomic.LoadInt64(&t.dropped),
+		"exportErrors":  atomic.LoadInt64(&t.exportFails),
+	}
+}
+
+// OTLP/JSON request body, see opentelemetry-proto's trace service.
+// IDs are hex strings and 64-bit integers are decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+// encode converts a batch to an OTLP export request
+func (t *Tracer) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: s.status, Message: s.message},
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			span.Attributes = append(span.Attributes, keyValue(a.key, a.value))
+		}
+		spans = append(spans, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{keyValue("service.name", t.cfg.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "web-crawler"}, Spans: spans}},
+	}}}
+}
+
+// keyValue encodes an attribute as an OTLP AnyValue
+func keyValue(key string, value interface{}) otlpKeyValue {
+	var v map[string]interface{}
+	switch value := value.(type) {
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,170 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// collector records the OTLP/JSON requests it receives
+type collector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	headers  []http.Header
+	status   int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.headers = append(c.headers, r.Header)
+	status := c.status
+	c.mu.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
+
+func newTracer(t *testing.T, endpoint string) *Tracer {
+	t.Helper()
+	tracer, err := New(config.TelemetryConfig{
+		Enabled:       true,
+		Endpoint:      endpoint,
+		ServiceName:   "crawler-test",
+		SampleRate:    1,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer
+}
+
+func TestTracerExportsOTLPJSON(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	tracer := newTracer(t, srv.URL+"/v1/traces")
+
+	page := tracer.StartPage("https://example.com/")
+	fetch := page.Child("fetch", KindClient)
+	fetch.SetInt("http.response.status_code", 503)
+	fetch.SetBool("cache.hit", false)
+	fetch.SetError(errors.New("service unavailable"))
+	fetch.End()
+	page.End()
+	page.End() // Ending twice exports once
+
+	if err := tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.requests) != 1 {
+		t.Fatalf("collector got %d requests, want 1", len(c.requests))
+	}
+	if got := c.headers[0].Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization header = %q", got)
+	}
+	rs := c.requests[0].ResourceSpans
+	if len(rs) != 1 || len(rs[0].ScopeSpans) != 1 {
+		t.Fatalf("request layout = %+v", c.requests[0])
+	}
+	if attr := rs[0].Resource.Attributes[0]; attr.Key != "service.name" || attr.Value["stringValue"] != "crawler-test" {
+		t.Errorf("resource attribute = %+v", attr)
+	}
+
+	spans := rs[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	child, root := spans[0], spans[1]
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 || root.ParentSpanID != "" {
+		t.Errorf("root span IDs = %q/%q/%q", root.TraceID, root.SpanID, root.ParentSpanID)
+	}
+	if child.TraceID != root.TraceID || child.ParentSpanID != root.SpanID {
+		t.Errorf("child span isn't nested in the page span")
+	}
+	if child.Name != "fetch" || child.Kind != KindClient || root.Kind != KindInternal {
+		t.Errorf("span names/kinds = %s/%d, %s/%d", child.Name, child.Kind, root.Name, root.Kind)
+	}
+	if child.Status.Code != statusError || child.Status.Message != "service unavailable" {
+		t.Errorf("child status = %+v", child.Status)
+	}
+	// 64-bit integers are decimal strings in OTLP/JSON
+	if v := child.Attributes[0].Value["intValue"]; v != "503" {
+		t.Errorf("int attribute = %#v, want \"503\"", v)
+	}
+	if v := child.Attributes[1].Value["boolValue"]; v != false {
+		t.Errorf("bool attribute = %#v", v)
+	}
+	start, _ := strconv.ParseInt(root.StartTimeUnixNano, 10, 64)
+	end, _ := strconv.ParseInt(root.EndTimeUnixNano, 10, 64)
+	if start == 0 || end < start {
+		t.Errorf("root span times = %s-%s", root.StartTimeUnixNano, root.EndTimeUnixNano)
+	}
+
+	if stats := tracer.GetStats(); stats["sampledPages"] != 1 || stats["exportedSpans"] != 2 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+}
+
+func TestTracerCountsFailedExports(t *testing.T) {
+	c := &collector{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	tracer := newTracer(t, srv.URL)
+
+	tracer.StartPage("https://example.com/").End()
+	if err := tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := tracer.GetStats(); stats["exportErrors"] != 1 || stats["droppedSpans"] != 1 || stats["exportedSpans"] != 0 {
+		t.Errorf("GetStats() = %v", stats)
+	}
+}
+
+func TestDisabledTracing(t *testing.T) {
+	tracer, err := New(config.TelemetryConfig{})
+	if err != nil || tracer != nil {
+		t.Fatalf("New() of disabled telemetry = %v, %v", tracer, err)
+	}
+	// A nil tracer and its nil spans do nothing
+	span := tracer.StartPage("https://example.com/")
+	span.Child("fetch", KindClient).End()
+	span.SetString("k", "v")
+	span.End()
+	if err := tracer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(config.TelemetryConfig{Enabled: true}); err == nil {
+		t.Fatal("New() without an endpoint succeeded")
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	srv := httptest.NewServer(&collector{})
+	defer srv.Close()
+	tracer := newTracer(t, srv.URL)
+	defer tracer.Close(context.Background())
+
+	tracer.cfg.SampleRate = 0
+	if tracer.StartPage("https://example.com/") != nil {
+		t.Fatal("page sampled at rate 0")
+	}
+}
@@ -0,0 +1,163 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// Lifecycle stages of a URL
+const (
+	StageQueued  = "queued"
+	StageFetched = "fetched"
+	StageParsed  = "parsed"
+	StageStored  = "stored"
+	StageSkipped = "skipped"
+	StageFailed  = "failed"
+)
+
+// flushInterval is how often buffered events are written out while events keep coming
+const flushInterval = time.Second
+
+// Event is one step in the lifecycle of a URL
+type Event struct {
+	Time      time.Time `json:"time"`
+	URL       string    `json:"url"`
+	Stage     string    `json:"stage"`
+	Parent    string    `json:"parent,omitempty"` // Page the URL was found on
+	Depth     int       `json:"depth,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	LatencyMS int64     `json:"latency_ms,omitempty"`
+	Bytes     int       `json:"bytes,omitempty"`
+	Links     int       `json:"links,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Tracer appends URL lifecycle events to a JSON-lines file. All methods are
+// safe to call on a nil Tracer, which records nothing.
+type Tracer struct {
+	match []*regexp.Regexp
+
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	enc     *json.Encoder
+	flushed time.Time
+}
+
+// New opens the trace file, or returns nil if tracing is disabled
+func New(cfg config.TraceConfig) (*Tracer, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	t := &Tracer{}
+	for _, expr := range cfg.Match {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace pattern %q: %w", expr, err)
+		}
+		t.match = append(t.match, re)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %w", err)
+	}
+	t.file = file
+	t.w = bufio.NewWriter(file)
+	t.enc = json.NewEncoder(t.w)
+	t.flushed = time.Now()
+	return t, nil
+}
+
+// traced reports whether events for rawURL are recorded
+func (t *Tracer) traced(rawURL string) bool {
+	if t == nil {
+		return false
+	}
+	if len(t.match) == 0 {
+		return true
+	}
+	for _, re := range t.match {
+		if re.MatchString(rawURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Record writes an event, filling in its time
+func (t *Tracer) Record(e Event) {
+	if !t.traced(e.URL) {
+		return
+	}
+	e.Time = time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.enc.Encode(e)
+	if time.Since(t.flushed) > flushInterval {
+		t.w.Flush()
+		t.flushed = time.Now()
+	}
+}
+
+// Queued records a URL entering the frontier
+func (t *Tracer) Queued(rawURL, parent string, depth int) {
+	t.Record(Event{URL: rawURL, Stage: StageQueued, Parent: parent, Depth: depth})
+}
+
+// Fetched records a completed request
+func (t *Tracer) Fetched(rawURL string, status int, latency time.Duration, bytes int) {
+	t.Record(Event{URL: rawURL, Stage: StageFetched, Status: status, LatencyMS: latency.Milliseconds(), Bytes: bytes})
+}
+
+// Parsed records the number of links extracted from a page
+func (t *Tracer) Parsed(rawURL string, links int) {
+	t.Record(Event{URL: rawURL, Stage: StageParsed, Links: links})
+}
+
+// Stored records a page handed to storage
+func (t *Tracer) Stored(rawURL string) {
+	t.Record(Event{URL: rawURL, Stage: StageStored})
+}
+
+// Skipped records a URL that was dropped, and why. parent is set when a
+// discovered link was not queued.
+func (t *Tracer) Skipped(rawURL, parent, reason string) {
+	t.Record(Event{URL: rawURL, Stage: StageSkipped, Parent: parent, Reason: reason})
+}
+
+// Failed records a fetch that failed
+func (t *Tracer) Failed(rawURL string, err error) {
+	t.Record(Event{URL: rawURL, Stage: StageFailed, Error: err.Error()})
+}
+
+// Close flushes buffered events and closes the trace file
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.w.Flush(); err != nil {
+		t.file.Close()
+		return fmt.Errorf("failed to flush trace file: %w", err)
+	}
+	return t.file.Close()
+}
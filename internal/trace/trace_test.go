@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// readEvents returns the events in a trace file
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestTracerRecordsLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace", "urls.jsonl")
+	tr, err := New(config.TraceConfig{Enabled: true, Path: path, Match: []string{`^https://a\.com/`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr.Queued("https://a.com/page", "https://a.com/", 1)
+	tr.Queued("https://b.com/", "https://a.com/", 1) // Not matched
+	tr.Fetched("https://a.com/page", 200, 150*time.Millisecond, 2048)
+	tr.Parsed("https://a.com/page", 12)
+	tr.Stored("https://a.com/page")
+	tr.Skipped("https://a.com/private", "https://a.com/page", "robots")
+	tr.Failed("https://a.com/gone", errors.New("connection refused"))
+	if err := tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := readEvents(t, path)
+	stages := []string{StageQueued, StageFetched, StageParsed, StageStored, StageSkipped, StageFailed}
+	if len(events) != len(stages) {
+		t.Fatalf("traced %d events, want %d: %+v", len(events), len(stages), events)
+	}
+	for i, stage := range stages {
+		if events[i].Stage != stage || events[i].Time.IsZero() {
+			t.Errorf("event %d = %+v, want stage %s", i, events[i], stage)
+		}
+	}
+	if e := events[0]; e.Parent != "https://a.com/" || e.Depth != 1 {
+		t.Errorf("queued event = %+v", e)
+	}
+	if e := events[1]; e.Status != 200 || e.LatencyMS != 150 || e.Bytes != 2048 {
+		t.Errorf("fetched event = %+v", e)
+	}
+	if e := events[4]; e.Reason != "robots" || e.Parent != "https://a.com/page" {
+		t.Errorf("skipped event = %+v", e)
+	}
+	if e := events[5]; e.Error != "connection refused" {
+		t.Errorf("failed event = %+v", e)
+	}
+}
+
+func TestTracerDisabled(t *testing.T) {
+	tr, err := New(config.TraceConfig{})
+	if tr != nil || err != nil {
+		t.Fatalf("New() = %v, %v with tracing disabled", tr, err)
+	}
+	// A nil tracer records nothing
+	tr.Queued("https://a.com/", "", 0)
+	if err := tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTracerInvalidPattern(t *testing.T) {
+	_, err := New(config.TraceConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "t.jsonl"), Match: []string{"("}})
+	if err == nil {
+		t.Fatal("New() accepted an invalid pattern")
+	}
+}
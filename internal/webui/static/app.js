@@ -0,0 +1,203 @@
+// Polls the /ui/ endpoints and redraws the page
+
+const REFRESH_MS = 2000;
+const MAX_POINTS = 600; // Older samples are dropped from the graphs
+
+const samples = []; // Points of /ui/metrics
+let lastT = 0;
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function fmtNumber(n) {
+  return Number(n).toLocaleString();
+}
+
+function fmtBytes(n) {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return n.toFixed(i ? 1 : 0) + " " + units[i];
+}
+
+function fmtDuration(secs) {
+  secs = Math.floor(secs);
+  const h = Math.floor(secs / 3600);
+  const m = Math.floor((secs % 3600) / 60);
+  const s = secs % 60;
+  return (h ? h + "h " : "") + (h || m ? m + "m " : "") + s + "s";
+}
+
+function fmtTime(iso) {
+  return new Date(iso).toLocaleTimeString();
+}
+
+async function getJSON(path) {
+  const resp = await fetch(path, {cache: "no-store"});
+  if (!resp.ok) {
+    throw new Error(path + ": " + resp.status);
+  }
+  return resp.json();
+}
+
+// fillTable replaces the body of a table with one row per item
+function fillTable(id, items, cells) {
+  const body = document.querySelector("#" + id + " tbody");
+  body.replaceChildren(...items.map(item => {
+    const tr = document.createElement("tr");
+    for (const value of cells(item)) {
+      const td = document.createElement("td");
+      td.textContent = value;
+      td.title = value;
+      tr.appendChild(td);
+    }
+    return tr;
+  }));
+}
+
+// drawChart plots points [{x, y}] as a line with a filled area
+function drawChart(canvas, points, color) {
+  const ratio = window.devicePixelRatio || 1;
+  const width = canvas.clientWidth;
+  const height = canvas.clientHeight;
+  canvas.width = width * ratio;
+  canvas.height = height * ratio;
+
+  const ctx = canvas.getContext("2d");
+  ctx.scale(ratio, ratio);
+  ctx.clearRect(0, 0, width, height);
+  if (points.length < 2) {
+    return;
+  }
+
+  const pad = {left: 48, right: 8, top: 8, bottom: 20};
+  const minX = points[0].x;
+  const maxX = points[points.length - 1].x;
+  const maxY = Math.max(1, ...points.map(p => p.y));
+  const x = v => pad.left + (v - minX) / Math.max(maxX - minX, 1e-9) * (width - pad.left - pad.right);
+  const y = v => height - pad.bottom - v / maxY * (height - pad.top - pad.bottom);
+
+  // Axes labels
+  ctx.fillStyle = "#888";
+  ctx.font = "11px sans-serif";
+  ctx.textAlign = "right";
+  ctx.fillText(maxY.toFixed(maxY < 10 ? 1 : 0), pad.left - 6, pad.top + 8);
+  ctx.fillText("0", pad.left - 6, height - pad.bottom);
+  ctx.textAlign = "left";
+  ctx.fillText(fmtDuration(minX), pad.left, height - 4);
+  ctx.textAlign = "right";
+  ctx.fillText(fmtDuration(maxX), width - pad.right, height - 4);
+
+  ctx.strokeStyle = "#eee";
+  ctx.beginPath();
+  ctx.moveTo(pad.left, y(0));
+  ctx.lineTo(width - pad.right, y(0));
+  ctx.stroke();
+
+  ctx.beginPath();
+  ctx.moveTo(x(points[0].x), y(points[0].y));
+  for (const p of points.slice(1)) {
+    ctx.lineTo(x(p.x), y(p.y));
+  }
+  ctx.strokeStyle = color;
+  ctx.lineWidth = 2;
+  ctx.stroke();
+
+  ctx.lineTo(x(maxX), y(0));
+  ctx.lineTo(x(minX), y(0));
+  ctx.closePath();
+  ctx.globalAlpha = 0.1;
+  ctx.fillStyle = color;
+  ctx.fill();
+  ctx.globalAlpha = 1;
+}
+
+function drawCharts() {
+  $("no-metrics").hidden = samples.length > 0;
+
+  const rate = [];
+  for (let i = 1; i < samples.length; i++) {
+    const dt = samples[i].t - samples[i - 1].t;
+    rate.push({x: samples[i].t, y: dt > 0 ? (samples[i].pages - samples[i - 1].pages) / dt : 0});
+  }
+  drawChart($("chart-pages"), samples.map(s => ({x: s.t, y: s.pages})), "#2e6bd6");
+  drawChart($("chart-rate"), rate, "#2e9e4f");
+  drawChart($("chart-queue"), samples.map(s => ({x: s.t, y: s.queued})), "#8e44ad");
+  // Samples without fetches have no latency
+  drawChart($("chart-latency"), samples.filter(s => s.latency_p95_ms > 0).map(s => ({x: s.t, y: s.latency_p95_ms})), "#d35400");
+
+  if (rate.length > 0) {
+    $("rate").textContent = rate[rate.length - 1].y.toFixed(1);
+  }
+}
+
+async function refreshMetrics() {
+  const points = await getJSON("/ui/metrics?since=" + lastT);
+  if (points.length === 0) {
+    return;
+  }
+  samples.push(...points);
+  samples.splice(0, Math.max(0, samples.length - MAX_POINTS));
+  lastT = samples[samples.length - 1].t;
+  drawCharts();
+}
+
+async function refreshOverview() {
+  const o = await getJSON("/ui/overview");
+  const status = $("status");
+  status.textContent = o.paused ? "paused" : "running";
+  status.className = "badge " + status.textContent;
+  $("elapsed").textContent = "elapsed " + fmtDuration(o.elapsed_seconds) + ", delay " + o.rate_limit;
+
+  $("pages").textContent = fmtNumber(o.pages);
+  $("stored").textContent = fmtNumber(o.stored);
+  $("errors").textContent = fmtNumber(o.errors);
+  $("queued").textContent = fmtNumber(o.queue.size || 0);
+  const workers = o.workers || {};
+  const total = Object.values(workers).reduce((a, b) => a + b, 0);
+  $("workers").textContent = (workers.fetching || 0) + " / " + total;
+  if (samples.length < 2 && o.elapsed_seconds > 0) {
+    $("rate").textContent = (o.pages / o.elapsed_seconds).toFixed(1);
+  }
+}
+
+async function refreshDomains() {
+  const domains = await getJSON("/ui/domains");
+  domains.sort((a, b) => b.pages - a.pages || a.host.localeCompare(b.host));
+  fillTable("domains", domains, d => {
+    const total = d.pages + d.errors;
+    return [
+      d.host,
+      fmtNumber(d.pages),
+      fmtNumber(d.errors),
+      total ? (d.errors * 100 / total).toFixed(1) + "%" : "-",
+      d.pages ? d.avg_latency_ms.toFixed(0) + " ms" : "-",
+      fmtBytes(d.bytes),
+      d.max_pages ? d.admitted + " / " + d.max_pages : String(d.admitted),
+    ];
+  });
+}
+
+async function refreshLists() {
+  const [pages, errors] = await Promise.all([getJSON("/ui/pages"), getJSON("/ui/errors")]);
+  fillTable("pages-list", pages, p => [fmtTime(p.time), String(p.status), p.latency_ms.toFixed(0) + " ms", p.url]);
+  fillTable("errors-list", errors, e => [fmtTime(e.time), e.error, e.url]);
+}
+
+async function refresh() {
+  try {
+    await Promise.all([refreshOverview(), refreshMetrics(), refreshDomains(), refreshLists()]);
+  } catch (err) {
+    const status = $("status");
+    status.textContent = "offline";
+    status.className = "badge offline";
+  }
+  setTimeout(refresh, REFRESH_MS);
+}
+
+window.addEventListener("resize", drawCharts);
+refresh();
@@ -0,0 +1,186 @@
+package webui
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"time"
+
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/dashboard"
+)
+
+//go:embed static
+var static embed.FS
+
+// Page is a recently crawled URL
+type Page struct {
+	URL     string    `json:"url"`
+	Status  int       `json:"status"`
+	Latency float64   `json:"latency_ms"`
+	Bytes   int       `json:"bytes"`
+	Time    time.Time `json:"time"`
+}
+
+// Failure is a recent fetch or storage error
+type Failure struct {
+	URL   string    `json:"url"`
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
+}
+
+// Source is implemented by the crawler to feed the web UI
+type Source interface {
+	Snapshot() dashboard.Snapshot
+	// Recorder returns the benchmark recorder sampling crawl progress
+	Recorder() *benchmark.Recorder
+	// RecentPages returns the last crawled pages, newest first
+	RecentPages() []Page
+	// RecentErrors returns the last errors, newest first
+	RecentErrors() []Failure
+}
+
+// Mux is where the UI registers its routes, e.g. the control API server
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Register serves the dashboard at / and its JSON endpoints under /ui/
+func Register(mux Mux, src Source) {
+	assets, _ := fs.Sub(static, "static")
+	files := http.FileServerFS(assets)
+
+	mux.Handle("GET /{$}", files)
+	mux.Handle("GET /ui/static/", http.StripPrefix("/ui/static", files))
+	mux.Handle("GET /ui/overview", handleOverview(src))
+	mux.Handle("GET /ui/metrics", handleMetrics(src))
+	mux.Handle("GET /ui/pages", handlePages(src))
+	mux.Handle("GET /ui/errors", handleErrors(src))
+	mux.Handle("GET /ui/domains", handleDomains(src))
+}
+
+// point is one benchmark sample, seconds since the crawl started
+type point struct {
+	T           float64          `json:"t"`
+	Pages       int              `json:"pages"`
+	Queued      int              `json:"queued"`
+	Workers     int              `json:"workers"`
+	Bytes       int64            `json:"bytes"`
+	LatencyP50  float64          `json:"latency_p50_ms"`
+	LatencyP95  float64          `json:"latency_p95_ms"`
+	LatencyP99  float64          `json:"latency_p99_ms"`
+	Errors      map[string]int64 `json:"errors"`
+	QueueDepths map[string]int64 `json:"queue_depths"`
+}
+
+// domain is the per-host row of /ui/domains
+type domain struct {
+	Host     string  `json:"host"`
+	Pages    int64   `json:"pages"`
+	Errors   int64   `json:"errors"`
+	Bytes    int64   `json:"bytes"`
+	Latency  float64 `json:"avg_latency_ms"`
+	Admitted int     `json:"admitted"`
+	MaxPages int     `json:"max_pages"`
+}
+
+func handleOverview(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snap := src.Snapshot()
+		workers := make(map[string]int)
+		for _, wk := range snap.Workers {
+			workers[wk.State]++
+		}
+		writeJSON(w, map[string]interface{}{
+			"elapsed_seconds": snap.Elapsed.Seconds(),
+			"pages":           snap.Pages,
+			"stored":          snap.Stored,
+			"errors":          snap.Errors,
+			"paused":          snap.Paused,
+			"rate_limit":      snap.RateLimit.String(),
+			"queue":           snap.Queue,
+			"workers":         workers,
+		})
+	}
+}
+
+// handleMetrics returns the samples after ?since=<seconds>, so the page
+// only fetches what it hasn't plotted yet
+func handleMetrics(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, _ := strconv.ParseFloat(r.URL.Query().Get("since"), 64)
+
+		rec := src.Recorder()
+		points := []point{}
+		for _, m := range rec.GetMetrics() {
+			if t := m.Timestamp.Sub(rec.Start()).Seconds(); t > since {
+				points = append(points, point{
+					T:           t,
+					Pages:       m.PagesCount,
+					Queued:      m.QueuedCount,
+					Workers:     m.Workers,
+					Bytes:       m.Bytes,
+					LatencyP50:  ms(m.LatencyP50),
+					LatencyP95:  ms(m.LatencyP95),
+					LatencyP99:  ms(m.LatencyP99),
+					Errors:      m.Errors,
+					QueueDepths: m.QueueDepths,
+				})
+			}
+		}
+		writeJSON(w, points)
+	}
+}
+
+func handlePages(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pages := src.RecentPages()
+		if pages == nil {
+			pages = []Page{}
+		}
+		writeJSON(w, pages)
+	}
+}
+
+func handleErrors(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		failures := src.RecentErrors()
+		if failures == nil {
+			failures = []Failure{}
+		}
+		writeJSON(w, failures)
+	}
+}
+
+func handleDomains(src Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hosts := src.Snapshot().Hosts
+		domains := make([]domain, 0, len(hosts))
+		for _, h := range hosts {
+			domains = append(domains, domain{
+				Host:     h.Host,
+				Pages:    h.Crawled,
+				Errors:   h.Errors,
+				Bytes:    h.Bytes,
+				Latency:  ms(h.Latency),
+				Admitted: h.Admitted,
+				MaxPages: h.MaxPages,
+			})
+		}
+		writeJSON(w, domains)
+	}
+}
+
+// ms converts d to fractional milliseconds
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
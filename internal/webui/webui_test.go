@@ -0,0 +1,156 @@
+package webui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web-crawler/internal/benchmark"
+	"web-crawler/internal/dashboard"
+)
+
+// fakeSource serves a fixed crawl state
+type fakeSource struct {
+	snap  dashboard.Snapshot
+	rec   *benchmark.Recorder
+	pages []Page
+}
+
+func (f *fakeSource) Snapshot() dashboard.Snapshot  { return f.snap }
+func (f *fakeSource) Recorder() *benchmark.Recorder { return f.rec }
+func (f *fakeSource) RecentPages() []Page           { return f.pages }
+func (f *fakeSource) RecentErrors() []Failure       { return nil }
+
+func newTestServer(t *testing.T, src Source) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	Register(mux, src)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get fetches path and decodes its JSON body into v
+func get(t *testing.T, srv *httptest.Server, path string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("GET %s = %s, %s", path, resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestOverviewAndDomains(t *testing.T) {
+	src := &fakeSource{
+		snap: dashboard.Snapshot{
+			Elapsed:   90 * time.Second,
+			Pages:     40,
+			RateLimit: 250 * time.Millisecond,
+			Queue:     map[string]int64{"size": 12},
+			Workers: []dashboard.WorkerState{
+				{ID: 1, State: "fetching"}, {ID: 2, State: "fetching"}, {ID: 3, State: "idle"},
+			},
+			Hosts: []dashboard.HostProgress{
+				{Host: "a.com", Crawled: 30, Errors: 2, Latency: 1500 * time.Microsecond, Admitted: 35, MaxPages: 100},
+			},
+		},
+		rec: benchmark.New(),
+	}
+	srv := newTestServer(t, src)
+
+	var overview struct {
+		Elapsed   float64          `json:"elapsed_seconds"`
+		Pages     int64            `json:"pages"`
+		RateLimit string           `json:"rate_limit"`
+		Queue     map[string]int64 `json:"queue"`
+		Workers   map[string]int   `json:"workers"`
+	}
+	get(t, srv, "/ui/overview", &overview)
+	if overview.Elapsed != 90 || overview.Pages != 40 || overview.RateLimit != "250ms" || overview.Queue["size"] != 12 {
+		t.Errorf("overview = %+v", overview)
+	}
+	if overview.Workers["fetching"] != 2 || overview.Workers["idle"] != 1 {
+		t.Errorf("workers by state = %v", overview.Workers)
+	}
+
+	var domains []domain
+	get(t, srv, "/ui/domains", &domains)
+	want := domain{Host: "a.com", Pages: 30, Errors: 2, Latency: 1.5, Admitted: 35, MaxPages: 100}
+	if len(domains) != 1 || domains[0] != want {
+		t.Errorf("domains = %+v, want [%+v]", domains, want)
+	}
+}
+
+func TestMetricsSince(t *testing.T) {
+	rec := benchmark.New()
+	rec.ObserveFetch("a.com", 20*time.Millisecond, 100)
+	rec.Record(1, 5, 2, nil)
+	time.Sleep(10 * time.Millisecond)
+	rec.Record(2, 4, 2, nil)
+	srv := newTestServer(t, &fakeSource{rec: rec})
+
+	var points []point
+	get(t, srv, "/ui/metrics", &points)
+	if len(points) != 2 || points[0].Pages != 1 || points[0].LatencyP50 != 20 || points[0].Bytes != 100 {
+		t.Fatalf("metrics = %+v", points)
+	}
+
+	var newer []point
+	get(t, srv, "/ui/metrics?since="+jsonNumber(points[0].T), &newer)
+	if len(newer) != 1 || newer[0].Pages != 2 {
+		t.Fatalf("metrics since the first sample = %+v", newer)
+	}
+}
+
+func TestEmptyListsAreArrays(t *testing.T) {
+	srv := newTestServer(t, &fakeSource{rec: benchmark.New()})
+	for _, path := range []string{"/ui/pages", "/ui/errors", "/ui/domains", "/ui/metrics"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.TrimSpace(string(body)) != "[]" {
+			t.Errorf("GET %s = %q, want []", path, body)
+		}
+	}
+}
+
+func TestServesEmbeddedPage(t *testing.T) {
+	srv := newTestServer(t, &fakeSource{rec: benchmark.New()})
+	for _, path := range []string{"/", "/ui/static/app.js", "/ui/static/style.css"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s = %s", path, resp.Status)
+		}
+	}
+	resp, err := http.Get(srv.URL + "/ui/static/missing.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of a missing asset = %s", resp.Status)
+	}
+}
+
+// jsonNumber formats f the way the page sends it back
+func jsonNumber(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
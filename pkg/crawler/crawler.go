@@ -0,0 +1,314 @@
+// Package crawler embeds the web crawler in other Go programs. It wraps the
+// crawler of the command line tool: the same configuration, filters,
+// storage backends and hooks, started and stopped from code, with stored
+// pages delivered on a channel.
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"web-crawler/internal/config"
+	"web-crawler/internal/crawler"
+	"web-crawler/internal/document"
+	"web-crawler/internal/fetcher"
+	"web-crawler/internal/logger"
+	"web-crawler/internal/queue"
+	"web-crawler/internal/seeds"
+	"web-crawler/internal/storage"
+)
+
+// Config is the crawler configuration, the same as the YAML config file
+type Config = config.Config
+
+// Page is a crawled page as it is stored
+type Page = storage.WebPage
+
+// KindGraphQL is the Kind of the records of GraphQL endpoints among the
+// results, see Config.GraphQL
+const KindGraphQL = storage.KindGraphQL
+
+// Response is a downloaded page, returned by fetchers and seen by fetch hooks
+type Response = fetcher.Response
+
+// URLItem is a queued URL with its priority and depth
+type URLItem = queue.URLItem
+
+// PausedHost is a host paused with PauseHost
+type PausedHost = queue.PausedHost
+
+// Archiver stores pages, e.g. in a database
+type Archiver = storage.Archiver
+
+// Fetcher downloads pages in place of the built-in HTTP client
+type Fetcher = crawler.Fetcher
+
+// Extractor extracts the text of a document type, see WithExtractor
+type Extractor = document.Extractor
+
+// Document is the text, title and links extracted from a document
+type Document = document.Document
+
+// Filter reports whether a URL may be crawled
+type Filter func(rawURL string) bool
+
+// Hook types, see OnFetch, OnParse, BeforeStore and OnError
+type (
+	FetchHook = crawler.FetchHook
+	PageHook  = crawler.PageHook
+	ErrorHook = crawler.ErrorHook
+)
+
+// ErrSkip returned by a hook drops the page without counting an error
+var ErrSkip = crawler.ErrSkip
+
+// ErrStarted is returned when a crawler is started twice
+var ErrStarted = errors.New("crawler already started")
+
+// DefaultConfig returns the configuration of an embedded crawler: the
+// defaults of the command line tool without the control API, content
+// files, benchmark reports and checkpoints, which write to the working
+// directory or listen on a port
+func DefaultConfig() *Config {
+	cfg := config.DefaultConfig()
+	cfg.API.Enabled = false
+	cfg.ContentSaver.Enabled = false
+	cfg.Benchmark.Enabled = false
+	cfg.Checkpoint.Enabled = false
+	cfg.Dashboard.Enabled = false
+	return cfg
+}
+
+// LoadConfig reads a YAML config file
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+// Crawler is an embedded crawl. Create it with New, start it with Start,
+// and read stored pages from Results until it is closed.
+type Crawler struct {
+	crawler *crawler.Crawler
+	results chan *Page
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	stop    <-chan struct{} // Closed when the crawl is stopped
+	done    chan struct{}
+	err     error
+}
+
+// New creates a crawler from the options. Seeds and hooks may be added
+// until Start is called.
+func New(opts ...Option) (*Crawler, error) {
+	o := &options{buffer: 100}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.cfg == nil {
+		o.cfg = DefaultConfig()
+	}
+	if err := o.cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := logger.Configure(logger.Options{
+		Format:     o.cfg.Logging.Format,
+		Level:      o.cfg.Logging.Level,
+		File:       o.cfg.Logging.File,
+		MaxSize:    o.cfg.Logging.MaxSize,
+		MaxBackups: o.cfg.Logging.MaxBackups,
+		Modules:    o.cfg.Logging.Modules,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
+	}
+
+	filters := make([]func(string) bool, len(o.filters))
+	for i, f := range o.filters {
+		filters[i] = f
+	}
+	inner, err := crawler.New(o.cfg, crawler.Options{
+		MongoURI:  o.mongoURI,
+		Fetcher:   o.fetcher,
+		Archivers: o.archivers,
+		Filters:   filters,
+
+		Extractors: o.extractors,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Crawler{
+		crawler: inner,
+		results: make(chan *Page, o.buffer),
+		done:    make(chan struct{}),
+	}
+	inner.OnStore(c.deliver)
+	if _, err := c.AddSeeds(append(o.cfg.Crawler.Seeds, o.seeds...)...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// deliver is the store hook that sends pages to Results. It blocks while
+// the channel is full, until the crawl is stopped. Pages are stored after
+// that, so ctx isn't cancelled with the crawl.
+func (c *Crawler) deliver(ctx context.Context, page *Page) error {
+	select {
+	case c.results <- page:
+	case <-c.stop:
+	}
+	return nil
+}
+
+// AddSeeds queues start URLs with high priority. When no allowed domains are
+// configured the crawl stays on the hosts of the seeds. It may be called
+// while the crawl runs.
+func (c *Crawler) AddSeeds(urls ...string) (int, error) {
+	return c.crawler.AddSeeds(urls)
+}
+
+// AddSeedFile queues the seeds of a file with one "url [priority] [depth]"
+// per line
+func (c *Crawler) AddSeedFile(path string) (int, error) {
+	result, err := seeds.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	return c.crawler.AddSeedList(result.Seeds), nil
+}
+
+// OnFetch registers a hook run on every HTML response before parsing
+func (c *Crawler) OnFetch(h FetchHook) {
+	c.crawler.OnFetch(h)
+}
+
+// OnParse registers a hook run on every parsed page before its links are
+// queued. Changing page.Links changes which links are followed.
+func (c *Crawler) OnParse(h PageHook) {
+	c.crawler.OnParse(h)
+}
+
+// BeforeStore registers a hook run on every page before it is stored
+func (c *Crawler) BeforeStore(h PageHook) {
+	c.crawler.BeforeStore(h)
+}
+
+// OnError registers a hook told about failed URLs
+func (c *Crawler) OnError(h ErrorHook) {
+	c.crawler.OnError(h)
+}
+
+// Start begins crawling in the background. The crawl ends when the
+// frontier is exhausted, max_pages is reached, ctx is cancelled, or Stop
+// is called; Results is closed after that.
+func (c *Crawler) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started {
+		return ErrStarted
+	}
+	c.started = true
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.stop = ctx.Done()
+	go func() {
+		err := c.crawler.Run(ctx)
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.results)
+		close(c.done)
+	}()
+	return nil
+}
+
+// Stop ends the crawl and waits until in-flight pages are drained and the
+// archivers are flushed, or ctx is done
+func (c *Crawler) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	select {
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wait blocks until the crawl has ended and returns its error. Results must
+// be drained meanwhile, or the crawl stalls once the buffer is full.
+func (c *Crawler) Wait() error {
+	c.mu.Lock()
+	started := c.started
+	c.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	<-c.done
+	return c.Err()
+}
+
+// Done is closed when the crawl has ended
+func (c *Crawler) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error the crawl ended with, if any
+func (c *Crawler) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// Results delivers every stored page, with all of its fields regardless of
+// storage.fields. It is closed when the crawl ends.
+func (c *Crawler) Results() <-chan *Page {
+	return c.results
+}
+
+// Stats returns the crawl statistics, as served by the control API
+func (c *Crawler) Stats() map[string]interface{} {
+	return c.crawler.Stats()
+}
+
+// Pause stops workers from taking new URLs until Resume is called
+func (c *Crawler) Pause() {
+	c.crawler.Pause()
+}
+
+// Resume lets paused workers continue
+func (c *Crawler) Resume() {
+	c.crawler.Resume()
+}
+
+// PauseHost holds back the URLs of host, for d or until ResumeHost if d is 0,
+// while the rest of the crawl goes on
+func (c *Crawler) PauseHost(host string, d time.Duration) {
+	c.crawler.PauseHost(host, d)
+}
+
+// ResumeHost queues the held back URLs of host again and returns how many
+// there were
+func (c *Crawler) ResumeHost(host string) int {
+	n, _ := c.crawler.ResumeHost(host)
+	return n
+}
+
+// PausedHosts lists the paused hosts with how many URLs each holds back and,
+// for timed pauses, when they end
+func (c *Crawler) PausedHosts() []PausedHost {
+	return c.crawler.PausedHosts()
+}
@@ -0,0 +1,511 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web-crawler/internal/config"
+)
+
+// site serves three linked pages
+func site(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"/":  `<html><head><title>Home</title></head><body><a href="/a">A</a> <a href="/b">B</a></body></html>`,
+		"/a": `<html><head><title>A</title></head><body><a href="/b">B</a> <a href="/">Home</a></body></html>`,
+		"/b": `<html><head><title>B</title></head><body><p>The end.</p></body></html>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testConfig crawls fast, with the output of the crawl kept in memory
+func testConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Crawler.Workers = 2
+	cfg.Crawler.RateLimit = time.Millisecond
+	cfg.Crawler.Timeout = 5 * time.Second
+	cfg.Logging.Level = "error"
+	return cfg
+}
+
+// collect reads Results until the crawl ends and returns the crawled paths
+func collect(t *testing.T, c *Crawler) []string {
+	t.Helper()
+	var paths []string
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case page, ok := <-c.Results():
+			if !ok {
+				sort.Strings(paths)
+				return paths
+			}
+			paths = append(paths, page.URL[strings.LastIndex(page.URL, "/"):])
+		case <-timeout:
+			t.Fatal("crawl didn't end")
+		}
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	srv := site(t)
+	c, err := New(WithConfig(testConfig()), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	titles := make(map[string]bool)
+	c.OnParse(func(ctx context.Context, page *Page) error {
+		mu.Lock()
+		titles[page.Title] = true
+		mu.Unlock()
+		return nil
+	})
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); !errors.Is(err, ErrStarted) {
+		t.Fatalf("second Start() = %v, want ErrStarted", err)
+	}
+
+	if got := strings.Join(collect(t, c), " "); got != "/ /a /b" {
+		t.Fatalf("crawled %s", got)
+	}
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !titles["Home"] || !titles["A"] || !titles["B"] {
+		t.Fatalf("parse hook saw %v", titles)
+	}
+}
+
+func TestCrawlFiltersAndSkips(t *testing.T) {
+	srv := site(t)
+	c, err := New(
+		WithConfig(testConfig()),
+		WithSeeds(srv.URL+"/"),
+		WithFilters(func(rawURL string) bool { return !strings.HasSuffix(rawURL, "/b") }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A skipped page is crawled but not stored
+	c.BeforeStore(func(ctx context.Context, page *Page) error {
+		if page.Title == "A" {
+			return ErrSkip
+		}
+		return nil
+	})
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(collect(t, c), " "); got != "/" {
+		t.Fatalf("stored %s", got)
+	}
+}
+
+// shoutExtractor takes documents as upper-cased text
+type shoutExtractor struct{}
+
+func (shoutExtractor) Extract(ctx context.Context, data []byte) (*Document, error) {
+	return &Document{Title: "Shout", Text: strings.ToUpper(string(data))}, nil
+}
+
+func TestCrawlDocuments(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<a href="/report.pdf">Report</a> <a href="/notes.txt">Notes</a>`))
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4\n1 0 obj\n<< /Title (Report) /A << /URI (" + srv.URL + "/b) >> >>\nendobj\n" +
+				"2 0 obj\n<< /Length 30 >>\nstream\nBT (Quarterly numbers) Tj ET\nendstream\nendobj\n%%EOF\n"))
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/x-notes")
+			w.Write([]byte("quiet notes"))
+		case "/b":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<title>B</title>`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.Documents.Enabled = true
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/"), WithExtractor("text/x-notes", shoutExtractor{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	pages := make(map[string]*Page)
+	c.OnParse(func(ctx context.Context, page *Page) error {
+		mu.Lock()
+		pages[page.URL[len(srv.URL):]] = page
+		mu.Unlock()
+		return nil
+	})
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(collect(t, c), " "); got != "/ /b /notes.txt /report.pdf" {
+		t.Fatalf("stored %s", got)
+	}
+
+	report := pages["/report.pdf"]
+	if report.Title != "Report" || report.Content != "Quarterly numbers" || report.ContentType != "application/pdf" {
+		t.Errorf("PDF page = %q, %q, %q", report.Title, report.Content, report.ContentType)
+	}
+	if notes := pages["/notes.txt"]; notes.Title != "Shout" || notes.Content != "QUIET NOTES" {
+		t.Errorf("custom document page = %q, %q", notes.Title, notes.Content)
+	}
+}
+
+func TestCrawlJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.RequestURI() {
+		case "/api/posts":
+			w.Write([]byte(`{"items": [{"id": 1, "url": "/api/posts/1"}, {"id": 2, "url": "/post/2"}], "next": "/api/posts?page=2"}`))
+		case "/api/posts?page=2":
+			w.Write([]byte(`{"items": [], "next": null}`))
+		case "/api/posts/1":
+			w.Header().Set("Content-Type", "application/vnd.post+json; charset=utf-8")
+			w.Write([]byte(`{"post": {"title": "First post", "body": "Hello"}}`))
+		case "/post/2":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<title>Second post</title>`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.JSON.Enabled = true
+	// Other +json types are parsed once they're downloaded
+	cfg.HTTP.AllowedContentTypes = append(cfg.HTTP.AllowedContentTypes, "application/vnd.post+json")
+	cfg.JSON.Rules = []config.JSONRule{
+		{URL: `/api/posts/\d+$`, Title: "$.post.title"},
+		{URL: `/api/posts`, Links: []string{"$.items[*].url", "$.next"}},
+	}
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/api/posts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	pages := make(map[string]*Page)
+	c.OnParse(func(ctx context.Context, page *Page) error {
+		mu.Lock()
+		pages[page.URL[len(srv.URL):]] = page
+		mu.Unlock()
+		return nil
+	})
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(collect(t, c), " "); got != "/1 /2 /posts /posts?page=2" {
+		t.Fatalf("stored %s", got)
+	}
+
+	post := pages["/api/posts/1"]
+	if post.Title != "First post" || post.Content != `{"post": {"title": "First post", "body": "Hello"}}` {
+		t.Errorf("JSON page = %q, %q", post.Title, post.Content)
+	}
+	body, _ := post.JSON.(map[string]interface{})["post"].(map[string]interface{})
+	if body["body"] != "Hello" {
+		t.Errorf("parsed JSON = %v", post.JSON)
+	}
+	if links := pages["/api/posts"].Links; len(links) != 3 || links[2] != srv.URL+"/api/posts?page=2" {
+		t.Errorf("links = %v", links)
+	}
+}
+
+func TestCrawlGraphQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/graphql" && r.Method == http.MethodPost:
+			w.Header().Set("Content-Type", "application/json")
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), "IntrospectionQuery") {
+				w.Write([]byte(`{"errors": [{"message": "introspection is disabled"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"__typename": "Query"}}`))
+		case r.URL.Path == "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<title>Home</title>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.GraphQL.Enabled = true
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var records []*Page
+	for page := range c.Results() {
+		records = append(records, page)
+	}
+	if len(records) != 2 {
+		t.Fatalf("stored %d records", len(records))
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].URL < records[j].URL })
+	if records[0].Kind != "" {
+		t.Errorf("page kind = %q", records[0].Kind)
+	}
+	endpoint := records[1]
+	if endpoint.URL != srv.URL+"/graphql" || endpoint.Kind != KindGraphQL || endpoint.GraphQL == nil || endpoint.GraphQL.Introspection {
+		t.Errorf("endpoint record = %+v", endpoint)
+	}
+}
+
+func TestCrawlLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/login" {
+			r.ParseForm()
+			if r.PostForm.Get("password") == "secret" {
+				http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1", Path: "/"})
+			}
+			return
+		}
+		if _, err := r.Cookie("sid"); err != nil {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<a href="/members">Members</a>`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.HTTP.Login = []config.LoginConfig{{Domain: "127.0.0.1", URL: srv.URL + "/login", Fields: map[string]string{"password": "secret"}}}
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(collect(t, c), " "); got != "/ /members" {
+		t.Fatalf("stored %s", got)
+	}
+
+	cfg.HTTP.Login[0].Fields["password"] = "wrong"
+	cfg.HTTP.Login[0].Check = "Welcome"
+	c, err = New(WithConfig(cfg), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	collect(t, c)
+	if err := c.Wait(); err == nil || !strings.Contains(err.Error(), "failed to log in") {
+		t.Errorf("Wait() = %v", err)
+	}
+}
+
+func TestCrawlDomainProfile(t *testing.T) {
+	var inFlight, maxInFlight int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		if r.Header.Get("X-Token") != "abc" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a>`))
+		case "/a", "/b", "/c":
+			w.Write([]byte(`<h1>` + r.URL.Path[1:] + `</h1><a href="` + r.URL.Path + `/deep">Deep</a>`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.Crawler.Workers = 4
+	cfg.Extraction.Rules = []config.ExtractionRule{{Name: "global", Selector: "a"}}
+	cfg.Domains = map[string]config.DomainProfile{
+		"127.0.0.1": {
+			Workers:    1,
+			MaxDepth:   1,
+			Headers:    map[string]string{"X-Token": "abc"},
+			Extraction: []config.ExtractionRule{{Name: "heading", Selector: "h1"}},
+		},
+	}
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for page := range c.Results() {
+		paths = append(paths, page.URL[strings.LastIndex(page.URL, "/"):])
+		if _, ok := page.Extracted["global"]; ok {
+			t.Errorf("%s extracted with the global rules", page.URL)
+		}
+		if page.URL == srv.URL+"/a" && page.Extracted["heading"] != "a" {
+			t.Errorf("%s extracted %v", page.URL, page.Extracted)
+		}
+	}
+	sort.Strings(paths)
+	if got := strings.Join(paths, " "); got != "/ /a /b /c" {
+		t.Errorf("stored %s", got)
+	}
+	if max := atomic.LoadInt64(&maxInFlight); max != 1 {
+		t.Errorf("%d requests in flight at once, want 1", max)
+	}
+}
+
+func TestCrawlLinkCheck(t *testing.T) {
+	// Another host, kept out of the crawl by the domain filter
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(other.Close)
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/missing">Missing</a> <a href="` + otherURL + `/ok">OK</a> <a href="` + otherURL + `/gone">Gone</a>`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := testConfig()
+	cfg.LinkCheck.Enabled = true
+	cfg.LinkCheck.OutputDir = t.TempDir()
+	cfg.LinkCheck.Formats = []string{"json"}
+	c, err := New(WithConfig(cfg), WithSeeds(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	collect(t, c)
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.LinkCheck.OutputDir, "broken-links.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report []struct {
+		Broken []struct {
+			URL       string   `json:"url"`
+			Status    int      `json:"status"`
+			Referrers []string `json:"referrers"`
+		} `json:"broken"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range report {
+		for _, b := range d.Broken {
+			if len(b.Referrers) != 1 || b.Referrers[0] != srv.URL+"/" {
+				t.Errorf("%s found on %v", b.URL, b.Referrers)
+			}
+			got = append(got, fmt.Sprintf("%s %d", b.URL, b.Status))
+		}
+	}
+	sort.Strings(got)
+	want := []string{srv.URL + "/missing 404", otherURL + "/gone 404"}
+	sort.Strings(want)
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("broken links %v, want %v", got, want)
+	}
+}
+
+func TestStop(t *testing.T) {
+	srv := site(t)
+	c, err := New(WithConfig(testConfig()), WithSeeds(srv.URL+"/"), WithResultBuffer(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stopping before Start does nothing
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody reads the results, so the crawl blocks on the first page until stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := c.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Fatal("Done not closed after Stop")
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Crawler.Workers = -1
+	if _, err := New(WithConfig(cfg)); err == nil {
+		t.Fatal("New() accepted an invalid config")
+	}
+	if _, err := New(WithConfig(testConfig()), WithSeeds("not a url")); err == nil {
+		t.Fatal("New() accepted an invalid seed")
+	}
+}
@@ -0,0 +1,85 @@
+package crawler
+
+// Option configures a crawler created by New
+type Option func(*options)
+
+// options collects the settings of New
+type options struct {
+	cfg       *Config
+	seeds     []string
+	mongoURI  string
+	archivers []Archiver
+	filters   []Filter
+	fetcher   Fetcher
+	buffer    int
+
+	extractors map[string]Extractor
+}
+
+// WithConfig crawls with cfg instead of DefaultConfig
+func WithConfig(cfg *Config) Option {
+	return func(o *options) {
+		o.cfg = cfg
+	}
+}
+
+// WithSeeds queues start URLs, in addition to crawler.seeds of the config
+func WithSeeds(urls ...string) Option {
+	return func(o *options) {
+		o.seeds = append(o.seeds, urls...)
+	}
+}
+
+// WithMongoDB stores pages in the MongoDB at uri, as storage.mongodb configures
+func WithMongoDB(uri string) Option {
+	return func(o *options) {
+		o.mongoURI = uri
+	}
+}
+
+// WithArchiver stores pages in a, in addition to the configured backends.
+// It is closed when the crawl ends.
+func WithArchiver(a Archiver) Option {
+	return func(o *options) {
+		o.archivers = append(o.archivers, a)
+	}
+}
+
+// WithFilters adds checks that every discovered URL must pass to be
+// queued, after the configured filters. Seeds are not checked.
+func WithFilters(filters ...Filter) Option {
+	return func(o *options) {
+		o.filters = append(o.filters, filters...)
+	}
+}
+
+// WithFetcher downloads pages with f instead of the built-in HTTP client.
+// Rate limits and robots.txt still apply; retries and conditional requests
+// are up to f.
+func WithFetcher(f Fetcher) Option {
+	return func(o *options) {
+		o.fetcher = f
+	}
+}
+
+// WithExtractor extracts the text of documents of mediaType with e, in
+// place of the built-in extractor or the configured command. It applies when
+// documents.enabled is set; the type needn't be in documents.types.
+func WithExtractor(mediaType string, e Extractor) Option {
+	return func(o *options) {
+		if o.extractors == nil {
+			o.extractors = make(map[string]Extractor)
+		}
+		o.extractors[mediaType] = e
+	}
+}
+
+// WithResultBuffer sets how many stored pages Results holds before the
+// crawl waits for them to be read, 100 by default
+func WithResultBuffer(n int) Option {
+	return func(o *options) {
+		if n >= 0 {
+			o.buffer = n
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Archive formats of the content saver
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// Archive groupings of the content saver
+const (
+	ArchivePerRun    = "run"    // One archive for the whole crawl
+	ArchivePerDomain = "domain" // One archive per domain
+)
+
+// archiveIndexName is the entry closing every archive, one JSON line per page
+const archiveIndexName = "index.jsonl"
+
+// archiveIndexEntry is a line of an archive index
+type archiveIndexEntry struct {
+	Path string `json:"path"` // Entry name in the archive
+	ManifestEntry
+}
+
+// pageArchive is an archive being written
+type pageArchive struct {
+	mu    sync.Mutex
+	name  string // File name in the content directory
+	file  *os.File
+	zip   *zip.Writer
+	gzip  *gzip.Writer
+	tar   *tar.Writer
+	index []archiveIndexEntry
+}
+
+// openArchive creates an archive of format at path
+func openArchive(path, format string) (*pageArchive, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	a := &pageArchive{name: filepath.Base(path), file: file}
+	if format == ArchiveZip {
+		a.zip = zip.NewWriter(file)
+	} else {
+		a.gzip = gzip.NewWriter(file)
+		a.tar = tar.NewWriter(a.gzip)
+	}
+	return a, nil
+}
+
+// add writes one page to the archive and remembers its index entry
+func (a *pageArchive) add(name string, data []byte, entry ManifestEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.write(name, data, entry.CrawledAt); err != nil {
+		return fmt.Errorf("failed to add %s to %s: %w", name, a.name, err)
+	}
+	a.index = append(a.index, archiveIndexEntry{Path: name, ManifestEntry: entry})
+	return nil
+}
+
+// write adds an entry. a.mu must be held.
+func (a *pageArchive) write(name string, data []byte, modTime time.Time) error {
+	if a.zip != nil {
+		w, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := a.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := a.tar.Write(data)
+	return err
+}
+
+// close ends the archive with its index
+func (a *pageArchive) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var index []byte
+	for _, entry := range a.index {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		index = append(append(index, line...), '\n')
+	}
+	errs := []error{a.write(archiveIndexName, index, time.Now())}
+	if a.zip != nil {
+		errs = append(errs, a.zip.Close())
+	} else {
+		errs = append(errs, a.tar.Close(), a.gzip.Close())
+	}
+	errs = append(errs, a.file.Close())
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to close archive %s: %w", a.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var archiveStart = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// archivedPages saves one page per URL into archives of format
+func archivedPages(t *testing.T, format, per string, urls ...string) (*ContentSaver, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cs := NewContentSaver(dir, true, 0)
+	if err := cs.SetArchive(format, per, archiveStart); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range urls {
+		page := savedPage()
+		page.URL = u
+		if err := cs.SavePage(page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return cs, dir
+}
+
+// archiveEntries reads the entries of an archive by name
+func archiveEntries(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	entries := make(map[string][]byte)
+	if strings.HasSuffix(path, ".zip") {
+		r, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			rc, _ := f.Open()
+			entries[f.Name], _ = io.ReadAll(rc)
+			rc.Close()
+		}
+		return entries
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name], _ = io.ReadAll(tr)
+	}
+}
+
+// archiveIndex parses the index entry of an archive
+func archiveIndex(t *testing.T, entries map[string][]byte) []archiveIndexEntry {
+	t.Helper()
+	var index []archiveIndexEntry
+	scanner := bufio.NewScanner(bytes.NewReader(entries[archiveIndexName]))
+	for scanner.Scan() {
+		var entry archiveIndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		index = append(index, entry)
+	}
+	return index
+}
+
+func TestZipArchivePerRun(t *testing.T) {
+	cs, dir := archivedPages(t, ArchiveZip, ArchivePerRun, "https://a.com/one", "https://b.com/two")
+
+	path := filepath.Join(dir, "pages-20240301T120000Z.zip")
+	entries := archiveEntries(t, path)
+	if len(entries) != 3 {
+		t.Fatalf("archive entries %d, want 2 pages and the index", len(entries))
+	}
+	index := archiveIndex(t, entries)
+	if len(index) != 2 || index[0].URL != "https://a.com/one" || index[1].URL != "https://b.com/two" {
+		t.Fatalf("index = %+v", index)
+	}
+	for _, entry := range index {
+		data := entries[entry.Path]
+		if !strings.HasSuffix(string(data), formatPage) || entry.Size != len(data) || entry.Archive != filepath.Base(path) {
+			t.Errorf("entry %s = %+v with %d bytes", entry.Path, entry, len(data))
+		}
+	}
+	// The manifest points into the archive and no loose files are written
+	if file, ok := cs.Lookup("https://a.com/one"); !ok || file != index[0].Path {
+		t.Fatalf("Lookup() = %s, %v", file, ok)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a_com")); !os.IsNotExist(err) {
+		t.Fatal("domain directory created in archive mode")
+	}
+}
+
+func TestTarGzArchivePerDomain(t *testing.T) {
+	cs, dir := archivedPages(t, ArchiveTarGz, ArchivePerDomain, "https://a.com/one", "https://a.com/two", "https://b.com/three")
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	sort.Strings(matches)
+	want := []string{filepath.Join(dir, "a_com-20240301T120000Z.tar.gz"), filepath.Join(dir, "b_com-20240301T120000Z.tar.gz")}
+	if strings.Join(matches, " ") != strings.Join(want, " ") {
+		t.Fatalf("archives %v, want %v", matches, want)
+	}
+	if index := archiveIndex(t, archiveEntries(t, want[0])); len(index) != 2 {
+		t.Fatalf("a.com index = %+v", index)
+	}
+
+	// Pages saved after Close go to a new archive instead of replacing one
+	page := savedPage()
+	page.URL = "https://a.com/four"
+	if err := cs.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if index := archiveIndex(t, archiveEntries(t, filepath.Join(dir, "a_com-20240301T120000Z-2.tar.gz"))); len(index) != 1 {
+		t.Fatalf("second archive index = %+v", index)
+	}
+	if index := archiveIndex(t, archiveEntries(t, want[0])); len(index) != 2 {
+		t.Fatal("first archive was replaced")
+	}
+}
+
+func TestSetArchiveErrors(t *testing.T) {
+	cs := NewContentSaver(t.TempDir(), true, 0)
+	if err := cs.SetArchive("rar", ArchivePerRun, archiveStart); err == nil {
+		t.Error("SetArchive(rar) succeeded")
+	}
+	if err := cs.SetArchive(ArchiveZip, "host", archiveStart); err == nil {
+		t.Error("SetArchive(zip, host) succeeded")
+	}
+}
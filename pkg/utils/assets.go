@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AssetRecord is one line of the asset manifest
+type AssetRecord struct {
+	URL         string    `json:"url"`
+	SourcePage  string    `json:"source_page"`
+	Path        string    `json:"path"` // Relative to the asset directory
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	SHA256      string    `json:"sha256"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// assetCategory maps a media type to the top-level directory of the asset layout
+func assetCategory(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mt, "image/"):
+		return "images"
+	case strings.HasPrefix(mt, "video/"), strings.HasPrefix(mt, "audio/"):
+		return "media"
+	case mt == "application/pdf", strings.HasPrefix(mt, "application/msword"),
+		strings.HasPrefix(mt, "application/vnd."), mt == "text/csv":
+		return "documents"
+	default:
+		return "other"
+	}
+}
+
+// assetExtension returns the file extension from the URL path, falling back to the media type
+func assetExtension(u *url.URL, contentType string) string {
+	if ext := path.Ext(u.Path); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if exts, err := mime.ExtensionsByType(mt); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// SaveAsset writes a binary asset under <assetDir>/<category>/<domain>/ and
+// appends a record to <assetDir>/manifest.jsonl. Returns the saved record, or
+// nil when saving is disabled.
+func (cs *ContentSaver) SaveAsset(assetDir, assetURL, sourcePage, contentType string, data []byte, fetchedAt time.Time) (*AssetRecord, error) {
+	if !cs.enabled {
+		return nil, nil
+	}
+
+	parsedURL, err := url.Parse(assetURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	// Asset names keep the original base name, prefixed with a short content hash
+	// so that different files with the same name don't overwrite each other
+	base := strings.TrimSuffix(path.Base(parsedURL.Path), path.Ext(parsedURL.Path))
+	if base == "" || base == "." || base == "/" {
+		base = "asset"
+	}
+	base = cs.createSafeFilename("/" + base)
+	filename := hash[:12] + "_" + base + assetExtension(parsedURL, contentType)
+
+	rel := filepath.Join(assetCategory(contentType), cs.sanitizeDomain(parsedURL.Host), filename)
+	dir := filepath.Join(cs.baseDir, assetDir)
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, rel), data, 0644); err != nil {
+		return nil, err
+	}
+
+	record := &AssetRecord{
+		URL:         assetURL,
+		SourcePage:  sourcePage,
+		Path:        filepath.ToSlash(rel),
+		ContentType: contentType,
+		Size:        len(data),
+		SHA256:      hash,
+		FetchedAt:   fetchedAt,
+	}
+	if err := cs.appendManifest(dir, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// appendManifest appends one JSON line describing a saved asset
+func (cs *ContentSaver) appendManifest(dir string, record *AssetRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	cs.manifestMu.Lock()
+	defer cs.manifestMu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(dir, "manifest.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open asset manifest: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAssetCategory(t *testing.T) {
+	tests := map[string]string{
+		"image/png":                "images",
+		"video/mp4":                "media",
+		"audio/mpeg; codecs=mp3":   "media",
+		"application/pdf":          "documents",
+		"application/vnd.ms-excel": "documents",
+		"text/csv":                 "documents",
+		"application/zip":          "other",
+		"":                         "other",
+	}
+	for contentType, want := range tests {
+		if got := assetCategory(contentType); got != want {
+			t.Errorf("assetCategory(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+func TestAssetExtension(t *testing.T) {
+	tests := []struct {
+		url, contentType, want string
+	}{
+		{"https://a.com/logo.PNG", "image/png", ".png"},
+		{"https://a.com/report", "application/pdf", ".pdf"},
+		{"https://a.com/file.reallylong", "application/x-unknown", ".bin"},
+		{"https://a.com/blob", "", ".bin"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := assetExtension(u, tt.contentType); got != tt.want {
+			t.Errorf("assetExtension(%q, %q) = %q, want %q", tt.url, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestSaveAsset(t *testing.T) {
+	base := t.TempDir()
+	cs := NewContentSaver(base, true, 0)
+	data := []byte("\x89PNG fake image")
+	fetched := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rec, err := cs.SaveAsset("assets", "https://www.example.com/img/logo.png", "https://www.example.com/", "image/png", data, fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if want := "images/example_com/" + hash[:12] + "_logo.png"; rec.Path != want {
+		t.Fatalf("Path = %q, want %q", rec.Path, want)
+	}
+	if rec.SHA256 != hash || rec.Size != len(data) || rec.SourcePage != "https://www.example.com/" {
+		t.Fatalf("record = %+v", rec)
+	}
+	saved, err := os.ReadFile(filepath.Join(base, "assets", filepath.FromSlash(rec.Path)))
+	if err != nil || string(saved) != string(data) {
+		t.Fatalf("saved asset = %q, %v", saved, err)
+	}
+
+	// A second asset with the same name but other content gets its own file
+	other, err := cs.SaveAsset("assets", "https://www.example.com/other/logo.png", "", "image/png", []byte("other"), fetched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Path == rec.Path {
+		t.Fatal("assets with the same name overwrote each other")
+	}
+
+	file, err := os.Open(filepath.Join(base, "assets", "manifest.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var records []AssetRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r AssetRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 || records[0] != *rec || records[1].URL != other.URL {
+		t.Fatalf("manifest = %+v", records)
+	}
+}
+
+func TestSaveAssetDisabled(t *testing.T) {
+	cs := NewContentSaver(t.TempDir(), false, 0)
+	rec, err := cs.SaveAsset("assets", "https://example.com/a.png", "", "image/png", []byte("x"), time.Now())
+	if rec != nil || err != nil {
+		t.Fatalf("SaveAsset() = %v, %v with saving disabled", rec, err)
+	}
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "unsafe"
+
+// BytesString returns b as a string without copying it. b must not be
+// modified while the string is in use.
+func BytesString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// StringBytes returns the bytes of s without copying them. They must not be
+// modified.
+func StringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
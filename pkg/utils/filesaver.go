@@ -2,11 +2,15 @@
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,7 +18,23 @@
 type ContentSaver struct {
 	baseDir     string
 	enabled     bool
-	maxFileSize int64 // Maximum file size to save (in bytes)
+	maxFileSize int64  // Maximum file size to save (in bytes)
+	format      string // Output format, FormatHTML by default
+	manifestMu  sync.Mutex
+	pages       *manifest
+
+	// Archive output, files when archive is empty
+	archive    string
+	archivePer string
+	runID      string // Crawl start, naming the archives of the run
+	archivesMu sync.Mutex
+	archives   map[string]*pageArchive // By domain, or "" for one per run
+
+	// Offline copy, off when mirrorDir is empty
+	mirrorDir   string
+	mirrorLocal func(pageURL string) bool // Pages linked inside the copy
+	mirroredMu  sync.Mutex
+	mirrored    map[string]bool // Resources claimed for download
 }
 
 // NewContentSaver creates a new content saver
@@ -23,45 +43,190 @@ func NewContentSaver(baseDir string, enabled bool, maxFileSize int64) *ContentSa
 		baseDir:     baseDir,
 		enabled:     enabled,
 		maxFileSize: maxFileSize,
+		format:      FormatHTML,
+		pages:       &manifest{path: filepath.Join(baseDir, manifestFile)},
+	}
+}
+
+// SetFormat selects the output format of saved pages: html, raw, json,
+// mhtml or warc
+func (cs *ContentSaver) SetFormat(format string) error {
+	if _, ok := formatExtensions[format]; !ok {
+		return fmt.Errorf("unknown content format %q", format)
+	}
+	cs.format = format
+	return nil
+}
+
+// SetArchive writes pages into zip or tar.gz archives, one per run or per
+// domain, instead of separate files. Archives are named after start, so every
+// run gets its own.
+func (cs *ContentSaver) SetArchive(format, per string, start time.Time) error {
+	if format != ArchiveZip && format != ArchiveTarGz {
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+	if per != ArchivePerRun && per != ArchivePerDomain {
+		return fmt.Errorf("unknown archive grouping %q", per)
 	}
+	cs.archive = format
+	cs.archivePer = per
+	cs.runID = start.UTC().Format("20060102T150405Z")
+	cs.archives = make(map[string]*pageArchive)
+	return nil
 }
 
-// SavePageContent saves page content to a file with metadata
-func (cs *ContentSaver) SavePageContent(pageURL, title, content, contentType string, statusCode int, crawledAt time.Time) error {
+// archiveFor returns the open archive of domain, creating it on first use
+func (cs *ContentSaver) archiveFor(domain string) (*pageArchive, error) {
+	key, name := "", "pages-"+cs.runID
+	if cs.archivePer == ArchivePerDomain {
+		key, name = domain, domain+"-"+cs.runID
+	}
+
+	cs.archivesMu.Lock()
+	defer cs.archivesMu.Unlock()
+
+	if a, ok := cs.archives[key]; ok {
+		return a, nil
+	}
+	if err := os.MkdirAll(cs.baseDir, 0755); err != nil {
+		return nil, err
+	}
+	// Archives closed earlier in the run are kept
+	path := filepath.Join(cs.baseDir, name+"."+cs.archive)
+	for n := 2; fileExists(path); n++ {
+		path = filepath.Join(cs.baseDir, fmt.Sprintf("%s-%d.%s", name, n, cs.archive))
+	}
+	a, err := openArchive(path, cs.archive)
+	if err != nil {
+		return nil, err
+	}
+	cs.archives[key] = a
+	return a, nil
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Format returns the output format of saved pages
+func (cs *ContentSaver) Format() string {
+	return cs.format
+}
+
+// SavePage saves a page to a file in the output format and records it in
+// the manifest. Recrawls of a URL overwrite its file.
+func (cs *ContentSaver) SavePage(page *SavedPage) error {
 	if !cs.enabled {
 		return nil
 	}
 
 	// Skip if content is too large
-	if cs.maxFileSize > 0 && int64(len(content)) > cs.maxFileSize {
+	if cs.maxFileSize > 0 && int64(len(page.Content)) > cs.maxFileSize {
 		return nil
 	}
 
-	// Create safe filename from URL
-	filename := cs.createSafeFilename(pageURL)
-
 	// Create domain-based directory structure
-	parsedURL, err := url.Parse(pageURL)
+	parsedURL, err := url.Parse(page.URL)
 	if err != nil {
 		return err
 	}
 
-	domainDir := filepath.Join(cs.baseDir, cs.sanitizeDomain(parsedURL.Host))
-	if err := os.MkdirAll(domainDir, 0755); err != nil {
+	domain := cs.sanitizeDomain(parsedURL.Host)
+	if cs.archive == "" {
+		if err := os.MkdirAll(filepath.Join(cs.baseDir, domain), 0755); err != nil {
+			return err
+		}
+	}
+	file, err := cs.pages.claim(page.URL, domain+"/"+cs.pageFileName(page.URL), formatExtensions[cs.format])
+	if err != nil {
 		return err
 	}
 
-	// Create full file path
-	filePath := filepath.Join(domainDir, filename+".html")
+	var data []byte
+	switch cs.format {
+	case FormatRaw:
+		data = StringBytes(page.Content)
+	case FormatJSON:
+		data, err = encodeJSON(page)
+	case FormatMHTML:
+		data, err = encodeMHTML(page)
+	case FormatWARC:
+		data, err = encodeWARC(page)
+	default:
+		metadata := cs.createMetadataHeader(page.URL, page.Title, page.ContentType, page.StatusCode, page.CrawledAt, len(page.Content))
+		data = make([]byte, 0, len(metadata)+2+len(page.Content))
+		data = append(append(append(data, metadata...), "\n\n"...), page.Content...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode %s as %s: %w", page.URL, cs.format, err)
+	}
 
-	// Create metadata header
-	metadata := cs.createMetadataHeader(pageURL, title, contentType, statusCode, crawledAt, len(content))
+	entry := ManifestEntry{
+		URL:         page.URL,
+		Title:       page.Title,
+		ContentType: page.ContentType,
+		StatusCode:  page.StatusCode,
+		Format:      cs.format,
+		Size:        len(data),
+		CrawledAt:   page.CrawledAt,
+	}
+	if cs.archive != "" {
+		err = cs.addToArchive(domain, file, data, &entry)
+	} else {
+		err = os.WriteFile(filepath.Join(cs.baseDir, filepath.FromSlash(file)), data, 0644)
+	}
+	if err != nil {
+		cs.pages.release(file)
+		return err
+	}
+	return cs.pages.record(file, entry)
+}
 
-	// Combine metadata and content
-	fullContent := metadata + "\n\n" + content
+// addToArchive writes a page into the archive of its domain or run
+func (cs *ContentSaver) addToArchive(domain, file string, data []byte, entry *ManifestEntry) error {
+	a, err := cs.archiveFor(domain)
+	if err != nil {
+		return err
+	}
+	entry.Archive = a.name
+	return a.add(file, data, *entry)
+}
+
+// pageFileName returns the name of a page's file without extension: the
+// sanitized URL followed by a hash of the whole URL, so URLs that sanitize
+// alike don't share a file
+func (cs *ContentSaver) pageFileName(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return cs.createSafeFilename(pageURL) + "_" + hex.EncodeToString(sum[:6])
+}
+
+// Lookup returns the file a URL was saved to, relative to the content
+// directory
+func (cs *ContentSaver) Lookup(pageURL string) (string, bool) {
+	return cs.pages.lookup(pageURL)
+}
 
-	// Write to file
-	return os.WriteFile(filePath, []byte(fullContent), 0644)
+// Flush writes the manifest of saved pages
+func (cs *ContentSaver) Flush() error {
+	if !cs.enabled {
+		return nil
+	}
+	return cs.pages.flush()
+}
+
+// Close finishes the open archives with their index and writes the
+// manifest. Pages saved afterwards start new archives.
+func (cs *ContentSaver) Close() error {
+	cs.archivesMu.Lock()
+	var errs []error
+	for key, a := range cs.archives {
+		errs = append(errs, a.close())
+		delete(cs.archives, key)
+	}
+	cs.archivesMu.Unlock()
+	return errors.Join(append(errs, cs.Flush())...)
 }
 
 // createSafeFilename creates a safe filename from URL
@@ -147,7 +312,7 @@ func (cs *ContentSaver) GetSavedFiles() ([]string, error) {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() && strings.HasSuffix(path, ".html") {
+		if !info.IsDir() && strings.HasSuffix(path, formatExtensions[cs.format]) && path != cs.pages.path {
 			files = append(files, path)
 		}
 		return nil
@@ -166,6 +331,7 @@ func (cs *ContentSaver) GetStats() (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	entries, collisions := cs.pages.stats()
 
 	var totalSize int64
 	domainCount := make(map[string]int)
@@ -189,5 +355,7 @@ func (cs *ContentSaver) GetStats() (map[string]interface{}, error) {
 		"domains":      len(domainCount),
 		"domain_count": domainCount,
 		"base_dir":     cs.baseDir,
+		"manifest":     entries,
+		"collisions":   collisions,
 	}, nil
 }
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// Output formats of the content saver
+const (
+	FormatHTML  = "html"  // Page with a metadata comment header
+	FormatRaw   = "raw"   // Page as received
+	FormatJSON  = "json"  // Metadata fields and the page
+	FormatMHTML = "mhtml" // Single-file archive of the page and its resources
+	FormatWARC  = "warc"  // WARC 1.1 response record
+)
+
+// formatExtensions are the file extensions of the output formats
+var formatExtensions = map[string]string{
+	FormatHTML:  ".html",
+	FormatRaw:   ".html",
+	FormatJSON:  ".json",
+	FormatMHTML: ".mhtml",
+	FormatWARC:  ".warc",
+}
+
+// SavedPage is a crawled page handed to the content saver
+type SavedPage struct {
+	URL         string
+	Title       string
+	Content     string
+	ContentType string
+	StatusCode  int
+	CrawledAt   time.Time
+	Headers     http.Header       // Response headers, for JSON and WARC
+	Metadata    map[string]string // Description, OpenGraph and Twitter card fields, for JSON
+	Resources   []Resource        // Stylesheets, scripts and images, for MHTML
+	RawRequest  []byte            // Request line and headers as sent, for WARC
+	RawResponse []byte            // Status line, headers and body as received, for WARC
+}
+
+// Resource is a file embedded in a page
+type Resource struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// encodeJSON writes the page with its metadata as one JSON document
+func encodeJSON(page *SavedPage) ([]byte, error) {
+	doc := struct {
+		URL         string            `json:"url"`
+		Title       string            `json:"title"`
+		ContentType string            `json:"content_type"`
+		StatusCode  int               `json:"status_code"`
+		CrawledAt   time.Time         `json:"crawled_at"`
+		Size        int               `json:"size"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Headers     http.Header       `json:"headers,omitempty"`
+		Content     string            `json:"content"`
+	}{page.URL, page.Title, page.ContentType, page.StatusCode, page.CrawledAt, len(page.Content), page.Metadata, page.Headers, page.Content}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// encodeMHTML writes the page and its resources as a multipart/related
+// message (RFC 2557), the format browsers save single-file pages in
+func encodeMHTML(page *SavedPage) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: <Saved by Go Web Crawler>\r\n")
+	fmt.Fprintf(&buf, "Snapshot-Content-Location: %s\r\n", page.URL)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", page.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", page.CrawledAt.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/related;\r\n\ttype=\"text/html\";\r\n\tboundary=\"%s\"\r\n\r\n", mw.Boundary())
+
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "text/html"
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+		"Content-Location":          {page.URL},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(part)
+	qp.Write(StringBytes(page.Content))
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, res := range page.Resources {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {res.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Location":          {res.URL},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Base64 lines are limited to 76 characters
+		encoded := base64.StdEncoding.EncodeToString(res.Data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeWARC writes the page as a WARC 1.1 response record, preceded by its
+// request record when the raw exchange is known. Without it the HTTP
+// message is rebuilt from the status, headers and decoded content.
+func encodeWARC(page *SavedPage) ([]byte, error) {
+	response := page.RawResponse
+	if response == nil {
+		var msg bytes.Buffer
+		fmt.Fprintf(&msg, "HTTP/1.1 %d %s\r\n", page.StatusCode, http.StatusText(page.StatusCode))
+		headers := page.Headers.Clone()
+		if headers == nil {
+			headers = http.Header{"Content-Type": {page.ContentType}}
+		}
+		// The content is stored decoded and whole
+		headers.Del("Content-Encoding")
+		headers.Del("Transfer-Encoding")
+		headers.Set("Content-Length", strconv.Itoa(len(page.Content)))
+		headers.Write(&msg)
+		msg.WriteString("\r\n")
+		msg.WriteString(page.Content)
+		response = msg.Bytes()
+	}
+
+	var buf bytes.Buffer
+	responseID := warcRecordID()
+	if page.RawRequest != nil {
+		writeWARCRecord(&buf, "request", warcRecordID(), page, "application/http;msgtype=request", page.RawRequest,
+			"WARC-Concurrent-To: "+responseID)
+	}
+	writeWARCRecord(&buf, "response", responseID, page, "application/http;msgtype=response", response)
+	return buf.Bytes(), nil
+}
+
+// writeWARCRecord appends one WARC record with a SHA-1 block digest
+func writeWARCRecord(buf *bytes.Buffer, recordType, id string, page *SavedPage, contentType string, block []byte, extra ...string) {
+	digest := sha1.Sum(block)
+	fmt.Fprintf(buf, "WARC/1.1\r\n")
+	fmt.Fprintf(buf, "WARC-Type: %s\r\n", recordType)
+	fmt.Fprintf(buf, "WARC-Record-ID: %s\r\n", id)
+	fmt.Fprintf(buf, "WARC-Date: %s\r\n", page.CrawledAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(buf, "WARC-Target-URI: %s\r\n", page.URL)
+	fmt.Fprintf(buf, "WARC-Block-Digest: sha1:%s\r\n", base32.StdEncoding.EncodeToString(digest[:]))
+	for _, field := range extra {
+		buf.WriteString(field + "\r\n")
+	}
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+}
+
+// warcRecordID returns a random UUID URN
+func warcRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
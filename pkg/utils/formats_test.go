@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const formatPage = "<html><head><title>Café</title></head><body>Hello</body></html>"
+
+func savedPage() *SavedPage {
+	return &SavedPage{
+		URL:         "https://www.example.com/docs/page",
+		Title:       "Café",
+		Content:     formatPage,
+		ContentType: "text/html; charset=utf-8",
+		StatusCode:  200,
+		CrawledAt:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Headers:     http.Header{"Content-Type": {"text/html; charset=utf-8"}, "Content-Encoding": {"gzip"}},
+		Metadata:    map[string]string{"description": "A page"},
+	}
+}
+
+// saveAs saves page in format and returns the file's contents
+func saveAs(t *testing.T, format string, page *SavedPage) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	cs := NewContentSaver(dir, true, 0)
+	if err := cs.SetFormat(format); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	files, err := cs.GetSavedFiles()
+	if err != nil || len(files) != 1 {
+		t.Fatalf("%s: saved files %v, %v", format, files, err)
+	}
+	if want := filepath.Join(dir, "example_com", cs.pageFileName(page.URL)+formatExtensions[format]); files[0] != want {
+		t.Fatalf("%s: saved %s, want %s", format, files[0], want)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSaveHTMLAndRaw(t *testing.T) {
+	data := string(saveAs(t, FormatHTML, savedPage()))
+	if !strings.HasPrefix(data, "<!--\nCRAWLED PAGE METADATA") || !strings.HasSuffix(data, "\n\n"+formatPage) {
+		t.Fatalf("html file = %q", data)
+	}
+	if data := string(saveAs(t, FormatRaw, savedPage())); data != formatPage {
+		t.Fatalf("raw file = %q", data)
+	}
+}
+
+func TestSaveJSON(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(saveAs(t, FormatJSON, savedPage()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["url"] != "https://www.example.com/docs/page" || doc["content"] != formatPage || doc["status_code"] != float64(200) ||
+		doc["size"] != float64(len(formatPage)) || doc["metadata"].(map[string]interface{})["description"] != "A page" {
+		t.Fatalf("json file = %v", doc)
+	}
+}
+
+func TestSaveMHTML(t *testing.T) {
+	page := savedPage()
+	image := bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 40)
+	page.Resources = []Resource{
+		{URL: "https://www.example.com/style.css", ContentType: "text/css", Data: []byte("body{color:red}")},
+		{URL: "https://www.example.com/logo.png", ContentType: "image/png", Data: image},
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(saveAs(t, FormatMHTML, page)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Café" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if msg.Header.Get("Snapshot-Content-Location") != page.URL {
+		t.Errorf("Snapshot-Content-Location = %q", msg.Header.Get("Snapshot-Content-Location"))
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" || params["type"] != "text/html" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	// The multipart reader undoes quoted-printable; base64 is left to us
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct {
+		location string
+		data     []byte
+	}{
+		{page.URL, []byte(formatPage)},
+		{page.Resources[0].URL, page.Resources[0].Data},
+		{page.Resources[1].URL, image},
+	}
+	for _, w := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\r\n") {
+				if len(line) > 76 {
+					t.Errorf("base64 line of %d characters", len(line))
+				}
+			}
+			data, err = io.ReadAll(base64Reader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if part.Header.Get("Content-Location") != w.location || !bytes.Equal(data, w.data) {
+			t.Errorf("part %s = %q", part.Header.Get("Content-Location"), data)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("extra part: %v", err)
+	}
+}
+
+func TestSaveWARC(t *testing.T) {
+	// Rebuilt from the decoded page
+	records := readWARC(t, saveAs(t, FormatWARC, savedPage()))
+	if len(records) != 1 || records[0].Get("WARC-Type") != "response" {
+		t.Fatalf("records = %v", records)
+	}
+	block := records[0].Get("block")
+	if !strings.HasPrefix(block, "HTTP/1.1 200 OK\r\n") || strings.Contains(block, "Content-Encoding") ||
+		!strings.Contains(block, "Content-Length: "+strconv.Itoa(len(formatPage))) || !strings.HasSuffix(block, formatPage) {
+		t.Fatalf("response block = %q", block)
+	}
+
+	// Exact exchange as request and response records
+	page := savedPage()
+	page.RawRequest = []byte("GET /docs/page HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+	page.RawResponse = []byte("HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\n\r\n\x1f\x8b")
+	records = readWARC(t, saveAs(t, FormatWARC, page))
+	if len(records) != 2 || records[0].Get("WARC-Type") != "request" || records[1].Get("WARC-Type") != "response" {
+		t.Fatalf("records = %v", records)
+	}
+	if records[0].Get("WARC-Concurrent-To") != records[1].Get("WARC-Record-ID") {
+		t.Error("request record doesn't point to its response")
+	}
+	if records[1].Get("block") != string(page.RawResponse) || records[1].Get("WARC-Target-URI") != page.URL ||
+		records[1].Get("WARC-Date") != "2024-03-01T12:00:00Z" {
+		t.Fatalf("response record = %v", records[1])
+	}
+}
+
+// readWARC parses WARC records, keeping each block under the key "block"
+func readWARC(t *testing.T, data []byte) []textproto.MIMEHeader {
+	t.Helper()
+	r := bufio.NewReader(bytes.NewReader(data))
+	var records []textproto.MIMEHeader
+	for {
+		version, err := r.ReadString('\n')
+		if err == io.EOF {
+			return records
+		}
+		if version != "WARC/1.1\r\n" {
+			t.Fatalf("record starts with %q", version)
+		}
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(header.Get("Content-Length"))
+		block := make([]byte, n+4)
+		if _, err := io.ReadFull(r, block); err != nil || string(block[n:]) != "\r\n\r\n" {
+			t.Fatalf("record block %q, %v", block, err)
+		}
+		header.Set("block", string(block[:n]))
+		records = append(records, header)
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	cs := NewContentSaver(t.TempDir(), true, 0)
+	if cs.Format() != FormatHTML {
+		t.Fatalf("default format %q", cs.Format())
+	}
+	if err := cs.SetFormat("pdf"); err == nil || cs.Format() != FormatHTML {
+		t.Fatalf("SetFormat(pdf) = %v, format %q", err, cs.Format())
+	}
+}
+
+func TestExtractEmbeddedResources(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/page")
+	content := `<link rel="stylesheet" href="/s.css"><link rel="canonical" href="/c"><link rel="icon" href="fav.ico">
+		<script src="app.js"></script><script>inline()</script><img src="/s.css"><img src="data:image/png;base64,AA==">
+		<img src="https://cdn.com/i.png#x"><a href="/doc.pdf">doc</a>`
+	got := strings.Join(ExtractEmbeddedResources(content, base), " ")
+	want := "https://example.com/s.css https://example.com/a/fav.ico https://example.com/a/app.js https://cdn.com/i.png"
+	if got != want {
+		t.Fatalf("ExtractEmbeddedResources() = %s, want %s", got, want)
+	}
+}
+
+// base64Reader decodes base64 split into lines
+func base64Reader(data []byte) io.Reader {
+	return base64.NewDecoder(base64.StdEncoding, bytes.NewReader(bytes.ReplaceAll(data, []byte("\r\n"), nil)))
+}
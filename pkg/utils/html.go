@@ -1,50 +1,147 @@
 package utils
 
 import (
+	"bytes"
 	"net/url"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
 )
 
-// ExtractLinks extracts all links from HTML content
-func ExtractLinks(content string) []string {
-	doc, err := html.Parse(strings.NewReader(content))
-	if err != nil {
-		return nil
+// Link is a hyperlink found in a page together with its metadata
+type Link struct {
+	Href    string   // Raw href attribute value
+	Text    string   // Anchor text with whitespace collapsed (alt text for <area>)
+	Rel     []string // Lowercased rel attribute values
+	Section string   // Page section: nav, header, footer, aside, main, or body
+	Element string   // Source element: a or area
+}
+
+// HasRel reports whether the link carries the given rel value
+func (l Link) HasRel(rel string) bool {
+	for _, r := range l.Rel {
+		if r == rel {
+			return true
+		}
 	}
+	return false
+}
 
-	var links []string
+// Link sections describing where in the page a link was found
+const (
+	SectionNav    = "nav"
+	SectionHeader = "header"
+	SectionFooter = "footer"
+	SectionAside  = "aside"
+	SectionMain   = "main"
+	SectionBody   = "body"
+)
+
+// ExtractLinkDetails extracts all links from HTML content with their anchor
+// text, rel attributes, the page section they appear in, and the source
+// element. ParseHTML gets them along with the title and head metadata.
+func ExtractLinkDetails(content string) []Link {
+	return ParseHTML(content, nil).Links
+}
+
+// nodeText returns the collapsed text content of a node and its descendants
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
 	var f func(*html.Node)
 	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, a := range n.Attr {
-				if a.Key == "href" {
-					links = append(links, a.Val)
-					break
-				}
-			}
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
 			f(c)
 		}
 	}
-	f(doc)
-	return links
+	f(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// FilterLinksByRel drops links carrying any of the given rel values (e.g. nofollow, ugc, sponsored)
+func FilterLinksByRel(links []Link, skipRels []string) []Link {
+	if len(skipRels) == 0 {
+		return links
+	}
+
+	filtered := links[:0:0]
+	for _, link := range links {
+		skip := false
+		for _, rel := range skipRels {
+			if link.HasRel(strings.ToLower(rel)) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			filtered = append(filtered, link)
+		}
+	}
+	return filtered
+}
+
+// DecodeHTML transcodes an HTML body to UTF-8. The charset comes from a byte
+// order mark, the Content-Type header, or a <meta charset> in the first 1024
+// bytes, in that order. Without one, the body is taken as UTF-8 if valid and
+// windows-1252 otherwise. It returns the content and the charset name.
+func DecodeHTML(body []byte, contentType string) (string, string) {
+	return decodeHTML(body, contentType, false)
+}
+
+// DecodeHTMLShared is DecodeHTML without copying UTF-8 content, which shares
+// the memory of body. body must not be modified while the content is in use.
+func DecodeHTMLShared(body []byte, contentType string) (string, string) {
+	return decodeHTML(body, contentType, true)
+}
+
+func decodeHTML(body []byte, contentType string, shared bool) (string, string) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && name == "windows-1252" && utf8.Valid(body) {
+		// DetermineEncoding only looks at the start and guesses windows-1252 for ASCII
+		name = "utf-8"
+	}
+	if name == "utf-8" {
+		body = bytes.TrimPrefix(body, []byte("\uFEFF"))
+	} else if decoded, err := enc.NewDecoder().Bytes(body); err == nil {
+		// The decoder's output isn't held by anything else
+		return BytesString(decoded), name
+	}
+	if shared {
+		return BytesString(body), name
+	}
+	return string(body), name
 }
 
 // ExtractTitle extracts the title from HTML content
 func ExtractTitle(content string) string {
+	return ParseHTML(content, nil).Title
+}
+
+// Heading is an h1 to h6 element of a page
+type Heading struct {
+	Level int // 1 to 6
+	Text  string
+}
+
+// ExtractHeadings returns the headings of HTML content in document order
+func ExtractHeadings(content string) []Heading {
 	doc, err := html.Parse(strings.NewReader(content))
 	if err != nil {
-		return ""
+		return nil
 	}
 
-	var title string
+	var headings []Heading
 	var f func(*html.Node)
 	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil {
-			title = n.FirstChild.Data
+		if n.Type == html.ElementNode && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6' {
+			headings = append(headings, Heading{Level: int(n.Data[1] - '0'), Text: nodeText(n)})
 			return
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
@@ -52,7 +149,57 @@ func ExtractTitle(content string) string {
 		}
 	}
 	f(doc)
-	return title
+	return headings
+}
+
+// ExtractCanonical returns the absolute URL of the page's <link rel="canonical">, or "" if absent
+func ExtractCanonical(content string, base *url.URL) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch tag := atom.Lookup(name); tag {
+			case atom.Body:
+				return ""
+			case atom.Link:
+				if canonical := canonicalURL(readAttrs(tokenizer, tag, hasAttr), base); canonical != "" {
+					return canonical
+				}
+			}
+		}
+	}
+}
+
+// metadataURLs are metadata keys holding URLs, resolved against the page
+var metadataURLs = map[string]bool{
+	"og:url": true, "og:image": true, "og:image:url": true, "og:image:secure_url": true,
+	"og:video": true, "og:audio": true, "twitter:image": true, "twitter:image:src": true,
+}
+
+// ExtractMetadata returns the OpenGraph (og:*) and Twitter card (twitter:*)
+// properties and the meta description and keywords of the page head, keyed by
+// lowercased property name. Repeated properties keep their first value.
+func ExtractMetadata(content string, base *url.URL) map[string]string {
+	metadata := make(map[string]string)
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return metadata
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch tag := atom.Lookup(name); tag {
+			case atom.Body:
+				return metadata
+			case atom.Meta:
+				a := readAttrs(tokenizer, tag, hasAttr)
+				addMetadata(metadata, a.name, a.content, base)
+			}
+		}
+	}
 }
 
 // ToAbsoluteURL converts a relative URL to an absolute URL
@@ -79,3 +226,233 @@ func ToAbsoluteURL(base *url.URL, href string) string {
 	absoluteURL := base.ResolveReference(relativeURL)
 	return absoluteURL.String()
 }
+
+// ExtractText extracts the visible text from HTML content, skipping scripts and styles
+func ExtractText(content string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+
+	var sb strings.Builder
+	skipDepth := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(sb.String())
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "template":
+				skipDepth++
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "noscript", "template":
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			}
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			text := strings.TrimSpace(string(tokenizer.Text()))
+			if text == "" {
+				continue
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(strings.Join(strings.Fields(text), " "))
+		}
+	}
+}
+
+// blockElements start a new line in CleanText
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true,
+	"nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true,
+}
+
+// hiddenElements hold no visible text
+var hiddenElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+// CleanText extracts the visible body text of HTML content as plain text,
+// one line per block element with whitespace collapsed
+func CleanText(content string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+
+	var lines []string
+	var line strings.Builder
+	space := false // Whitespace between the last text and the next one
+	endLine := func() {
+		if line.Len() > 0 {
+			lines = append(lines, line.String())
+			line.Reset()
+		}
+		space = false
+	}
+
+	skipDepth := 0
+	for {
+		tt := tokenizer.Next()
+		switch tt {
+		case html.ErrorToken:
+			endLine()
+			return strings.Join(lines, "\n")
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if hiddenElements[string(name)] {
+				// A self-closing <svg/> has no end tag to leave it
+				if tt == html.StartTagToken {
+					skipDepth++
+				}
+			} else if blockElements[string(name)] {
+				endLine()
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if hiddenElements[string(name)] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			} else if blockElements[string(name)] {
+				endLine()
+			}
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			text := string(tokenizer.Text())
+			words := strings.Fields(text)
+			if len(words) == 0 {
+				space = space || text != ""
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(text)
+			if line.Len() > 0 && (space || unicode.IsSpace(first)) {
+				line.WriteByte(' ')
+			}
+			line.WriteString(strings.Join(words, " "))
+			last, _ := utf8.DecodeLastRuneInString(text)
+			space = unicode.IsSpace(last)
+		}
+	}
+}
+
+// documentExtensions are linked files treated as downloadable assets rather than pages
+var documentExtensions = []string{".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx", ".odt", ".csv"}
+
+// ExtractEmbeddedResources returns the absolute URLs of the stylesheets,
+// scripts, images and icons a page needs to render, without duplicates
+func ExtractEmbeddedResources(content string, base *url.URL) []string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	seen := make(map[string]bool)
+	var resources []string
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return resources
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			var ref string
+			switch token.Data {
+			case "img", "script":
+				ref = attr(token, "src")
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+					if rel == "stylesheet" || rel == "icon" {
+						ref = attr(token, "href")
+						break
+					}
+				}
+			}
+			abs := ToAbsoluteURL(base, strings.TrimSpace(ref))
+			// Inline data: URIs are already part of the page
+			if ref != "" && strings.HasPrefix(abs, "http") && !seen[abs] {
+				seen[abs] = true
+				resources = append(resources, abs)
+			}
+		}
+	}
+}
+
+// attr returns the value of an attribute of token, empty if it's missing
+func attr(token html.Token, key string) string {
+	for _, a := range token.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// ExtractAssetLinks returns the absolute URLs of images, media, icons, and
+// linked documents referenced by the page, without duplicates
+func ExtractAssetLinks(content string, base *url.URL) []string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	seen := make(map[string]bool)
+	var assets []string
+
+	add := func(href string) {
+		abs := ToAbsoluteURL(base, strings.TrimSpace(href))
+		if abs == "" || seen[abs] {
+			return
+		}
+		seen[abs] = true
+		assets = append(assets, abs)
+	}
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return assets
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := make(map[string]string, len(token.Attr))
+			for _, a := range token.Attr {
+				attrs[a.Key] = a.Val
+			}
+
+			switch token.Data {
+			case "img", "source", "video", "audio", "embed":
+				if src := attrs["src"]; src != "" {
+					add(src)
+				}
+				if poster := attrs["poster"]; poster != "" {
+					add(poster)
+				}
+				// srcset lists "url descriptor" candidates separated by commas
+				for _, candidate := range strings.Split(attrs["srcset"], ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						add(fields[0])
+					}
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if rel == "icon" || rel == "apple-touch-icon" {
+						add(attrs["href"])
+						break
+					}
+				}
+			case "a":
+				href := attrs["href"]
+				if u, err := url.Parse(href); err == nil {
+					lower := strings.ToLower(u.Path)
+					for _, ext := range documentExtensions {
+						if strings.HasSuffix(lower, ext) {
+							add(href)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestDecodeHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+		charset     string
+	}{
+		{"utf-8", []byte("<p>café</p>"), "text/html", "<p>café</p>", "utf-8"},
+		{"bom", []byte("\xef\xbb\xbf<p>café</p>"), "text/html; charset=iso-8859-1", "<p>café</p>", "utf-8"},
+		{"header", []byte("<p>caf\xe9</p>"), "text/html; charset=ISO-8859-1", "<p>café</p>", "windows-1252"},
+		{"meta", []byte(`<meta charset="shift_jis"><p>` + "\x93\xfa\x96\x7b" + `</p>`), "text/html", `<meta charset="shift_jis"><p>日本</p>`, "shift_jis"},
+		{"invalid utf-8", []byte("<p>caf\xe9</p>"), "text/html", "<p>café</p>", "windows-1252"},
+	}
+	for _, tt := range tests {
+		got, charset := DecodeHTML(tt.body, tt.contentType)
+		if got != tt.want || charset != tt.charset {
+			t.Errorf("%s: DecodeHTML() = %q, %q, want %q, %q", tt.name, got, charset, tt.want, tt.charset)
+		}
+	}
+}
+
+func TestDecodeHTMLShared(t *testing.T) {
+	body := []byte("<p>cafe</p>")
+	copied, _ := DecodeHTML(body, "text/html")
+	shared, _ := DecodeHTMLShared(body, "text/html")
+	if copied != "<p>cafe</p>" || shared != copied {
+		t.Fatalf("DecodeHTML() = %q, DecodeHTMLShared() = %q", copied, shared)
+	}
+	body[3] = 'C'
+	if copied != "<p>cafe</p>" {
+		t.Fatalf("DecodeHTML() content changed with its body: %q", copied)
+	}
+	if shared != "<p>Cafe</p>" {
+		t.Fatalf("DecodeHTMLShared() = %q, want the memory of the body", shared)
+	}
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// manifestFile lists the saved pages in the content directory
+const manifestFile = "manifest.json"
+
+// manifestFlushEvery is how many saves the manifest is rewritten after
+const manifestFlushEvery = 100
+
+// ManifestEntry describes a saved page file
+type ManifestEntry struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	Format      string    `json:"format"`
+	Size        int       `json:"size"` // Bytes of the file
+	CrawledAt   time.Time `json:"crawled_at"`
+	Archive     string    `json:"archive,omitempty"` // Archive holding the file, if any
+}
+
+// manifest maps saved files, relative to the content directory, to the
+// pages they hold
+type manifest struct {
+	mu         sync.Mutex
+	path       string
+	loaded     bool
+	files      map[string]ManifestEntry
+	urls       map[string]string // URL -> file
+	dirty      int               // Saves since the last write
+	collisions int64
+}
+
+// LoadManifest reads the manifest of a content directory, keyed by file path
+// relative to the directory. A missing manifest is empty.
+func LoadManifest(baseDir string) (map[string]ManifestEntry, error) {
+	files := make(map[string]ManifestEntry)
+	data, err := os.ReadFile(filepath.Join(baseDir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse content manifest: %w", err)
+	}
+	return files, nil
+}
+
+// load reads the manifest left by an earlier crawl on first use. m.mu must
+// be held.
+func (m *manifest) load() error {
+	if m.loaded {
+		return nil
+	}
+	files, err := LoadManifest(filepath.Dir(m.path))
+	if err != nil {
+		return err
+	}
+	m.files = files
+	m.urls = make(map[string]string, len(files))
+	for file, entry := range files {
+		m.urls[entry.URL] = file
+	}
+	m.loaded = true
+	return nil
+}
+
+// claim returns the file for pageURL, based on name and ext. A file already
+// holding another URL is a collision and gets a numbered name instead.
+func (m *manifest) claim(pageURL, name, ext string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return "", err
+	}
+	if file, ok := m.urls[pageURL]; ok && filepath.Ext(file) == ext {
+		return file, nil
+	}
+	file := name + ext
+	for n := 2; ; n++ {
+		entry, taken := m.files[file]
+		if !taken {
+			// Reserved until the page is recorded, so concurrent saves can't share it
+			m.files[file] = ManifestEntry{URL: pageURL}
+			break
+		}
+		if entry.URL == pageURL {
+			break
+		}
+		m.collisions++
+		file = fmt.Sprintf("%s_%d%s", name, n, ext)
+	}
+	m.urls[pageURL] = file
+	return file, nil
+}
+
+// record stores the entry of a saved file and rewrites the manifest every
+// manifestFlushEvery saves
+func (m *manifest) record(file string, entry ManifestEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[file] = entry
+	m.dirty++
+	if m.dirty < manifestFlushEvery {
+		return nil
+	}
+	return m.write()
+}
+
+// release drops the reservation of a file whose page couldn't be written
+func (m *manifest) release(file string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry := m.files[file]; entry.CrawledAt.IsZero() {
+		delete(m.files, file)
+		delete(m.urls, entry.URL)
+	}
+}
+
+// remove drops a deleted file
+func (m *manifest) remove(file string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return
+	}
+	if entry, ok := m.files[file]; ok {
+		delete(m.files, file)
+		if m.urls[entry.URL] == file {
+			delete(m.urls, entry.URL)
+		}
+		m.dirty++
+	}
+}
+
+// urlOf returns the URL saved in file, empty if it isn't in the manifest
+func (m *manifest) urlOf(file string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return ""
+	}
+	return m.files[file].URL
+}
+
+// stats returns the number of files in the manifest and the collisions
+// resolved by renaming
+func (m *manifest) stats() (int, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return 0, m.collisions
+	}
+	return len(m.files), m.collisions
+}
+
+// lookup returns the file saved for pageURL
+func (m *manifest) lookup(pageURL string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return "", false
+	}
+	file, ok := m.urls[pageURL]
+	return file, ok
+}
+
+// flush writes the manifest if it changed since the last write
+func (m *manifest) flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dirty == 0 {
+		return nil
+	}
+	return m.write()
+}
+
+// write replaces the manifest file atomically. m.mu must be held.
+func (m *manifest) write() error {
+	data, err := json.MarshalIndent(m.files, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write content manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to write content manifest: %w", err)
+	}
+	m.dirty = 0
+	return nil
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageFileName(t *testing.T) {
+	cs := NewContentSaver(t.TempDir(), true, 0)
+	// Both sanitize to docs_a_b
+	a, b := cs.pageFileName("https://example.com/docs/a?b"), cs.pageFileName("https://example.com/docs/a_b")
+	if a == b || !strings.HasPrefix(a, "docs_a_b_") || !strings.HasPrefix(b, "docs_a_b_") || len(a) != len("docs_a_b_")+12 {
+		t.Fatalf("pageFileName() = %s, %s", a, b)
+	}
+}
+
+func TestManifestCollisions(t *testing.T) {
+	m := &manifest{path: filepath.Join(t.TempDir(), manifestFile)}
+	first, err := m.claim("https://a.com/x", "a_com/x_1234", ".html")
+	if err != nil || first != "a_com/x_1234.html" {
+		t.Fatalf("claim() = %s, %v", first, err)
+	}
+	// Another URL with the same name is renamed, the same URL keeps its file
+	second, _ := m.claim("https://a.com/y", "a_com/x_1234", ".html")
+	again, _ := m.claim("https://a.com/x", "a_com/x_1234", ".html")
+	if second != "a_com/x_1234_2.html" || again != first || m.collisions != 1 {
+		t.Fatalf("claims %s, %s, collisions %d", second, again, m.collisions)
+	}
+
+	// A failed write frees the reservation
+	m.release(second)
+	if _, ok := m.lookup("https://a.com/y"); ok {
+		t.Fatal("released file still in the manifest")
+	}
+}
+
+func TestSavePageManifest(t *testing.T) {
+	dir := t.TempDir()
+	cs := NewContentSaver(dir, true, 0)
+	page := savedPage()
+	if err := cs.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	file, ok := cs.Lookup(page.URL)
+	if !ok || !strings.HasPrefix(file, "example_com/docs_page_") {
+		t.Fatalf("Lookup() = %s, %v", file, ok)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The manifest is written on flush and read back by a new saver
+	if err := cs.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := files[file]
+	if len(files) != 1 || entry.URL != page.URL || entry.Title != "Café" || entry.Format != FormatHTML ||
+		entry.StatusCode != 200 || entry.Size == 0 || !entry.CrawledAt.Equal(page.CrawledAt) {
+		t.Fatalf("manifest = %+v", files)
+	}
+
+	next := NewContentSaver(dir, true, 0)
+	if got, ok := next.Lookup(page.URL); !ok || got != file {
+		t.Fatalf("Lookup() after reload = %s, %v", got, ok)
+	}
+	page.CrawledAt = page.CrawledAt.Add(time.Hour)
+	if err := next.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := next.GetSavedFiles(); len(saved) != 1 {
+		t.Fatalf("recrawl saved %v", saved)
+	}
+}
@@ -0,0 +1,335 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// mirrorAttrs are the attributes rewritten in mirrored pages, by element:
+// true for links to pages, false for resources the page needs
+var mirrorAttrs = map[string]map[string]bool{
+	"a":      {"href": true},
+	"area":   {"href": true},
+	"iframe": {"src": true},
+	"frame":  {"src": true},
+	"img":    {"src": false, "srcset": false},
+	"source": {"src": false, "srcset": false},
+	"script": {"src": false},
+	"video":  {"src": false, "poster": false},
+	"audio":  {"src": false},
+	"track":  {"src": false},
+	"embed":  {"src": false},
+	"input":  {"src": false},
+}
+
+// mirrorLinkRels are the <link> relations whose target is mirrored
+var mirrorLinkRels = map[string]bool{"stylesheet": true, "icon": true, "apple-touch-icon": true}
+
+// cssURL matches url() references in stylesheets, quoted or not
+var cssURL = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]*))\s*\)`)
+
+// cssImport matches @import rules with a plain string
+var cssImport = regexp.MustCompile(`@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// unsafePathChars can't appear in file names on every filesystem
+var unsafePathChars = strings.NewReplacer(`\`, "_", ":", "_", "*", "_", "?", "_", `"`, "_", "<", "_", ">", "_", "|", "_")
+
+// SetMirror also writes every page and the stylesheets, scripts and images it
+// uses to dir, inside the output directory, as a copy of the site that can be
+// browsed offline. Links to pages that local accepts point into the copy,
+// other links are made absolute.
+func (cs *ContentSaver) SetMirror(dir string, local func(pageURL string) bool) {
+	cs.mirrorDir = filepath.Join(cs.baseDir, dir)
+	cs.mirrorLocal = local
+	cs.mirrored = make(map[string]bool)
+}
+
+// Mirroring reports whether pages are mirrored
+func (cs *ContentSaver) Mirroring() bool {
+	return cs.enabled && cs.mirrorDir != ""
+}
+
+// MirrorPage writes a page to the mirror with its links rewritten to
+// relative paths. It returns the resources the page references that no
+// earlier page claimed, for the caller to download with MirrorResource.
+func (cs *ContentSaver) MirrorPage(pageURL, content string) ([]string, error) {
+	if !cs.Mirroring() {
+		return nil, nil
+	}
+	if cs.maxFileSize > 0 && int64(len(content)) > cs.maxFileSize {
+		return nil, nil
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	file := mirrorPath(base, true)
+	rewritten, refs := cs.rewriteHTML(content, base, file)
+	if err := cs.writeMirror(file, []byte(rewritten)); err != nil {
+		return nil, err
+	}
+	return cs.claimResources(refs), nil
+}
+
+// MirrorResource writes a downloaded resource to the mirror. Stylesheets get
+// their url() and @import references rewritten too; the ones no earlier page
+// claimed are returned.
+func (cs *ContentSaver) MirrorResource(resourceURL, contentType string, data []byte) ([]string, error) {
+	if !cs.Mirroring() {
+		return nil, nil
+	}
+	u, err := url.Parse(resourceURL)
+	if err != nil {
+		return nil, err
+	}
+	file := mirrorPath(u, false)
+	var refs []string
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "text/css" || path.Ext(u.Path) == ".css" {
+		var css string
+		css, refs = cs.rewriteCSS(string(data), u, file)
+		data = []byte(css)
+	}
+	if err := cs.writeMirror(file, data); err != nil {
+		return nil, err
+	}
+	return cs.claimResources(refs), nil
+}
+
+// mirrorPath returns the file of a URL in the mirror, host first, the way
+// wget lays out a mirror: directories get index.html, pages without an .html
+// extension get one, and query strings become a hash in the name
+func mirrorPath(u *url.URL, page bool) string {
+	p := path.Clean("/" + u.Path)
+	if strings.HasSuffix(u.Path, "/") || p == "/" {
+		index := "index"
+		if page {
+			index = "index.html"
+		}
+		p = path.Join(p, index)
+	}
+	p = unsafePathChars.Replace(p)
+
+	ext := path.Ext(p)
+	name := strings.TrimSuffix(p, ext)
+	if u.RawQuery != "" {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+	if page && ext != ".html" && ext != ".htm" {
+		ext += ".html"
+	}
+	return unsafePathChars.Replace(strings.ToLower(u.Host)) + name + ext
+}
+
+// relativeLink returns the link from the mirror file from to the file to
+func relativeLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).String()
+}
+
+// mirrorLink rewrites a reference found in the mirror file of base. It
+// returns the new reference and, for resources, the URL to download.
+func (cs *ContentSaver) mirrorLink(base *url.URL, file, ref string, page bool) (string, string) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ref, ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ref, ""
+	}
+	fragment := u.EscapedFragment()
+	u.Fragment, u.RawFragment = "", ""
+	if page && !cs.mirrorLocal(u.String()) {
+		// Pages outside the crawl stay online
+		if fragment != "" {
+			return u.String() + "#" + fragment, ""
+		}
+		return u.String(), ""
+	}
+
+	link := relativeLink(file, mirrorPath(u, page))
+	if fragment != "" {
+		link += "#" + fragment
+	}
+	if page {
+		return link, ""
+	}
+	return link, u.String()
+}
+
+// rewriteHTML rewrites the links, resources, inline styles and srcset
+// candidates of a page for the mirror. A <base> element is dropped since the
+// links no longer depend on it. It returns the page and the resource URLs.
+func (cs *ContentSaver) rewriteHTML(content string, base *url.URL, file string) (string, []string) {
+	var out strings.Builder
+	var refs []string
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	inStyle := false
+
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return out.String(), refs
+		}
+		// Token lowercases the tag name in place
+		raw := string(tokenizer.Raw())
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data == "base" {
+				if href := attr(token, "href"); href != "" {
+					if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+						base = u
+					}
+				}
+				continue
+			}
+			if token.Data == "style" && tt == html.StartTagToken {
+				inStyle = true
+			}
+			if cs.rewriteTag(&token, base, file, &refs) {
+				raw = token.String()
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "style" {
+				inStyle = false
+			}
+		case html.TextToken:
+			if inStyle {
+				var found []string
+				raw, found = cs.rewriteCSS(raw, base, file)
+				refs = append(refs, found...)
+			}
+		}
+		out.WriteString(raw)
+	}
+}
+
+// rewriteTag rewrites the attributes of one element and reports whether any
+// changed
+func (cs *ContentSaver) rewriteTag(token *html.Token, base *url.URL, file string, refs *[]string) bool {
+	attrs := mirrorAttrs[token.Data]
+	if token.Data == "link" {
+		for _, rel := range strings.Fields(strings.ToLower(attr(*token, "rel"))) {
+			if mirrorLinkRels[rel] {
+				attrs = map[string]bool{"href": false}
+				break
+			}
+		}
+	}
+
+	changed, resource := false, false
+	kept := token.Attr[:0]
+	for _, a := range token.Attr {
+		page, ok := attrs[a.Key]
+		switch {
+		case a.Key == "style":
+			var found []string
+			a.Val, found = cs.rewriteCSS(a.Val, base, file)
+			*refs = append(*refs, found...)
+		case ok && a.Key == "srcset":
+			a.Val = cs.rewriteSrcset(a.Val, base, file, refs)
+			resource = true
+		case ok:
+			var ref string
+			a.Val, ref = cs.mirrorLink(base, file, a.Val, page)
+			if ref != "" {
+				*refs = append(*refs, ref)
+				resource = true
+			}
+		}
+		kept = append(kept, a)
+		changed = changed || ok || a.Key == "style"
+	}
+	token.Attr = kept
+
+	if resource {
+		// Subresource integrity fails on rewritten stylesheets and local files
+		kept = token.Attr[:0]
+		for _, a := range token.Attr {
+			if a.Key != "integrity" && a.Key != "crossorigin" {
+				kept = append(kept, a)
+			}
+		}
+		token.Attr = kept
+	}
+	return changed
+}
+
+// rewriteSrcset rewrites the URLs of "url descriptor" candidates
+func (cs *ContentSaver) rewriteSrcset(srcset string, base *url.URL, file string, refs *[]string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		link, ref := cs.mirrorLink(base, file, fields[0], false)
+		if ref != "" {
+			*refs = append(*refs, ref)
+		}
+		fields[0] = link
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// rewriteCSS rewrites the url() and @import references of a stylesheet
+// whose mirror file is file, returning it and the resource URLs
+func (cs *ContentSaver) rewriteCSS(css string, base *url.URL, file string) (string, []string) {
+	var refs []string
+	rewrite := func(re *regexp.Regexp, format func(string) string) {
+		css = re.ReplaceAllStringFunc(css, func(match string) string {
+			groups := re.FindStringSubmatch(match)
+			value := strings.Join(groups[1:], "")
+			link, ref := cs.mirrorLink(base, file, value, false)
+			if ref == "" {
+				return match
+			}
+			refs = append(refs, ref)
+			return format(link)
+		})
+	}
+	rewrite(cssURL, func(link string) string { return `url("` + link + `")` })
+	rewrite(cssImport, func(link string) string { return `@import "` + link + `"` })
+	return css, refs
+}
+
+// claimResources returns the resources in refs not claimed before, once
+// each, and claims them
+func (cs *ContentSaver) claimResources(refs []string) []string {
+	cs.mirroredMu.Lock()
+	defer cs.mirroredMu.Unlock()
+
+	var claimed []string
+	for _, ref := range refs {
+		if !cs.mirrored[ref] {
+			cs.mirrored[ref] = true
+			claimed = append(claimed, ref)
+		}
+	}
+	return claimed
+}
+
+// writeMirror writes a file of the mirror
+func (cs *ContentSaver) writeMirror(file string, data []byte) error {
+	target := filepath.Join(cs.mirrorDir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, data, 0644)
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMirrorPath(t *testing.T) {
+	tests := []struct {
+		url  string
+		page bool
+		want string
+	}{
+		{"https://a.com", true, "a.com/index.html"},
+		{"https://a.com/blog/", true, "a.com/blog/index.html"},
+		{"https://a.com/about", true, "a.com/about.html"},
+		{"https://a.com/page.php", true, "a.com/page.php.html"},
+		{"https://a.com/doc.htm", true, "a.com/doc.htm"},
+		{"https://a.com/search?q=go", true, "a.com/search_" + mirrorQueryHash("q=go") + ".html"},
+		{"https://A.com:8080/../x.html", true, "a.com_8080/x.html"},
+		{"https://a.com/css/site.css?v=2", false, "a.com/css/site_" + mirrorQueryHash("v=2") + ".css"},
+		{"https://a.com/img/logo", false, "a.com/img/logo"},
+		{"https://a.com/img/", false, "a.com/img/index"},
+		{"https://a.com/a:b*c.png", false, "a.com/a_b_c.png"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := mirrorPath(u, tt.page); got != tt.want {
+			t.Errorf("mirrorPath(%q, %v) = %q, want %q", tt.url, tt.page, got, tt.want)
+		}
+	}
+}
+
+// mirrorQueryHash is the name suffix of a query string in the mirror
+func mirrorQueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:4])
+}
+
+func mirrorSaver(t *testing.T) (*ContentSaver, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cs := NewContentSaver(dir, true, 0)
+	cs.SetMirror("mirror", func(pageURL string) bool {
+		u, _ := url.Parse(pageURL)
+		return u.Host == "a.com"
+	})
+	return cs, filepath.Join(dir, "mirror")
+}
+
+func TestMirrorPage(t *testing.T) {
+	cs, dir := mirrorSaver(t)
+	page := `<html><head>
+<base href="https://a.com/blog/">
+<link rel="stylesheet" href="/css/site.css" integrity="sha384-x" crossorigin="anonymous">
+<link rel="canonical" href="https://a.com/blog/post">
+<style>@import "theme.css"; body { background: url('/img/bg.png') }</style>
+</head><body>
+<a href="/about">About</a> <a href="https://b.com/x#y">Out</a> <a href="#top">Top</a> <a href="mailto:x@a.com">Mail</a>
+<a href="/blog/next#part">Next</a>
+<img src="../img/logo.png" srcset="/img/a.png 1x, /img/b.png 2x">
+<div style="background: url(/img/div.png)"></div>
+<img src="data:image/png;base64,AAAA">
+<script src="https://cdn.com/lib.js"></script>
+</body></html>`
+
+	refs, err := cs.MirrorPage("https://a.com/blog/post", page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRefs := []string{
+		"https://a.com/css/site.css", "https://a.com/img/bg.png", "https://a.com/blog/theme.css",
+		"https://a.com/img/logo.png", "https://a.com/img/a.png", "https://a.com/img/b.png",
+		"https://a.com/img/div.png", "https://cdn.com/lib.js",
+	}
+	if !reflect.DeepEqual(refs, wantRefs) {
+		t.Fatalf("MirrorPage() refs =\n%v\nwant\n%v", refs, wantRefs)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.com", "blog", "post.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := string(data)
+	for _, want := range []string{
+		`<link rel="stylesheet" href="../css/site.css">`,
+		`<link rel="canonical" href="https://a.com/blog/post">`,
+		`@import "theme.css"; body { background: url("../img/bg.png") }`,
+		`<a href="../about.html">About</a>`,
+		`<a href="https://b.com/x#y">Out</a>`,
+		`<a href="#top">Top</a>`,
+		`<a href="mailto:x@a.com">Mail</a>`,
+		`<a href="next.html#part">Next</a>`,
+		`<img src="../img/logo.png" srcset="../img/a.png 1x, ../img/b.png 2x">`,
+		`<div style="background: url(&#34;../img/div.png&#34;)">`,
+		`<img src="data:image/png;base64,AAAA">`,
+		`<script src="../../cdn.com/lib.js"></script>`,
+	} {
+		if !strings.Contains(saved, want) {
+			t.Errorf("mirrored page lacks %s:\n%s", want, saved)
+		}
+	}
+	if strings.Contains(saved, "<base") {
+		t.Error("mirrored page kept its <base> element")
+	}
+
+	// Resources are claimed once across pages
+	refs, err = cs.MirrorPage("https://a.com/", `<img src="/img/logo.png"><img src="/img/new.png">`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(refs, []string{"https://a.com/img/new.png"}) {
+		t.Fatalf("second MirrorPage() refs = %v", refs)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.com", "index.html")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirrorResource(t *testing.T) {
+	cs, dir := mirrorSaver(t)
+	css := `@font-face { src: url(../fonts/f.woff2) format("woff2"), url(data:font/woff;base64,AA) }
+.logo { background: url("https://cdn.com/logo.svg") }`
+
+	refs, err := cs.MirrorResource("https://a.com/css/site.css?v=1", "text/css; charset=utf-8", []byte(css))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://a.com/fonts/f.woff2", "https://cdn.com/logo.svg"}; !reflect.DeepEqual(refs, want) {
+		t.Fatalf("MirrorResource() refs = %v, want %v", refs, want)
+	}
+	u, _ := url.Parse("https://a.com/css/site.css?v=1")
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(mirrorPath(u, false))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`url("../fonts/f.woff2")`, `url(data:font/woff;base64,AA)`, `url("../../cdn.com/logo.svg")`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("mirrored stylesheet lacks %s:\n%s", want, data)
+		}
+	}
+
+	// Other resources are written as they are
+	if _, err := cs.MirrorResource("https://a.com/img/logo.png", "image/png", []byte("png")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.com", "img", "logo.png")); string(data) != "png" {
+		t.Fatalf("mirrored image = %q", data)
+	}
+}
+
+func TestMirrorDisabled(t *testing.T) {
+	cs := NewContentSaver(t.TempDir(), true, 0)
+	if refs, err := cs.MirrorPage("https://a.com/", `<img src="/x.png">`); err != nil || refs != nil || cs.Mirroring() {
+		t.Fatalf("MirrorPage() without SetMirror = %v, %v", refs, err)
+	}
+}
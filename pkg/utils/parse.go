@@ -0,0 +1,297 @@
+package utils
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ParsedPage is what ParseHTML finds in a page
+type ParsedPage struct {
+	Title     string            // Text of the first non-empty <title>
+	Links     []Link            // In document order
+	Canonical string            // URL of the <link rel="canonical"> in the head, empty if absent
+	Metadata  map[string]string // Head metadata, as returned by ExtractMetadata
+}
+
+// voidElements have no end tag, so they never enclose anything
+var voidElements = map[atom.Atom]bool{
+	atom.Area: true, atom.Base: true, atom.Br: true, atom.Col: true, atom.Embed: true,
+	atom.Hr: true, atom.Img: true, atom.Input: true, atom.Link: true, atom.Meta: true,
+	atom.Param: true, atom.Source: true, atom.Track: true, atom.Wbr: true,
+}
+
+// openElement is an element the tokenizer is inside of
+type openElement struct {
+	tag     atom.Atom
+	section string
+}
+
+// tagAttrs are the attributes of a tag that ParseHTML reads
+type tagAttrs struct {
+	href    string
+	hasHref bool
+	rel     string
+	alt     string
+	name    string // Lowercased name or property of a meta tag, whichever comes first
+	content string
+	section string // From the ARIA role
+}
+
+// readAttrs reads the attributes of the current tag. Values are only copied
+// for the tags ParseHTML takes them from.
+func readAttrs(z *html.Tokenizer, tag atom.Atom, hasAttr bool) tagAttrs {
+	var a tagAttrs
+	keep := tag == atom.A || tag == atom.Area || tag == atom.Link || tag == atom.Meta
+	for hasAttr {
+		key, val, more := z.TagAttr()
+		hasAttr = more
+		switch string(key) {
+		case "role":
+			if a.section == "" {
+				a.section = roleSection(val)
+			}
+		case "href":
+			if keep {
+				a.href = string(val)
+				a.hasHref = true
+			}
+		case "rel":
+			if keep {
+				a.rel = string(val)
+			}
+		case "alt":
+			if keep {
+				a.alt = string(val)
+			}
+		case "name", "property":
+			if keep && a.name == "" {
+				a.name = strings.ToLower(strings.TrimSpace(string(val)))
+			}
+		case "content":
+			if keep {
+				a.content = string(val)
+			}
+		}
+	}
+	return a
+}
+
+// roleSection returns the section an ARIA role starts
+func roleSection(role []byte) string {
+	switch {
+	case bytes.EqualFold(role, []byte("navigation")):
+		return SectionNav
+	case bytes.EqualFold(role, []byte("banner")):
+		return SectionHeader
+	case bytes.EqualFold(role, []byte("contentinfo")):
+		return SectionFooter
+	case bytes.EqualFold(role, []byte("complementary")):
+		return SectionAside
+	case bytes.EqualFold(role, []byte("main")):
+		return SectionMain
+	}
+	return ""
+}
+
+// tagSections are the elements that start a section
+var tagSections = map[atom.Atom]string{
+	atom.Nav:     SectionNav,
+	atom.Header:  SectionHeader,
+	atom.Footer:  SectionFooter,
+	atom.Aside:   SectionAside,
+	atom.Main:    SectionMain,
+	atom.Article: SectionMain,
+}
+
+// ParseHTML reads the links, title, canonical URL and metadata of HTML
+// content in a single pass of the tokenizer, without building a DOM.
+// Elements are tracked on a stack of open tags to find link sections, and
+// canonical and meta tags are read up to <body>. The canonical URL and URL
+// metadata are resolved against base, or left as written if base is nil.
+func ParseHTML(content string, base *url.URL) ParsedPage {
+	page := ParsedPage{Metadata: make(map[string]string)}
+	z := html.NewTokenizer(strings.NewReader(content))
+
+	var open []openElement
+	inHead := true
+	inTitle := false
+	anchor, anchorAt := -1, 0 // Link of the open <a> and its place in open
+	var text []byte           // Of the open <a>
+
+	endAnchor := func() {
+		if anchor >= 0 {
+			page.Links[anchor].Text = collapseSpace(text)
+			anchor = -1
+		}
+		text = text[:0]
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			endAnchor()
+			return page
+
+		case html.TextToken:
+			if inTitle && page.Title == "" {
+				page.Title = string(z.Text())
+			}
+			if anchor >= 0 {
+				text = append(text, z.Text()...)
+				text = append(text, ' ')
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			a := readAttrs(z, tag, hasAttr)
+
+			section := SectionBody
+			if len(open) > 0 {
+				section = open[len(open)-1].section
+			}
+			if a.section != "" {
+				section = a.section
+			} else if s := tagSections[tag]; s != "" {
+				section = s
+			}
+
+			switch tag {
+			case atom.Body:
+				inHead = false
+			case atom.Title:
+				inTitle = true
+			case atom.A:
+				// An <a> closes the one before, they don't nest
+				endAnchor()
+				if a.hasHref {
+					page.Links = append(page.Links, Link{Href: a.href, Rel: relValues(a.rel), Section: section, Element: "a"})
+					anchor, anchorAt = len(page.Links)-1, len(open)
+				}
+			case atom.Area:
+				if a.hasHref {
+					page.Links = append(page.Links, Link{
+						Href:    a.href,
+						Text:    strings.TrimSpace(a.alt),
+						Rel:     relValues(a.rel),
+						Section: section,
+						Element: "area",
+					})
+				}
+			case atom.Link:
+				if inHead && page.Canonical == "" {
+					page.Canonical = canonicalURL(a, base)
+				}
+			case atom.Meta:
+				if inHead {
+					addMetadata(page.Metadata, a.name, a.content, base)
+				}
+			}
+
+			// Like the HTML parser, ignore self-closing slashes on other elements
+			if !voidElements[tag] {
+				open = append(open, openElement{tag: tag, section: section})
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Title {
+				inTitle = false
+			}
+			// Close the innermost element of the tag and any left open inside it
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].tag == tag {
+					if anchor >= 0 && anchorAt >= i {
+						endAnchor()
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// relValues splits a rel attribute into lowercased values
+func relValues(rel string) []string {
+	if rel == "" {
+		return nil
+	}
+	return strings.Fields(strings.ToLower(rel))
+}
+
+// canonicalURL returns the URL of a <link rel="canonical">, empty for other links
+func canonicalURL(a tagAttrs, base *url.URL) string {
+	href := strings.TrimSpace(a.href)
+	if href == "" {
+		return ""
+	}
+	for _, rel := range relValues(a.rel) {
+		if rel == "canonical" {
+			return resolveURL(base, href)
+		}
+	}
+	return ""
+}
+
+// addMetadata adds the content of a meta tag named key to metadata if it's
+// one ExtractMetadata returns
+func addMetadata(metadata map[string]string, key, value string, base *url.URL) {
+	value = strings.TrimSpace(value)
+	// Dots would nest the key in stored documents
+	if value == "" || strings.Contains(key, ".") {
+		return
+	}
+	if !strings.HasPrefix(key, "og:") && !strings.HasPrefix(key, "twitter:") && key != "description" && key != "keywords" {
+		return
+	}
+	if _, ok := metadata[key]; ok {
+		return
+	}
+	if metadataURLs[key] {
+		if value = resolveURL(base, value); value == "" {
+			return
+		}
+	}
+	metadata[key] = value
+}
+
+// resolveURL returns href as an absolute URL, or as written without base
+func resolveURL(base *url.URL, href string) string {
+	if base == nil {
+		return href
+	}
+	return ToAbsoluteURL(base, href)
+}
+
+// collapseSpace returns the words of b separated by single spaces
+func collapseSpace(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.Grow(len(b))
+	space := false
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if unicode.IsSpace(r) {
+			space = sb.Len() > 0
+		} else {
+			if space {
+				sb.WriteByte(' ')
+				space = false
+			}
+			sb.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return sb.String()
+}
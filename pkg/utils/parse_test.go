@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parsePage exercises sections, nesting and the head tags ParseHTML reads
+const parsePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Caf&eacute; &amp; Bar</title>
+  <link rel="stylesheet" href="/style.css">
+  <link rel="Canonical" href=" /menu ">
+  <meta property="og:title" content="Cafe">
+  <meta property="og:image" content="/img/front.jpg">
+  <meta name="description" content=" Coffee and more ">
+  <meta name="description" content="Second">
+  <meta name="robots" content="noindex">
+</head>
+<body>
+  <header><a href="/">Home</a></header>
+  <div role="navigation">
+    <div><a href="/menu" rel="Nofollow UGC">Our <b>menu</b></a></div>
+    <a href="/about">About</a>
+  </div>
+  <main>
+    <p>Open <a href="/hours">every
+      day</a>
+    <svg><title>icon</title></svg>
+    <a href="/a">A<a href="/b">B</a>
+    <a name="anchor">No href</a>
+    <map><area href="/map" alt=" Map "></map>
+  </main>
+  <aside><a href=/side>Side</aside>
+  <a href="/end">End</a>
+  <link rel="canonical" href="/late">
+  <meta property="og:url" content="/late">
+</body>
+</html>`
+
+func TestParseHTML(t *testing.T) {
+	base, _ := url.Parse("https://cafe.example/home/")
+	page := ParseHTML(parsePage, base)
+
+	if page.Title != "Café & Bar" {
+		t.Errorf("Title = %q", page.Title)
+	}
+	if page.Canonical != "https://cafe.example/menu" {
+		t.Errorf("Canonical = %q", page.Canonical)
+	}
+	wantMeta := map[string]string{
+		"og:title":    "Cafe",
+		"og:image":    "https://cafe.example/img/front.jpg",
+		"description": "Coffee and more",
+	}
+	if !reflect.DeepEqual(page.Metadata, wantMeta) {
+		t.Errorf("Metadata = %v", page.Metadata)
+	}
+
+	want := []Link{
+		{Href: "/", Text: "Home", Section: SectionHeader, Element: "a"},
+		{Href: "/menu", Text: "Our menu", Rel: []string{"nofollow", "ugc"}, Section: SectionNav, Element: "a"},
+		{Href: "/about", Text: "About", Section: SectionNav, Element: "a"},
+		{Href: "/hours", Text: "every day", Section: SectionMain, Element: "a"},
+		{Href: "/a", Text: "A", Section: SectionMain, Element: "a"},
+		{Href: "/b", Text: "B", Section: SectionMain, Element: "a"},
+		{Href: "/map", Text: "Map", Section: SectionMain, Element: "area"},
+		{Href: "/side", Text: "Side", Section: SectionAside, Element: "a"},
+		{Href: "/end", Text: "End", Section: SectionBody, Element: "a"},
+	}
+	if !reflect.DeepEqual(page.Links, want) {
+		t.Errorf("Links =\n%+v\nwant\n%+v", page.Links, want)
+	}
+}
+
+func TestParseHTMLTitle(t *testing.T) {
+	tests := map[string]string{
+		"<title></title><title>Second</title>": "Second",
+		"<body><h1>No title</h1>":              "",
+		"<title> Spaced </title>":              " Spaced ",
+		"<title>Unclosed":                      "Unclosed",
+	}
+	for content, want := range tests {
+		if got := ExtractTitle(content); got != want {
+			t.Errorf("ExtractTitle(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func BenchmarkParseHTML(b *testing.B) {
+	content := benchmarkPage()
+	base, _ := url.Parse("https://cafe.example/home/")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+	for i := 0; i < b.N; i++ {
+		ParseHTML(content, base)
+	}
+}
+
+// benchmarkPage returns a page the size of a typical article with a few
+// hundred links
+func benchmarkPage() string {
+	var sb strings.Builder
+	sb.WriteString(`<html><head><title>Benchmark</title><link rel="canonical" href="/bench">`)
+	sb.WriteString(`<meta property="og:title" content="Benchmark"><meta name="description" content="A page"></head><body>`)
+	sb.WriteString(`<header><nav><ul>`)
+	for i := 0; i < 50; i++ {
+		sb.WriteString(`<li><a href="/section/` + strings.Repeat("x", i%7) + `">Section</a></li>`)
+	}
+	sb.WriteString(`</ul></nav></header><main><article>`)
+	for i := 0; i < 100; i++ {
+		sb.WriteString(`<p class="text">Some paragraph text with a <a href="/article/`)
+		sb.WriteString(strings.Repeat("y", i%11))
+		sb.WriteString(`" rel="nofollow">link <em>inside</em></a> and more words after it.</p>`)
+	}
+	sb.WriteString(`</article></main><footer>`)
+	for i := 0; i < 50; i++ {
+		sb.WriteString(`<a href="https://other.example/` + strings.Repeat("z", i%5) + `">Other</a> `)
+	}
+	sb.WriteString(`</footer><script>var x = "<a href='/nope'>";</script></body></html>`)
+	return sb.String()
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reasons a file is deleted by a retention sweep
+const (
+	RetentionAge    = "age"          // Older than MaxAge
+	RetentionDomain = "domain_limit" // Beyond MaxFilesPerDomain
+	RetentionDisk   = "disk_limit"   // Beyond MaxTotalSize
+)
+
+// RetentionPolicy limits what the content saver keeps. Zero values disable
+// a limit.
+type RetentionPolicy struct {
+	MaxTotalSize      int64         // Bytes of all saved pages
+	MaxFilesPerDomain int           // Saved pages per domain
+	MaxAge            time.Duration // Age of a saved page, from its modification time
+}
+
+// RetentionReport lists what a sweep deleted, or would delete in a dry run
+type RetentionReport struct {
+	DryRun     bool          `json:"dry_run"`
+	Scanned    int           `json:"scanned"`
+	TotalSize  int64         `json:"total_size"` // Bytes before the sweep
+	Deleted    []DeletedFile `json:"deleted"`
+	FreedBytes int64         `json:"freed_bytes"`
+}
+
+// DeletedFile is a saved page removed by a retention sweep
+type DeletedFile struct {
+	Path    string    `json:"path"` // Relative to the content directory
+	URL     string    `json:"url,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Reason  string    `json:"reason"`
+}
+
+// savedFile is a page file found by a sweep
+type savedFile struct {
+	path    string // Relative, with forward slashes
+	domain  string
+	size    int64
+	modTime time.Time
+}
+
+// Sweep applies policy to the saved pages, oldest first: pages past MaxAge
+// go, then the oldest pages of domains over MaxFilesPerDomain, then the
+// oldest pages until the total fits MaxTotalSize. Assets and the manifest
+// are left alone. With dryRun nothing is deleted.
+func (cs *ContentSaver) Sweep(policy RetentionPolicy, now time.Time, dryRun bool) (*RetentionReport, error) {
+	report := &RetentionReport{DryRun: dryRun, Deleted: []DeletedFile{}}
+	if !cs.enabled {
+		return report, nil
+	}
+	files, err := cs.pageFiles()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	report.Scanned = len(files)
+	perDomain := make(map[string]int)
+	for _, f := range files {
+		report.TotalSize += f.size
+		perDomain[f.domain]++
+	}
+
+	remaining := report.TotalSize
+	deleted := make([]bool, len(files))
+	drop := func(i int, reason string) {
+		f := files[i]
+		deleted[i] = true
+		remaining -= f.size
+		perDomain[f.domain]--
+		entry := DeletedFile{Path: f.path, Size: f.size, ModTime: f.modTime, Reason: reason}
+		entry.URL = cs.pages.urlOf(f.path)
+		report.Deleted = append(report.Deleted, entry)
+		report.FreedBytes += f.size
+	}
+
+	for i, f := range files {
+		if policy.MaxAge > 0 && now.Sub(f.modTime) > policy.MaxAge {
+			drop(i, RetentionAge)
+		}
+	}
+	if policy.MaxFilesPerDomain > 0 {
+		for i, f := range files {
+			if !deleted[i] && perDomain[f.domain] > policy.MaxFilesPerDomain {
+				drop(i, RetentionDomain)
+			}
+		}
+	}
+	if policy.MaxTotalSize > 0 {
+		for i := range files {
+			if remaining <= policy.MaxTotalSize {
+				break
+			}
+			if !deleted[i] {
+				drop(i, RetentionDisk)
+			}
+		}
+	}
+
+	if dryRun || len(report.Deleted) == 0 {
+		return report, nil
+	}
+	for _, d := range report.Deleted {
+		if err := os.Remove(filepath.Join(cs.baseDir, filepath.FromSlash(d.Path))); err != nil && !os.IsNotExist(err) {
+			return report, err
+		}
+		cs.pages.remove(d.Path)
+	}
+	return report, cs.pages.flush()
+}
+
+// pageFiles lists the saved pages: files of an output format directly in a
+// domain directory
+func (cs *ContentSaver) pageFiles() ([]savedFile, error) {
+	var files []savedFile
+	err := filepath.Walk(cs.baseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == cs.baseDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(cs.baseDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		domain, name, ok := strings.Cut(rel, "/")
+		if info.IsDir() {
+			// Domain directories hold pages, deeper ones hold assets
+			if ok && rel != "." {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !ok || !isPageFile(name) {
+			return nil
+		}
+		files = append(files, savedFile{path: rel, domain: domain, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// isPageFile reports whether name has the extension of an output format
+func isPageFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, e := range formatExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAged writes a saved page of size bytes last modified age before now
+func writeAged(t *testing.T, dir, rel string, size int, now time.Time, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deletedPaths(report *RetentionReport) string {
+	var paths []string
+	for _, d := range report.Deleted {
+		paths = append(paths, d.Path+":"+d.Reason)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	writeAged(t, dir, "a_com/old.html", 10, now, 48*time.Hour)
+	writeAged(t, dir, "a_com/one.html", 10, now, 3*time.Hour)
+	writeAged(t, dir, "a_com/two.json", 10, now, 2*time.Hour)
+	writeAged(t, dir, "a_com/three.html", 10, now, time.Hour)
+	writeAged(t, dir, "b_com/x.mhtml", 30, now, 90*time.Minute)
+	writeAged(t, dir, "b_com/y.warc", 5, now, time.Minute)
+	// Assets and other files aren't pages
+	writeAged(t, dir, "assets/images/a_com/logo.png", 100, now, 72*time.Hour)
+	writeAged(t, dir, "a_com/notes.txt", 100, now, 72*time.Hour)
+
+	cs := NewContentSaver(dir, true, 0)
+	policy := RetentionPolicy{MaxAge: 24 * time.Hour, MaxFilesPerDomain: 2, MaxTotalSize: 20}
+
+	report, err := cs.Sweep(policy, now, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a_com/old.html:age a_com/one.html:domain_limit a_com/two.json:disk_limit b_com/x.mhtml:disk_limit"
+	if got := deletedPaths(report); got != want {
+		t.Fatalf("dry run deletes %s, want %s", got, want)
+	}
+	if report.Scanned != 6 || report.TotalSize != 75 || report.FreedBytes != 60 || !report.DryRun {
+		t.Fatalf("report = %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a_com", "old.html")); err != nil {
+		t.Fatal("dry run deleted a file")
+	}
+
+	if report, err = cs.Sweep(policy, now, false); err != nil || deletedPaths(report) != want {
+		t.Fatalf("Sweep() = %s, %v", deletedPaths(report), err)
+	}
+	for _, rel := range []string{"a_com/old.html", "a_com/one.html", "a_com/two.json", "b_com/x.mhtml"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+			t.Errorf("%s not deleted", rel)
+		}
+	}
+	if report, _ = cs.Sweep(policy, now, false); len(report.Deleted) != 0 {
+		t.Fatalf("second sweep deletes %s", deletedPaths(report))
+	}
+}
+
+func TestSweepUpdatesManifest(t *testing.T) {
+	dir := t.TempDir()
+	cs := NewContentSaver(dir, true, 0)
+	page := savedPage()
+	if err := cs.SavePage(page); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := cs.Lookup(page.URL)
+
+	report, err := cs.Sweep(RetentionPolicy{MaxAge: time.Nanosecond}, time.Now().Add(time.Hour), false)
+	if err != nil || len(report.Deleted) != 1 || report.Deleted[0].URL != page.URL {
+		t.Fatalf("Sweep() = %+v, %v", report, err)
+	}
+	if _, ok := cs.Lookup(page.URL); ok {
+		t.Fatal("deleted page still in the manifest")
+	}
+	files, err := LoadManifest(dir)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("manifest after sweep = %v, %v (deleted %s)", files, err, file)
+	}
+}
@@ -63,7 +63,7 @@ echo "Building crawler..."
 go build -o crawler ./cmd/crawler
 
 # Construct the command
-CMD="./crawler -seed \"$DOMAIN\" -config \"$CONFIG\""
+CMD="./crawler crawl -seed \"$DOMAIN\" -config \"$CONFIG\""
 if [ ! -z "$MONGO_URI" ]; then
     CMD="$CMD -mongo \"$MONGO_URI\""
 fi